@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"backend/internal/audit"
+	"backend/internal/auth"
+	"backend/internal/config"
+	"backend/internal/database"
+	"backend/internal/models"
+	"backend/internal/telemetry"
+)
+
+const usage = `Usage: admin <command> [args]
+
+Commands:
+  create-admin <email> <name>  Create a local account holding the admin role,
+                               reading its password from standard input. An
+                               existing account is granted the role and keeps
+                               its password.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	// Load configuration; the arguments are admin commands, not flags
+	cfg, err := config.Load(nil)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	// Initialize database
+	db, err := database.Connect(cfg.DatabaseURL, telemetry.Disabled())
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, db, os.Stdin, os.Args[1], os.Args[2:]); err != nil {
+		log.Fatalf("admin %s: %v", os.Args[1], err)
+	}
+}
+
+// run dispatches a single admin command
+func run(ctx context.Context, db *sql.DB, stdin io.Reader, command string, args []string) error {
+	switch command {
+	case "create-admin":
+		if len(args) != 2 || args[0] == "" || strings.TrimSpace(args[1]) == "" {
+			fmt.Fprint(os.Stderr, usage)
+			return errors.New("an email and a name are required")
+		}
+		return createAdmin(ctx, db, stdin, args[0], strings.TrimSpace(args[1]))
+
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown command %q", command)
+	}
+}
+
+// createAdmin grants the admin role to the account with the given email,
+// first creating it with a password read from stdin if there is none. This
+// is how a fresh install without single sign-on gets its first
+// administrator, who can then manage everyone else through the API.
+func createAdmin(ctx context.Context, db *sql.DB, stdin io.Reader, email, name string) error {
+	userRepo := models.NewUserRepository(db)
+	auditLog := audit.NewLogger(models.NewAuditRepository(db))
+
+	user, err := userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		return fmt.Errorf("failed to look up %s: %w", email, err)
+	}
+
+	if user == nil {
+		password, err := readPassword(stdin)
+		if err != nil {
+			return err
+		}
+		if err := auth.ValidatePassword(password); err != nil {
+			return err
+		}
+		hash, err := auth.HashPassword(password)
+		if err != nil {
+			return err
+		}
+
+		user = &models.User{Name: name, Email: email, PasswordHash: hash}
+		err = audit.Audited(ctx, db, func(tx *sql.Tx) (audit.Event, error) {
+			if err := models.NewUserRepository(tx).Create(ctx, user); err != nil {
+				return audit.Event{}, err
+			}
+			return audit.Event{
+				Action:       "user.create",
+				ResourceType: "user",
+				ResourceID:   strconv.Itoa(user.ID),
+				After:        user,
+			}, nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", email, err)
+		}
+		log.Printf("Created account %d for %s", user.ID, email)
+	} else {
+		log.Printf("Account %d for %s already exists; its password is unchanged", user.ID, email)
+	}
+
+	if err := models.NewRoleRepository(db).Assign(ctx, user.ID, "admin", nil); err != nil {
+		return fmt.Errorf("failed to grant the admin role: %w", err)
+	}
+	if err := auditLog.Record(ctx, audit.Event{
+		Action:       "role.assign",
+		ResourceType: "user",
+		ResourceID:   strconv.Itoa(user.ID),
+		Details:      map[string]string{"role": "admin"},
+	}); err != nil {
+		return fmt.Errorf("failed to audit the role grant: %w", err)
+	}
+	log.Printf("Granted admin to %s", email)
+
+	return nil
+}
+
+// readPassword reads the first line of stdin, so the password can be piped
+// from a secret file rather than appearing in arguments or the environment
+func readPassword(stdin io.Reader) (string, error) {
+	line, err := bufio.NewReader(stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("failed to read the password: %w", err)
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", errors.New("a password is required on standard input")
+	}
+	return password, nil
+}
@@ -2,34 +2,148 @@ package main
 
 import (
 	"context"
-	"log"
+	"errors"
+	"flag"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"backend/internal/api/handlers"
 	"backend/internal/api/router"
 	"backend/internal/config"
 	"backend/internal/database"
+	"backend/internal/health"
+	"backend/internal/hl7"
+	"backend/internal/inference"
+	"backend/internal/logging"
+	"backend/internal/models"
+	"backend/internal/slide"
+	"backend/internal/storage"
+	"backend/internal/telemetry"
 )
 
 func main() {
-	// Load configuration
-	cfg, err := config.Load()
+	// Load configuration; flags override the config file and environment
+	loader := config.NewLoader(os.Args[1:])
+	cfg, err := loader.Load(context.Background())
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fatal("Failed to load configuration", err)
+	}
+
+	// Log structured JSON; the standard library logger writes through it too
+	logLevel := new(slog.LevelVar)
+	logger, err := logging.NewFromConfig(os.Stdout, cfg, logLevel)
 	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
+		fatal("Failed to configure logging", err)
+	}
+	slog.SetDefault(logger)
+
+	// Reload reloadable settings on SIGHUP or when a source file changes
+	settings := config.NewWatcher(loader, cfg, logger)
+	settings.Subscribe(func(old, new *config.Config) {
+		if new.LogLevel != old.LogLevel {
+			logging.SetLevel(logLevel, new.LogLevel)
+		}
+	})
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	go settings.Run(watchCtx, cfg.ConfigReloadInterval)
+
+	// Record metrics for Prometheus, and export traces and metrics when an
+	// OTLP endpoint is configured
+	tel, err := telemetry.NewFromConfig(context.Background(), cfg)
+	if err != nil {
+		fatal("Failed to configure telemetry", err)
 	}
 
 	// Initialize database
-	db, err := database.Connect(cfg.DatabaseURL)
+	db, err := database.Connect(cfg.DatabaseURL, tel)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		fatal("Failed to connect to database", err)
 	}
 	defer db.Close()
 
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		fatal("Failed to load migrations", err)
+	}
+
+	// Apply pending migrations unless they are run separately via cmd/migrate
+	if cfg.AutoMigrate {
+		migrateCtx, cancelMigrate := context.WithTimeout(context.Background(), 5*time.Minute)
+		applied, err := migrator.Up(migrateCtx)
+		cancelMigrate()
+		if err != nil {
+			fatal("Failed to run database migrations", err)
+		}
+		slog.Info("Applied database migrations", "count", applied)
+	}
+
+	// Grant the admin role to bootstrap users that already exist
+	roleRepo := models.NewRoleRepository(db)
+	for _, email := range cfg.BootstrapAdminEmails {
+		found, err := roleRepo.AssignByEmail(context.Background(), email, "admin")
+		if err != nil {
+			fatal("Failed to grant bootstrap admin role", err)
+		}
+		if !found {
+			slog.Warn("Bootstrap admin has no account yet; create one with cmd/admin create-admin, or restart after they sign in", "email", email)
+		}
+	}
+
+	// Start inference workers; they stop with the server
+	modelClient, err := inference.NewClientFromConfig(cfg)
+	if err != nil {
+		fatal("Failed to create inference client", err)
+	}
+	workers := inference.NewPool(models.NewInferenceJobRepository(db), modelClient, inference.OptionsFromConfig(cfg))
+	workers.Start()
+
+	// Connect to blob storage for uploaded slides
+	blobCtx, cancelBlob := context.WithTimeout(context.Background(), 30*time.Second)
+	blobs, err := storage.NewBlobStoreFromConfig(blobCtx, cfg)
+	cancelBlob()
+	if err != nil {
+		fatal("Failed to initialize blob storage", err)
+	}
+
+	// Cache rendered slide tiles in memory and on local disk
+	tiles, err := slide.NewCache(cfg.TileCacheMemory, cfg.TileCacheDir, cfg.TileCacheDiskSize)
+	if err != nil {
+		fatal("Failed to initialize tile cache", err)
+	}
+
+	// Accept HL7 orders over MLLP and send results to the configured receiver
+	var hl7Server *hl7.Server
+	if cfg.HL7Port != "" {
+		hl7Server = hl7.NewServer(hl7.NewIngestor(hl7.NewStore(db), hl7.HeaderFromConfig(cfg)), cfg.HL7IdleTimeout)
+		go func() {
+			slog.Info("HL7 listener starting", "port", cfg.HL7Port)
+			if err := hl7Server.ListenAndServe(":" + cfg.HL7Port); err != nil && err != hl7.ErrServerClosed {
+				fatal("HL7 listener failed to start", err)
+			}
+		}()
+	}
+	hl7Sender := hl7.NewSender(models.NewHL7MessageRepository(db), hl7.SenderOptionsFromConfig(cfg))
+	if cfg.HL7OutboundAddr != "" {
+		hl7Sender.Start()
+	}
+
+	// Check dependencies for the liveness, readiness and startup probes
+	checks := health.NewRegistry()
+	checks.Register(health.Database(db))
+	checks.Register(health.Migrations(migrator))
+	checks.Register(health.BlobStore(blobs))
+	checks.Register(health.Inference(modelClient))
+
 	// Create router with dependencies
-	r := router.New(db, cfg)
+	r := router.New(db, settings, blobs, tiles, logger, tel, checks)
 
 	// Create server
 	srv := &http.Server{
@@ -42,25 +156,83 @@ func main() {
 
 	// Start server in a goroutine
 	go func() {
-		log.Printf("Server starting on port %s", cfg.Port)
+		slog.Info("Server starting", "port", cfg.Port)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server failed to start: %v", err)
+			fatal("Server failed to start", err)
 		}
 	}()
 
+	// Serve Prometheus metrics and verbose health probes on an internal port,
+	// kept off the public API
+	var metricsSrv *http.Server
+	if cfg.MetricsPort != "" {
+		internalHealth := handlers.NewHealthHandler(checks, true)
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("GET /metrics", tel.MetricsHandler())
+		metricsMux.HandleFunc("GET /livez", internalHealth.Livez)
+		metricsMux.HandleFunc("GET /readyz", internalHealth.Readyz)
+		metricsMux.HandleFunc("GET /startupz", internalHealth.Startupz)
+		metricsSrv = &http.Server{
+			Addr:        ":" + cfg.MetricsPort,
+			Handler:     metricsMux,
+			ReadTimeout: 15 * time.Second,
+			IdleTimeout: 60 * time.Second,
+		}
+		go func() {
+			slog.Info("Metrics server starting", "port", cfg.MetricsPort)
+			if err := metricsSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				fatal("Metrics server failed to start", err)
+			}
+		}()
+	}
+
 	// Wait for interrupt signal to gracefully shutdown the server
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
-	log.Println("Shutting down server...")
+	slog.Info("Shutting down server")
 
 	// Graceful shutdown with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+		fatal("Server forced to shutdown", err)
+	}
+
+	if metricsSrv != nil {
+		if err := metricsSrv.Shutdown(ctx); err != nil {
+			slog.Warn("Metrics server stopped before scrapes finished", "error", err)
+		}
+	}
+
+	if hl7Server != nil {
+		if err := hl7Server.Shutdown(ctx); err != nil {
+			slog.Warn("HL7 listener stopped before messages were acknowledged", "error", err)
+		}
+	}
+	if err := hl7Sender.Stop(ctx); err != nil {
+		slog.Warn("HL7 sender stopped before its message was acknowledged", "error", err)
+	}
+
+	// Let running inference jobs finish; any still running at the deadline are requeued
+	if err := workers.Stop(ctx); err != nil {
+		slog.Warn("Inference workers stopped before jobs finished", "error", err)
+	}
+	if err := inference.Close(modelClient); err != nil {
+		slog.Warn("Failed to close the inference client", "error", err)
+	}
+
+	// Flush spans and metrics recorded during shutdown
+	if err := tel.Shutdown(ctx); err != nil {
+		slog.Warn("Telemetry was not fully exported", "error", err)
 	}
 
-	log.Println("Server exited")
+	slog.Info("Server exited")
+}
+
+// fatal logs an error that prevents the server from running and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"backend/internal/config"
+	"backend/internal/database"
+	"backend/internal/telemetry"
+)
+
+const usage = `Usage: migrate <command> [args]
+
+Commands:
+  up          Apply all pending migrations
+  down [N]    Roll back the N most recent migrations (default 1)
+  status      List migrations and whether they are applied
+  redo        Roll back and re-apply the most recent migration
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	// Load configuration; the arguments are migrate commands, not flags
+	cfg, err := config.Load(nil)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	// Initialize database
+	db, err := database.Connect(cfg.DatabaseURL, telemetry.Disabled())
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		log.Fatalf("Failed to load migrations: %v", err)
+	}
+
+	// Cancel in-flight migrations on interrupt; each runs in a transaction
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, migrator, os.Args[1], os.Args[2:]); err != nil {
+		log.Fatalf("migrate %s: %v", os.Args[1], err)
+	}
+}
+
+// run dispatches a single migrate command
+func run(ctx context.Context, migrator *database.Migrator, command string, args []string) error {
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		log.Printf("Applied %d migration(s)", applied)
+
+	case "down":
+		n := 1
+		if len(args) > 0 {
+			parsed, err := strconv.Atoi(args[0])
+			if err != nil || parsed < 1 {
+				return fmt.Errorf("invalid number of migrations %q", args[0])
+			}
+			n = parsed
+		}
+		rolledBack, err := migrator.Down(ctx, n)
+		if err != nil {
+			return err
+		}
+		log.Printf("Rolled back %d migration(s)", rolledBack)
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if status.Missing {
+				state += " (no local file)"
+			}
+			fmt.Printf("%04d  %-40s  %s\n", status.Version, status.Name, state)
+		}
+
+	case "redo":
+		if err := migrator.Redo(ctx); err != nil {
+			return err
+		}
+		log.Println("Re-applied most recent migration")
+
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown command %q", command)
+	}
+
+	return nil
+}
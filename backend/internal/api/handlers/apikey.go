@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/api/problem"
+	"backend/internal/audit"
+	"backend/internal/auth"
+	"backend/internal/models"
+)
+
+// APIKeyHandler handles HTTP requests for managing API keys
+type APIKeyHandler struct {
+	keyRepo  *models.APIKeyRepository
+	auditLog *audit.Logger
+}
+
+// NewAPIKeyHandler creates a new API key handler
+func NewAPIKeyHandler(db *sql.DB) *APIKeyHandler {
+	return &APIKeyHandler{
+		keyRepo:  models.NewAPIKeyRepository(db),
+		auditLog: newAuditLogger(db),
+	}
+}
+
+// CreateAPIKeyRequest represents the create API key request body
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// CreateAPIKeyResponse includes the plaintext token, which is only returned once
+type CreateAPIKeyResponse struct {
+	APIKey models.APIKey `json:"api_key"`
+	Token  string        `json:"token"`
+}
+
+// GetAPIKeys handles GET /api/api-keys
+func (h *APIKeyHandler) GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		problem.Error(w, r, http.StatusUnauthorized, "Not authenticated")
+		return
+	}
+
+	keys, err := h.keyRepo.GetByUserID(r.Context(), user.ID)
+	if err != nil {
+		problem.Internal(w, r, "Failed to get API keys", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(keys)
+}
+
+// CreateAPIKey handles POST /api/api-keys. Scopes must be a non-empty subset
+// of the caller's own permissions.
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		problem.Error(w, r, http.StatusUnauthorized, "Not authenticated")
+		return
+	}
+
+	// A key minting keys could outlive its own expiry or revocation
+	if _, ok := auth.APIKeyFromContext(r.Context()); ok {
+		problem.Error(w, r, http.StatusForbidden, "API keys cannot create API keys; sign in to create one")
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		problem.InvalidJSON(w, r)
+		return
+	}
+
+	// Basic validation
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Scopes) == 0 {
+		problem.Error(w, r, http.StatusBadRequest, "Name and at least one scope are required")
+		return
+	}
+
+	for _, scope := range req.Scopes {
+		if !auth.HasPermission(r.Context(), auth.Permission(scope)) {
+			problem.Error(w, r, http.StatusForbidden, fmt.Sprintf("Scope %q exceeds your permissions", scope))
+			return
+		}
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		problem.Error(w, r, http.StatusBadRequest, "expires_at must be in the future")
+		return
+	}
+
+	token, prefix, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		problem.Internal(w, r, "Failed to create API key", err)
+		return
+	}
+
+	key := models.APIKey{
+		UserID:    user.ID,
+		Name:      req.Name,
+		Prefix:    prefix,
+		KeyHash:   hash,
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+	}
+
+	if err := h.keyRepo.Create(r.Context(), &key); err != nil {
+		problem.Internal(w, r, "Failed to create API key", err)
+		return
+	}
+
+	recordAudit(r, h.auditLog, audit.Event{
+		Action:       "api_key.create",
+		ResourceType: "api_key",
+		ResourceID:   strconv.Itoa(key.ID),
+		After:        key,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(CreateAPIKeyResponse{APIKey: key, Token: token})
+}
+
+// RevokeAPIKey handles DELETE /api/api-keys/{id}. Owners may revoke their own
+// keys; role managers may revoke any key.
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		problem.Error(w, r, http.StatusUnauthorized, "Not authenticated")
+		return
+	}
+
+	// Extract ID from URL path
+	idStr := strings.TrimPrefix(r.URL.Path, "/api/api-keys/")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, "Invalid API key ID")
+		return
+	}
+
+	key, err := h.keyRepo.GetByID(r.Context(), id)
+	if err != nil {
+		problem.Internal(w, r, "Failed to get API key", err)
+		return
+	}
+
+	// Do not reveal other users' keys
+	if key == nil || (key.UserID != user.ID && !auth.HasPermission(r.Context(), auth.PermissionRolesManage)) {
+		problem.Error(w, r, http.StatusNotFound, "API key not found")
+		return
+	}
+
+	if err := h.keyRepo.Revoke(r.Context(), id, time.Now().UTC()); err != nil {
+		if err == sql.ErrNoRows {
+			problem.Error(w, r, http.StatusConflict, "API key already revoked")
+			return
+		}
+		problem.Internal(w, r, "Failed to revoke API key", err)
+		return
+	}
+
+	recordAudit(r, h.auditLog, audit.Event{
+		Action:       "api_key.revoke",
+		ResourceType: "api_key",
+		ResourceID:   strconv.Itoa(id),
+		Before:       key,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"backend/internal/auth"
+	"backend/internal/models"
+)
+
+func TestAPIKeyHandler_CreateAPIKey_Unauthenticated(t *testing.T) {
+	handler := NewAPIKeyHandler(nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/api-keys", bytes.NewBufferString(`{"name":"analyzer","scopes":["users:read"]}`))
+	w := httptest.NewRecorder()
+
+	handler.CreateAPIKey(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestAPIKeyHandler_CreateAPIKey_ScopeExceedsPermissions(t *testing.T) {
+	handler := NewAPIKeyHandler(nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/api-keys", bytes.NewBufferString(`{"name":"analyzer","scopes":["users:delete"]}`))
+	ctx := auth.WithUser(req.Context(), &models.User{ID: 1})
+	ctx = auth.WithAccess(ctx, auth.NewAccess([]string{"viewer"}, []string{"users:read"}))
+	w := httptest.NewRecorder()
+
+	handler.CreateAPIKey(w, req.WithContext(ctx))
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d, got %d", http.StatusForbidden, w.Code)
+	}
+}
+
+func TestAPIKeyHandler_CreateAPIKey_MissingScopes(t *testing.T) {
+	handler := NewAPIKeyHandler(nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/api-keys", bytes.NewBufferString(`{"name":"analyzer"}`))
+	ctx := auth.WithUser(req.Context(), &models.User{ID: 1})
+	w := httptest.NewRecorder()
+
+	handler.CreateAPIKey(w, req.WithContext(ctx))
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestAPIKeyHandler_CreateAPIKey_WithAPIKey(t *testing.T) {
+	handler := NewAPIKeyHandler(nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/api-keys", bytes.NewBufferString(`{"name":"analyzer","scopes":["users:read"]}`))
+	ctx := auth.WithUser(req.Context(), &models.User{ID: 1})
+	ctx = auth.WithAccess(ctx, auth.NewAccess([]string{"admin"}, []string{"users:read"}))
+	ctx = auth.WithAPIKey(ctx, &models.APIKey{ID: 3, UserID: 1})
+	w := httptest.NewRecorder()
+
+	handler.CreateAPIKey(w, req.WithContext(ctx))
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d, got %d", http.StatusForbidden, w.Code)
+	}
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"backend/internal/models"
+)
+
+func TestAuditList_Parse(t *testing.T) {
+	values, _ := url.ParseQuery("actor_id=7&action=test_order.read&resource_type=test_order&resource_id=42" +
+		"&from=2026-03-01T00:00:00Z&limit=20")
+
+	q, err := models.AuditList.Parse(values)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if q.Limit != 20 || q.Sort != "id" || !q.Desc {
+		t.Errorf("Expected 20 entries newest first, got %+v", q)
+	}
+
+	where, args := q.Where(false)
+	expected := "WHERE action = $1 AND actor_id = $2 AND occurred_at >= $3 AND resource_id = $4 AND resource_type = $5"
+	if where != expected {
+		t.Errorf("Expected %q, got %q", expected, where)
+	}
+	if len(args) != 5 || args[1] != int64(7) || args[2] != "2026-03-01 00:00:00Z" {
+		t.Errorf("Unexpected arguments %#v", args)
+	}
+
+	q, _ = models.AuditList.Parse(url.Values{})
+	if q.Limit != 100 {
+		t.Errorf("Expected default limit 100, got %d", q.Limit)
+	}
+}
+
+func TestAuditHandler_GetAuditLog_InvalidFilter(t *testing.T) {
+	handler := NewAuditHandler(nil)
+
+	for _, query := range []string{"actor_id=abc", "from=yesterday", "before_id=-1", "limit=0", "limit=5000"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/audit?"+query, nil)
+		w := httptest.NewRecorder()
+
+		handler.GetAuditLog(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", query, http.StatusBadRequest, w.Code)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"backend/internal/api/problem"
+	"backend/internal/audit"
+	"backend/internal/auth"
+	"backend/internal/models"
+)
+
+// codeAccountLocked is the problem code for a login refused by the lockout
+const codeAccountLocked = "account_locked"
+
+// AuthHandler handles login, logout and session endpoints
+type AuthHandler struct {
+	userRepo *models.UserRepository
+	sessions *auth.Manager
+	auditLog *audit.Logger
+}
+
+// NewAuthHandler creates a new auth handler
+func NewAuthHandler(db *sql.DB, sessions *auth.Manager) *AuthHandler {
+	return &AuthHandler{
+		userRepo: models.NewUserRepository(db),
+		sessions: sessions,
+		auditLog: newAuditLogger(db),
+	}
+}
+
+// LoginRequest represents the login request body
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// MeResponse represents the current user with their roles and permissions
+type MeResponse struct {
+	models.User
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+}
+
+// ChangePasswordRequest represents the change password request body
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// Login handles POST /api/auth/login
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	var req LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		problem.InvalidJSON(w, r)
+		return
+	}
+
+	if req.Email == "" || req.Password == "" {
+		problem.Error(w, r, http.StatusBadRequest, "Email and password are required")
+		return
+	}
+
+	user, err := h.sessions.Authenticate(r.Context(), req.Email, req.Password)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidCredentials):
+			h.recordLoginFailure(r, req.Email, "invalid_credentials")
+			problem.Error(w, r, http.StatusUnauthorized, "Invalid email or password")
+		case errors.Is(err, auth.ErrAccountLocked):
+			h.recordLoginFailure(r, req.Email, "account_locked")
+			problem.ErrorCode(w, r, http.StatusLocked, codeAccountLocked, "Account temporarily locked after repeated failed logins")
+		default:
+			problem.Internal(w, r, "Failed to log in", err)
+		}
+		return
+	}
+
+	if _, err := h.sessions.StartSession(w, r, user); err != nil {
+		problem.Internal(w, r, "Failed to log in", err)
+		return
+	}
+
+	recordAudit(r.WithContext(auth.WithUser(r.Context(), user)), h.auditLog, audit.Event{
+		Action:       "auth.login",
+		ResourceType: "user",
+		ResourceID:   strconv.Itoa(user.ID),
+		Details:      map[string]string{"method": "password"},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// Logout handles POST /api/auth/logout
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if err := h.sessions.EndSession(w, r); err != nil {
+		problem.Internal(w, r, "Failed to log out", err)
+		return
+	}
+
+	if user, ok := auth.UserFromContext(r.Context()); ok {
+		recordAudit(r, h.auditLog, audit.Event{
+			Action:       "auth.logout",
+			ResourceType: "user",
+			ResourceID:   strconv.Itoa(user.ID),
+		})
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Me handles GET /api/auth/me
+func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		problem.Error(w, r, http.StatusUnauthorized, "Not authenticated")
+		return
+	}
+
+	response := MeResponse{User: *user, Roles: []string{}, Permissions: []string{}}
+	if access, ok := auth.AccessFromContext(r.Context()); ok {
+		response.Roles = access.Roles
+		response.Permissions = access.Permissions()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// ChangePassword handles POST /api/auth/password. Every existing session for
+// the user is revoked and a fresh one is issued to the caller.
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		problem.Error(w, r, http.StatusUnauthorized, "Not authenticated")
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		problem.InvalidJSON(w, r)
+		return
+	}
+
+	if err := auth.ValidatePassword(req.NewPassword); err != nil {
+		problem.Invalid(w, r, err)
+		return
+	}
+
+	if _, err := h.sessions.Authenticate(r.Context(), user.Email, req.CurrentPassword); err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidCredentials):
+			problem.Error(w, r, http.StatusForbidden, "Current password is incorrect")
+		case errors.Is(err, auth.ErrAccountLocked):
+			problem.ErrorCode(w, r, http.StatusLocked, codeAccountLocked, "Account temporarily locked after repeated failed logins")
+		default:
+			problem.Internal(w, r, "Failed to change password", err)
+		}
+		return
+	}
+
+	hash, err := auth.HashPassword(req.NewPassword)
+	if err != nil {
+		problem.Internal(w, r, "Failed to change password", err)
+		return
+	}
+
+	if err := h.userRepo.SetPassword(r.Context(), user.ID, hash); err != nil {
+		problem.Internal(w, r, "Failed to change password", err)
+		return
+	}
+
+	if err := h.sessions.EndAllSessions(r.Context(), user.ID); err != nil {
+		problem.Internal(w, r, "Failed to change password", err)
+		return
+	}
+
+	if _, err := h.sessions.StartSession(w, r, user); err != nil {
+		problem.Internal(w, r, "Failed to change password", err)
+		return
+	}
+
+	recordAudit(r, h.auditLog, audit.Event{
+		Action:       "auth.password_change",
+		ResourceType: "user",
+		ResourceID:   strconv.Itoa(user.ID),
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// recordLoginFailure audits a rejected sign-in. There is no authenticated
+// actor, so the email that was tried is kept in the details.
+func (h *AuthHandler) recordLoginFailure(r *http.Request, email, reason string) {
+	recordAudit(r, h.auditLog, audit.Event{
+		Action:       "auth.login_failed",
+		ResourceType: "user",
+		Details:      map[string]string{"email": email, "reason": reason},
+	})
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"backend/internal/auth"
+	"backend/internal/models"
+)
+
+func TestAuthHandler_Login_InvalidJSON(t *testing.T) {
+	handler := NewAuthHandler(nil, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/login", bytes.NewBufferString("invalid json"))
+	w := httptest.NewRecorder()
+
+	handler.Login(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestAuthHandler_Login_MissingFields(t *testing.T) {
+	handler := NewAuthHandler(nil, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/login", bytes.NewBufferString(`{"email":"jane@example.com"}`))
+	w := httptest.NewRecorder()
+
+	handler.Login(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestAuthHandler_Me_Unauthenticated(t *testing.T) {
+	handler := NewAuthHandler(nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/me", nil)
+	w := httptest.NewRecorder()
+
+	handler.Me(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestAuthHandler_Me_Authenticated(t *testing.T) {
+	handler := NewAuthHandler(nil, nil)
+
+	user := &models.User{ID: 1, Name: "Jane Doe", Email: "jane@example.com", PasswordHash: "secret-hash"}
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/me", nil)
+	req = req.WithContext(auth.WithUser(req.Context(), user))
+	w := httptest.NewRecorder()
+
+	handler.Me(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	if bytes.Contains(w.Body.Bytes(), []byte("secret-hash")) {
+		t.Error("Expected password hash not to be serialized")
+	}
+}
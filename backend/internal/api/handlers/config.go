@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"backend/internal/config"
+)
+
+// ConfigHandler reports the running configuration for debugging
+type ConfigHandler struct {
+	settings *config.Watcher
+}
+
+// NewConfigHandler creates a handler reporting the configuration settings
+// currently holds
+func NewConfigHandler(settings *config.Watcher) *ConfigHandler {
+	return &ConfigHandler{settings: settings}
+}
+
+// GetConfig handles GET /debug/config, listing each setting's value and the
+// layer it came from, with secrets redacted
+func (h *ConfigHandler) GetConfig(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(h.settings.Current().Settings())
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"backend/internal/config"
+)
+
+func TestConfigHandler_GetConfig(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.DatabaseURL = "postgres://app:hunter2@db/app"
+	cfg.OIDCClientSecret = "s3cret"
+
+	w := httptest.NewRecorder()
+	NewConfigHandler(config.NewWatcher(nil, cfg, slog.Default())).GetConfig(w, httptest.NewRequest(http.MethodGet, "/debug/config", nil))
+
+	if w.Code != http.StatusOK || w.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("Expected an uncached 200, got %d %q", w.Code, w.Header().Get("Cache-Control"))
+	}
+	if body := w.Body.String(); strings.Contains(body, "hunter2") || strings.Contains(body, "s3cret") {
+		t.Errorf("Expected secrets to be redacted, got %s", body)
+	}
+
+	var settings []config.Setting
+	if err := json.NewDecoder(w.Body).Decode(&settings); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(settings) == 0 || settings[0].Key != "PORT" || settings[0].Value != "8080" || settings[0].Source != config.SourceDefault {
+		t.Errorf("Expected settings in declaration order with their sources, got %+v", settings)
+	}
+}
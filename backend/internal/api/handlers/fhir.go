@@ -0,0 +1,442 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/audit"
+	"backend/internal/fhir"
+	"backend/internal/logging"
+	"backend/internal/models"
+)
+
+// FHIRHandler serves the read-only FHIR R4 API under /fhir. Responses are
+// FHIR JSON, and errors are OperationOutcomes.
+type FHIRHandler struct {
+	patientRepo *models.PatientRepository
+	orderRepo   *models.TestOrderRepository
+	resultRepo  *models.TestResultRepository
+	auditLog    *audit.Logger
+	started     time.Time
+}
+
+// NewFHIRHandler creates a new FHIR handler
+func NewFHIRHandler(db *sql.DB) *FHIRHandler {
+	return &FHIRHandler{
+		patientRepo: models.NewPatientRepository(db),
+		orderRepo:   models.NewTestOrderRepository(db),
+		resultRepo:  models.NewTestResultRepository(db),
+		auditLog:    newAuditLogger(db),
+		started:     time.Now(),
+	}
+}
+
+// fhirEntry is a resource found by a search. auditID identifies the
+// underlying record in the audit log.
+type fhirEntry struct {
+	id       string
+	auditID  string
+	resource interface{}
+}
+
+// fhirFinder runs a search for one resource type, returning a page of
+// matches and the number of matches across all pages
+type fhirFinder func(ctx context.Context, search *fhir.Search) ([]fhirEntry, int, error)
+
+// fhirResource describes how one resource type is searched and audited
+type fhirResource struct {
+	resourceType string
+	auditType    string
+	find         fhirFinder
+}
+
+// Metadata handles GET /fhir/metadata
+func (h *FHIRHandler) Metadata(w http.ResponseWriter, r *http.Request) {
+	contentType, ok := negotiateFHIR(w, r)
+	if !ok {
+		return
+	}
+	writeFHIR(w, contentType, http.StatusOK, fhir.NewCapabilityStatement(fhirBase(r), h.started))
+}
+
+// SearchPatients handles GET /fhir/Patient
+func (h *FHIRHandler) SearchPatients(w http.ResponseWriter, r *http.Request) {
+	h.search(w, r, h.patients())
+}
+
+// ReadPatient handles GET /fhir/Patient/{id}
+func (h *FHIRHandler) ReadPatient(w http.ResponseWriter, r *http.Request) {
+	h.read(w, r, h.patients())
+}
+
+// SearchServiceRequests handles GET /fhir/ServiceRequest
+func (h *FHIRHandler) SearchServiceRequests(w http.ResponseWriter, r *http.Request) {
+	h.search(w, r, h.serviceRequests())
+}
+
+// ReadServiceRequest handles GET /fhir/ServiceRequest/{id}
+func (h *FHIRHandler) ReadServiceRequest(w http.ResponseWriter, r *http.Request) {
+	h.read(w, r, h.serviceRequests())
+}
+
+// SearchDiagnosticReports handles GET /fhir/DiagnosticReport
+func (h *FHIRHandler) SearchDiagnosticReports(w http.ResponseWriter, r *http.Request) {
+	h.search(w, r, h.diagnosticReports())
+}
+
+// ReadDiagnosticReport handles GET /fhir/DiagnosticReport/{id}
+func (h *FHIRHandler) ReadDiagnosticReport(w http.ResponseWriter, r *http.Request) {
+	h.read(w, r, h.diagnosticReports())
+}
+
+// SearchObservations handles GET /fhir/Observation
+func (h *FHIRHandler) SearchObservations(w http.ResponseWriter, r *http.Request) {
+	h.search(w, r, h.observations())
+}
+
+// ReadObservation handles GET /fhir/Observation/{id}
+func (h *FHIRHandler) ReadObservation(w http.ResponseWriter, r *http.Request) {
+	h.read(w, r, h.observations())
+}
+
+// search runs a search and responds with a searchset Bundle
+func (h *FHIRHandler) search(w http.ResponseWriter, r *http.Request, resource fhirResource) {
+	contentType, ok := negotiateFHIR(w, r)
+	if !ok {
+		return
+	}
+
+	search, err := fhir.ParseSearch(resource.resourceType, r.URL.Query())
+	if err != nil {
+		writeOutcome(w, contentType, http.StatusBadRequest, fhir.IssueInvalid, err.Error())
+		return
+	}
+
+	entries, total, err := resource.find(r.Context(), search)
+	if err != nil {
+		writeInternalOutcome(w, r, contentType, "Failed to search "+resource.resourceType, err)
+		return
+	}
+
+	ids := make([]string, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.auditID
+	}
+	if !h.auditRead(w, r, contentType, audit.Event{
+		Action:       resource.auditType + ".list",
+		ResourceType: resource.auditType,
+		Details:      map[string]interface{}{"api": "fhir", "query": r.URL.Query(), "ids": ids},
+	}) {
+		return
+	}
+
+	base := fhirBase(r)
+	bundle := fhir.NewSearchSet(fhirRequestURL(r), search, total, time.Now())
+	for _, entry := range entries {
+		bundle.AddMatch(base+"/"+resource.resourceType+"/"+entry.id, entry.resource)
+	}
+
+	writeFHIR(w, contentType, http.StatusOK, bundle)
+}
+
+// read responds with the resource named by the last path segment
+func (h *FHIRHandler) read(w http.ResponseWriter, r *http.Request, resource fhirResource) {
+	contentType, ok := negotiateFHIR(w, r)
+	if !ok {
+		return
+	}
+
+	id := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+
+	entries, _, err := resource.find(r.Context(), &fhir.Search{IDs: []string{id}, Count: 1})
+	if err != nil {
+		writeInternalOutcome(w, r, contentType, "Failed to read "+resource.resourceType, err)
+		return
+	}
+	if len(entries) == 0 {
+		writeOutcome(w, contentType, http.StatusNotFound, fhir.IssueNotFound,
+			fmt.Sprintf("%s/%s not found", resource.resourceType, id))
+		return
+	}
+
+	if !h.auditRead(w, r, contentType, audit.Event{
+		Action:       resource.auditType + ".read",
+		ResourceType: resource.auditType,
+		ResourceID:   entries[0].auditID,
+		Details:      map[string]string{"api": "fhir", "resource": resource.resourceType + "/" + id},
+	}) {
+		return
+	}
+
+	writeFHIR(w, contentType, http.StatusOK, entries[0].resource)
+}
+
+// auditRead is auditRead with an OperationOutcome as the error response
+func (h *FHIRHandler) auditRead(w http.ResponseWriter, r *http.Request, contentType string, event audit.Event) bool {
+	if err := h.auditLog.Record(r.Context(), event); err != nil {
+		logging.FromContext(r.Context()).Error("Failed to record audit event", "action", event.Action,
+			"resource_type", event.ResourceType, "resource_id", event.ResourceID, "error", err)
+		writeOutcome(w, contentType, http.StatusInternalServerError, fhir.IssueException, "Failed to record access")
+		return false
+	}
+	return true
+}
+
+// patients searches the patient registry
+func (h *FHIRHandler) patients() fhirResource {
+	return fhirResource{resourceType: "Patient", auditType: "patient", find: func(ctx context.Context, search *fhir.Search) ([]fhirEntry, int, error) {
+		ids, ok := numericIDs(search.IDs)
+		if !ok {
+			return nil, 0, nil
+		}
+
+		patients, total, err := h.patientRepo.Search(ctx, models.PatientSearch{
+			IDs:              ids,
+			IdentifierSystem: search.IdentifierSystem,
+			IdentifierValue:  search.IdentifierValue,
+			Limit:            search.Count,
+			Offset:           search.Offset,
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+
+		entries := make([]fhirEntry, len(patients))
+		for i := range patients {
+			id := strconv.Itoa(patients[i].ID)
+			entries[i] = fhirEntry{id: id, auditID: id, resource: fhir.NewPatient(&patients[i])}
+		}
+		return entries, total, nil
+	}}
+}
+
+// serviceRequests searches test orders
+func (h *FHIRHandler) serviceRequests() fhirResource {
+	return fhirResource{resourceType: "ServiceRequest", auditType: "test_order", find: func(ctx context.Context, search *fhir.Search) ([]fhirEntry, int, error) {
+		ids, ok := numericIDs(search.IDs)
+		if !ok {
+			return nil, 0, nil
+		}
+
+		var statuses []models.TestOrderStatus
+		for _, status := range search.Statuses {
+			statuses = append(statuses, fhir.OrderStatuses(status)...)
+		}
+
+		orders, total, err := h.orderRepo.Search(ctx, models.TestOrderSearch{
+			IDs:       ids,
+			PatientID: search.PatientID,
+			Statuses:  statuses,
+			From:      search.From,
+			To:        search.To,
+			Limit:     search.Count,
+			Offset:    search.Offset,
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+
+		entries := make([]fhirEntry, len(orders))
+		for i := range orders {
+			id := strconv.Itoa(orders[i].ID)
+			entries[i] = fhirEntry{id: id, auditID: id, resource: fhir.NewServiceRequest(&orders[i])}
+		}
+		return entries, total, nil
+	}}
+}
+
+// diagnosticReports searches reported results
+func (h *FHIRHandler) diagnosticReports() fhirResource {
+	return fhirResource{resourceType: "DiagnosticReport", auditType: "test_result", find: func(ctx context.Context, search *fhir.Search) ([]fhirEntry, int, error) {
+		ids, ok := numericIDs(search.IDs)
+		if !ok {
+			return nil, 0, nil
+		}
+
+		results, total, err := h.resultRepo.SearchReported(ctx, resultSearch(search, ids, nil))
+		if err != nil {
+			return nil, 0, err
+		}
+
+		orders, err := h.resultOrders(ctx, results)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		entries := make([]fhirEntry, 0, len(results))
+		for i := range results {
+			order := orders[results[i].TestOrderID]
+			if order == nil {
+				continue
+			}
+			entries = append(entries, fhirEntry{
+				id:       strconv.Itoa(order.ID),
+				auditID:  order.AccessionNumber,
+				resource: fhir.NewDiagnosticReport(order, &results[i]),
+			})
+		}
+		return entries, total, nil
+	}}
+}
+
+// observations searches the findings of reported results
+func (h *FHIRHandler) observations() fhirResource {
+	return fhirResource{resourceType: "Observation", auditType: "test_result", find: func(ctx context.Context, search *fhir.Search) ([]fhirEntry, int, error) {
+		findings, total, err := h.resultRepo.SearchFindings(ctx, resultSearch(search, nil, search.IDs))
+		if err != nil {
+			return nil, 0, err
+		}
+
+		results := make([]models.TestResult, len(findings))
+		for i := range findings {
+			results[i] = findings[i].Result
+		}
+		orders, err := h.resultOrders(ctx, results)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		entries := make([]fhirEntry, 0, len(findings))
+		for i := range findings {
+			order := orders[findings[i].Result.TestOrderID]
+			if order == nil {
+				continue
+			}
+			entries = append(entries, fhirEntry{
+				id:       fhir.ObservationID(order.ID, findings[i].Number),
+				auditID:  order.AccessionNumber,
+				resource: fhir.NewObservation(order, &findings[i].Result, findings[i].Number),
+			})
+		}
+		return entries, total, nil
+	}}
+}
+
+// resultOrders loads the test orders of the given results
+func (h *FHIRHandler) resultOrders(ctx context.Context, results []models.TestResult) (map[int]*models.TestOrder, error) {
+	if len(results) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]int, len(results))
+	for i, result := range results {
+		ids[i] = result.TestOrderID
+	}
+	return h.orderRepo.GetByIDs(ctx, ids)
+}
+
+// resultSearch converts a search on reported results
+func resultSearch(search *fhir.Search, orderIDs []int, findingIDs []string) models.ResultSearch {
+	var amended *bool
+	if len(search.Statuses) == 1 {
+		value := search.Statuses[0] == fhir.ReportAmended
+		amended = &value
+	}
+
+	return models.ResultSearch{
+		TestOrderIDs: orderIDs,
+		FindingIDs:   findingIDs,
+		PatientID:    search.PatientID,
+		Amended:      amended,
+		From:         search.From,
+		To:           search.To,
+		Limit:        search.Count,
+		Offset:       search.Offset,
+	}
+}
+
+// numericIDs converts resource IDs that are record IDs. ok is false when
+// IDs were given but none can exist, so the search cannot match.
+func numericIDs(ids []string) ([]int, bool) {
+	var converted []int
+	for _, id := range ids {
+		if n, err := strconv.Atoi(id); err == nil && n > 0 {
+			converted = append(converted, n)
+		}
+	}
+	return converted, len(ids) == 0 || len(converted) > 0
+}
+
+// fhirBase returns the absolute URL of the FHIR API
+func fhirBase(r *http.Request) string {
+	return baseURL(r) + "/fhir"
+}
+
+// fhirRequestURL returns the absolute URL of the request
+func fhirRequestURL(r *http.Request) *url.URL {
+	requestURL := *r.URL
+	base, _ := url.Parse(baseURL(r))
+	requestURL.Scheme, requestURL.Host = base.Scheme, base.Host
+	return &requestURL
+}
+
+// negotiateFHIR picks the response media type from _format or the Accept
+// header: application/fhir+json unless the client only accepts
+// application/json. If the client accepts neither it responds 406 and
+// returns false.
+func negotiateFHIR(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if format := r.URL.Query().Get("_format"); format != "" {
+		switch format {
+		case "json", fhir.MediaType:
+			return fhir.MediaType, true
+		case "application/json":
+			return "application/json", true
+		}
+		writeOutcome(w, fhir.MediaType, http.StatusNotAcceptable, fhir.IssueNotSupported,
+			fmt.Sprintf("Unsupported _format %q; only JSON is supported", format))
+		return "", false
+	}
+
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return fhir.MediaType, true
+	}
+
+	jsonOnly := false
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+		if err != nil || params["q"] == "0" {
+			continue
+		}
+		switch mediaType {
+		case fhir.MediaType, "application/*", "*/*":
+			return fhir.MediaType, true
+		case "application/json":
+			jsonOnly = true
+		}
+	}
+	if jsonOnly {
+		return "application/json", true
+	}
+
+	writeOutcome(w, fhir.MediaType, http.StatusNotAcceptable, fhir.IssueNotSupported,
+		"Only application/fhir+json and application/json are supported")
+	return "", false
+}
+
+// writeFHIR writes a FHIR resource
+func writeFHIR(w http.ResponseWriter, contentType string, status int, resource interface{}) {
+	w.Header().Set("Content-Type", contentType+"; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resource)
+}
+
+// writeOutcome writes an OperationOutcome reporting an error
+func writeOutcome(w http.ResponseWriter, contentType string, status int, code, diagnostics string) {
+	writeFHIR(w, contentType, status, fhir.NewOperationOutcome(code, diagnostics))
+}
+
+// writeInternalOutcome logs err and responds 500 with only diagnostics, as
+// problem.Internal does, so driver errors never reach clients
+func writeInternalOutcome(w http.ResponseWriter, r *http.Request, contentType, diagnostics string, err error) {
+	logging.FromContext(r.Context()).Error(diagnostics, "error", err, "method", r.Method, "path", r.URL.Path)
+	writeOutcome(w, contentType, http.StatusInternalServerError, fhir.IssueException, diagnostics)
+}
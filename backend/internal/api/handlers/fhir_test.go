@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"backend/internal/fhir"
+)
+
+func TestFHIRHandler_Metadata(t *testing.T) {
+	handler := NewFHIRHandler(nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/fhir/metadata", nil)
+	req.Host = "lab.example.org"
+	w := httptest.NewRecorder()
+
+	handler.Metadata(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if contentType := w.Header().Get("Content-Type"); !strings.HasPrefix(contentType, fhir.MediaType) {
+		t.Errorf("Expected %s, got %s", fhir.MediaType, contentType)
+	}
+
+	var statement fhir.CapabilityStatement
+	if err := json.NewDecoder(w.Body).Decode(&statement); err != nil {
+		t.Fatalf("Failed to decode CapabilityStatement: %v", err)
+	}
+	if statement.FHIRVersion != fhir.Version || statement.Implementation.URL != "http://lab.example.org/fhir" {
+		t.Errorf("Unexpected CapabilityStatement %+v", statement)
+	}
+	if len(statement.Rest) != 1 || len(statement.Rest[0].Resource) != len(fhir.ResourceTypes) {
+		t.Errorf("Expected every resource type to be described, got %+v", statement.Rest)
+	}
+}
+
+func TestFHIRHandler_ContentNegotiation(t *testing.T) {
+	handler := NewFHIRHandler(nil)
+
+	tests := []struct {
+		accept   string
+		format   string
+		status   int
+		expected string
+	}{
+		{"", "", http.StatusOK, fhir.MediaType},
+		{"application/fhir+json", "", http.StatusOK, fhir.MediaType},
+		{"application/json", "", http.StatusOK, "application/json"},
+		{"application/json, application/fhir+json;q=0.9", "", http.StatusOK, fhir.MediaType},
+		{"*/*", "", http.StatusOK, fhir.MediaType},
+		{"application/fhir+xml", "", http.StatusNotAcceptable, fhir.MediaType},
+		{"application/fhir+xml", "json", http.StatusOK, fhir.MediaType},
+		{"", "xml", http.StatusNotAcceptable, fhir.MediaType},
+	}
+
+	for _, tt := range tests {
+		target := "/fhir/metadata"
+		if tt.format != "" {
+			target += "?_format=" + tt.format
+		}
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		w := httptest.NewRecorder()
+
+		handler.Metadata(w, req)
+
+		if w.Code != tt.status {
+			t.Errorf("Accept %q, _format %q: expected status %d, got %d", tt.accept, tt.format, tt.status, w.Code)
+		}
+		if contentType := w.Header().Get("Content-Type"); !strings.HasPrefix(contentType, tt.expected+";") {
+			t.Errorf("Accept %q, _format %q: expected %s, got %s", tt.accept, tt.format, tt.expected, contentType)
+		}
+	}
+}
+
+func TestFHIRHandler_Search_InvalidParameters(t *testing.T) {
+	handler := NewFHIRHandler(nil)
+
+	tests := []struct {
+		target string
+		search http.HandlerFunc
+	}{
+		{"/fhir/Patient?name=smith", handler.SearchPatients},
+		{"/fhir/ServiceRequest?status=final", handler.SearchServiceRequests},
+		{"/fhir/DiagnosticReport?date=yesterday", handler.SearchDiagnosticReports},
+		{"/fhir/Observation?_count=1000", handler.SearchObservations},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+		w := httptest.NewRecorder()
+
+		tt.search(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", tt.target, http.StatusBadRequest, w.Code)
+			continue
+		}
+
+		var outcome fhir.OperationOutcome
+		if err := json.NewDecoder(w.Body).Decode(&outcome); err != nil || outcome.ResourceType != "OperationOutcome" ||
+			outcome.Issue[0].Code != fhir.IssueInvalid {
+			t.Errorf("%s: expected an OperationOutcome, got %+v (%v)", tt.target, outcome, err)
+		}
+	}
+}
+
+func TestNumericIDs(t *testing.T) {
+	if ids, ok := numericIDs(nil); ids != nil || !ok {
+		t.Errorf("Expected no IDs to match everything, got %v %v", ids, ok)
+	}
+	if ids, ok := numericIDs([]string{"12", "abc", "0"}); len(ids) != 1 || ids[0] != 12 || !ok {
+		t.Errorf("Expected only 12, got %v %v", ids, ok)
+	}
+	if _, ok := numericIDs([]string{"abc"}); ok {
+		t.Errorf("Expected IDs that cannot exist to match nothing")
+	}
+}
+
+func TestFHIRHandler_InternalErrorsHidden(t *testing.T) {
+	handler := NewFHIRHandler(nil)
+	failing := fhirResource{
+		resourceType: "Patient",
+		auditType:    "patient",
+		find: func(context.Context, *fhir.Search) ([]fhirEntry, int, error) {
+			return nil, 0, errors.New(`pq: relation "patients" does not exist`)
+		},
+	}
+
+	for _, serve := range []func(http.ResponseWriter, *http.Request, fhirResource){handler.search, handler.read} {
+		w := httptest.NewRecorder()
+		serve(w, httptest.NewRequest(http.MethodGet, "/fhir/Patient/1", nil), failing)
+
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, w.Code)
+		}
+		if body := w.Body.String(); strings.Contains(body, "pq:") || !strings.Contains(body, "Failed to") {
+			t.Errorf("Expected a fixed message without the driver error, got %s", body)
+		}
+	}
+}
@@ -1,71 +1,118 @@
 package handlers
 
 import (
-	"database/sql"
 	"encoding/json"
 	"net/http"
+	"time"
 
-	"backend/internal/config"
+	"backend/internal/auth"
+	"backend/internal/health"
+	"backend/internal/version"
 )
 
-// HealthHandler handles health check endpoints
+// HealthHandler serves the liveness, readiness and startup probes
 type HealthHandler struct {
-	db  *sql.DB
-	cfg *config.Config
+	checks   *health.Registry
+	internal bool
 }
 
-// NewHealthHandler creates a new health handler
-func NewHealthHandler(db *sql.DB) *HealthHandler {
-	cfg, _ := config.Load()
-	return &HealthHandler{
-		db:  db,
-		cfg: cfg,
-	}
+// NewHealthHandler creates a health handler reporting checks. Verbose
+// reports name each check and its error, so on the public API they are only
+// given to callers holding metrics:read; internal handlers, served on the
+// internal metrics port, give them to anyone.
+func NewHealthHandler(checks *health.Registry, internal bool) *HealthHandler {
+	return &HealthHandler{checks: checks, internal: internal}
 }
 
-// HealthResponse represents the health check response
+// HealthResponse represents the legacy health check response
 type HealthResponse struct {
 	Status   string `json:"status"`
 	Database string `json:"database"`
 	Version  string `json:"version"`
 }
 
-// ConfigResponse represents the configuration debug response
-type ConfigResponse struct {
-	Port        string `json:"port"`
-	Environment string `json:"environment"`
-	FrontendURL string `json:"frontend_url"`
-	LogLevel    string `json:"log_level"`
+// ProbeResponse is a probe's outcome. Checks, the version and the commit are
+// only included in verbose reports.
+type ProbeResponse struct {
+	Status  health.Status   `json:"status"`
+	Version string          `json:"version,omitempty"`
+	Commit  string          `json:"commit,omitempty"`
+	Checks  []CheckResponse `json:"checks,omitempty"`
 }
 
-// Check performs a health check
-func (h *HealthHandler) Check(w http.ResponseWriter, r *http.Request) {
-	response := HealthResponse{
-		Status:  "ok",
-		Version: "1.0.0",
-	}
+// CheckResponse is one check's outcome in a verbose report
+type CheckResponse struct {
+	health.Result
+	DurationMS float64 `json:"duration_ms"`
+}
 
-	// Check database connection
-	if err := h.db.Ping(); err != nil {
-		response.Database = "error: " + err.Error()
-		w.WriteHeader(http.StatusServiceUnavailable)
-	} else {
-		response.Database = "ok"
+// Livez reports whether the process should keep running
+func (h *HealthHandler) Livez(w http.ResponseWriter, r *http.Request) {
+	h.probe(w, r, health.Live)
+}
+
+// Readyz reports whether the instance should receive traffic
+func (h *HealthHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	h.probe(w, r, health.Ready)
+}
+
+// Startupz reports whether the instance has finished starting
+func (h *HealthHandler) Startupz(w http.ResponseWriter, r *http.Request) {
+	h.probe(w, r, health.Startup)
+}
+
+// probe runs a probe's checks, answering 503 if a required check failed.
+// Adding ?verbose lists each check for callers allowed to see them.
+func (h *HealthHandler) probe(w http.ResponseWriter, r *http.Request, probe health.Probe) {
+	report := h.checks.Run(r.Context(), probe)
+
+	response := ProbeResponse{Status: report.Status}
+	if r.URL.Query().Has("verbose") && h.verboseAllowed(r) {
+		response.Version = version.Version
+		response.Commit = version.Commit
+		response.Checks = make([]CheckResponse, len(report.Checks))
+		for i, result := range report.Checks {
+			response.Checks[i] = CheckResponse{
+				Result:     result,
+				DurationMS: float64(result.Duration) / float64(time.Millisecond),
+			}
+		}
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	writeProbe(w, report.Status, response)
+}
+
+// verboseAllowed reports whether the caller may see individual checks
+func (h *HealthHandler) verboseAllowed(r *http.Request) bool {
+	return h.internal || auth.HasPermission(r.Context(), auth.PermissionMetricsRead)
 }
 
-// Config returns the current configuration for debugging
-func (h *HealthHandler) Config(w http.ResponseWriter, r *http.Request) {
-	response := ConfigResponse{
-		Port:        h.cfg.Port,
-		Environment: h.cfg.Environment,
-		FrontendURL: h.cfg.FrontendURL,
-		LogLevel:    h.cfg.LogLevel,
+// Check reports readiness in the original /health format, without error
+// details
+func (h *HealthHandler) Check(w http.ResponseWriter, r *http.Request) {
+	report := h.checks.Run(r.Context(), health.Ready)
+
+	response := HealthResponse{
+		Status:   string(report.Status),
+		Database: string(health.StatusOK),
+		Version:  version.Version,
+	}
+	for _, result := range report.Checks {
+		if result.Name == "database" {
+			response.Database = string(result.Status)
+		}
 	}
 
+	writeProbe(w, report.Status, response)
+}
+
+// writeProbe writes a probe response, failing with 503 so orchestrators
+// take the instance out of rotation
+func writeProbe(w http.ResponseWriter, status health.Status, response interface{}) {
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if status == health.StatusFailed {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
 	json.NewEncoder(w).Encode(response)
 }
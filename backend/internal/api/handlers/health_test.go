@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"backend/internal/auth"
+	"backend/internal/health"
+)
+
+func newTestChecks(dbErr error) *health.Registry {
+	checks := health.NewRegistry()
+	checks.Register(health.Check{Name: "database", Probes: []health.Probe{health.Ready, health.Startup}, Run: func(context.Context) error {
+		return dbErr
+	}})
+	return checks
+}
+
+func TestHealthHandler_Readyz(t *testing.T) {
+	handler := NewHealthHandler(newTestChecks(errors.New(`dial tcp 10.0.4.7:5432: connection refused`)), false)
+
+	w := httptest.NewRecorder()
+	handler.Readyz(w, httptest.NewRequest(http.MethodGet, "/readyz?verbose", nil))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "10.0.4.7") || strings.Contains(w.Body.String(), "database") {
+		t.Errorf("Expected no check details for anonymous callers, got %s", w.Body.String())
+	}
+}
+
+func TestHealthHandler_Verbose(t *testing.T) {
+	checks := newTestChecks(errors.New("connection refused"))
+	ctx := auth.WithAccess(context.Background(), auth.NewAccess([]string{"monitoring"}, []string{"metrics:read"}))
+
+	tests := []struct {
+		name    string
+		handler *HealthHandler
+		ctx     context.Context
+	}{
+		{"metrics:read", NewHealthHandler(checks, false), ctx},
+		{"internal port", NewHealthHandler(checks, true), context.Background()},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		tt.handler.Startupz(w, httptest.NewRequest(http.MethodGet, "/startupz?verbose", nil).WithContext(tt.ctx))
+
+		var response ProbeResponse
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("%s: invalid JSON: %v", tt.name, err)
+		}
+		if response.Status != health.StatusFailed || response.Version == "" || len(response.Checks) != 1 {
+			t.Errorf("%s: expected a verbose report, got %+v", tt.name, response)
+			continue
+		}
+		if check := response.Checks[0]; check.Name != "database" || check.Error != "connection refused" {
+			t.Errorf("%s: unexpected check %+v", tt.name, check)
+		}
+	}
+}
+
+func TestHealthHandler_Check(t *testing.T) {
+	handler := NewHealthHandler(newTestChecks(nil), false)
+
+	w := httptest.NewRecorder()
+	handler.Check(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	var response HealthResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if w.Code != http.StatusOK || response.Status != "ok" || response.Database != "ok" || response.Version != "dev" {
+		t.Errorf("Unexpected health response %d %+v", w.Code, response)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"net/http"
 	"time"
+
+	"backend/internal/api/problem"
 )
 
 type HelloResponse struct {
@@ -25,9 +27,9 @@ func NewHelloHandler(db *sql.DB) *HelloHandler {
 func (h *HelloHandler) GetHello(w http.ResponseWriter, r *http.Request) {
 	// Get current timestamp from database to prove connectivity
 	var dbTimestamp time.Time
-	err := h.db.QueryRow("SELECT NOW()").Scan(&dbTimestamp)
+	err := h.db.QueryRowContext(r.Context(), "SELECT NOW()").Scan(&dbTimestamp)
 	if err != nil {
-		http.Error(w, "Database connection failed", http.StatusInternalServerError)
+		problem.Internal(w, r, "Database connection failed", err)
 		return
 	}
 
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"backend/internal/api/problem"
+	"backend/internal/audit"
+	"backend/internal/listing"
+	"backend/internal/models"
+)
+
+// InferenceHandler handles HTTP requests for AlphaPath inference jobs
+type InferenceHandler struct {
+	jobRepo     *models.InferenceJobRepository
+	orderRepo   *models.TestOrderRepository
+	maxAttempts int
+	auditLog    *audit.Logger
+}
+
+// NewInferenceHandler creates a new inference handler. Jobs it enqueues are
+// attempted up to maxAttempts times.
+func NewInferenceHandler(db *sql.DB, maxAttempts int) *InferenceHandler {
+	return &InferenceHandler{
+		jobRepo:     models.NewInferenceJobRepository(db),
+		orderRepo:   models.NewTestOrderRepository(db),
+		maxAttempts: maxAttempts,
+		auditLog:    newAuditLogger(db),
+	}
+}
+
+// CreateJobRequest represents the body of POST /api/inference/jobs
+type CreateJobRequest struct {
+	TestOrderID  *int            `json:"test_order_id"`
+	Model        string          `json:"model"`
+	ModelVersion string          `json:"model_version"`
+	Input        json.RawMessage `json:"input"`
+}
+
+// GetJobs handles GET /api/inference/jobs. It returns one page of jobs; see
+// models.InferenceJobList for the accepted sort fields and filters.
+func (h *InferenceHandler) GetJobs(w http.ResponseWriter, r *http.Request) {
+	if status := models.InferenceJobStatus(r.URL.Query().Get("status")); status != "" && !status.Valid() {
+		problem.Error(w, r, http.StatusBadRequest, "Invalid status filter")
+		return
+	}
+
+	q, err := models.InferenceJobList.Parse(r.URL.Query())
+	if err != nil {
+		problem.Invalid(w, r, err)
+		return
+	}
+
+	jobs, total, err := h.jobRepo.List(r.Context(), q)
+	if err != nil {
+		problem.Internal(w, r, "Failed to get inference jobs", err)
+		return
+	}
+
+	ids := make([]int, len(jobs))
+	for i, job := range jobs {
+		ids[i] = job.ID
+	}
+	if !auditRead(w, r, h.auditLog, audit.Event{
+		Action:       "inference_job.list",
+		ResourceType: "inference_job",
+		Details:      map[string]interface{}{"query": r.URL.Query(), "ids": ids},
+	}) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(listing.NewPage(jobs, q, models.InferenceJob.ListKey).WithTotal(total))
+}
+
+// GetJob handles GET /api/inference/jobs/{id}
+func (h *InferenceHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/inference/jobs/"))
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, "Invalid job ID")
+		return
+	}
+
+	job, err := h.jobRepo.GetByID(r.Context(), id)
+	if err != nil {
+		problem.Internal(w, r, "Failed to get inference job", err)
+		return
+	}
+
+	if job == nil {
+		problem.Error(w, r, http.StatusNotFound, "Inference job not found")
+		return
+	}
+
+	if !auditRead(w, r, h.auditLog, audit.Event{
+		Action:       "inference_job.read",
+		ResourceType: "inference_job",
+		ResourceID:   strconv.Itoa(job.ID),
+	}) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// CreateJob handles POST /api/inference/jobs. The job is queued and runs
+// asynchronously; poll GET /api/inference/jobs/{id} for the outcome.
+func (h *InferenceHandler) CreateJob(w http.ResponseWriter, r *http.Request) {
+	var req CreateJobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		problem.InvalidJSON(w, r)
+		return
+	}
+
+	if err := validateCreateJob(&req); err != nil {
+		problem.Invalid(w, r, err)
+		return
+	}
+
+	if req.TestOrderID != nil {
+		order, err := h.orderRepo.GetByID(r.Context(), *req.TestOrderID)
+		if err != nil {
+			problem.Internal(w, r, "Failed to get test order", err)
+			return
+		}
+		if order == nil {
+			problem.Error(w, r, http.StatusBadRequest, "test_order_id does not exist")
+			return
+		}
+	}
+
+	job := models.InferenceJob{
+		TestOrderID:  req.TestOrderID,
+		Model:        req.Model,
+		ModelVersion: req.ModelVersion,
+		Input:        req.Input,
+		MaxAttempts:  h.maxAttempts,
+		CreatedBy:    callerID(r),
+	}
+
+	if err := h.jobRepo.Create(r.Context(), &job); err != nil {
+		problem.Internal(w, r, "Failed to create inference job", err)
+		return
+	}
+
+	// The input can be large, so only the job's identity is recorded
+	recordAudit(r, h.auditLog, audit.Event{
+		Action:       "inference_job.create",
+		ResourceType: "inference_job",
+		ResourceID:   strconv.Itoa(job.ID),
+		Details:      map[string]interface{}{"test_order_id": job.TestOrderID, "model": job.Model, "model_version": job.ModelVersion},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", fmt.Sprintf("/api/inference/jobs/%d", job.ID))
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// validateCreateJob checks the model name and input
+func validateCreateJob(req *CreateJobRequest) error {
+	req.Model = strings.TrimSpace(req.Model)
+	req.ModelVersion = strings.TrimSpace(req.ModelVersion)
+
+	if req.Model == "" {
+		return problem.Field("model", problem.FieldRequired, "model is required")
+	}
+
+	if len(req.Input) == 0 || string(req.Input) == "null" {
+		return problem.Field("input", problem.FieldRequired, "input is required")
+	}
+
+	return nil
+}
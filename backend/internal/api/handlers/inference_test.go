@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestInferenceHandler_CreateJob_InvalidJSON(t *testing.T) {
+	handler := NewInferenceHandler(nil, 3)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/inference/jobs", bytes.NewBufferString("invalid json"))
+	w := httptest.NewRecorder()
+
+	handler.CreateJob(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestInferenceHandler_CreateJob_MissingInput(t *testing.T) {
+	handler := NewInferenceHandler(nil, 3)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/inference/jobs", bytes.NewBufferString(`{"model":"alphapath"}`))
+	w := httptest.NewRecorder()
+
+	handler.CreateJob(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestInferenceHandler_GetJob_InvalidID(t *testing.T) {
+	handler := NewInferenceHandler(nil, 3)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/inference/jobs/abc", nil)
+	w := httptest.NewRecorder()
+
+	handler.GetJob(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestInferenceHandler_GetJobs_InvalidQuery(t *testing.T) {
+	handler := NewInferenceHandler(nil, 3)
+
+	for _, query := range []string{"status=done", "sort=created_at", "test_order_id=abc", "limit=1000"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/inference/jobs?"+query, nil)
+		w := httptest.NewRecorder()
+
+		handler.GetJobs(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", query, http.StatusBadRequest, w.Code)
+		}
+	}
+}
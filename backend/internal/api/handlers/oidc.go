@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"backend/internal/api/problem"
+	"backend/internal/audit"
+	"backend/internal/auth"
+	"backend/internal/logging"
+)
+
+// OIDCHandler handles single sign-on through the institutional identity provider
+type OIDCHandler struct {
+	client      *auth.OIDCClient
+	sessions    *auth.Manager
+	frontendURL func() string
+	auditLog    *audit.Logger
+}
+
+// NewOIDCHandler creates a new OIDC handler. After sign-in the browser is
+// sent back to the frontend, whose URL is read per request so a
+// configuration reload takes effect.
+func NewOIDCHandler(db *sql.DB, client *auth.OIDCClient, sessions *auth.Manager, frontendURL func() string) *OIDCHandler {
+	return &OIDCHandler{
+		client:      client,
+		sessions:    sessions,
+		frontendURL: func() string { return strings.TrimSuffix(frontendURL(), "/") },
+		auditLog:    newAuditLogger(db),
+	}
+}
+
+// Login handles GET /api/auth/oidc/login
+func (h *OIDCHandler) Login(w http.ResponseWriter, r *http.Request) {
+	returnTo := safeReturnPath(r.URL.Query().Get("return_to"))
+
+	redirectURL, err := h.client.BeginLogin(r.Context(), w, returnTo)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Failed to start OIDC login", "error", err)
+		problem.Error(w, r, http.StatusBadGateway, "Single sign-on is unavailable")
+		return
+	}
+
+	http.Redirect(w, r, redirectURL, http.StatusFound)
+}
+
+// Callback handles GET /api/auth/oidc/callback
+func (h *OIDCHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	user, returnTo, err := h.client.CompleteLogin(r.Context(), w, r)
+	if err != nil {
+		logging.FromContext(r.Context()).Warn("OIDC login failed", "error", err)
+		http.Redirect(w, r, h.frontendURL()+"/?auth_error=sso_failed", http.StatusFound)
+		return
+	}
+
+	if _, err := h.sessions.StartSession(w, r, user); err != nil {
+		logging.FromContext(r.Context()).Error("Failed to start session", "error", err)
+		http.Redirect(w, r, h.frontendURL()+"/?auth_error=sso_failed", http.StatusFound)
+		return
+	}
+
+	recordAudit(r.WithContext(auth.WithUser(r.Context(), user)), h.auditLog, audit.Event{
+		Action:       "auth.login",
+		ResourceType: "user",
+		ResourceID:   strconv.Itoa(user.ID),
+		Details:      map[string]string{"method": "oidc"},
+	})
+
+	http.Redirect(w, r, h.frontendURL()+safeReturnPath(returnTo), http.StatusFound)
+}
+
+// safeReturnPath only allows local absolute paths so the login flow cannot be
+// used as an open redirect
+func safeReturnPath(path string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.Contains(path, "\\") {
+		return "/"
+	}
+
+	parsed, err := url.Parse(path)
+	if err != nil || parsed.Host != "" || parsed.Scheme != "" {
+		return "/"
+	}
+
+	return path
+}
@@ -0,0 +1,22 @@
+package handlers
+
+import "testing"
+
+func TestSafeReturnPath(t *testing.T) {
+	tests := map[string]string{
+		"":                     "/",
+		"/tests":               "/tests",
+		"/tests?id=4":          "/tests?id=4",
+		"https://evil.example": "/",
+		"//evil.example/path":  "/",
+		"/\\evil.example":      "/",
+		"relative/path":        "/",
+		"javascript:alert(1)":  "/",
+	}
+
+	for input, expected := range tests {
+		if got := safeReturnPath(input); got != expected {
+			t.Errorf("safeReturnPath(%q) = %q, expected %q", input, got, expected)
+		}
+	}
+}
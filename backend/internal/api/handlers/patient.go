@@ -0,0 +1,497 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/api/problem"
+	"backend/internal/audit"
+	"backend/internal/listing"
+	"backend/internal/models"
+)
+
+// duplicateCandidates is the number of candidates scored per duplicate check
+const duplicateCandidates = 50
+
+// Problem codes for patient registry conflicts
+const (
+	codePossibleDuplicate = "possible_duplicate"
+	codeIdentifierExists  = "identifier_exists"
+	codePatientMerged     = "patient_merged"
+	codeMergeReversed     = "merge_reversed"
+)
+
+// PatientHandler handles HTTP requests for the patient registry
+type PatientHandler struct {
+	db          *sql.DB
+	patientRepo *models.PatientRepository
+	auditLog    *audit.Logger
+}
+
+// NewPatientHandler creates a new patient handler
+func NewPatientHandler(db *sql.DB) *PatientHandler {
+	return &PatientHandler{
+		db:          db,
+		patientRepo: models.NewPatientRepository(db),
+		auditLog:    newAuditLogger(db),
+	}
+}
+
+// MergeRequest represents the body of POST /api/patients/{id}/merges
+type MergeRequest struct {
+	PatientID int    `json:"patient_id"`
+	Reason    string `json:"reason"`
+}
+
+// GetPatients handles GET /api/patients. Merged records are not listed.
+func (h *PatientHandler) GetPatients(w http.ResponseWriter, r *http.Request) {
+	q, err := models.PatientList.Parse(r.URL.Query())
+	if err != nil {
+		problem.Invalid(w, r, err)
+		return
+	}
+
+	patients, total, err := h.patientRepo.List(r.Context(), q)
+	if err != nil {
+		problem.Internal(w, r, "Failed to get patients", err)
+		return
+	}
+
+	ids := make([]int, len(patients))
+	for i, patient := range patients {
+		ids[i] = patient.ID
+	}
+	if !auditRead(w, r, h.auditLog, audit.Event{
+		Action:       "patient.list",
+		ResourceType: "patient",
+		Details:      map[string]interface{}{"query": r.URL.Query(), "ids": ids},
+	}) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(listing.NewPage(patients, q, models.Patient.ListKey).WithTotal(total))
+}
+
+// GetPatient handles GET /api/patients/{id}. A merged record is still
+// returned, with merged_into_id set.
+func (h *PatientHandler) GetPatient(w http.ResponseWriter, r *http.Request) {
+	patient, ok := h.loadPatient(w, r)
+	if !ok {
+		return
+	}
+
+	if !auditRead(w, r, h.auditLog, audit.Event{
+		Action:       "patient.read",
+		ResourceType: "patient",
+		ResourceID:   strconv.Itoa(patient.ID),
+	}) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(patient)
+}
+
+// CreatePatient handles POST /api/patients. If the patient looks like one
+// already registered, it responds 409 with the likely matches instead;
+// resend with ?allow_duplicate=true to register them anyway.
+func (h *PatientHandler) CreatePatient(w http.ResponseWriter, r *http.Request) {
+	var patient models.Patient
+	if err := json.NewDecoder(r.Body).Decode(&patient); err != nil {
+		problem.InvalidJSON(w, r)
+		return
+	}
+
+	if err := validatePatient(&patient); err != nil {
+		problem.Invalid(w, r, err)
+		return
+	}
+
+	if r.URL.Query().Get("allow_duplicate") != "true" {
+		candidates, err := h.patientRepo.FindCandidates(r.Context(), &patient, duplicateCandidates)
+		if err != nil {
+			problem.Internal(w, r, "Failed to check for duplicates", err)
+			return
+		}
+
+		if matches := models.FindDuplicates(&patient, candidates); len(matches) > 0 {
+			ids := make([]int, len(matches))
+			for i, match := range matches {
+				ids[i] = match.Patient.ID
+			}
+			if !auditRead(w, r, h.auditLog, audit.Event{
+				Action:       "patient.duplicates",
+				ResourceType: "patient",
+				Details:      map[string]interface{}{"ids": ids},
+			}) {
+				return
+			}
+
+			// The likely duplicates are listed in the problem's matches member
+			p := problem.New(http.StatusConflict, codePossibleDuplicate, "Patient may already be registered")
+			p.Extensions = map[string]interface{}{"matches": matches}
+			problem.Write(w, r, p)
+			return
+		}
+	}
+
+	err := audit.Audited(r.Context(), h.db, func(tx *sql.Tx) (audit.Event, error) {
+		if err := models.NewPatientRepository(tx).Create(r.Context(), &patient); err != nil {
+			return audit.Event{}, err
+		}
+		return audit.Event{
+			Action:       "patient.create",
+			ResourceType: "patient",
+			ResourceID:   strconv.Itoa(patient.ID),
+			After:        patient,
+		}, nil
+	})
+	if err != nil {
+		if errors.Is(err, models.ErrPatientConflict) {
+			problem.ErrorCode(w, r, http.StatusConflict, codeIdentifierExists, "MRN or identifier already belongs to another patient")
+			return
+		}
+		problem.Internal(w, r, "Failed to create patient", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(patient)
+}
+
+// UpdatePatient handles PUT /api/patients/{id}, replacing the demographics
+// and identifiers. The MRN cannot be changed.
+func (h *PatientHandler) UpdatePatient(w http.ResponseWriter, r *http.Request) {
+	id, err := parsePatientID(r.URL.Path)
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, "Invalid patient ID")
+		return
+	}
+
+	var update models.Patient
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		problem.InvalidJSON(w, r)
+		return
+	}
+
+	if err := validatePatient(&update); err != nil {
+		problem.Invalid(w, r, err)
+		return
+	}
+
+	patient, err := h.patientRepo.GetByID(r.Context(), id)
+	if err != nil {
+		problem.Internal(w, r, "Failed to get patient", err)
+		return
+	}
+
+	if patient == nil {
+		problem.Error(w, r, http.StatusNotFound, "Patient not found")
+		return
+	}
+
+	if update.MRN != "" && update.MRN != patient.MRN {
+		problem.Error(w, r, http.StatusBadRequest, "MRN cannot be changed")
+		return
+	}
+
+	before := *patient
+	update.ID = id
+
+	err = audit.Audited(r.Context(), h.db, func(tx *sql.Tx) (audit.Event, error) {
+		if err := models.NewPatientRepository(tx).Update(r.Context(), &update); err != nil {
+			return audit.Event{}, err
+		}
+		return audit.Event{
+			Action:       "patient.update",
+			ResourceType: "patient",
+			ResourceID:   strconv.Itoa(id),
+			Before:       before,
+			After:        update,
+		}, nil
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrPatientMerged):
+			problem.ErrorCode(w, r, http.StatusConflict, codePatientMerged, "Patient has been merged; update the surviving record")
+		case errors.Is(err, models.ErrPatientConflict):
+			problem.ErrorCode(w, r, http.StatusConflict, codeIdentifierExists, "Identifier already belongs to another patient")
+		case err == sql.ErrNoRows:
+			problem.Error(w, r, http.StatusNotFound, "Patient not found")
+		default:
+			problem.Internal(w, r, "Failed to update patient", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(update)
+}
+
+// GetDuplicates handles GET /api/patients/{id}/duplicates, listing
+// registered patients that may be the same person
+func (h *PatientHandler) GetDuplicates(w http.ResponseWriter, r *http.Request) {
+	patient, ok := h.loadPatient(w, r)
+	if !ok {
+		return
+	}
+
+	candidates, err := h.patientRepo.FindCandidates(r.Context(), patient, duplicateCandidates)
+	if err != nil {
+		problem.Internal(w, r, "Failed to find duplicates", err)
+		return
+	}
+
+	matches := models.FindDuplicates(patient, candidates)
+
+	ids := make([]int, len(matches))
+	for i, match := range matches {
+		ids[i] = match.Patient.ID
+	}
+	if !auditRead(w, r, h.auditLog, audit.Event{
+		Action:       "patient.duplicates",
+		ResourceType: "patient",
+		ResourceID:   strconv.Itoa(patient.ID),
+		Details:      map[string]interface{}{"ids": ids},
+	}) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(matches)
+}
+
+// GetMerges handles GET /api/patients/{id}/merges, the merges the patient
+// took part in as survivor or as the merged record
+func (h *PatientHandler) GetMerges(w http.ResponseWriter, r *http.Request) {
+	patient, ok := h.loadPatient(w, r)
+	if !ok {
+		return
+	}
+
+	merges, err := h.patientRepo.GetMerges(r.Context(), patient.ID)
+	if err != nil {
+		problem.Internal(w, r, "Failed to get merges", err)
+		return
+	}
+
+	if merges == nil {
+		merges = []models.PatientMerge{}
+	}
+
+	if !auditRead(w, r, h.auditLog, audit.Event{
+		Action:       "patient.merge_history",
+		ResourceType: "patient",
+		ResourceID:   strconv.Itoa(patient.ID),
+	}) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(merges)
+}
+
+// MergePatient handles POST /api/patients/{id}/merges, merging the patient
+// named in the body into patient {id}. Everything referencing the merged
+// patient is re-pointed to the survivor; the merge can be reversed.
+func (h *PatientHandler) MergePatient(w http.ResponseWriter, r *http.Request) {
+	survivorID, err := parsePatientID(strings.TrimSuffix(r.URL.Path, "/merges"))
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, "Invalid patient ID")
+		return
+	}
+
+	var req MergeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		problem.InvalidJSON(w, r)
+		return
+	}
+
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.PatientID <= 0 || req.Reason == "" {
+		problem.Error(w, r, http.StatusBadRequest, "patient_id and reason are required")
+		return
+	}
+
+	if req.PatientID == survivorID {
+		problem.Error(w, r, http.StatusBadRequest, "A patient cannot be merged into itself")
+		return
+	}
+
+	var merge *models.PatientMerge
+	err = audit.Audited(r.Context(), h.db, func(tx *sql.Tx) (audit.Event, error) {
+		var err error
+		if merge, err = models.NewPatientRepository(tx).Merge(r.Context(), survivorID, req.PatientID, req.Reason, callerID(r)); err != nil {
+			return audit.Event{}, err
+		}
+		return audit.Event{
+			Action:       "patient.merge",
+			ResourceType: "patient",
+			ResourceID:   strconv.Itoa(merge.MergedID),
+			After:        merge,
+		}, nil
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrPatientMerged):
+			problem.ErrorCode(w, r, http.StatusConflict, codePatientMerged, "Patient has already been merged")
+		case err == sql.ErrNoRows:
+			problem.Error(w, r, http.StatusNotFound, "Patient not found")
+		default:
+			problem.Internal(w, r, "Failed to merge patients", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(merge)
+}
+
+// ReverseMerge handles POST /api/patients/{id}/merges/{mergeId}/reverse
+func (h *PatientHandler) ReverseMerge(w http.ResponseWriter, r *http.Request) {
+	survivorID, mergeID, err := parseMergePath(r.URL.Path)
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, "Invalid merge path")
+		return
+	}
+
+	var merge *models.PatientMerge
+	err = audit.Audited(r.Context(), h.db, func(tx *sql.Tx) (audit.Event, error) {
+		var err error
+		if merge, err = models.NewPatientRepository(tx).Reverse(r.Context(), mergeID, survivorID, callerID(r)); err != nil {
+			return audit.Event{}, err
+		}
+		return audit.Event{
+			Action:       "patient.unmerge",
+			ResourceType: "patient",
+			ResourceID:   strconv.Itoa(merge.MergedID),
+			After:        merge,
+		}, nil
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrMergeReversed):
+			problem.ErrorCode(w, r, http.StatusConflict, codeMergeReversed, "Merge has already been reversed")
+		case errors.Is(err, models.ErrPatientMerged):
+			problem.ErrorCode(w, r, http.StatusConflict, codePatientMerged, "Surviving patient has since been merged; reverse that merge first")
+		case err == sql.ErrNoRows:
+			problem.Error(w, r, http.StatusNotFound, "Merge not found")
+		default:
+			problem.Internal(w, r, "Failed to reverse merge", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(merge)
+}
+
+// loadPatient fetches the patient named in /api/patients/{id}[/...],
+// writing an error and returning false if it cannot
+func (h *PatientHandler) loadPatient(w http.ResponseWriter, r *http.Request) (*models.Patient, bool) {
+	id, err := parsePatientID(r.URL.Path)
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, "Invalid patient ID")
+		return nil, false
+	}
+
+	patient, err := h.patientRepo.GetByID(r.Context(), id)
+	if err != nil {
+		problem.Internal(w, r, "Failed to get patient", err)
+		return nil, false
+	}
+
+	if patient == nil {
+		problem.Error(w, r, http.StatusNotFound, "Patient not found")
+		return nil, false
+	}
+
+	return patient, true
+}
+
+// validatePatient checks and normalizes the fields of a patient
+func validatePatient(patient *models.Patient) error {
+	patient.MRN = strings.TrimSpace(patient.MRN)
+	patient.FamilyName = strings.TrimSpace(patient.FamilyName)
+	patient.GivenName = strings.TrimSpace(patient.GivenName)
+	patient.Phone = strings.TrimSpace(patient.Phone)
+	patient.Email = strings.TrimSpace(patient.Email)
+	patient.Address = strings.TrimSpace(patient.Address)
+
+	if patient.FamilyName == "" {
+		return problem.Field("family_name", problem.FieldRequired, "family_name is required")
+	}
+
+	if patient.BirthDate != "" && !validBirthDate(patient.BirthDate) {
+		return problem.Field("birth_date", problem.FieldInvalid, "birth_date must be a past date in YYYY-MM-DD format")
+	}
+
+	if patient.Sex == "" {
+		patient.Sex = models.PatientSexUnknown
+	}
+
+	if !patient.Sex.Valid() {
+		return problem.Field("sex", problem.FieldInvalid, "sex must be female, male, other or unknown")
+	}
+
+	seen := make(map[models.PatientIdentifier]bool, len(patient.Identifiers))
+	for i := range patient.Identifiers {
+		identifier := &patient.Identifiers[i]
+		identifier.ID = 0
+		identifier.System = strings.TrimSpace(identifier.System)
+		identifier.Value = strings.TrimSpace(identifier.Value)
+
+		if identifier.System == "" || identifier.Value == "" {
+			return problem.Field(fmt.Sprintf("identifiers[%d]", i), problem.FieldRequired, "identifiers require a system and a value")
+		}
+		if seen[*identifier] {
+			return problem.Field(fmt.Sprintf("identifiers[%d]", i), problem.FieldDuplicate,
+				fmt.Sprintf("identifier %s %s is listed twice", identifier.System, identifier.Value))
+		}
+		seen[*identifier] = true
+	}
+
+	if patient.Identifiers == nil {
+		patient.Identifiers = []models.PatientIdentifier{}
+	}
+
+	return nil
+}
+
+// validBirthDate reports whether s is a YYYY-MM-DD date not in the future
+func validBirthDate(s string) bool {
+	t, err := time.Parse(models.BirthDateLayout, s)
+	return err == nil && !t.After(time.Now())
+}
+
+// parsePatientID extracts the ID from /api/patients/{id}[/...]
+func parsePatientID(path string) (int, error) {
+	segment, _, _ := strings.Cut(strings.TrimPrefix(path, "/api/patients/"), "/")
+	return strconv.Atoi(segment)
+}
+
+// parseMergePath extracts the IDs from
+// /api/patients/{id}/merges/{mergeId}/reverse
+func parseMergePath(path string) (patientID, mergeID int, err error) {
+	parts := strings.Split(strings.TrimPrefix(path, "/api/patients/"), "/")
+	if len(parts) != 4 || parts[1] != "merges" || parts[3] != "reverse" {
+		return 0, 0, errors.New("invalid merge path")
+	}
+
+	if patientID, err = strconv.Atoi(parts[0]); err != nil {
+		return 0, 0, err
+	}
+	if mergeID, err = strconv.Atoi(parts[2]); err != nil {
+		return 0, 0, err
+	}
+	return patientID, mergeID, nil
+}
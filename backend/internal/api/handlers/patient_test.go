@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"backend/internal/models"
+)
+
+func TestPatientHandler_CreatePatient_InvalidJSON(t *testing.T) {
+	handler := NewPatientHandler(nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/patients", bytes.NewBufferString("invalid json"))
+	w := httptest.NewRecorder()
+
+	handler.CreatePatient(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestPatientHandler_CreatePatient_InvalidBirthDate(t *testing.T) {
+	handler := NewPatientHandler(nil)
+
+	body := `{"family_name":"Okafor","birth_date":"02/11/1975"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/patients", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+
+	handler.CreatePatient(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestPatientHandler_GetPatients_InvalidLimit(t *testing.T) {
+	handler := NewPatientHandler(nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/patients?limit=5000", nil)
+	w := httptest.NewRecorder()
+
+	handler.GetPatients(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestPatientHandler_GetPatients_InvalidQuery(t *testing.T) {
+	handler := NewPatientHandler(nil)
+
+	for _, query := range []string{"birth_date=02/11/1975", "sort=birth_date", "before_id=10", "name=a&name=b"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/patients?"+query, nil)
+		w := httptest.NewRecorder()
+
+		handler.GetPatients(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", query, http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
+func TestPatientHandler_MergePatient_Validation(t *testing.T) {
+	handler := NewPatientHandler(nil)
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"missing reason", `{"patient_id":8}`},
+		{"missing patient", `{"reason":"duplicate registration"}`},
+		{"into itself", `{"patient_id":7,"reason":"duplicate registration"}`},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/api/patients/7/merges", bytes.NewBufferString(tt.body))
+		w := httptest.NewRecorder()
+
+		handler.MergePatient(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", tt.name, http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
+func TestValidatePatient(t *testing.T) {
+	patient := models.Patient{
+		FamilyName:  " Okafor ",
+		BirthDate:   "1975-11-02",
+		Identifiers: []models.PatientIdentifier{{ID: 9, System: " hospital-a ", Value: "H100"}},
+	}
+	if err := validatePatient(&patient); err != nil {
+		t.Fatalf("Expected valid patient, got %v", err)
+	}
+
+	if patient.FamilyName != "Okafor" || patient.Sex != models.PatientSexUnknown {
+		t.Errorf("Expected defaults and trimming, got %+v", patient)
+	}
+	if identifier := patient.Identifiers[0]; identifier.ID != 0 || identifier.System != "hospital-a" {
+		t.Errorf("Expected a normalized identifier, got %+v", identifier)
+	}
+
+	patient.Identifiers = append(patient.Identifiers, models.PatientIdentifier{System: "hospital-a", Value: "H100"})
+	if err := validatePatient(&patient); err == nil {
+		t.Error("Expected a repeated identifier to be rejected")
+	}
+
+	patient.Identifiers = nil
+	patient.BirthDate = "2999-01-01"
+	if err := validatePatient(&patient); err == nil {
+		t.Error("Expected a future birth date to be rejected")
+	}
+}
+
+func TestParseMergePath(t *testing.T) {
+	patientID, mergeID, err := parseMergePath("/api/patients/7/merges/3/reverse")
+	if err != nil || patientID != 7 || mergeID != 3 {
+		t.Errorf("Expected 7 and 3, got %d, %d, %v", patientID, mergeID, err)
+	}
+
+	if _, _, err := parseMergePath("/api/patients/7/merges/3"); err == nil {
+		t.Error("Expected an error for a path without /reverse")
+	}
+}
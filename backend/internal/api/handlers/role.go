@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"backend/internal/api/problem"
+	"backend/internal/audit"
+	"backend/internal/auth"
+	"backend/internal/models"
+)
+
+// RoleHandler handles HTTP requests for role management
+type RoleHandler struct {
+	roleRepo *models.RoleRepository
+	userRepo *models.UserRepository
+	auditLog *audit.Logger
+}
+
+// NewRoleHandler creates a new role handler
+func NewRoleHandler(db *sql.DB) *RoleHandler {
+	return &RoleHandler{
+		roleRepo: models.NewRoleRepository(db),
+		userRepo: models.NewUserRepository(db),
+		auditLog: newAuditLogger(db),
+	}
+}
+
+// GetRoles handles GET /api/roles
+func (h *RoleHandler) GetRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.roleRepo.GetAll(r.Context())
+	if err != nil {
+		problem.Internal(w, r, "Failed to get roles", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(roles)
+}
+
+// GetUserRoles handles GET /api/users/{id}/roles
+func (h *RoleHandler) GetUserRoles(w http.ResponseWriter, r *http.Request) {
+	userID, _, err := parseUserRolePath(r.URL.Path)
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	user, err := h.userRepo.GetByID(r.Context(), userID)
+	if err != nil {
+		problem.Internal(w, r, "Failed to get user", err)
+		return
+	}
+
+	if user == nil {
+		problem.Error(w, r, http.StatusNotFound, "User not found")
+		return
+	}
+
+	roles, err := h.roleRepo.GetUserRoles(r.Context(), userID)
+	if err != nil {
+		problem.Internal(w, r, "Failed to get user roles", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(roles)
+}
+
+// AssignRole handles PUT /api/users/{id}/roles/{role}
+func (h *RoleHandler) AssignRole(w http.ResponseWriter, r *http.Request) {
+	userID, roleName, err := parseUserRolePath(r.URL.Path)
+	if err != nil || roleName == "" {
+		problem.Error(w, r, http.StatusBadRequest, "Invalid user ID or role")
+		return
+	}
+
+	user, err := h.userRepo.GetByID(r.Context(), userID)
+	if err != nil {
+		problem.Internal(w, r, "Failed to get user", err)
+		return
+	}
+
+	if user == nil {
+		problem.Error(w, r, http.StatusNotFound, "User not found")
+		return
+	}
+
+	var grantedBy *int
+	if admin, ok := auth.UserFromContext(r.Context()); ok {
+		grantedBy = &admin.ID
+	}
+
+	if err := h.roleRepo.Assign(r.Context(), userID, roleName, grantedBy); err != nil {
+		if err == sql.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, "Role not found")
+			return
+		}
+		problem.Internal(w, r, "Failed to assign role", err)
+		return
+	}
+
+	recordAudit(r, h.auditLog, audit.Event{
+		Action:       "role.assign",
+		ResourceType: "user",
+		ResourceID:   strconv.Itoa(userID),
+		Details:      map[string]string{"role": roleName},
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeRole handles DELETE /api/users/{id}/roles/{role}
+func (h *RoleHandler) RevokeRole(w http.ResponseWriter, r *http.Request) {
+	userID, roleName, err := parseUserRolePath(r.URL.Path)
+	if err != nil || roleName == "" {
+		problem.Error(w, r, http.StatusBadRequest, "Invalid user ID or role")
+		return
+	}
+
+	// Prevent administrators from locking themselves out
+	if admin, ok := auth.UserFromContext(r.Context()); ok && admin.ID == userID && roleName == "admin" {
+		problem.Error(w, r, http.StatusConflict, "Cannot revoke your own admin role")
+		return
+	}
+
+	if err := h.roleRepo.Revoke(r.Context(), userID, roleName); err != nil {
+		if err == sql.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, "Role assignment not found")
+			return
+		}
+		problem.Internal(w, r, "Failed to revoke role", err)
+		return
+	}
+
+	recordAudit(r, h.auditLog, audit.Event{
+		Action:       "role.revoke",
+		ResourceType: "user",
+		ResourceID:   strconv.Itoa(userID),
+		Details:      map[string]string{"role": roleName},
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseUserRolePath extracts the user ID and optional role name from
+// /api/users/{id}/roles[/{role}]
+func parseUserRolePath(path string) (int, string, error) {
+	parts := strings.Split(strings.TrimPrefix(path, "/api/users/"), "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[1] != "roles" {
+		return 0, "", fmt.Errorf("invalid path %q", path)
+	}
+
+	userID, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, "", err
+	}
+
+	if len(parts) == 3 {
+		return userID, parts[2], nil
+	}
+	return userID, "", nil
+}
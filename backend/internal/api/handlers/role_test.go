@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"backend/internal/auth"
+	"backend/internal/models"
+)
+
+func TestParseUserRolePath(t *testing.T) {
+	userID, role, err := parseUserRolePath("/api/users/12/roles/pathologist")
+	if err != nil || userID != 12 || role != "pathologist" {
+		t.Errorf("Expected 12 pathologist, got %d %q %v", userID, role, err)
+	}
+
+	userID, role, err = parseUserRolePath("/api/users/12/roles")
+	if err != nil || userID != 12 || role != "" {
+		t.Errorf("Expected 12 with no role, got %d %q %v", userID, role, err)
+	}
+
+	if _, _, err := parseUserRolePath("/api/users/abc/roles"); err == nil {
+		t.Error("Expected error for non-numeric user ID")
+	}
+}
+
+func TestRoleHandler_AssignRole_InvalidID(t *testing.T) {
+	handler := NewRoleHandler(nil)
+
+	req := httptest.NewRequest(http.MethodPut, "/api/users/abc/roles/admin", nil)
+	w := httptest.NewRecorder()
+
+	handler.AssignRole(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestRoleHandler_RevokeRole_OwnAdmin(t *testing.T) {
+	handler := NewRoleHandler(nil)
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/users/1/roles/admin", nil)
+	req = req.WithContext(auth.WithUser(req.Context(), &models.User{ID: 1}))
+	w := httptest.NewRecorder()
+
+	handler.RevokeRole(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status %d, got %d", http.StatusConflict, w.Code)
+	}
+}
@@ -0,0 +1,478 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/api/problem"
+	"backend/internal/audit"
+	"backend/internal/auth"
+	"backend/internal/models"
+	"backend/internal/slide"
+	"backend/internal/storage"
+)
+
+// slideLibrarySize is the number of slides kept open between requests
+const slideLibrarySize = 64
+
+// tileMaxAge is how long browsers may reuse a tile; tiles of a completed
+// upload never change
+const tileMaxAge = 24 * time.Hour
+
+// SlideHandler serves whole-slide images as Deep Zoom and IIIF tiles. Each
+// request checks that the caller may view the slide.
+type SlideHandler struct {
+	uploadRepo *models.UploadRepository
+	grantRepo  *models.SlideGrantRepository
+	userRepo   *models.UserRepository
+	library    *slide.Library
+	tiles      *slide.Cache
+	renders    chan struct{}
+	auditLog   *audit.Logger
+}
+
+// NewSlideHandler creates a new slide handler reading slides from blobs and
+// caching rendered tiles in tiles
+func NewSlideHandler(db *sql.DB, blobs storage.BlobStore, tiles *slide.Cache) *SlideHandler {
+	return &SlideHandler{
+		uploadRepo: models.NewUploadRepository(db),
+		grantRepo:  models.NewSlideGrantRepository(db),
+		userRepo:   models.NewUserRepository(db),
+		library:    slide.NewLibrary(blobs, slideLibrarySize),
+		tiles:      tiles,
+		renders:    make(chan struct{}, runtime.NumCPU()),
+		auditLog:   newAuditLogger(db),
+	}
+}
+
+// slideLevel is a pyramid level in the slide metadata response
+type slideLevel struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// slideInfo is the response body of GET /api/slides/{id}
+type slideInfo struct {
+	ID              int          `json:"id"`
+	AccessionNumber string       `json:"accession_number"`
+	Filename        string       `json:"filename"`
+	Width           int          `json:"width"`
+	Height          int          `json:"height"`
+	Levels          []slideLevel `json:"levels"`
+	DZI             string       `json:"dzi"`
+	IIIF            string       `json:"iiif"`
+}
+
+// GetSlide handles GET /api/slides/{id}
+func (h *SlideHandler) GetSlide(w http.ResponseWriter, r *http.Request) {
+	upload, s, ok := h.loadSlide(w, r)
+	if !ok || !h.auditView(w, r, upload, "metadata") {
+		return
+	}
+
+	info := slideInfo{
+		ID:              upload.ID,
+		AccessionNumber: upload.AccessionNumber,
+		Filename:        upload.Filename,
+		Width:           s.Width(),
+		Height:          s.Height(),
+		DZI:             fmt.Sprintf("/api/slides/%d.dzi", upload.ID),
+		IIIF:            fmt.Sprintf("/api/slides/%d/iiif/info.json", upload.ID),
+	}
+	for _, level := range s.Levels {
+		info.Levels = append(info.Levels, slideLevel{Width: level.Width, Height: level.Height})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(info)
+}
+
+// GetDZI handles GET /api/slides/{id}.dzi, the Deep Zoom descriptor
+func (h *SlideHandler) GetDZI(w http.ResponseWriter, r *http.Request) {
+	upload, s, ok := h.loadSlide(w, r)
+	if !ok || !h.auditView(w, r, upload, "dzi") {
+		return
+	}
+
+	h.serveImage(w, r, upload, "dzi", "application/xml", func(ctx context.Context) ([]byte, error) {
+		return []byte(slide.DZIDescriptor(s.Width(), s.Height())), nil
+	})
+}
+
+// GetDZITile handles GET /api/slides/{id}_files/{level}/{col}_{row}.jpeg
+func (h *SlideHandler) GetDZITile(w http.ResponseWriter, r *http.Request) {
+	level, col, row, err := parseDZITilePath(r.URL.Path)
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, "Invalid tile path")
+		return
+	}
+
+	upload, s, ok := h.loadSlide(w, r)
+	if !ok {
+		return
+	}
+
+	region, size, err := slide.DZITile(s.Width(), s.Height(), level, col, row)
+	if err != nil {
+		problem.Error(w, r, http.StatusNotFound, "Tile not found")
+		return
+	}
+
+	variant := fmt.Sprintf("dzi/%d/%d_%d.jpeg", level, col, row)
+	h.serveImage(w, r, upload, variant, "image/jpeg", func(ctx context.Context) ([]byte, error) {
+		img, err := s.Render(ctx, region, size.X, size.Y)
+		if err != nil {
+			return nil, err
+		}
+		return slide.Encode(img, "jpg", false)
+	})
+}
+
+// RedirectIIIF handles GET /api/slides/{id}/iiif, redirecting to info.json
+func (h *SlideHandler) RedirectIIIF(w http.ResponseWriter, r *http.Request) {
+	http.Redirect(w, r, strings.TrimSuffix(r.URL.Path, "/")+"/info.json", http.StatusSeeOther)
+}
+
+// GetIIIFInfo handles GET /api/slides/{id}/iiif/info.json
+func (h *SlideHandler) GetIIIFInfo(w http.ResponseWriter, r *http.Request) {
+	upload, s, ok := h.loadSlide(w, r)
+	if !ok || !h.auditView(w, r, upload, "iiif") {
+		return
+	}
+
+	id := fmt.Sprintf("%s/api/slides/%d/iiif", baseURL(r), upload.ID)
+	info := slide.IIIFInfo(id, s.Width(), s.Height())
+
+	contentType := "application/json"
+	if strings.Contains(r.Header.Get("Accept"), "application/ld+json") {
+		contentType = `application/ld+json;profile="http://iiif.io/api/image/3/context.json"`
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	json.NewEncoder(w).Encode(info)
+}
+
+// GetIIIFImage handles GET /api/slides/{id}/iiif/{region}/{size}/{rotation}/{quality}.{format}
+func (h *SlideHandler) GetIIIFImage(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/slides/"), "/")
+	if len(parts) != 6 {
+		problem.Error(w, r, http.StatusBadRequest, "Invalid IIIF image path")
+		return
+	}
+
+	upload, s, ok := h.loadSlide(w, r)
+	if !ok {
+		return
+	}
+
+	req, err := slide.ParseIIIFRequest(s.Width(), s.Height(), parts[2], parts[3], parts[4], parts[5])
+	if err != nil {
+		problem.Invalid(w, r, err)
+		return
+	}
+
+	contentType := "image/jpeg"
+	if req.Format == "png" {
+		contentType = "image/png"
+	}
+
+	h.serveImage(w, r, upload, req.CacheKey(), contentType, func(ctx context.Context) ([]byte, error) {
+		img, err := s.Render(ctx, req.Region, req.Size.X, req.Size.Y)
+		if err != nil {
+			return nil, err
+		}
+		return slide.Encode(img, req.Format, req.Quality == "gray")
+	})
+}
+
+// GetGrants handles GET /api/slides/{id}/grants
+func (h *SlideHandler) GetGrants(w http.ResponseWriter, r *http.Request) {
+	upload, ok := h.loadViewableUpload(w, r)
+	if !ok {
+		return
+	}
+
+	grants, err := h.grantRepo.GetByUpload(r.Context(), upload.ID)
+	if err != nil {
+		problem.Internal(w, r, "Failed to get slide grants", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(grants)
+}
+
+// GrantAccess handles PUT /api/slides/{id}/grants/{userId}
+func (h *SlideHandler) GrantAccess(w http.ResponseWriter, r *http.Request) {
+	userID, err := parseGrantUserID(r.URL.Path)
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	upload, ok := h.loadViewableUpload(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.userRepo.GetByID(r.Context(), userID)
+	if err != nil {
+		problem.Internal(w, r, "Failed to get user", err)
+		return
+	}
+
+	if user == nil {
+		problem.Error(w, r, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if err := h.grantRepo.Grant(r.Context(), upload.ID, userID, callerID(r)); err != nil {
+		problem.Internal(w, r, "Failed to grant slide access", err)
+		return
+	}
+
+	recordAudit(r, h.auditLog, audit.Event{
+		Action:       "slide.grant",
+		ResourceType: "slide",
+		ResourceID:   strconv.Itoa(upload.ID),
+		Details:      map[string]int{"user_id": userID},
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeAccess handles DELETE /api/slides/{id}/grants/{userId}
+func (h *SlideHandler) RevokeAccess(w http.ResponseWriter, r *http.Request) {
+	userID, err := parseGrantUserID(r.URL.Path)
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	upload, ok := h.loadViewableUpload(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.grantRepo.Revoke(r.Context(), upload.ID, userID); err != nil {
+		if err == sql.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, "User has no grant for this slide")
+			return
+		}
+		problem.Internal(w, r, "Failed to revoke slide access", err)
+		return
+	}
+
+	recordAudit(r, h.auditLog, audit.Event{
+		Action:       "slide.revoke",
+		ResourceType: "slide",
+		ResourceID:   strconv.Itoa(upload.ID),
+		Details:      map[string]int{"user_id": userID},
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// auditView records that the caller opened a slide. Viewers fetch metadata
+// or a descriptor before any tiles, so individual tiles are not audited.
+func (h *SlideHandler) auditView(w http.ResponseWriter, r *http.Request, upload *models.Upload, via string) bool {
+	return auditRead(w, r, h.auditLog, audit.Event{
+		Action:       "slide.view",
+		ResourceType: "slide",
+		ResourceID:   strconv.Itoa(upload.ID),
+		Details:      map[string]string{"accession_number": upload.AccessionNumber, "via": via},
+	})
+}
+
+// loadViewableUpload loads the completed upload in the path and checks that
+// the caller may view it, writing the error response and returning false
+// otherwise
+func (h *SlideHandler) loadViewableUpload(w http.ResponseWriter, r *http.Request) (*models.Upload, bool) {
+	id, err := parseSlideID(r.URL.Path)
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, "Invalid slide ID")
+		return nil, false
+	}
+
+	upload, err := h.uploadRepo.GetByID(r.Context(), id)
+	if err != nil {
+		problem.Internal(w, r, "Failed to get slide", err)
+		return nil, false
+	}
+
+	if upload == nil || upload.Status != models.UploadComplete {
+		problem.Error(w, r, http.StatusNotFound, "Slide not found")
+		return nil, false
+	}
+
+	if !checkSlideAccess(w, r, h.grantRepo, upload) {
+		return nil, false
+	}
+
+	return upload, true
+}
+
+// checkSlideAccess checks that the caller may view the upload, writing the
+// error response and returning false otherwise. Pathologists and admins see
+// every slide; others only slides they uploaded, ordered, or were granted.
+func checkSlideAccess(w http.ResponseWriter, r *http.Request, grantRepo *models.SlideGrantRepository, upload *models.Upload) bool {
+	access, _ := auth.AccessFromContext(r.Context())
+	if access.Has(auth.PermissionSlidesReadAny) {
+		return true
+	}
+
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		problem.Error(w, r, http.StatusUnauthorized, "Not authenticated")
+		return false
+	}
+
+	allowed, err := grantRepo.CanView(r.Context(), upload.ID, user.ID)
+	if err != nil {
+		problem.Internal(w, r, "Failed to check slide access", err)
+		return false
+	}
+
+	if !allowed {
+		problem.Error(w, r, http.StatusForbidden, "You do not have access to this slide")
+		return false
+	}
+	return true
+}
+
+// loadSlide loads a viewable upload and opens it as a slide
+func (h *SlideHandler) loadSlide(w http.ResponseWriter, r *http.Request) (*models.Upload, *slide.Slide, bool) {
+	upload, ok := h.loadViewableUpload(w, r)
+	if !ok {
+		return nil, nil, false
+	}
+
+	s, err := h.library.Open(r.Context(), upload.StorageKey)
+	if err != nil {
+		if errors.Is(err, slide.ErrUnsupported) {
+			problem.Error(w, r, http.StatusUnprocessableEntity, fmt.Sprintf("Slide cannot be displayed: %v", err))
+			return nil, nil, false
+		}
+		problem.Internal(w, r, "Failed to open slide", err)
+		return nil, nil, false
+	}
+
+	return upload, s, true
+}
+
+// serveImage writes a rendered tile, taking it from the tile cache when
+// possible. Tiles are keyed by the upload's checksum, so a cached tile is
+// never stale and browsers may revalidate with its ETag.
+func (h *SlideHandler) serveImage(w http.ResponseWriter, r *http.Request, upload *models.Upload, variant, contentType string,
+	render func(ctx context.Context) ([]byte, error)) {
+	key := fmt.Sprintf("%d-%s/%s", upload.ID, upload.SHA256, variant)
+	sum := sha256.Sum256([]byte(key))
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(tileMaxAge.Seconds())))
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Content-Type", contentType)
+
+	// Answer revalidation before rendering anything
+	if match := r.Header.Get("If-None-Match"); match != "" && (match == "*" || strings.Contains(match, etag)) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	data, ok := h.tiles.Get(key)
+	if !ok {
+		// Bound concurrent rendering to the available CPUs
+		select {
+		case h.renders <- struct{}{}:
+		case <-r.Context().Done():
+			return
+		}
+		var err error
+		data, err = render(r.Context())
+		<-h.renders
+		if err != nil {
+			if r.Context().Err() == nil {
+				problem.Internal(w, r, "Failed to render tile", fmt.Errorf("render slide %d %s: %w", upload.ID, variant, err))
+			}
+			return
+		}
+		h.tiles.Put(key, data)
+	}
+
+	var modified time.Time
+	if upload.CompletedAt != nil {
+		modified = *upload.CompletedAt
+	}
+	http.ServeContent(w, r, "", modified, bytes.NewReader(data))
+}
+
+// baseURL returns the scheme and host the request was sent to
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// parseSlideID extracts the ID from /api/slides/{id}, /api/slides/{id}.dzi
+// and /api/slides/{id}_files/...
+func parseSlideID(path string) (int, error) {
+	segment, _, _ := strings.Cut(strings.TrimPrefix(path, "/api/slides/"), "/")
+	segment = strings.TrimSuffix(segment, ".dzi")
+	segment = strings.TrimSuffix(segment, "_files")
+	return strconv.Atoi(segment)
+}
+
+// parseDZITilePath extracts the level and tile position from
+// /api/slides/{id}_files/{level}/{col}_{row}.jpeg
+func parseDZITilePath(path string) (level, col, row int, err error) {
+	parts := strings.Split(strings.TrimPrefix(path, "/api/slides/"), "/")
+	if len(parts) != 3 {
+		return 0, 0, 0, errors.New("invalid tile path")
+	}
+
+	name, ok := strings.CutSuffix(parts[2], ".jpeg")
+	if !ok {
+		if name, ok = strings.CutSuffix(parts[2], ".jpg"); !ok {
+			return 0, 0, 0, errors.New("invalid tile format")
+		}
+	}
+
+	colText, rowText, ok := strings.Cut(name, "_")
+	if !ok {
+		return 0, 0, 0, errors.New("invalid tile name")
+	}
+
+	if level, err = strconv.Atoi(parts[1]); err != nil {
+		return 0, 0, 0, err
+	}
+	if col, err = strconv.Atoi(colText); err != nil {
+		return 0, 0, 0, err
+	}
+	if row, err = strconv.Atoi(rowText); err != nil {
+		return 0, 0, 0, err
+	}
+	return level, col, row, nil
+}
+
+// parseGrantUserID extracts the user ID from /api/slides/{id}/grants/{userId}
+func parseGrantUserID(path string) (int, error) {
+	parts := strings.Split(strings.TrimPrefix(path, "/api/slides/"), "/")
+	if len(parts) != 3 || parts[1] != "grants" {
+		return 0, errors.New("invalid grant path")
+	}
+	return strconv.Atoi(parts[2])
+}
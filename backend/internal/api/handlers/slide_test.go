@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSlideHandler_GetSlide_InvalidID(t *testing.T) {
+	handler := NewSlideHandler(nil, nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/slides/abc", nil)
+	w := httptest.NewRecorder()
+
+	handler.GetSlide(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestSlideHandler_GetDZITile_InvalidPath(t *testing.T) {
+	handler := NewSlideHandler(nil, nil, nil)
+
+	paths := []string{
+		"/api/slides/1_files/x/0_0.jpeg",
+		"/api/slides/1_files/10/0-0.jpeg",
+		"/api/slides/1_files/10/0_0.png",
+	}
+
+	for _, path := range paths {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+
+		handler.GetDZITile(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", path, http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
+func TestSlideHandler_RedirectIIIF(t *testing.T) {
+	handler := NewSlideHandler(nil, nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/slides/7/iiif", nil)
+	w := httptest.NewRecorder()
+
+	handler.RedirectIIIF(w, req)
+
+	if w.Code != http.StatusSeeOther {
+		t.Errorf("Expected status %d, got %d", http.StatusSeeOther, w.Code)
+	}
+	if location := w.Header().Get("Location"); location != "/api/slides/7/iiif/info.json" {
+		t.Errorf("Expected redirect to info.json, got %s", location)
+	}
+}
+
+func TestSlideHandler_GrantAccess_InvalidUserID(t *testing.T) {
+	handler := NewSlideHandler(nil, nil, nil)
+
+	req := httptest.NewRequest(http.MethodPut, "/api/slides/1/grants/abc", nil)
+	w := httptest.NewRecorder()
+
+	handler.GrantAccess(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestParseSlidePaths(t *testing.T) {
+	for path, want := range map[string]int{
+		"/api/slides/12":                             12,
+		"/api/slides/12.dzi":                         12,
+		"/api/slides/12_files/3/1_2.jpeg":            12,
+		"/api/slides/12/iiif/full/max/0/default.jpg": 12,
+	} {
+		if id, err := parseSlideID(path); err != nil || id != want {
+			t.Errorf("parseSlideID(%s) = %d, %v; want %d", path, id, err, want)
+		}
+	}
+
+	level, col, row, err := parseDZITilePath("/api/slides/12_files/3/1_2.jpeg")
+	if err != nil || level != 3 || col != 1 || row != 2 {
+		t.Errorf("parseDZITilePath = %d, %d, %d, %v; want 3, 1, 2", level, col, row, err)
+	}
+}
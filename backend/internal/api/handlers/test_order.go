@@ -0,0 +1,424 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"backend/internal/api/problem"
+	"backend/internal/audit"
+	"backend/internal/auth"
+	"backend/internal/listing"
+	"backend/internal/models"
+)
+
+// Problem codes for test order and result conflicts
+const (
+	codeInvalidTransition = "invalid_transition"
+	codeConcurrentUpdate  = "concurrent_update"
+)
+
+// TestOrderHandler handles HTTP requests for diagnostic test orders
+type TestOrderHandler struct {
+	db          *sql.DB
+	orderRepo   *models.TestOrderRepository
+	patientRepo *models.PatientRepository
+	auditLog    *audit.Logger
+}
+
+// NewTestOrderHandler creates a new test order handler
+func NewTestOrderHandler(db *sql.DB) *TestOrderHandler {
+	return &TestOrderHandler{
+		db:          db,
+		orderRepo:   models.NewTestOrderRepository(db),
+		patientRepo: models.NewPatientRepository(db),
+		auditLog:    newAuditLogger(db),
+	}
+}
+
+// StatusChangeRequest represents the body of POST /api/tests/{id}/status
+type StatusChangeRequest struct {
+	Status models.TestOrderStatus `json:"status"`
+	Reason string                 `json:"reason"`
+}
+
+// GetTestOrders handles GET /api/tests. It returns one page of orders; see
+// models.TestOrderList for the accepted sort fields and filters.
+func (h *TestOrderHandler) GetTestOrders(w http.ResponseWriter, r *http.Request) {
+	if status := models.TestOrderStatus(r.URL.Query().Get("status")); status != "" && !status.Valid() {
+		problem.Error(w, r, http.StatusBadRequest, "Invalid status filter")
+		return
+	}
+
+	q, err := models.TestOrderList.Parse(r.URL.Query())
+	if err != nil {
+		problem.Invalid(w, r, err)
+		return
+	}
+
+	orders, total, err := h.orderRepo.List(r.Context(), q)
+	if err != nil {
+		problem.Internal(w, r, "Failed to get test orders", err)
+		return
+	}
+
+	ids := make([]int, len(orders))
+	for i, order := range orders {
+		ids[i] = order.ID
+	}
+	if !auditRead(w, r, h.auditLog, audit.Event{
+		Action:       "test_order.list",
+		ResourceType: "test_order",
+		Details:      map[string]interface{}{"query": r.URL.Query(), "ids": ids},
+	}) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(listing.NewPage(orders, q, models.TestOrder.ListKey).WithTotal(total))
+}
+
+// GetTestOrder handles GET /api/tests/{id}
+func (h *TestOrderHandler) GetTestOrder(w http.ResponseWriter, r *http.Request) {
+	id, err := parseTestOrderID(r.URL.Path)
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, "Invalid test order ID")
+		return
+	}
+
+	order, err := h.orderRepo.GetByID(r.Context(), id)
+	if err != nil {
+		problem.Internal(w, r, "Failed to get test order", err)
+		return
+	}
+
+	if order == nil {
+		problem.Error(w, r, http.StatusNotFound, "Test order not found")
+		return
+	}
+
+	if !auditRead(w, r, h.auditLog, audit.Event{
+		Action:       "test_order.read",
+		ResourceType: "test_order",
+		ResourceID:   strconv.Itoa(order.ID),
+	}) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
+
+// CreateTestOrder handles POST /api/tests. New orders always start in the
+// ordered status; the ordering clinician defaults to the caller.
+func (h *TestOrderHandler) CreateTestOrder(w http.ResponseWriter, r *http.Request) {
+	var order models.TestOrder
+	if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
+		problem.InvalidJSON(w, r)
+		return
+	}
+
+	if err := validateTestOrder(&order); err != nil {
+		problem.Invalid(w, r, err)
+		return
+	}
+
+	if order.OrderingClinicianID == nil {
+		if user, ok := auth.UserFromContext(r.Context()); ok {
+			order.OrderingClinicianID = &user.ID
+		}
+	}
+
+	if err := h.linkPatient(r.Context(), &order); err != nil {
+		if errors.Is(err, errPatientNotFound) {
+			problem.Error(w, r, http.StatusBadRequest, "Patient not found")
+			return
+		}
+		problem.Internal(w, r, "Failed to resolve patient", err)
+		return
+	}
+
+	err := audit.Audited(r.Context(), h.db, func(tx *sql.Tx) (audit.Event, error) {
+		if err := models.NewTestOrderRepository(tx).Create(r.Context(), &order); err != nil {
+			return audit.Event{}, err
+		}
+		return audit.Event{
+			Action:       "test_order.create",
+			ResourceType: "test_order",
+			ResourceID:   strconv.Itoa(order.ID),
+			After:        order,
+		}, nil
+	})
+	if err != nil {
+		problem.Internal(w, r, "Failed to create test order", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(order)
+}
+
+// UpdateTestOrder handles PUT /api/tests/{id}. Status is changed through
+// POST /api/tests/{id}/status, not here.
+func (h *TestOrderHandler) UpdateTestOrder(w http.ResponseWriter, r *http.Request) {
+	id, err := parseTestOrderID(r.URL.Path)
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, "Invalid test order ID")
+		return
+	}
+
+	var update models.TestOrder
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		problem.InvalidJSON(w, r)
+		return
+	}
+
+	order, err := h.orderRepo.GetByID(r.Context(), id)
+	if err != nil {
+		problem.Internal(w, r, "Failed to get test order", err)
+		return
+	}
+
+	if order == nil {
+		problem.Error(w, r, http.StatusNotFound, "Test order not found")
+		return
+	}
+
+	if update.Status != "" && update.Status != order.Status {
+		problem.Error(w, r, http.StatusBadRequest, "Use POST /api/tests/{id}/status to change status")
+		return
+	}
+
+	before := *order
+	order.TestType = update.TestType
+	order.Priority = update.Priority
+	order.SpecimenID = update.SpecimenID
+	order.Notes = update.Notes
+
+	if err := validateTestOrder(order); err != nil {
+		problem.Invalid(w, r, err)
+		return
+	}
+
+	err = audit.Audited(r.Context(), h.db, func(tx *sql.Tx) (audit.Event, error) {
+		if err := models.NewTestOrderRepository(tx).Update(r.Context(), order); err != nil {
+			return audit.Event{}, err
+		}
+		return audit.Event{
+			Action:       "test_order.update",
+			ResourceType: "test_order",
+			ResourceID:   strconv.Itoa(id),
+			Before:       before,
+			After:        order,
+		}, nil
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			problem.ErrorCode(w, r, http.StatusConflict, codeInvalidTransition, "Test order can no longer be edited")
+			return
+		}
+		problem.Internal(w, r, "Failed to update test order", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
+
+// ChangeStatus handles POST /api/tests/{id}/status
+func (h *TestOrderHandler) ChangeStatus(w http.ResponseWriter, r *http.Request) {
+	id, err := parseTestOrderID(strings.TrimSuffix(r.URL.Path, "/status"))
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, "Invalid test order ID")
+		return
+	}
+
+	var req StatusChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		problem.InvalidJSON(w, r)
+		return
+	}
+
+	if !req.Status.Valid() {
+		problem.Error(w, r, http.StatusBadRequest, "Invalid status")
+		return
+	}
+
+	// Signing out is controlled by results:verify on the result endpoints,
+	// so those statuses cannot be set here
+	if req.Status.SetByResult() {
+		problem.ErrorCode(w, r, http.StatusConflict, codeInvalidTransition,
+			fmt.Sprintf("Test orders become %s through their result, not a status change", req.Status))
+		return
+	}
+
+	if req.Status == models.TestOrderCancelled && strings.TrimSpace(req.Reason) == "" {
+		problem.Error(w, r, http.StatusBadRequest, "A reason is required to cancel a test order")
+		return
+	}
+
+	order, err := h.orderRepo.GetByID(r.Context(), id)
+	if err != nil {
+		problem.Internal(w, r, "Failed to get test order", err)
+		return
+	}
+
+	if order == nil {
+		problem.Error(w, r, http.StatusNotFound, "Test order not found")
+		return
+	}
+
+	if !order.Status.CanTransitionTo(req.Status) {
+		problem.ErrorCode(w, r, http.StatusConflict, codeInvalidTransition, fmt.Sprintf("Cannot change status from %s to %s", order.Status, req.Status))
+		return
+	}
+
+	var updated *models.TestOrder
+	err = audit.Audited(r.Context(), h.db, func(tx *sql.Tx) (audit.Event, error) {
+		var err error
+		if updated, err = models.NewTestOrderRepository(tx).UpdateStatus(r.Context(), id, order.Status, req.Status, strings.TrimSpace(req.Reason)); err != nil {
+			return audit.Event{}, err
+		}
+		return audit.Event{
+			Action:       "test_order.status_change",
+			ResourceType: "test_order",
+			ResourceID:   strconv.Itoa(id),
+			Before:       order,
+			After:        updated,
+		}, nil
+	})
+	if err != nil {
+		if errors.Is(err, models.ErrStatusConflict) {
+			problem.ErrorCode(w, r, http.StatusConflict, codeConcurrentUpdate, "Test order status changed concurrently; reload and retry")
+			return
+		}
+		problem.Internal(w, r, "Failed to change test order status", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// DeleteTestOrder handles DELETE /api/tests/{id}. Only orders entered in
+// error (still in the ordered status) may be deleted; others are cancelled.
+func (h *TestOrderHandler) DeleteTestOrder(w http.ResponseWriter, r *http.Request) {
+	id, err := parseTestOrderID(r.URL.Path)
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, "Invalid test order ID")
+		return
+	}
+
+	order, err := h.orderRepo.GetByID(r.Context(), id)
+	if err != nil {
+		problem.Internal(w, r, "Failed to get test order", err)
+		return
+	}
+
+	if order == nil {
+		problem.Error(w, r, http.StatusNotFound, "Test order not found")
+		return
+	}
+
+	err = audit.Audited(r.Context(), h.db, func(tx *sql.Tx) (audit.Event, error) {
+		if err := models.NewTestOrderRepository(tx).Delete(r.Context(), id); err != nil {
+			return audit.Event{}, err
+		}
+		return audit.Event{
+			Action:       "test_order.delete",
+			ResourceType: "test_order",
+			ResourceID:   strconv.Itoa(id),
+			Before:       order,
+		}, nil
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			problem.ErrorCode(w, r, http.StatusConflict, codeInvalidTransition, "Only orders in the ordered status can be deleted; cancel it instead")
+			return
+		}
+		problem.Internal(w, r, "Failed to delete test order", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// errPatientNotFound is returned by linkPatient for an unknown patient_id
+var errPatientNotFound = errors.New("patient not found")
+
+// linkPatient points a new order at its patient record, which may be named
+// by patient_id or by MRN. An MRN not yet in the registry registers a bare
+// patient so the order can take part in later merges. Orders for a merged
+// patient go to the record it was merged into.
+func (h *TestOrderHandler) linkPatient(ctx context.Context, order *models.TestOrder) error {
+	var patient *models.Patient
+	var err error
+
+	if order.PatientID != nil {
+		patient, err = h.patientRepo.GetByID(ctx, *order.PatientID)
+		if err == nil && patient == nil {
+			return errPatientNotFound
+		}
+	} else {
+		patient, err = h.patientRepo.GetByMRN(ctx, order.PatientMRN)
+		if err == nil && patient == nil {
+			patient = &models.Patient{MRN: order.PatientMRN, Sex: models.PatientSexUnknown}
+			if err = h.patientRepo.Create(ctx, patient); errors.Is(err, models.ErrPatientConflict) {
+				// Registered concurrently
+				patient, err = h.patientRepo.GetByMRN(ctx, order.PatientMRN)
+			}
+		}
+	}
+
+	for err == nil && patient.MergedIntoID != nil {
+		patient, err = h.patientRepo.GetByID(ctx, *patient.MergedIntoID)
+		if err == nil && patient == nil {
+			return errPatientNotFound
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	order.PatientID = &patient.ID
+	order.PatientMRN = patient.MRN
+	return nil
+}
+
+// validateTestOrder checks required fields and defaults the priority
+func validateTestOrder(order *models.TestOrder) error {
+	order.PatientMRN = strings.TrimSpace(order.PatientMRN)
+	order.TestType = strings.TrimSpace(order.TestType)
+
+	var errs problem.FieldErrors
+	if order.PatientMRN == "" && order.PatientID == nil {
+		errs = append(errs, problem.Field("patient_id", problem.FieldRequired, "either patient_mrn or patient_id is required"))
+	}
+	if order.TestType == "" {
+		errs = append(errs, problem.Field("test_type", problem.FieldRequired, "test_type is required"))
+	}
+
+	if order.Priority == "" {
+		order.Priority = models.TestOrderRoutine
+	}
+
+	if !order.Priority.Valid() {
+		errs = append(errs, problem.Field("priority", problem.FieldInvalid, "priority must be routine, urgent or stat"))
+	}
+
+	if errs != nil {
+		return errs
+	}
+	return nil
+}
+
+// parseTestOrderID extracts the ID from /api/tests/{id}
+func parseTestOrderID(path string) (int, error) {
+	return strconv.Atoi(strings.TrimPrefix(path, "/api/tests/"))
+}
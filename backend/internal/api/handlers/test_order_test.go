@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"backend/internal/models"
+)
+
+func TestTestOrderHandler_CreateTestOrder_InvalidJSON(t *testing.T) {
+	handler := NewTestOrderHandler(nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/tests", bytes.NewBufferString("invalid json"))
+	w := httptest.NewRecorder()
+
+	handler.CreateTestOrder(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestTestOrderHandler_CreateTestOrder_MissingFields(t *testing.T) {
+	handler := NewTestOrderHandler(nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/tests", bytes.NewBufferString(`{"test_type":"alphapath_panel"}`))
+	w := httptest.NewRecorder()
+
+	handler.CreateTestOrder(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestTestOrderHandler_ChangeStatus_CancelRequiresReason(t *testing.T) {
+	handler := NewTestOrderHandler(nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/tests/4/status", bytes.NewBufferString(`{"status":"cancelled"}`))
+	w := httptest.NewRecorder()
+
+	handler.ChangeStatus(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestTestOrderHandler_ChangeStatus_SetByResult(t *testing.T) {
+	handler := NewTestOrderHandler(nil)
+
+	for _, status := range []string{"resulted", "verified"} {
+		req := httptest.NewRequest(http.MethodPost, "/api/tests/4/status", bytes.NewBufferString(`{"status":"`+status+`"}`))
+		w := httptest.NewRecorder()
+
+		handler.ChangeStatus(w, req)
+
+		if w.Code != http.StatusConflict {
+			t.Errorf("%s: expected status %d, got %d", status, http.StatusConflict, w.Code)
+		}
+	}
+}
+
+func TestTestOrderHandler_GetTestOrders_InvalidStatus(t *testing.T) {
+	handler := NewTestOrderHandler(nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/tests?status=shipped", nil)
+	w := httptest.NewRecorder()
+
+	handler.GetTestOrders(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestTestOrderHandler_GetTestOrders_InvalidQuery(t *testing.T) {
+	handler := NewTestOrderHandler(nil)
+
+	for _, query := range []string{"sort=notes", "limit=0", "patient_id=abc", "ordered_after=yesterday", "cursor=bogus", "offset=50"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/tests?"+query, nil)
+		w := httptest.NewRecorder()
+
+		handler.GetTestOrders(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", query, http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
+func TestValidateTestOrder(t *testing.T) {
+	order := models.TestOrder{PatientMRN: " 12345 ", TestType: "alphapath_panel"}
+	if err := validateTestOrder(&order); err != nil {
+		t.Fatalf("Expected valid order, got %v", err)
+	}
+
+	if order.Priority != models.TestOrderRoutine || order.PatientMRN != "12345" {
+		t.Errorf("Expected defaults and trimming, got %+v", order)
+	}
+
+	order.Priority = "whenever"
+	if err := validateTestOrder(&order); err == nil {
+		t.Error("Expected invalid priority to be rejected")
+	}
+}
+
+func TestValidateTestOrder_PatientID(t *testing.T) {
+	patientID := 7
+	order := models.TestOrder{PatientID: &patientID, TestType: "alphapath_panel"}
+	if err := validateTestOrder(&order); err != nil {
+		t.Errorf("Expected an order naming only patient_id to be valid, got %v", err)
+	}
+}
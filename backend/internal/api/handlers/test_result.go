@@ -0,0 +1,414 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"backend/internal/api/problem"
+	"backend/internal/audit"
+	"backend/internal/auth"
+	"backend/internal/hl7"
+	"backend/internal/logging"
+	"backend/internal/models"
+)
+
+// Problem codes for result conflicts
+const (
+	codeResultExists      = "result_exists"
+	codeResultVerified    = "result_verified"
+	codeResultNotVerified = "result_not_verified"
+)
+
+// TestResultHandler handles HTTP requests for test results and their amendments
+type TestResultHandler struct {
+	db         *sql.DB
+	orderRepo  *models.TestOrderRepository
+	resultRepo *models.TestResultRepository
+	auditLog   *audit.Logger
+	hl7Outbox  *hl7.Outbox
+}
+
+// NewTestResultHandler creates a new test result handler. Verified results
+// are reported as HL7 ORU^R01 messages through outbox, if it is not nil.
+func NewTestResultHandler(db *sql.DB, outbox *hl7.Outbox) *TestResultHandler {
+	return &TestResultHandler{
+		db:         db,
+		orderRepo:  models.NewTestOrderRepository(db),
+		resultRepo: models.NewTestResultRepository(db),
+		auditLog:   newAuditLogger(db),
+		hl7Outbox:  outbox,
+	}
+}
+
+// TestResultRequest represents the body used to record or correct a result
+type TestResultRequest struct {
+	Findings               []models.Finding `json:"findings"`
+	Interpretation         string           `json:"interpretation"`
+	ReportingPathologistID *int             `json:"reporting_pathologist_id"`
+	ModelOutput            json.RawMessage  `json:"model_output"`
+	Reason                 string           `json:"reason"`
+}
+
+// TestResultHistory is the response of GET /api/results/{accession}
+type TestResultHistory struct {
+	AccessionNumber string              `json:"accession_number"`
+	TestOrderID     int                 `json:"test_order_id"`
+	Current         models.TestResult   `json:"current"`
+	Versions        []models.TestResult `json:"versions"`
+}
+
+// GetResult handles GET /api/results/{accession}
+func (h *TestResultHandler) GetResult(w http.ResponseWriter, r *http.Request) {
+	order, ok := h.loadOrder(w, r)
+	if !ok {
+		return
+	}
+
+	versions, err := h.resultRepo.GetHistory(r.Context(), order.ID)
+	if err != nil {
+		problem.Internal(w, r, "Failed to get test result", err)
+		return
+	}
+
+	if len(versions) == 0 {
+		problem.Error(w, r, http.StatusNotFound, "No result recorded for this accession")
+		return
+	}
+
+	if !auditRead(w, r, h.auditLog, audit.Event{
+		Action:       "test_result.read",
+		ResourceType: "test_result",
+		ResourceID:   order.AccessionNumber,
+		Details:      map[string]int{"versions": len(versions)},
+	}) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(TestResultHistory{
+		AccessionNumber: order.AccessionNumber,
+		TestOrderID:     order.ID,
+		Current:         versions[len(versions)-1],
+		Versions:        versions,
+	})
+}
+
+// CreateResult handles POST /api/results/{accession}, recording version 1
+func (h *TestResultHandler) CreateResult(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeTestResultRequest(w, r)
+	if !ok {
+		return
+	}
+
+	order, ok := h.loadOrder(w, r)
+	if !ok {
+		return
+	}
+
+	if order.Status != models.TestOrderInAnalysis && order.Status != models.TestOrderResulted {
+		problem.ErrorCode(w, r, http.StatusConflict, codeInvalidTransition, "Results can only be recorded for test orders in analysis")
+		return
+	}
+
+	result := models.TestResult{
+		TestOrderID:            order.ID,
+		Findings:               req.Findings,
+		Interpretation:         req.Interpretation,
+		ReportingPathologistID: req.ReportingPathologistID,
+		ModelOutput:            req.ModelOutput,
+		CreatedBy:              callerID(r),
+	}
+
+	err := audit.Audited(r.Context(), h.db, func(tx *sql.Tx) (audit.Event, error) {
+		if err := models.NewTestResultRepository(tx).Create(r.Context(), &result); err != nil {
+			return audit.Event{}, err
+		}
+		return audit.Event{
+			Action:       "test_result.create",
+			ResourceType: "test_result",
+			ResourceID:   order.AccessionNumber,
+			After:        result,
+			Details:      map[string]models.TestOrderStatus{"order_status": models.TestOrderResulted},
+		}, nil
+	})
+	if err != nil {
+		if errors.Is(err, models.ErrResultExists) {
+			problem.ErrorCode(w, r, http.StatusConflict, codeResultExists, "A result is already recorded; update it or submit an amendment")
+			return
+		}
+		if errors.Is(err, models.ErrOrderNotReady) {
+			problem.ErrorCode(w, r, http.StatusConflict, codeInvalidTransition, "Results can only be recorded for test orders in analysis")
+			return
+		}
+		problem.Internal(w, r, "Failed to create test result", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(result)
+}
+
+// UpdateResult handles PUT /api/results/{accession}, editing the current
+// version while it is unverified
+func (h *TestResultHandler) UpdateResult(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeTestResultRequest(w, r)
+	if !ok {
+		return
+	}
+
+	current, ok := h.loadCurrent(w, r)
+	if !ok {
+		return
+	}
+
+	if current.Verified() {
+		problem.ErrorCode(w, r, http.StatusConflict, codeResultVerified, "Verified results cannot be changed; submit an amendment")
+		return
+	}
+
+	before := *current
+	current.Findings = req.Findings
+	current.Interpretation = req.Interpretation
+	current.ReportingPathologistID = req.ReportingPathologistID
+	current.ModelOutput = req.ModelOutput
+
+	err := audit.Audited(r.Context(), h.db, func(tx *sql.Tx) (audit.Event, error) {
+		if err := models.NewTestResultRepository(tx).Update(r.Context(), current); err != nil {
+			return audit.Event{}, err
+		}
+		return audit.Event{
+			Action:       "test_result.update",
+			ResourceType: "test_result",
+			ResourceID:   parseAccession(r.URL.Path),
+			Before:       before,
+			After:        current,
+		}, nil
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			problem.ErrorCode(w, r, http.StatusConflict, codeResultVerified, "Verified results cannot be changed; submit an amendment")
+			return
+		}
+		problem.Internal(w, r, "Failed to update test result", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(current)
+}
+
+// VerifyResult handles POST /api/results/{accession}/verify, signing out the
+// current version
+func (h *TestResultHandler) VerifyResult(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		problem.Error(w, r, http.StatusUnauthorized, "Not authenticated")
+		return
+	}
+
+	current, ok := h.loadCurrent(w, r)
+	if !ok {
+		return
+	}
+
+	var verified *models.TestResult
+	err := audit.Audited(r.Context(), h.db, func(tx *sql.Tx) (audit.Event, error) {
+		var err error
+		if verified, err = models.NewTestResultRepository(tx).Verify(r.Context(), current.ID, user.ID); err != nil {
+			return audit.Event{}, err
+		}
+		return audit.Event{
+			Action:       "test_result.verify",
+			ResourceType: "test_result",
+			ResourceID:   parseAccession(r.URL.Path),
+			Before:       current,
+			After:        verified,
+			Details:      map[string]models.TestOrderStatus{"order_status": models.TestOrderVerified},
+		}, nil
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			problem.ErrorCode(w, r, http.StatusConflict, codeResultVerified, "Result is already verified")
+			return
+		}
+		if errors.Is(err, models.ErrOrderNotReady) {
+			problem.ErrorCode(w, r, http.StatusConflict, codeInvalidTransition, "Only results of resulted test orders can be verified")
+			return
+		}
+		problem.Internal(w, r, "Failed to verify test result", err)
+		return
+	}
+
+	// The result is signed out either way; a report that cannot be queued
+	// is logged for follow-up rather than failing the request
+	if h.hl7Outbox != nil {
+		if err := h.hl7Outbox.QueueResult(r.Context(), verified); err != nil {
+			logging.FromContext(r.Context()).Error("Failed to queue HL7 result", "accession", parseAccession(r.URL.Path), "error", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(verified)
+}
+
+// AmendResult handles POST /api/results/{accession}/amendments. The current
+// version must be verified; the correction becomes a new version.
+func (h *TestResultHandler) AmendResult(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeTestResultRequest(w, r)
+	if !ok {
+		return
+	}
+
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		problem.Error(w, r, http.StatusBadRequest, "A reason is required to amend a result")
+		return
+	}
+
+	current, ok := h.loadCurrent(w, r)
+	if !ok {
+		return
+	}
+
+	if !current.Verified() {
+		problem.ErrorCode(w, r, http.StatusConflict, codeResultNotVerified, "Only verified results can be amended; update the unverified version instead")
+		return
+	}
+
+	amendment := models.TestResult{
+		TestOrderID:            current.TestOrderID,
+		Findings:               req.Findings,
+		Interpretation:         req.Interpretation,
+		ReportingPathologistID: req.ReportingPathologistID,
+		ModelOutput:            req.ModelOutput,
+		AmendmentReason:        req.Reason,
+		CreatedBy:              callerID(r),
+	}
+
+	err := audit.Audited(r.Context(), h.db, func(tx *sql.Tx) (audit.Event, error) {
+		if err := models.NewTestResultRepository(tx).Amend(r.Context(), &amendment); err != nil {
+			return audit.Event{}, err
+		}
+		return audit.Event{
+			Action:       "test_result.amend",
+			ResourceType: "test_result",
+			ResourceID:   parseAccession(r.URL.Path),
+			Before:       current,
+			After:        amendment,
+		}, nil
+	})
+	if err != nil {
+		if err == sql.ErrNoRows || errors.Is(err, models.ErrResultConflict) {
+			problem.ErrorCode(w, r, http.StatusConflict, codeConcurrentUpdate, "Result changed concurrently; reload and retry")
+			return
+		}
+		problem.Internal(w, r, "Failed to amend test result", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(amendment)
+}
+
+// loadOrder resolves the accession number in the path, writing the error
+// response and returning false if it cannot
+func (h *TestResultHandler) loadOrder(w http.ResponseWriter, r *http.Request) (*models.TestOrder, bool) {
+	accession := parseAccession(r.URL.Path)
+	if accession == "" {
+		problem.Error(w, r, http.StatusBadRequest, "Invalid accession number")
+		return nil, false
+	}
+
+	order, err := h.orderRepo.GetByAccession(r.Context(), accession)
+	if err != nil {
+		problem.Internal(w, r, "Failed to get test order", err)
+		return nil, false
+	}
+
+	if order == nil {
+		problem.Error(w, r, http.StatusNotFound, "Test order not found")
+		return nil, false
+	}
+
+	return order, true
+}
+
+// loadCurrent resolves the current result version for the accession in the path
+func (h *TestResultHandler) loadCurrent(w http.ResponseWriter, r *http.Request) (*models.TestResult, bool) {
+	order, ok := h.loadOrder(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	current, err := h.resultRepo.GetLatest(r.Context(), order.ID)
+	if err != nil {
+		problem.Internal(w, r, "Failed to get test result", err)
+		return nil, false
+	}
+
+	if current == nil {
+		problem.Error(w, r, http.StatusNotFound, "No result recorded for this accession")
+		return nil, false
+	}
+
+	return current, true
+}
+
+// decodeTestResultRequest decodes and validates a result body, writing the
+// error response and returning false if it is invalid
+func decodeTestResultRequest(w http.ResponseWriter, r *http.Request) (*TestResultRequest, bool) {
+	var req TestResultRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		problem.InvalidJSON(w, r)
+		return nil, false
+	}
+
+	if err := validateTestResult(&req); err != nil {
+		problem.Invalid(w, r, err)
+		return nil, false
+	}
+
+	return &req, true
+}
+
+// validateTestResult checks that a result has content and well-formed findings
+func validateTestResult(req *TestResultRequest) error {
+	req.Interpretation = strings.TrimSpace(req.Interpretation)
+
+	if len(req.Findings) == 0 && req.Interpretation == "" {
+		return problem.Field("findings", problem.FieldRequired, "findings or interpretation is required")
+	}
+
+	for i, finding := range req.Findings {
+		if strings.TrimSpace(finding.Code) == "" || strings.TrimSpace(finding.Value) == "" {
+			return problem.Field(fmt.Sprintf("findings[%d]", i), problem.FieldRequired,
+				fmt.Sprintf("findings[%d]: code and value are required", i))
+		}
+	}
+
+	if string(req.ModelOutput) == "null" {
+		req.ModelOutput = nil
+	}
+
+	return nil
+}
+
+// parseAccession extracts the accession number from /api/results/{accession}[/...]
+func parseAccession(path string) string {
+	accession, _, _ := strings.Cut(strings.TrimPrefix(path, "/api/results/"), "/")
+	return accession
+}
+
+// callerID returns the signed-in user's ID, if any
+func callerID(r *http.Request) *int {
+	if user, ok := auth.UserFromContext(r.Context()); ok {
+		return &user.ID
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"backend/internal/models"
+)
+
+func TestTestResultHandler_CreateResult_InvalidJSON(t *testing.T) {
+	handler := NewTestResultHandler(nil, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/results/AP26-000001", bytes.NewBufferString("invalid json"))
+	w := httptest.NewRecorder()
+
+	handler.CreateResult(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestTestResultHandler_CreateResult_EmptyResult(t *testing.T) {
+	handler := NewTestResultHandler(nil, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/results/AP26-000001", bytes.NewBufferString(`{"interpretation":"  "}`))
+	w := httptest.NewRecorder()
+
+	handler.CreateResult(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestTestResultHandler_AmendResult_RequiresReason(t *testing.T) {
+	handler := NewTestResultHandler(nil, nil)
+
+	body := `{"findings":[{"code":"AP-1","name":"Marker","value":"positive"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/results/AP26-000001/amendments", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+
+	handler.AmendResult(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestValidateTestResult(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    TestResultRequest
+		wantErr bool
+	}{
+		{"interpretation only", TestResultRequest{Interpretation: "No malignancy identified"}, false},
+		{"finding", TestResultRequest{Findings: findings("AP-1", "positive")}, false},
+		{"empty", TestResultRequest{}, true},
+		{"finding without value", TestResultRequest{Findings: findings("AP-1", "")}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTestResult(&tt.body)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestParseAccession(t *testing.T) {
+	if got := parseAccession("/api/results/AP26-000123/amendments"); got != "AP26-000123" {
+		t.Errorf("Expected AP26-000123, got %q", got)
+	}
+}
+
+func findings(code, value string) []models.Finding {
+	return []models.Finding{{Code: code, Name: "Marker", Value: value}}
+}
@@ -0,0 +1,446 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/api/problem"
+	"backend/internal/audit"
+	"backend/internal/auth"
+	"backend/internal/logging"
+	"backend/internal/models"
+	"backend/internal/storage"
+)
+
+const (
+	// tusVersion is the tus resumable upload protocol version implemented here
+	tusVersion = "1.0.0"
+
+	// statusChecksumMismatch is the tus checksum extension's 460 status
+	statusChecksumMismatch = 460
+)
+
+// sha256Pattern matches a hex-encoded SHA-256 digest
+var sha256Pattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// UploadHandler implements resumable uploads using the tus 1.0 protocol with
+// the creation, checksum and termination extensions
+type UploadHandler struct {
+	uploadRepo   *models.UploadRepository
+	grantRepo    *models.SlideGrantRepository
+	uploader     *storage.Uploader
+	maxSize      int64
+	chunkTimeout time.Duration
+	auditLog     *audit.Logger
+}
+
+// NewUploadHandler creates a new upload handler. Uploads may be up to maxSize
+// bytes; each chunk request may take up to chunkTimeout.
+func NewUploadHandler(db *sql.DB, blobs storage.BlobStore, maxSize int64, chunkTimeout time.Duration) *UploadHandler {
+	uploadRepo := models.NewUploadRepository(db)
+	return &UploadHandler{
+		uploadRepo:   uploadRepo,
+		grantRepo:    models.NewSlideGrantRepository(db),
+		uploader:     storage.NewUploader(blobs, uploadRepo),
+		maxSize:      maxSize,
+		chunkTimeout: chunkTimeout,
+		auditLog:     newAuditLogger(db),
+	}
+}
+
+// CreateUpload handles POST /api/uploads. The Upload-Length header gives the
+// file size; Upload-Metadata must include the accession and may include
+// filename, filetype and a sha256 hex digest of the whole file.
+func (h *UploadHandler) CreateUpload(w http.ResponseWriter, r *http.Request) {
+	if !checkTusVersion(w, r) {
+		return
+	}
+
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		problem.Error(w, r, http.StatusUnauthorized, "Not authenticated")
+		return
+	}
+
+	size, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || size < 0 {
+		problem.Error(w, r, http.StatusBadRequest, "Upload-Length header is required")
+		return
+	}
+
+	if size > h.maxSize {
+		w.Header().Set("Tus-Max-Size", strconv.FormatInt(h.maxSize, 10))
+		problem.Error(w, r, http.StatusRequestEntityTooLarge, "Upload exceeds the maximum size")
+		return
+	}
+
+	metadata, err := parseUploadMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		problem.Invalid(w, r, err)
+		return
+	}
+
+	upload := models.Upload{
+		AccessionNumber: strings.TrimSpace(metadata["accession"]),
+		Filename:        metadata["filename"],
+		ContentType:     metadata["filetype"],
+		Size:            size,
+		ExpectedSHA256:  strings.ToLower(metadata["sha256"]),
+		OwnerID:         &user.ID,
+	}
+
+	if err := validateUpload(&upload); err != nil {
+		problem.Invalid(w, r, err)
+		return
+	}
+
+	if err := h.uploadRepo.Create(r.Context(), &upload); err != nil {
+		problem.Internal(w, r, "Failed to create upload", err)
+		return
+	}
+
+	recordAudit(r, h.auditLog, audit.Event{
+		Action:       "upload.create",
+		ResourceType: "upload",
+		ResourceID:   strconv.Itoa(upload.ID),
+		After:        upload,
+	})
+
+	// An empty file is complete as soon as it is created
+	if upload.Size == 0 {
+		if err := h.uploader.Finalize(r.Context(), &upload); err != nil {
+			h.writeChunkError(w, r, err)
+			return
+		}
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/uploads/%d", upload.ID))
+	w.WriteHeader(http.StatusCreated)
+}
+
+// HeadUpload handles HEAD /api/uploads/{id}, reporting the offset to resume from
+func (h *UploadHandler) HeadUpload(w http.ResponseWriter, r *http.Request) {
+	if !checkTusVersion(w, r) {
+		return
+	}
+
+	upload, ok := h.loadOwnUpload(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(upload.Size, 10))
+	w.WriteHeader(http.StatusOK)
+}
+
+// PatchUpload handles PATCH /api/uploads/{id}, appending a chunk at Upload-Offset
+func (h *UploadHandler) PatchUpload(w http.ResponseWriter, r *http.Request) {
+	if !checkTusVersion(w, r) {
+		return
+	}
+
+	if r.Header.Get("Content-Type") != "application/offset+octet-stream" {
+		problem.Error(w, r, http.StatusUnsupportedMediaType, "Content-Type must be application/offset+octet-stream")
+		return
+	}
+
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		problem.Error(w, r, http.StatusBadRequest, "Upload-Offset header is required")
+		return
+	}
+
+	checksum, err := parseUploadChecksum(r.Header.Get("Upload-Checksum"))
+	if err != nil {
+		problem.Invalid(w, r, err)
+		return
+	}
+
+	upload, ok := h.loadOwnUpload(w, r)
+	if !ok {
+		return
+	}
+
+	// Chunks of a multi-gigabyte file outlast the server's default timeouts
+	rc := http.NewResponseController(w)
+	deadline := time.Now().Add(h.chunkTimeout)
+	if err := rc.SetReadDeadline(deadline); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		logging.FromContext(r.Context()).Warn("Failed to extend upload read deadline", "error", err)
+	}
+	if err := rc.SetWriteDeadline(deadline); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		logging.FromContext(r.Context()).Warn("Failed to extend upload write deadline", "error", err)
+	}
+
+	// A complete upload acknowledges a retried final chunk
+	if upload.Status == models.UploadComplete && offset == upload.Size {
+		w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Size, 10))
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if err := h.uploader.WriteChunk(r.Context(), upload, offset, r.Body, checksum); err != nil {
+		h.writeChunkError(w, r, err)
+		return
+	}
+
+	// Individual chunks are not audited, only the finished file
+	if upload.Status == models.UploadComplete {
+		recordAudit(r, h.auditLog, audit.Event{
+			Action:       "upload.complete",
+			ResourceType: "upload",
+			ResourceID:   strconv.Itoa(upload.ID),
+			Details:      map[string]interface{}{"size": upload.Size, "sha256": upload.SHA256},
+		})
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteUpload handles DELETE /api/uploads/{id}, abandoning an unfinished upload
+func (h *UploadHandler) DeleteUpload(w http.ResponseWriter, r *http.Request) {
+	if !checkTusVersion(w, r) {
+		return
+	}
+
+	upload, ok := h.loadOwnUpload(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.uploadRepo.Delete(r.Context(), upload.ID); err != nil {
+		if err == sql.ErrNoRows {
+			problem.Error(w, r, http.StatusConflict, "Completed uploads cannot be deleted")
+			return
+		}
+		problem.Internal(w, r, "Failed to delete upload", err)
+		return
+	}
+
+	h.uploader.Abort(upload)
+
+	recordAudit(r, h.auditLog, audit.Event{
+		Action:       "upload.delete",
+		ResourceType: "upload",
+		ResourceID:   strconv.Itoa(upload.ID),
+		Before:       upload,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetUpload handles GET /api/uploads/{id}. Callers without slides:read_any
+// only see uploads whose slide they may view.
+func (h *UploadHandler) GetUpload(w http.ResponseWriter, r *http.Request) {
+	id, err := parseUploadID(r.URL.Path)
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, "Invalid upload ID")
+		return
+	}
+
+	upload, err := h.uploadRepo.GetByID(r.Context(), id)
+	if err != nil {
+		problem.Internal(w, r, "Failed to get upload", err)
+		return
+	}
+
+	if upload == nil {
+		problem.Error(w, r, http.StatusNotFound, "Upload not found")
+		return
+	}
+
+	if !checkSlideAccess(w, r, h.grantRepo, upload) {
+		return
+	}
+
+	if !auditRead(w, r, h.auditLog, audit.Event{
+		Action:       "upload.read",
+		ResourceType: "upload",
+		ResourceID:   strconv.Itoa(upload.ID),
+	}) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(upload)
+}
+
+// GetUploads handles GET /api/uploads?accession=..., listing completed
+// uploads. Callers without slides:read_any only see uploads whose slide they
+// may view.
+func (h *UploadHandler) GetUploads(w http.ResponseWriter, r *http.Request) {
+	accession := strings.TrimSpace(r.URL.Query().Get("accession"))
+	if accession == "" {
+		problem.Error(w, r, http.StatusBadRequest, "accession query parameter is required")
+		return
+	}
+
+	var uploads []models.Upload
+	var err error
+	if access, _ := auth.AccessFromContext(r.Context()); access.Has(auth.PermissionSlidesReadAny) {
+		uploads, err = h.uploadRepo.GetByAccession(r.Context(), accession)
+	} else {
+		user, ok := auth.UserFromContext(r.Context())
+		if !ok {
+			problem.Error(w, r, http.StatusUnauthorized, "Not authenticated")
+			return
+		}
+		uploads, err = h.uploadRepo.GetViewableByAccession(r.Context(), accession, user.ID)
+	}
+	if err != nil {
+		problem.Internal(w, r, "Failed to get uploads", err)
+		return
+	}
+
+	ids := make([]int, len(uploads))
+	for i, upload := range uploads {
+		ids[i] = upload.ID
+	}
+	if !auditRead(w, r, h.auditLog, audit.Event{
+		Action:       "upload.list",
+		ResourceType: "upload",
+		Details:      map[string]interface{}{"accession": accession, "ids": ids},
+	}) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(uploads)
+}
+
+// loadOwnUpload loads the upload in the path and checks that the caller
+// started it, writing the error response and returning false otherwise
+func (h *UploadHandler) loadOwnUpload(w http.ResponseWriter, r *http.Request) (*models.Upload, bool) {
+	id, err := parseUploadID(r.URL.Path)
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, "Invalid upload ID")
+		return nil, false
+	}
+
+	upload, err := h.uploadRepo.GetByID(r.Context(), id)
+	if err != nil {
+		problem.Internal(w, r, "Failed to get upload", err)
+		return nil, false
+	}
+
+	if upload == nil {
+		problem.Error(w, r, http.StatusNotFound, "Upload not found")
+		return nil, false
+	}
+
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok || upload.OwnerID == nil || *upload.OwnerID != user.ID {
+		problem.Error(w, r, http.StatusForbidden, "Only the user who started an upload can access it")
+		return nil, false
+	}
+
+	if upload.Status == models.UploadFailed {
+		problem.Error(w, r, http.StatusGone, "Upload failed: "+upload.Error)
+		return nil, false
+	}
+
+	return upload, true
+}
+
+// writeChunkError maps an Uploader error to a tus response
+func (h *UploadHandler) writeChunkError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, storage.ErrOffsetMismatch), errors.Is(err, storage.ErrUploadClosed):
+		problem.Error(w, r, http.StatusConflict, "Upload-Offset does not match the current offset")
+	case errors.Is(err, storage.ErrUploadTooLarge):
+		problem.Error(w, r, http.StatusRequestEntityTooLarge, "Chunk exceeds Upload-Length")
+	case errors.Is(err, storage.ErrChecksumMismatch):
+		problem.ErrorCode(w, r, statusChecksumMismatch, "checksum_mismatch", "Checksum mismatch")
+	default:
+		problem.Internal(w, r, "Failed to store upload", err)
+	}
+}
+
+// checkTusVersion sets the Tus-Resumable response header and rejects
+// clients speaking another protocol version
+func checkTusVersion(w http.ResponseWriter, r *http.Request) bool {
+	w.Header().Set("Tus-Resumable", tusVersion)
+
+	if r.Header.Get("Tus-Resumable") != tusVersion {
+		w.Header().Set("Tus-Version", tusVersion)
+		problem.Error(w, r, http.StatusPreconditionFailed, "Unsupported tus protocol version")
+		return false
+	}
+	return true
+}
+
+// validateUpload checks the metadata supplied when an upload is created
+func validateUpload(upload *models.Upload) error {
+	if upload.AccessionNumber == "" {
+		return problem.Field("accession", problem.FieldRequired, "Upload-Metadata must include accession")
+	}
+	if len(upload.AccessionNumber) > 64 || len(upload.Filename) > 255 || len(upload.ContentType) > 128 {
+		return problem.Field("Upload-Metadata", problem.FieldTooLong, "Upload-Metadata value too long")
+	}
+	if upload.ExpectedSHA256 != "" && !sha256Pattern.MatchString(upload.ExpectedSHA256) {
+		return problem.Field("sha256", problem.FieldInvalid, "sha256 metadata must be a hex-encoded SHA-256 digest")
+	}
+	if upload.ContentType == "" {
+		upload.ContentType = "application/octet-stream"
+	}
+	return nil
+}
+
+// parseUploadMetadata decodes the tus Upload-Metadata header: comma-separated
+// pairs of a key and a base64-encoded value
+func parseUploadMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	if strings.TrimSpace(header) == "" {
+		return metadata, nil
+	}
+
+	for _, pair := range strings.Split(header, ",") {
+		key, encoded, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, errors.New("invalid Upload-Metadata header")
+		}
+
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Upload-Metadata value for %s", key)
+		}
+		metadata[key] = string(value)
+	}
+
+	return metadata, nil
+}
+
+// parseUploadChecksum decodes an Upload-Checksum header ("sha256 <base64>").
+// A missing header returns nil.
+func parseUploadChecksum(header string) ([]byte, error) {
+	if header == "" {
+		return nil, nil
+	}
+
+	algorithm, encoded, _ := strings.Cut(header, " ")
+	if algorithm != "sha256" {
+		return nil, errors.New("Upload-Checksum algorithm must be sha256")
+	}
+
+	checksum, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(checksum) != 32 {
+		return nil, errors.New("invalid Upload-Checksum value")
+	}
+
+	return checksum, nil
+}
+
+// parseUploadID extracts the ID from /api/uploads/{id}
+func parseUploadID(path string) (int, error) {
+	return strconv.Atoi(strings.TrimPrefix(path, "/api/uploads/"))
+}
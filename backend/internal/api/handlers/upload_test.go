@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"backend/internal/auth"
+	"backend/internal/models"
+)
+
+func newUploadRequest(method, path string) *http.Request {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Tus-Resumable", tusVersion)
+	return req.WithContext(auth.WithUser(req.Context(), &models.User{ID: 1}))
+}
+
+func TestUploadHandler_CreateUpload_UnsupportedVersion(t *testing.T) {
+	handler := NewUploadHandler(nil, nil, 1<<20, time.Minute)
+
+	req := newUploadRequest(http.MethodPost, "/api/uploads")
+	req.Header.Del("Tus-Resumable")
+	w := httptest.NewRecorder()
+
+	handler.CreateUpload(w, req)
+
+	if w.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected status %d, got %d", http.StatusPreconditionFailed, w.Code)
+	}
+	if w.Header().Get("Tus-Version") != tusVersion {
+		t.Errorf("Expected Tus-Version header %s, got %q", tusVersion, w.Header().Get("Tus-Version"))
+	}
+}
+
+func TestUploadHandler_CreateUpload_MissingLength(t *testing.T) {
+	handler := NewUploadHandler(nil, nil, 1<<20, time.Minute)
+
+	req := newUploadRequest(http.MethodPost, "/api/uploads")
+	w := httptest.NewRecorder()
+
+	handler.CreateUpload(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestUploadHandler_CreateUpload_TooLarge(t *testing.T) {
+	handler := NewUploadHandler(nil, nil, 1<<20, time.Minute)
+
+	req := newUploadRequest(http.MethodPost, "/api/uploads")
+	req.Header.Set("Upload-Length", "2097152")
+	w := httptest.NewRecorder()
+
+	handler.CreateUpload(w, req)
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status %d, got %d", http.StatusRequestEntityTooLarge, w.Code)
+	}
+}
+
+func TestUploadHandler_CreateUpload_InvalidMetadata(t *testing.T) {
+	handler := NewUploadHandler(nil, nil, 1<<20, time.Minute)
+
+	tests := []struct {
+		name     string
+		metadata string
+	}{
+		{"missing accession", "filename c2xpZGUuc3Zz"},
+		{"invalid base64", "accession !!!"},
+		{"invalid checksum", "accession QVAtMjAyNi0wMDE=,sha256 bm90LWEtZGlnZXN0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newUploadRequest(http.MethodPost, "/api/uploads")
+			req.Header.Set("Upload-Length", "100")
+			req.Header.Set("Upload-Metadata", tt.metadata)
+			w := httptest.NewRecorder()
+
+			handler.CreateUpload(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+			}
+		})
+	}
+}
+
+func TestUploadHandler_PatchUpload_WrongContentType(t *testing.T) {
+	handler := NewUploadHandler(nil, nil, 1<<20, time.Minute)
+
+	req := newUploadRequest(http.MethodPatch, "/api/uploads/1")
+	req.Body = http.NoBody
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Upload-Offset", "0")
+	w := httptest.NewRecorder()
+
+	handler.PatchUpload(w, req)
+
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected status %d, got %d", http.StatusUnsupportedMediaType, w.Code)
+	}
+}
+
+func TestUploadHandler_PatchUpload_InvalidChecksum(t *testing.T) {
+	handler := NewUploadHandler(nil, nil, 1<<20, time.Minute)
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/uploads/1", bytes.NewBufferString("data"))
+	req.Header.Set("Tus-Resumable", tusVersion)
+	req.Header.Set("Content-Type", "application/offset+octet-stream")
+	req.Header.Set("Upload-Offset", "0")
+	req.Header.Set("Upload-Checksum", "md5 Zm9v")
+	w := httptest.NewRecorder()
+
+	handler.PatchUpload(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestUploadHandler_GetUploads_MissingAccession(t *testing.T) {
+	handler := NewUploadHandler(nil, nil, 1<<20, time.Minute)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/uploads", nil)
+	w := httptest.NewRecorder()
+
+	handler.GetUploads(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestUploadHandler_GetUploads_NotAuthenticated(t *testing.T) {
+	handler := NewUploadHandler(nil, nil, 1<<20, time.Minute)
+
+	// Without slides:read_any the listing is filtered by the caller, so one is needed
+	req := httptest.NewRequest(http.MethodGet, "/api/uploads?accession=ACC-1", nil)
+	req = req.WithContext(auth.WithAccess(req.Context(), auth.NewAccess([]string{"viewer"}, []string{"slides:read"})))
+	w := httptest.NewRecorder()
+
+	handler.GetUploads(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
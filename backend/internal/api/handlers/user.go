@@ -3,36 +3,69 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
 
+	"backend/internal/api/problem"
+	"backend/internal/audit"
+	"backend/internal/auth"
+	"backend/internal/listing"
 	"backend/internal/models"
 )
 
+// codeEmailExists is the problem code for an email already used by another user
+const codeEmailExists = "email_exists"
+
+// codeUserInUse is the problem code for deleting a user named on test results
+const codeUserInUse = "user_in_use"
+
 // UserHandler handles HTTP requests for user operations
 type UserHandler struct {
+	db       *sql.DB
 	userRepo *models.UserRepository
+	auditLog *audit.Logger
 }
 
 // NewUserHandler creates a new user handler
 func NewUserHandler(db *sql.DB) *UserHandler {
 	return &UserHandler{
+		db:       db,
 		userRepo: models.NewUserRepository(db),
+		auditLog: newAuditLogger(db),
 	}
 }
 
-// GetUsers handles GET /api/users
+// GetUsers handles GET /api/users. It returns one page of users; see
+// models.UserList for the accepted sort fields and filters.
 func (h *UserHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
-	users, err := h.userRepo.GetAll()
+	q, err := models.UserList.Parse(r.URL.Query())
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to get users: %v", err), http.StatusInternalServerError)
+		problem.Invalid(w, r, err)
+		return
+	}
+
+	users, total, err := h.userRepo.List(r.Context(), q)
+	if err != nil {
+		problem.Internal(w, r, "Failed to get users", err)
+		return
+	}
+
+	ids := make([]int, len(users))
+	for i, user := range users {
+		ids[i] = user.ID
+	}
+	if !auditRead(w, r, h.auditLog, audit.Event{
+		Action:       "user.list",
+		ResourceType: "user",
+		Details:      map[string]interface{}{"query": r.URL.Query(), "ids": ids},
+	}) {
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(users)
+	json.NewEncoder(w).Encode(listing.NewPage(users, q, models.User.ListKey).WithTotal(total))
 }
 
 // GetUser handles GET /api/users/{id}
@@ -41,18 +74,26 @@ func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 	idStr := strings.TrimPrefix(r.URL.Path, "/api/users/")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		problem.Error(w, r, http.StatusBadRequest, "Invalid user ID")
 		return
 	}
 
-	user, err := h.userRepo.GetByID(id)
+	user, err := h.userRepo.GetByID(r.Context(), id)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to get user: %v", err), http.StatusInternalServerError)
+		problem.Internal(w, r, "Failed to get user", err)
 		return
 	}
 
 	if user == nil {
-		http.Error(w, "User not found", http.StatusNotFound)
+		problem.Error(w, r, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if !auditRead(w, r, h.auditLog, audit.Event{
+		Action:       "user.read",
+		ResourceType: "user",
+		ResourceID:   strconv.Itoa(user.ID),
+	}) {
 		return
 	}
 
@@ -60,26 +101,58 @@ func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(user)
 }
 
+// createUserRequest is the body of POST /api/users. Password is optional;
+// accounts without one can only sign in through single sign-on.
+type createUserRequest struct {
+	models.User
+	Password string `json:"password"`
+}
+
 // CreateUser handles POST /api/users
 func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
-	var user models.User
-	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+	var req createUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		problem.InvalidJSON(w, r)
 		return
 	}
+	user := req.User
 
-	// Basic validation
-	if user.Name == "" || user.Email == "" {
-		http.Error(w, "Name and email are required", http.StatusBadRequest)
+	if err := validateUser(&user); err != nil {
+		problem.Invalid(w, r, err)
 		return
 	}
 
-	if err := h.userRepo.Create(&user); err != nil {
-		if strings.Contains(err.Error(), "duplicate") || strings.Contains(err.Error(), "unique") {
-			http.Error(w, "Email already exists", http.StatusConflict)
+	if req.Password != "" {
+		if err := auth.ValidatePassword(req.Password); err != nil {
+			problem.Invalid(w, r, problem.Field("password", problem.FieldInvalid, err.Error()))
+			return
+		}
+
+		hash, err := auth.HashPassword(req.Password)
+		if err != nil {
+			problem.Internal(w, r, "Failed to create user", err)
+			return
+		}
+		user.PasswordHash = hash
+	}
+
+	err := audit.Audited(r.Context(), h.db, func(tx *sql.Tx) (audit.Event, error) {
+		if err := models.NewUserRepository(tx).Create(r.Context(), &user); err != nil {
+			return audit.Event{}, err
+		}
+		return audit.Event{
+			Action:       "user.create",
+			ResourceType: "user",
+			ResourceID:   strconv.Itoa(user.ID),
+			After:        user,
+		}, nil
+	})
+	if err != nil {
+		if errors.Is(err, models.ErrEmailExists) {
+			problem.ErrorCode(w, r, http.StatusConflict, codeEmailExists, "Email already exists")
 			return
 		}
-		http.Error(w, fmt.Sprintf("Failed to create user: %v", err), http.StatusInternalServerError)
+		problem.Internal(w, r, "Failed to create user", err)
 		return
 	}
 
@@ -94,35 +167,57 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	idStr := strings.TrimPrefix(r.URL.Path, "/api/users/")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		problem.Error(w, r, http.StatusBadRequest, "Invalid user ID")
 		return
 	}
 
 	var user models.User
 	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		problem.InvalidJSON(w, r)
 		return
 	}
 
 	// Set the ID from URL
 	user.ID = id
 
-	// Basic validation
-	if user.Name == "" || user.Email == "" {
-		http.Error(w, "Name and email are required", http.StatusBadRequest)
+	if err := validateUser(&user); err != nil {
+		problem.Invalid(w, r, err)
 		return
 	}
 
-	if err := h.userRepo.Update(&user); err != nil {
+	before, err := h.userRepo.GetByID(r.Context(), id)
+	if err != nil {
+		problem.Internal(w, r, "Failed to get user", err)
+		return
+	}
+
+	if before == nil {
+		problem.Error(w, r, http.StatusNotFound, "User not found")
+		return
+	}
+
+	err = audit.Audited(r.Context(), h.db, func(tx *sql.Tx) (audit.Event, error) {
+		if err := models.NewUserRepository(tx).Update(r.Context(), &user); err != nil {
+			return audit.Event{}, err
+		}
+		return audit.Event{
+			Action:       "user.update",
+			ResourceType: "user",
+			ResourceID:   strconv.Itoa(id),
+			Before:       before,
+			After:        user,
+		}, nil
+	})
+	if err != nil {
 		if err == sql.ErrNoRows {
-			http.Error(w, "User not found", http.StatusNotFound)
+			problem.Error(w, r, http.StatusNotFound, "User not found")
 			return
 		}
-		if strings.Contains(err.Error(), "duplicate") || strings.Contains(err.Error(), "unique") {
-			http.Error(w, "Email already exists", http.StatusConflict)
+		if errors.Is(err, models.ErrEmailExists) {
+			problem.ErrorCode(w, r, http.StatusConflict, codeEmailExists, "Email already exists")
 			return
 		}
-		http.Error(w, fmt.Sprintf("Failed to update user: %v", err), http.StatusInternalServerError)
+		problem.Internal(w, r, "Failed to update user", err)
 		return
 	}
 
@@ -136,18 +231,60 @@ func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	idStr := strings.TrimPrefix(r.URL.Path, "/api/users/")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		problem.Error(w, r, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	user, err := h.userRepo.GetByID(r.Context(), id)
+	if err != nil {
+		problem.Internal(w, r, "Failed to get user", err)
 		return
 	}
 
-	if err := h.userRepo.Delete(id); err != nil {
+	if user == nil {
+		problem.Error(w, r, http.StatusNotFound, "User not found")
+		return
+	}
+
+	err = audit.Audited(r.Context(), h.db, func(tx *sql.Tx) (audit.Event, error) {
+		if err := models.NewUserRepository(tx).Delete(r.Context(), id); err != nil {
+			return audit.Event{}, err
+		}
+		return audit.Event{
+			Action:       "user.delete",
+			ResourceType: "user",
+			ResourceID:   strconv.Itoa(id),
+			Before:       user,
+		}, nil
+	})
+	if err != nil {
 		if err == sql.ErrNoRows {
-			http.Error(w, "User not found", http.StatusNotFound)
+			problem.Error(w, r, http.StatusNotFound, "User not found")
 			return
 		}
-		http.Error(w, fmt.Sprintf("Failed to delete user: %v", err), http.StatusInternalServerError)
+		if errors.Is(err, models.ErrUserInUse) {
+			problem.ErrorCode(w, r, http.StatusConflict, codeUserInUse,
+				"User is named on test results and cannot be deleted; revoke their roles instead")
+			return
+		}
+		problem.Internal(w, r, "Failed to delete user", err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// validateUser checks the fields required of every user
+func validateUser(user *models.User) error {
+	var errs problem.FieldErrors
+	if user.Name == "" {
+		errs = append(errs, problem.Field("name", problem.FieldRequired, "name is required"))
+	}
+	if user.Email == "" {
+		errs = append(errs, problem.Field("email", problem.FieldRequired, "email is required"))
+	}
+	if errs != nil {
+		return errs
+	}
+	return nil
+}
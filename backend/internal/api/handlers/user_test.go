@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"backend/internal/api/problem"
 	"backend/internal/models"
 )
 
@@ -59,3 +60,38 @@ func TestUserHandler_GetUser_InvalidID(t *testing.T) {
 		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
 	}
 }
+
+func TestUserHandler_GetUsers_InvalidQuery(t *testing.T) {
+	handler := NewUserHandler(nil)
+
+	for _, query := range []string{"sort=password_hash", "limit=0", "created_after=yesterday", "role=admin", "cursor=abc"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/users?"+query, nil)
+		w := httptest.NewRecorder()
+
+		handler.GetUsers(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", query, http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
+func TestUserHandler_CreateUser_FieldErrors(t *testing.T) {
+	handler := NewUserHandler(nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/users", bytes.NewBufferString(`{"name":""}`))
+	w := httptest.NewRecorder()
+
+	handler.CreateUser(w, req)
+
+	var p problem.Problem
+	if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+		t.Fatalf("Failed to decode problem: %v", err)
+	}
+	if w.Code != http.StatusBadRequest || p.Code != problem.CodeValidationFailed {
+		t.Fatalf("Expected a validation problem, got %d %+v", w.Code, p)
+	}
+	if len(p.Errors) != 2 || p.Errors[0].Field != "name" || p.Errors[1].Field != "email" {
+		t.Errorf("Expected name and email field errors, got %+v", p.Errors)
+	}
+}
@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"backend/internal/api/problem"
+	"backend/internal/auth"
+)
+
+// Authenticate resolves the session cookie and attaches the user, session and
+// the user's roles and permissions to the request context. Requests without a
+// valid session continue unauthenticated; Require or the handler decides
+// whether a user is needed.
+func Authenticate(sessions *auth.Manager, access auth.AccessStore) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Already authenticated by API key
+			if _, ok := auth.UserFromContext(r.Context()); ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			user, session, err := sessions.Resolve(w, r)
+			if err != nil {
+				problem.Internal(w, r, "Failed to resolve session", err)
+				return
+			}
+
+			if user != nil {
+				roles, permissions, err := access.GetUserAccess(r.Context(), user.ID)
+				if err != nil {
+					problem.Internal(w, r, "Failed to resolve session", fmt.Errorf("load permissions: %w", err))
+					return
+				}
+
+				ctx := auth.WithUser(r.Context(), user)
+				ctx = auth.WithSession(ctx, session)
+				ctx = auth.WithAccess(ctx, auth.NewAccess(roles, permissions))
+				r = identifyUser(r.WithContext(ctx), user.ID)
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// APIKey authenticates machine clients sending "Authorization: Bearer <key>".
+// Requests without an Authorization header pass through to session
+// authentication; a malformed, unknown, revoked or expired key is rejected.
+func APIKey(keys *auth.APIKeyAuthenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get("Authorization")
+			if header == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			scheme, token, ok := strings.Cut(header, " ")
+			if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="alphapath"`)
+				problem.Error(w, r, http.StatusUnauthorized, "Unsupported authorization scheme")
+				return
+			}
+
+			ctx, err := keys.Authenticate(r.Context(), strings.TrimSpace(token))
+			if err != nil {
+				if errors.Is(err, auth.ErrInvalidAPIKey) {
+					w.Header().Set("WWW-Authenticate", `Bearer realm="alphapath", error="invalid_token"`)
+					problem.Error(w, r, http.StatusUnauthorized, "Invalid API key")
+					return
+				}
+				problem.Internal(w, r, "Failed to authenticate API key", err)
+				return
+			}
+
+			r = r.WithContext(ctx)
+			if user, ok := auth.UserFromContext(ctx); ok {
+				r = identifyUser(r, user.ID)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Require rejects requests from callers lacking the permission: 401 when
+// there is no authenticated user, 403 when the user lacks the permission
+func Require(permission auth.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := auth.UserFromContext(r.Context()); !ok {
+				problem.Error(w, r, http.StatusUnauthorized, "Not authenticated")
+				return
+			}
+
+			if !auth.HasPermission(r.Context(), permission) {
+				problem.Error(w, r, http.StatusForbidden, "Forbidden")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"backend/internal/auth"
+	"backend/internal/models"
+)
+
+func TestRequire(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	protected := Require(auth.PermissionUsersDelete)(handler)
+
+	user := &models.User{ID: 1, Name: "Jane Doe", Email: "jane@example.com"}
+
+	tests := []struct {
+		name     string
+		user     *models.User
+		access   *auth.Access
+		expected int
+	}{
+		{"unauthenticated", nil, nil, http.StatusUnauthorized},
+		{"no roles", user, auth.NewAccess(nil, nil), http.StatusForbidden},
+		{"missing permission", user, auth.NewAccess([]string{"viewer"}, []string{"users:read"}), http.StatusForbidden},
+		{"granted", user, auth.NewAccess([]string{"admin"}, []string{"users:read", "users:delete"}), http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodDelete, "/api/users/2", nil)
+			ctx := req.Context()
+			if tt.user != nil {
+				ctx = auth.WithUser(ctx, tt.user)
+			}
+			if tt.access != nil {
+				ctx = auth.WithAccess(ctx, tt.access)
+			}
+			w := httptest.NewRecorder()
+
+			protected.ServeHTTP(w, req.WithContext(ctx))
+
+			if w.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+}
+
+func TestAPIKey_NoAuthorizationHeader(t *testing.T) {
+	called := false
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+	w := httptest.NewRecorder()
+
+	APIKey(nil)(handler).ServeHTTP(w, req)
+
+	if !called {
+		t.Error("Expected request without Authorization to reach the next handler")
+	}
+}
+
+func TestAPIKey_UnsupportedScheme(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Expected request to be rejected")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+	req.Header.Set("Authorization", "Basic dXNlcjpwYXNz")
+	w := httptest.NewRecorder()
+
+	APIKey(nil)(handler).ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+
+	if w.Header().Get("WWW-Authenticate") == "" {
+		t.Error("Expected WWW-Authenticate header")
+	}
+}
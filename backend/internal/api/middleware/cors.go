@@ -1,29 +1,242 @@
 package middleware
 
-import "net/http"
+import (
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
-// CORS adds CORS headers to the response with the specified frontend URL
-func CORS(frontendURL string) func(http.Handler) http.Handler {
+	"backend/internal/api/problem"
+)
+
+// CORSPolicy says which cross-origin requests browsers may make to a
+// group of routes
+type CORSPolicy struct {
+	// AllowedOrigins lists origins ("https://app.example.com") and patterns
+	// matching any subdomain ("https://*.preview.example.com") or port
+	// ("http://localhost:*"). Entries that are not origins never match.
+	AllowedOrigins []string
+
+	// AllowedMethods and AllowedHeaders bound what preflight requests may
+	// ask for. Header names are case-insensitive.
+	AllowedMethods []string
+	AllowedHeaders []string
+
+	// ExposedHeaders lists response headers scripts may read
+	ExposedHeaders []string
+
+	// AllowCredentials lets browsers send cookies
+	AllowCredentials bool
+
+	// MaxAge is how long browsers may cache a preflight response
+	MaxAge time.Duration
+
+	origins []originPattern
+}
+
+// originPattern is a parsed AllowedOrigins entry
+type originPattern struct {
+	scheme string
+	host   string
+	port   string
+
+	// subdomains matches any subdomain of host, but not host itself
+	subdomains bool
+	// anyPort matches any port, or none
+	anyPort bool
+}
+
+// parseOriginPattern parses scheme://host[:port], where host may start
+// with "*." and port may be "*"
+func parseOriginPattern(pattern string) (originPattern, bool) {
+	scheme, rest, ok := strings.Cut(strings.ToLower(strings.TrimSuffix(pattern, "/")), "://")
+	if !ok || (scheme != "http" && scheme != "https") || rest == "" || strings.ContainsAny(rest, "/?#@") {
+		return originPattern{}, false
+	}
+
+	var p originPattern
+	p.scheme = scheme
+	p.host, p.port = rest, ""
+	if i := strings.LastIndex(rest, ":"); i >= 0 && !strings.HasSuffix(rest, "]") {
+		p.host, p.port = rest[:i], rest[i+1:]
+		if p.port == "*" {
+			p.anyPort, p.port = true, ""
+		} else if _, err := strconv.Atoi(p.port); err != nil {
+			return originPattern{}, false
+		}
+	}
+	if host, ok := strings.CutPrefix(p.host, "*."); ok {
+		p.host, p.subdomains = host, true
+	}
+	if p.host == "" || strings.Contains(p.host, "*") {
+		return originPattern{}, false
+	}
+
+	// Browsers omit default ports from the Origin header
+	if (p.scheme == "http" && p.port == "80") || (p.scheme == "https" && p.port == "443") {
+		p.port = ""
+	}
+	return p, true
+}
+
+// matches reports whether the pattern allows a request's origin
+func (p originPattern) matches(origin originPattern) bool {
+	if origin.scheme != p.scheme || (!p.anyPort && origin.port != p.port) {
+		return false
+	}
+	if p.subdomains {
+		return strings.HasSuffix(origin.host, "."+p.host)
+	}
+	return origin.host == p.host
+}
+
+// compile parses the origin patterns
+func (p *CORSPolicy) compile() {
+	p.origins = nil
+	for _, origin := range p.AllowedOrigins {
+		if pattern, ok := parseOriginPattern(origin); ok {
+			p.origins = append(p.origins, pattern)
+		}
+	}
+}
+
+// allowsOrigin reports whether the policy allows the Origin header value.
+// Wildcards in the request's origin are literal, so never match.
+func (p *CORSPolicy) allowsOrigin(value string) bool {
+	origin, ok := parseOriginPattern(value)
+	if !ok || origin.subdomains || origin.anyPort {
+		return false
+	}
+	for _, pattern := range p.origins {
+		if pattern.matches(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsMethod reports whether a preflight may ask for method
+func (p *CORSPolicy) allowsMethod(method string) bool {
+	return slices.Contains(p.AllowedMethods, method)
+}
+
+// allowsHeaders reports whether a preflight may ask for each header in a
+// comma-separated Access-Control-Request-Headers value
+func (p *CORSPolicy) allowsHeaders(requested string) bool {
+	for _, header := range strings.Split(requested, ",") {
+		header = strings.TrimSpace(header)
+		if header == "" {
+			continue
+		}
+		if !slices.ContainsFunc(p.AllowedHeaders, func(allowed string) bool { return strings.EqualFold(allowed, header) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// CORSPolicies picks the CORS policy for each request
+type CORSPolicies struct {
+	defaultPolicy *CORSPolicy
+	routes        map[string]*CORSPolicy
+}
+
+// NewCORSPolicies creates policies applying routes' overrides, keyed by
+// path prefix ("/api/uploads" covers "/api/uploads/7"), and defaultPolicy
+// elsewhere. The longest matching prefix wins.
+func NewCORSPolicies(defaultPolicy CORSPolicy, routes map[string]CORSPolicy) *CORSPolicies {
+	policies := &CORSPolicies{defaultPolicy: &defaultPolicy, routes: make(map[string]*CORSPolicy, len(routes))}
+	policies.defaultPolicy.compile()
+	for prefix, policy := range routes {
+		policy.compile()
+		policies.routes[strings.TrimSuffix(prefix, "/")] = &policy
+	}
+	return policies
+}
+
+// For returns the policy for a request path
+func (p *CORSPolicies) For(path string) *CORSPolicy {
+	policy, longest := p.defaultPolicy, -1
+	for prefix, route := range p.routes {
+		if (path == prefix || strings.HasPrefix(path, prefix+"/")) && len(prefix) > longest {
+			policy, longest = route, len(prefix)
+		}
+	}
+	return policy
+}
+
+// CORS applies policies to cross-origin requests; see DynamicCORS
+func CORS(policies *CORSPolicies) func(http.Handler) http.Handler {
+	return DynamicCORS(func() *CORSPolicies { return policies })
+}
+
+// DynamicCORS applies the policies current for each request, so a
+// configuration reload takes effect without a restart. Only an allowed
+// request's own origin is echoed; requests without an Origin header, such
+// as those from servers and instruments, get no CORS headers. Preflights
+// are answered here, and rejected with 403 if the origin, method or any
+// header is not allowed.
+func DynamicCORS(policies func() *CORSPolicies) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Set CORS headers with specific frontend domain
-			w.Header().Set("Access-Control-Allow-Origin", frontendURL)
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-			w.Header().Set("Access-Control-Allow-Credentials", "true")
-			w.Header().Set("Access-Control-Max-Age", "86400")
-
-			// Handle preflight OPTIONS request
-			if r.Method == "OPTIONS" {
-				w.WriteHeader(http.StatusOK)
+			// Responses differ by origin, so caches must not share them
+			w.Header().Add("Vary", "Origin")
+
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			policy := policies().For(r.URL.Path)
+
+			requestMethod := r.Header.Get("Access-Control-Request-Method")
+			if r.Method == http.MethodOptions && requestMethod != "" {
+				w.Header().Add("Vary", "Access-Control-Request-Method")
+				w.Header().Add("Vary", "Access-Control-Request-Headers")
+
+				requestHeaders := r.Header.Get("Access-Control-Request-Headers")
+				switch {
+				case !policy.allowsOrigin(origin):
+					problem.Error(w, r, http.StatusForbidden, "Origin not allowed")
+				case !policy.allowsMethod(requestMethod):
+					problem.Error(w, r, http.StatusForbidden, "Method not allowed for cross-origin requests")
+				case !policy.allowsHeaders(requestHeaders):
+					problem.Error(w, r, http.StatusForbidden, "Headers not allowed for cross-origin requests")
+				default:
+					setAllowOrigin(w, policy, origin)
+					w.Header().Set("Access-Control-Allow-Methods", strings.Join(policy.AllowedMethods, ", "))
+					if requestHeaders != "" {
+						w.Header().Set("Access-Control-Allow-Headers", strings.Join(policy.AllowedHeaders, ", "))
+					}
+					if policy.MaxAge > 0 {
+						w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(policy.MaxAge.Seconds())))
+					}
+					w.WriteHeader(http.StatusNoContent)
+				}
 				return
 			}
 
+			if policy.allowsOrigin(origin) {
+				setAllowOrigin(w, policy, origin)
+				if len(policy.ExposedHeaders) > 0 {
+					w.Header().Set("Access-Control-Expose-Headers", strings.Join(policy.ExposedHeaders, ", "))
+				}
+			}
 			next.ServeHTTP(w, r)
 		})
 	}
 }
 
+// setAllowOrigin echoes an allowed origin
+func setAllowOrigin(w http.ResponseWriter, policy *CORSPolicy, origin string) {
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	if policy.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
 // HTTPSEnforcement adds security headers and enforces HTTPS in production
 func HTTPSEnforcement(environment string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -49,7 +262,9 @@ func HTTPSEnforcement(environment string) func(http.Handler) http.Handler {
 	}
 }
 
-// RequestValidation adds basic request validation (content-type, size limits)
+// RequestValidation adds basic request validation (content-type, size limits).
+// JSON bodies may be sent as application/json or, for FHIR clients,
+// application/fhir+json, with or without a charset.
 func RequestValidation(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Limit request body size to 1MB for basic protection
@@ -57,10 +272,12 @@ func RequestValidation(next http.Handler) http.Handler {
 
 		// Validate content-type for POST/PUT requests
 		if r.Method == "POST" || r.Method == "PUT" {
-			contentType := r.Header.Get("Content-Type")
-			if contentType != "" && contentType != "application/json" {
-				http.Error(w, "Invalid content-type. Expected application/json", http.StatusUnsupportedMediaType)
-				return
+			if contentType := r.Header.Get("Content-Type"); contentType != "" {
+				mediaType, _, err := mime.ParseMediaType(contentType)
+				if err != nil || (mediaType != "application/json" && mediaType != "application/fhir+json") {
+					problem.Error(w, r, http.StatusUnsupportedMediaType, "Invalid content-type. Expected application/json")
+					return
+				}
 			}
 		}
 
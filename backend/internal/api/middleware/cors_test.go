@@ -3,83 +3,265 @@ package middleware
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
+// testCORSPolicy allows the test frontend and its preview subdomains
+func testCORSPolicy() CORSPolicy {
+	return CORSPolicy{
+		AllowedOrigins:   []string{"https://testfrontend.example.com", "https://*.preview.example.com", "http://localhost:*"},
+		AllowedMethods:   []string{http.MethodGet, http.MethodPost, http.MethodDelete},
+		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		ExposedHeaders:   []string{"Location"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}
+}
+
 func TestCORS(t *testing.T) {
 	// Create a test handler
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
 
-	// Wrap with CORS middleware using test frontend URL
-	corsHandler := CORS("https://testfrontend.example.com")(handler)
+	// Wrap with CORS middleware using test policy
+	corsHandler := CORS(NewCORSPolicies(testCORSPolicy(), nil))(handler)
 
-	// Test regular request
+	// Test regular request from the frontend
 	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("Origin", "https://testfrontend.example.com")
 	w := httptest.NewRecorder()
 
 	corsHandler.ServeHTTP(w, req)
 
-	// Check CORS headers are set to specific frontend URL
+	// Check CORS headers echo the frontend's origin
 	if w.Header().Get("Access-Control-Allow-Origin") != "https://testfrontend.example.com" {
 		t.Errorf("Expected Access-Control-Allow-Origin header to be 'https://testfrontend.example.com', got '%s'", w.Header().Get("Access-Control-Allow-Origin"))
 	}
 
-	if w.Header().Get("Access-Control-Allow-Methods") == "" {
-		t.Error("Expected Access-Control-Allow-Methods header to be set")
-	}
-
 	if w.Header().Get("Access-Control-Allow-Credentials") != "true" {
 		t.Error("Expected Access-Control-Allow-Credentials header to be 'true'")
 	}
 
+	if w.Header().Get("Access-Control-Expose-Headers") != "Location" {
+		t.Errorf("Expected Access-Control-Expose-Headers header to be 'Location', got '%s'", w.Header().Get("Access-Control-Expose-Headers"))
+	}
+
+	if w.Header().Get("Vary") != "Origin" {
+		t.Errorf("Expected Vary header to be 'Origin', got '%s'", w.Header().Get("Vary"))
+	}
+
 	if w.Code != http.StatusOK {
 		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
 	}
 }
 
+func TestCORS_Origins(t *testing.T) {
+	corsHandler := CORS(NewCORSPolicies(testCORSPolicy(), nil))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"https://testfrontend.example.com", true},
+		{"https://TestFrontend.example.com:443", true},
+		{"https://pr-42.preview.example.com", true},
+		{"https://a.b.preview.example.com", true},
+		{"http://localhost:3000", true},
+		{"http://localhost", true},
+		{"", false},
+		{"null", false},
+		{"http://testfrontend.example.com", false},
+		{"https://testfrontend.example.com:8443", false},
+		{"https://preview.example.com", false},
+		{"https://evilpreview.example.com", false},
+		{"https://preview.example.com.evil.com", false},
+		{"https://*.preview.example.com", false},
+		{"https://testfrontend.example.com.evil.com", false},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		w := httptest.NewRecorder()
+
+		corsHandler.ServeHTTP(w, req)
+
+		got := w.Header().Get("Access-Control-Allow-Origin")
+		if tt.allowed && got != tt.origin {
+			t.Errorf("Origin %q: expected it to be echoed, got '%s'", tt.origin, got)
+		}
+		if !tt.allowed && (got != "" || w.Header().Get("Access-Control-Allow-Credentials") != "") {
+			t.Errorf("Origin %q: expected no CORS headers, got Access-Control-Allow-Origin '%s'", tt.origin, got)
+		}
+	}
+}
+
+func TestCORS_Preflight(t *testing.T) {
+	called := false
+	corsHandler := CORS(NewCORSPolicies(testCORSPolicy(), nil))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	tests := []struct {
+		name     string
+		origin   string
+		method   string
+		headers  string
+		expected int
+	}{
+		{"allowed", "https://pr-7.preview.example.com", http.MethodPost, "content-type, Authorization", http.StatusNoContent},
+		{"no headers", "https://testfrontend.example.com", http.MethodDelete, "", http.StatusNoContent},
+		{"unknown origin", "https://evil.example.com", http.MethodPost, "", http.StatusForbidden},
+		{"method not allowed", "https://testfrontend.example.com", http.MethodPut, "", http.StatusForbidden},
+		{"header not allowed", "https://testfrontend.example.com", http.MethodPost, "Content-Type, X-Secret", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodOptions, "/test", nil)
+		req.Header.Set("Origin", tt.origin)
+		req.Header.Set("Access-Control-Request-Method", tt.method)
+		if tt.headers != "" {
+			req.Header.Set("Access-Control-Request-Headers", tt.headers)
+		}
+		w := httptest.NewRecorder()
+
+		corsHandler.ServeHTTP(w, req)
+
+		if w.Code != tt.expected {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.expected, w.Code)
+		}
+		if vary := strings.Join(w.Header().Values("Vary"), ", "); vary != "Origin, Access-Control-Request-Method, Access-Control-Request-Headers" {
+			t.Errorf("%s: expected Vary to cover the preflight headers, got '%s'", tt.name, vary)
+		}
+
+		allowOrigin := w.Header().Get("Access-Control-Allow-Origin")
+		if tt.expected == http.StatusNoContent {
+			if allowOrigin != tt.origin || w.Header().Get("Access-Control-Allow-Methods") != "GET, POST, DELETE" || w.Header().Get("Access-Control-Max-Age") != "600" {
+				t.Errorf("%s: expected preflight headers, got %v", tt.name, w.Header())
+			}
+			if allowHeaders := w.Header().Get("Access-Control-Allow-Headers"); (tt.headers != "") != (allowHeaders != "") {
+				t.Errorf("%s: expected Access-Control-Allow-Headers only when headers are requested, got '%s'", tt.name, allowHeaders)
+			}
+		} else if allowOrigin != "" {
+			t.Errorf("%s: expected a rejected preflight not to allow the origin, got '%s'", tt.name, allowOrigin)
+		}
+	}
+
+	if called {
+		t.Error("Expected preflight requests to be answered by the middleware")
+	}
+}
+
 func TestCORS_OptionsRequest(t *testing.T) {
-	// Create a test handler
+	// OPTIONS without Access-Control-Request-Method is not a preflight
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
+	corsHandler := CORS(NewCORSPolicies(testCORSPolicy(), nil))(handler)
 
-	// Wrap with CORS middleware using test frontend URL
-	corsHandler := CORS("https://testfrontend.example.com")(handler)
-
-	// Test OPTIONS request (preflight)
 	req := httptest.NewRequest(http.MethodOptions, "/test", nil)
+	req.Header.Set("Origin", "https://testfrontend.example.com")
 	w := httptest.NewRecorder()
 
 	corsHandler.ServeHTTP(w, req)
 
-	// OPTIONS request should return 200 and not call the wrapped handler
 	if w.Code != http.StatusOK {
-		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+		t.Errorf("Expected the request to reach the handler, got status %d", w.Code)
 	}
+	if w.Header().Get("Access-Control-Allow-Methods") != "" {
+		t.Error("Expected no preflight headers")
+	}
+}
 
-	// Check CORS headers are set to specific frontend URL
-	if w.Header().Get("Access-Control-Allow-Origin") != "https://testfrontend.example.com" {
-		t.Errorf("Expected Access-Control-Allow-Origin header to be 'https://testfrontend.example.com', got '%s'", w.Header().Get("Access-Control-Allow-Origin"))
+func TestCORS_RouteOverrides(t *testing.T) {
+	uploads := testCORSPolicy()
+	uploads.AllowedHeaders = append(uploads.AllowedHeaders, "Upload-Offset")
+	uploads.ExposedHeaders = []string{"Upload-Offset"}
+	policies := NewCORSPolicies(testCORSPolicy(), map[string]CORSPolicy{"/api/uploads/": uploads})
+
+	tests := []struct {
+		path     string
+		expected int
+	}{
+		{"/api/uploads", http.StatusNoContent},
+		{"/api/uploads/7", http.StatusNoContent},
+		{"/api/uploadsx", http.StatusForbidden},
+		{"/api/users", http.StatusForbidden},
+	}
+
+	corsHandler := CORS(policies)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodOptions, tt.path, nil)
+		req.Header.Set("Origin", "https://testfrontend.example.com")
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		req.Header.Set("Access-Control-Request-Headers", "Upload-Offset")
+		w := httptest.NewRecorder()
+
+		corsHandler.ServeHTTP(w, req)
+
+		if w.Code != tt.expected {
+			t.Errorf("%s: expected status %d, got %d", tt.path, tt.expected, w.Code)
+		}
+	}
+
+	if got := policies.For("/api/uploads/7").ExposedHeaders; len(got) != 1 || got[0] != "Upload-Offset" {
+		t.Errorf("Expected the uploads policy for an upload, got exposed headers %v", got)
 	}
 }
 
-func TestCORS_DifferentFrontendURL(t *testing.T) {
-	// Test that different frontend URLs are properly set
-	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+func TestDynamicCORS(t *testing.T) {
+	policies := NewCORSPolicies(CORSPolicy{AllowedOrigins: []string{"https://old.example.com"}}, nil)
+	corsHandler := DynamicCORS(func() *CORSPolicies { return policies })(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	policies = NewCORSPolicies(CORSPolicy{AllowedOrigins: []string{"https://new.example.com"}}, nil)
+	for origin, expected := range map[string]string{"https://old.example.com": "", "https://new.example.com": "https://new.example.com"} {
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+
+		corsHandler.ServeHTTP(w, req)
+
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != expected {
+			t.Errorf("Expected the policies to be read per request: origin %q got '%s'", origin, got)
+		}
+	}
+}
+
+func TestRequestValidation_ContentType(t *testing.T) {
+	handler := RequestValidation(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
-	})
+	}))
 
-	testURL := "https://myapp.azurestaticapps.net"
-	corsHandler := CORS(testURL)(handler)
+	tests := []struct {
+		contentType string
+		expected    int
+	}{
+		{"", http.StatusOK},
+		{"application/json", http.StatusOK},
+		{"application/json; charset=utf-8", http.StatusOK},
+		{"application/fhir+json", http.StatusOK},
+		{"application/fhir+json;charset=UTF-8", http.StatusOK},
+		{"text/plain", http.StatusUnsupportedMediaType},
+		{"application/xml", http.StatusUnsupportedMediaType},
+		{"application/json;;", http.StatusUnsupportedMediaType},
+	}
 
-	req := httptest.NewRequest(http.MethodGet, "/test", nil)
-	w := httptest.NewRecorder()
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/test", nil)
+		if tt.contentType != "" {
+			req.Header.Set("Content-Type", tt.contentType)
+		}
+		w := httptest.NewRecorder()
 
-	corsHandler.ServeHTTP(w, req)
+		handler.ServeHTTP(w, req)
 
-	if w.Header().Get("Access-Control-Allow-Origin") != testURL {
-		t.Errorf("Expected Access-Control-Allow-Origin header to be '%s', got '%s'", testURL, w.Header().Get("Access-Control-Allow-Origin"))
+		if w.Code != tt.expected {
+			t.Errorf("Content-Type %q: expected status %d, got %d", tt.contentType, tt.expected, w.Code)
+		}
 	}
 }
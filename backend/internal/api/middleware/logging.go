@@ -1,15 +1,23 @@
 package middleware
 
 import (
-	"log"
+	"context"
+	"log/slog"
 	"net/http"
 	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"backend/internal/audit"
+	"backend/internal/logging"
 )
 
-// responseWriter wraps http.ResponseWriter to capture status code
+// responseWriter wraps http.ResponseWriter to capture the status code and
+// the number of body bytes written
 type responseWriter struct {
 	http.ResponseWriter
 	statusCode int
+	bytes      int64
 }
 
 func (rw *responseWriter) WriteHeader(code int) {
@@ -17,26 +25,79 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
-// Logging logs HTTP requests with method, path, status code, and duration
-func Logging(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-
-		// Wrap the response writer to capture status code
-		wrapped := &responseWriter{
-			ResponseWriter: w,
-			statusCode:     http.StatusOK,
-		}
-
-		next.ServeHTTP(wrapped, r)
-
-		// Log the request
-		log.Printf(
-			"%s %s %d %v",
-			r.Method,
-			r.URL.Path,
-			wrapped.statusCode,
-			time.Since(start),
-		)
-	})
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// requestLog collects what inner middleware learns about a request for the
+// access log line, such as the authenticated user
+type requestLog struct {
+	userID *int
+}
+
+type requestLogContextKey struct{}
+
+// identifyUser records the authenticated user for the access log and adds
+// their ID to the request's logger
+func identifyUser(r *http.Request, userID int) *http.Request {
+	if entry, ok := r.Context().Value(requestLogContextKey{}).(*requestLog); ok {
+		entry.userID = &userID
+	}
+	return r.WithContext(logging.With(r.Context(), "user_id", userID))
+}
+
+// Logging gives each request a logger carrying its request ID and client
+// IP, available to handlers through logging.FromContext, and writes an
+// access log line when the request completes. It must run inside
+// RequestInfo, which assigns the request ID, and inside Telemetry for log
+// lines to carry the request's trace ID.
+func Logging(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			requestLogger := logger
+			if req, ok := audit.RequestFromContext(r.Context()); ok {
+				requestLogger = logger.With("request_id", req.ID, "client_ip", req.IP)
+			}
+			if span := trace.SpanContextFromContext(r.Context()); span.IsValid() {
+				requestLogger = requestLogger.With("trace_id", span.TraceID().String(), "span_id", span.SpanID().String())
+			}
+			entry := &requestLog{}
+			ctx := context.WithValue(r.Context(), requestLogContextKey{}, entry)
+			ctx = logging.WithLogger(ctx, requestLogger)
+
+			// Wrap the response writer to capture status code and size
+			wrapped := &responseWriter{
+				ResponseWriter: w,
+				statusCode:     http.StatusOK,
+			}
+
+			next.ServeHTTP(wrapped, r.WithContext(ctx))
+
+			attrs := []slog.Attr{
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", wrapped.statusCode),
+				slog.Int64("bytes", wrapped.bytes),
+				slog.Duration("duration", time.Since(start)),
+			}
+			if entry.userID != nil {
+				attrs = append(attrs, slog.Int("user_id", *entry.userID))
+			}
+
+			level := slog.LevelInfo
+			if wrapped.statusCode >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+			requestLogger.LogAttrs(r.Context(), level, "request", attrs...)
+		})
+	}
 }
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"backend/internal/logging"
+)
+
+func TestLogging(t *testing.T) {
+	var out bytes.Buffer
+	logger, _ := logging.New(&out, "info", logging.NewPolicy(logging.DefaultRedactFields))
+
+	handler := RequestInfo(nil)(Logging(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Authentication identifies the user for the access log
+		r = identifyUser(r, 42)
+		logging.FromContext(r.Context()).Info("handling", "email", "jane@example.com")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	})))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/users", nil)
+	req.Header.Set("X-Request-ID", "req-7")
+	req.RemoteAddr = "10.0.0.5:51234"
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	var lines []map[string]interface{}
+	decoder := json.NewDecoder(&out)
+	for decoder.More() {
+		var line map[string]interface{}
+		if err := decoder.Decode(&line); err != nil {
+			t.Fatalf("Log output is not JSON: %v", err)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("Expected a handler line and an access line, got %v", lines)
+	}
+
+	handled, access := lines[0], lines[1]
+	if handled["request_id"] != "req-7" || handled["user_id"] != float64(42) || handled["email"] != logging.Redacted {
+		t.Errorf("Unexpected handler log line %v", handled)
+	}
+
+	expected := map[string]interface{}{
+		"msg":        "request",
+		"request_id": "req-7",
+		"client_ip":  "10.0.0.5",
+		"method":     http.MethodPost,
+		"path":       "/api/users",
+		"status":     float64(http.StatusCreated),
+		"bytes":      float64(5),
+		"user_id":    float64(42),
+	}
+	for key, value := range expected {
+		if access[key] != value {
+			t.Errorf("Expected access log %s %v, got %v", key, value, access[key])
+		}
+	}
+}
+
+func TestLogging_ServerErrors(t *testing.T) {
+	var out bytes.Buffer
+	logger, _ := logging.New(&out, "error", nil)
+
+	handler := Logging(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/hello", nil))
+
+	var line map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &line); err != nil || line["level"] != "ERROR" {
+		t.Errorf("Expected server errors to be logged at ERROR, got %q", out.String())
+	}
+}
@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"backend/internal/api/problem"
+	"backend/internal/audit"
+	"backend/internal/auth"
+	"backend/internal/logging"
+	"backend/internal/ratelimit"
+)
+
+// RateLimit takes a token from the client's bucket for the request's
+// policy, refusing the request with 429 when the bucket is empty. Clients
+// are identified by API key, then user, then IP address, so it must run
+// inside Authenticate and RequestInfo. Limited responses carry the
+// RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and
+// RateLimit-Policy headers, and refusals Retry-After. The policies current
+// for each request apply, so a configuration reload takes effect without a
+// restart. If the store fails, the request is allowed and the error logged,
+// so a database hiccup does not take the API down.
+func RateLimit(limiter *ratelimit.Limiter, policies func() *ratelimit.Policies) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			policy := policies().For(r)
+			if policy.Limit.Unlimited() {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			result, err := limiter.Allow(r.Context(), policy, rateLimitClient(r))
+			if err != nil {
+				logging.FromContext(r.Context()).Error("Rate limit check failed; allowing the request", "policy", policy.Name, "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			limit := policy.Limit
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(limit.Requests))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("RateLimit-Reset", ceilSeconds(result.Reset))
+			w.Header().Set("RateLimit-Policy", strconv.Itoa(limit.Requests)+";w="+ceilSeconds(limit.Period))
+
+			if !result.Allowed {
+				w.Header().Set("Retry-After", ceilSeconds(result.RetryAfter))
+				problem.Error(w, r, http.StatusTooManyRequests, "Rate limit exceeded; retry later")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RateLimitFailedAuth throttles guessing of credentials sent in the
+// Authorization header. Each request answered 401 takes a token from the
+// client IP's bucket for the policy, and once the bucket is empty requests
+// with credentials are refused with 429 before they are checked, so a flood
+// of invalid API keys reaches neither the key store nor RateLimit, which
+// only sees authenticated callers. It must run outside APIKey and inside
+// RequestInfo. Requests without an Authorization header pass through, and a
+// store failure is logged and allows the request, as in RateLimit.
+func RateLimitFailedAuth(limiter *ratelimit.Limiter, policy func() ratelimit.Policy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p := policy()
+			if r.Header.Get("Authorization") == "" || p.Limit.Unlimited() {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			client := rateLimitIP(r)
+			result, err := limiter.Check(r.Context(), p, client)
+			if err != nil {
+				logging.FromContext(r.Context()).Error("Rate limit check failed; allowing the request", "policy", p.Name, "error", err)
+			} else if !result.Allowed {
+				w.Header().Set("Retry-After", ceilSeconds(result.RetryAfter))
+				problem.Error(w, r, http.StatusTooManyRequests, "Too many failed authentication attempts; retry later")
+				return
+			}
+
+			wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(wrapped, r)
+
+			if wrapped.statusCode == http.StatusUnauthorized {
+				if _, err := limiter.Allow(r.Context(), p, client); err != nil {
+					logging.FromContext(r.Context()).Error("Failed to record failed authentication", "policy", p.Name, "error", err)
+				}
+			}
+		})
+	}
+}
+
+// rateLimitClient names the caller a bucket belongs to
+func rateLimitClient(r *http.Request) string {
+	if key, ok := auth.APIKeyFromContext(r.Context()); ok {
+		return "key:" + strconv.Itoa(key.ID)
+	}
+	if user, ok := auth.UserFromContext(r.Context()); ok {
+		return "user:" + strconv.Itoa(user.ID)
+	}
+	return rateLimitIP(r)
+}
+
+// rateLimitIP names the client address a bucket belongs to
+func rateLimitIP(r *http.Request) string {
+	if req, ok := audit.RequestFromContext(r.Context()); ok && req.IP != "" {
+		return "ip:" + req.IP
+	}
+	return "ip:" + r.RemoteAddr
+}
+
+// ceilSeconds formats d as whole seconds, rounded up so clients never retry
+// too early
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,185 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"backend/internal/audit"
+	"backend/internal/auth"
+	"backend/internal/models"
+	"backend/internal/ratelimit"
+)
+
+// failingStore is a rate limit store whose database is down
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, ratelimit.Limit, time.Time) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("connection refused")
+}
+
+func (failingStore) Peek(context.Context, string, ratelimit.Limit, time.Time) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("connection refused")
+}
+
+func TestRateLimit(t *testing.T) {
+	clock := ratelimit.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	policies := ratelimit.NewPolicies(
+		ratelimit.Policy{Name: "default", Limit: ratelimit.Limit{Requests: 100, Period: time.Minute}},
+		map[string]ratelimit.Policy{"POST /api/auth/login": {Name: "auth", Limit: ratelimit.Limit{Requests: 2, Period: time.Minute}}},
+	)
+	handler := RateLimit(ratelimit.NewLimiter(ratelimit.NewMemoryStore(), clock.Now), func() *ratelimit.Policies { return policies })(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	login := func(ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/login", nil)
+		req = req.WithContext(audit.WithRequest(req.Context(), audit.Request{IP: ip}))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := login("203.0.113.9"); w.Code != http.StatusOK {
+			t.Fatalf("Expected login %d to be allowed, got %d", i+1, w.Code)
+		}
+	}
+
+	w := login("203.0.113.9")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected the third login to be refused, got %d", w.Code)
+	}
+	expected := map[string]string{
+		"Retry-After":         "30",
+		"RateLimit-Limit":     "2",
+		"RateLimit-Remaining": "0",
+		"RateLimit-Reset":     "60",
+		"RateLimit-Policy":    "2;w=60",
+	}
+	for header, value := range expected {
+		if got := w.Header().Get(header); got != value {
+			t.Errorf("Expected %s: %s, got %q", header, value, got)
+		}
+	}
+
+	// Other addresses, and other routes for the same address, are unaffected
+	if w := login("198.51.100.1"); w.Code != http.StatusOK {
+		t.Errorf("Expected another client to be allowed, got %d", w.Code)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+	req = req.WithContext(audit.WithRequest(req.Context(), audit.Request{IP: "203.0.113.9"}))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Header().Get("RateLimit-Remaining") != "99" {
+		t.Errorf("Expected the default policy to apply elsewhere, got %d with %q remaining", w.Code, w.Header().Get("RateLimit-Remaining"))
+	}
+
+	clock.Advance(30 * time.Second)
+	if w := login("203.0.113.9"); w.Code != http.StatusOK {
+		t.Errorf("Expected a login after Retry-After to be allowed, got %d", w.Code)
+	}
+}
+
+func TestRateLimit_Clients(t *testing.T) {
+	clock := ratelimit.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	policies := ratelimit.NewPolicies(ratelimit.Policy{Name: "default", Limit: ratelimit.Limit{Requests: 1, Period: time.Minute}}, nil)
+	handler := RateLimit(ratelimit.NewLimiter(ratelimit.NewMemoryStore(), clock.Now), func() *ratelimit.Policies { return policies })(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	// Users and API keys have their own buckets wherever they connect from
+	user := &models.User{ID: 7}
+	contexts := []func(context.Context) context.Context{
+		func(ctx context.Context) context.Context { return ctx },
+		func(ctx context.Context) context.Context { return auth.WithUser(ctx, user) },
+		func(ctx context.Context) context.Context {
+			return auth.WithAPIKey(auth.WithUser(ctx, user), &models.APIKey{ID: 3, UserID: user.ID})
+		},
+	}
+	for i, withClient := range contexts {
+		req := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+		req = req.WithContext(withClient(audit.WithRequest(req.Context(), audit.Request{IP: "203.0.113.9"})))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Errorf("Client %d: expected its own bucket, got %d", i, w.Code)
+		}
+	}
+}
+
+func TestRateLimit_StoreFailure(t *testing.T) {
+	policies := ratelimit.NewPolicies(ratelimit.Policy{Name: "default", Limit: ratelimit.Limit{Requests: 1, Period: time.Minute}}, nil)
+	handler := RateLimit(ratelimit.NewLimiter(failingStore{}, nil), func() *ratelimit.Policies { return policies })(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/users", nil))
+	if w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "" {
+		t.Errorf("Expected the request to be allowed without rate limit headers, got %d", w.Code)
+	}
+}
+
+func TestRateLimitFailedAuth(t *testing.T) {
+	clock := ratelimit.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	policy := ratelimit.Policy{Name: "auth", Limit: ratelimit.Limit{Requests: 2, Period: time.Minute}}
+
+	// Stands in for APIKey: only "valid" authenticates
+	checked := 0
+	handler := RateLimitFailedAuth(ratelimit.NewLimiter(ratelimit.NewMemoryStore(), clock.Now), func() ratelimit.Policy { return policy })(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			checked++
+			if r.Header.Get("Authorization") != "Bearer valid" {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		}))
+
+	send := func(ip, authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		req = req.WithContext(audit.WithRequest(req.Context(), audit.Request{IP: ip}))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// Successes are not counted
+	for i := 0; i < 3; i++ {
+		if w := send("203.0.113.9", "Bearer valid"); w.Code != http.StatusOK {
+			t.Fatalf("Expected a valid key to be allowed, got %d", w.Code)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if w := send("203.0.113.9", "Bearer ap_guess"); w.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected invalid key %d to be rejected, got %d", i+1, w.Code)
+		}
+	}
+
+	// The address is now refused without its keys being checked, valid or not
+	checked = 0
+	for _, authorization := range []string{"Bearer ap_guess", "Bearer valid"} {
+		w := send("203.0.113.9", authorization)
+		if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "30" {
+			t.Errorf("Expected 429 with Retry-After: 30, got %d with %q", w.Code, w.Header().Get("Retry-After"))
+		}
+	}
+	if checked != 0 {
+		t.Errorf("Expected no keys to be checked once throttled, got %d", checked)
+	}
+
+	// Other addresses and requests without credentials are unaffected
+	if w := send("198.51.100.1", "Bearer ap_guess"); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected another address to be checked, got %d", w.Code)
+	}
+	if w := send("203.0.113.9", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a request without credentials to pass through, got %d", w.Code)
+	}
+
+	clock.Advance(30 * time.Second)
+	if w := send("203.0.113.9", "Bearer valid"); w.Code != http.StatusOK {
+		t.Errorf("Expected a key after Retry-After to be checked, got %d", w.Code)
+	}
+}
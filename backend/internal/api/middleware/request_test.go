@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"backend/internal/audit"
+)
+
+func TestRequestInfo(t *testing.T) {
+	var got audit.Request
+	handler := RequestInfo(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = audit.RequestFromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.RemoteAddr = "10.0.0.5:51234"
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if len(got.ID) != 32 || w.Header().Get("X-Request-ID") != got.ID {
+		t.Errorf("Expected a generated ID echoed in the response, got %q and header %q", got.ID, w.Header().Get("X-Request-ID"))
+	}
+	if got.IP != "10.0.0.5" {
+		t.Errorf("Expected IP 10.0.0.5, got %q", got.IP)
+	}
+}
+
+func TestRequestInfo_ForwardedHeaders(t *testing.T) {
+	// httptest requests come from 192.0.2.1
+	var got audit.Request
+	handler := RequestInfo([]netip.Prefix{netip.MustParsePrefix("192.0.2.0/24")})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = audit.RequestFromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("X-Request-ID", "trace-abc_123")
+	req.Header.Set("X-Forwarded-For", "1.2.3.4, 203.0.113.9")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if got.ID != "trace-abc_123" {
+		t.Errorf("Expected the client's request ID to be kept, got %q", got.ID)
+	}
+	if got.IP != "203.0.113.9" {
+		t.Errorf("Expected the proxy-appended address, got %q", got.IP)
+	}
+
+	// Malformed IDs are replaced rather than logged
+	req.Header.Set("X-Request-ID", "bad id\n"+strings.Repeat("x", 10))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if strings.Contains(got.ID, " ") || len(got.ID) != 32 {
+		t.Errorf("Expected a generated ID for a malformed header, got %q", got.ID)
+	}
+}
+
+func TestClientIP_TrustedProxies(t *testing.T) {
+	proxies := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("192.0.2.7/32")}
+
+	tests := []struct {
+		remoteAddr, forwarded, expected string
+	}{
+		{"10.0.0.5:443", "", "10.0.0.5"},
+		{"10.0.0.5:443", "203.0.113.9", "203.0.113.9"},
+		{"10.0.0.5:443", "1.2.3.4, 203.0.113.9, 192.0.2.7", "203.0.113.9"},
+		{"10.0.0.5:443", "10.1.1.1, 10.2.2.2", "10.1.1.1"},
+		{"10.0.0.5:443", "203.0.113.9, not-an-ip", "10.0.0.5"},
+		{"[::ffff:10.0.0.5]:443", "203.0.113.9", "203.0.113.9"},
+		{"198.51.100.1:443", "203.0.113.9", "198.51.100.1"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.RemoteAddr = tt.remoteAddr
+		if tt.forwarded != "" {
+			req.Header.Set("X-Forwarded-For", tt.forwarded)
+		}
+		if got := clientIP(req, proxies); got != tt.expected {
+			t.Errorf("From %s with X-Forwarded-For %q: expected %s, got %s", tt.remoteAddr, tt.forwarded, tt.expected, got)
+		}
+	}
+}
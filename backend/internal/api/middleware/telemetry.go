@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.32.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"backend/internal/audit"
+	"backend/internal/telemetry"
+)
+
+// requestDurationBuckets are the OpenTelemetry recommended boundaries for
+// http.server.request.duration, in seconds
+var requestDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.075, 0.1, 0.25, 0.5, 0.75, 1, 2.5, 5, 7.5, 10}
+
+// requestMethod names a request's method for telemetry, folding methods
+// the API does not use into "_OTHER" so clients cannot create unbounded
+// metric series
+func requestMethod(r *http.Request) string {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete, http.MethodOptions:
+		return r.Method
+	default:
+		return "_OTHER"
+	}
+}
+
+// Telemetry wraps each request in a server span, continuing the caller's
+// trace when the request carries a W3C traceparent, and records its
+// duration in the http.server.request.duration histogram. Request rate,
+// error rate and latency per route all derive from that histogram;
+// http.server.active_requests counts requests in flight.
+//
+// route names the matched route pattern, keeping span names and metric
+// attributes low-cardinality; it returns "" for unmatched requests. The
+// middleware must run inside RequestInfo so spans carry the request ID.
+func Telemetry(t *telemetry.Telemetry, route func(*http.Request) string) func(http.Handler) http.Handler {
+	tracer := t.Tracer()
+	propagator := propagation.TraceContext{}
+
+	// Creating an instrument only fails for an invalid name, and a usable
+	// instrument is returned even then
+	duration, _ := t.Meter().Float64Histogram("http.server.request.duration",
+		metric.WithDescription("Duration of HTTP server requests"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(requestDurationBuckets...),
+	)
+	active, _ := t.Meter().Int64UpDownCounter("http.server.active_requests",
+		metric.WithDescription("Number of HTTP server requests in flight"),
+		metric.WithUnit("{request}"),
+	)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			pattern := route(r)
+
+			method := requestMethod(r)
+			name := method
+			attrs := []attribute.KeyValue{semconv.HTTPRequestMethodKey.String(method)}
+			if pattern != "" {
+				name += " " + pattern
+				attrs = append(attrs, semconv.HTTPRoute(pattern))
+			}
+
+			spanAttrs := append([]attribute.KeyValue{
+				semconv.URLPath(r.URL.Path),
+				semconv.UserAgentOriginal(r.UserAgent()),
+			}, attrs...)
+			if req, ok := audit.RequestFromContext(r.Context()); ok {
+				spanAttrs = append(spanAttrs,
+					semconv.ClientAddress(req.IP),
+					attribute.String("request.id", req.ID),
+				)
+			}
+
+			inFlight := metric.WithAttributes(attrs...)
+			active.Add(r.Context(), 1, inFlight)
+			defer active.Add(r.Context(), -1, inFlight)
+
+			ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := tracer.Start(ctx, name,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(spanAttrs...),
+			)
+			defer span.End()
+
+			wrapped := &responseWriter{
+				ResponseWriter: w,
+				statusCode:     http.StatusOK,
+			}
+
+			next.ServeHTTP(wrapped, r.WithContext(ctx))
+
+			attrs = append(attrs, semconv.HTTPResponseStatusCode(wrapped.statusCode))
+			if wrapped.statusCode >= http.StatusInternalServerError {
+				attrs = append(attrs, semconv.ErrorTypeKey.String(strconv.Itoa(wrapped.statusCode)))
+				span.SetStatus(codes.Error, http.StatusText(wrapped.statusCode))
+			}
+			span.SetAttributes(semconv.HTTPResponseStatusCode(wrapped.statusCode))
+
+			duration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(attrs...))
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"backend/internal/logging"
+	"backend/internal/telemetry"
+)
+
+func newTestTelemetry() (*telemetry.Telemetry, *tracetest.InMemoryExporter, *sdkmetric.ManualReader) {
+	exporter := tracetest.NewInMemoryExporter()
+	reader := sdkmetric.NewManualReader()
+	tel := telemetry.New(
+		sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)),
+		sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)),
+		prometheus.NewRegistry(),
+	)
+	return tel, exporter, reader
+}
+
+func spanAttribute(span tracetest.SpanStub, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTelemetry_Spans(t *testing.T) {
+	tel, exporter, _ := newTestTelemetry()
+	route := func(r *http.Request) string { return "/api/patients/" }
+
+	var handlerSpan trace.SpanContext
+	handler := RequestInfo(nil)(Telemetry(tel, route)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlerSpan = trace.SpanContextFromContext(r.Context())
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/patients/42", nil)
+	req.Header.Set("X-Request-ID", "req-7")
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("Expected one span, got %d", len(spans))
+	}
+	span := spans[0]
+
+	if span.Name != "GET /api/patients/" || span.SpanKind != trace.SpanKindServer {
+		t.Errorf("Expected a server span named by route, got %q %v", span.Name, span.SpanKind)
+	}
+	if span.SpanContext.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" || span.Parent.SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("Expected the caller's trace to be continued, got trace %s parent %s", span.SpanContext.TraceID(), span.Parent.SpanID())
+	}
+	if handlerSpan.SpanID() != span.SpanContext.SpanID() {
+		t.Error("Expected the handler's context to carry the server span")
+	}
+	if span.Status.Code != codes.Error {
+		t.Errorf("Expected server errors to mark the span failed, got %v", span.Status)
+	}
+
+	expected := map[attribute.Key]attribute.Value{
+		"http.route":                attribute.StringValue("/api/patients/"),
+		"url.path":                  attribute.StringValue("/api/patients/42"),
+		"http.response.status_code": attribute.IntValue(http.StatusServiceUnavailable),
+		"request.id":                attribute.StringValue("req-7"),
+	}
+	for key, value := range expected {
+		if got := spanAttribute(span, key); got != value {
+			t.Errorf("Expected span attribute %s %v, got %v", key, value.Emit(), got.Emit())
+		}
+	}
+}
+
+func TestTelemetry_Metrics(t *testing.T) {
+	tel, _, reader := newTestTelemetry()
+	route := func(r *http.Request) string {
+		if r.URL.Path == "/missing" {
+			return ""
+		}
+		return "/api/users"
+	}
+
+	handler := Telemetry(tel, route)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	for _, path := range []string{"/api/users", "/api/users", "/missing"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+
+	counts := map[string]uint64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != "http.server.request.duration" {
+				continue
+			}
+			for _, dp := range m.Data.(metricdata.Histogram[float64]).DataPoints {
+				route, _ := dp.Attributes.Value("http.route")
+				status, _ := dp.Attributes.Value("http.response.status_code")
+				counts[route.AsString()+" "+status.Emit()] += dp.Count
+			}
+		}
+	}
+
+	if counts["/api/users 200"] != 2 || counts[" 404"] != 1 || len(counts) != 2 {
+		t.Errorf("Expected requests counted by route and status, got %v", counts)
+	}
+}
+
+func TestTelemetry_ActiveRequests(t *testing.T) {
+	tel, _, reader := newTestTelemetry()
+
+	active := func() int64 {
+		var rm metricdata.ResourceMetrics
+		if err := reader.Collect(context.Background(), &rm); err != nil {
+			t.Fatalf("Collect failed: %v", err)
+		}
+		var total int64
+		for _, sm := range rm.ScopeMetrics {
+			for _, m := range sm.Metrics {
+				if m.Name == "http.server.active_requests" {
+					for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+						total += dp.Value
+					}
+				}
+			}
+		}
+		return total
+	}
+
+	var during int64
+	handler := Telemetry(tel, func(*http.Request) string { return "/api/uploads/{id}" })(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		during = active()
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPatch, "/api/uploads/9", nil))
+
+	if during != 1 || active() != 0 {
+		t.Errorf("Expected one request in flight while handling and none after, got %d and %d", during, active())
+	}
+}
+
+func TestRequestMethod(t *testing.T) {
+	for method, expected := range map[string]string{http.MethodGet: http.MethodGet, "PROPFIND": "_OTHER"} {
+		if got := requestMethod(httptest.NewRequest(method, "/", nil)); got != expected {
+			t.Errorf("requestMethod(%s) = %s, expected %s", method, got, expected)
+		}
+	}
+}
+
+func TestLogging_TraceID(t *testing.T) {
+	tel, exporter, _ := newTestTelemetry()
+	var out bytes.Buffer
+	logger, _ := logging.New(&out, "info", nil)
+
+	handler := Telemetry(tel, func(*http.Request) string { return "" })(Logging(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/hello", nil))
+
+	var line map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &line); err != nil {
+		t.Fatalf("Expected one JSON line, got %q", out.String())
+	}
+	if span := exporter.GetSpans()[0]; line["trace_id"] != span.SpanContext.TraceID().String() {
+		t.Errorf("Expected the access log to carry trace ID %s, got %v", span.SpanContext.TraceID(), line["trace_id"])
+	}
+}
@@ -0,0 +1,182 @@
+// Package problem writes API errors as RFC 9457 problem details
+// (application/problem+json). Every problem carries a stable,
+// machine-readable code and the request ID; validation failures list the
+// fields at fault. Internal errors are logged with the request ID and never
+// sent to the client.
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"backend/internal/audit"
+	"backend/internal/logging"
+)
+
+// MediaType is the content type of problem responses
+const MediaType = "application/problem+json"
+
+// Codes shared across endpoints. Other errors use a code naming their HTTP
+// status, such as "not_found" or "conflict", unless they set their own.
+const (
+	CodeInvalidJSON      = "invalid_json"
+	CodeValidationFailed = "validation_failed"
+	CodeInternal         = "internal_server_error"
+)
+
+// Field error codes
+const (
+	FieldRequired  = "required"
+	FieldInvalid   = "invalid"
+	FieldTooLong   = "too_long"
+	FieldDuplicate = "duplicate"
+)
+
+// Problem is an RFC 9457 problem details object. Type is always
+// "about:blank", so Title is the HTTP status text and Code identifies the
+// error.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+
+	// Extensions are further members specific to the problem. They must
+	// not reuse the names above.
+	Extensions map[string]interface{} `json:"-"`
+}
+
+// MarshalJSON encodes the problem with its extensions as top-level members
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	type members Problem
+	data, err := json.Marshal((*members)(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return data, err
+	}
+
+	extensions, err := json.Marshal(p.Extensions)
+	if err != nil {
+		return nil, err
+	}
+	// Splice {"type":...} and {"matches":...} into one object
+	return append(append(data[:len(data)-1], ','), extensions[1:]...), nil
+}
+
+// New creates a problem. An empty code is derived from the status.
+func New(status int, code, detail string) *Problem {
+	if code == "" {
+		code = statusCode(status)
+	}
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// statusCode derives a code from an HTTP status: 404 is "not_found"
+func statusCode(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return "error"
+	}
+	return strings.ToLower(strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(text))
+}
+
+// Write sends p as the response to r, filling in the request path and ID
+func Write(w http.ResponseWriter, r *http.Request, p *Problem) {
+	if p.Instance == "" {
+		p.Instance = r.URL.Path
+	}
+	if req, ok := audit.RequestFromContext(r.Context()); ok {
+		p.RequestID = req.ID
+	}
+
+	w.Header().Del("Content-Length")
+	w.Header().Set("Content-Type", MediaType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	if r.Method != http.MethodHead {
+		json.NewEncoder(w).Encode(p)
+	}
+}
+
+// Error sends a problem with the code for its status. It replaces
+// http.Error; detail is shown to the client, so it must not include
+// internal errors.
+func Error(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	Write(w, r, New(status, "", detail))
+}
+
+// ErrorCode sends a problem with a specific code
+func ErrorCode(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	Write(w, r, New(status, code, detail))
+}
+
+// Internal logs err with the request's logger and sends a 500 problem carrying
+// only detail, such as "Failed to get users"
+func Internal(w http.ResponseWriter, r *http.Request, detail string, err error) {
+	logging.FromContext(r.Context()).Error(detail, "error", err, "method", r.Method, "path", r.URL.Path)
+	Write(w, r, New(http.StatusInternalServerError, CodeInternal, detail))
+}
+
+// InvalidJSON reports a request body that could not be decoded
+func InvalidJSON(w http.ResponseWriter, r *http.Request) {
+	ErrorCode(w, r, http.StatusBadRequest, CodeInvalidJSON, "Invalid JSON")
+}
+
+// Invalid reports a bad request. Field errors in err are listed
+// individually under a validation_failed code; any other error is sent as
+// a bad_request with its message.
+func Invalid(w http.ResponseWriter, r *http.Request, err error) {
+	var fields FieldErrors
+	var field FieldError
+	switch {
+	case errors.As(err, &fields):
+	case errors.As(err, &field):
+		fields = FieldErrors{field}
+	default:
+		Error(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	p := New(http.StatusBadRequest, CodeValidationFailed, fields.Error())
+	p.Errors = fields
+	Write(w, r, p)
+}
+
+// FieldError is a validation failure of one request field. It is an error
+// so validators can return it.
+type FieldError struct {
+	Field  string `json:"field"`
+	Code   string `json:"code"`
+	Detail string `json:"detail"`
+}
+
+// Field creates a field error
+func Field(field, code, detail string) FieldError {
+	return FieldError{Field: field, Code: code, Detail: detail}
+}
+
+func (e FieldError) Error() string {
+	return e.Detail
+}
+
+// FieldErrors reports several invalid fields at once
+type FieldErrors []FieldError
+
+func (e FieldErrors) Error() string {
+	details := make([]string, len(e))
+	for i, field := range e {
+		details[i] = field.Detail
+	}
+	return strings.Join(details, "; ")
+}
@@ -0,0 +1,144 @@
+package problem
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"backend/internal/audit"
+	"backend/internal/logging"
+)
+
+// decode reads the problem written to w
+func decode(t *testing.T, w *httptest.ResponseRecorder) map[string]interface{} {
+	t.Helper()
+
+	if contentType := w.Header().Get("Content-Type"); contentType != MediaType {
+		t.Errorf("Expected Content-Type %s, got %q", MediaType, contentType)
+	}
+
+	var body map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode problem: %v", err)
+	}
+	return body
+}
+
+func TestError(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/users/7", nil)
+	req = req.WithContext(audit.WithRequest(req.Context(), audit.Request{ID: "req-1"}))
+	w := httptest.NewRecorder()
+
+	Error(w, req, http.StatusNotFound, "User not found")
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+	body := decode(t, w)
+	expected := map[string]interface{}{
+		"type":       "about:blank",
+		"title":      "Not Found",
+		"status":     float64(404),
+		"detail":     "User not found",
+		"instance":   "/api/users/7",
+		"code":       "not_found",
+		"request_id": "req-1",
+	}
+	for key, value := range expected {
+		if body[key] != value {
+			t.Errorf("Expected %s %v, got %v", key, value, body[key])
+		}
+	}
+}
+
+func TestInternal(t *testing.T) {
+	var logged bytes.Buffer
+	logger, _ := logging.New(&logged, "info", nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+	req = req.WithContext(logging.WithLogger(req.Context(), logger))
+	w := httptest.NewRecorder()
+
+	Internal(w, req, "Failed to get users", errors.New(`pq: relation "users" does not exist`))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status 500, got %d", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "relation") {
+		t.Errorf("Internal error leaked to the client: %s", w.Body.String())
+	}
+	body := decode(t, w)
+	if body["code"] != CodeInternal || body["detail"] != "Failed to get users" {
+		t.Errorf("Unexpected problem %v", body)
+	}
+	if !strings.Contains(logged.String(), `relation \"users\" does not exist`) {
+		t.Errorf("Expected the error to be logged, got %q", logged.String())
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/users", nil)
+
+	w := httptest.NewRecorder()
+	Invalid(w, req, FieldErrors{
+		Field("name", FieldRequired, "name is required"),
+		Field("email", FieldRequired, "email is required"),
+	})
+	var p Problem
+	if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+		t.Fatalf("Failed to decode problem: %v", err)
+	}
+	if w.Code != http.StatusBadRequest || p.Code != CodeValidationFailed || len(p.Errors) != 2 || p.Errors[1].Field != "email" {
+		t.Errorf("Unexpected validation problem %d %+v", w.Code, p)
+	}
+
+	// A wrapped single field error is found too
+	w = httptest.NewRecorder()
+	Invalid(w, req, fmt.Errorf("decoding: %w", Field("sex", FieldInvalid, "sex is invalid")))
+	if body := decode(t, w); body["code"] != CodeValidationFailed || body["detail"] != "sex is invalid" {
+		t.Errorf("Unexpected field problem %v", body)
+	}
+
+	// Other errors are plain bad requests
+	w = httptest.NewRecorder()
+	Invalid(w, req, errors.New("limit must be between 1 and 500"))
+	if body := decode(t, w); body["code"] != "bad_request" || body["errors"] != nil {
+		t.Errorf("Unexpected bad request problem %v", body)
+	}
+}
+
+func TestProblem_Extensions(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/patients", nil)
+	w := httptest.NewRecorder()
+
+	p := New(http.StatusConflict, "possible_duplicate", "Patient may already be registered")
+	p.Extensions = map[string]interface{}{"matches": []int{4, 9}}
+	Write(w, req, p)
+
+	body := decode(t, w)
+	if body["code"] != "possible_duplicate" || body["title"] != "Conflict" {
+		t.Errorf("Unexpected problem %v", body)
+	}
+	if matches, ok := body["matches"].([]interface{}); !ok || len(matches) != 2 {
+		t.Errorf("Expected matches extension, got %v", body["matches"])
+	}
+}
+
+func TestStatusCode(t *testing.T) {
+	tests := map[int]string{
+		http.StatusMethodNotAllowed:      "method_not_allowed",
+		http.StatusRequestEntityTooLarge: "request_entity_too_large",
+		http.StatusUnsupportedMediaType:  "unsupported_media_type",
+		460:                              "error",
+	}
+	for status, code := range tests {
+		if got := New(status, "", "").Code; got != code {
+			t.Errorf("Expected %d to have code %q, got %q", status, code, got)
+		}
+	}
+}
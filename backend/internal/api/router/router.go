@@ -2,73 +2,746 @@ package router
 
 import (
 	"database/sql"
+	"log/slog"
 	"net/http"
+	"slices"
 	"strings"
+	"sync/atomic"
 
 	"backend/internal/api/handlers"
 	"backend/internal/api/middleware"
+	"backend/internal/api/problem"
+	"backend/internal/audit"
+	"backend/internal/auth"
 	"backend/internal/config"
-	"backend/internal/database"
+	"backend/internal/health"
+	"backend/internal/hl7"
+	"backend/internal/models"
+	"backend/internal/ratelimit"
+	"backend/internal/slide"
+	"backend/internal/storage"
+	"backend/internal/telemetry"
 )
 
-// New creates a new HTTP router with all routes configured
-func New(db *sql.DB, cfg *config.Config) http.Handler {
-	// Run database migrations
-	if err := database.Migrate(db); err != nil {
-		panic("Failed to run database migrations: " + err.Error())
+// New creates a new HTTP router with all routes configured. Settings that
+// can be reloaded are read from settings per request; the rest are read
+// once. Uploaded files are stored in blobs and rendered slide tiles are
+// cached in tiles. Requests are logged to logger and traced and measured
+// through t; the health probes run checks.
+func New(db *sql.DB, settings *config.Watcher, blobs storage.BlobStore, tiles *slide.Cache, logger *slog.Logger, t *telemetry.Telemetry, checks *health.Registry) http.Handler {
+	cfg := settings.Current()
+	frontendURL := func() string { return settings.Current().FrontendURL }
+
+	// CORS policies are rebuilt when their settings are reloaded
+	var cors atomic.Pointer[middleware.CORSPolicies]
+	cors.Store(corsPolicies(cfg))
+
+	// Rate limit policies likewise follow reloads; the store is fixed
+	var rateLimits atomic.Pointer[ratelimit.Policies]
+	rateLimits.Store(rateLimitPolicies(cfg))
+	var rateLimitStore ratelimit.Store = ratelimit.NewMemoryStore()
+	if cfg.RateLimitStore == "postgres" {
+		rateLimitStore = ratelimit.NewPostgresStore(db)
+	}
+	limiter := ratelimit.NewLimiter(rateLimitStore, nil)
+
+	settings.Subscribe(func(_, next *config.Config) {
+		cors.Store(corsPolicies(next))
+		rateLimits.Store(rateLimitPolicies(next))
+	})
+
+	// Session management shared by the auth handler and middleware
+	sessionOpts := auth.OptionsFromConfig(cfg)
+	sessionOpts.ClientIP = func(r *http.Request) string {
+		req, _ := audit.RequestFromContext(r.Context())
+		return req.IP
+	}
+	sessions := auth.NewManager(models.NewUserRepository(db), models.NewSessionRepository(db), sessionOpts)
+
+	// Bearer API keys for machine clients
+	apiKeys := auth.NewAPIKeyAuthenticator(models.NewAPIKeyRepository(db), models.NewUserRepository(db), models.NewRoleRepository(db))
+
+	// Verified results are reported over HL7 when an outbound destination is configured
+	var hl7Outbox *hl7.Outbox
+	if cfg.HL7OutboundAddr != "" {
+		hl7Outbox = hl7.NewOutbox(db, hl7.HeaderFromConfig(cfg))
 	}
 
 	// Initialize handlers
 	userHandler := handlers.NewUserHandler(db)
-	healthHandler := handlers.NewHealthHandler(db)
+	authHandler := handlers.NewAuthHandler(db, sessions)
+	roleHandler := handlers.NewRoleHandler(db)
+	apiKeyHandler := handlers.NewAPIKeyHandler(db)
+	patientHandler := handlers.NewPatientHandler(db)
+	testOrderHandler := handlers.NewTestOrderHandler(db)
+	testResultHandler := handlers.NewTestResultHandler(db, hl7Outbox)
+	inferenceHandler := handlers.NewInferenceHandler(db, cfg.InferenceMaxAttempts)
+	uploadHandler := handlers.NewUploadHandler(db, blobs, cfg.UploadMaxSize, cfg.UploadChunkTimeout)
+	slideHandler := handlers.NewSlideHandler(db, blobs, tiles)
+	auditHandler := handlers.NewAuditHandler(db)
+	fhirHandler := handlers.NewFHIRHandler(db)
+	healthHandler := handlers.NewHealthHandler(checks, false)
+	configHandler := handlers.NewConfigHandler(settings)
 	helloHandler := handlers.NewHelloHandler(db)
 
 	// Create main router
 	mux := http.NewServeMux()
 
-	// Health endpoint
+	// Health endpoints; /health predates the split probes
 	mux.HandleFunc("/health", healthHandler.Check)
+	mux.HandleFunc("/livez", healthHandler.Livez)
+	mux.HandleFunc("/readyz", healthHandler.Readyz)
+	mux.HandleFunc("/startupz", healthHandler.Startupz)
 
 	// Hello endpoint for frontend integration testing
 	mux.HandleFunc("/api/hello", helloHandler.GetHello)
 
+	// Authentication endpoints
+	mux.HandleFunc("/api/auth/login", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			problem.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		authHandler.Login(w, r)
+	})
+
+	mux.HandleFunc("/api/auth/logout", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			problem.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		authHandler.Logout(w, r)
+	})
+
+	mux.HandleFunc("/api/auth/me", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			problem.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		authHandler.Me(w, r)
+	})
+
+	mux.HandleFunc("/api/auth/password", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			problem.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		authHandler.ChangePassword(w, r)
+	})
+
+	// Single sign-on endpoints, only when an identity provider is configured
+	if cfg.OIDCDiscoveryURL != "" {
+		oidcClient := auth.NewOIDCClient(auth.OIDCOptionsFromConfig(cfg), models.NewUserIdentityRepository(db), models.NewUserRepository(db))
+		oidcHandler := handlers.NewOIDCHandler(db, oidcClient, sessions, frontendURL)
+
+		mux.HandleFunc("/api/auth/oidc/login", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				problem.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+				return
+			}
+			oidcHandler.Login(w, r)
+		})
+
+		mux.HandleFunc("/api/auth/oidc/callback", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				problem.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+				return
+			}
+			oidcHandler.Callback(w, r)
+		})
+	}
+
+	// Permission-guarded user handlers
+	getUsers := middleware.Require(auth.PermissionUsersRead)(http.HandlerFunc(userHandler.GetUsers))
+	createUser := middleware.Require(auth.PermissionUsersWrite)(http.HandlerFunc(userHandler.CreateUser))
+	getUser := middleware.Require(auth.PermissionUsersRead)(http.HandlerFunc(userHandler.GetUser))
+	updateUser := middleware.Require(auth.PermissionUsersWrite)(http.HandlerFunc(userHandler.UpdateUser))
+	deleteUser := middleware.Require(auth.PermissionUsersDelete)(http.HandlerFunc(userHandler.DeleteUser))
+
+	// Role management is admin-only
+	getRoles := middleware.Require(auth.PermissionRolesManage)(http.HandlerFunc(roleHandler.GetRoles))
+	getUserRoles := middleware.Require(auth.PermissionRolesManage)(http.HandlerFunc(roleHandler.GetUserRoles))
+	assignRole := middleware.Require(auth.PermissionRolesManage)(http.HandlerFunc(roleHandler.AssignRole))
+	revokeRole := middleware.Require(auth.PermissionRolesManage)(http.HandlerFunc(roleHandler.RevokeRole))
+
 	// User endpoints with method routing
 	mux.HandleFunc("/api/users", func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodGet:
-			userHandler.GetUsers(w, r)
+			getUsers.ServeHTTP(w, r)
 		case http.MethodPost:
-			userHandler.CreateUser(w, r)
+			createUser.ServeHTTP(w, r)
 		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			problem.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
 		}
 	})
 
-	// User by ID endpoints
+	// User by ID and user role endpoints
 	mux.HandleFunc("/api/users/", func(w http.ResponseWriter, r *http.Request) {
-		// Check if this is a specific user endpoint (has ID)
-		if strings.Count(r.URL.Path, "/") != 3 || strings.HasSuffix(r.URL.Path, "/") {
-			http.Error(w, "Not found", http.StatusNotFound)
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/users/"), "/")
+
+		switch {
+		// /api/users/{id}
+		case len(parts) == 1 && parts[0] != "":
+			switch r.Method {
+			case http.MethodGet:
+				getUser.ServeHTTP(w, r)
+			case http.MethodPut:
+				updateUser.ServeHTTP(w, r)
+			case http.MethodDelete:
+				deleteUser.ServeHTTP(w, r)
+			default:
+				problem.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+			}
+
+		// /api/users/{id}/roles
+		case len(parts) == 2 && parts[1] == "roles":
+			if r.Method != http.MethodGet {
+				problem.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+				return
+			}
+			getUserRoles.ServeHTTP(w, r)
+
+		// /api/users/{id}/roles/{role}
+		case len(parts) == 3 && parts[1] == "roles" && parts[2] != "":
+			switch r.Method {
+			case http.MethodPut:
+				assignRole.ServeHTTP(w, r)
+			case http.MethodDelete:
+				revokeRole.ServeHTTP(w, r)
+			default:
+				problem.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+			}
+
+		default:
+			problem.Error(w, r, http.StatusNotFound, "Not found")
+		}
+	})
+
+	// Slide viewing; each handler also checks access to the individual slide
+	getSlide := middleware.Require(auth.PermissionSlidesRead)(http.HandlerFunc(slideHandler.GetSlide))
+	getDZI := middleware.Require(auth.PermissionSlidesRead)(http.HandlerFunc(slideHandler.GetDZI))
+	getDZITile := middleware.Require(auth.PermissionSlidesRead)(http.HandlerFunc(slideHandler.GetDZITile))
+	redirectIIIF := middleware.Require(auth.PermissionSlidesRead)(http.HandlerFunc(slideHandler.RedirectIIIF))
+	getIIIFInfo := middleware.Require(auth.PermissionSlidesRead)(http.HandlerFunc(slideHandler.GetIIIFInfo))
+	getIIIFImage := middleware.Require(auth.PermissionSlidesRead)(http.HandlerFunc(slideHandler.GetIIIFImage))
+	getSlideGrants := middleware.Require(auth.PermissionSlidesShare)(http.HandlerFunc(slideHandler.GetGrants))
+	grantSlideAccess := middleware.Require(auth.PermissionSlidesShare)(http.HandlerFunc(slideHandler.GrantAccess))
+	revokeSlideAccess := middleware.Require(auth.PermissionSlidesShare)(http.HandlerFunc(slideHandler.RevokeAccess))
+
+	// Slide endpoints: metadata, Deep Zoom, IIIF Image API and access grants
+	mux.HandleFunc("/api/slides/", func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/slides/"), "/")
+
+		switch {
+		// /api/slides/{id}/grants/{userId}
+		case len(parts) == 3 && parts[1] == "grants":
+			switch r.Method {
+			case http.MethodPut:
+				grantSlideAccess.ServeHTTP(w, r)
+			case http.MethodDelete:
+				revokeSlideAccess.ServeHTTP(w, r)
+			default:
+				problem.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+			}
+
+		// Everything else is read-only
+		case r.Method != http.MethodGet && r.Method != http.MethodHead:
+			problem.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+
+		// /api/slides/{id}.dzi
+		case len(parts) == 1 && strings.HasSuffix(parts[0], ".dzi"):
+			getDZI.ServeHTTP(w, r)
+
+		// /api/slides/{id}
+		case len(parts) == 1 && parts[0] != "":
+			getSlide.ServeHTTP(w, r)
+
+		// /api/slides/{id}_files/{level}/{col}_{row}.jpeg
+		case len(parts) == 3 && strings.HasSuffix(parts[0], "_files"):
+			getDZITile.ServeHTTP(w, r)
+
+		// /api/slides/{id}/iiif
+		case len(parts) == 2 && parts[1] == "iiif":
+			redirectIIIF.ServeHTTP(w, r)
+
+		// /api/slides/{id}/iiif/info.json
+		case len(parts) == 3 && parts[1] == "iiif" && parts[2] == "info.json":
+			getIIIFInfo.ServeHTTP(w, r)
+
+		// /api/slides/{id}/iiif/{region}/{size}/{rotation}/{quality}.{format}
+		case len(parts) == 6 && parts[1] == "iiif":
+			getIIIFImage.ServeHTTP(w, r)
+
+		// /api/slides/{id}/grants
+		case len(parts) == 2 && parts[1] == "grants":
+			getSlideGrants.ServeHTTP(w, r)
+
+		default:
+			problem.Error(w, r, http.StatusNotFound, "Not found")
+		}
+	})
+
+	// Role endpoints
+	mux.HandleFunc("/api/roles", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			problem.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
 			return
 		}
+		getRoles.ServeHTTP(w, r)
+	})
+
+	// Permission-guarded patient handlers
+	getPatients := middleware.Require(auth.PermissionPatientsRead)(http.HandlerFunc(patientHandler.GetPatients))
+	createPatient := middleware.Require(auth.PermissionPatientsWrite)(http.HandlerFunc(patientHandler.CreatePatient))
+	getPatient := middleware.Require(auth.PermissionPatientsRead)(http.HandlerFunc(patientHandler.GetPatient))
+	updatePatient := middleware.Require(auth.PermissionPatientsWrite)(http.HandlerFunc(patientHandler.UpdatePatient))
+	getPatientDuplicates := middleware.Require(auth.PermissionPatientsRead)(http.HandlerFunc(patientHandler.GetDuplicates))
+	getPatientMerges := middleware.Require(auth.PermissionPatientsRead)(http.HandlerFunc(patientHandler.GetMerges))
+	mergePatient := middleware.Require(auth.PermissionPatientsMerge)(http.HandlerFunc(patientHandler.MergePatient))
+	reversePatientMerge := middleware.Require(auth.PermissionPatientsMerge)(http.HandlerFunc(patientHandler.ReverseMerge))
+
+	// Patient registry endpoints
+	mux.HandleFunc("/api/patients", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			getPatients.ServeHTTP(w, r)
+		case http.MethodPost:
+			createPatient.ServeHTTP(w, r)
+		default:
+			problem.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	})
+
+	mux.HandleFunc("/api/patients/", func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/patients/"), "/")
+
+		switch {
+		// /api/patients/{id}
+		case len(parts) == 1 && parts[0] != "":
+			switch r.Method {
+			case http.MethodGet:
+				getPatient.ServeHTTP(w, r)
+			case http.MethodPut:
+				updatePatient.ServeHTTP(w, r)
+			default:
+				problem.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+			}
+
+		// /api/patients/{id}/duplicates
+		case len(parts) == 2 && parts[1] == "duplicates":
+			if r.Method != http.MethodGet {
+				problem.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+				return
+			}
+			getPatientDuplicates.ServeHTTP(w, r)
+
+		// /api/patients/{id}/merges
+		case len(parts) == 2 && parts[1] == "merges":
+			switch r.Method {
+			case http.MethodGet:
+				getPatientMerges.ServeHTTP(w, r)
+			case http.MethodPost:
+				mergePatient.ServeHTTP(w, r)
+			default:
+				problem.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+			}
+
+		// /api/patients/{id}/merges/{mergeId}/reverse
+		case len(parts) == 4 && parts[1] == "merges" && parts[3] == "reverse":
+			if r.Method != http.MethodPost {
+				problem.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+				return
+			}
+			reversePatientMerge.ServeHTTP(w, r)
+
+		default:
+			problem.Error(w, r, http.StatusNotFound, "Not found")
+		}
+	})
+
+	// Permission-guarded test order handlers
+	getTestOrders := middleware.Require(auth.PermissionTestsRead)(http.HandlerFunc(testOrderHandler.GetTestOrders))
+	createTestOrder := middleware.Require(auth.PermissionTestsWrite)(http.HandlerFunc(testOrderHandler.CreateTestOrder))
+	getTestOrder := middleware.Require(auth.PermissionTestsRead)(http.HandlerFunc(testOrderHandler.GetTestOrder))
+	updateTestOrder := middleware.Require(auth.PermissionTestsWrite)(http.HandlerFunc(testOrderHandler.UpdateTestOrder))
+	changeTestOrderStatus := middleware.Require(auth.PermissionTestsWrite)(http.HandlerFunc(testOrderHandler.ChangeStatus))
+	deleteTestOrder := middleware.Require(auth.PermissionTestsDelete)(http.HandlerFunc(testOrderHandler.DeleteTestOrder))
 
+	// Test order endpoints
+	mux.HandleFunc("/api/tests", func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodGet:
-			userHandler.GetUser(w, r)
-		case http.MethodPut:
-			userHandler.UpdateUser(w, r)
+			getTestOrders.ServeHTTP(w, r)
+		case http.MethodPost:
+			createTestOrder.ServeHTTP(w, r)
+		default:
+			problem.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	})
+
+	mux.HandleFunc("/api/tests/", func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/tests/"), "/")
+
+		switch {
+		// /api/tests/{id}
+		case len(parts) == 1 && parts[0] != "":
+			switch r.Method {
+			case http.MethodGet:
+				getTestOrder.ServeHTTP(w, r)
+			case http.MethodPut:
+				updateTestOrder.ServeHTTP(w, r)
+			case http.MethodDelete:
+				deleteTestOrder.ServeHTTP(w, r)
+			default:
+				problem.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+			}
+
+		// /api/tests/{id}/status
+		case len(parts) == 2 && parts[1] == "status":
+			if r.Method != http.MethodPost {
+				problem.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+				return
+			}
+			changeTestOrderStatus.ServeHTTP(w, r)
+
+		default:
+			problem.Error(w, r, http.StatusNotFound, "Not found")
+		}
+	})
+
+	// Permission-guarded test result handlers
+	getTestResult := middleware.Require(auth.PermissionResultsRead)(http.HandlerFunc(testResultHandler.GetResult))
+	createTestResult := middleware.Require(auth.PermissionResultsWrite)(http.HandlerFunc(testResultHandler.CreateResult))
+	updateTestResult := middleware.Require(auth.PermissionResultsWrite)(http.HandlerFunc(testResultHandler.UpdateResult))
+	verifyTestResult := middleware.Require(auth.PermissionResultsVerify)(http.HandlerFunc(testResultHandler.VerifyResult))
+	amendTestResult := middleware.Require(auth.PermissionResultsVerify)(http.HandlerFunc(testResultHandler.AmendResult))
+
+	// Test result endpoints, keyed by accession number
+	mux.HandleFunc("/api/results/", func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/results/"), "/")
+
+		switch {
+		// /api/results/{accession}
+		case len(parts) == 1 && parts[0] != "":
+			switch r.Method {
+			case http.MethodGet:
+				getTestResult.ServeHTTP(w, r)
+			case http.MethodPost:
+				createTestResult.ServeHTTP(w, r)
+			case http.MethodPut:
+				updateTestResult.ServeHTTP(w, r)
+			default:
+				problem.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+			}
+
+		// /api/results/{accession}/verify
+		case len(parts) == 2 && parts[1] == "verify":
+			if r.Method != http.MethodPost {
+				problem.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+				return
+			}
+			verifyTestResult.ServeHTTP(w, r)
+
+		// /api/results/{accession}/amendments
+		case len(parts) == 2 && parts[1] == "amendments":
+			if r.Method != http.MethodPost {
+				problem.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+				return
+			}
+			amendTestResult.ServeHTTP(w, r)
+
+		default:
+			problem.Error(w, r, http.StatusNotFound, "Not found")
+		}
+	})
+
+	// Permission-guarded inference job handlers
+	getInferenceJobs := middleware.Require(auth.PermissionInferenceRead)(http.HandlerFunc(inferenceHandler.GetJobs))
+	createInferenceJob := middleware.Require(auth.PermissionInferenceRun)(http.HandlerFunc(inferenceHandler.CreateJob))
+	getInferenceJob := middleware.Require(auth.PermissionInferenceRead)(http.HandlerFunc(inferenceHandler.GetJob))
+
+	// Inference job endpoints
+	mux.HandleFunc("/api/inference/jobs", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			getInferenceJobs.ServeHTTP(w, r)
+		case http.MethodPost:
+			createInferenceJob.ServeHTTP(w, r)
+		default:
+			problem.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	})
+
+	mux.HandleFunc("/api/inference/jobs/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			problem.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		getInferenceJob.ServeHTTP(w, r)
+	})
+
+	// API key endpoints; keys belong to the signed-in user
+	mux.HandleFunc("/api/api-keys", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			apiKeyHandler.GetAPIKeys(w, r)
+		case http.MethodPost:
+			apiKeyHandler.CreateAPIKey(w, r)
+		default:
+			problem.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	})
+
+	mux.HandleFunc("/api/api-keys/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			problem.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		apiKeyHandler.RevokeAPIKey(w, r)
+	})
+
+	// Audit log review for compliance officers
+	getAuditLog := middleware.Require(auth.PermissionAuditRead)(http.HandlerFunc(auditHandler.GetAuditLog))
+	verifyAuditLog := middleware.Require(auth.PermissionAuditRead)(http.HandlerFunc(auditHandler.VerifyAuditLog))
+
+	mux.HandleFunc("/api/audit", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			problem.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		getAuditLog.ServeHTTP(w, r)
+	})
+
+	mux.HandleFunc("/api/audit/verify", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			problem.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		verifyAuditLog.ServeHTTP(w, r)
+	})
+
+	// Prometheus metrics, unless they are served on a separate internal port;
+	// scrapers authenticate with an API key holding metrics:read
+	if cfg.MetricsPort == "" {
+		getMetrics := middleware.Require(auth.PermissionMetricsRead)(t.MetricsHandler())
+		mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				problem.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+				return
+			}
+			getMetrics.ServeHTTP(w, r)
+		})
+	}
+
+	// Effective configuration with secrets redacted, for administrators
+	getConfig := middleware.Require(auth.PermissionConfigRead)(http.HandlerFunc(configHandler.GetConfig))
+	mux.HandleFunc("/debug/config", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			problem.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		getConfig.ServeHTTP(w, r)
+	})
+
+	// FHIR R4 API for EHR integrations; each resource type needs the same
+	// permission as the records it is mapped from
+	fhirSearch := map[string]http.Handler{
+		"Patient":          middleware.Require(auth.PermissionPatientsRead)(http.HandlerFunc(fhirHandler.SearchPatients)),
+		"ServiceRequest":   middleware.Require(auth.PermissionTestsRead)(http.HandlerFunc(fhirHandler.SearchServiceRequests)),
+		"DiagnosticReport": middleware.Require(auth.PermissionResultsRead)(http.HandlerFunc(fhirHandler.SearchDiagnosticReports)),
+		"Observation":      middleware.Require(auth.PermissionResultsRead)(http.HandlerFunc(fhirHandler.SearchObservations)),
+	}
+	fhirRead := map[string]http.Handler{
+		"Patient":          middleware.Require(auth.PermissionPatientsRead)(http.HandlerFunc(fhirHandler.ReadPatient)),
+		"ServiceRequest":   middleware.Require(auth.PermissionTestsRead)(http.HandlerFunc(fhirHandler.ReadServiceRequest)),
+		"DiagnosticReport": middleware.Require(auth.PermissionResultsRead)(http.HandlerFunc(fhirHandler.ReadDiagnosticReport)),
+		"Observation":      middleware.Require(auth.PermissionResultsRead)(http.HandlerFunc(fhirHandler.ReadObservation)),
+	}
+
+	mux.HandleFunc("/fhir/", func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/fhir/"), "/")
+
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			problem.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		switch {
+		// /fhir/metadata
+		case len(parts) == 1 && parts[0] == "metadata":
+			fhirHandler.Metadata(w, r)
+
+		// /fhir/{type}
+		case len(parts) == 1 && fhirSearch[parts[0]] != nil:
+			fhirSearch[parts[0]].ServeHTTP(w, r)
+
+		// /fhir/{type}/{id}
+		case len(parts) == 2 && parts[1] != "" && fhirRead[parts[0]] != nil:
+			fhirRead[parts[0]].ServeHTTP(w, r)
+
+		default:
+			problem.Error(w, r, http.StatusNotFound, "Not found")
+		}
+	})
+
+	// Permission-guarded upload handlers
+	getUploads := middleware.Require(auth.PermissionSlidesRead)(http.HandlerFunc(uploadHandler.GetUploads))
+	createUpload := middleware.Require(auth.PermissionSlidesWrite)(http.HandlerFunc(uploadHandler.CreateUpload))
+	getUpload := middleware.Require(auth.PermissionSlidesRead)(http.HandlerFunc(uploadHandler.GetUpload))
+	headUpload := middleware.Require(auth.PermissionSlidesWrite)(http.HandlerFunc(uploadHandler.HeadUpload))
+	patchUpload := middleware.Require(auth.PermissionSlidesWrite)(http.HandlerFunc(uploadHandler.PatchUpload))
+	deleteUpload := middleware.Require(auth.PermissionSlidesWrite)(http.HandlerFunc(uploadHandler.DeleteUpload))
+
+	// Uploads stream binary chunks far larger than RequestValidation allows,
+	// so they are routed around it
+	root := http.NewServeMux()
+	root.Handle("/", middleware.RequestValidation(mux))
+
+	// Upload endpoints (tus protocol)
+	root.HandleFunc("/api/uploads", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			getUploads.ServeHTTP(w, r)
+		case http.MethodPost:
+			createUpload.ServeHTTP(w, r)
+		default:
+			problem.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	})
+
+	root.HandleFunc("/api/uploads/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			getUpload.ServeHTTP(w, r)
+		case http.MethodHead:
+			headUpload.ServeHTTP(w, r)
+		case http.MethodPatch:
+			patchUpload.ServeHTTP(w, r)
 		case http.MethodDelete:
-			userHandler.DeleteUser(w, r)
+			deleteUpload.ServeHTTP(w, r)
 		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			problem.Error(w, r, http.StatusMethodNotAllowed, "Method not allowed")
 		}
 	})
 
 	// Apply middleware (order matters - applied in reverse)
-	handler := middleware.RequestValidation(mux)
-	handler = middleware.CORS(cfg.FrontendURL)(handler)
+	var handler http.Handler = root
+	handler = middleware.RateLimit(limiter, rateLimits.Load)(handler)
+	handler = middleware.Authenticate(sessions, models.NewRoleRepository(db))(handler)
+	handler = middleware.APIKey(apiKeys)(handler)
+	handler = middleware.RateLimitFailedAuth(limiter, func() ratelimit.Policy { return signInPolicy(settings.Current()) })(handler)
+	handler = middleware.DynamicCORS(cors.Load)(handler)
 	handler = middleware.HTTPSEnforcement(cfg.Environment)(handler)
-	handler = middleware.Logging(handler)
+	handler = middleware.Logging(logger)(handler)
+	handler = middleware.Telemetry(t, routePattern(root, mux, fhirRead))(handler)
+	handler = middleware.RequestInfo(cfg.TrustedProxyPrefixes())(handler)
 
 	return handler
 }
+
+// corsPolicies builds the CORS policies from cfg. Browsers on the frontend
+// and the other allowed origins may call the API with credentials; tus
+// uploads also send and read the tus protocol headers.
+func corsPolicies(cfg *config.Config) *middleware.CORSPolicies {
+	api := middleware.CORSPolicy{
+		AllowedOrigins:   append([]string{cfg.FrontendURL}, cfg.CORSAllowedOrigins...),
+		AllowedMethods:   []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "If-None-Match", "X-Request-ID"},
+		ExposedHeaders:   cfg.CORSExposedHeaders,
+		AllowCredentials: true,
+		MaxAge:           cfg.CORSMaxAge,
+	}
+
+	uploads := api
+	uploads.AllowedHeaders = append(slices.Clone(api.AllowedHeaders), "Tus-Resumable", "Upload-Length", "Upload-Metadata", "Upload-Offset", "Upload-Checksum")
+	uploads.ExposedHeaders = append(slices.Clone(api.ExposedHeaders), "Tus-Resumable", "Tus-Version", "Tus-Max-Size", "Upload-Length", "Upload-Offset")
+
+	return middleware.NewCORSPolicies(api, map[string]middleware.CORSPolicy{"/api/uploads": uploads})
+}
+
+// rateLimitPolicies builds the rate limit policies from cfg. Sign-in is
+// limited strictly against password guessing, as is creating users and API
+// keys; health probes are never limited.
+func rateLimitPolicies(cfg *config.Config) *ratelimit.Policies {
+	signIn := signInPolicy(cfg)
+	account := ratelimit.Policy{Name: "account", Limit: ratelimit.Limit{Requests: cfg.RateLimitAccountRequests, Period: cfg.RateLimitAccountPeriod}}
+	probes := ratelimit.Policy{Name: "probes"}
+
+	return ratelimit.NewPolicies(
+		ratelimit.Policy{Name: "default", Limit: ratelimit.Limit{Requests: cfg.RateLimitRequests, Period: cfg.RateLimitPeriod}},
+		map[string]ratelimit.Policy{
+			"POST /api/auth/login":        signIn,
+			"POST /api/auth/password":     signIn,
+			"GET /api/auth/oidc/login":    signIn,
+			"GET /api/auth/oidc/callback": signIn,
+			"POST /api/users":             account,
+			"POST /api/api-keys":          account,
+			"/health":                     probes,
+			"/livez":                      probes,
+			"/readyz":                     probes,
+			"/startupz":                   probes,
+		},
+	)
+}
+
+// signInPolicy limits attempts to sign in, by password or single sign-on,
+// and failed API key authentication, which share a bucket per address
+func signInPolicy(cfg *config.Config) ratelimit.Policy {
+	return ratelimit.Policy{Name: "auth", Limit: ratelimit.Limit{Requests: cfg.RateLimitAuthRequests, Period: cfg.RateLimitAuthPeriod}}
+}
+
+// routeTemplates are the parameterised routes the prefix handlers above
+// dispatch by hand. Telemetry labels requests with these templates rather
+// than raw paths, keeping metric series bounded. Wildcards span whole
+// segments, so /api/slides/{id}.dzi is counted under /api/slides/{id}.
+var routeTemplates = []string{
+	"/api/users/{id}",
+	"/api/users/{id}/roles",
+	"/api/users/{id}/roles/{role}",
+	"/api/slides/{id}",
+	"/api/slides/{id_files}/{level}/{tile}",
+	"/api/slides/{id}/iiif",
+	"/api/slides/{id}/iiif/info.json",
+	"/api/slides/{id}/iiif/{region}/{size}/{rotation}/{quality}",
+	"/api/slides/{id}/grants",
+	"/api/slides/{id}/grants/{userId}",
+	"/api/patients/{id}",
+	"/api/patients/{id}/duplicates",
+	"/api/patients/{id}/merges",
+	"/api/patients/{id}/merges/{mergeId}/reverse",
+	"/api/tests/{id}",
+	"/api/tests/{id}/status",
+	"/api/results/{accession}",
+	"/api/results/{accession}/verify",
+	"/api/results/{accession}/amendments",
+	"/api/inference/jobs/{id}",
+	"/api/api-keys/{id}",
+	"/api/uploads/{id}",
+	"/fhir/metadata",
+}
+
+// routePattern names requests by the template they match, falling back to
+// the pattern registered on root or mux. Requests matching nothing, such as
+// unknown paths under a prefix, are named by the prefix.
+func routePattern(root, mux *http.ServeMux, fhirResources map[string]http.Handler) func(*http.Request) string {
+	templates := http.NewServeMux()
+	for _, template := range routeTemplates {
+		templates.Handle(template, http.NotFoundHandler())
+	}
+	for resourceType := range fhirResources {
+		templates.Handle("/fhir/"+resourceType, http.NotFoundHandler())
+		templates.Handle("/fhir/"+resourceType+"/{id}", http.NotFoundHandler())
+	}
+
+	return func(r *http.Request) string {
+		if _, pattern := templates.Handler(r); pattern != "" {
+			return pattern
+		}
+		if _, pattern := root.Handler(r); pattern != "/" {
+			return pattern
+		}
+		_, pattern := mux.Handler(r)
+		return pattern
+	}
+}
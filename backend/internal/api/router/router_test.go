@@ -0,0 +1,86 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"backend/internal/config"
+)
+
+func TestRoutePattern(t *testing.T) {
+	handler := http.NotFoundHandler()
+
+	mux := http.NewServeMux()
+	for _, pattern := range []string{"/health", "/api/users", "/api/users/", "/api/slides/", "/fhir/"} {
+		mux.Handle(pattern, handler)
+	}
+	root := http.NewServeMux()
+	for _, pattern := range []string{"/", "/api/uploads", "/api/uploads/"} {
+		root.Handle(pattern, handler)
+	}
+	route := routePattern(root, mux, map[string]http.Handler{"Patient": handler})
+
+	tests := map[string]string{
+		"/health":                         "/health",
+		"/api/users":                      "/api/users",
+		"/api/users/42":                   "/api/users/{id}",
+		"/api/users/42/roles/admin":       "/api/users/{id}/roles/{role}",
+		"/api/users/42/unknown/path":      "/api/users/",
+		"/api/slides/7_files/12/3_4.jpeg": "/api/slides/{id_files}/{level}/{tile}",
+		"/api/slides/7/iiif/info.json":    "/api/slides/{id}/iiif/info.json",
+		"/api/slides/7/grants/3":          "/api/slides/{id}/grants/{userId}",
+		"/api/uploads":                    "/api/uploads",
+		"/api/uploads/f81d4fae":           "/api/uploads/{id}",
+		"/fhir/Patient/12":                "/fhir/Patient/{id}",
+		"/fhir/Medication":                "/fhir/",
+		"/not/a/route":                    "",
+	}
+	for path, expected := range tests {
+		if got := route(httptest.NewRequest(http.MethodGet, path, nil)); got != expected {
+			t.Errorf("Route for %s = %q, expected %q", path, got, expected)
+		}
+	}
+}
+
+func TestCORSPolicies(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.CORSAllowedOrigins = []string{"https://*.preview.example.com"}
+	policies := corsPolicies(cfg)
+
+	if got := policies.For("/api/users").AllowedOrigins; !slices.Equal(got, []string{cfg.FrontendURL, "https://*.preview.example.com"}) {
+		t.Errorf("Expected the frontend and configured origins to be allowed, got %v", got)
+	}
+	if !slices.Contains(policies.For("/api/uploads/7").AllowedHeaders, "Upload-Offset") {
+		t.Error("Expected uploads to allow the tus headers")
+	}
+	if slices.Contains(policies.For("/api/users").AllowedHeaders, "Upload-Offset") {
+		t.Error("Expected other routes not to allow the tus headers")
+	}
+	if !slices.Contains(policies.For("/api/uploads").ExposedHeaders, "X-Request-ID") {
+		t.Error("Expected uploads to keep the configured exposed headers")
+	}
+}
+
+func TestRateLimitPolicies(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.RateLimitAuthRequests = 0
+	policies := rateLimitPolicies(cfg)
+
+	tests := []struct {
+		method, path, expected string
+		unlimited              bool
+	}{
+		{http.MethodPost, "/api/auth/login", "auth", true},
+		{http.MethodPost, "/api/users", "account", false},
+		{http.MethodGet, "/api/users", "default", false},
+		{http.MethodGet, "/readyz", "probes", true},
+	}
+	for _, tt := range tests {
+		policy := policies.For(httptest.NewRequest(tt.method, tt.path, nil))
+		if policy.Name != tt.expected || policy.Limit.Unlimited() != tt.unlimited {
+			t.Errorf("%s %s: expected policy %s (unlimited %t), got %+v", tt.method, tt.path, tt.expected, tt.unlimited, policy)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+// Package audit records who read or changed what in the append-only,
+// hash-chained audit log, along with the request it happened in.
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"backend/internal/auth"
+	"backend/internal/models"
+)
+
+// Store is the subset of models.AuditRepository used by the Logger
+type Store interface {
+	Append(ctx context.Context, entry *models.AuditEntry) error
+}
+
+// Event describes one audited action. Before and After are the states of
+// the resource around a mutation; Before is nil for creates and After is nil
+// for deletes, and only fields that differ are recorded. Details carries
+// other context, such as the filters of a search or the records it returned.
+type Event struct {
+	Action       string
+	ResourceType string
+	ResourceID   string
+	Before       interface{}
+	After        interface{}
+	Details      interface{}
+}
+
+// Logger records events, attributing them to the caller and request found
+// in the context
+type Logger struct {
+	store Store
+	now   func() time.Time
+}
+
+// NewLogger creates a logger appending to store
+func NewLogger(store Store) *Logger {
+	return &Logger{store: store, now: time.Now}
+}
+
+// Record appends an event to the audit log
+func (l *Logger) Record(ctx context.Context, event Event) error {
+	changes, err := Diff(event.Before, event.After)
+	if err != nil {
+		return err
+	}
+
+	entry := &models.AuditEntry{
+		OccurredAt:   l.now(),
+		Action:       event.Action,
+		ResourceType: event.ResourceType,
+		ResourceID:   event.ResourceID,
+		Changes:      changes,
+	}
+
+	if event.Details != nil {
+		details, err := toJSONValue(event.Details)
+		if err != nil {
+			return err
+		}
+		if entry.Details, err = json.Marshal(redact(details)); err != nil {
+			return err
+		}
+	}
+
+	if user, ok := auth.UserFromContext(ctx); ok {
+		entry.ActorID = &user.ID
+		entry.ActorEmail = user.Email
+	}
+	if key, ok := auth.APIKeyFromContext(ctx); ok {
+		entry.APIKeyID = &key.ID
+	}
+	if req, ok := RequestFromContext(ctx); ok {
+		entry.RequestID = req.ID
+		entry.IP = req.IP
+	}
+
+	return l.store.Append(ctx, entry)
+}
+
+// Audited makes a change and appends its audit entry in one transaction, so
+// the change is only committed if it was recorded. change makes it with
+// repositories created on tx and returns the event describing it.
+func Audited(ctx context.Context, db *sql.DB, change func(tx *sql.Tx) (Event, error)) error {
+	return models.InTx(ctx, db, func(tx *sql.Tx) error {
+		event, err := change(tx)
+		if err != nil {
+			return err
+		}
+		return NewLogger(models.NewAuditRepository(tx)).Record(ctx, event)
+	})
+}
+
+// Diff returns the top-level fields that differ between two states of a
+// resource as {"field": {"before": x, "after": y}}, using their JSON
+// encoding. Either state may be nil. Values that are not JSON objects are
+// compared whole under the "value" key. Secrets are redacted, and nil is
+// returned when nothing changed.
+func Diff(before, after interface{}) (json.RawMessage, error) {
+	if before == nil && after == nil {
+		return nil, nil
+	}
+
+	beforeFields, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]map[string]interface{})
+	for key, value := range beforeFields {
+		if other, ok := afterFields[key]; !ok || !reflect.DeepEqual(value, other) {
+			changes[key] = map[string]interface{}{"before": value}
+		}
+	}
+	for key, value := range afterFields {
+		if other, ok := beforeFields[key]; ok && reflect.DeepEqual(value, other) {
+			continue
+		}
+		if changes[key] == nil {
+			changes[key] = map[string]interface{}{}
+		}
+		changes[key]["after"] = value
+	}
+
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(changes)
+}
+
+// fields returns the redacted JSON fields of a value
+func fields(v interface{}) (map[string]interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	value, err := toJSONValue(v)
+	if err != nil {
+		return nil, err
+	}
+
+	switch value := redact(value).(type) {
+	case map[string]interface{}:
+		return value, nil
+	case nil:
+		return nil, nil
+	default:
+		return map[string]interface{}{"value": value}, nil
+	}
+}
+
+// toJSONValue converts a value to its generic JSON representation
+func toJSONValue(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	err = json.Unmarshal(data, &value)
+	return value, err
+}
+
+// sensitiveFields are JSON field names whose values never enter the log
+var sensitiveFields = map[string]bool{
+	"password":         true,
+	"current_password": true,
+	"new_password":     true,
+	"password_hash":    true,
+	"token":            true,
+	"key_hash":         true,
+	"secret":           true,
+	"client_secret":    true,
+}
+
+// redacted replaces the value of a sensitive field
+const redacted = "[REDACTED]"
+
+// redact replaces sensitive fields at any depth of a generic JSON value
+func redact(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, field := range value {
+			if sensitiveFields[strings.ToLower(key)] {
+				value[key] = redacted
+			} else {
+				value[key] = redact(field)
+			}
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = redact(item)
+		}
+	}
+	return value
+}
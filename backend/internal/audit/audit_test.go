@@ -0,0 +1,136 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"backend/internal/auth"
+	"backend/internal/models"
+)
+
+// fakeStore keeps appended entries in memory
+type fakeStore struct {
+	entries []*models.AuditEntry
+	err     error
+}
+
+func (s *fakeStore) Append(_ context.Context, entry *models.AuditEntry) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.entries = append(s.entries, entry)
+	return nil
+}
+
+func TestLogger_Record(t *testing.T) {
+	store := &fakeStore{}
+	logger := NewLogger(store)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	logger.now = func() time.Time { return now }
+
+	ctx := auth.WithUser(context.Background(), &models.User{ID: 7, Email: "pathologist@example.com"})
+	ctx = auth.WithAPIKey(ctx, &models.APIKey{ID: 3})
+	ctx = WithRequest(ctx, Request{ID: "req-1", IP: "203.0.113.9"})
+
+	err := logger.Record(ctx, Event{
+		Action:       "test_order.update",
+		ResourceType: "test_order",
+		ResourceID:   "42",
+		Before:       map[string]string{"priority": "routine", "notes": "same"},
+		After:        map[string]string{"priority": "stat", "notes": "same"},
+		Details:      map[string]string{"token": "secret-value"},
+	})
+	if err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+
+	if len(store.entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(store.entries))
+	}
+	entry := store.entries[0]
+
+	if entry.ActorID == nil || *entry.ActorID != 7 || entry.ActorEmail != "pathologist@example.com" {
+		t.Errorf("Expected actor 7, got %v %q", entry.ActorID, entry.ActorEmail)
+	}
+	if entry.APIKeyID == nil || *entry.APIKeyID != 3 {
+		t.Errorf("Expected API key 3, got %v", entry.APIKeyID)
+	}
+	if entry.RequestID != "req-1" || entry.IP != "203.0.113.9" {
+		t.Errorf("Expected request req-1 from 203.0.113.9, got %q from %q", entry.RequestID, entry.IP)
+	}
+	if !entry.OccurredAt.Equal(now) {
+		t.Errorf("Expected time %v, got %v", now, entry.OccurredAt)
+	}
+	if string(entry.Changes) != `{"priority":{"after":"stat","before":"routine"}}` {
+		t.Errorf("Unexpected changes %s", entry.Changes)
+	}
+	if string(entry.Details) != `{"token":"[REDACTED]"}` {
+		t.Errorf("Expected redacted details, got %s", entry.Details)
+	}
+}
+
+func TestLogger_Record_Anonymous(t *testing.T) {
+	store := &fakeStore{}
+
+	if err := NewLogger(store).Record(context.Background(), Event{Action: "auth.login_failed", ResourceType: "user"}); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+
+	entry := store.entries[0]
+	if entry.ActorID != nil || entry.Changes != nil || entry.Details != nil {
+		t.Errorf("Expected no actor, changes or details, got %+v", entry)
+	}
+}
+
+func TestLogger_Record_StoreError(t *testing.T) {
+	store := &fakeStore{err: errors.New("database unavailable")}
+
+	if err := NewLogger(store).Record(context.Background(), Event{Action: "test_order.read"}); err == nil {
+		t.Error("Expected the store error to be returned")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	type record struct {
+		Name         string `json:"name"`
+		PasswordHash string `json:"password_hash"`
+		Count        int    `json:"count"`
+	}
+
+	tests := []struct {
+		name          string
+		before, after interface{}
+		expected      string
+	}{
+		{"create", nil, record{Name: "a", Count: 1}, `{"count":{"after":1},"name":{"after":"a"},"password_hash":{"after":"[REDACTED]"}}`},
+		{"delete", record{Name: "a"}, nil, `{"count":{"before":0},"name":{"before":"a"},"password_hash":{"before":"[REDACTED]"}}`},
+		{"update", record{Name: "a", Count: 1}, record{Name: "a", Count: 2}, `{"count":{"after":2,"before":1}}`},
+		{"secret change is not revealed", record{PasswordHash: "x"}, record{PasswordHash: "y"}, ``},
+		{"unchanged", record{Name: "a"}, record{Name: "a"}, ``},
+		{"non-object", []int{1}, []int{2}, `{"value":{"after":[2],"before":[1]}}`},
+		{"nothing", nil, nil, ``},
+	}
+
+	for _, tt := range tests {
+		changes, err := Diff(tt.before, tt.after)
+		if err != nil {
+			t.Fatalf("%s: Diff failed: %v", tt.name, err)
+		}
+		if string(changes) != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.expected, changes)
+		}
+	}
+}
+
+func TestRedact_Nested(t *testing.T) {
+	var value interface{}
+	json.Unmarshal([]byte(`{"items":[{"Password":"p","id":1}],"client_secret":"s"}`), &value)
+
+	data, _ := json.Marshal(redact(value))
+	if string(data) != `{"client_secret":"[REDACTED]","items":[{"Password":"[REDACTED]","id":1}]}` {
+		t.Errorf("Unexpected redaction %s", data)
+	}
+}
@@ -0,0 +1,24 @@
+package audit
+
+import "context"
+
+type contextKey int
+
+const requestContextKey contextKey = iota
+
+// Request identifies the HTTP request an audited action happened in
+type Request struct {
+	ID string
+	IP string
+}
+
+// WithRequest returns a context carrying the current request's identity
+func WithRequest(ctx context.Context, req Request) context.Context {
+	return context.WithValue(ctx, requestContextKey, req)
+}
+
+// RequestFromContext returns the current request's identity, if any
+func RequestFromContext(ctx context.Context) (Request, bool) {
+	req, ok := ctx.Value(requestContextKey).(Request)
+	return req, ok
+}
@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"backend/internal/models"
+)
+
+const (
+	// apiKeyPrefix marks tokens issued by this service, e.g. ap_1a2b3c4d_<secret>
+	apiKeyPrefix = "ap_"
+
+	// apiKeyTouchInterval limits how often last_used_at is written
+	apiKeyTouchInterval = time.Minute
+)
+
+// ErrInvalidAPIKey is returned for unknown, malformed, revoked or expired keys
+var ErrInvalidAPIKey = errors.New("invalid API key")
+
+// APIKeyStore is the subset of models.APIKeyRepository used to authenticate keys
+type APIKeyStore interface {
+	GetByPrefix(ctx context.Context, prefix string) (*models.APIKey, error)
+	TouchLastUsed(ctx context.Context, id int, at time.Time) error
+}
+
+// UserLookup loads the owner of a credential
+type UserLookup interface {
+	GetByID(ctx context.Context, id int) (*models.User, error)
+}
+
+// GenerateAPIKey returns a new token, its public prefix and the hash to store.
+// The token is shown to the caller once and never persisted.
+func GenerateAPIKey() (token, prefix, hash string, err error) {
+	idBytes := make([]byte, 4)
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", "", "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", "", "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+
+	prefix = apiKeyPrefix + hex.EncodeToString(idBytes)
+	token = prefix + "_" + hex.EncodeToString(secretBytes)
+	return token, prefix, hashAPIKey(token), nil
+}
+
+// parseAPIKey returns the public prefix of a well-formed token
+func parseAPIKey(token string) (string, bool) {
+	if !strings.HasPrefix(token, apiKeyPrefix) {
+		return "", false
+	}
+
+	idx := strings.LastIndex(token, "_")
+	if idx <= len(apiKeyPrefix) || idx == len(token)-1 {
+		return "", false
+	}
+
+	return token[:idx], true
+}
+
+// hashAPIKey returns the stored hash of a token. Keys carry 256 bits of
+// entropy, so a fast hash is sufficient.
+func hashAPIKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// APIKeyAuthenticator validates bearer API keys and resolves the owning user
+// with permissions narrowed to the key's scopes
+type APIKeyAuthenticator struct {
+	keys   APIKeyStore
+	users  UserLookup
+	access AccessStore
+	now    func() time.Time
+}
+
+// NewAPIKeyAuthenticator creates a new API key authenticator
+func NewAPIKeyAuthenticator(keys APIKeyStore, users UserLookup, access AccessStore) *APIKeyAuthenticator {
+	return &APIKeyAuthenticator{
+		keys:   keys,
+		users:  users,
+		access: access,
+		now:    func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Authenticate validates a token and returns a context carrying the owner, the
+// key and the effective access: the owner's current permissions intersected
+// with the key's scopes
+func (a *APIKeyAuthenticator) Authenticate(ctx context.Context, token string) (context.Context, error) {
+	prefix, ok := parseAPIKey(token)
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+
+	key, err := a.keys.GetByPrefix(ctx, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load API key: %w", err)
+	}
+	if key == nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashAPIKey(token)), []byte(key.KeyHash)) != 1 {
+		return nil, ErrInvalidAPIKey
+	}
+
+	now := a.now()
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && !now.Before(*key.ExpiresAt)) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	user, err := a.users.GetByID(ctx, key.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load API key owner: %w", err)
+	}
+	if user == nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	roles, permissions, err := a.access.GetUserAccess(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load permissions: %w", err)
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+		if err := a.keys.TouchLastUsed(ctx, key.ID, now); err != nil {
+			return nil, fmt.Errorf("failed to record API key use: %w", err)
+		}
+	}
+
+	ctx = WithUser(ctx, user)
+	ctx = WithAPIKey(ctx, key)
+	ctx = WithAccess(ctx, NewAccess(roles, intersect(permissions, key.Scopes)))
+	return ctx, nil
+}
+
+// WithAPIKey returns a context carrying the API key used for the request
+func WithAPIKey(ctx context.Context, key *models.APIKey) context.Context {
+	return context.WithValue(ctx, apiKeyContextKey, key)
+}
+
+// APIKeyFromContext returns the API key used for the request, if any
+func APIKeyFromContext(ctx context.Context) (*models.APIKey, bool) {
+	key, ok := ctx.Value(apiKeyContextKey).(*models.APIKey)
+	return key, ok && key != nil
+}
+
+// intersect returns the elements of a also present in b
+func intersect(a, b []string) []string {
+	allowed := make(map[string]bool, len(b))
+	for _, item := range b {
+		allowed[item] = true
+	}
+
+	var result []string
+	for _, item := range a {
+		if allowed[item] {
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+)
+
+// fakeAPIKeyStore is an in-memory APIKeyStore
+type fakeAPIKeyStore struct {
+	keys    map[string]*models.APIKey
+	touched int
+}
+
+func (s *fakeAPIKeyStore) GetByPrefix(_ context.Context, prefix string) (*models.APIKey, error) {
+	return s.keys[prefix], nil
+}
+
+func (s *fakeAPIKeyStore) TouchLastUsed(_ context.Context, id int, at time.Time) error {
+	s.touched++
+	for _, key := range s.keys {
+		if key.ID == id {
+			key.LastUsedAt = &at
+		}
+	}
+	return nil
+}
+
+// fakeAccessStore grants fixed roles and permissions to every user
+type fakeAccessStore struct {
+	roles       []string
+	permissions []string
+}
+
+func (s fakeAccessStore) GetUserAccess(_ context.Context, userID int) ([]string, []string, error) {
+	return s.roles, s.permissions, nil
+}
+
+func newTestAPIKey(t *testing.T, scopes []string) (string, *fakeAPIKeyStore, *APIKeyAuthenticator) {
+	t.Helper()
+
+	token, prefix, hash, err := GenerateAPIKey()
+	if err != nil {
+		t.Fatalf("Failed to generate API key: %v", err)
+	}
+
+	keys := &fakeAPIKeyStore{keys: map[string]*models.APIKey{
+		prefix: {ID: 1, UserID: 1, Name: "analyzer", Prefix: prefix, KeyHash: hash, Scopes: scopes},
+	}}
+	users := &fakeUserStore{users: map[int]*models.User{
+		1: {ID: 1, Name: "Lab Bot", Email: "lab@example.com"},
+	}}
+	access := fakeAccessStore{roles: []string{"lab_technician"}, permissions: []string{"users:read", "users:write"}}
+
+	return token, keys, NewAPIKeyAuthenticator(keys, users, access)
+}
+
+func TestGenerateAPIKey(t *testing.T) {
+	token, prefix, hash, err := GenerateAPIKey()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	parsed, ok := parseAPIKey(token)
+	if !ok || parsed != prefix {
+		t.Errorf("Expected token to parse to prefix %q, got %q", prefix, parsed)
+	}
+
+	if hash == token || hash != hashAPIKey(token) {
+		t.Error("Expected stored hash to differ from the token and match hashAPIKey")
+	}
+
+	if _, ok := parseAPIKey("not-a-key"); ok {
+		t.Error("Expected malformed tokens to be rejected")
+	}
+	if _, ok := parseAPIKey("ap_"); ok {
+		t.Error("Expected malformed tokens to be rejected")
+	}
+}
+
+func TestAPIKeyAuthenticator_ScopesNarrowPermissions(t *testing.T) {
+	token, keys, authenticator := newTestAPIKey(t, []string{"users:read", "roles:manage"})
+
+	ctx, err := authenticator.Authenticate(context.Background(), token)
+	if err != nil {
+		t.Fatalf("Expected key to authenticate, got %v", err)
+	}
+
+	if user, ok := UserFromContext(ctx); !ok || user.ID != 1 {
+		t.Error("Expected owner in context")
+	}
+
+	if !HasPermission(ctx, PermissionUsersRead) {
+		t.Error("Expected scoped permission held by the owner to be granted")
+	}
+
+	if HasPermission(ctx, PermissionUsersWrite) {
+		t.Error("Expected owner permission outside the key's scopes to be withheld")
+	}
+
+	if HasPermission(ctx, PermissionRolesManage) {
+		t.Error("Expected scope the owner does not hold to be withheld")
+	}
+
+	if keys.touched != 1 {
+		t.Errorf("Expected last_used_at to be recorded once, got %d", keys.touched)
+	}
+
+	// A second use within the touch interval does not write again
+	authenticator.Authenticate(context.Background(), token)
+	if keys.touched != 1 {
+		t.Errorf("Expected last_used_at writes to be throttled, got %d", keys.touched)
+	}
+}
+
+func TestAPIKeyAuthenticator_Rejects(t *testing.T) {
+	token, keys, authenticator := newTestAPIKey(t, []string{"users:read"})
+	prefix, _ := parseAPIKey(token)
+
+	if _, err := authenticator.Authenticate(context.Background(), prefix+"_wrongsecret"); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("Expected wrong secret to be rejected, got %v", err)
+	}
+
+	expired := time.Now().Add(-time.Hour)
+	keys.keys[prefix].ExpiresAt = &expired
+	if _, err := authenticator.Authenticate(context.Background(), token); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("Expected expired key to be rejected, got %v", err)
+	}
+
+	keys.keys[prefix].ExpiresAt = nil
+	revoked := time.Now()
+	keys.keys[prefix].RevokedAt = &revoked
+	if _, err := authenticator.Authenticate(context.Background(), token); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("Expected revoked key to be rejected, got %v", err)
+	}
+}
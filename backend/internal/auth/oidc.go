@@ -0,0 +1,355 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+
+	"backend/internal/config"
+	"backend/internal/models"
+)
+
+const (
+	// oidcCookieName holds the state, nonce and PKCE verifier between the
+	// redirect to the identity provider and the callback
+	oidcCookieName = "alphapath_oidc"
+
+	// oidcFlowTimeout bounds how long a user may take at the identity provider
+	oidcFlowTimeout = 10 * time.Minute
+
+	wellKnownSuffix = "/.well-known/openid-configuration"
+)
+
+var (
+	// ErrOIDCState is returned when the callback does not match a login started by this browser
+	ErrOIDCState = errors.New("invalid or expired sign-in state")
+
+	// ErrOIDCEmail is returned when the identity provider does not supply a usable email
+	ErrOIDCEmail = errors.New("identity provider did not supply a verified email")
+)
+
+// IdentityStore is the subset of models.UserIdentityRepository used for single sign-on
+type IdentityStore interface {
+	GetUser(ctx context.Context, issuer, subject string) (*models.User, error)
+	Create(ctx context.Context, identity *models.UserIdentity) error
+	TouchLogin(ctx context.Context, issuer, subject string, at time.Time) error
+}
+
+// AccountStore is the subset of models.UserRepository used to link or create
+// users on first single sign-on
+type AccountStore interface {
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	Create(ctx context.Context, user *models.User) error
+}
+
+// OIDCOptions configures the OpenID Connect client
+type OIDCOptions struct {
+	DiscoveryURL string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	CookieSecure bool
+}
+
+// OIDCOptionsFromConfig builds OIDC options from application configuration
+func OIDCOptionsFromConfig(cfg *config.Config) OIDCOptions {
+	return OIDCOptions{
+		DiscoveryURL: cfg.OIDCDiscoveryURL,
+		ClientID:     cfg.OIDCClientID,
+		ClientSecret: cfg.OIDCClientSecret,
+		RedirectURL:  cfg.OIDCRedirectURL,
+		Scopes:       cfg.OIDCScopes,
+		CookieSecure: cfg.SessionCookieSecure,
+	}
+}
+
+// oidcFlowState is stored in the flow cookie during a login
+type oidcFlowState struct {
+	State    string `json:"s"`
+	Nonce    string `json:"n"`
+	Verifier string `json:"v"`
+	ReturnTo string `json:"r"`
+}
+
+// oidcClaims are the ID token claims mapped onto a user
+type oidcClaims struct {
+	Email         string `json:"email"`
+	EmailVerified *bool  `json:"email_verified"`
+	Name          string `json:"name"`
+	GivenName     string `json:"given_name"`
+	FamilyName    string `json:"family_name"`
+}
+
+// OIDCClient runs the authorization code + PKCE flow against an institutional
+// identity provider and maps verified ID tokens onto local users
+type OIDCClient struct {
+	opts       OIDCOptions
+	identities IdentityStore
+	accounts   AccountStore
+	httpClient *http.Client
+	now        func() time.Time
+
+	mu       sync.Mutex
+	provider *oidc.Provider
+}
+
+// NewOIDCClient creates a new OIDC client. Provider discovery happens on the
+// first login so the API can start while the identity provider is unreachable.
+func NewOIDCClient(opts OIDCOptions, identities IdentityStore, accounts AccountStore) *OIDCClient {
+	if len(opts.Scopes) == 0 {
+		opts.Scopes = []string{oidc.ScopeOpenID, "email", "profile"}
+	}
+
+	return &OIDCClient{
+		opts:       opts,
+		identities: identities,
+		accounts:   accounts,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		now:        func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// BeginLogin stores the flow state in a short-lived cookie and returns the
+// identity provider URL to redirect the browser to. returnTo is carried
+// through the flow and handed back by CompleteLogin.
+func (c *OIDCClient) BeginLogin(ctx context.Context, w http.ResponseWriter, returnTo string) (string, error) {
+	provider, err := c.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	state, err := randomString()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := randomString()
+	if err != nil {
+		return "", err
+	}
+
+	flow := oidcFlowState{
+		State:    state,
+		Nonce:    nonce,
+		Verifier: oauth2.GenerateVerifier(),
+		ReturnTo: returnTo,
+	}
+
+	encoded, err := json.Marshal(flow)
+	if err != nil {
+		return "", err
+	}
+
+	// SameSite=Lax so the cookie accompanies the top-level redirect back from
+	// the identity provider
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcCookieName,
+		Value:    base64.RawURLEncoding.EncodeToString(encoded),
+		Path:     "/api/auth/oidc",
+		MaxAge:   int(oidcFlowTimeout.Seconds()),
+		HttpOnly: true,
+		Secure:   c.opts.CookieSecure,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return c.oauth2Config(provider).AuthCodeURL(state,
+		oidc.Nonce(nonce),
+		oauth2.S256ChallengeOption(flow.Verifier),
+	), nil
+}
+
+// CompleteLogin handles the identity provider callback: it checks state,
+// exchanges the code with the PKCE verifier, verifies the ID token against the
+// provider's JWKS and returns the linked or newly created user together with
+// the returnTo value passed to BeginLogin.
+func (c *OIDCClient) CompleteLogin(ctx context.Context, w http.ResponseWriter, r *http.Request) (*models.User, string, error) {
+	flow, err := readFlowCookie(r)
+	// The flow cookie is single use
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcCookieName,
+		Value:    "",
+		Path:     "/api/auth/oidc",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   c.opts.CookieSecure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	query := r.URL.Query()
+	if providerErr := query.Get("error"); providerErr != "" {
+		return nil, "", fmt.Errorf("identity provider returned %s: %s", providerErr, query.Get("error_description"))
+	}
+
+	if subtle.ConstantTimeCompare([]byte(query.Get("state")), []byte(flow.State)) != 1 {
+		return nil, "", ErrOIDCState
+	}
+
+	code := query.Get("code")
+	if code == "" {
+		return nil, "", fmt.Errorf("callback is missing the authorization code")
+	}
+
+	provider, err := c.discover(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	ctx = oidc.ClientContext(ctx, c.httpClient)
+	token, err := c.oauth2Config(provider).Exchange(ctx, code, oauth2.VerifierOption(flow.Verifier))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, "", fmt.Errorf("token response did not include an id_token")
+	}
+
+	verifier := provider.Verifier(&oidc.Config{ClientID: c.opts.ClientID, Now: c.now})
+	idToken, err := verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to verify id_token: %w", err)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(flow.Nonce)) != 1 {
+		return nil, "", fmt.Errorf("id_token nonce does not match")
+	}
+
+	var claims oidcClaims
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, "", fmt.Errorf("failed to parse id_token claims: %w", err)
+	}
+
+	user, err := c.resolveUser(ctx, idToken.Issuer, idToken.Subject, claims)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return user, flow.ReturnTo, nil
+}
+
+// resolveUser finds the user linked to the identity, linking an existing
+// account by email or creating a new one on first login
+func (c *OIDCClient) resolveUser(ctx context.Context, issuer, subject string, claims oidcClaims) (*models.User, error) {
+	user, err := c.identities.GetUser(ctx, issuer, subject)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up identity: %w", err)
+	}
+
+	if user != nil {
+		if err := c.identities.TouchLogin(ctx, issuer, subject, c.now()); err != nil {
+			return nil, fmt.Errorf("failed to record identity login: %w", err)
+		}
+		return user, nil
+	}
+
+	// Institutional providers such as Entra ID omit email_verified; only an
+	// explicit false is rejected
+	email := strings.TrimSpace(claims.Email)
+	if email == "" || (claims.EmailVerified != nil && !*claims.EmailVerified) {
+		return nil, ErrOIDCEmail
+	}
+
+	user, err = c.accounts.GetByEmail(ctx, email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up user by email: %w", err)
+	}
+
+	if user == nil {
+		user = &models.User{Name: displayName(claims), Email: email}
+		if err := c.accounts.Create(ctx, user); err != nil {
+			return nil, fmt.Errorf("failed to create user: %w", err)
+		}
+	}
+
+	identity := &models.UserIdentity{UserID: user.ID, Issuer: issuer, Subject: subject, Email: email}
+	if err := c.identities.Create(ctx, identity); err != nil {
+		return nil, fmt.Errorf("failed to link identity: %w", err)
+	}
+
+	return user, nil
+}
+
+// discover fetches and caches the provider's discovery document and JWKS location
+func (c *OIDCClient) discover(ctx context.Context) (*oidc.Provider, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.provider != nil {
+		return c.provider, nil
+	}
+
+	issuer := strings.TrimSuffix(c.opts.DiscoveryURL, wellKnownSuffix)
+	provider, err := oidc.NewProvider(oidc.ClientContext(ctx, c.httpClient), issuer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover identity provider: %w", err)
+	}
+
+	c.provider = provider
+	return provider, nil
+}
+
+// oauth2Config returns the OAuth2 client configuration for the provider
+func (c *OIDCClient) oauth2Config(provider *oidc.Provider) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     c.opts.ClientID,
+		ClientSecret: c.opts.ClientSecret,
+		RedirectURL:  c.opts.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       c.opts.Scopes,
+	}
+}
+
+// readFlowCookie decodes the flow state stored by BeginLogin
+func readFlowCookie(r *http.Request) (*oidcFlowState, error) {
+	cookie, err := r.Cookie(oidcCookieName)
+	if err != nil {
+		return nil, ErrOIDCState
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	if err != nil {
+		return nil, ErrOIDCState
+	}
+
+	var flow oidcFlowState
+	if err := json.Unmarshal(decoded, &flow); err != nil || flow.State == "" || flow.Verifier == "" {
+		return nil, ErrOIDCState
+	}
+
+	return &flow, nil
+}
+
+// displayName derives a user name from ID token claims
+func displayName(claims oidcClaims) string {
+	if claims.Name != "" {
+		return claims.Name
+	}
+	if name := strings.TrimSpace(claims.GivenName + " " + claims.FamilyName); name != "" {
+		return name
+	}
+	return claims.Email
+}
+
+// randomString returns a random URL-safe string for state and nonce values
+func randomString() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate random value: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
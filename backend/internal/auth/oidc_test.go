@@ -0,0 +1,380 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+
+	"backend/internal/models"
+)
+
+// fakeIdP is an in-process OpenID provider supporting discovery, JWKS,
+// authorization with PKCE and the token endpoint
+type fakeIdP struct {
+	server *httptest.Server
+
+	// published is the key served from the JWKS endpoint; tokens are signed
+	// with key, which tests may replace to simulate an untrusted signer
+	published *rsa.PublicKey
+	key       *rsa.PrivateKey
+
+	mu     sync.Mutex
+	codes  map[string]fakeAuthorization
+	claims map[string]interface{}
+}
+
+// fakeAuthorization is what the provider remembers about an issued code
+type fakeAuthorization struct {
+	challenge string
+	nonce     string
+}
+
+func newFakeIdP(t *testing.T) *fakeIdP {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	idp := &fakeIdP{
+		published: &key.PublicKey,
+		key:       key,
+		codes:     map[string]fakeAuthorization{},
+		claims: map[string]interface{}{
+			"sub":   "staff-123",
+			"email": "jane.doe@hospital.example.org",
+			"name":  "Jane Doe",
+		},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", idp.discovery)
+	mux.HandleFunc("/keys", idp.keys)
+	mux.HandleFunc("/authorize", idp.authorize)
+	mux.HandleFunc("/token", idp.token)
+
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+
+	return idp
+}
+
+func (p *fakeIdP) discovery(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"issuer":                                p.server.URL,
+		"authorization_endpoint":                p.server.URL + "/authorize",
+		"token_endpoint":                        p.server.URL + "/token",
+		"jwks_uri":                              p.server.URL + "/keys",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (p *fakeIdP) keys(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: p.published, KeyID: "test-key", Algorithm: "RS256", Use: "sig"},
+	}})
+}
+
+func (p *fakeIdP) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		http.Error(w, "PKCE required", http.StatusBadRequest)
+		return
+	}
+
+	code := "code-" + query.Get("state")
+	p.mu.Lock()
+	p.codes[code] = fakeAuthorization{challenge: query.Get("code_challenge"), nonce: query.Get("nonce")}
+	p.mu.Unlock()
+
+	redirect, _ := url.Parse(query.Get("redirect_uri"))
+	values := redirect.Query()
+	values.Set("code", code)
+	values.Set("state", query.Get("state"))
+	redirect.RawQuery = values.Encode()
+
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (p *fakeIdP) token(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+
+	p.mu.Lock()
+	authorization, ok := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	claims := make(map[string]interface{}, len(p.claims))
+	for k, v := range p.claims {
+		claims[k] = v
+	}
+	p.mu.Unlock()
+
+	if !ok {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != authorization.challenge {
+		http.Error(w, `{"error":"invalid_grant","error_description":"PKCE verification failed"}`, http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	claims["iss"] = p.server.URL
+	claims["aud"] = "alphapath"
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(time.Hour).Unix()
+	if _, set := claims["nonce"]; !set {
+		claims["nonce"] = authorization.nonce
+	}
+
+	p.mu.Lock()
+	key := p.key
+	p.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": "access-token",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     sign(key, claims),
+	})
+}
+
+// sign returns a compact RS256 JWT for the claims
+func sign(key *rsa.PrivateKey, claims map[string]interface{}) string {
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: key},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", "test-key"))
+	if err != nil {
+		panic(err)
+	}
+
+	payload, _ := json.Marshal(claims)
+	signed, err := signer.Sign(payload)
+	if err != nil {
+		panic(err)
+	}
+
+	compact, _ := signed.CompactSerialize()
+	return compact
+}
+
+func (p *fakeIdP) setClaim(key string, value interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.claims[key] = value
+}
+
+// fakeIdentityStore is an in-memory IdentityStore and AccountStore
+type fakeIdentityStore struct {
+	users      map[int]*models.User
+	identities []models.UserIdentity
+}
+
+func (s *fakeIdentityStore) GetUser(_ context.Context, issuer, subject string) (*models.User, error) {
+	for _, identity := range s.identities {
+		if identity.Issuer == issuer && identity.Subject == subject {
+			return s.users[identity.UserID], nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *fakeIdentityStore) Create(_ context.Context, identity *models.UserIdentity) error {
+	identity.ID = len(s.identities) + 1
+	s.identities = append(s.identities, *identity)
+	return nil
+}
+
+func (s *fakeIdentityStore) TouchLogin(_ context.Context, issuer, subject string, at time.Time) error {
+	return nil
+}
+
+func (s *fakeIdentityStore) GetByEmail(_ context.Context, email string) (*models.User, error) {
+	for _, user := range s.users {
+		if strings.EqualFold(user.Email, email) {
+			return user, nil
+		}
+	}
+	return nil, nil
+}
+
+// fakeAccounts adapts fakeIdentityStore to AccountStore, whose Create takes a user
+type fakeAccounts struct {
+	*fakeIdentityStore
+}
+
+func (a fakeAccounts) Create(_ context.Context, user *models.User) error {
+	user.ID = len(a.users) + 1
+	a.users[user.ID] = user
+	return nil
+}
+
+func newTestOIDCClient(idp *fakeIdP, store *fakeIdentityStore) *OIDCClient {
+	return NewOIDCClient(OIDCOptions{
+		DiscoveryURL: idp.server.URL + "/.well-known/openid-configuration",
+		ClientID:     "alphapath",
+		ClientSecret: "secret",
+		RedirectURL:  "http://api.example.org/api/auth/oidc/callback",
+	}, store, fakeAccounts{store})
+}
+
+// runFlow drives a login through the fake provider and returns the callback
+// request the browser would make, carrying the flow cookie
+func runFlow(t *testing.T, client *OIDCClient) *http.Request {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	authURL, err := client.BeginLogin(context.Background(), w, "/tests")
+	if err != nil {
+		t.Fatalf("Expected login to begin, got %v", err)
+	}
+
+	noRedirect := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := noRedirect.Get(authURL)
+	if err != nil {
+		t.Fatalf("Failed to call authorize endpoint: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("Expected redirect from authorize endpoint, got %d", resp.StatusCode)
+	}
+
+	callback := httptest.NewRequest(http.MethodGet, resp.Header.Get("Location"), nil)
+	for _, cookie := range w.Result().Cookies() {
+		callback.AddCookie(cookie)
+	}
+	return callback
+}
+
+func TestOIDCClient_FirstLoginCreatesUser(t *testing.T) {
+	idp := newFakeIdP(t)
+	store := &fakeIdentityStore{users: map[int]*models.User{}}
+	client := newTestOIDCClient(idp, store)
+
+	user, returnTo, err := client.CompleteLogin(context.Background(), httptest.NewRecorder(), runFlow(t, client))
+	if err != nil {
+		t.Fatalf("Expected login to complete, got %v", err)
+	}
+
+	if user.Email != "jane.doe@hospital.example.org" || user.Name != "Jane Doe" {
+		t.Errorf("Expected user mapped from claims, got %+v", user)
+	}
+
+	if returnTo != "/tests" {
+		t.Errorf("Expected returnTo to round-trip, got %q", returnTo)
+	}
+
+	if len(store.identities) != 1 || store.identities[0].Subject != "staff-123" || store.identities[0].Issuer != idp.server.URL {
+		t.Errorf("Expected identity to be linked, got %+v", store.identities)
+	}
+
+	// A second login resolves the same user through the linked identity
+	again, _, err := client.CompleteLogin(context.Background(), httptest.NewRecorder(), runFlow(t, client))
+	if err != nil {
+		t.Fatalf("Expected second login to complete, got %v", err)
+	}
+
+	if again.ID != user.ID || len(store.users) != 1 {
+		t.Error("Expected second login to reuse the linked user")
+	}
+}
+
+func TestOIDCClient_LinksExistingUserByEmail(t *testing.T) {
+	idp := newFakeIdP(t)
+	store := &fakeIdentityStore{users: map[int]*models.User{
+		7: {ID: 7, Name: "Dr. Jane Doe", Email: "Jane.Doe@hospital.example.org"},
+	}}
+	client := newTestOIDCClient(idp, store)
+
+	user, _, err := client.CompleteLogin(context.Background(), httptest.NewRecorder(), runFlow(t, client))
+	if err != nil {
+		t.Fatalf("Expected login to complete, got %v", err)
+	}
+
+	if user.ID != 7 || len(store.users) != 1 {
+		t.Errorf("Expected existing user 7 to be linked, got %+v", user)
+	}
+}
+
+func TestOIDCClient_UnverifiedEmailRejected(t *testing.T) {
+	idp := newFakeIdP(t)
+	idp.setClaim("email_verified", false)
+	store := &fakeIdentityStore{users: map[int]*models.User{}}
+	client := newTestOIDCClient(idp, store)
+
+	_, _, err := client.CompleteLogin(context.Background(), httptest.NewRecorder(), runFlow(t, client))
+	if !errors.Is(err, ErrOIDCEmail) {
+		t.Errorf("Expected ErrOIDCEmail, got %v", err)
+	}
+}
+
+func TestOIDCClient_StateMismatch(t *testing.T) {
+	idp := newFakeIdP(t)
+	client := newTestOIDCClient(idp, &fakeIdentityStore{users: map[int]*models.User{}})
+
+	callback := runFlow(t, client)
+	query := callback.URL.Query()
+	query.Set("state", "forged")
+	callback.URL.RawQuery = query.Encode()
+
+	if _, _, err := client.CompleteLogin(context.Background(), httptest.NewRecorder(), callback); !errors.Is(err, ErrOIDCState) {
+		t.Errorf("Expected ErrOIDCState, got %v", err)
+	}
+}
+
+func TestOIDCClient_MissingFlowCookie(t *testing.T) {
+	idp := newFakeIdP(t)
+	client := newTestOIDCClient(idp, &fakeIdentityStore{users: map[int]*models.User{}})
+
+	callback := httptest.NewRequest(http.MethodGet, "/api/auth/oidc/callback?code=x&state=y", nil)
+	if _, _, err := client.CompleteLogin(context.Background(), httptest.NewRecorder(), callback); !errors.Is(err, ErrOIDCState) {
+		t.Errorf("Expected ErrOIDCState, got %v", err)
+	}
+}
+
+func TestOIDCClient_NonceMismatch(t *testing.T) {
+	idp := newFakeIdP(t)
+	idp.setClaim("nonce", "replayed-nonce")
+	client := newTestOIDCClient(idp, &fakeIdentityStore{users: map[int]*models.User{}})
+
+	_, _, err := client.CompleteLogin(context.Background(), httptest.NewRecorder(), runFlow(t, client))
+	if err == nil || !strings.Contains(err.Error(), "nonce") {
+		t.Errorf("Expected nonce mismatch error, got %v", err)
+	}
+}
+
+func TestOIDCClient_UntrustedSignature(t *testing.T) {
+	idp := newFakeIdP(t)
+	client := newTestOIDCClient(idp, &fakeIdentityStore{users: map[int]*models.User{}})
+	callback := runFlow(t, client)
+
+	// Sign with a key that is not published in the JWKS
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	idp.mu.Lock()
+	idp.key = otherKey
+	idp.mu.Unlock()
+
+	if _, _, err := client.CompleteLogin(context.Background(), httptest.NewRecorder(), callback); err == nil {
+		t.Error("Expected id_token signed by an unknown key to be rejected")
+	}
+}
@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// passwordCost is the bcrypt work factor for new hashes
+var passwordCost = 12
+
+const (
+	// MinPasswordLength is the minimum accepted password length in characters
+	MinPasswordLength = 12
+
+	// maxPasswordBytes is bcrypt's input limit; longer passwords are rejected
+	// rather than silently truncated
+	maxPasswordBytes = 72
+)
+
+// dummyHash is compared against when a login names an unknown account so the
+// response time does not reveal whether the email exists
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("alphapath-dummy-password"), passwordCost)
+
+// ValidatePassword checks a new password against the password policy
+func ValidatePassword(password string) error {
+	if utf8.RuneCountInString(password) < MinPasswordLength {
+		return fmt.Errorf("password must be at least %d characters", MinPasswordLength)
+	}
+	if len(password) > maxPasswordBytes {
+		return fmt.Errorf("password must be at most %d bytes", maxPasswordBytes)
+	}
+	return nil
+}
+
+// HashPassword returns a bcrypt hash of the password
+func HashPassword(password string) (string, error) {
+	if len(password) > maxPasswordBytes {
+		return "", errors.New("password too long")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), passwordCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	return string(hash), nil
+}
+
+// CheckPassword reports whether the password matches the hash. An empty hash
+// (account without a local password) never matches.
+func CheckPassword(hash, password string) bool {
+	if hash == "" {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestMain(m *testing.M) {
+	// Keep hashing fast in tests
+	passwordCost = bcrypt.MinCost
+	os.Exit(m.Run())
+}
+
+func TestHashPassword(t *testing.T) {
+	hash, err := HashPassword("correct horse battery")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if !CheckPassword(hash, "correct horse battery") {
+		t.Error("Expected password to match its hash")
+	}
+
+	if CheckPassword(hash, "wrong password") {
+		t.Error("Expected wrong password not to match")
+	}
+
+	if CheckPassword("", "correct horse battery") {
+		t.Error("Expected empty hash never to match")
+	}
+}
+
+func TestValidatePassword(t *testing.T) {
+	if err := ValidatePassword("short"); err == nil {
+		t.Error("Expected short password to be rejected")
+	}
+
+	if err := ValidatePassword(strings.Repeat("a", 80)); err == nil {
+		t.Error("Expected password over 72 bytes to be rejected")
+	}
+
+	if err := ValidatePassword("correct horse battery"); err != nil {
+		t.Errorf("Expected valid password to pass, got %v", err)
+	}
+}
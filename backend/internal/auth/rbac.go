@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"context"
+	"sort"
+)
+
+// Permission names an action a role may grant. The role → permission matrix
+// lives in the role_permissions table.
+type Permission string
+
+const (
+	PermissionUsersRead     Permission = "users:read"
+	PermissionUsersWrite    Permission = "users:write"
+	PermissionUsersDelete   Permission = "users:delete"
+	PermissionRolesManage   Permission = "roles:manage"
+	PermissionTestsRead     Permission = "tests:read"
+	PermissionTestsWrite    Permission = "tests:write"
+	PermissionTestsDelete   Permission = "tests:delete"
+	PermissionResultsRead   Permission = "results:read"
+	PermissionResultsWrite  Permission = "results:write"
+	PermissionResultsVerify Permission = "results:verify"
+	PermissionInferenceRead Permission = "inference:read"
+	PermissionInferenceRun  Permission = "inference:run"
+	PermissionSlidesRead    Permission = "slides:read"
+	PermissionSlidesWrite   Permission = "slides:write"
+	PermissionSlidesReadAny Permission = "slides:read_any"
+	PermissionSlidesShare   Permission = "slides:share"
+	PermissionAuditRead     Permission = "audit:read"
+	PermissionPatientsRead  Permission = "patients:read"
+	PermissionPatientsWrite Permission = "patients:write"
+	PermissionPatientsMerge Permission = "patients:merge"
+	PermissionMetricsRead   Permission = "metrics:read"
+	PermissionConfigRead    Permission = "config:read"
+)
+
+// AccessStore loads the roles and permissions granted to a user
+type AccessStore interface {
+	GetUserAccess(ctx context.Context, userID int) (roles []string, permissions []string, err error)
+}
+
+// Access is the set of roles and permissions held by the current caller
+type Access struct {
+	Roles       []string
+	permissions map[Permission]bool
+}
+
+// NewAccess builds an Access from role and permission names
+func NewAccess(roles []string, permissions []string) *Access {
+	access := &Access{
+		Roles:       roles,
+		permissions: make(map[Permission]bool, len(permissions)),
+	}
+	for _, permission := range permissions {
+		access.permissions[Permission(permission)] = true
+	}
+	return access
+}
+
+// Has reports whether the permission is granted
+func (a *Access) Has(permission Permission) bool {
+	return a != nil && a.permissions[permission]
+}
+
+// Permissions returns the granted permissions in sorted order
+func (a *Access) Permissions() []string {
+	if a == nil {
+		return nil
+	}
+	permissions := make([]string, 0, len(a.permissions))
+	for permission := range a.permissions {
+		permissions = append(permissions, string(permission))
+	}
+	sort.Strings(permissions)
+	return permissions
+}
+
+// WithAccess returns a context carrying the caller's roles and permissions
+func WithAccess(ctx context.Context, access *Access) context.Context {
+	return context.WithValue(ctx, accessContextKey, access)
+}
+
+// AccessFromContext returns the caller's roles and permissions, if any
+func AccessFromContext(ctx context.Context) (*Access, bool) {
+	access, ok := ctx.Value(accessContextKey).(*Access)
+	return access, ok && access != nil
+}
+
+// HasPermission reports whether the caller in ctx holds the permission
+func HasPermission(ctx context.Context, permission Permission) bool {
+	access, _ := AccessFromContext(ctx)
+	return access.Has(permission)
+}
@@ -0,0 +1,350 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"backend/internal/config"
+	"backend/internal/models"
+)
+
+const (
+	// rotationGrace keeps a rotated-out session valid briefly so requests
+	// already in flight with the old cookie are not rejected
+	rotationGrace = 30 * time.Second
+
+	// touchInterval limits how often last_seen_at is written
+	touchInterval = time.Minute
+)
+
+var (
+	// ErrInvalidCredentials is returned for an unknown email or wrong password
+	ErrInvalidCredentials = errors.New("invalid email or password")
+
+	// ErrAccountLocked is returned while an account is locked out
+	ErrAccountLocked = errors.New("account temporarily locked")
+)
+
+// UserStore is the subset of models.UserRepository used for authentication
+type UserStore interface {
+	GetByID(ctx context.Context, id int) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	IncrementFailedLogins(ctx context.Context, id int) (int, error)
+	Lock(ctx context.Context, id int, until time.Time) error
+	ResetFailedLogins(ctx context.Context, id int) error
+}
+
+// SessionStore is the subset of models.SessionRepository used for sessions
+type SessionStore interface {
+	Create(ctx context.Context, session *models.Session) error
+	GetByID(ctx context.Context, id string) (*models.Session, error)
+	Touch(ctx context.Context, id string, lastSeen time.Time) error
+	Rotate(ctx context.Context, oldID string, graceUntil time.Time, session *models.Session) error
+	Delete(ctx context.Context, id string) error
+	DeleteByUserID(ctx context.Context, userID int) error
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Options configures session cookies, lifetimes and login lockout
+type Options struct {
+	CookieName        string
+	CookieSecure      bool
+	CookieSameSite    http.SameSite
+	TTL               time.Duration
+	IdleTimeout       time.Duration
+	RotationInterval  time.Duration
+	MaxFailedAttempts int
+	LockoutDuration   time.Duration
+
+	// ClientIP returns the client address recorded on new sessions. The
+	// router supplies the one resolved through TRUSTED_PROXIES; auth cannot
+	// read it from the audit context itself without an import cycle.
+	ClientIP func(r *http.Request) string
+}
+
+// OptionsFromConfig builds session options from application configuration
+func OptionsFromConfig(cfg *config.Config) Options {
+	opts := Options{
+		CookieName:        "alphapath_session",
+		CookieSecure:      cfg.SessionCookieSecure,
+		CookieSameSite:    http.SameSiteLaxMode,
+		TTL:               cfg.SessionTTL,
+		IdleTimeout:       cfg.SessionIdleTimeout,
+		RotationInterval:  cfg.SessionRotationInterval,
+		MaxFailedAttempts: cfg.LoginMaxAttempts,
+		LockoutDuration:   cfg.LoginLockoutDuration,
+	}
+
+	switch strings.ToLower(cfg.SessionCookieSameSite) {
+	case "strict":
+		opts.CookieSameSite = http.SameSiteStrictMode
+	case "none":
+		// Browsers reject SameSite=None cookies that are not Secure
+		opts.CookieSameSite = http.SameSiteNoneMode
+		opts.CookieSecure = true
+	}
+
+	return opts
+}
+
+// Manager verifies credentials and issues, resolves and revokes sessions
+type Manager struct {
+	users    UserStore
+	sessions SessionStore
+	opts     Options
+	now      func() time.Time
+}
+
+// NewManager creates a new session manager
+func NewManager(users UserStore, sessions SessionStore, opts Options) *Manager {
+	return &Manager{
+		users:    users,
+		sessions: sessions,
+		opts:     opts,
+		now:      func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Authenticate verifies an email and password, applying lockout after
+// repeated failures
+func (m *Manager) Authenticate(ctx context.Context, email, password string) (*models.User, error) {
+	user, err := m.users.GetByEmail(ctx, email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user: %w", err)
+	}
+
+	if user == nil {
+		// Spend the same time as a real comparison
+		CheckPassword("", password)
+		return nil, ErrInvalidCredentials
+	}
+
+	now := m.now()
+	if user.LockedUntil != nil && now.Before(*user.LockedUntil) {
+		return nil, ErrAccountLocked
+	}
+
+	if !CheckPassword(user.PasswordHash, password) {
+		attempts, err := m.users.IncrementFailedLogins(ctx, user.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record login failure: %w", err)
+		}
+
+		if m.opts.MaxFailedAttempts > 0 && attempts >= m.opts.MaxFailedAttempts {
+			if err := m.users.Lock(ctx, user.ID, now.Add(m.opts.LockoutDuration)); err != nil {
+				return nil, fmt.Errorf("failed to lock account: %w", err)
+			}
+			return nil, ErrAccountLocked
+		}
+
+		return nil, ErrInvalidCredentials
+	}
+
+	if user.FailedLoginAttempts > 0 || user.LockedUntil != nil {
+		if err := m.users.ResetFailedLogins(ctx, user.ID); err != nil {
+			return nil, fmt.Errorf("failed to reset login failures: %w", err)
+		}
+	}
+
+	return user, nil
+}
+
+// StartSession issues a new session for the user and sets the session cookie.
+// Any session presented with the request is revoked to prevent fixation.
+func (m *Manager) StartSession(w http.ResponseWriter, r *http.Request, user *models.User) (*models.Session, error) {
+	if cookie, err := r.Cookie(m.opts.CookieName); err == nil && cookie.Value != "" {
+		if err := m.sessions.Delete(r.Context(), hashToken(cookie.Value)); err != nil {
+			return nil, fmt.Errorf("failed to revoke previous session: %w", err)
+		}
+	}
+
+	// Opportunistically purge expired sessions
+	if _, err := m.sessions.DeleteExpired(r.Context(), m.now()); err != nil {
+		return nil, fmt.Errorf("failed to purge expired sessions: %w", err)
+	}
+
+	now := m.now()
+	token, session, err := m.newSession(r, user.ID, now, now.Add(m.opts.TTL))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.sessions.Create(r.Context(), session); err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	m.setCookie(w, token, session.ExpiresAt)
+	return session, nil
+}
+
+// Resolve returns the user and session for the request's session cookie, or
+// nil if there is no valid session. Idle and expired sessions are revoked, and
+// sessions older than the rotation interval are reissued under a new token.
+func (m *Manager) Resolve(w http.ResponseWriter, r *http.Request) (*models.User, *models.Session, error) {
+	cookie, err := r.Cookie(m.opts.CookieName)
+	if err != nil || cookie.Value == "" {
+		return nil, nil, nil
+	}
+
+	session, err := m.sessions.GetByID(r.Context(), hashToken(cookie.Value))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load session: %w", err)
+	}
+	if session == nil {
+		m.ClearCookie(w)
+		return nil, nil, nil
+	}
+
+	now := m.now()
+	if !now.Before(session.ExpiresAt) || (m.opts.IdleTimeout > 0 && now.Sub(session.LastSeenAt) > m.opts.IdleTimeout) {
+		if err := m.sessions.Delete(r.Context(), session.ID); err != nil {
+			return nil, nil, fmt.Errorf("failed to delete expired session: %w", err)
+		}
+		m.ClearCookie(w)
+		return nil, nil, nil
+	}
+
+	user, err := m.users.GetByID(r.Context(), session.UserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load session user: %w", err)
+	}
+	if user == nil {
+		if err := m.sessions.Delete(r.Context(), session.ID); err != nil {
+			return nil, nil, fmt.Errorf("failed to delete orphaned session: %w", err)
+		}
+		m.ClearCookie(w)
+		return nil, nil, nil
+	}
+
+	if m.opts.RotationInterval > 0 && now.Sub(session.CreatedAt) >= m.opts.RotationInterval {
+		token, rotated, err := m.newSession(r, session.UserID, session.AuthenticatedAt, session.ExpiresAt)
+		if err != nil {
+			return nil, nil, err
+		}
+		err = m.sessions.Rotate(r.Context(), session.ID, now.Add(rotationGrace), rotated)
+		switch {
+		case err == nil:
+			m.setCookie(w, token, rotated.ExpiresAt)
+			return user, rotated, nil
+		case !errors.Is(err, models.ErrSessionRotated):
+			return nil, nil, fmt.Errorf("failed to rotate session: %w", err)
+		}
+		// Rotated by an earlier request; the old cookie is honoured until
+		// its grace period ends
+	}
+
+	if now.Sub(session.LastSeenAt) >= touchInterval {
+		if err := m.sessions.Touch(r.Context(), session.ID, now); err != nil {
+			return nil, nil, fmt.Errorf("failed to update session: %w", err)
+		}
+		session.LastSeenAt = now
+	}
+
+	return user, session, nil
+}
+
+// EndSession revokes the request's session and clears the cookie
+func (m *Manager) EndSession(w http.ResponseWriter, r *http.Request) error {
+	m.ClearCookie(w)
+
+	cookie, err := r.Cookie(m.opts.CookieName)
+	if err != nil || cookie.Value == "" {
+		return nil
+	}
+
+	return m.sessions.Delete(r.Context(), hashToken(cookie.Value))
+}
+
+// EndAllSessions revokes every session belonging to the user
+func (m *Manager) EndAllSessions(ctx context.Context, userID int) error {
+	return m.sessions.DeleteByUserID(ctx, userID)
+}
+
+// ClearCookie instructs the browser to drop the session cookie
+func (m *Manager) ClearCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     m.opts.CookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   m.opts.CookieSecure,
+		SameSite: m.opts.CookieSameSite,
+	})
+}
+
+// newSession builds a session with a fresh random token
+func (m *Manager) newSession(r *http.Request, userID int, authenticatedAt, expiresAt time.Time) (string, *models.Session, error) {
+	token, err := newToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := m.now()
+	session := &models.Session{
+		ID:              hashToken(token),
+		UserID:          userID,
+		AuthenticatedAt: authenticatedAt,
+		CreatedAt:       now,
+		LastSeenAt:      now,
+		ExpiresAt:       expiresAt,
+		IPAddress:       m.clientIP(r),
+		UserAgent:       truncate(r.UserAgent(), 512),
+	}
+
+	return token, session, nil
+}
+
+// setCookie writes the session cookie
+func (m *Manager) setCookie(w http.ResponseWriter, token string, expiresAt time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     m.opts.CookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  expiresAt,
+		MaxAge:   int(expiresAt.Sub(m.now()).Seconds()),
+		HttpOnly: true,
+		Secure:   m.opts.CookieSecure,
+		SameSite: m.opts.CookieSameSite,
+	})
+}
+
+// newToken returns a random URL-safe session token
+func newToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate session token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashToken returns the storage key for a session token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// clientIP returns the address to record for the request's client, or ""
+// if none is configured
+func (m *Manager) clientIP(r *http.Request) string {
+	if m.opts.ClientIP == nil {
+		return ""
+	}
+	return truncate(m.opts.ClientIP(r), 64)
+}
+
+// truncate limits s to n bytes without leaving a partial UTF-8 sequence
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return strings.ToValidUTF8(s[:n], "")
+	}
+	return s
+}
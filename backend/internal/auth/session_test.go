@@ -0,0 +1,351 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+)
+
+// fakeUserStore is an in-memory UserStore
+type fakeUserStore struct {
+	users map[int]*models.User
+}
+
+func (s *fakeUserStore) GetByID(_ context.Context, id int) (*models.User, error) {
+	return s.users[id], nil
+}
+
+func (s *fakeUserStore) GetByEmail(_ context.Context, email string) (*models.User, error) {
+	for _, user := range s.users {
+		if strings.EqualFold(user.Email, email) {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *fakeUserStore) IncrementFailedLogins(_ context.Context, id int) (int, error) {
+	s.users[id].FailedLoginAttempts++
+	return s.users[id].FailedLoginAttempts, nil
+}
+
+func (s *fakeUserStore) Lock(_ context.Context, id int, until time.Time) error {
+	s.users[id].LockedUntil = &until
+	s.users[id].FailedLoginAttempts = 0
+	return nil
+}
+
+func (s *fakeUserStore) ResetFailedLogins(_ context.Context, id int) error {
+	s.users[id].LockedUntil = nil
+	s.users[id].FailedLoginAttempts = 0
+	return nil
+}
+
+// fakeSessionStore is an in-memory SessionStore
+type fakeSessionStore struct {
+	sessions map[string]*models.Session
+}
+
+func (s *fakeSessionStore) Create(_ context.Context, session *models.Session) error {
+	copied := *session
+	s.sessions[session.ID] = &copied
+	return nil
+}
+
+func (s *fakeSessionStore) GetByID(_ context.Context, id string) (*models.Session, error) {
+	if session, ok := s.sessions[id]; ok {
+		copied := *session
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (s *fakeSessionStore) Touch(_ context.Context, id string, lastSeen time.Time) error {
+	s.sessions[id].LastSeenAt = lastSeen
+	return nil
+}
+
+func (s *fakeSessionStore) Rotate(_ context.Context, oldID string, graceUntil time.Time, session *models.Session) error {
+	old, ok := s.sessions[oldID]
+	if !ok || !graceUntil.Before(old.ExpiresAt) {
+		return models.ErrSessionRotated
+	}
+	old.ExpiresAt = graceUntil
+	return s.Create(context.Background(), session)
+}
+
+func (s *fakeSessionStore) Delete(_ context.Context, id string) error {
+	delete(s.sessions, id)
+	return nil
+}
+
+func (s *fakeSessionStore) DeleteByUserID(_ context.Context, userID int) error {
+	for id, session := range s.sessions {
+		if session.UserID == userID {
+			delete(s.sessions, id)
+		}
+	}
+	return nil
+}
+
+func (s *fakeSessionStore) DeleteExpired(_ context.Context, before time.Time) (int64, error) {
+	var n int64
+	for id, session := range s.sessions {
+		if session.ExpiresAt.Before(before) {
+			delete(s.sessions, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// testClock is a manually advanced clock
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func newTestManager(t *testing.T) (*Manager, *fakeUserStore, *fakeSessionStore, *testClock) {
+	t.Helper()
+
+	hash, err := HashPassword("correct horse battery")
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+
+	users := &fakeUserStore{users: map[int]*models.User{
+		1: {ID: 1, Name: "Jane Doe", Email: "jane@example.com", PasswordHash: hash},
+	}}
+	sessions := &fakeSessionStore{sessions: map[string]*models.Session{}}
+	clock := &testClock{now: time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)}
+
+	manager := NewManager(users, sessions, Options{
+		CookieName:        "session",
+		CookieSecure:      true,
+		CookieSameSite:    http.SameSiteLaxMode,
+		TTL:               8 * time.Hour,
+		IdleTimeout:       30 * time.Minute,
+		RotationInterval:  15 * time.Minute,
+		MaxFailedAttempts: 3,
+		LockoutDuration:   10 * time.Minute,
+		ClientIP:          func(*http.Request) string { return "203.0.113.9" },
+	})
+	manager.now = clock.Now
+
+	return manager, users, sessions, clock
+}
+
+// login authenticates and returns the issued session cookie
+func login(t *testing.T, manager *Manager) *http.Cookie {
+	t.Helper()
+
+	user, err := manager.Authenticate(context.Background(), "jane@example.com", "correct horse battery")
+	if err != nil {
+		t.Fatalf("Expected login to succeed, got %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	if _, err := manager.StartSession(w, httptest.NewRequest(http.MethodPost, "/api/auth/login", nil), user); err != nil {
+		t.Fatalf("Expected session to start, got %v", err)
+	}
+
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("Expected one cookie, got %d", len(cookies))
+	}
+	return cookies[0]
+}
+
+func TestManager_Authenticate_WrongPassword(t *testing.T) {
+	manager, _, _, _ := newTestManager(t)
+
+	if _, err := manager.Authenticate(context.Background(), "jane@example.com", "wrong password!"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected ErrInvalidCredentials, got %v", err)
+	}
+
+	if _, err := manager.Authenticate(context.Background(), "nobody@example.com", "whatever"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected ErrInvalidCredentials for unknown email, got %v", err)
+	}
+}
+
+func TestManager_Authenticate_Lockout(t *testing.T) {
+	manager, users, _, clock := newTestManager(t)
+
+	for i := 0; i < 2; i++ {
+		if _, err := manager.Authenticate(context.Background(), "jane@example.com", "wrong password!"); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("Attempt %d: expected ErrInvalidCredentials, got %v", i+1, err)
+		}
+	}
+
+	if _, err := manager.Authenticate(context.Background(), "jane@example.com", "wrong password!"); !errors.Is(err, ErrAccountLocked) {
+		t.Fatalf("Expected third failure to lock the account, got %v", err)
+	}
+
+	// Correct password is rejected while locked
+	if _, err := manager.Authenticate(context.Background(), "jane@example.com", "correct horse battery"); !errors.Is(err, ErrAccountLocked) {
+		t.Errorf("Expected ErrAccountLocked while locked, got %v", err)
+	}
+
+	clock.now = clock.now.Add(11 * time.Minute)
+	if _, err := manager.Authenticate(context.Background(), "jane@example.com", "correct horse battery"); err != nil {
+		t.Errorf("Expected login after lockout expiry, got %v", err)
+	}
+
+	if users.users[1].LockedUntil != nil {
+		t.Error("Expected successful login to clear the lockout")
+	}
+}
+
+func TestManager_StartSession_Cookie(t *testing.T) {
+	manager, _, sessions, _ := newTestManager(t)
+
+	cookie := login(t, manager)
+	if !cookie.HttpOnly || !cookie.Secure || cookie.SameSite != http.SameSiteLaxMode {
+		t.Errorf("Expected HttpOnly, Secure, SameSite=Lax cookie, got %+v", cookie)
+	}
+
+	if _, ok := sessions.sessions[cookie.Value]; ok {
+		t.Error("Expected session to be stored by token hash, not raw token")
+	}
+
+	session, ok := sessions.sessions[hashToken(cookie.Value)]
+	if !ok {
+		t.Fatal("Expected session to be stored")
+	}
+	if session.IPAddress != "203.0.113.9" {
+		t.Errorf("Expected the resolved client address, got %q", session.IPAddress)
+	}
+}
+
+func TestManager_Resolve(t *testing.T) {
+	manager, _, _, clock := newTestManager(t)
+	cookie := login(t, manager)
+
+	clock.now = clock.now.Add(5 * time.Minute)
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/me", nil)
+	req.AddCookie(cookie)
+
+	user, session, err := manager.Resolve(httptest.NewRecorder(), req)
+	if err != nil || user == nil || session == nil {
+		t.Fatalf("Expected session to resolve, got user=%v err=%v", user, err)
+	}
+
+	if user.Email != "jane@example.com" {
+		t.Errorf("Expected jane@example.com, got %s", user.Email)
+	}
+}
+
+func TestManager_Resolve_IdleTimeout(t *testing.T) {
+	manager, _, sessions, clock := newTestManager(t)
+	cookie := login(t, manager)
+
+	clock.now = clock.now.Add(31 * time.Minute)
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/me", nil)
+	req.AddCookie(cookie)
+
+	user, _, err := manager.Resolve(httptest.NewRecorder(), req)
+	if err != nil || user != nil {
+		t.Errorf("Expected idle session to be rejected, got user=%v err=%v", user, err)
+	}
+
+	if len(sessions.sessions) != 0 {
+		t.Error("Expected idle session to be deleted")
+	}
+}
+
+func TestManager_Resolve_Rotation(t *testing.T) {
+	manager, _, sessions, clock := newTestManager(t)
+	cookie := login(t, manager)
+	originalExpiry := sessions.sessions[hashToken(cookie.Value)].ExpiresAt
+
+	clock.now = clock.now.Add(10 * time.Minute)
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/me", nil)
+	req.AddCookie(cookie)
+	manager.Resolve(httptest.NewRecorder(), req)
+
+	clock.now = clock.now.Add(6 * time.Minute)
+	w := httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/api/auth/me", nil)
+	req.AddCookie(cookie)
+
+	user, rotated, err := manager.Resolve(w, req)
+	if err != nil || user == nil {
+		t.Fatalf("Expected rotation to keep the user signed in, got err=%v", err)
+	}
+
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Value == cookie.Value {
+		t.Fatal("Expected a new session cookie after rotation")
+	}
+
+	if !rotated.ExpiresAt.Equal(originalExpiry) {
+		t.Error("Expected rotation to keep the absolute expiry")
+	}
+
+	if old := sessions.sessions[hashToken(cookie.Value)]; !old.ExpiresAt.Equal(clock.now.Add(rotationGrace)) {
+		t.Errorf("Expected old session to expire after the grace period, got %v", old.ExpiresAt)
+	}
+}
+
+func TestManager_Resolve_RotatedCookieDuringGrace(t *testing.T) {
+	manager, _, sessions, clock := newTestManager(t)
+	cookie := login(t, manager)
+
+	clock.now = clock.now.Add(16 * time.Minute)
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/me", nil)
+	req.AddCookie(cookie)
+	if _, _, err := manager.Resolve(httptest.NewRecorder(), req); err != nil {
+		t.Fatalf("Expected rotation to succeed, got %v", err)
+	}
+
+	// Requests already in flight with the old cookie are served without
+	// rotating again
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/auth/me", nil)
+		req.AddCookie(cookie)
+
+		user, session, err := manager.Resolve(w, req)
+		if err != nil || user == nil {
+			t.Fatalf("Expected the old cookie to resolve during the grace period, got err=%v", err)
+		}
+		if session.ID != hashToken(cookie.Value) || len(w.Result().Cookies()) != 0 {
+			t.Error("Expected the rotated-out session not to rotate again")
+		}
+	}
+
+	if len(sessions.sessions) != 2 {
+		t.Errorf("Expected the original and one rotated session, got %d", len(sessions.sessions))
+	}
+}
+
+func TestManager_EndSession(t *testing.T) {
+	manager, _, sessions, _ := newTestManager(t)
+	cookie := login(t, manager)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/logout", nil)
+	req.AddCookie(cookie)
+	w := httptest.NewRecorder()
+
+	if err := manager.EndSession(w, req); err != nil {
+		t.Fatalf("Expected logout to succeed, got %v", err)
+	}
+
+	if len(sessions.sessions) != 0 {
+		t.Error("Expected session to be deleted")
+	}
+
+	if cookies := w.Result().Cookies(); len(cookies) != 1 || cookies[0].MaxAge >= 0 {
+		t.Error("Expected cookie to be cleared")
+	}
+}
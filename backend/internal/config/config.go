@@ -1,40 +1,271 @@
+// Package config loads the application's settings. Each setting is read, in
+// increasing precedence, from its default, a YAML or TOML config file, the
+// environment and command-line flags, then checked as a whole so every
+// problem is reported at once.
 package config
 
 import (
+	"context"
+	"net/netip"
 	"os"
-
-	"github.com/joho/godotenv"
+	"strings"
+	"time"
 )
 
-// Config holds all configuration for the application
+// Config holds all configuration for the application. Each field's env tag
+// names the setting: the environment variable, the snake_case key in config
+// files (database_url) and the flag (-database-url). Fields tagged secret
+// are redacted by Settings, and fields tagged reload take effect when a
+// Watcher reloads; the rest need a restart.
 type Config struct {
-	Port        string
-	DatabaseURL string
-	Environment string
-	LogLevel    string
-	FrontendURL string
+	Port        string `env:"PORT"`
+	DatabaseURL string `env:"DATABASE_URL" secret:"url"`
+	Environment string `env:"ENVIRONMENT"`
+	LogLevel    string `env:"LOG_LEVEL" reload:"true"`
+	FrontendURL string `env:"FRONTEND_URL" reload:"true"`
+	AutoMigrate bool   `env:"AUTO_MIGRATE"`
+
+	// Cross-origin requests are allowed from FrontendURL and
+	// CORSAllowedOrigins, which may hold patterns such as
+	// https://*.preview.example.com or http://localhost:*
+	CORSAllowedOrigins []string      `env:"CORS_ALLOWED_ORIGINS" reload:"true"`
+	CORSExposedHeaders []string      `env:"CORS_EXPOSED_HEADERS" reload:"true"`
+	CORSMaxAge         time.Duration `env:"CORS_MAX_AGE" reload:"true"`
+
+	// Log attribute keys whose values are redacted; nil uses the defaults
+	LogRedactFields []string `env:"LOG_REDACT_FIELDS"`
+
+	// OpenTelemetry traces and metrics are exported over OTLP/HTTP to
+	// OTelEndpoint when it is set
+	OTelEndpoint    string  `env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	OTelServiceName string  `env:"OTEL_SERVICE_NAME"`
+	OTelSampleRatio float64 `env:"OTEL_SAMPLE_RATIO"`
+
+	// Prometheus metrics are served at /metrics on MetricsPort when it is
+	// set, and otherwise on the API port to callers with metrics:read
+	MetricsPort string `env:"METRICS_PORT"`
+
+	// Session and login settings
+	SessionTTL              time.Duration `env:"SESSION_TTL"`
+	SessionIdleTimeout      time.Duration `env:"SESSION_IDLE_TIMEOUT"`
+	SessionRotationInterval time.Duration `env:"SESSION_ROTATION_INTERVAL"`
+	SessionCookieSecure     bool          `env:"SESSION_COOKIE_SECURE"`
+	SessionCookieSameSite   string        `env:"SESSION_COOKIE_SAMESITE"`
+	LoginMaxAttempts        int           `env:"LOGIN_MAX_ATTEMPTS"`
+	LoginLockoutDuration    time.Duration `env:"LOGIN_LOCKOUT_DURATION"`
+
+	// Proxies whose X-Forwarded-For entries are trusted, as addresses or
+	// CIDR ranges; the client IP is the nearest untrusted address
+	TrustedProxies []string `env:"TRUSTED_PROXIES"`
+
+	// Per-client rate limits: RateLimitRequests per RateLimitPeriod for
+	// most routes, with stricter limits on sign-in and on creating
+	// accounts and API keys. Zero requests disables a limit. RateLimitStore
+	// is "memory", per replica, or "postgres", shared by all replicas.
+	RateLimitStore           string        `env:"RATE_LIMIT_STORE"`
+	RateLimitRequests        int           `env:"RATE_LIMIT_REQUESTS" reload:"true"`
+	RateLimitPeriod          time.Duration `env:"RATE_LIMIT_PERIOD" reload:"true"`
+	RateLimitAuthRequests    int           `env:"RATE_LIMIT_AUTH_REQUESTS" reload:"true"`
+	RateLimitAuthPeriod      time.Duration `env:"RATE_LIMIT_AUTH_PERIOD" reload:"true"`
+	RateLimitAccountRequests int           `env:"RATE_LIMIT_ACCOUNT_REQUESTS" reload:"true"`
+	RateLimitAccountPeriod   time.Duration `env:"RATE_LIMIT_ACCOUNT_PERIOD" reload:"true"`
+
+	// OIDC single sign-on; disabled when OIDCDiscoveryURL is empty
+	OIDCDiscoveryURL string   `env:"OIDC_DISCOVERY_URL"`
+	OIDCClientID     string   `env:"OIDC_CLIENT_ID"`
+	OIDCClientSecret string   `env:"OIDC_CLIENT_SECRET" secret:"true"`
+	OIDCRedirectURL  string   `env:"OIDC_REDIRECT_URL"`
+	OIDCScopes       []string `env:"OIDC_SCOPES"`
+
+	// Users granted the admin role at startup, for bootstrapping a fresh install
+	BootstrapAdminEmails []string `env:"BOOTSTRAP_ADMIN_EMAILS"`
+
+	// Inference job queue; InferenceClient selects the model-serving backend
+	// ("fake", "http" or "grpc") reached at InferenceEndpoint
+	InferenceClient          string        `env:"INFERENCE_CLIENT"`
+	InferenceEndpoint        string        `env:"INFERENCE_ENDPOINT"`
+	InferenceRequestTimeout  time.Duration `env:"INFERENCE_REQUEST_TIMEOUT"`
+	InferenceGRPCPlaintext   bool          `env:"INFERENCE_GRPC_PLAINTEXT"`
+	InferenceModelVersions   []string      `env:"INFERENCE_MODEL_VERSIONS"`
+	InferenceBreakerFailures int           `env:"INFERENCE_BREAKER_FAILURES"`
+	InferenceBreakerCooldown time.Duration `env:"INFERENCE_BREAKER_COOLDOWN"`
+	InferenceWorkers         int           `env:"INFERENCE_WORKERS"`
+	InferencePollInterval    time.Duration `env:"INFERENCE_POLL_INTERVAL"`
+	InferenceJobTimeout      time.Duration `env:"INFERENCE_JOB_TIMEOUT"`
+	InferenceMaxAttempts     int           `env:"INFERENCE_MAX_ATTEMPTS"`
+	InferenceRetryBackoff    time.Duration `env:"INFERENCE_RETRY_BACKOFF"`
+	InferenceRetryMaxBackoff time.Duration `env:"INFERENCE_RETRY_MAX_BACKOFF"`
+
+	// Blob storage for uploaded slides; BlobStore is "local" or "azure"
+	BlobStore                 string        `env:"BLOB_STORE"`
+	BlobLocalDir              string        `env:"BLOB_LOCAL_DIR"`
+	BlobAzureConnectionString string        `env:"BLOB_AZURE_CONNECTION_STRING" secret:"true"`
+	BlobAzureContainer        string        `env:"BLOB_AZURE_CONTAINER"`
+	UploadMaxSize             int64         `env:"UPLOAD_MAX_SIZE"`
+	UploadChunkTimeout        time.Duration `env:"UPLOAD_CHUNK_TIMEOUT"`
+
+	// Rendered slide tile caches, in bytes; an empty TileCacheDir keeps tiles in memory only
+	TileCacheMemory   int64  `env:"TILE_CACHE_MEMORY"`
+	TileCacheDir      string `env:"TILE_CACHE_DIR"`
+	TileCacheDiskSize int64  `env:"TILE_CACHE_DISK_SIZE"`
+
+	// HL7 v2 interface. The MLLP listener on HL7Port accepts ORM^O01 orders
+	// and is disabled when the port is empty; ORU^R01 results are sent to
+	// HL7OutboundAddr when it is set.
+	HL7Port                 string        `env:"HL7_PORT"`
+	HL7SendingApplication   string        `env:"HL7_SENDING_APPLICATION"`
+	HL7SendingFacility      string        `env:"HL7_SENDING_FACILITY"`
+	HL7ReceivingApplication string        `env:"HL7_RECEIVING_APPLICATION"`
+	HL7ReceivingFacility    string        `env:"HL7_RECEIVING_FACILITY"`
+	HL7IdleTimeout          time.Duration `env:"HL7_IDLE_TIMEOUT"`
+	HL7OutboundAddr         string        `env:"HL7_OUTBOUND_ADDR"`
+	HL7AckTimeout           time.Duration `env:"HL7_ACK_TIMEOUT"`
+	HL7PollInterval         time.Duration `env:"HL7_POLL_INTERVAL"`
+	HL7MaxAttempts          int           `env:"HL7_MAX_ATTEMPTS"`
+	HL7RetryBackoff         time.Duration `env:"HL7_RETRY_BACKOFF"`
+	HL7RetryMaxBackoff      time.Duration `env:"HL7_RETRY_MAX_BACKOFF"`
+
+	// A Watcher checks the files settings were read from for changes this
+	// often
+	ConfigReloadInterval time.Duration `env:"CONFIG_RELOAD_INTERVAL"`
+
+	// Features lists the feature flags turned on; see FeatureEnabled
+	Features []string `env:"FEATURES" reload:"true"`
+
+	// origins records where each setting came from, for Settings
+	origins map[string]origin
+
+	// files lists the files the settings were read from, for Watcher
+	files []string
+}
+
+// Environments lists the accepted values of ENVIRONMENT
+var Environments = []string{"development", "staging", "production"}
+
+// Defaults returns the configuration used when no source sets a value.
+// SESSION_COOKIE_SECURE defaults to true outside development, so it is
+// settled by Load once ENVIRONMENT is known.
+func Defaults() *Config {
+	return &Config{
+		Port:        "8080",
+		Environment: "development",
+		LogLevel:    "info",
+		FrontendURL: "http://localhost:3000",
+		AutoMigrate: true,
+
+		CORSMaxAge: 24 * time.Hour,
+		CORSExposedHeaders: []string{"ETag", "Location", "X-Request-ID",
+			"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After"},
+
+		OTelServiceName: "alphapath-api",
+		OTelSampleRatio: 1,
+
+		SessionTTL:              12 * time.Hour,
+		SessionIdleTimeout:      30 * time.Minute,
+		SessionRotationInterval: 15 * time.Minute,
+		SessionCookieSameSite:   "lax",
+		LoginMaxAttempts:        5,
+		LoginLockoutDuration:    15 * time.Minute,
+
+		// Private networks and loopback, where the ingress proxy connects from
+		TrustedProxies: []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "127.0.0.0/8", "fc00::/7", "::1/128"},
+
+		RateLimitStore:           "memory",
+		RateLimitRequests:        1200,
+		RateLimitPeriod:          time.Minute,
+		RateLimitAuthRequests:    10,
+		RateLimitAuthPeriod:      time.Minute,
+		RateLimitAccountRequests: 30,
+		RateLimitAccountPeriod:   time.Hour,
+
+		OIDCRedirectURL: "http://localhost:8080/api/auth/oidc/callback",
+		OIDCScopes:      []string{"openid", "email", "profile"},
+
+		InferenceClient:          "fake",
+		InferenceRequestTimeout:  30 * time.Second,
+		InferenceBreakerFailures: 5,
+		InferenceBreakerCooldown: 30 * time.Second,
+		InferenceWorkers:         2,
+		InferencePollInterval:    2 * time.Second,
+		InferenceJobTimeout:      2 * time.Minute,
+		InferenceMaxAttempts:     3,
+		InferenceRetryBackoff:    10 * time.Second,
+		InferenceRetryMaxBackoff: 5 * time.Minute,
+
+		BlobStore:          "local",
+		BlobLocalDir:       "./data/blobs",
+		BlobAzureContainer: "slides",
+		UploadMaxSize:      50 << 30,
+		UploadChunkTimeout: 15 * time.Minute,
+		TileCacheMemory:    256 << 20,
+		TileCacheDir:       "./data/tile-cache",
+		TileCacheDiskSize:  10 << 30,
+
+		HL7SendingApplication: "ALPHAPATH",
+		HL7SendingFacility:    "ALPHAPATH_LAB",
+		HL7IdleTimeout:        10 * time.Minute,
+		HL7AckTimeout:         30 * time.Second,
+		HL7PollInterval:       2 * time.Second,
+		HL7MaxAttempts:        10,
+		HL7RetryBackoff:       30 * time.Second,
+		HL7RetryMaxBackoff:    30 * time.Minute,
+
+		ConfigReloadInterval: 10 * time.Second,
+	}
+}
+
+// FeatureEnabled reports whether the named feature flag is listed in
+// FEATURES. Flags can be switched by a reload, so check them per use rather
+// than once at startup.
+func (c *Config) FeatureEnabled(name string) bool {
+	for _, feature := range c.Features {
+		if strings.EqualFold(feature, name) {
+			return true
+		}
+	}
+	return false
 }
 
-// Load reads configuration from environment variables
-func Load() (*Config, error) {
-	// Load .env file if it exists (for local development)
-	_ = godotenv.Load()
-
-	cfg := &Config{
-		Port:        getEnv("PORT", "8080"),
-		DatabaseURL: getEnv("DATABASE_URL", ""),
-		Environment: getEnv("ENVIRONMENT", "development"),
-		LogLevel:    getEnv("LOG_LEVEL", "info"),
-		FrontendURL: getEnv("FRONTEND_URL", "http://localhost:3000"),
+// TrustedProxyPrefixes returns TRUSTED_PROXIES as address ranges, a single
+// address being a range of one. Load has rejected any that do not parse.
+func (c *Config) TrustedProxyPrefixes() []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, proxy := range c.TrustedProxies {
+		if prefix, ok := parsePrefix(proxy); ok {
+			prefixes = append(prefixes, prefix)
+		}
 	}
+	return prefixes
+}
 
-	return cfg, nil
+// parsePrefix parses a CIDR range or a single address
+func parsePrefix(value string) (netip.Prefix, bool) {
+	if prefix, err := netip.ParsePrefix(value); err == nil {
+		return prefix.Masked(), true
+	}
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), true
 }
 
-// getEnv retrieves an environment variable with a fallback default value
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// NewLoader creates a loader for the process: args are the command-line
+// flags without the program name, the environment is supplemented by a .env
+// file in the working directory (for local development), and Key Vault
+// references are resolved with the process's Azure credentials.
+func NewLoader(args []string) *Loader {
+	return &Loader{
+		Args:      args,
+		LookupEnv: os.LookupEnv,
+		DotEnv:    ".env",
+		ReadFile:  os.ReadFile,
+		Resolver:  &KeyVaultResolver{},
 	}
-	return defaultValue
+}
+
+// Load reads the process's configuration once; see NewLoader. The error
+// lists every invalid setting.
+func Load(args []string) (*Config, error) {
+	return NewLoader(args).Load(context.Background())
 }
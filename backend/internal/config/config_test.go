@@ -0,0 +1,261 @@
+package config
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestLoader returns a loader reading env and files from maps
+func newTestLoader(env map[string]string, files map[string]string, args ...string) *Loader {
+	return &Loader{
+		Args: args,
+		LookupEnv: func(key string) (string, bool) {
+			value, ok := env[key]
+			return value, ok
+		},
+		ReadFile: func(name string) ([]byte, error) {
+			data, ok := files[name]
+			if !ok {
+				return nil, os.ErrNotExist
+			}
+			return []byte(data), nil
+		},
+		Resolver: FakeResolver{},
+	}
+}
+
+func settingOf(cfg *Config, key string) Setting {
+	for _, setting := range cfg.Settings() {
+		if setting.Key == key {
+			return setting
+		}
+	}
+	return Setting{}
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, err := newTestLoader(map[string]string{"DATABASE_URL": "postgres://localhost/app"}, nil).Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.Port != "8080" || cfg.SessionTTL != 12*time.Hour || cfg.UploadMaxSize != 50<<30 || !cfg.AutoMigrate {
+		t.Errorf("Expected defaults, got %+v", cfg)
+	}
+	if cfg.SessionCookieSecure {
+		t.Error("Expected insecure cookies by default in development")
+	}
+	if source := settingOf(cfg, "PORT").Source; source != SourceDefault {
+		t.Errorf("Expected PORT from defaults, got %s", source)
+	}
+}
+
+func TestLoad_Layers(t *testing.T) {
+	files := map[string]string{
+		"app.yaml": "port: 9000\nlog_level: debug\nsession_ttl: 2h\noidc_scopes: [openid, email]\nauto_migrate: false\n",
+	}
+	env := map[string]string{
+		"CONFIG_FILE":  "app.yaml",
+		"DATABASE_URL": "postgres://localhost/app",
+		"ENVIRONMENT":  "production",
+		"LOG_LEVEL":    "warn",
+		"PORT":         "9100",
+	}
+
+	cfg, err := newTestLoader(env, files, "-port", "9200", "-auto-migrate").Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.Port != "9200" || cfg.LogLevel != "warn" || cfg.SessionTTL != 2*time.Hour || !cfg.AutoMigrate {
+		t.Errorf("Expected flags over env over file, got port %s, level %s, TTL %s, auto-migrate %t",
+			cfg.Port, cfg.LogLevel, cfg.SessionTTL, cfg.AutoMigrate)
+	}
+	if strings.Join(cfg.OIDCScopes, " ") != "openid email" {
+		t.Errorf("Expected scopes from the file's list, got %v", cfg.OIDCScopes)
+	}
+	if !cfg.SessionCookieSecure {
+		t.Error("Expected secure cookies by default in production")
+	}
+
+	for key, expected := range map[string]Source{"PORT": SourceFlag, "LOG_LEVEL": SourceEnv, "SESSION_TTL": SourceFile, "FRONTEND_URL": SourceDefault} {
+		if source := settingOf(cfg, key).Source; source != expected {
+			t.Errorf("Expected %s from %s, got %s", key, expected, source)
+		}
+	}
+}
+
+func TestLoad_TOML(t *testing.T) {
+	files := map[string]string{
+		"app.toml": "database_url = \"postgres://localhost/app\"\ninference_workers = 4\notel_sample_ratio = 0.25\n",
+	}
+
+	cfg, err := newTestLoader(nil, files, "-config", "app.toml").Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.InferenceWorkers != 4 || cfg.OTelSampleRatio != 0.25 {
+		t.Errorf("Expected settings from TOML, got workers %d, ratio %v", cfg.InferenceWorkers, cfg.OTelSampleRatio)
+	}
+}
+
+func TestLoad_AggregatesErrors(t *testing.T) {
+	files := map[string]string{"app.yaml": "prot: 9000\n"}
+	env := map[string]string{
+		"CONFIG_FILE":       "app.yaml",
+		"ENVIRONMENT":       "prod",
+		"SESSION_TTL":       "12 hours",
+		"INFERENCE_CLIENT":  "http",
+		"OTEL_SAMPLE_RATIO": "2",
+
+		"CORS_ALLOWED_ORIGINS": "https://*.preview.example.com, https://app.example.com/path",
+	}
+
+	_, err := newTestLoader(env, files).Load(context.Background())
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+
+	for _, expected := range []string{
+		`config file app.yaml: unknown setting "prot"`,
+		`SESSION_TTL (env): invalid Duration "12 hours"`,
+		"DATABASE_URL: is required",
+		`ENVIRONMENT: "prod" is not one of development, staging, production`,
+		"INFERENCE_ENDPOINT: is required for the http client",
+		"OTEL_SAMPLE_RATIO: must be between 0 and 1",
+		`CORS_ALLOWED_ORIGINS: "https://app.example.com/path" is not an origin`,
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected the error to include %q, got:\n%v", expected, err)
+		}
+	}
+}
+
+func TestLoad_Flags(t *testing.T) {
+	env := map[string]string{"DATABASE_URL": "postgres://localhost/app"}
+
+	_, err := newTestLoader(env, nil, "-inference-workers", "two").Load(context.Background())
+	if err == nil || !strings.Contains(err.Error(), `INFERENCE_WORKERS (flag): invalid int "two"`) {
+		t.Errorf("Expected an invalid flag value to fail, got %v", err)
+	}
+
+	_, err = newTestLoader(env, nil, "serve").Load(context.Background())
+	if err == nil || !strings.Contains(err.Error(), `unexpected argument "serve"`) {
+		t.Errorf("Expected stray arguments to be rejected, got %v", err)
+	}
+}
+
+func TestLoad_SecretFiles(t *testing.T) {
+	env := map[string]string{
+		"DATABASE_URL_FILE":       "/run/secrets/db",
+		"OIDC_CLIENT_SECRET_FILE": "/run/secrets/oidc",
+	}
+	files := map[string]string{
+		"/run/secrets/db":   "postgres://app:hunter2@db/app\n",
+		"/run/secrets/oidc": "s3cret\n",
+	}
+
+	cfg, err := newTestLoader(env, files).Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.DatabaseURL != "postgres://app:hunter2@db/app" || cfg.OIDCClientSecret != "s3cret" {
+		t.Errorf("Expected secrets read from files without trailing newlines, got %q and %q", cfg.DatabaseURL, cfg.OIDCClientSecret)
+	}
+
+	env["DATABASE_URL"] = "postgres://localhost/app"
+	if _, err := newTestLoader(env, files).Load(context.Background()); err == nil || !strings.Contains(err.Error(), "both DATABASE_URL and DATABASE_URL_FILE are set") {
+		t.Errorf("Expected a conflict error, got %v", err)
+	}
+}
+
+func TestLoad_KeyVaultReferences(t *testing.T) {
+	env := map[string]string{
+		"DATABASE_URL":       "@Microsoft.KeyVault(SecretUri=https://alphapath.vault.azure.net/secrets/database-url/)",
+		"OIDC_CLIENT_SECRET": "@Microsoft.KeyVault(VaultName=alphapath;SecretName=oidc-secret;SecretVersion=v2)",
+	}
+	loader := newTestLoader(env, nil)
+	loader.Resolver = FakeResolver{
+		"https://alphapath.vault.azure.net/secrets/database-url":   "postgres://app:hunter2@db/app",
+		"https://alphapath.vault.azure.net/secrets/oidc-secret/v2": "s3cret",
+	}
+
+	cfg, err := loader.Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.DatabaseURL != "postgres://app:hunter2@db/app" || cfg.OIDCClientSecret != "s3cret" {
+		t.Errorf("Expected references resolved, got %q and %q", cfg.DatabaseURL, cfg.OIDCClientSecret)
+	}
+
+	env["OIDC_CLIENT_ID"] = "@Microsoft.KeyVault(VaultName=alphapath;SecretName=missing)"
+	_, err = newTestLoader(env, nil).Load(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "OIDC_CLIENT_ID: resolving https://alphapath.vault.azure.net/secrets/missing: secret not found") {
+		t.Errorf("Expected an unresolvable reference to fail, got %v", err)
+	}
+}
+
+func TestParseSecretRef(t *testing.T) {
+	tests := []struct {
+		value   string
+		ref     SecretRef
+		isRef   bool
+		wantErr bool
+	}{
+		{"plain", SecretRef{}, false, false},
+		{"@Microsoft.KeyVault(SecretUri=https://kv.vault.azure.net/secrets/name/abc)", SecretRef{"https://kv.vault.azure.net/", "name", "abc"}, true, false},
+		{"@Microsoft.KeyVault(VaultName=kv; SecretName=name)", SecretRef{"https://kv.vault.azure.net/", "name", ""}, true, false},
+		{"@Microsoft.KeyVault(SecretUri=http://kv.vault.azure.net/secrets/name)", SecretRef{}, true, true},
+		{"@Microsoft.KeyVault(SecretUri=https://kv.vault.azure.net/keys/name)", SecretRef{}, true, true},
+		{"@Microsoft.KeyVault(VaultName=kv)", SecretRef{}, true, true},
+		{"@Microsoft.KeyVault(VaultName=kv;SecretName=name", SecretRef{}, true, true},
+	}
+
+	for _, tt := range tests {
+		ref, isRef, err := ParseSecretRef(tt.value)
+		if ref != tt.ref || isRef != tt.isRef || (err != nil) != tt.wantErr {
+			t.Errorf("ParseSecretRef(%q) = %+v, %t, %v", tt.value, ref, isRef, err)
+		}
+	}
+}
+
+func TestSettings_Redacted(t *testing.T) {
+	env := map[string]string{
+		"DATABASE_URL":       "postgres://app:hunter2@db/app?sslmode=require",
+		"OIDC_CLIENT_SECRET": "s3cret",
+		"OIDC_CLIENT_ID":     "@Microsoft.KeyVault(VaultName=kv;SecretName=client-id)",
+		"OIDC_DISCOVERY_URL": "https://login.example.com/.well-known/openid-configuration",
+		"HL7_PORT_FILE":      "/run/secrets/hl7-port",
+	}
+	loader := newTestLoader(env, map[string]string{"/run/secrets/hl7-port": "2575"})
+	loader.Resolver = FakeResolver{"https://kv.vault.azure.net/secrets/client-id": "alphapath"}
+
+	cfg, err := loader.Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"DATABASE_URL":                 "postgres://app:xxxxx@db/app?sslmode=require",
+		"OIDC_CLIENT_SECRET":           redacted,
+		"OIDC_CLIENT_ID":               redacted,
+		"HL7_PORT":                     redacted,
+		"BLOB_AZURE_CONNECTION_STRING": "",
+		"SESSION_TTL":                  "12h0m0s",
+	}
+	for key, value := range expected {
+		if got := settingOf(cfg, key).Value; got != value {
+			t.Errorf("Expected %s to be shown as %v, got %v", key, value, got)
+		}
+	}
+
+	if got := redactURL("host=db password=hunter2"); got != redacted {
+		t.Errorf("Expected a key=value connection string to be redacted entirely, got %q", got)
+	}
+	if got := redactURL("postgres://db/app?password=hunter2"); strings.Contains(got, "hunter2") {
+		t.Errorf("Expected the password parameter to be redacted, got %q", got)
+	}
+}
@@ -0,0 +1,379 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// resolveTimeout bounds Key Vault lookups so a missing identity cannot hang
+// startup or a reload
+const resolveTimeout = 30 * time.Second
+
+// Source names the layer a setting's value came from
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+)
+
+// origin records where a setting's value came from. Values read from a
+// *_FILE or resolved from Key Vault are secret wherever they are used.
+type origin struct {
+	source Source
+	secret bool
+}
+
+// Loader reads configuration from its sources. NewLoader builds one for the
+// process; tests supply their own environment, files and resolver.
+type Loader struct {
+	// Args are the command-line flags, without the program name
+	Args []string
+
+	// LookupEnv reads an environment variable
+	LookupEnv func(key string) (string, bool)
+
+	// DotEnv names a .env file whose variables apply where the environment
+	// does not set them. It is read on every Load, so edits are picked up
+	// by reloads; a missing file is ignored.
+	DotEnv string
+
+	// ReadFile reads the config file and *_FILE secrets
+	ReadFile func(name string) ([]byte, error)
+
+	// Resolver resolves Key Vault references; with none, any reference is
+	// an error
+	Resolver SecretResolver
+}
+
+// field is a Config field and the setting it holds
+type field struct {
+	key    string
+	index  int
+	secret string
+	reload bool
+}
+
+// fields lists the settings in declaration order
+var fields = configFields()
+
+func configFields() []field {
+	t := reflect.TypeOf(Config{})
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		if key := t.Field(i).Tag.Get("env"); key != "" {
+			fields = append(fields, field{
+				key:    key,
+				index:  i,
+				secret: t.Field(i).Tag.Get("secret"),
+				reload: t.Field(i).Tag.Get("reload") == "true",
+			})
+		}
+	}
+	return fields
+}
+
+// fileKey is the setting's key in config files
+func (f field) fileKey() string {
+	return strings.ToLower(f.key)
+}
+
+// flagName is the setting's command-line flag
+func (f field) flagName() string {
+	return strings.ReplaceAll(strings.ToLower(f.key), "_", "-")
+}
+
+// flagValue is a setting given on the command line
+type flagValue struct {
+	field field
+	value string
+}
+
+// Load layers the sources over the defaults, resolves Key Vault references
+// and validates the result. Every problem found is reported in one error.
+func (l *Loader) Load(ctx context.Context) (*Config, error) {
+	cfg := Defaults()
+	cfg.origins = make(map[string]origin)
+
+	flags, path, err := l.parseFlags()
+	if err != nil {
+		return nil, err
+	}
+
+	env, err := l.readDotEnv(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if path == "" {
+		path, _ = env("CONFIG_FILE")
+	}
+
+	var errs []error
+	if path != "" {
+		errs = append(errs, l.loadFile(cfg, path)...)
+	}
+	errs = append(errs, l.loadEnv(cfg, env)...)
+	for _, f := range flags {
+		if err := cfg.set(f.field, f.value, origin{source: SourceFlag}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// Secure cookies are required everywhere except plain-HTTP local development
+	if _, set := cfg.origins["SESSION_COOKIE_SECURE"]; !set {
+		cfg.SessionCookieSecure = cfg.Environment != "development"
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, resolveTimeout)
+	defer cancel()
+	errs = append(errs, l.resolveSecrets(ctx, cfg)...)
+	errs = append(errs, cfg.validate()...)
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return cfg, nil
+}
+
+// parseFlags parses Args, returning the settings given in order and the
+// config file named by -config
+func (l *Loader) parseFlags() ([]flagValue, string, error) {
+	flagSet := flag.NewFlagSet("alphapath", flag.ContinueOnError)
+	path := flagSet.String("config", "", "path to a YAML or TOML config file; overrides CONFIG_FILE")
+
+	var values []flagValue
+	for _, f := range fields {
+		record := func(value string) error {
+			values = append(values, flagValue{field: f, value: value})
+			return nil
+		}
+		usage := "sets " + f.key
+		if reflect.TypeOf(Config{}).Field(f.index).Type.Kind() == reflect.Bool {
+			flagSet.BoolFunc(f.flagName(), usage, record)
+		} else {
+			flagSet.Func(f.flagName(), usage, record)
+		}
+	}
+
+	if err := flagSet.Parse(l.Args); err != nil {
+		return nil, "", err
+	}
+	if flagSet.NArg() > 0 {
+		return nil, "", fmt.Errorf("unexpected argument %q", flagSet.Arg(0))
+	}
+	return values, *path, nil
+}
+
+// readDotEnv reads the .env file, returning a lookup that prefers the
+// environment
+func (l *Loader) readDotEnv(cfg *Config) (func(key string) (string, bool), error) {
+	if l.DotEnv == "" {
+		return l.LookupEnv, nil
+	}
+
+	data, err := l.readFile(cfg, l.DotEnv)
+	if errors.Is(err, fs.ErrNotExist) {
+		return l.LookupEnv, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", l.DotEnv, err)
+	}
+	dotenv, err := godotenv.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", l.DotEnv, err)
+	}
+
+	return func(key string) (string, bool) {
+		if value, ok := l.LookupEnv(key); ok && value != "" {
+			return value, true
+		}
+		value, ok := dotenv[key]
+		return value, ok
+	}, nil
+}
+
+// readFile reads a source file, recording it so a Watcher can reload when
+// it changes
+func (l *Loader) readFile(cfg *Config, name string) ([]byte, error) {
+	cfg.files = append(cfg.files, name)
+	return l.ReadFile(name)
+}
+
+// loadFile applies a YAML or TOML config file, chosen by its extension.
+// Keys are the settings' names in lower case; unknown keys are errors so
+// typos are not silently ignored.
+func (l *Loader) loadFile(cfg *Config, path string) []error {
+	data, err := l.readFile(cfg, path)
+	if err != nil {
+		return []error{fmt.Errorf("config file: %w", err)}
+	}
+
+	var values map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		_, err = toml.Decode(string(data), &values)
+	default:
+		return []error{fmt.Errorf("config file %s: unsupported format, expected .yaml, .yml or .toml", path)}
+	}
+	if err != nil {
+		return []error{fmt.Errorf("config file %s: %w", path, err)}
+	}
+
+	byKey := make(map[string]field, len(fields))
+	for _, f := range fields {
+		byKey[f.fileKey()] = f
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	for _, key := range keys {
+		f, ok := byKey[key]
+		if !ok {
+			errs = append(errs, fmt.Errorf("config file %s: unknown setting %q", path, key))
+			continue
+		}
+		if err := cfg.setFileValue(f, values[key]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// setFileValue applies a decoded config file value. Lists may be written as
+// arrays or comma-separated strings; a null leaves the setting unchanged.
+func (c *Config) setFileValue(f field, value interface{}) error {
+	o := origin{source: SourceFile}
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		return c.set(f, v, o)
+	case float64:
+		return c.set(f, strconv.FormatFloat(v, 'f', -1, 64), o)
+	case bool, int, int64, uint64:
+		return c.set(f, fmt.Sprint(v), o)
+	case []interface{}:
+		list, ok := c.field(f).Addr().Interface().(*[]string)
+		if !ok {
+			return fmt.Errorf("%s (%s): expected a single value, got a list", f.key, o.source)
+		}
+		items := make([]string, len(v))
+		for i, item := range v {
+			switch item.(type) {
+			case string, bool, int, int64, uint64, float64:
+				items[i] = fmt.Sprint(item)
+			default:
+				return fmt.Errorf("%s (%s): list items must be single values", f.key, o.source)
+			}
+		}
+		*list = items
+		c.origins[f.key] = o
+		return nil
+	default:
+		return fmt.Errorf("%s (%s): expected a single value or list", f.key, o.source)
+	}
+}
+
+// loadEnv applies environment variables. Empty variables are ignored, and
+// KEY_FILE reads the value of KEY from a file, such as a mounted secret.
+func (l *Loader) loadEnv(cfg *Config, env func(key string) (string, bool)) []error {
+	var errs []error
+	for _, f := range fields {
+		value, _ := env(f.key)
+		path, _ := env(f.key + "_FILE")
+
+		o := origin{source: SourceEnv}
+		switch {
+		case value != "" && path != "":
+			errs = append(errs, fmt.Errorf("%s: both %s and %s_FILE are set", f.key, f.key, f.key))
+			continue
+		case path != "":
+			data, err := l.readFile(cfg, path)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s_FILE: %w", f.key, err))
+				continue
+			}
+			value = strings.TrimRight(string(data), "\r\n")
+			o.secret = true
+		case value == "":
+			continue
+		}
+
+		if err := cfg.set(f, value, o); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// field returns the Config field holding f
+func (c *Config) field(f field) reflect.Value {
+	return reflect.ValueOf(c).Elem().Field(f.index)
+}
+
+// set parses value into the setting's type and records its origin. Secret
+// values are left out of parse errors.
+func (c *Config) set(f field, value string, o origin) error {
+	var err error
+	switch p := c.field(f).Addr().Interface().(type) {
+	case *string:
+		*p = value
+	case *bool:
+		*p, err = strconv.ParseBool(value)
+	case *int:
+		*p, err = strconv.Atoi(value)
+	case *int64:
+		*p, err = strconv.ParseInt(value, 10, 64)
+	case *float64:
+		*p, err = strconv.ParseFloat(value, 64)
+	case *time.Duration:
+		*p, err = time.ParseDuration(value)
+	case *[]string:
+		*p = splitList(value)
+	default:
+		err = fmt.Errorf("unsupported type %T", p)
+	}
+
+	if err != nil {
+		kind := strings.TrimPrefix(c.field(f).Type().String(), "time.")
+		if f.secret != "" || o.secret {
+			return fmt.Errorf("%s (%s): invalid %s", f.key, o.source, kind)
+		}
+		return fmt.Errorf("%s (%s): invalid %s %q", f.key, o.source, kind, value)
+	}
+	c.origins[f.key] = o
+	return nil
+}
+
+// splitList splits a comma-separated list, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,213 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/security/keyvault/azsecrets"
+)
+
+// keyVaultPrefix starts an App Service-style Key Vault reference
+const keyVaultPrefix = "@Microsoft.KeyVault("
+
+// ErrSecretNotFound is returned by resolvers when a referenced secret does
+// not exist
+var ErrSecretNotFound = errors.New("secret not found")
+
+// SecretRef identifies a Key Vault secret. Settings refer to one with
+// either of the forms App Service accepts:
+//
+//	@Microsoft.KeyVault(SecretUri=https://myvault.vault.azure.net/secrets/db-url/)
+//	@Microsoft.KeyVault(VaultName=myvault;SecretName=db-url;SecretVersion=abc123)
+//
+// An empty Version reads the latest version.
+type SecretRef struct {
+	VaultURL string
+	Name     string
+	Version  string
+}
+
+// String returns the secret's URI
+func (r SecretRef) String() string {
+	uri := r.VaultURL + "secrets/" + r.Name
+	if r.Version != "" {
+		uri += "/" + r.Version
+	}
+	return uri
+}
+
+// ParseSecretRef parses a Key Vault reference, reporting false if value is
+// not one
+func ParseSecretRef(value string) (SecretRef, bool, error) {
+	if !strings.HasPrefix(value, keyVaultPrefix) {
+		return SecretRef{}, false, nil
+	}
+	if !strings.HasSuffix(value, ")") {
+		return SecretRef{}, true, errors.New("Key Vault reference is missing its closing parenthesis")
+	}
+
+	params := make(map[string]string)
+	for _, param := range strings.Split(strings.TrimSuffix(strings.TrimPrefix(value, keyVaultPrefix), ")"), ";") {
+		name, val, ok := strings.Cut(param, "=")
+		if !ok {
+			return SecretRef{}, true, fmt.Errorf("Key Vault reference has malformed parameter %q", param)
+		}
+		params[strings.ToLower(strings.TrimSpace(name))] = strings.TrimSpace(val)
+	}
+
+	if uri := params["secreturi"]; uri != "" {
+		ref, err := parseSecretURI(uri)
+		return ref, true, err
+	}
+
+	vault, name := params["vaultname"], params["secretname"]
+	if vault == "" || name == "" {
+		return SecretRef{}, true, errors.New("Key Vault reference needs SecretUri, or VaultName and SecretName")
+	}
+	return SecretRef{
+		VaultURL: "https://" + vault + ".vault.azure.net/",
+		Name:     name,
+		Version:  params["secretversion"],
+	}, true, nil
+}
+
+// parseSecretURI parses https://<vault>/secrets/<name>[/<version>]
+func parseSecretURI(uri string) (SecretRef, error) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return SecretRef{}, fmt.Errorf("Key Vault SecretUri %q is not an https URL", uri)
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] != "secrets" || parts[1] == "" {
+		return SecretRef{}, fmt.Errorf("Key Vault SecretUri %q does not name a secret", uri)
+	}
+
+	ref := SecretRef{VaultURL: "https://" + u.Host + "/", Name: parts[1]}
+	if len(parts) == 3 {
+		ref.Version = parts[2]
+	}
+	return ref, nil
+}
+
+// SecretResolver reads referenced secrets
+type SecretResolver interface {
+	Resolve(ctx context.Context, ref SecretRef) (string, error)
+}
+
+// resolveSecrets replaces Key Vault references in string settings with the
+// secrets they name
+func (l *Loader) resolveSecrets(ctx context.Context, cfg *Config) []error {
+	var errs []error
+	for _, f := range fields {
+		value, ok := cfg.field(f).Addr().Interface().(*string)
+		if !ok {
+			continue
+		}
+
+		ref, isRef, err := ParseSecretRef(*value)
+		switch {
+		case !isRef:
+			continue
+		case err != nil:
+			errs = append(errs, fmt.Errorf("%s: %w", f.key, err))
+			continue
+		case l.Resolver == nil:
+			errs = append(errs, fmt.Errorf("%s: Key Vault references are not supported here", f.key))
+			continue
+		}
+
+		secret, err := l.Resolver.Resolve(ctx, ref)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: resolving %s: %w", f.key, ref, err))
+			continue
+		}
+		*value = secret
+
+		o := cfg.origins[f.key]
+		o.secret = true
+		cfg.origins[f.key] = o
+	}
+	return errs
+}
+
+// KeyVaultResolver reads secrets from Azure Key Vault. Its zero value
+// authenticates with DefaultAzureCredential (the managed identity in Azure,
+// the Azure CLI locally), created on first use so that configurations
+// without references need no Azure setup.
+type KeyVaultResolver struct {
+	// Credential replaces DefaultAzureCredential
+	Credential azcore.TokenCredential
+
+	mu      sync.Mutex
+	clients map[string]*azsecrets.Client
+}
+
+// Resolve reads the referenced secret
+func (r *KeyVaultResolver) Resolve(ctx context.Context, ref SecretRef) (string, error) {
+	client, err := r.client(ref.VaultURL)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := client.GetSecret(ctx, ref.Name, ref.Version, nil)
+	if err != nil {
+		var respErr *azcore.ResponseError
+		if errors.As(err, &respErr) && respErr.StatusCode == 404 {
+			return "", ErrSecretNotFound
+		}
+		return "", err
+	}
+	if resp.Value == nil {
+		return "", errors.New("secret has no value")
+	}
+	return *resp.Value, nil
+}
+
+// client returns the vault's client, creating it and the credential on
+// first use
+func (r *KeyVaultResolver) client(vaultURL string) (*azsecrets.Client, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if client, ok := r.clients[vaultURL]; ok {
+		return client, nil
+	}
+
+	if r.Credential == nil {
+		credential, err := azidentity.NewDefaultAzureCredential(nil)
+		if err != nil {
+			return nil, fmt.Errorf("creating Azure credential: %w", err)
+		}
+		r.Credential = credential
+	}
+
+	client, err := azsecrets.NewClient(vaultURL, r.Credential, nil)
+	if err != nil {
+		return nil, err
+	}
+	if r.clients == nil {
+		r.clients = make(map[string]*azsecrets.Client)
+	}
+	r.clients[vaultURL] = client
+	return client, nil
+}
+
+// FakeResolver resolves references from memory, keyed by secret URI
+// (SecretRef.String), for tests and local development
+type FakeResolver map[string]string
+
+// Resolve returns the secret stored under ref's URI
+func (f FakeResolver) Resolve(ctx context.Context, ref SecretRef) (string, error) {
+	secret, ok := f[ref.String()]
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+	return secret, nil
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"net/url"
+	"time"
+)
+
+// redacted replaces secret values in Settings
+const redacted = "[REDACTED]"
+
+// Setting is one setting's effective value and the layer it came from
+type Setting struct {
+	Key        string      `json:"key"`
+	Value      interface{} `json:"value"`
+	Source     Source      `json:"source"`
+	Redacted   bool        `json:"redacted,omitempty"`
+	Reloadable bool        `json:"reloadable,omitempty"`
+}
+
+// Settings lists every setting in declaration order for display. Secrets
+// are redacted: fields tagged secret, and values read from a *_FILE or Key
+// Vault. A secret tagged "url" keeps all but its password, so the database
+// host and name stay visible.
+func (c *Config) Settings() []Setting {
+	settings := make([]Setting, 0, len(fields))
+	for _, f := range fields {
+		o, ok := c.origins[f.key]
+		if !ok {
+			o.source = SourceDefault
+		}
+
+		setting := Setting{Key: f.key, Source: o.source, Reloadable: f.reload}
+		switch value := c.field(f).Interface().(type) {
+		case time.Duration:
+			setting.Value = value.String()
+		default:
+			setting.Value = value
+		}
+
+		if f.secret != "" || o.secret {
+			value, _ := setting.Value.(string)
+			switch {
+			case value == "" && !o.secret:
+			case f.secret == "url":
+				setting.Value, setting.Redacted = redactURL(value), true
+			default:
+				setting.Value, setting.Redacted = redacted, true
+			}
+		}
+		settings = append(settings, setting)
+	}
+	return settings
+}
+
+// redactURL masks the password in a URL's user info or password query
+// parameter as url.URL.Redacted does. Values that are not URLs, such as
+// key=value connection strings, are redacted entirely.
+func redactURL(value string) string {
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return redacted
+	}
+
+	if query := u.Query(); query.Has("password") {
+		query.Set("password", "xxxxx")
+		u.RawQuery = query.Encode()
+	}
+	return u.Redacted()
+}
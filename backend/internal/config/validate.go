@@ -0,0 +1,133 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Validate checks the configuration as a whole, reporting every invalid
+// setting in one error. Load validates what it returns.
+func (c *Config) Validate() error {
+	return errors.Join(c.validate()...)
+}
+
+func (c *Config) validate() []error {
+	var errs []error
+	check := func(ok bool, key, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+		}
+	}
+	oneOf := func(key, value string, allowed ...string) {
+		check(slices.Contains(allowed, strings.ToLower(value)), key, "%q is not one of %s", value, strings.Join(allowed, ", "))
+	}
+
+	check(c.DatabaseURL != "", "DATABASE_URL", "is required")
+	check(slices.Contains(Environments, c.Environment), "ENVIRONMENT", "%q is not one of %s", c.Environment, strings.Join(Environments, ", "))
+	oneOf("LOG_LEVEL", c.LogLevel, "debug", "info", "warn", "warning", "error")
+
+	check(validPort(c.Port), "PORT", "%q is not a port number", c.Port)
+	for _, p := range []struct{ key, port string }{{"METRICS_PORT", c.MetricsPort}, {"HL7_PORT", c.HL7Port}} {
+		if p.port != "" {
+			check(validPort(p.port), p.key, "%q is not a port number", p.port)
+			check(p.port != c.Port, p.key, "must differ from PORT")
+		}
+	}
+
+	check(validURL(c.FrontendURL), "FRONTEND_URL", "%q is not an http(s) URL", c.FrontendURL)
+	for _, origin := range c.CORSAllowedOrigins {
+		check(validOriginPattern(origin), "CORS_ALLOWED_ORIGINS", "%q is not an origin such as https://app.example.com or https://*.example.com", origin)
+	}
+	if c.OTelEndpoint != "" {
+		check(validURL(c.OTelEndpoint), "OTEL_EXPORTER_OTLP_ENDPOINT", "%q is not an http(s) URL", c.OTelEndpoint)
+	}
+	check(c.OTelServiceName != "", "OTEL_SERVICE_NAME", "is required")
+	check(c.OTelSampleRatio >= 0 && c.OTelSampleRatio <= 1, "OTEL_SAMPLE_RATIO", "must be between 0 and 1")
+
+	oneOf("SESSION_COOKIE_SAMESITE", c.SessionCookieSameSite, "lax", "strict", "none")
+	check(c.LoginMaxAttempts > 0, "LOGIN_MAX_ATTEMPTS", "must be positive")
+
+	for _, proxy := range c.TrustedProxies {
+		_, ok := parsePrefix(proxy)
+		check(ok, "TRUSTED_PROXIES", "%q is not an IP address or CIDR range", proxy)
+	}
+	oneOf("RATE_LIMIT_STORE", c.RateLimitStore, "memory", "postgres")
+	check(c.RateLimitRequests >= 0, "RATE_LIMIT_REQUESTS", "must not be negative")
+	check(c.RateLimitAuthRequests >= 0, "RATE_LIMIT_AUTH_REQUESTS", "must not be negative")
+	check(c.RateLimitAccountRequests >= 0, "RATE_LIMIT_ACCOUNT_REQUESTS", "must not be negative")
+
+	if c.OIDCDiscoveryURL != "" {
+		check(validURL(c.OIDCDiscoveryURL), "OIDC_DISCOVERY_URL", "%q is not an http(s) URL", c.OIDCDiscoveryURL)
+		check(c.OIDCClientID != "", "OIDC_CLIENT_ID", "is required when OIDC_DISCOVERY_URL is set")
+		check(validURL(c.OIDCRedirectURL), "OIDC_REDIRECT_URL", "%q is not an http(s) URL", c.OIDCRedirectURL)
+	}
+
+	oneOf("INFERENCE_CLIENT", c.InferenceClient, "fake", "http", "grpc")
+	if c.InferenceClient == "http" || c.InferenceClient == "grpc" {
+		check(c.InferenceEndpoint != "", "INFERENCE_ENDPOINT", "is required for the %s client", c.InferenceClient)
+	}
+	check(c.InferenceBreakerFailures >= 0, "INFERENCE_BREAKER_FAILURES", "must not be negative")
+	check(c.InferenceWorkers > 0, "INFERENCE_WORKERS", "must be positive")
+	check(c.InferenceMaxAttempts > 0, "INFERENCE_MAX_ATTEMPTS", "must be positive")
+	check(c.InferenceRetryMaxBackoff >= c.InferenceRetryBackoff, "INFERENCE_RETRY_MAX_BACKOFF", "must not be less than INFERENCE_RETRY_BACKOFF")
+
+	oneOf("BLOB_STORE", c.BlobStore, "local", "azure")
+	switch c.BlobStore {
+	case "local":
+		check(c.BlobLocalDir != "", "BLOB_LOCAL_DIR", "is required for the local blob store")
+	case "azure":
+		check(c.BlobAzureConnectionString != "", "BLOB_AZURE_CONNECTION_STRING", "is required for the azure blob store")
+		check(c.BlobAzureContainer != "", "BLOB_AZURE_CONTAINER", "is required for the azure blob store")
+	}
+	check(c.UploadMaxSize > 0, "UPLOAD_MAX_SIZE", "must be positive")
+	check(c.TileCacheMemory >= 0, "TILE_CACHE_MEMORY", "must not be negative")
+	check(c.TileCacheDiskSize >= 0, "TILE_CACHE_DISK_SIZE", "must not be negative")
+
+	check(c.HL7MaxAttempts > 0, "HL7_MAX_ATTEMPTS", "must be positive")
+	check(c.HL7RetryMaxBackoff >= c.HL7RetryBackoff, "HL7_RETRY_MAX_BACKOFF", "must not be less than HL7_RETRY_BACKOFF")
+
+	// Every duration is a timeout, interval or backoff
+	for _, f := range fields {
+		if d, ok := c.field(f).Interface().(time.Duration); ok {
+			check(d > 0, f.key, "must be positive")
+		}
+	}
+
+	return errs
+}
+
+// validPort reports whether port is a TCP port number
+func validPort(port string) bool {
+	n, err := strconv.Atoi(port)
+	return err == nil && n > 0 && n <= 65535
+}
+
+// validURL reports whether value is an absolute http or https URL
+func validURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// validOriginPattern reports whether value is an http(s) origin, without a
+// path, whose host may start with "*." and whose port may be "*". It
+// mirrors the patterns the CORS middleware accepts.
+func validOriginPattern(value string) bool {
+	scheme, rest, ok := strings.Cut(strings.TrimSuffix(value, "/"), "://")
+	if !ok || (scheme != "http" && scheme != "https") || rest == "" || strings.ContainsAny(rest, "/?#@") {
+		return false
+	}
+	host := rest
+	if i := strings.LastIndex(rest, ":"); i >= 0 && !strings.HasSuffix(rest, "]") {
+		host = rest[:i]
+		if port := rest[i+1:]; port != "*" && !validPort(port) {
+			return false
+		}
+	}
+	host = strings.TrimPrefix(host, "*.")
+	return host != "" && !strings.Contains(host, "*")
+}
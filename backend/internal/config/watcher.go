@@ -0,0 +1,186 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// Watcher holds the running configuration and reloads it on request, on
+// SIGHUP, or when a file it was read from changes. Each reload is validated
+// as a whole and swapped in atomically; a failed reload keeps the current
+// configuration. Only settings tagged reload change: the rest were used to
+// build long-lived components at startup, so changes to them are logged and
+// held back until a restart.
+type Watcher struct {
+	loader  *Loader
+	logger  *slog.Logger
+	current atomic.Pointer[Config]
+
+	// mu serializes reloads and guards the fields below
+	mu          sync.Mutex
+	subscribers []func(old, new *Config)
+	stamps      map[string]fileStamp
+}
+
+// fileStamp identifies a version of a source file
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+	exists  bool
+}
+
+// NewWatcher creates a watcher serving cfg, which loader reloads
+func NewWatcher(loader *Loader, cfg *Config, logger *slog.Logger) *Watcher {
+	w := &Watcher{loader: loader, logger: logger, stamps: stampFiles(cfg.files)}
+	w.current.Store(cfg)
+	return w
+}
+
+// Current returns the configuration in effect. Callers should not keep it
+// across requests, so they see reloads.
+func (w *Watcher) Current() *Config {
+	return w.current.Load()
+}
+
+// Subscribe registers fn to be called after each reload that changes a
+// setting, with the previous and new configuration. Subscribers are called
+// one at a time in registration order.
+func (w *Watcher) Subscribe(fn func(old, new *Config)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subscribers = append(w.subscribers, fn)
+}
+
+// Run reloads on SIGHUP and when a source file changes, checking every
+// interval, until ctx is done
+func (w *Watcher) Run(ctx context.Context, interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			w.logger.Info("Reloading configuration", "trigger", "SIGHUP")
+			w.Reload(ctx)
+		case <-ticker.C:
+			if w.filesChanged() {
+				w.logger.Info("Reloading configuration", "trigger", "file change")
+				w.Reload(ctx)
+			}
+		}
+	}
+}
+
+// Reload loads and applies the configuration, logging each change. Errors
+// are logged as well as returned, and leave the current configuration in
+// place.
+func (w *Watcher) Reload(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	next, err := w.loader.Load(ctx)
+	if err != nil {
+		w.logger.Error("Configuration reload failed; keeping the current configuration", "error", err)
+		return err
+	}
+	w.stamps = stampFiles(next.files)
+
+	current := w.current.Load()
+	var applied, held []string
+	for _, f := range fields {
+		if reflect.DeepEqual(current.field(f).Interface(), next.field(f).Interface()) {
+			continue
+		}
+		if f.reload {
+			applied = append(applied, f.key)
+			continue
+		}
+
+		held = append(held, f.key)
+		next.field(f).Set(current.field(f))
+		if o, ok := current.origins[f.key]; ok {
+			next.origins[f.key] = o
+		} else {
+			delete(next.origins, f.key)
+		}
+	}
+
+	// Holding settings back can combine values that were never checked together
+	if err := next.Validate(); err != nil {
+		w.logger.Error("Configuration reload conflicts with settings awaiting a restart; keeping the current configuration",
+			"held", held, "error", err)
+		return err
+	}
+
+	for _, key := range held {
+		w.logger.Warn("Configuration setting changed; restart to apply", "key", key)
+	}
+	if len(applied) == 0 {
+		return nil
+	}
+
+	before, after := settingsByKey(current), settingsByKey(next)
+	for _, key := range applied {
+		w.logger.Info("Configuration setting changed", "key", key, "old", before[key].Value, "new", after[key].Value)
+	}
+
+	w.current.Store(next)
+	for _, fn := range w.subscribers {
+		fn(current, next)
+	}
+	return nil
+}
+
+// filesChanged reports whether a source file has changed since the last load
+func (w *Watcher) filesChanged() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for name, stamp := range w.stamps {
+		if stampFile(name) != stamp {
+			return true
+		}
+	}
+	return false
+}
+
+// stampFiles records the current version of each file
+func stampFiles(names []string) map[string]fileStamp {
+	stamps := make(map[string]fileStamp, len(names))
+	for _, name := range names {
+		stamps[name] = stampFile(name)
+	}
+	return stamps
+}
+
+// stampFile records a file's modification time and size. Symbolic links are
+// followed, so swapping a mounted ConfigMap or secret counts as a change.
+func stampFile(name string) fileStamp {
+	info, err := os.Stat(name)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size(), exists: true}
+}
+
+// settingsByKey indexes a configuration's redacted settings
+func settingsByKey(c *Config) map[string]Setting {
+	settings := make(map[string]Setting, len(fields))
+	for _, setting := range c.Settings() {
+		settings[setting.Key] = setting
+	}
+	return settings
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatcher_Reload(t *testing.T) {
+	files := map[string]string{"app.yaml": "log_level: info\nport: 8080\n"}
+	env := map[string]string{"CONFIG_FILE": "app.yaml", "DATABASE_URL": "postgres://localhost/app"}
+	loader := newTestLoader(env, files)
+
+	cfg, err := loader.Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	var logs bytes.Buffer
+	watcher := NewWatcher(loader, cfg, slog.New(slog.NewJSONHandler(&logs, nil)))
+
+	var notified []string
+	watcher.Subscribe(func(old, new *Config) {
+		notified = append(notified, old.LogLevel+"->"+new.LogLevel)
+	})
+
+	files["app.yaml"] = "log_level: debug\nport: 9090\nfeatures: [new_viewer]\n"
+	if err := watcher.Reload(context.Background()); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	current := watcher.Current()
+	if current.LogLevel != "debug" || !current.FeatureEnabled("NEW_VIEWER") {
+		t.Errorf("Expected reloadable settings to change, got level %s, features %v", current.LogLevel, current.Features)
+	}
+	if current.Port != "8080" || settingOf(current, "PORT").Source != SourceFile {
+		t.Errorf("Expected PORT to be held until a restart, got %s", current.Port)
+	}
+	if cfg.LogLevel != "info" {
+		t.Error("Expected the previous snapshot to be left unchanged")
+	}
+	if len(notified) != 1 || notified[0] != "info->debug" {
+		t.Errorf("Expected subscribers to be notified once, got %v", notified)
+	}
+
+	for _, expected := range []string{
+		`"msg":"Configuration setting changed","key":"LOG_LEVEL","old":"info","new":"debug"`,
+		`"msg":"Configuration setting changed; restart to apply","key":"PORT"`,
+	} {
+		if !strings.Contains(logs.String(), expected) {
+			t.Errorf("Expected the logs to include %s, got:\n%s", expected, logs.String())
+		}
+	}
+
+	// An unchanged reload notifies nobody
+	if err := watcher.Reload(context.Background()); err != nil || len(notified) != 1 {
+		t.Errorf("Expected a no-op reload, got %v and %d notifications", err, len(notified))
+	}
+}
+
+func TestWatcher_InvalidReload(t *testing.T) {
+	files := map[string]string{"app.yaml": "log_level: info\n"}
+	env := map[string]string{"CONFIG_FILE": "app.yaml", "DATABASE_URL": "postgres://localhost/app"}
+	loader := newTestLoader(env, files)
+
+	cfg, err := loader.Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	var logs bytes.Buffer
+	watcher := NewWatcher(loader, cfg, slog.New(slog.NewJSONHandler(&logs, nil)))
+	watcher.Subscribe(func(old, new *Config) {
+		t.Error("Expected no notification for a rejected reload")
+	})
+
+	files["app.yaml"] = "log_level: loud\n"
+	if err := watcher.Reload(context.Background()); err == nil {
+		t.Error("Expected an invalid configuration to be rejected")
+	}
+	if watcher.Current() != cfg {
+		t.Error("Expected the current configuration to be kept")
+	}
+	if !strings.Contains(logs.String(), "Configuration reload failed") {
+		t.Errorf("Expected the failure to be logged, got:\n%s", logs.String())
+	}
+}
+
+func TestWatcher_FileChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.yaml")
+	if err := os.WriteFile(path, []byte("log_level: info\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := newTestLoader(map[string]string{"CONFIG_FILE": path, "DATABASE_URL": "postgres://localhost/app"}, nil)
+	loader.ReadFile = os.ReadFile
+
+	cfg, err := loader.Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	watcher := NewWatcher(loader, cfg, slog.New(slog.NewJSONHandler(&bytes.Buffer{}, nil)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watcher.Run(ctx, 10*time.Millisecond)
+
+	if err := os.WriteFile(path, []byte("log_level: error\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for watcher.Current().LogLevel != "error" {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the file change to be picked up")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -4,22 +4,27 @@ import (
 	"database/sql"
 	"fmt"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
+
+	"backend/internal/telemetry"
 )
 
-// Connect establishes a connection to the PostgreSQL database
-func Connect(databaseURL string) (*sql.DB, error) {
+// Connect establishes a connection to the PostgreSQL database. Every query
+// is traced, and the pool's statistics reported, through t.
+func Connect(databaseURL string, t *telemetry.Telemetry) (*sql.DB, error) {
 	if databaseURL == "" {
 		return nil, fmt.Errorf("database URL is required")
 	}
 
-	db, err := sql.Open("postgres", databaseURL)
+	connector, err := pq.NewConnector(databaseURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
+	db := sql.OpenDB(&tracedConnector{Connector: connector, tracer: t.Tracer()})
 
 	// Test the connection
 	if err := db.Ping(); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
@@ -27,25 +32,10 @@ func Connect(databaseURL string) (*sql.DB, error) {
 	db.SetMaxOpenConns(25)
 	db.SetMaxIdleConns(5)
 
-	return db, nil
-}
-
-// Migrate runs database migrations (placeholder for now)
-func Migrate(db *sql.DB) error {
-	// Create users table if it doesn't exist
-	query := `
-	CREATE TABLE IF NOT EXISTS users (
-		id SERIAL PRIMARY KEY,
-		name VARCHAR(255) NOT NULL,
-		email VARCHAR(255) UNIQUE NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	)`
-
-	_, err := db.Exec(query)
-	if err != nil {
-		return fmt.Errorf("failed to create users table: %w", err)
+	if err := observePool(db, t.Meter()); err != nil {
+		db.Close()
+		return nil, err
 	}
 
-	return nil
+	return db, nil
 }
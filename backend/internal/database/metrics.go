@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.32.0"
+)
+
+// closeReasonKey distinguishes why the pool closed connections
+var closeReasonKey = attribute.Key("db.client.connection.close_reason")
+
+// observePool reports the connection pool's statistics each time metrics
+// are collected
+func observePool(db *sql.DB, meter metric.Meter) error {
+	count, err := meter.Int64ObservableUpDownCounter("db.client.connection.count",
+		metric.WithDescription("Connections in the pool, by state"),
+		metric.WithUnit("{connection}"),
+	)
+	if err != nil {
+		return err
+	}
+	maxOpen, err := meter.Int64ObservableUpDownCounter("db.client.connection.max",
+		metric.WithDescription("Maximum open connections allowed"),
+		metric.WithUnit("{connection}"),
+	)
+	if err != nil {
+		return err
+	}
+	waits, err := meter.Int64ObservableCounter("db.client.connection.waits",
+		metric.WithDescription("Times a query waited for a free connection"),
+		metric.WithUnit("{wait}"),
+	)
+	if err != nil {
+		return err
+	}
+	waitTime, err := meter.Float64ObservableCounter("db.client.connection.wait_duration",
+		metric.WithDescription("Total time spent waiting for a free connection"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return err
+	}
+	closed, err := meter.Int64ObservableCounter("db.client.connection.closed",
+		metric.WithDescription("Connections closed by the pool's idle and lifetime limits"),
+		metric.WithUnit("{connection}"),
+	)
+	if err != nil {
+		return err
+	}
+
+	idle := metric.WithAttributes(semconv.DBClientConnectionStateIdle)
+	used := metric.WithAttributes(semconv.DBClientConnectionStateUsed)
+
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		stats := db.Stats()
+		o.ObserveInt64(count, int64(stats.Idle), idle)
+		o.ObserveInt64(count, int64(stats.InUse), used)
+		o.ObserveInt64(maxOpen, int64(stats.MaxOpenConnections))
+		o.ObserveInt64(waits, stats.WaitCount)
+		o.ObserveFloat64(waitTime, stats.WaitDuration.Seconds())
+		o.ObserveInt64(closed, stats.MaxIdleClosed, metric.WithAttributes(closeReasonKey.String("max_idle")))
+		o.ObserveInt64(closed, stats.MaxIdleTimeClosed, metric.WithAttributes(closeReasonKey.String("max_idle_time")))
+		o.ObserveInt64(closed, stats.MaxLifetimeClosed, metric.WithAttributes(closeReasonKey.String("max_lifetime")))
+		return nil
+	}, count, maxOpen, waits, waitTime, closed)
+	if err != nil {
+		return fmt.Errorf("failed to observe connection pool: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package database
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestLoadMigrations_Ordered(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/0002_add_roles.up.sql":      {Data: []byte("CREATE TABLE roles ();")},
+		"m/0002_add_roles.down.sql":    {Data: []byte("DROP TABLE roles;")},
+		"m/0001_create_users.up.sql":   {Data: []byte("CREATE TABLE users ();")},
+		"m/0001_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+	}
+
+	migrations, err := LoadMigrations(fsys, "m")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(migrations) != 2 {
+		t.Fatalf("Expected 2 migrations, got %d", len(migrations))
+	}
+
+	if migrations[0].Version != 1 || migrations[0].Name != "create_users" {
+		t.Errorf("Expected first migration to be 1 create_users, got %d %s", migrations[0].Version, migrations[0].Name)
+	}
+
+	if migrations[1].Down != "DROP TABLE roles;" {
+		t.Errorf("Expected down SQL to be loaded, got %q", migrations[1].Down)
+	}
+
+	if len(migrations[0].Checksum) != 64 {
+		t.Errorf("Expected hex SHA-256 checksum, got %q", migrations[0].Checksum)
+	}
+}
+
+func TestLoadMigrations_MissingDown(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/0001_create_users.up.sql": {Data: []byte("CREATE TABLE users ();")},
+	}
+
+	_, err := LoadMigrations(fsys, "m")
+	if err == nil || !strings.Contains(err.Error(), "no down file") {
+		t.Errorf("Expected missing down file error, got %v", err)
+	}
+}
+
+func TestLoadMigrations_InvalidName(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/create_users.sql": {Data: []byte("CREATE TABLE users ();")},
+	}
+
+	if _, err := LoadMigrations(fsys, "m"); err == nil {
+		t.Error("Expected error for invalid file name")
+	}
+}
+
+func TestLoadMigrations_Embedded(t *testing.T) {
+	migrations, err := LoadMigrations(embeddedMigrations, "migrations")
+	if err != nil {
+		t.Fatalf("Expected embedded migrations to load, got %v", err)
+	}
+
+	if len(migrations) == 0 || migrations[0].Name != "create_users" {
+		t.Error("Expected embedded migrations to start with create_users")
+	}
+}
+
+func TestVerifyChecksums(t *testing.T) {
+	migrations := []Migration{{Version: 1, Name: "create_users", Checksum: "abc"}}
+
+	if err := verifyChecksums(migrations, map[int64]appliedMigration{1: {Version: 1, Checksum: "abc"}}); err != nil {
+		t.Errorf("Expected matching checksum to pass, got %v", err)
+	}
+
+	if err := verifyChecksums(migrations, map[int64]appliedMigration{1: {Version: 1, Checksum: "def"}}); err == nil {
+		t.Error("Expected checksum mismatch error")
+	}
+
+	// Versions applied by a newer build are tolerated
+	if err := verifyChecksums(migrations, map[int64]appliedMigration{2: {Version: 2, Checksum: "xyz"}}); err != nil {
+		t.Errorf("Expected unknown applied version to be tolerated, got %v", err)
+	}
+}
+
+func TestBuildStatus(t *testing.T) {
+	migrations := []Migration{
+		{Version: 1, Name: "create_users"},
+		{Version: 2, Name: "add_roles"},
+	}
+	applied := map[int64]appliedMigration{
+		1: {Version: 1, Name: "create_users", AppliedAt: time.Now()},
+		3: {Version: 3, Name: "from_newer_build", AppliedAt: time.Now()},
+	}
+
+	statuses := buildStatus(migrations, applied)
+	if len(statuses) != 3 {
+		t.Fatalf("Expected 3 statuses, got %d", len(statuses))
+	}
+
+	if !statuses[0].Applied || statuses[1].Applied {
+		t.Error("Expected only version 1 of the known migrations to be applied")
+	}
+
+	if !statuses[2].Missing {
+		t.Error("Expected version 3 to be reported as missing locally")
+	}
+}
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS sessions;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS locked_until,
+    DROP COLUMN IF EXISTS failed_login_attempts,
+    DROP COLUMN IF EXISTS password_hash;
@@ -0,0 +1,4 @@
+DELETE FROM role_permissions WHERE permission IN ('tests:read', 'tests:write', 'tests:delete');
+
+DROP TABLE IF EXISTS test_orders;
+DROP SEQUENCE IF EXISTS accession_number_seq;
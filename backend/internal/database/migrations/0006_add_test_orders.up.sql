@@ -0,0 +1,44 @@
+CREATE SEQUENCE accession_number_seq;
+
+CREATE TABLE test_orders (
+    id SERIAL PRIMARY KEY,
+    accession_number VARCHAR(32) UNIQUE NOT NULL
+        DEFAULT 'AP' || to_char(CURRENT_DATE, 'YY') || '-' || lpad(nextval('accession_number_seq')::text, 6, '0'),
+    patient_mrn VARCHAR(64) NOT NULL,
+    ordering_clinician_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    test_type VARCHAR(64) NOT NULL,
+    priority VARCHAR(16) NOT NULL DEFAULT 'routine',
+    status VARCHAR(16) NOT NULL DEFAULT 'ordered',
+    specimen_id VARCHAR(64) NOT NULL DEFAULT '',
+    notes TEXT NOT NULL DEFAULT '',
+    cancel_reason TEXT NOT NULL DEFAULT '',
+    ordered_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    collected_at TIMESTAMP,
+    received_at TIMESTAMP,
+    analysis_started_at TIMESTAMP,
+    resulted_at TIMESTAMP,
+    verified_at TIMESTAMP,
+    cancelled_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT test_orders_priority_check CHECK (priority IN ('routine', 'urgent', 'stat')),
+    CONSTRAINT test_orders_status_check CHECK (status IN
+        ('ordered', 'collected', 'received', 'in_analysis', 'resulted', 'verified', 'cancelled'))
+);
+
+CREATE INDEX test_orders_patient_mrn_idx ON test_orders (patient_mrn);
+CREATE INDEX test_orders_status_idx ON test_orders (status);
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT r.id, p.permission
+FROM roles r
+JOIN (VALUES
+    ('admin', 'tests:read'),
+    ('admin', 'tests:write'),
+    ('admin', 'tests:delete'),
+    ('pathologist', 'tests:read'),
+    ('pathologist', 'tests:write'),
+    ('lab_technician', 'tests:read'),
+    ('lab_technician', 'tests:write'),
+    ('viewer', 'tests:read')
+) AS p(role, permission) ON p.role = r.name;
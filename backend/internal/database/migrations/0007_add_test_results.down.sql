@@ -0,0 +1,4 @@
+DELETE FROM role_permissions WHERE permission IN ('results:read', 'results:write', 'results:verify');
+
+DROP TABLE IF EXISTS test_results;
+DROP FUNCTION IF EXISTS test_results_protect_verified();
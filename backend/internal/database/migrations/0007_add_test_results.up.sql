@@ -0,0 +1,49 @@
+CREATE TABLE test_results (
+    id SERIAL PRIMARY KEY,
+    test_order_id INTEGER NOT NULL REFERENCES test_orders(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    findings JSONB NOT NULL DEFAULT '[]',
+    interpretation TEXT NOT NULL DEFAULT '',
+    reporting_pathologist_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    model_output JSONB,
+    amendment_reason TEXT NOT NULL DEFAULT '',
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    verified_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    verified_at TIMESTAMP,
+    CONSTRAINT test_results_version_unique UNIQUE (test_order_id, version),
+    CONSTRAINT test_results_amendment_reason_check CHECK (version = 1 OR amendment_reason <> '')
+);
+
+-- Verified results are part of the medical record: corrections are recorded
+-- as new versions, never by changing or removing a verified row.
+CREATE FUNCTION test_results_protect_verified() RETURNS trigger AS $$
+BEGIN
+    IF OLD.verified_at IS NOT NULL THEN
+        RAISE EXCEPTION 'verified test result % is immutable', OLD.id;
+    END IF;
+    IF TG_OP = 'DELETE' THEN
+        RETURN OLD;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER test_results_protect_verified
+    BEFORE UPDATE OR DELETE ON test_results
+    FOR EACH ROW EXECUTE FUNCTION test_results_protect_verified();
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT r.id, p.permission
+FROM roles r
+JOIN (VALUES
+    ('admin', 'results:read'),
+    ('admin', 'results:write'),
+    ('admin', 'results:verify'),
+    ('pathologist', 'results:read'),
+    ('pathologist', 'results:write'),
+    ('pathologist', 'results:verify'),
+    ('lab_technician', 'results:read'),
+    ('viewer', 'results:read')
+) AS p(role, permission) ON p.role = r.name;
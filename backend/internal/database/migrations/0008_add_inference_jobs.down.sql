@@ -0,0 +1,3 @@
+DELETE FROM role_permissions WHERE permission IN ('inference:read', 'inference:run');
+
+DROP TABLE IF EXISTS inference_jobs;
@@ -0,0 +1,40 @@
+CREATE TABLE inference_jobs (
+    id SERIAL PRIMARY KEY,
+    test_order_id INTEGER REFERENCES test_orders(id) ON DELETE SET NULL,
+    model VARCHAR(128) NOT NULL,
+    model_version VARCHAR(64) NOT NULL DEFAULT '',
+    input JSONB NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'queued',
+    output JSONB,
+    error TEXT NOT NULL DEFAULT '',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL DEFAULT 3,
+    run_after TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    locked_by VARCHAR(128) NOT NULL DEFAULT '',
+    lease_expires_at TIMESTAMP,
+    latency_ms INTEGER,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMP,
+    finished_at TIMESTAMP,
+    CONSTRAINT inference_jobs_status_check CHECK (status IN ('queued', 'running', 'succeeded', 'failed'))
+);
+
+-- Workers poll for runnable jobs; finished jobs are excluded from the index
+CREATE INDEX inference_jobs_runnable_idx ON inference_jobs (run_after, id)
+    WHERE status IN ('queued', 'running');
+CREATE INDEX inference_jobs_test_order_idx ON inference_jobs (test_order_id);
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT r.id, p.permission
+FROM roles r
+JOIN (VALUES
+    ('admin', 'inference:read'),
+    ('admin', 'inference:run'),
+    ('pathologist', 'inference:read'),
+    ('pathologist', 'inference:run'),
+    ('lab_technician', 'inference:read'),
+    ('lab_technician', 'inference:run'),
+    ('viewer', 'inference:read')
+) AS p(role, permission) ON p.role = r.name;
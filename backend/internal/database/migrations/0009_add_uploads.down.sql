@@ -0,0 +1,3 @@
+DELETE FROM role_permissions WHERE permission IN ('slides:read', 'slides:write');
+
+DROP TABLE IF EXISTS uploads;
@@ -0,0 +1,36 @@
+CREATE TABLE uploads (
+    id SERIAL PRIMARY KEY,
+    accession_number VARCHAR(64) NOT NULL,
+    filename VARCHAR(255) NOT NULL DEFAULT '',
+    content_type VARCHAR(128) NOT NULL DEFAULT 'application/octet-stream',
+    size BIGINT NOT NULL,
+    upload_offset BIGINT NOT NULL DEFAULT 0,
+    part_keys TEXT[] NOT NULL DEFAULT '{}',
+    expected_sha256 VARCHAR(64) NOT NULL DEFAULT '',
+    sha256 VARCHAR(64) NOT NULL DEFAULT '',
+    storage_key VARCHAR(255) NOT NULL DEFAULT '',
+    status VARCHAR(16) NOT NULL DEFAULT 'uploading',
+    error TEXT NOT NULL DEFAULT '',
+    owner_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP,
+    CONSTRAINT uploads_status_check CHECK (status IN ('uploading', 'complete', 'failed')),
+    CONSTRAINT uploads_offset_check CHECK (upload_offset >= 0 AND upload_offset <= size)
+);
+
+CREATE INDEX uploads_accession_number_idx ON uploads (accession_number);
+CREATE INDEX uploads_owner_id_idx ON uploads (owner_id);
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT r.id, p.permission
+FROM roles r
+JOIN (VALUES
+    ('admin', 'slides:read'),
+    ('admin', 'slides:write'),
+    ('pathologist', 'slides:read'),
+    ('pathologist', 'slides:write'),
+    ('lab_technician', 'slides:read'),
+    ('lab_technician', 'slides:write'),
+    ('viewer', 'slides:read')
+) AS p(role, permission) ON p.role = r.name;
@@ -0,0 +1,3 @@
+DELETE FROM role_permissions WHERE permission IN ('slides:read_any', 'slides:share');
+
+DROP TABLE IF EXISTS slide_grants;
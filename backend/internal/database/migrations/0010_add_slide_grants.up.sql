@@ -0,0 +1,19 @@
+CREATE TABLE slide_grants (
+    upload_id INTEGER NOT NULL REFERENCES uploads(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    granted_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (upload_id, user_id)
+);
+
+CREATE INDEX slide_grants_user_id_idx ON slide_grants (user_id);
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT r.id, p.permission
+FROM roles r
+JOIN (VALUES
+    ('admin', 'slides:read_any'),
+    ('admin', 'slides:share'),
+    ('pathologist', 'slides:read_any'),
+    ('pathologist', 'slides:share')
+) AS p(role, permission) ON p.role = r.name;
@@ -0,0 +1,8 @@
+DELETE FROM role_permissions WHERE permission IN ('patients:read', 'patients:write', 'patients:merge');
+
+ALTER TABLE test_orders DROP COLUMN IF EXISTS patient_id;
+
+DROP TABLE IF EXISTS patient_merges;
+DROP TABLE IF EXISTS patient_identifiers;
+DROP TABLE IF EXISTS patients;
+DROP SEQUENCE IF EXISTS patient_mrn_seq;
@@ -0,0 +1,70 @@
+CREATE SEQUENCE patient_mrn_seq;
+
+CREATE TABLE patients (
+    id SERIAL PRIMARY KEY,
+    mrn VARCHAR(64) UNIQUE NOT NULL,
+    family_name VARCHAR(255) NOT NULL DEFAULT '',
+    given_name VARCHAR(255) NOT NULL DEFAULT '',
+    birth_date DATE,
+    sex VARCHAR(16) NOT NULL DEFAULT 'unknown',
+    phone VARCHAR(64) NOT NULL DEFAULT '',
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    address TEXT NOT NULL DEFAULT '',
+    merged_into_id INTEGER REFERENCES patients(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT patients_sex_check CHECK (sex IN ('female', 'male', 'other', 'unknown')),
+    CONSTRAINT patients_not_merged_into_self CHECK (merged_into_id <> id)
+);
+
+CREATE INDEX patients_birth_date_idx ON patients (birth_date);
+CREATE INDEX patients_name_idx ON patients (lower(family_name), lower(given_name));
+
+-- Identifiers assigned by other systems (hospital MRNs, national IDs, ...)
+CREATE TABLE patient_identifiers (
+    id SERIAL PRIMARY KEY,
+    patient_id INTEGER NOT NULL REFERENCES patients(id) ON DELETE CASCADE,
+    system VARCHAR(255) NOT NULL,
+    value VARCHAR(255) NOT NULL,
+    CONSTRAINT patient_identifiers_system_value_key UNIQUE (system, value)
+);
+
+CREATE INDEX patient_identifiers_patient_id_idx ON patient_identifiers (patient_id);
+
+-- Each merge lists the rows it re-pointed so it can be reversed exactly
+CREATE TABLE patient_merges (
+    id SERIAL PRIMARY KEY,
+    survivor_id INTEGER NOT NULL REFERENCES patients(id),
+    merged_id INTEGER NOT NULL REFERENCES patients(id),
+    reason TEXT NOT NULL DEFAULT '',
+    moved JSONB NOT NULL,
+    merged_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    merged_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    reversed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    reversed_at TIMESTAMP
+);
+
+CREATE INDEX patient_merges_survivor_id_idx ON patient_merges (survivor_id);
+CREATE INDEX patient_merges_merged_id_idx ON patient_merges (merged_id);
+
+-- Link test orders to the registry, registering the patients already ordered for
+ALTER TABLE test_orders ADD COLUMN patient_id INTEGER REFERENCES patients(id);
+CREATE INDEX test_orders_patient_id_idx ON test_orders (patient_id);
+
+INSERT INTO patients (mrn)
+SELECT DISTINCT patient_mrn FROM test_orders;
+
+UPDATE test_orders o SET patient_id = p.id
+FROM patients p WHERE p.mrn = o.patient_mrn;
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT r.id, p.permission
+FROM roles r
+JOIN (VALUES
+    ('admin', 'patients:read'),
+    ('admin', 'patients:write'),
+    ('admin', 'patients:merge'),
+    ('pathologist', 'patients:read'),
+    ('lab_technician', 'patients:read'),
+    ('lab_technician', 'patients:write')
+) AS p(role, permission) ON p.role = r.name;
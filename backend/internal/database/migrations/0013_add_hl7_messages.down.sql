@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS hl7_messages;
+
+DROP INDEX IF EXISTS test_orders_placer_idx;
+ALTER TABLE test_orders DROP COLUMN IF EXISTS placer_order_number;
+ALTER TABLE test_orders DROP COLUMN IF EXISTS placer_system;
@@ -0,0 +1,35 @@
+-- Orders received over HL7 keep the placer's order number so results can be
+-- reported against it and resent orders are not duplicated
+ALTER TABLE test_orders ADD COLUMN placer_system VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE test_orders ADD COLUMN placer_order_number VARCHAR(64) NOT NULL DEFAULT '';
+CREATE UNIQUE INDEX test_orders_placer_idx ON test_orders (placer_system, placer_order_number)
+    WHERE placer_order_number <> '';
+
+-- Every HL7 message received or sent. Outbound messages wait here as
+-- pending until the receiver acknowledges them.
+CREATE TABLE hl7_messages (
+    id BIGSERIAL PRIMARY KEY,
+    direction VARCHAR(16) NOT NULL,
+    message_type VARCHAR(32) NOT NULL,
+    control_id VARCHAR(64) NOT NULL,
+    sending_application VARCHAR(255) NOT NULL DEFAULT '',
+    sending_facility VARCHAR(255) NOT NULL DEFAULT '',
+    body TEXT NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    ack_code VARCHAR(2) NOT NULL DEFAULT '',
+    ack_body TEXT NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT '',
+    test_order_id INTEGER REFERENCES test_orders(id) ON DELETE SET NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    processed_at TIMESTAMP,
+    CONSTRAINT hl7_messages_direction_check CHECK (direction IN ('inbound', 'outbound')),
+    CONSTRAINT hl7_messages_status_check CHECK (status IN ('received', 'pending', 'accepted', 'rejected', 'failed'))
+);
+
+-- A resent inbound message is recognized by its sender and control ID
+CREATE UNIQUE INDEX hl7_messages_inbound_idx ON hl7_messages (sending_application, sending_facility, control_id)
+    WHERE direction = 'inbound';
+CREATE INDEX hl7_messages_pending_idx ON hl7_messages (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX hl7_messages_test_order_id_idx ON hl7_messages (test_order_id);
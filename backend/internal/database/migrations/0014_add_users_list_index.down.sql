@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS users_created_at_idx;
+
+ALTER TABLE users ALTER COLUMN created_at DROP NOT NULL;
@@ -0,0 +1,5 @@
+-- Lists are paged by (created_at, id), which needs created_at to be set
+UPDATE users SET created_at = CURRENT_TIMESTAMP WHERE created_at IS NULL;
+ALTER TABLE users ALTER COLUMN created_at SET NOT NULL;
+
+CREATE INDEX users_created_at_idx ON users (created_at, id);
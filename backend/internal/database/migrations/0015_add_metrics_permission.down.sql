@@ -0,0 +1,2 @@
+DELETE FROM role_permissions WHERE permission = 'metrics:read';
+DELETE FROM roles WHERE name = 'monitoring';
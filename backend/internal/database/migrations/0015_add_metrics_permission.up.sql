@@ -0,0 +1,10 @@
+INSERT INTO roles (name, description) VALUES
+    ('monitoring', 'Scrapes operational metrics');
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT r.id, p.permission
+FROM roles r
+JOIN (VALUES
+    ('admin', 'metrics:read'),
+    ('monitoring', 'metrics:read')
+) AS p(role, permission) ON p.role = r.name;
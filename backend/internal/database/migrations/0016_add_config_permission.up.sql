@@ -0,0 +1,6 @@
+INSERT INTO role_permissions (role_id, permission)
+SELECT r.id, p.permission
+FROM roles r
+JOIN (VALUES
+    ('admin', 'config:read')
+) AS p(role, permission) ON p.role = r.name;
@@ -0,0 +1,10 @@
+-- Token buckets shared by API replicas; see internal/ratelimit. Rows are
+-- deleted once full_at passes, as a full bucket is the same as none.
+CREATE TABLE rate_limit_buckets (
+    key VARCHAR(512) PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    full_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX rate_limit_buckets_full_at_idx ON rate_limit_buckets (full_at);
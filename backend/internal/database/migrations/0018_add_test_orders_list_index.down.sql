@@ -0,0 +1 @@
+DROP INDEX IF EXISTS test_orders_ordered_at_idx;
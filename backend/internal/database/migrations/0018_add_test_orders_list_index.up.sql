@@ -0,0 +1,2 @@
+-- Test orders are listed by (ordered_at, id) by default
+CREATE INDEX test_orders_ordered_at_idx ON test_orders (ordered_at, id);
@@ -0,0 +1,10 @@
+ALTER TABLE test_results
+    DROP CONSTRAINT test_results_reporting_pathologist_id_fkey,
+    DROP CONSTRAINT test_results_created_by_fkey,
+    DROP CONSTRAINT test_results_verified_by_fkey,
+    ADD CONSTRAINT test_results_reporting_pathologist_id_fkey
+        FOREIGN KEY (reporting_pathologist_id) REFERENCES users(id) ON DELETE SET NULL,
+    ADD CONSTRAINT test_results_created_by_fkey
+        FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL,
+    ADD CONSTRAINT test_results_verified_by_fkey
+        FOREIGN KEY (verified_by) REFERENCES users(id) ON DELETE SET NULL;
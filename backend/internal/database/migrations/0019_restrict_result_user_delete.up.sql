@@ -0,0 +1,13 @@
+-- Verified results are immutable, so a user who created, reported or signed
+-- out a result cannot be deleted: setting these columns to NULL would both
+-- trip test_results_protect_verified and erase who signed the report.
+ALTER TABLE test_results
+    DROP CONSTRAINT test_results_reporting_pathologist_id_fkey,
+    DROP CONSTRAINT test_results_created_by_fkey,
+    DROP CONSTRAINT test_results_verified_by_fkey,
+    ADD CONSTRAINT test_results_reporting_pathologist_id_fkey
+        FOREIGN KEY (reporting_pathologist_id) REFERENCES users(id) ON DELETE RESTRICT,
+    ADD CONSTRAINT test_results_created_by_fkey
+        FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE RESTRICT,
+    ADD CONSTRAINT test_results_verified_by_fkey
+        FOREIGN KEY (verified_by) REFERENCES users(id) ON DELETE RESTRICT;
@@ -0,0 +1,236 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.32.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracedConnector wraps a driver so every query, statement execution and
+// transaction issued through the *sql.DB is recorded as a client span. Spans
+// are children of the span in the query's context, so queries made with the
+// *Context methods nest under the HTTP request that issued them.
+//
+// Only the query text is recorded, never its arguments, which may hold
+// patient data.
+type tracedConnector struct {
+	driver.Connector
+	tracer trace.Tracer
+}
+
+// Connect wraps each new connection so its queries are traced
+func (c *tracedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedConn{Conn: conn, tracer: c.tracer}, nil
+}
+
+// startSpan starts a client span for a statement, named by its operation
+func startSpan(ctx context.Context, tracer trace.Tracer, query string) (context.Context, trace.Span) {
+	operation := queryOperation(query)
+	attrs := []attribute.KeyValue{semconv.DBSystemNamePostgreSQL, semconv.DBQueryText(query)}
+	if operation != "" {
+		attrs = append(attrs, semconv.DBOperationName(operation))
+	}
+	return tracer.Start(ctx, spanName(operation), trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// spanName names a database span after its operation, falling back to the
+// database system for statements without one
+func spanName(operation string) string {
+	if operation == "" {
+		return "postgresql"
+	}
+	return operation
+}
+
+// queryOperation returns a statement's leading keyword, such as SELECT
+func queryOperation(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToUpper(fields[0])
+}
+
+// endSpan records a statement's error, if any, and ends its span.
+// driver.ErrSkip only tells database/sql to take a fallback path and is not
+// a failure.
+func endSpan(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, driver.ErrSkip) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// tracedConn traces the context-aware query paths database/sql uses. The
+// optional interfaces are forwarded to the wrapped connection, returning
+// driver.ErrSkip where it lacks them so database/sql falls back as it would
+// for the bare driver.
+type tracedConn struct {
+	driver.Conn
+	tracer trace.Tracer
+}
+
+func (c *tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	ctx, span := startSpan(ctx, c.tracer, query)
+	rows, err := queryer.QueryContext(ctx, query, args)
+	endSpan(span, err)
+	return rows, err
+}
+
+func (c *tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	ctx, span := startSpan(ctx, c.tracer, query)
+	result, err := execer.ExecContext(ctx, query, args)
+	endSpan(span, err)
+	return result, err
+}
+
+func (c *tracedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tracedStmt{Stmt: stmt, tracer: c.tracer, query: query}, nil
+}
+
+func (c *tracedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	spanCtx, span := startSpan(ctx, c.tracer, "BEGIN")
+	var tx driver.Tx
+	var err error
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		tx, err = beginner.BeginTx(spanCtx, opts)
+	} else {
+		tx, err = c.Conn.Begin()
+	}
+	endSpan(span, err)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedTx{Tx: tx, ctx: ctx, tracer: c.tracer}, nil
+}
+
+func (c *tracedConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *tracedConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *tracedConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+func (c *tracedConn) CheckNamedValue(value *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(value)
+	}
+	return driver.ErrSkip
+}
+
+// tracedTx records the end of a transaction as a span alongside its
+// statements, in the context the transaction began in
+type tracedTx struct {
+	driver.Tx
+	ctx    context.Context
+	tracer trace.Tracer
+}
+
+func (tx *tracedTx) Commit() error {
+	_, span := startSpan(tx.ctx, tx.tracer, "COMMIT")
+	err := tx.Tx.Commit()
+	endSpan(span, err)
+	return err
+}
+
+func (tx *tracedTx) Rollback() error {
+	_, span := startSpan(tx.ctx, tx.tracer, "ROLLBACK")
+	err := tx.Tx.Rollback()
+	endSpan(span, err)
+	return err
+}
+
+// tracedStmt traces each execution of a prepared statement. database/sql
+// does not fall back when a statement returns driver.ErrSkip, so statements
+// without the context-aware methods are run through the legacy ones.
+type tracedStmt struct {
+	driver.Stmt
+	tracer trace.Tracer
+	query  string
+}
+
+func (s *tracedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	ctx, span := startSpan(ctx, s.tracer, s.query)
+	var rows driver.Rows
+	var err error
+	if queryer, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = queryer.QueryContext(ctx, args)
+	} else {
+		rows, err = s.Stmt.Query(namedValues(args))
+	}
+	endSpan(span, err)
+	return rows, err
+}
+
+func (s *tracedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	ctx, span := startSpan(ctx, s.tracer, s.query)
+	var result driver.Result
+	var err error
+	if execer, ok := s.Stmt.(driver.StmtExecContext); ok {
+		result, err = execer.ExecContext(ctx, args)
+	} else {
+		result, err = s.Stmt.Exec(namedValues(args))
+	}
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStmt) CheckNamedValue(value *driver.NamedValue) error {
+	if checker, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(value)
+	}
+	return driver.ErrSkip
+}
+
+// namedValues converts arguments for the legacy driver.Stmt methods, which
+// take them by position
+func namedValues(args []driver.NamedValue) []driver.Value {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return values
+}
@@ -0,0 +1,177 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"backend/internal/models"
+)
+
+var errFakeQuery = errors.New("relation \"missing\" does not exist")
+
+// fakeConnector hands out connections that answer every query with no rows
+type fakeConnector struct{}
+
+func (fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn{}, nil }
+func (fakeConnector) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (fakeConn) Close() error                        { return nil }
+func (fakeConn) Begin() (driver.Tx, error)           { return fakeTx{}, nil }
+
+func (fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	if query == "SELECT * FROM missing" {
+		return nil, errFakeQuery
+	}
+	return fakeRows{}, nil
+}
+
+func (fakeConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeRows struct{}
+
+func (fakeRows) Columns() []string         { return []string{"id"} }
+func (fakeRows) Close() error              { return nil }
+func (fakeRows) Next([]driver.Value) error { return io.EOF }
+
+func TestTracedConnector(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	db := sql.OpenDB(&tracedConnector{Connector: fakeConnector{}, tracer: tp.Tracer("test")})
+	defer db.Close()
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "GET /api/patients")
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("BeginTx failed: %v", err)
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE patients SET mrn = $1", "MRN000123"); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if _, err := db.QueryContext(ctx, "SELECT * FROM missing"); !errors.Is(err, errFakeQuery) {
+		t.Fatalf("Expected the driver's error, got %v", err)
+	}
+	parent.End()
+
+	spans := exporter.GetSpans()
+	var names []string
+	for _, span := range spans[:len(spans)-1] {
+		names = append(names, span.Name)
+		if span.Parent.SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("Expected %s to be a child of the request span", span.Name)
+		}
+		for _, kv := range span.Attributes {
+			if kv.Value.Type() == attribute.STRING && kv.Value.AsString() == "MRN000123" {
+				t.Errorf("Expected query arguments not to be recorded, got %v", kv)
+			}
+		}
+	}
+
+	expected := []string{"BEGIN", "UPDATE", "COMMIT", "SELECT"}
+	if len(names) != len(expected) {
+		t.Fatalf("Expected spans %v, got %v", expected, names)
+	}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Errorf("Expected spans %v, got %v", expected, names)
+			break
+		}
+	}
+
+	failed := spans[len(spans)-2]
+	if failed.Status.Code != codes.Error || len(failed.Events) == 0 {
+		t.Errorf("Expected the failed query to record its error, got %v", failed.Status)
+	}
+}
+
+func TestTracedConnector_RepositorySpansNestUnderRequest(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	db := sql.OpenDB(&tracedConnector{Connector: fakeConnector{}, tracer: tp.Tracer("test")})
+	defer db.Close()
+
+	ctx, server := tp.Tracer("test").Start(context.Background(), "GET /api/users/{id}", trace.WithSpanKind(trace.SpanKindServer))
+	if _, err := models.NewUserRepository(db).GetByID(ctx, 1); err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	server.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 || spans[0].Name != "SELECT" {
+		t.Fatalf("Expected a SELECT span and the server span, got %d spans", len(spans))
+	}
+	if spans[0].Parent.SpanID() != server.SpanContext().SpanID() || spans[0].SpanContext.TraceID() != server.SpanContext().TraceID() {
+		t.Errorf("Expected the repository query to be a child of the server span")
+	}
+}
+
+func TestQueryOperation(t *testing.T) {
+	tests := map[string]string{
+		"SELECT 1":                       "SELECT",
+		"\n\t\tinsert into users (name)": "INSERT",
+		"":                               "",
+	}
+	for query, operation := range tests {
+		if got := queryOperation(query); got != operation {
+			t.Errorf("queryOperation(%q) = %q, expected %q", query, got, operation)
+		}
+	}
+}
+
+func TestObservePool(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	db := sql.OpenDB(fakeConnector{})
+	defer db.Close()
+	db.SetMaxOpenConns(25)
+
+	if err := observePool(db, mp.Meter("test")); err != nil {
+		t.Fatalf("observePool failed: %v", err)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+
+	found := map[string]bool{}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		found[m.Name] = true
+		if m.Name == "db.client.connection.max" {
+			if value := m.Data.(metricdata.Sum[int64]).DataPoints[0].Value; value != 25 {
+				t.Errorf("Expected max connections 25, got %d", value)
+			}
+		}
+	}
+	for _, name := range []string{"db.client.connection.count", "db.client.connection.max", "db.client.connection.waits", "db.client.connection.closed"} {
+		if !found[name] {
+			t.Errorf("Expected metric %s, got %v", name, found)
+		}
+	}
+}
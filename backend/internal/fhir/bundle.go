@@ -0,0 +1,108 @@
+package fhir
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// BundleLink is a link to this or an adjacent page of search results
+type BundleLink struct {
+	Relation string `json:"relation"`
+	URL      string `json:"url"`
+}
+
+// EntrySearch says why an entry is in a search result
+type EntrySearch struct {
+	Mode string `json:"mode"`
+}
+
+// BundleEntry is one resource in a Bundle
+type BundleEntry struct {
+	FullURL  string       `json:"fullUrl"`
+	Resource interface{}  `json:"resource"`
+	Search   *EntrySearch `json:"search,omitempty"`
+}
+
+// Bundle is a page of search results
+type Bundle struct {
+	ResourceType string        `json:"resourceType"`
+	Meta         *Meta         `json:"meta,omitempty"`
+	Type         string        `json:"type"`
+	Total        int           `json:"total"`
+	Link         []BundleLink  `json:"link"`
+	Entry        []BundleEntry `json:"entry,omitempty"`
+}
+
+// NewSearchSet starts the Bundle for one page of a search. requestURL is the
+// absolute URL searched; the self, previous and next links are built from it
+// with _count and _offset set for each page.
+func NewSearchSet(requestURL *url.URL, search *Search, total int, now time.Time) *Bundle {
+	bundle := &Bundle{
+		ResourceType: "Bundle",
+		Meta:         &Meta{LastUpdated: instant(now)},
+		Type:         "searchset",
+		Total:        total,
+		Link:         []BundleLink{{Relation: "self", URL: pageURL(requestURL, search.Count, search.Offset)}},
+	}
+
+	if search.Offset > 0 && search.Count > 0 {
+		previous := search.Offset - search.Count
+		if previous < 0 {
+			previous = 0
+		}
+		bundle.Link = append(bundle.Link, BundleLink{Relation: "previous", URL: pageURL(requestURL, search.Count, previous)})
+	}
+	if search.Count > 0 && search.Offset+search.Count < total {
+		bundle.Link = append(bundle.Link, BundleLink{
+			Relation: "next",
+			URL:      pageURL(requestURL, search.Count, search.Offset+search.Count),
+		})
+	}
+
+	return bundle
+}
+
+// AddMatch adds a resource matching the search
+func (b *Bundle) AddMatch(fullURL string, resource interface{}) {
+	b.Entry = append(b.Entry, BundleEntry{FullURL: fullURL, Resource: resource, Search: &EntrySearch{Mode: "match"}})
+}
+
+// pageURL is requestURL with _count and _offset set
+func pageURL(requestURL *url.URL, count, offset int) string {
+	page := *requestURL
+	query := page.Query()
+	query.Set(paramCount, strconv.Itoa(count))
+	query.Set(paramOffset, strconv.Itoa(offset))
+	page.RawQuery = query.Encode()
+	return page.String()
+}
+
+// Issue types used in OperationOutcomes
+const (
+	IssueInvalid      = "invalid"
+	IssueNotFound     = "not-found"
+	IssueNotSupported = "not-supported"
+	IssueException    = "exception"
+)
+
+// OperationOutcomeIssue is one problem reported by an OperationOutcome
+type OperationOutcomeIssue struct {
+	Severity    string `json:"severity"`
+	Code        string `json:"code"`
+	Diagnostics string `json:"diagnostics,omitempty"`
+}
+
+// OperationOutcome reports why a request failed
+type OperationOutcome struct {
+	ResourceType string                  `json:"resourceType"`
+	Issue        []OperationOutcomeIssue `json:"issue"`
+}
+
+// NewOperationOutcome reports a single error of the given issue type
+func NewOperationOutcome(code, diagnostics string) *OperationOutcome {
+	return &OperationOutcome{
+		ResourceType: "OperationOutcome",
+		Issue:        []OperationOutcomeIssue{{Severity: "error", Code: code, Diagnostics: diagnostics}},
+	}
+}
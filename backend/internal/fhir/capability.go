@@ -0,0 +1,133 @@
+package fhir
+
+import "time"
+
+// SearchParam is a search parameter a resource supports
+type SearchParam struct {
+	Name          string `json:"name"`
+	Type          string `json:"type"`
+	Documentation string `json:"documentation,omitempty"`
+}
+
+// Interaction is an operation a resource supports
+type Interaction struct {
+	Code string `json:"code"`
+}
+
+// RestResource describes the API for one resource type
+type RestResource struct {
+	Type        string        `json:"type"`
+	Interaction []Interaction `json:"interaction"`
+	SearchParam []SearchParam `json:"searchParam"`
+}
+
+// Rest describes the API
+type Rest struct {
+	Mode     string         `json:"mode"`
+	Resource []RestResource `json:"resource"`
+}
+
+// Software names the server software
+type Software struct {
+	Name string `json:"name"`
+}
+
+// Implementation describes this server
+type Implementation struct {
+	Description string `json:"description"`
+	URL         string `json:"url"`
+}
+
+// CapabilityStatement is returned by GET /fhir/metadata
+type CapabilityStatement struct {
+	ResourceType   string         `json:"resourceType"`
+	Status         string         `json:"status"`
+	Date           string         `json:"date"`
+	Kind           string         `json:"kind"`
+	Software       Software       `json:"software"`
+	Implementation Implementation `json:"implementation"`
+	FHIRVersion    string         `json:"fhirVersion"`
+	Format         []string       `json:"format"`
+	Rest           []Rest         `json:"rest"`
+}
+
+// Search parameter names
+const (
+	paramID         = "_id"
+	paramIdentifier = "identifier"
+	paramPatient    = "patient"
+	paramStatus     = "status"
+	paramDate       = "date"
+	paramAuthored   = "authored"
+	paramCount      = "_count"
+	paramOffset     = "_offset"
+	paramFormat     = "_format"
+)
+
+// searchParams lists the search parameters each resource type supports.
+// _count, _offset and _format apply to all of them.
+var searchParams = map[string][]SearchParam{
+	"Patient": {
+		{paramID, "token", "Logical ID; merged records are only returned by ID"},
+		{paramIdentifier, "token", "MRN or other identifier, as value or system|value"},
+	},
+	"ServiceRequest": {
+		{paramID, "token", "Logical ID (the test order ID)"},
+		{paramPatient, "reference", "Patient the order is for"},
+		{paramStatus, "token", "active, completed or revoked"},
+		{paramAuthored, "date", "When the order was placed"},
+		{paramDate, "date", "Same as authored"},
+	},
+	"DiagnosticReport": {
+		{paramID, "token", "Logical ID (the test order ID)"},
+		{paramPatient, "reference", "Patient the report is about"},
+		{paramStatus, "token", "final or amended"},
+		{paramDate, "date", "When the specimen was collected"},
+	},
+	"Observation": {
+		{paramID, "token", "Logical ID ({test order ID}-{n})"},
+		{paramPatient, "reference", "Patient the observation is about"},
+		{paramStatus, "token", "final or amended"},
+		{paramDate, "date", "When the specimen was collected"},
+	},
+}
+
+// ResourceTypes lists the resource types the API serves, in the order they
+// are described in the CapabilityStatement
+var ResourceTypes = []string{"Patient", "ServiceRequest", "DiagnosticReport", "Observation"}
+
+// NewCapabilityStatement describes the API served at base. date is when the
+// server started.
+func NewCapabilityStatement(base string, date time.Time) *CapabilityStatement {
+	statement := &CapabilityStatement{
+		ResourceType: "CapabilityStatement",
+		Status:       "active",
+		Date:         instant(date),
+		Kind:         "instance",
+		Software:     Software{Name: "AlphaPath"},
+		Implementation: Implementation{
+			Description: "AlphaPath read-only FHIR API",
+			URL:         base,
+		},
+		FHIRVersion: Version,
+		Format:      []string{MediaType, "json"},
+	}
+
+	rest := Rest{Mode: "server"}
+	for _, resourceType := range ResourceTypes {
+		params := append([]SearchParam(nil), searchParams[resourceType]...)
+		params = append(params,
+			SearchParam{paramCount, "number", "Page size, at most 100"},
+			SearchParam{paramOffset, "number", "Matches to skip"},
+		)
+
+		rest.Resource = append(rest.Resource, RestResource{
+			Type:        resourceType,
+			Interaction: []Interaction{{Code: "read"}, {Code: "search-type"}},
+			SearchParam: params,
+		})
+	}
+	statement.Rest = []Rest{rest}
+
+	return statement
+}
@@ -0,0 +1,274 @@
+package fhir
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"backend/internal/models"
+)
+
+// ServiceRequest statuses
+const (
+	RequestActive    = "active"
+	RequestCompleted = "completed"
+	RequestRevoked   = "revoked"
+)
+
+// DiagnosticReport and Observation statuses. Only verified results are
+// reported, so these are the only two.
+const (
+	ReportFinal   = "final"
+	ReportAmended = "amended"
+)
+
+// requestStatuses maps each ServiceRequest status to the order statuses it
+// covers
+var requestStatuses = map[string][]models.TestOrderStatus{
+	RequestActive: {models.TestOrderOrdered, models.TestOrderCollected, models.TestOrderReceived,
+		models.TestOrderInAnalysis},
+	RequestCompleted: {models.TestOrderResulted, models.TestOrderVerified},
+	RequestRevoked:   {models.TestOrderCancelled},
+}
+
+// jsonNumber matches a value that can be written as a JSON number
+var jsonNumber = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// interpretationCodes are the finding flags that are codes in the HL7
+// observation interpretation code system; other flags are sent as text
+var interpretationCodes = map[string]string{
+	"N":   "Normal",
+	"A":   "Abnormal",
+	"AA":  "Critical abnormal",
+	"H":   "High",
+	"HH":  "Critical high",
+	"L":   "Low",
+	"LL":  "Critical low",
+	"POS": "Positive",
+	"NEG": "Negative",
+}
+
+// instant formats a timestamp as a FHIR instant. Timestamps are stored in UTC.
+func instant(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// optionalInstant formats a timestamp that may not be set
+func optionalInstant(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return instant(*t)
+}
+
+// identifierType is an identifier type from HL7 table 0203
+func identifierType(code, display string) *CodeableConcept {
+	return &CodeableConcept{Coding: []Coding{{System: identifierTypeSystem, Code: code, Display: display}}}
+}
+
+// PatientReference references the patient an order is for. Orders predating
+// the registry may only carry an MRN.
+func PatientReference(patientID *int, mrn string) Reference {
+	if patientID == nil {
+		return Reference{Display: mrn}
+	}
+	return Reference{Reference: "Patient/" + strconv.Itoa(*patientID)}
+}
+
+// NewPatient maps a registered patient. A merged record is inactive and
+// links to the record that replaced it.
+func NewPatient(patient *models.Patient) *Patient {
+	resource := &Patient{
+		ResourceType: "Patient",
+		ID:           strconv.Itoa(patient.ID),
+		Meta:         &Meta{LastUpdated: instant(patient.UpdatedAt)},
+		Identifier: []Identifier{{
+			Use:   "usual",
+			Type:  identifierType("MR", "Medical record number"),
+			Value: patient.MRN,
+		}},
+		Active:    patient.MergedIntoID == nil,
+		Gender:    string(patient.Sex),
+		BirthDate: patient.BirthDate,
+	}
+
+	for _, identifier := range patient.Identifiers {
+		resource.Identifier = append(resource.Identifier, Identifier{System: identifier.System, Value: identifier.Value})
+	}
+
+	if patient.FamilyName != "" || patient.GivenName != "" {
+		name := HumanName{Use: "official", Family: patient.FamilyName}
+		if patient.GivenName != "" {
+			name.Given = []string{patient.GivenName}
+		}
+		resource.Name = []HumanName{name}
+	}
+
+	if patient.Phone != "" {
+		resource.Telecom = append(resource.Telecom, ContactPoint{System: "phone", Value: patient.Phone})
+	}
+	if patient.Email != "" {
+		resource.Telecom = append(resource.Telecom, ContactPoint{System: "email", Value: patient.Email})
+	}
+	if patient.Address != "" {
+		resource.Address = []Address{{Text: patient.Address}}
+	}
+
+	if patient.MergedIntoID != nil {
+		resource.Link = []PatientLink{{
+			Other: Reference{Reference: "Patient/" + strconv.Itoa(*patient.MergedIntoID)},
+			Type:  "replaced-by",
+		}}
+	}
+
+	return resource
+}
+
+// NewServiceRequest maps a test order
+func NewServiceRequest(order *models.TestOrder) *ServiceRequest {
+	resource := &ServiceRequest{
+		ResourceType: "ServiceRequest",
+		ID:           strconv.Itoa(order.ID),
+		Meta:         &Meta{LastUpdated: instant(order.UpdatedAt)},
+		Identifier: []Identifier{{
+			Type:  identifierType("ACSN", "Accession ID"),
+			Value: order.AccessionNumber,
+		}},
+		Status:     RequestStatus(order.Status),
+		Intent:     "order",
+		Priority:   string(order.Priority),
+		Code:       CodeableConcept{Text: order.TestType},
+		Subject:    PatientReference(order.PatientID, order.PatientMRN),
+		AuthoredOn: instant(order.OrderedAt),
+	}
+
+	if order.PlacerOrderNumber != "" {
+		resource.Identifier = append(resource.Identifier, Identifier{
+			Type:   identifierType("PLAC", "Placer Identifier"),
+			System: order.PlacerSystem,
+			Value:  order.PlacerOrderNumber,
+		})
+	}
+	if order.Notes != "" {
+		resource.Note = []Annotation{{Text: order.Notes}}
+	}
+
+	return resource
+}
+
+// RequestStatus maps an order status to a ServiceRequest status
+func RequestStatus(status models.TestOrderStatus) string {
+	for requestStatus, statuses := range requestStatuses {
+		for _, s := range statuses {
+			if s == status {
+				return requestStatus
+			}
+		}
+	}
+	return "unknown"
+}
+
+// OrderStatuses returns the order statuses a ServiceRequest status covers
+func OrderStatuses(status string) []models.TestOrderStatus {
+	return requestStatuses[status]
+}
+
+// ReportStatus is the status of a reported result: amended once a
+// correction has been verified
+func ReportStatus(result *models.TestResult) string {
+	if result.Version > 1 {
+		return ReportAmended
+	}
+	return ReportFinal
+}
+
+// ObservationID identifies finding n (counting from 1) of an order's result
+func ObservationID(testOrderID, n int) string {
+	return fmt.Sprintf("%d-%d", testOrderID, n)
+}
+
+// NewDiagnosticReport maps the reported result of a test order. The report
+// shares the order's ID.
+func NewDiagnosticReport(order *models.TestOrder, result *models.TestResult) *DiagnosticReport {
+	resource := &DiagnosticReport{
+		ResourceType: "DiagnosticReport",
+		ID:           strconv.Itoa(order.ID),
+		Meta: &Meta{
+			VersionID:   strconv.Itoa(result.Version),
+			LastUpdated: optionalInstant(result.VerifiedAt),
+		},
+		Identifier: []Identifier{{
+			Type:  identifierType("ACSN", "Accession ID"),
+			Value: order.AccessionNumber,
+		}},
+		BasedOn: []Reference{{Reference: "ServiceRequest/" + strconv.Itoa(order.ID)}},
+		Status:  ReportStatus(result),
+		Category: []CodeableConcept{{Coding: []Coding{{
+			System:  diagnosticServiceSection,
+			Code:    "PAT",
+			Display: "Pathology (gross & histopath, not surgical)",
+		}}}},
+		Code:              CodeableConcept{Text: order.TestType},
+		Subject:           PatientReference(order.PatientID, order.PatientMRN),
+		EffectiveDateTime: optionalInstant(order.CollectedAt),
+		Issued:            optionalInstant(result.VerifiedAt),
+		Conclusion:        result.Interpretation,
+	}
+
+	for i := range result.Findings {
+		resource.Result = append(resource.Result, Reference{Reference: "Observation/" + ObservationID(order.ID, i+1)})
+	}
+
+	return resource
+}
+
+// NewObservation maps finding n (counting from 1) of the reported result of
+// a test order. Numeric values are sent as quantities.
+func NewObservation(order *models.TestOrder, result *models.TestResult, n int) *Observation {
+	finding := result.Findings[n-1]
+
+	resource := &Observation{
+		ResourceType: "Observation",
+		ID:           ObservationID(order.ID, n),
+		Meta: &Meta{
+			VersionID:   strconv.Itoa(result.Version),
+			LastUpdated: optionalInstant(result.VerifiedAt),
+		},
+		BasedOn: []Reference{{Reference: "ServiceRequest/" + strconv.Itoa(order.ID)}},
+		Status:  ReportStatus(result),
+		Category: []CodeableConcept{{Coding: []Coding{{
+			System:  observationCategory,
+			Code:    "laboratory",
+			Display: "Laboratory",
+		}}}},
+		Code: CodeableConcept{
+			Coding: []Coding{{Code: finding.Code, Display: finding.Name}},
+			Text:   finding.Name,
+		},
+		Subject:           PatientReference(order.PatientID, order.PatientMRN),
+		EffectiveDateTime: optionalInstant(order.CollectedAt),
+		Issued:            optionalInstant(result.VerifiedAt),
+	}
+
+	if jsonNumber.MatchString(finding.Value) {
+		resource.ValueQuantity = &Quantity{Value: json.Number(finding.Value), Unit: finding.Unit}
+	} else {
+		resource.ValueString = finding.Value
+	}
+
+	if finding.Flag != "" {
+		if display, ok := interpretationCodes[finding.Flag]; ok {
+			resource.Interpretation = []CodeableConcept{{Coding: []Coding{{
+				System:  interpretationSystem,
+				Code:    finding.Flag,
+				Display: display,
+			}}}}
+		} else {
+			resource.Interpretation = []CodeableConcept{{Text: finding.Flag}}
+		}
+	}
+
+	return resource
+}
@@ -0,0 +1,144 @@
+package fhir
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+)
+
+func TestNewPatient(t *testing.T) {
+	survivor := 9
+	patient := &models.Patient{
+		ID:           4,
+		MRN:          "P00000004",
+		FamilyName:   "O'Brien",
+		GivenName:    "Siobhan",
+		BirthDate:    "1975-11-02",
+		Sex:          models.PatientFemale,
+		Email:        "siobhan@example.org",
+		Identifiers:  []models.PatientIdentifier{{System: "GENERAL_HOSP", Value: "H100"}},
+		MergedIntoID: &survivor,
+	}
+
+	resource := NewPatient(patient)
+
+	if resource.ID != "4" || resource.Gender != "female" || resource.BirthDate != "1975-11-02" {
+		t.Errorf("Unexpected patient %+v", resource)
+	}
+	if len(resource.Identifier) != 2 || resource.Identifier[0].Value != "P00000004" ||
+		resource.Identifier[1].System != "GENERAL_HOSP" {
+		t.Errorf("Unexpected identifiers %+v", resource.Identifier)
+	}
+	if resource.Active || len(resource.Link) != 1 || resource.Link[0].Other.Reference != "Patient/9" {
+		t.Errorf("Expected a merged patient to be inactive and replaced by Patient/9, got %+v", resource)
+	}
+	if len(resource.Telecom) != 1 || resource.Telecom[0].System != "email" {
+		t.Errorf("Unexpected telecom %+v", resource.Telecom)
+	}
+}
+
+func TestNewServiceRequest(t *testing.T) {
+	patientID := 4
+	order := &models.TestOrder{
+		ID:                12,
+		AccessionNumber:   "AP26-000012",
+		PatientID:         &patientID,
+		PlacerSystem:      "EPIC",
+		PlacerOrderNumber: "PO-1001",
+		TestType:          "alphapath_panel",
+		Priority:          models.TestOrderStat,
+		Status:            models.TestOrderInAnalysis,
+		OrderedAt:         time.Date(2026, 3, 1, 8, 30, 0, 0, time.UTC),
+	}
+
+	resource := NewServiceRequest(order)
+
+	if resource.Status != RequestActive || resource.Priority != "stat" || resource.Subject.Reference != "Patient/4" {
+		t.Errorf("Unexpected service request %+v", resource)
+	}
+	if resource.AuthoredOn != "2026-03-01T08:30:00Z" {
+		t.Errorf("Unexpected authoredOn %q", resource.AuthoredOn)
+	}
+	if len(resource.Identifier) != 2 || resource.Identifier[1].Value != "PO-1001" {
+		t.Errorf("Unexpected identifiers %+v", resource.Identifier)
+	}
+
+	for status, expected := range map[models.TestOrderStatus]string{
+		models.TestOrderOrdered:   RequestActive,
+		models.TestOrderVerified:  RequestCompleted,
+		models.TestOrderCancelled: RequestRevoked,
+	} {
+		if got := RequestStatus(status); got != expected {
+			t.Errorf("RequestStatus(%s): expected %s, got %s", status, expected, got)
+		}
+	}
+}
+
+func TestNewObservation(t *testing.T) {
+	verifiedAt := time.Date(2026, 3, 2, 15, 4, 5, 0, time.UTC)
+	order := &models.TestOrder{ID: 12, AccessionNumber: "AP26-000012", PatientMRN: "LEGACY-1"}
+	result := &models.TestResult{
+		Version: 2,
+		Findings: []models.Finding{
+			{Code: "ki67", Name: "Ki-67 index", Value: "12.5", Unit: "%", Flag: "H"},
+			{Code: "grade", Name: "Grade", Value: "G2", Flag: "borderline"},
+		},
+		VerifiedAt: &verifiedAt,
+	}
+
+	numeric := NewObservation(order, result, 1)
+	if numeric.ID != "12-1" || numeric.Status != ReportAmended || numeric.ValueQuantity == nil ||
+		numeric.ValueQuantity.Value != "12.5" || numeric.ValueQuantity.Unit != "%" {
+		t.Errorf("Unexpected numeric observation %+v", numeric)
+	}
+	if numeric.Interpretation[0].Coding[0].Code != "H" || numeric.Subject.Display != "LEGACY-1" {
+		t.Errorf("Unexpected interpretation or subject %+v", numeric)
+	}
+
+	text := NewObservation(order, result, 2)
+	if text.ValueQuantity != nil || text.ValueString != "G2" || text.Interpretation[0].Text != "borderline" {
+		t.Errorf("Unexpected text observation %+v", text)
+	}
+
+	encoded, err := json.Marshal(numeric)
+	if err != nil || !strings.Contains(string(encoded), `"valueQuantity":{"value":12.5,"unit":"%"}`) {
+		t.Errorf("Expected the quantity to be a JSON number, got %s (%v)", encoded, err)
+	}
+
+	report := NewDiagnosticReport(order, result)
+	if report.ID != "12" || len(report.Result) != 2 || report.Result[1].Reference != "Observation/12-2" {
+		t.Errorf("Unexpected report %+v", report)
+	}
+}
+
+func TestNewSearchSet(t *testing.T) {
+	requestURL, _ := url.Parse("https://lab.example.org/fhir/Observation?patient=7&_count=10&_offset=10")
+	search := &Search{Count: 10, Offset: 10}
+
+	bundle := NewSearchSet(requestURL, search, 25, time.Now())
+
+	links := map[string]string{}
+	for _, link := range bundle.Link {
+		links[link.Relation] = link.URL
+	}
+	if links["previous"] != "https://lab.example.org/fhir/Observation?_count=10&_offset=0&patient=7" {
+		t.Errorf("Unexpected previous link %q", links["previous"])
+	}
+	if links["next"] != "https://lab.example.org/fhir/Observation?_count=10&_offset=20&patient=7" {
+		t.Errorf("Unexpected next link %q", links["next"])
+	}
+
+	bundle = NewSearchSet(requestURL, &Search{Count: 10, Offset: 20}, 25, time.Now())
+	for _, link := range bundle.Link {
+		if link.Relation == "next" {
+			t.Errorf("Expected no next link on the last page")
+		}
+	}
+	if bundle.Total != 25 || bundle.Type != "searchset" {
+		t.Errorf("Unexpected bundle %+v", bundle)
+	}
+}
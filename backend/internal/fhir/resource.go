@@ -0,0 +1,158 @@
+// Package fhir maps patients, test orders and results to HL7 FHIR R4
+// resources for the read-only FHIR API: Patient, ServiceRequest,
+// DiagnosticReport and Observation. It also parses the search parameters
+// the API supports and builds the Bundles, CapabilityStatement and
+// OperationOutcomes it returns.
+package fhir
+
+import "encoding/json"
+
+// Version is the FHIR version the API implements
+const Version = "4.0.1"
+
+// MediaType is the FHIR JSON media type. Plain application/json is accepted
+// too.
+const MediaType = "application/fhir+json"
+
+// Code systems defined by HL7
+const (
+	identifierTypeSystem     = "http://terminology.hl7.org/CodeSystem/v2-0203"
+	observationCategory      = "http://terminology.hl7.org/CodeSystem/observation-category"
+	diagnosticServiceSection = "http://terminology.hl7.org/CodeSystem/v2-0074"
+	interpretationSystem     = "http://terminology.hl7.org/CodeSystem/v3-ObservationInterpretation"
+)
+
+// Meta is the metadata common to all resources
+type Meta struct {
+	VersionID   string `json:"versionId,omitempty"`
+	LastUpdated string `json:"lastUpdated,omitempty"`
+}
+
+// Coding is a code from a code system
+type Coding struct {
+	System  string `json:"system,omitempty"`
+	Code    string `json:"code,omitempty"`
+	Display string `json:"display,omitempty"`
+}
+
+// CodeableConcept is a concept given as codes and/or text
+type CodeableConcept struct {
+	Coding []Coding `json:"coding,omitempty"`
+	Text   string   `json:"text,omitempty"`
+}
+
+// Identifier is a business identifier issued by System
+type Identifier struct {
+	Use    string           `json:"use,omitempty"`
+	Type   *CodeableConcept `json:"type,omitempty"`
+	System string           `json:"system,omitempty"`
+	Value  string           `json:"value"`
+}
+
+// Reference points at another resource, as "Patient/123". A patient not in
+// the registry is referenced by display (their MRN) alone.
+type Reference struct {
+	Reference string `json:"reference,omitempty"`
+	Display   string `json:"display,omitempty"`
+}
+
+// HumanName is a person's name
+type HumanName struct {
+	Use    string   `json:"use,omitempty"`
+	Family string   `json:"family,omitempty"`
+	Given  []string `json:"given,omitempty"`
+}
+
+// ContactPoint is a phone number or email address
+type ContactPoint struct {
+	System string `json:"system"`
+	Value  string `json:"value"`
+}
+
+// Address is a postal address. Addresses are registered as free text.
+type Address struct {
+	Text string `json:"text"`
+}
+
+// Annotation is a note
+type Annotation struct {
+	Text string `json:"text"`
+}
+
+// Quantity is a measured amount
+type Quantity struct {
+	Value json.Number `json:"value"`
+	Unit  string      `json:"unit,omitempty"`
+}
+
+// PatientLink links a merged patient to the record that replaced it
+type PatientLink struct {
+	Other Reference `json:"other"`
+	Type  string    `json:"type"`
+}
+
+// Patient is the FHIR Patient resource
+type Patient struct {
+	ResourceType string         `json:"resourceType"`
+	ID           string         `json:"id"`
+	Meta         *Meta          `json:"meta,omitempty"`
+	Identifier   []Identifier   `json:"identifier,omitempty"`
+	Active       bool           `json:"active"`
+	Name         []HumanName    `json:"name,omitempty"`
+	Telecom      []ContactPoint `json:"telecom,omitempty"`
+	Gender       string         `json:"gender,omitempty"`
+	BirthDate    string         `json:"birthDate,omitempty"`
+	Address      []Address      `json:"address,omitempty"`
+	Link         []PatientLink  `json:"link,omitempty"`
+}
+
+// ServiceRequest is the FHIR ServiceRequest resource, for a test order
+type ServiceRequest struct {
+	ResourceType string          `json:"resourceType"`
+	ID           string          `json:"id"`
+	Meta         *Meta           `json:"meta,omitempty"`
+	Identifier   []Identifier    `json:"identifier,omitempty"`
+	Status       string          `json:"status"`
+	Intent       string          `json:"intent"`
+	Priority     string          `json:"priority,omitempty"`
+	Code         CodeableConcept `json:"code"`
+	Subject      Reference       `json:"subject"`
+	AuthoredOn   string          `json:"authoredOn,omitempty"`
+	Note         []Annotation    `json:"note,omitempty"`
+}
+
+// DiagnosticReport is the FHIR DiagnosticReport resource, for the reported
+// result of a test order
+type DiagnosticReport struct {
+	ResourceType      string            `json:"resourceType"`
+	ID                string            `json:"id"`
+	Meta              *Meta             `json:"meta,omitempty"`
+	Identifier        []Identifier      `json:"identifier,omitempty"`
+	BasedOn           []Reference       `json:"basedOn,omitempty"`
+	Status            string            `json:"status"`
+	Category          []CodeableConcept `json:"category,omitempty"`
+	Code              CodeableConcept   `json:"code"`
+	Subject           Reference         `json:"subject"`
+	EffectiveDateTime string            `json:"effectiveDateTime,omitempty"`
+	Issued            string            `json:"issued,omitempty"`
+	Result            []Reference       `json:"result,omitempty"`
+	Conclusion        string            `json:"conclusion,omitempty"`
+}
+
+// Observation is the FHIR Observation resource, for one finding of a
+// reported result
+type Observation struct {
+	ResourceType      string            `json:"resourceType"`
+	ID                string            `json:"id"`
+	Meta              *Meta             `json:"meta,omitempty"`
+	BasedOn           []Reference       `json:"basedOn,omitempty"`
+	Status            string            `json:"status"`
+	Category          []CodeableConcept `json:"category,omitempty"`
+	Code              CodeableConcept   `json:"code"`
+	Subject           Reference         `json:"subject"`
+	EffectiveDateTime string            `json:"effectiveDateTime,omitempty"`
+	Issued            string            `json:"issued,omitempty"`
+	ValueQuantity     *Quantity         `json:"valueQuantity,omitempty"`
+	ValueString       string            `json:"valueString,omitempty"`
+	Interpretation    []CodeableConcept `json:"interpretation,omitempty"`
+}
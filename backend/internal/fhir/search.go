@@ -0,0 +1,244 @@
+package fhir
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Page sizes for searches
+const (
+	DefaultCount = 20
+	MaxCount     = 100
+)
+
+// statusValues lists the status tokens each resource type can be searched by
+var statusValues = map[string][]string{
+	"ServiceRequest":   {RequestActive, RequestCompleted, RequestRevoked},
+	"DiagnosticReport": {ReportFinal, ReportAmended},
+	"Observation":      {ReportFinal, ReportAmended},
+}
+
+// Search is a parsed search request. Parameters are combined with AND;
+// comma-separated values within _id and status with OR.
+type Search struct {
+	IDs      []string
+	Statuses []string
+
+	// PatientID is set by the patient parameter
+	PatientID *int
+
+	// IdentifierSystem is empty if the identifier was given without one
+	IdentifierSystem string
+	IdentifierValue  string
+
+	// From and To bound the searched date: matches are at or after From
+	// and before To
+	From *time.Time
+	To   *time.Time
+
+	Count  int
+	Offset int
+}
+
+// ParseSearch parses the query of a search on resourceType. Parameters the
+// resource does not support are rejected rather than ignored, so a client
+// is never sent more than it asked for.
+func ParseSearch(resourceType string, query url.Values) (*Search, error) {
+	supported := make(map[string]bool)
+	for _, param := range searchParams[resourceType] {
+		supported[param.Name] = true
+	}
+
+	search := &Search{Count: DefaultCount}
+	for name, values := range query {
+		switch {
+		case name == paramFormat:
+			continue
+		case name == paramCount || name == paramOffset:
+		case !supported[name]:
+			return nil, fmt.Errorf("unsupported search parameter %q for %s", name, resourceType)
+		}
+
+		if name != paramDate && name != paramAuthored && len(values) > 1 {
+			return nil, fmt.Errorf("search parameter %s may only be given once", name)
+		}
+		value := values[0]
+
+		switch name {
+		case paramCount:
+			count, err := strconv.Atoi(value)
+			if err != nil || count < 0 || count > MaxCount {
+				return nil, fmt.Errorf("_count must be between 0 and %d", MaxCount)
+			}
+			search.Count = count
+
+		case paramOffset:
+			offset, err := strconv.Atoi(value)
+			if err != nil || offset < 0 {
+				return nil, errors.New("_offset must be a non-negative integer")
+			}
+			search.Offset = offset
+
+		case paramID:
+			search.IDs = splitValues(value)
+			if len(search.IDs) == 0 {
+				return nil, errors.New("_id must have a value")
+			}
+
+		case paramIdentifier:
+			system, identifier, found := strings.Cut(value, "|")
+			if !found {
+				system, identifier = "", value
+			}
+			if identifier == "" {
+				return nil, errors.New("identifier must have a value")
+			}
+			search.IdentifierSystem, search.IdentifierValue = system, identifier
+
+		case paramPatient:
+			id, err := parsePatientReference(value)
+			if err != nil {
+				return nil, err
+			}
+			search.PatientID = &id
+
+		case paramStatus:
+			search.Statuses = splitValues(value)
+			if len(search.Statuses) == 0 {
+				return nil, errors.New("status must have a value")
+			}
+			for _, status := range search.Statuses {
+				if !contains(statusValues[resourceType], status) {
+					return nil, fmt.Errorf("unknown %s status %q", resourceType, status)
+				}
+			}
+
+		case paramDate, paramAuthored:
+			for _, value := range values {
+				if err := search.addDate(value); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if search.From != nil && search.To != nil && !search.From.Before(*search.To) {
+		// The date ranges do not overlap; nothing can match
+		search.To = search.From
+	}
+
+	return search, nil
+}
+
+// splitValues splits a comma-separated parameter value, dropping empty values
+func splitValues(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePatientReference reads a patient ID given as "123", "Patient/123" or
+// an absolute URL ending in "/Patient/123"
+func parsePatientReference(value string) (int, error) {
+	id := value
+	if i := strings.LastIndex(value, "/"); i >= 0 {
+		if !strings.HasSuffix(value[:i], "Patient") {
+			return 0, fmt.Errorf("patient must reference a Patient, got %q", value)
+		}
+		id = value[i+1:]
+	}
+
+	patientID, err := strconv.Atoi(id)
+	if err != nil || patientID <= 0 {
+		return 0, fmt.Errorf("invalid patient reference %q", value)
+	}
+	return patientID, nil
+}
+
+// dateLayouts are the accepted date and dateTime formats, with the length of
+// the range each denotes. Times must carry a time zone.
+var dateLayouts = []struct {
+	layout string
+	years  int
+	months int
+	step   time.Duration
+}{
+	{layout: "2006", years: 1},
+	{layout: "2006-01", months: 1},
+	{layout: "2006-01-02", step: 24 * time.Hour},
+	{layout: "2006-01-02T15:04Z07:00", step: time.Minute},
+	{layout: "2006-01-02T15:04:05Z07:00", step: time.Second},
+}
+
+// addDate narrows the search to a date parameter value: a prefix (eq, gt,
+// ge, lt or le, eq if omitted) and a date whose precision sets the range it
+// covers, so "2026-03" is all of March
+func (s *Search) addDate(value string) error {
+	prefix := "eq"
+	if len(value) > 2 && value[0] >= 'a' && value[0] <= 'z' {
+		prefix, value = value[:2], value[2:]
+	}
+
+	start, end, err := parseDateRange(value)
+	if err != nil {
+		return err
+	}
+
+	var from, to *time.Time
+	switch prefix {
+	case "eq":
+		from, to = &start, &end
+	case "ge":
+		from = &start
+	case "gt":
+		from = &end
+	case "le":
+		to = &end
+	case "lt":
+		to = &start
+	default:
+		return fmt.Errorf("unsupported date prefix %q", prefix)
+	}
+
+	if from != nil && (s.From == nil || from.After(*s.From)) {
+		s.From = from
+	}
+	if to != nil && (s.To == nil || to.Before(*s.To)) {
+		s.To = to
+	}
+	return nil
+}
+
+// parseDateRange returns the start and end of the range a date denotes.
+// Dates without a time are taken as UTC.
+func parseDateRange(value string) (time.Time, time.Time, error) {
+	for _, format := range dateLayouts {
+		start, err := time.Parse(format.layout, value)
+		if err != nil {
+			continue
+		}
+		start = start.UTC()
+		if format.years > 0 || format.months > 0 {
+			return start, start.AddDate(format.years, format.months, 0), nil
+		}
+		return start, start.Add(format.step), nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("invalid date %q", value)
+}
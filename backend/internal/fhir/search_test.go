@@ -0,0 +1,134 @@
+package fhir
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestParseSearch(t *testing.T) {
+	query, _ := url.ParseQuery("_id=12,13&patient=Patient/7&status=active,revoked&_count=5&_offset=10&_format=json")
+
+	search, err := ParseSearch("ServiceRequest", query)
+	if err != nil {
+		t.Fatalf("ParseSearch failed: %v", err)
+	}
+
+	if len(search.IDs) != 2 || search.IDs[1] != "13" {
+		t.Errorf("Unexpected IDs %v", search.IDs)
+	}
+	if search.PatientID == nil || *search.PatientID != 7 {
+		t.Errorf("Expected patient 7, got %v", search.PatientID)
+	}
+	if len(search.Statuses) != 2 || search.Statuses[1] != RequestRevoked {
+		t.Errorf("Unexpected statuses %v", search.Statuses)
+	}
+	if search.Count != 5 || search.Offset != 10 {
+		t.Errorf("Expected _count 5 and _offset 10, got %d and %d", search.Count, search.Offset)
+	}
+}
+
+func TestParseSearch_Defaults(t *testing.T) {
+	search, err := ParseSearch("Patient", url.Values{})
+	if err != nil {
+		t.Fatalf("ParseSearch failed: %v", err)
+	}
+	if search.Count != DefaultCount || search.Offset != 0 || search.IDs != nil {
+		t.Errorf("Unexpected defaults %+v", search)
+	}
+
+	query, _ := url.ParseQuery("identifier=GENERAL_HOSP|H100")
+	search, err = ParseSearch("Patient", query)
+	if err != nil || search.IdentifierSystem != "GENERAL_HOSP" || search.IdentifierValue != "H100" {
+		t.Errorf("Unexpected identifier search %+v (%v)", search, err)
+	}
+
+	query, _ = url.ParseQuery("identifier=H100")
+	search, err = ParseSearch("Patient", query)
+	if err != nil || search.IdentifierSystem != "" || search.IdentifierValue != "H100" {
+		t.Errorf("Unexpected identifier search %+v (%v)", search, err)
+	}
+}
+
+func TestParseSearch_Invalid(t *testing.T) {
+	tests := []struct {
+		resourceType string
+		query        string
+	}{
+		{"Patient", "name=smith"},
+		{"Patient", "status=active"},
+		{"ServiceRequest", "status=final"},
+		{"DiagnosticReport", "status=active"},
+		{"DiagnosticReport", "status="},
+		{"Observation", "_id="},
+		{"Observation", "patient=Practitioner/3"},
+		{"Observation", "patient=abc"},
+		{"Observation", "patient=1&patient=2"},
+		{"Observation", "date=2026-13"},
+		{"Observation", "date=ne2026-03-01"},
+		{"Observation", "date=2026-03-01T10:00"},
+		{"Observation", "_count=101"},
+		{"Observation", "_count=-1"},
+		{"Observation", "_offset=x"},
+		{"Patient", "identifier=system|"},
+	}
+
+	for _, tt := range tests {
+		query, _ := url.ParseQuery(tt.query)
+		if _, err := ParseSearch(tt.resourceType, query); err == nil {
+			t.Errorf("Expected %s?%s to be rejected", tt.resourceType, tt.query)
+		}
+	}
+}
+
+func TestParseSearch_PatientReference(t *testing.T) {
+	for _, value := range []string{"7", "Patient/7", "https://ehr.example.org/fhir/Patient/7"} {
+		search, err := ParseSearch("Observation", url.Values{"patient": {value}})
+		if err != nil || search.PatientID == nil || *search.PatientID != 7 {
+			t.Errorf("Expected %q to reference patient 7, got %v (%v)", value, search, err)
+		}
+	}
+}
+
+func TestParseSearch_Dates(t *testing.T) {
+	date := func(value string) time.Time {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t.Fatalf("Invalid test date %q", value)
+		}
+		return parsed
+	}
+
+	tests := []struct {
+		values []string
+		from   string
+		to     string
+	}{
+		{[]string{"2026"}, "2026-01-01T00:00:00Z", "2027-01-01T00:00:00Z"},
+		{[]string{"2026-03"}, "2026-03-01T00:00:00Z", "2026-04-01T00:00:00Z"},
+		{[]string{"eq2026-03-01"}, "2026-03-01T00:00:00Z", "2026-03-02T00:00:00Z"},
+		{[]string{"ge2026-03-01"}, "2026-03-01T00:00:00Z", ""},
+		{[]string{"gt2026-03-01"}, "2026-03-02T00:00:00Z", ""},
+		{[]string{"le2026-03-01"}, "", "2026-03-02T00:00:00Z"},
+		{[]string{"lt2026-03-01"}, "", "2026-03-01T00:00:00Z"},
+		{[]string{"ge2026-03-01T10:30+02:00"}, "2026-03-01T08:30:00Z", ""},
+		{[]string{"lt2026-03-01T10:30:15Z"}, "", "2026-03-01T10:30:15Z"},
+		{[]string{"ge2026-01", "lt2026-03", "le2026-06"}, "2026-01-01T00:00:00Z", "2026-03-01T00:00:00Z"},
+		{[]string{"ge2026-03", "lt2026-01"}, "2026-03-01T00:00:00Z", "2026-03-01T00:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		search, err := ParseSearch("DiagnosticReport", url.Values{"date": tt.values})
+		if err != nil {
+			t.Errorf("%v: ParseSearch failed: %v", tt.values, err)
+			continue
+		}
+
+		if (tt.from == "") != (search.From == nil) || (search.From != nil && !search.From.Equal(date(tt.from))) {
+			t.Errorf("%v: expected from %q, got %v", tt.values, tt.from, search.From)
+		}
+		if (tt.to == "") != (search.To == nil) || (search.To != nil && !search.To.Equal(date(tt.to))) {
+			t.Errorf("%v: expected to %q, got %v", tt.values, tt.to, search.To)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"backend/internal/database"
+	"backend/internal/inference"
+	"backend/internal/storage"
+)
+
+// Database checks that a connection to PostgreSQL can be made
+func Database(db *sql.DB) Check {
+	return Check{
+		Name:   "database",
+		Probes: []Probe{Ready, Startup},
+		Run:    db.PingContext,
+	}
+}
+
+// Migrations checks that every migration this build knows of is applied,
+// so the instance does not serve against an older schema
+func Migrations(migrator *database.Migrator) Check {
+	return Check{
+		Name:   "migrations",
+		Probes: []Probe{Ready, Startup},
+		Run: func(ctx context.Context) error {
+			pending, err := migrator.Pending(ctx)
+			if err != nil {
+				return err
+			}
+			if pending > 0 {
+				return fmt.Errorf("%d migrations pending", pending)
+			}
+			return nil
+		},
+	}
+}
+
+// Inference checks the model server. It is optional: only inference jobs
+// need it, and they wait in the queue until it recovers.
+func Inference(client inference.ModelClient) Check {
+	return Check{
+		Name:     "inference",
+		Probes:   []Probe{Ready},
+		Run:      func(ctx context.Context) error { return inference.Check(ctx, client) },
+		Optional: true,
+	}
+}
+
+// BlobStore checks the store holding uploaded slides
+func BlobStore(store storage.BlobStore) Check {
+	return Check{
+		Name:   "blob_store",
+		Probes: []Probe{Ready},
+		Run:    func(ctx context.Context) error { return storage.Check(ctx, store) },
+	}
+}
@@ -0,0 +1,206 @@
+// Package health runs the named dependency checks behind the liveness,
+// readiness and startup probes. Each check has its own timeout and its
+// result is cached briefly, so frequent probes from several sources cost
+// one round trip per dependency.
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Probe names the question a group of checks answers
+type Probe string
+
+const (
+	// Live asks whether the process is healthy enough to keep running
+	Live Probe = "live"
+	// Ready asks whether the instance should receive traffic
+	Ready Probe = "ready"
+	// Startup asks whether the instance has finished starting
+	Startup Probe = "startup"
+)
+
+// Defaults for checks that do not set their own
+const (
+	DefaultTimeout  = 2 * time.Second
+	DefaultCacheFor = 5 * time.Second
+)
+
+// Status is the outcome of a check or probe
+type Status string
+
+const (
+	StatusOK Status = "ok"
+	// StatusDegraded means only optional checks failed
+	StatusDegraded Status = "degraded"
+	StatusFailed   Status = "failed"
+)
+
+// Check is a named dependency check
+type Check struct {
+	Name string
+
+	// Probes lists the probes the check belongs to
+	Probes []Probe
+
+	// Run reports the dependency's health, returning promptly once ctx is
+	// done
+	Run func(ctx context.Context) error
+
+	// Timeout bounds each run; zero uses DefaultTimeout
+	Timeout time.Duration
+
+	// CacheFor is how long a result is reused; zero uses DefaultCacheFor
+	CacheFor time.Duration
+
+	// Optional checks are reported but do not fail their probes, for
+	// dependencies only some features need
+	Optional bool
+}
+
+// Result is the outcome of one check
+type Result struct {
+	Name      string        `json:"name"`
+	Status    Status        `json:"status"`
+	Optional  bool          `json:"optional,omitempty"`
+	Error     string        `json:"error,omitempty"`
+	Duration  time.Duration `json:"-"`
+	CheckedAt time.Time     `json:"checked_at"`
+}
+
+// Report is the outcome of a probe: StatusOK, StatusDegraded when only
+// optional checks failed, or StatusFailed
+type Report struct {
+	Status Status   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+// entry caches the latest result of a check. Its lock is held while the
+// check runs, so concurrent probes wait for one run rather than starting
+// their own.
+type entry struct {
+	check Check
+
+	mu      sync.Mutex
+	result  Result
+	expires time.Time
+}
+
+// Registry holds the checks for every probe
+type Registry struct {
+	mu     sync.RWMutex
+	checks []*entry
+
+	// now is replaceable for tests
+	now func() time.Time
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{now: time.Now}
+}
+
+// Register adds a check. Checks run in registration order in reports.
+func (r *Registry) Register(check Check) {
+	if check.Timeout <= 0 {
+		check.Timeout = DefaultTimeout
+	}
+	if check.CacheFor <= 0 {
+		check.CacheFor = DefaultCacheFor
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, &entry{check: check})
+}
+
+// Run runs the probe's checks concurrently, reusing cached results. A probe
+// with no checks is healthy.
+func (r *Registry) Run(ctx context.Context, probe Probe) Report {
+	r.mu.RLock()
+	var entries []*entry
+	for _, e := range r.checks {
+		for _, p := range e.check.Probes {
+			if p == probe {
+				entries = append(entries, e)
+				break
+			}
+		}
+	}
+	r.mu.RUnlock()
+
+	report := Report{Status: StatusOK, Checks: make([]Result, len(entries))}
+
+	var wg sync.WaitGroup
+	for i, e := range entries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = r.result(ctx, e)
+		}()
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		switch {
+		case result.Status == StatusOK:
+		case result.Optional:
+			if report.Status == StatusOK {
+				report.Status = StatusDegraded
+			}
+		default:
+			report.Status = StatusFailed
+		}
+	}
+	return report
+}
+
+// result returns the check's cached result, running it if that has expired
+func (r *Registry) result(ctx context.Context, e *entry) Result {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if r.now().Before(e.expires) {
+		return e.result
+	}
+
+	start := r.now()
+	err := run(ctx, e.check)
+
+	e.result = Result{
+		Name:      e.check.Name,
+		Status:    StatusOK,
+		Optional:  e.check.Optional,
+		Duration:  r.now().Sub(start),
+		CheckedAt: start.UTC(),
+	}
+	if err != nil {
+		e.result.Status = StatusFailed
+		e.result.Error = err.Error()
+	}
+	e.expires = start.Add(e.check.CacheFor)
+	return e.result
+}
+
+// run runs a check within its timeout. The probing request's cancellation
+// is ignored so a disconnecting caller cannot cache a failure, and a check
+// that overruns its timeout is abandoned rather than awaited.
+func run(ctx context.Context, check Check) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), check.Timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- check.Run(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("timed out after %s", check.Timeout)
+	}
+}
@@ -0,0 +1,104 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRegistry_Run(t *testing.T) {
+	checks := NewRegistry()
+	checks.Register(Check{Name: "database", Probes: []Probe{Ready, Startup}, Run: func(context.Context) error { return nil }})
+	checks.Register(Check{Name: "inference", Probes: []Probe{Ready}, Optional: true, Run: func(context.Context) error {
+		return errors.New("connection refused")
+	}})
+
+	report := checks.Run(context.Background(), Ready)
+	if report.Status != StatusDegraded {
+		t.Errorf("Expected an optional failure to degrade readiness, got %s", report.Status)
+	}
+	if len(report.Checks) != 2 || report.Checks[0].Name != "database" || report.Checks[1].Error != "connection refused" {
+		t.Errorf("Unexpected checks %+v", report.Checks)
+	}
+
+	if report := checks.Run(context.Background(), Startup); report.Status != StatusOK || len(report.Checks) != 1 {
+		t.Errorf("Expected only the database check at startup, got %+v", report)
+	}
+	if report := checks.Run(context.Background(), Live); report.Status != StatusOK || len(report.Checks) != 0 {
+		t.Errorf("Expected liveness without checks to pass, got %+v", report)
+	}
+}
+
+func TestRegistry_RequiredFailure(t *testing.T) {
+	checks := NewRegistry()
+	checks.Register(Check{Name: "blob_store", Probes: []Probe{Ready}, Run: func(context.Context) error {
+		return errors.New("403 AuthorizationFailure")
+	}})
+	checks.Register(Check{Name: "inference", Probes: []Probe{Ready}, Optional: true, Run: func(context.Context) error {
+		return errors.New("connection refused")
+	}})
+
+	if report := checks.Run(context.Background(), Ready); report.Status != StatusFailed {
+		t.Errorf("Expected a required failure to fail readiness, got %s", report.Status)
+	}
+}
+
+func TestRegistry_Cache(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	checks := NewRegistry()
+	checks.now = func() time.Time { return now }
+
+	runs := 0
+	checks.Register(Check{Name: "database", Probes: []Probe{Ready}, CacheFor: 10 * time.Second, Run: func(context.Context) error {
+		runs++
+		return nil
+	}})
+
+	checks.Run(context.Background(), Ready)
+	now = now.Add(5 * time.Second)
+	checks.Run(context.Background(), Ready)
+	if runs != 1 {
+		t.Errorf("Expected the cached result to be reused, got %d runs", runs)
+	}
+
+	now = now.Add(5 * time.Second)
+	report := checks.Run(context.Background(), Ready)
+	if runs != 2 || !report.Checks[0].CheckedAt.Equal(now) {
+		t.Errorf("Expected the check to rerun once its result expired, got %d runs at %v", runs, report.Checks[0].CheckedAt)
+	}
+}
+
+func TestRegistry_Timeout(t *testing.T) {
+	checks := NewRegistry()
+	release := make(chan struct{})
+	defer close(release)
+
+	checks.Register(Check{Name: "database", Probes: []Probe{Ready}, Timeout: 20 * time.Millisecond, Run: func(context.Context) error {
+		// Ignores its context, like a driver stuck on a dead connection
+		<-release
+		return nil
+	}})
+
+	start := time.Now()
+	report := checks.Run(context.Background(), Ready)
+	if report.Status != StatusFailed || report.Checks[0].Error != "timed out after 20ms" {
+		t.Errorf("Expected the check to time out, got %+v", report)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the probe to return at the timeout, took %s", elapsed)
+	}
+}
+
+func TestRegistry_IgnoresCallerCancellation(t *testing.T) {
+	checks := NewRegistry()
+	checks.Register(Check{Name: "database", Probes: []Probe{Ready}, Run: func(ctx context.Context) error {
+		return ctx.Err()
+	}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if report := checks.Run(ctx, Ready); report.Status != StatusOK {
+		t.Errorf("Expected a disconnected caller not to fail the check, got %+v", report)
+	}
+}
@@ -0,0 +1,98 @@
+package hl7
+
+import (
+	"fmt"
+	"time"
+)
+
+// Acknowledgment codes, MSA-1
+const (
+	AckAccept = "AA"
+	AckError  = "AE"
+	AckReject = "AR"
+)
+
+// HL7 table 0357 message error condition codes
+const (
+	ConditionSegmentSequence   = "100"
+	ConditionRequiredField     = "101"
+	ConditionDataType          = "102"
+	ConditionTableValue        = "103"
+	ConditionUnsupportedType   = "200"
+	ConditionUnsupportedEvent  = "201"
+	ConditionUnknownKey        = "204"
+	ConditionApplicationRecord = "206"
+	ConditionInternalError     = "207"
+)
+
+// Error is a problem with a received message, reported to the sender in the
+// ACK. Reject errors (AR) mean the message should not be resent as is;
+// others (AE) mean it was understood but could not be processed.
+type Error struct {
+	Reject    bool
+	Condition string
+	Message   string
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (HL7 condition %s)", e.Message, e.Condition)
+}
+
+// Code returns the acknowledgment code for the error
+func (e *Error) Code() string {
+	if e.Reject {
+		return AckReject
+	}
+	return AckError
+}
+
+// NewACK builds the acknowledgment of msg, sent by header. With a nil err
+// the message is accepted (AA); otherwise err is reported in MSA and ERR, as
+// AE unless it is a reject *Error.
+func NewACK(msg *Message, header Header, err error, controlID string, now time.Time) *Message {
+	ack := NewMessage(header, "ACK^"+msg.Segments[0].Get(9, 2)+"^ACK", controlID, now)
+
+	msa := ack.Add("MSA")
+	if err == nil {
+		msa.Set(1, AckAccept)
+		msa.Set(2, msg.ControlID())
+		return ack
+	}
+
+	hl7Err, ok := err.(*Error)
+	if !ok {
+		hl7Err = &Error{Condition: ConditionInternalError, Message: err.Error()}
+	}
+
+	msa.Set(1, hl7Err.Code())
+	msa.Set(2, msg.ControlID())
+	msa.Set(3, hl7Err.Message)
+
+	errSegment := ack.Add("ERR")
+	errSegment.Set(3, hl7Err.Condition, "", "HL70357")
+	errSegment.Set(4, "E")
+	errSegment.Set(8, hl7Err.Message)
+
+	return ack
+}
+
+// AckCode returns the MSA-1 acknowledgment code of an ACK, with the
+// original-mode commit codes (CA, CE, CR) mapped to AA, AE and AR
+func AckCode(ack *Message) string {
+	msa := ack.Segment("MSA")
+	if msa == nil {
+		return ""
+	}
+
+	switch code := msa.Get(1, 1); code {
+	case "CA":
+		return AckAccept
+	case "CE":
+		return AckError
+	case "CR":
+		return AckReject
+	default:
+		return code
+	}
+}
@@ -0,0 +1,128 @@
+package hl7
+
+import (
+	"context"
+	"time"
+
+	"backend/internal/config"
+	"backend/internal/logging"
+	"backend/internal/models"
+)
+
+// Store persists received messages and the orders they carry
+type Store interface {
+	// LogInbound records a received message. If the sender already sent
+	// one with the same control ID, the earlier entry is returned with
+	// created false.
+	LogInbound(ctx context.Context, msg *models.HL7Message) (*models.HL7Message, bool, error)
+	FinishInbound(ctx context.Context, id int64, status models.HL7MessageStatus, ackCode, ackBody, message string) error
+
+	// PlaceOrder creates the order, registering the patient if needed. An
+	// order already placed under the same placer order number is returned
+	// as it is.
+	PlaceOrder(ctx context.Context, req *OrderRequest) (*models.TestOrder, error)
+
+	// CancelOrder cancels the order with the request's placer order number
+	CancelOrder(ctx context.Context, req *OrderRequest) (*models.TestOrder, error)
+}
+
+// HeaderFromConfig returns the header of the messages this application
+// sends: from the configured sending application and facility, to the
+// configured receiver of results
+func HeaderFromConfig(cfg *config.Config) Header {
+	return Header{
+		SendingApplication:   cfg.HL7SendingApplication,
+		SendingFacility:      cfg.HL7SendingFacility,
+		ReceivingApplication: cfg.HL7ReceivingApplication,
+		ReceivingFacility:    cfg.HL7ReceivingFacility,
+	}
+}
+
+// Ingestor handles ORM^O01 messages received by the MLLP listener. Every
+// message is logged with the ACK sent for it. A message resent after it
+// was accepted, because the sender missed the ACK, is answered with the
+// same ACK without being processed again.
+type Ingestor struct {
+	store  Store
+	header Header
+	now    func() time.Time
+}
+
+// NewIngestor creates an ingestor. header names this application; messages
+// addressed to another receiving facility are rejected.
+func NewIngestor(store Store, header Header) *Ingestor {
+	return &Ingestor{store: store, header: header, now: time.Now}
+}
+
+// ServeHL7 implements Handler
+func (i *Ingestor) ServeHL7(ctx context.Context, msg *Message) *Message {
+	header := msg.Header()
+
+	entry, created, err := i.store.LogInbound(ctx, &models.HL7Message{
+		MessageType:        msg.Type(),
+		ControlID:          msg.ControlID(),
+		SendingApplication: header.SendingApplication,
+		SendingFacility:    header.SendingFacility,
+		Body:               string(msg.Bytes()),
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to log HL7 message", "control_id", msg.ControlID(),
+			"sending_facility", header.SendingFacility, "error", err)
+		return i.ack(msg, &Error{Condition: ConditionInternalError, Message: "message could not be stored; resend later"})
+	}
+
+	if !created && entry.Status == models.HL7Accepted {
+		if ack, err := Parse([]byte(entry.AckBody)); err == nil {
+			return ack
+		}
+	}
+
+	processErr := i.process(ctx, msg)
+	ack := i.ack(msg, processErr)
+
+	status, message := models.HL7Accepted, ""
+	if processErr != nil {
+		status, message = models.HL7Rejected, processErr.Error()
+		logging.FromContext(ctx).Warn("Rejected HL7 message", "control_id", msg.ControlID(),
+			"sending_facility", header.SendingFacility, "error", processErr)
+	}
+	if err := i.store.FinishInbound(ctx, entry.ID, status, AckCode(ack), string(ack.Bytes()), message); err != nil {
+		logging.FromContext(ctx).Error("Failed to record HL7 ACK", "control_id", msg.ControlID(), "error", err)
+	}
+
+	return ack
+}
+
+// process applies the orders in msg
+func (i *Ingestor) process(ctx context.Context, msg *Message) error {
+	if facility := msg.Header().ReceivingFacility; facility != "" && i.header.SendingFacility != "" &&
+		facility != i.header.SendingFacility {
+		return &Error{Reject: true, Condition: ConditionTableValue, Message: "message is addressed to facility " + facility}
+	}
+
+	orders, err := ParseOrders(msg)
+	if err != nil {
+		return err
+	}
+
+	for j := range orders {
+		switch orders[j].Control {
+		case OrderNew:
+			_, err = i.store.PlaceOrder(ctx, &orders[j])
+		case OrderCancel:
+			_, err = i.store.CancelOrder(ctx, &orders[j])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ack builds the ACK for msg, sent as this application to the sender
+func (i *Ingestor) ack(msg *Message, err error) *Message {
+	header := msg.Header().Reply()
+	header.SendingApplication = i.header.SendingApplication
+	header.SendingFacility = i.header.SendingFacility
+	return NewACK(msg, header, err, NewControlID(), i.now())
+}
@@ -0,0 +1,225 @@
+package hl7
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+)
+
+// fakeStore is an in-memory Store
+type fakeStore struct {
+	mu        sync.Mutex
+	messages  []*models.HL7Message
+	placed    []OrderRequest
+	cancelled []OrderRequest
+	cancelErr error
+}
+
+func (s *fakeStore) LogInbound(_ context.Context, msg *models.HL7Message) (*models.HL7Message, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.messages {
+		if existing.SendingApplication == msg.SendingApplication &&
+			existing.SendingFacility == msg.SendingFacility && existing.ControlID == msg.ControlID {
+			copied := *existing
+			return &copied, false, nil
+		}
+	}
+
+	logged := *msg
+	logged.ID = int64(len(s.messages) + 1)
+	logged.Status = models.HL7Received
+	s.messages = append(s.messages, &logged)
+	copied := logged
+	return &copied, true, nil
+}
+
+func (s *fakeStore) FinishInbound(_ context.Context, id int64, status models.HL7MessageStatus, ackCode, ackBody, message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msg := s.messages[id-1]
+	msg.Status, msg.AckCode, msg.AckBody, msg.Error = status, ackCode, ackBody, message
+	return nil
+}
+
+func (s *fakeStore) PlaceOrder(ctx context.Context, req *OrderRequest) (*models.TestOrder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.placed = append(s.placed, *req)
+	return &models.TestOrder{ID: len(s.placed), TestType: req.TestType}, nil
+}
+
+func (s *fakeStore) CancelOrder(ctx context.Context, req *OrderRequest) (*models.TestOrder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancelErr != nil {
+		return nil, s.cancelErr
+	}
+	s.cancelled = append(s.cancelled, *req)
+	return &models.TestOrder{ID: 1, Status: models.TestOrderCancelled}, nil
+}
+
+// startServer serves handler on a loopback port and returns a connected
+// client
+func startServer(t *testing.T, handler Handler) *Client {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	server := NewServer(handler, time.Minute)
+	go server.Serve(l)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	})
+
+	client, err := Dial(context.Background(), l.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+func send(t *testing.T, client *Client, data string) *Message {
+	t.Helper()
+
+	msg, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ack, err := client.Send(ctx, msg)
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	return ack
+}
+
+var testHeader = Header{SendingApplication: "ALPHAPATH", SendingFacility: "ALPHAPATH_LAB"}
+
+func TestIngestor_NewOrder(t *testing.T) {
+	store := &fakeStore{}
+	client := startServer(t, NewIngestor(store, testHeader))
+
+	ack := send(t, client, sampleORM)
+	if AckCode(ack) != AckAccept {
+		t.Fatalf("Expected AA, got %q", ack.Bytes())
+	}
+	if header := ack.Header(); header.SendingApplication != "ALPHAPATH" || header.ReceivingApplication != "EPIC" {
+		t.Errorf("Expected the ACK to be addressed back to the sender, got %+v", header)
+	}
+
+	if len(store.placed) != 1 {
+		t.Fatalf("Expected 1 order to be placed, got %d", len(store.placed))
+	}
+	order := store.placed[0]
+	if order.PlacerOrderNumber != "PO-1001" || order.PlacerSystem != "EPIC" || order.TestType != "alphapath_panel" {
+		t.Errorf("Unexpected order %+v", order)
+	}
+	if store.messages[0].Status != models.HL7Accepted || store.messages[0].AckCode != AckAccept {
+		t.Errorf("Expected the message to be logged as accepted, got %+v", store.messages[0])
+	}
+}
+
+func TestIngestor_Resend(t *testing.T) {
+	store := &fakeStore{}
+	client := startServer(t, NewIngestor(store, testHeader))
+
+	first := send(t, client, sampleORM)
+	second := send(t, client, sampleORM)
+
+	if len(store.placed) != 1 {
+		t.Errorf("Expected a resent message not to be processed again, got %d orders", len(store.placed))
+	}
+	if string(first.Bytes()) != string(second.Bytes()) {
+		t.Errorf("Expected the same ACK to be replayed, got %q and %q", first.Bytes(), second.Bytes())
+	}
+}
+
+func TestIngestor_Cancel(t *testing.T) {
+	store := &fakeStore{}
+	client := startServer(t, NewIngestor(store, testHeader))
+
+	cancel := strings.Replace(strings.Replace(sampleORM, "ORC|NW|", "ORC|CA|", 1), "MSG00001", "MSG00002", 1)
+	if ack := send(t, client, cancel); AckCode(ack) != AckAccept {
+		t.Fatalf("Expected AA, got %q", ack.Bytes())
+	}
+	if len(store.cancelled) != 1 || store.cancelled[0].PlacerOrderNumber != "PO-1001" {
+		t.Errorf("Expected PO-1001 to be cancelled, got %+v", store.cancelled)
+	}
+
+	store.cancelErr = &Error{Condition: ConditionUnknownKey, Message: "unknown placer order number PO-1001"}
+	cancel = strings.Replace(cancel, "MSG00002", "MSG00003", 1)
+	ack := send(t, client, cancel)
+	if AckCode(ack) != AckError || ack.Segment("ERR").Get(3, 1) != ConditionUnknownKey {
+		t.Errorf("Expected AE with condition %s, got %q", ConditionUnknownKey, ack.Bytes())
+	}
+	if store.messages[1].Status != models.HL7Rejected {
+		t.Errorf("Expected the message to be logged as rejected, got %s", store.messages[1].Status)
+	}
+}
+
+func TestIngestor_Rejects(t *testing.T) {
+	tests := []struct {
+		name      string
+		message   string
+		condition string
+	}{
+		{"unsupported type", strings.Replace(sampleORM, "ORM^O01", "ADT^A01", 1), ConditionUnsupportedType},
+		{"unsupported event", strings.Replace(sampleORM, "ORM^O01", "ORM^O02", 1), ConditionUnsupportedEvent},
+		{"other facility", strings.Replace(sampleORM, "ALPHAPATH_LAB", "OTHER_LAB", 1), ConditionTableValue},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeStore{}
+			client := startServer(t, NewIngestor(store, testHeader))
+
+			ack := send(t, client, tt.message)
+			if AckCode(ack) != AckReject || ack.Segment("ERR").Get(3, 1) != tt.condition {
+				t.Errorf("Expected AR with condition %s, got %q", tt.condition, ack.Bytes())
+			}
+			if len(store.placed) != 0 {
+				t.Errorf("Expected no orders to be placed")
+			}
+		})
+	}
+}
+
+func TestServer_Unparseable(t *testing.T) {
+	client := startServer(t, HandlerFunc(func(ctx context.Context, msg *Message) *Message {
+		t.Errorf("Handler should not be called for an unparseable message")
+		return nil
+	}))
+
+	if err := WriteFrame(client.conn, []byte("not hl7")); err != nil {
+		t.Fatalf("WriteFrame failed: %v", err)
+	}
+	data, err := ReadFrame(client.reader, DefaultMaxMessageSize)
+	if err != nil {
+		t.Fatalf("ReadFrame failed: %v", err)
+	}
+
+	ack, err := Parse(data)
+	if err != nil || AckCode(ack) != AckReject {
+		t.Errorf("Expected an AR, got %q (%v)", data, err)
+	}
+}
@@ -0,0 +1,427 @@
+// Package hl7 reads and writes HL7 v2 messages, carries them over MLLP and
+// maps ORM^O01 orders and ORU^R01 results to test orders and results.
+package hl7
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version is the HL7 version written in the messages this package builds
+const Version = "2.5.1"
+
+// timestampLayout is the HL7 DTM format used for message timestamps
+const timestampLayout = "20060102150405-0700"
+
+// Delimiters are the separators declared in MSH-1 and MSH-2
+type Delimiters struct {
+	Field        byte
+	Component    byte
+	Repetition   byte
+	Escape       byte
+	Subcomponent byte
+}
+
+// DefaultDelimiters are the separators recommended by the standard, and the
+// ones used for messages built here
+var DefaultDelimiters = Delimiters{Field: '|', Component: '^', Repetition: '~', Escape: '\\', Subcomponent: '&'}
+
+// encoding returns the MSH-2 encoding characters
+func (d Delimiters) encoding() string {
+	return string([]byte{d.Component, d.Repetition, d.Escape, d.Subcomponent})
+}
+
+// Header names the sending and receiving application and facility of a
+// message, MSH-3 to MSH-6
+type Header struct {
+	SendingApplication   string
+	SendingFacility      string
+	ReceivingApplication string
+	ReceivingFacility    string
+}
+
+// Reply returns the header of a reply to a message with this header
+func (h Header) Reply() Header {
+	return Header{
+		SendingApplication:   h.ReceivingApplication,
+		SendingFacility:      h.ReceivingFacility,
+		ReceivingApplication: h.SendingApplication,
+		ReceivingFacility:    h.SendingFacility,
+	}
+}
+
+// Message is a parsed HL7 v2 message: an MSH segment followed by others
+type Message struct {
+	Delimiters Delimiters
+	Segments   []*Segment
+}
+
+// Segment is one segment of a message. Fields are stored encoded and split
+// into components when read, so unrecognized content is written back as it
+// was received.
+type Segment struct {
+	Name string
+
+	// fields[n] is field n of the segment in its encoded form; fields[0]
+	// is unused. For MSH, fields[1] and fields[2] hold the delimiters.
+	fields []string
+	delims *Delimiters
+}
+
+// Field is one repetition of a field, split into components and their
+// subcomponents. Values are unescaped.
+type Field [][]string
+
+// NewField builds a field from plain component values
+func NewField(components ...string) Field {
+	field := make(Field, len(components))
+	for i, component := range components {
+		field[i] = []string{component}
+	}
+	return field
+}
+
+// Component returns component n (1-based), or its first subcomponent if it
+// has several, or "" if absent
+func (f Field) Component(n int) string {
+	return f.Subcomponent(n, 1)
+}
+
+// Subcomponent returns subcomponent s of component c (both 1-based)
+func (f Field) Subcomponent(c, s int) string {
+	if c < 1 || c > len(f) || s < 1 || s > len(f[c-1]) {
+		return ""
+	}
+	return f[c-1][s-1]
+}
+
+// Parse parses an HL7 v2 message. Segments may be separated by carriage
+// returns or, as some senders do, by line feeds.
+func Parse(data []byte) (*Message, error) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\r")
+	text = strings.ReplaceAll(text, "\n", "\r")
+	text = strings.Trim(text, "\r")
+
+	if len(text) < 8 || !strings.HasPrefix(text, "MSH") {
+		return nil, errors.New("message does not start with an MSH segment")
+	}
+
+	msg := &Message{}
+	msg.Delimiters.Field = text[3]
+	encoding, _, _ := strings.Cut(text[4:], string(msg.Delimiters.Field))
+	if len(encoding) < 3 {
+		return nil, fmt.Errorf("invalid encoding characters %q", encoding)
+	}
+	msg.Delimiters.Component = encoding[0]
+	msg.Delimiters.Repetition = encoding[1]
+	msg.Delimiters.Escape = encoding[2]
+	msg.Delimiters.Subcomponent = DefaultDelimiters.Subcomponent
+	if len(encoding) > 3 {
+		msg.Delimiters.Subcomponent = encoding[3]
+	}
+
+	for _, line := range strings.Split(text, "\r") {
+		if line == "" {
+			continue
+		}
+
+		fields := strings.Split(line, string(msg.Delimiters.Field))
+		name := fields[0]
+		if len(name) != 3 {
+			return nil, fmt.Errorf("invalid segment name %q", name)
+		}
+
+		segment := &Segment{Name: name, delims: &msg.Delimiters}
+		if name == "MSH" {
+			// MSH-1 is the field separator itself, so MSH-2 is the first
+			// value after it
+			segment.fields = append([]string{"", string(msg.Delimiters.Field)}, fields[1:]...)
+		} else {
+			segment.fields = append([]string{""}, fields[1:]...)
+		}
+		msg.Segments = append(msg.Segments, segment)
+	}
+
+	if msg.Segments[0].Name != "MSH" {
+		return nil, errors.New("message does not start with an MSH segment")
+	}
+	return msg, nil
+}
+
+// NewMessage starts a message of the given type, such as "ORU^R01^ORU_R01",
+// with an MSH segment addressed by header
+func NewMessage(header Header, messageType, controlID string, now time.Time) *Message {
+	msg := &Message{Delimiters: DefaultDelimiters}
+
+	msh := msg.Add("MSH")
+	msh.fields = []string{"", string(msg.Delimiters.Field), msg.Delimiters.encoding()}
+	msh.Set(3, header.SendingApplication)
+	msh.Set(4, header.SendingFacility)
+	msh.Set(5, header.ReceivingApplication)
+	msh.Set(6, header.ReceivingFacility)
+	msh.Set(7, FormatTimestamp(now))
+	msh.Set(9, strings.Split(messageType, "^")...)
+	msh.Set(10, controlID)
+	msh.Set(11, "P")
+	msh.Set(12, Version)
+
+	return msg
+}
+
+// NewControlID returns a random message control ID of 20 characters, the
+// longest MSH-10 allows
+func NewControlID() string {
+	b := make([]byte, 10)
+	rand.Read(b) // never fails since Go 1.24
+	return hex.EncodeToString(b)
+}
+
+// Add appends an empty segment
+func (m *Message) Add(name string) *Segment {
+	segment := &Segment{Name: name, fields: []string{""}, delims: &m.Delimiters}
+	m.Segments = append(m.Segments, segment)
+	return segment
+}
+
+// Segment returns the first segment with the given name, or nil
+func (m *Message) Segment(name string) *Segment {
+	for _, segment := range m.Segments {
+		if segment.Name == name {
+			return segment
+		}
+	}
+	return nil
+}
+
+// All returns every segment with the given name, in order
+func (m *Message) All(name string) []*Segment {
+	var segments []*Segment
+	for _, segment := range m.Segments {
+		if segment.Name == name {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+// Header returns the sender and receiver named in MSH
+func (m *Message) Header() Header {
+	msh := m.Segments[0]
+	return Header{
+		SendingApplication:   msh.Get(3, 1),
+		SendingFacility:      msh.Get(4, 1),
+		ReceivingApplication: msh.Get(5, 1),
+		ReceivingFacility:    msh.Get(6, 1),
+	}
+}
+
+// Type returns the message type and trigger event, such as "ORM^O01"
+func (m *Message) Type() string {
+	msh := m.Segments[0]
+	return msh.Get(9, 1) + "^" + msh.Get(9, 2)
+}
+
+// ControlID returns MSH-10, which the receiver echoes in its ACK
+func (m *Message) ControlID() string {
+	return m.Segments[0].Get(10, 1)
+}
+
+// Bytes encodes the message, terminating each segment with a carriage return
+func (m *Message) Bytes() []byte {
+	var b strings.Builder
+	for _, segment := range m.Segments {
+		b.WriteString(segment.Name)
+		start := 1
+		if segment.Name == "MSH" {
+			// The field separator is written once, not as a field
+			start = 2
+		}
+		for _, field := range segment.fields[start:] {
+			b.WriteByte(m.Delimiters.Field)
+			b.WriteString(field)
+		}
+		b.WriteByte('\r')
+	}
+	return []byte(b.String())
+}
+
+// String returns the encoded message with segments on separate lines, for logs
+func (m *Message) String() string {
+	return strings.ReplaceAll(strings.TrimSuffix(string(m.Bytes()), "\r"), "\r", "\n")
+}
+
+// raw returns field n in its encoded form
+func (s *Segment) raw(n int) string {
+	if n < 1 || n >= len(s.fields) {
+		return ""
+	}
+	return s.fields[n]
+}
+
+// Get returns component c (1-based) of the first repetition of field n
+func (s *Segment) Get(n, c int) string {
+	if s.Name == "MSH" && n <= 2 {
+		return s.raw(n)
+	}
+	repeats := s.Repeats(n)
+	if len(repeats) == 0 {
+		return ""
+	}
+	return repeats[0].Component(c)
+}
+
+// Repeats returns every repetition of field n
+func (s *Segment) Repeats(n int) []Field {
+	value := s.raw(n)
+	if value == "" {
+		return nil
+	}
+
+	d := s.delims
+	var repeats []Field
+	for _, repetition := range strings.Split(value, string(d.Repetition)) {
+		var field Field
+		for _, component := range strings.Split(repetition, string(d.Component)) {
+			subcomponents := strings.Split(component, string(d.Subcomponent))
+			for i := range subcomponents {
+				subcomponents[i] = unescape(subcomponents[i], d)
+			}
+			field = append(field, subcomponents)
+		}
+		repeats = append(repeats, field)
+	}
+	return repeats
+}
+
+// Set replaces field n with a single repetition of the given components
+func (s *Segment) Set(n int, components ...string) {
+	s.SetRepeats(n, NewField(components...))
+}
+
+// SetRepeats replaces field n with the given repetitions
+func (s *Segment) SetRepeats(n int, repeats ...Field) {
+	for len(s.fields) <= n {
+		s.fields = append(s.fields, "")
+	}
+
+	d := s.delims
+	encoded := make([]string, len(repeats))
+	for i, field := range repeats {
+		components := make([]string, len(field))
+		for j, subcomponents := range field {
+			escaped := make([]string, len(subcomponents))
+			for k, value := range subcomponents {
+				escaped[k] = escape(value, d)
+			}
+			components[j] = strings.Join(escaped, string(d.Subcomponent))
+		}
+		encoded[i] = strings.TrimRight(strings.Join(components, string(d.Component)), string(d.Component))
+	}
+	s.fields[n] = strings.Join(encoded, string(d.Repetition))
+}
+
+// escape replaces delimiters in a value with HL7 escape sequences
+func escape(value string, d *Delimiters) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case d.Escape:
+			b.WriteString(string(d.Escape) + "E" + string(d.Escape))
+		case d.Field:
+			b.WriteString(string(d.Escape) + "F" + string(d.Escape))
+		case d.Component:
+			b.WriteString(string(d.Escape) + "S" + string(d.Escape))
+		case d.Repetition:
+			b.WriteString(string(d.Escape) + "R" + string(d.Escape))
+		case d.Subcomponent:
+			b.WriteString(string(d.Escape) + "T" + string(d.Escape))
+		case '\r', '\n':
+			b.WriteString(string(d.Escape) + ".br" + string(d.Escape))
+			if c == '\r' && i+1 < len(value) && value[i+1] == '\n' {
+				i++
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// unescape reverses escape. Hex sequences (\Xhh\) are decoded; other
+// formatting sequences are dropped.
+func unescape(value string, d *Delimiters) string {
+	if strings.IndexByte(value, d.Escape) < 0 {
+		return value
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != d.Escape {
+			b.WriteByte(value[i])
+			continue
+		}
+
+		end := strings.IndexByte(value[i+1:], d.Escape)
+		if end < 0 {
+			// Unterminated: keep the rest as it is
+			b.WriteString(value[i:])
+			break
+		}
+		sequence := value[i+1 : i+1+end]
+		i += end + 1
+
+		switch {
+		case sequence == "E":
+			b.WriteByte(d.Escape)
+		case sequence == "F":
+			b.WriteByte(d.Field)
+		case sequence == "S":
+			b.WriteByte(d.Component)
+		case sequence == "R":
+			b.WriteByte(d.Repetition)
+		case sequence == "T":
+			b.WriteByte(d.Subcomponent)
+		case sequence == ".br":
+			b.WriteByte('\n')
+		case strings.HasPrefix(sequence, "X"):
+			if decoded, err := hex.DecodeString(sequence[1:]); err == nil {
+				b.Write(decoded)
+			}
+		}
+	}
+	return b.String()
+}
+
+// FormatTimestamp formats t as an HL7 DTM with seconds and zone offset
+func FormatTimestamp(t time.Time) string {
+	return t.Format(timestampLayout)
+}
+
+// ParseTimestamp parses an HL7 DTM of any precision from year to
+// fractional seconds, with or without a zone offset. Timestamps without an
+// offset are read as UTC.
+func ParseTimestamp(value string) (time.Time, error) {
+	digits, offset := value, ""
+	if i := strings.IndexAny(value, "+-"); i >= 0 {
+		digits, offset = value[:i], value[i:]
+	}
+	digits, _, _ = strings.Cut(digits, ".")
+
+	if len(digits) < 4 || len(digits) > 14 || len(digits)%2 != 0 {
+		return time.Time{}, fmt.Errorf("invalid HL7 timestamp %q", value)
+	}
+	if _, err := strconv.ParseUint(digits, 10, 64); err != nil {
+		return time.Time{}, fmt.Errorf("invalid HL7 timestamp %q", value)
+	}
+
+	layout := "20060102150405"[:len(digits)]
+	if offset != "" {
+		return time.Parse(layout+"-0700", digits+offset)
+	}
+	return time.Parse(layout, digits)
+}
@@ -0,0 +1,151 @@
+package hl7
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// sampleORM is an order as a hospital system might send it, with line feeds
+// between segments and a custom subcomponent in PID-3
+const sampleORM = "MSH|^~\\&|EPIC|GENERAL_HOSP|ALPHAPATH|ALPHAPATH_LAB|20260301083000||ORM^O01|MSG00001|P|2.3\n" +
+	"PID|1||H100^^^GENERAL_HOSP^MR~999-88-7777^^^SSA^SS||O'Brien^Siobhan^M||19751102|F|||12 Main St^^Springfield^IL^62701||555-0100^^^siobhan@example.org\n" +
+	"ORC|NW|PO-1001^EPIC|||||^^^^^S\n" +
+	"OBR|1|PO-1001^EPIC||alphapath_panel^AlphaPath Panel^L|||||||||Suspected lesion \\T\\ margin check\n" +
+	"NTE|1||Biopsy from left breast\n"
+
+func TestParse(t *testing.T) {
+	msg, err := Parse([]byte(sampleORM))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if len(msg.Segments) != 5 {
+		t.Fatalf("Expected 5 segments, got %d", len(msg.Segments))
+	}
+	if msg.Type() != "ORM^O01" || msg.ControlID() != "MSG00001" {
+		t.Errorf("Expected ORM^O01 MSG00001, got %s %s", msg.Type(), msg.ControlID())
+	}
+
+	header := msg.Header()
+	if header.SendingApplication != "EPIC" || header.ReceivingFacility != "ALPHAPATH_LAB" {
+		t.Errorf("Unexpected header %+v", header)
+	}
+
+	msh := msg.Segment("MSH")
+	if msh.Get(1, 1) != "|" || msh.Get(2, 1) != "^~\\&" {
+		t.Errorf("Expected MSH-1 and MSH-2 to hold the delimiters, got %q and %q", msh.Get(1, 1), msh.Get(2, 1))
+	}
+
+	pid := msg.Segment("PID")
+	identifiers := pid.Repeats(3)
+	if len(identifiers) != 2 || identifiers[1].Component(1) != "999-88-7777" || identifiers[1].Component(4) != "SSA" {
+		t.Errorf("Unexpected identifiers %v", identifiers)
+	}
+	if pid.Get(5, 1) != "O'Brien" || pid.Get(5, 2) != "Siobhan" {
+		t.Errorf("Unexpected name %q %q", pid.Get(5, 1), pid.Get(5, 2))
+	}
+
+	if notes := msg.Segment("OBR").Get(13, 1); notes != "Suspected lesion & margin check" {
+		t.Errorf("Expected the escaped subcomponent separator to be decoded, got %q", notes)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, data := range []string{"", "PID|1||H100", "MSH|^", "MSH|^~\\&|A\rP|1"} {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("Expected %q to be rejected", data)
+		}
+	}
+}
+
+func TestMessage_RoundTrip(t *testing.T) {
+	msg, err := Parse([]byte(sampleORM))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	encoded := string(msg.Bytes())
+	if encoded != strings.ReplaceAll(sampleORM, "\n", "\r") {
+		t.Errorf("Expected the message to be written back unchanged, got %q", encoded)
+	}
+}
+
+func TestSegment_SetEscapes(t *testing.T) {
+	now := time.Date(2026, 3, 1, 8, 30, 0, 0, time.UTC)
+	msg := NewMessage(Header{SendingApplication: "ALPHAPATH"}, "ORU^R01^ORU_R01", "CTRL1", now)
+
+	nte := msg.Add("NTE")
+	nte.Set(3, "a|b^c~d\\e&f\nline two")
+
+	parsed, err := Parse(msg.Bytes())
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if got := parsed.Segment("NTE").Get(3, 1); got != "a|b^c~d\\e&f\nline two" {
+		t.Errorf("Expected the value to survive escaping, got %q", got)
+	}
+	if got := parsed.Segment("MSH").Get(7, 1); got != "20260301083000+0000" {
+		t.Errorf("Unexpected timestamp %q", got)
+	}
+	if parsed.Type() != "ORU^R01" || parsed.Segment("MSH").Get(12, 1) != Version {
+		t.Errorf("Unexpected MSH in %q", parsed.Bytes())
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Time
+	}{
+		{"1975", time.Date(1975, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"19751102", time.Date(1975, 11, 2, 0, 0, 0, 0, time.UTC)},
+		{"202603010830", time.Date(2026, 3, 1, 8, 30, 0, 0, time.UTC)},
+		{"20260301083015.1234-0500", time.Date(2026, 3, 1, 13, 30, 15, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseTimestamp(tt.value)
+		if err != nil || !got.Equal(tt.expected) {
+			t.Errorf("ParseTimestamp(%q): expected %v, got %v (%v)", tt.value, tt.expected, got, err)
+		}
+	}
+
+	for _, value := range []string{"", "197", "19751102x", "1975110"} {
+		if _, err := ParseTimestamp(value); err == nil {
+			t.Errorf("Expected %q to be rejected", value)
+		}
+	}
+}
+
+func TestNewACK(t *testing.T) {
+	msg, _ := Parse([]byte(sampleORM))
+	header := msg.Header().Reply()
+	now := time.Now()
+
+	ack := NewACK(msg, header, nil, "ACK1", now)
+	if AckCode(ack) != AckAccept || ack.Segment("MSA").Get(2, 1) != "MSG00001" {
+		t.Errorf("Expected AA for MSG00001, got %q", ack.Bytes())
+	}
+	if ack.Type() != "ACK^O01" || ack.Header().ReceivingApplication != "EPIC" {
+		t.Errorf("Unexpected ACK header in %q", ack.Bytes())
+	}
+
+	ack = NewACK(msg, header, &Error{Reject: true, Condition: ConditionUnsupportedType, Message: "nope"}, "ACK2", now)
+	if AckCode(ack) != AckReject || ack.Segment("ERR").Get(3, 1) != ConditionUnsupportedType {
+		t.Errorf("Expected AR with ERR, got %q", ack.Bytes())
+	}
+
+	ack = NewACK(msg, header, errTest, "ACK3", now)
+	if AckCode(ack) != AckError || ack.Segment("ERR").Get(3, 1) != ConditionInternalError {
+		t.Errorf("Expected AE for an internal error, got %q", ack.Bytes())
+	}
+}
+
+// errTest is an error that is not an *Error
+var errTest = &testError{}
+
+type testError struct{}
+
+func (*testError) Error() string { return "database unavailable" }
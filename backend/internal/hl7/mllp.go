@@ -0,0 +1,353 @@
+package hl7
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"backend/internal/audit"
+)
+
+// MLLP frame delimiters: each message is sent as <VT> message <FS><CR>
+const (
+	startBlock     = 0x0b
+	endBlock       = 0x1c
+	carriageReturn = 0x0d
+)
+
+// DefaultMaxMessageSize bounds the messages read from a connection
+const DefaultMaxMessageSize = 1 << 20
+
+// ErrServerClosed is returned by Serve after Shutdown
+var ErrServerClosed = errors.New("hl7: server closed")
+
+// ErrFrameTooLarge is returned when a frame exceeds the size limit
+var ErrFrameTooLarge = errors.New("hl7: frame too large")
+
+// ReadFrame reads one MLLP frame and returns the message inside it. Bytes
+// before the start block are discarded.
+func ReadFrame(r *bufio.Reader, maxSize int) ([]byte, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if b == startBlock {
+			break
+		}
+	}
+
+	var payload []byte
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+
+		if b == endBlock {
+			next, err := r.ReadByte()
+			if err != nil {
+				if err == io.EOF {
+					err = io.ErrUnexpectedEOF
+				}
+				return nil, err
+			}
+			if next == carriageReturn {
+				return payload, nil
+			}
+			payload = append(payload, b)
+			b = next
+		}
+
+		if len(payload) >= maxSize {
+			return nil, ErrFrameTooLarge
+		}
+		payload = append(payload, b)
+	}
+}
+
+// WriteFrame writes a message as one MLLP frame
+func WriteFrame(w io.Writer, payload []byte) error {
+	frame := make([]byte, 0, len(payload)+3)
+	frame = append(frame, startBlock)
+	frame = append(frame, payload...)
+	frame = append(frame, endBlock, carriageReturn)
+	_, err := w.Write(frame)
+	return err
+}
+
+// Handler processes a received message and returns the ACK to send back
+type Handler interface {
+	ServeHL7(ctx context.Context, msg *Message) *Message
+}
+
+// HandlerFunc adapts a function to a Handler
+type HandlerFunc func(ctx context.Context, msg *Message) *Message
+
+// ServeHL7 calls f(ctx, msg)
+func (f HandlerFunc) ServeHL7(ctx context.Context, msg *Message) *Message {
+	return f(ctx, msg)
+}
+
+// Server is an MLLP listener. Messages on a connection are handled one at a
+// time, each answered with its ACK before the next is read, as MLLP senders
+// expect.
+type Server struct {
+	Handler Handler
+
+	// IdleTimeout closes connections that send nothing for this long
+	IdleTimeout time.Duration
+
+	// MaxMessageSize bounds each message; DefaultMaxMessageSize if zero
+	MaxMessageSize int
+
+	// now supplies timestamps for the ACKs of unparseable messages
+	now func() time.Time
+
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	closed    bool
+	wg        sync.WaitGroup
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+// NewServer creates an MLLP server that passes messages to handler
+func NewServer(handler Handler, idleTimeout time.Duration) *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Server{
+		Handler:     handler,
+		IdleTimeout: idleTimeout,
+		now:         time.Now,
+		listeners:   make(map[net.Listener]struct{}),
+		conns:       make(map[net.Conn]struct{}),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+// ListenAndServe listens on addr and serves connections until Shutdown
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts connections on l until Shutdown, which makes it return
+// ErrServerClosed
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		l.Close()
+		return ErrServerClosed
+	}
+	s.listeners[l] = struct{}{}
+	s.mu.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			delete(s.listeners, l)
+			s.mu.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+			return err
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			continue
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go s.serveConn(conn)
+	}
+}
+
+// Shutdown stops accepting connections and closes idle ones, then waits for
+// messages being handled to be answered. If ctx expires first, remaining
+// connections are closed and ctx's error is returned.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	for l := range s.listeners {
+		l.Close()
+	}
+	// Unblock connections waiting for their next message. One whose message
+	// is being handled sends its ACK first; the sender resends anything
+	// left unacknowledged.
+	for conn := range s.conns {
+		conn.SetReadDeadline(time.Now())
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		s.cancel()
+		return nil
+	case <-ctx.Done():
+		s.cancel()
+		s.mu.Lock()
+		for conn := range s.conns {
+			conn.Close()
+		}
+		s.mu.Unlock()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// serveConn reads and answers messages until the peer disconnects, the
+// connection idles out or the server shuts down
+func (s *Server) serveConn(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	maxSize := s.MaxMessageSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxMessageSize
+	}
+
+	host, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+	if err != nil {
+		host = conn.RemoteAddr().String()
+	}
+
+	reader := bufio.NewReader(conn)
+	for {
+		if s.IdleTimeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(s.IdleTimeout))
+		}
+
+		s.mu.Lock()
+		closed := s.closed
+		s.mu.Unlock()
+		if closed {
+			return
+		}
+
+		payload, err := ReadFrame(reader, maxSize)
+		if err != nil {
+			if err != io.EOF && !errors.Is(err, net.ErrClosed) && !isTimeout(err) {
+				slog.Warn("HL7 connection failed", "host", host, "error", err)
+			}
+			return
+		}
+		conn.SetReadDeadline(time.Time{})
+
+		var ack *Message
+		msg, err := Parse(payload)
+		if err != nil {
+			ack = rejectUnparseable(err, s.now())
+		} else {
+			ctx := audit.WithRequest(s.ctx, audit.Request{ID: msg.ControlID(), IP: host})
+			ack = s.Handler.ServeHL7(ctx, msg)
+		}
+
+		if err := WriteFrame(conn, ack.Bytes()); err != nil {
+			slog.Warn("Failed to send HL7 ACK", "host", host, "error", err)
+			return
+		}
+	}
+}
+
+// rejectUnparseable builds an AR for a message whose MSH could not be read,
+// so there is no control ID to acknowledge
+func rejectUnparseable(err error, now time.Time) *Message {
+	ack := NewMessage(Header{}, "ACK", NewControlID(), now)
+	msa := ack.Add("MSA")
+	msa.Set(1, AckReject)
+	msa.Set(3, err.Error())
+	return ack
+}
+
+// isTimeout reports whether err is a network timeout
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// Client sends messages to an MLLP listener over one connection
+type Client struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// Dial connects to the MLLP listener at addr
+func Dial(ctx context.Context, addr string) (*Client, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{conn: conn, reader: bufio.NewReader(conn)}, nil
+}
+
+// Send sends msg and waits for its ACK until ctx expires. An ACK for a
+// different control ID is an error; the connection should then be closed.
+func (c *Client) Send(ctx context.Context, msg *Message) (*Message, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		c.conn.SetDeadline(deadline)
+		defer c.conn.SetDeadline(time.Time{})
+	}
+
+	if err := WriteFrame(c.conn, msg.Bytes()); err != nil {
+		return nil, err
+	}
+
+	payload, err := ReadFrame(c.reader, DefaultMaxMessageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ACK: %w", err)
+	}
+
+	ack, err := Parse(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ACK: %w", err)
+	}
+
+	msa := ack.Segment("MSA")
+	if msa == nil {
+		return nil, errors.New("ACK has no MSA segment")
+	}
+	if id := msa.Get(2, 1); id != msg.ControlID() {
+		return nil, fmt.Errorf("ACK is for message %q, not %q", id, msg.ControlID())
+	}
+
+	return ack, nil
+}
+
+// Close closes the connection
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,47 @@
+package hl7
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestFrames(t *testing.T) {
+	var buf bytes.Buffer
+	buf.WriteString("noise")
+	for _, payload := range []string{"MSH|first", "MSH|second\x1cwith an end block"} {
+		if err := WriteFrame(&buf, []byte(payload)); err != nil {
+			t.Fatalf("WriteFrame failed: %v", err)
+		}
+	}
+
+	r := bufio.NewReader(&buf)
+	for _, expected := range []string{"MSH|first", "MSH|second\x1cwith an end block"} {
+		payload, err := ReadFrame(r, DefaultMaxMessageSize)
+		if err != nil {
+			t.Fatalf("ReadFrame failed: %v", err)
+		}
+		if string(payload) != expected {
+			t.Errorf("Expected %q, got %q", expected, payload)
+		}
+	}
+
+	if _, err := ReadFrame(r, DefaultMaxMessageSize); err != io.EOF {
+		t.Errorf("Expected io.EOF after the last frame, got %v", err)
+	}
+}
+
+func TestReadFrame_Errors(t *testing.T) {
+	if _, err := ReadFrame(bufio.NewReader(strings.NewReader("\x0bMSH|truncated")), DefaultMaxMessageSize); err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected io.ErrUnexpectedEOF, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	WriteFrame(&buf, []byte(strings.Repeat("x", 100)))
+	if _, err := ReadFrame(bufio.NewReader(&buf), 50); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("Expected ErrFrameTooLarge, got %v", err)
+	}
+}
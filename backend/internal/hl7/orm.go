@@ -0,0 +1,197 @@
+package hl7
+
+import (
+	"strings"
+
+	"backend/internal/models"
+)
+
+// Order control codes, ORC-1
+const (
+	OrderNew    = "NW"
+	OrderCancel = "CA"
+)
+
+// OrderRequest is one order from an ORM^O01 message: an ORC segment with
+// its OBR, and the patient the message is about
+type OrderRequest struct {
+	// Control is ORC-1, OrderNew or OrderCancel
+	Control string
+
+	// PlacerSystem and PlacerOrderNumber identify the order in the system
+	// that placed it (ORC-2, or OBR-2)
+	PlacerSystem      string
+	PlacerOrderNumber string
+
+	TestType string
+	Priority models.TestOrderPriority
+	Notes    string
+	Patient  models.Patient
+}
+
+// ParseOrders extracts the orders from an ORM^O01 message. Patient
+// identifiers without an assigning authority (PID-3.4) are attributed to
+// the sending facility.
+func ParseOrders(msg *Message) ([]OrderRequest, error) {
+	msh := msg.Segments[0]
+	if msh.Get(9, 1) != "ORM" {
+		return nil, &Error{Reject: true, Condition: ConditionUnsupportedType, Message: "unsupported message type " + msg.Type()}
+	}
+	if msh.Get(9, 2) != "O01" {
+		return nil, &Error{Reject: true, Condition: ConditionUnsupportedEvent, Message: "unsupported trigger event " + msg.Type()}
+	}
+
+	header := msg.Header()
+	pid := msg.Segment("PID")
+	if pid == nil {
+		return nil, &Error{Condition: ConditionSegmentSequence, Message: "PID segment is required"}
+	}
+
+	patient, err := parsePatient(pid, header)
+	if err != nil {
+		return nil, err
+	}
+
+	var orders []OrderRequest
+	var current *OrderRequest
+	for _, segment := range msg.Segments {
+		switch segment.Name {
+		case "ORC":
+			orders = append(orders, OrderRequest{
+				Control:           segment.Get(1, 1),
+				PlacerSystem:      segment.Get(2, 2),
+				PlacerOrderNumber: segment.Get(2, 1),
+				Priority:          parsePriority(segment.Get(7, 6)),
+				Patient:           patient,
+			})
+			current = &orders[len(orders)-1]
+
+		case "OBR":
+			if current == nil {
+				return nil, &Error{Condition: ConditionSegmentSequence, Message: "OBR segment before ORC"}
+			}
+			if current.PlacerOrderNumber == "" {
+				current.PlacerOrderNumber = segment.Get(2, 1)
+				current.PlacerSystem = segment.Get(2, 2)
+			}
+			current.TestType = segment.Get(4, 1)
+			if priority := segment.Get(27, 6); priority != "" {
+				current.Priority = parsePriority(priority)
+			}
+			current.Notes = segment.Get(13, 1)
+
+		case "NTE":
+			if current != nil {
+				current.Notes = strings.TrimSpace(current.Notes + "\n" + segment.Get(3, 1))
+			}
+		}
+	}
+
+	if len(orders) == 0 {
+		return nil, &Error{Condition: ConditionSegmentSequence, Message: "ORC segment is required"}
+	}
+
+	for i := range orders {
+		order := &orders[i]
+		if order.Control != OrderNew && order.Control != OrderCancel {
+			return nil, &Error{Condition: ConditionTableValue, Message: "unsupported order control " + order.Control}
+		}
+		if order.PlacerOrderNumber == "" {
+			return nil, &Error{Condition: ConditionRequiredField, Message: "placer order number (ORC-2) is required"}
+		}
+		if order.PlacerSystem == "" {
+			order.PlacerSystem = header.SendingApplication + "^" + header.SendingFacility
+		}
+		if order.Control == OrderNew && order.TestType == "" {
+			return nil, &Error{Condition: ConditionRequiredField, Message: "universal service identifier (OBR-4) is required"}
+		}
+	}
+
+	return orders, nil
+}
+
+// parsePatient reads the demographics and identifiers in PID
+func parsePatient(pid *Segment, header Header) (models.Patient, error) {
+	patient := models.Patient{
+		FamilyName:  pid.Get(5, 1),
+		GivenName:   pid.Get(5, 2),
+		Sex:         parseSex(pid.Get(8, 1)),
+		Identifiers: []models.PatientIdentifier{},
+	}
+
+	for _, identifier := range pid.Repeats(3) {
+		value := identifier.Component(1)
+		if value == "" {
+			continue
+		}
+		system := identifier.Component(4)
+		if system == "" {
+			system = header.SendingFacility
+		}
+		patient.Identifiers = append(patient.Identifiers, models.PatientIdentifier{System: system, Value: value})
+	}
+
+	if len(patient.Identifiers) == 0 {
+		return patient, &Error{Condition: ConditionRequiredField, Message: "patient identifier (PID-3) is required"}
+	}
+
+	if dob := pid.Get(7, 1); dob != "" {
+		t, err := ParseTimestamp(dob)
+		if err != nil || len(dob) < 8 {
+			return patient, &Error{Condition: ConditionDataType, Message: "invalid date of birth (PID-7)"}
+		}
+		patient.BirthDate = t.Format(models.BirthDateLayout)
+	}
+
+	if addresses := pid.Repeats(11); len(addresses) > 0 {
+		var parts []string
+		for c := 1; c <= 6; c++ {
+			if part := addresses[0].Component(c); part != "" {
+				parts = append(parts, part)
+			}
+		}
+		patient.Address = strings.Join(parts, ", ")
+	}
+
+	for _, telecom := range pid.Repeats(13) {
+		if telecom.Component(4) != "" && patient.Email == "" {
+			patient.Email = telecom.Component(4)
+		}
+		number := telecom.Component(1)
+		if number == "" {
+			number = strings.TrimSpace(telecom.Component(6) + " " + telecom.Component(7))
+		}
+		if number != "" && patient.Phone == "" {
+			patient.Phone = number
+		}
+	}
+
+	return patient, nil
+}
+
+// parseSex maps HL7 table 0001 administrative sex
+func parseSex(value string) models.PatientSex {
+	switch value {
+	case "F":
+		return models.PatientFemale
+	case "M":
+		return models.PatientMale
+	case "O", "A", "N":
+		return models.PatientOther
+	default:
+		return models.PatientSexUnknown
+	}
+}
+
+// parsePriority maps the priority component of a timing/quantity field:
+// S (stat), A (ASAP) and R (routine). Others are treated as routine.
+func parsePriority(value string) models.TestOrderPriority {
+	switch value {
+	case "S":
+		return models.TestOrderStat
+	case "A":
+		return models.TestOrderUrgent
+	default:
+		return models.TestOrderRoutine
+	}
+}
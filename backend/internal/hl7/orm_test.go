@@ -0,0 +1,38 @@
+package hl7
+
+import (
+	"testing"
+
+	"backend/internal/models"
+)
+
+func TestParseOrders_Patient(t *testing.T) {
+	msg, _ := Parse([]byte(sampleORM))
+
+	orders, err := ParseOrders(msg)
+	if err != nil {
+		t.Fatalf("ParseOrders failed: %v", err)
+	}
+	if len(orders) != 1 {
+		t.Fatalf("Expected 1 order, got %d", len(orders))
+	}
+
+	order := orders[0]
+	if order.Priority != models.TestOrderStat {
+		t.Errorf("Expected stat priority, got %s", order.Priority)
+	}
+	if order.Notes != "Suspected lesion & margin check\nBiopsy from left breast" {
+		t.Errorf("Unexpected notes %q", order.Notes)
+	}
+
+	patient := order.Patient
+	if patient.FamilyName != "O'Brien" || patient.BirthDate != "1975-11-02" || patient.Sex != models.PatientFemale {
+		t.Errorf("Unexpected patient %+v", patient)
+	}
+	if patient.Phone != "555-0100" || patient.Email != "siobhan@example.org" || patient.Address != "12 Main St, Springfield, IL, 62701" {
+		t.Errorf("Unexpected contact details %+v", patient)
+	}
+	if len(patient.Identifiers) != 2 || patient.Identifiers[1].System != "SSA" {
+		t.Errorf("Unexpected identifiers %+v", patient.Identifiers)
+	}
+}
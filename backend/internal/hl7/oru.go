@@ -0,0 +1,104 @@
+package hl7
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/models"
+)
+
+// Result statuses, OBR-25 and OBX-11
+const (
+	resultFinal     = "F"
+	resultCorrected = "C"
+)
+
+// BuildResult builds the ORU^R01 message reporting a verified result.
+// Amendments (version 2 and later) are reported as corrections.
+func BuildResult(header Header, controlID string, now time.Time, patient *models.Patient,
+	order *models.TestOrder, result *models.TestResult) *Message {
+	msg := NewMessage(header, "ORU^R01^ORU_R01", controlID, now)
+
+	status := resultFinal
+	if result.Version > 1 {
+		status = resultCorrected
+	}
+
+	pid := msg.Add("PID")
+	pid.Set(1, "1")
+	identifiers := []Field{NewField(patient.MRN, "", "", header.SendingFacility, "MR")}
+	for _, identifier := range patient.Identifiers {
+		identifiers = append(identifiers, NewField(identifier.Value, "", "", identifier.System))
+	}
+	pid.SetRepeats(3, identifiers...)
+	pid.Set(5, patient.FamilyName, patient.GivenName)
+	if birthDate, err := time.Parse(models.BirthDateLayout, patient.BirthDate); err == nil {
+		pid.Set(7, birthDate.Format("20060102"))
+	}
+	pid.Set(8, formatSex(patient.Sex))
+
+	orc := msg.Add("ORC")
+	orc.Set(1, "RE")
+	orc.Set(2, order.PlacerOrderNumber, order.PlacerSystem)
+	orc.Set(3, order.AccessionNumber, header.SendingFacility)
+
+	obr := msg.Add("OBR")
+	obr.Set(1, "1")
+	obr.Set(2, order.PlacerOrderNumber, order.PlacerSystem)
+	obr.Set(3, order.AccessionNumber, header.SendingFacility)
+	obr.Set(4, order.TestType)
+	if order.CollectedAt != nil {
+		obr.Set(7, FormatTimestamp(*order.CollectedAt))
+	}
+	if result.VerifiedAt != nil {
+		obr.Set(22, FormatTimestamp(*result.VerifiedAt))
+	}
+	obr.Set(25, status)
+	if result.ReportingPathologistID != nil {
+		obr.Set(32, strconv.Itoa(*result.ReportingPathologistID))
+	}
+
+	for i, finding := range result.Findings {
+		obx := msg.Add("OBX")
+		obx.Set(1, strconv.Itoa(i+1))
+		obx.Set(2, valueType(finding.Value))
+		obx.Set(3, finding.Code, finding.Name)
+		obx.Set(5, finding.Value)
+		obx.Set(6, finding.Unit)
+		obx.Set(8, finding.Flag)
+		obx.Set(11, status)
+	}
+
+	if result.Interpretation != "" {
+		for i, line := range strings.Split(result.Interpretation, "\n") {
+			nte := msg.Add("NTE")
+			nte.Set(1, strconv.Itoa(i+1))
+			nte.Set(3, strings.TrimRight(line, "\r"))
+		}
+	}
+
+	return msg
+}
+
+// valueType returns the OBX-2 value type: NM for numbers, ST otherwise
+func valueType(value string) string {
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return "NM"
+	}
+	return "ST"
+}
+
+// formatSex maps administrative sex to HL7 table 0001
+func formatSex(sex models.PatientSex) string {
+	switch sex {
+	case models.PatientFemale:
+		return "F"
+	case models.PatientMale:
+		return "M"
+	case models.PatientOther:
+		return "O"
+	default:
+		return "U"
+	}
+}
@@ -0,0 +1,86 @@
+package hl7
+
+import (
+	"testing"
+	"time"
+
+	"backend/internal/models"
+)
+
+func TestBuildResult(t *testing.T) {
+	verifiedAt := time.Date(2026, 3, 2, 15, 4, 5, 0, time.UTC)
+	pathologist := 7
+
+	patient := &models.Patient{
+		MRN:         "P00000042",
+		FamilyName:  "O'Brien",
+		GivenName:   "Siobhan",
+		BirthDate:   "1975-11-02",
+		Sex:         models.PatientFemale,
+		Identifiers: []models.PatientIdentifier{{System: "GENERAL_HOSP", Value: "H100"}},
+	}
+	order := &models.TestOrder{
+		ID:                12,
+		AccessionNumber:   "ACC-12",
+		PlacerSystem:      "EPIC",
+		PlacerOrderNumber: "PO-1001",
+		TestType:          "alphapath_panel",
+	}
+	result := &models.TestResult{
+		TestOrderID: 12,
+		Version:     1,
+		Findings: []models.Finding{
+			{Code: "ki67", Name: "Ki-67 index", Value: "12.5", Unit: "%", Flag: "H"},
+			{Code: "grade", Name: "Grade", Value: "G2"},
+		},
+		Interpretation:         "Moderately differentiated.\nMargins clear.",
+		ReportingPathologistID: &pathologist,
+		VerifiedAt:             &verifiedAt,
+	}
+
+	msg := BuildResult(testHeader, "CTRL1", verifiedAt, patient, order, result)
+
+	parsed, err := Parse(msg.Bytes())
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if parsed.Type() != "ORU^R01" || parsed.ControlID() != "CTRL1" {
+		t.Errorf("Unexpected message %s %s", parsed.Type(), parsed.ControlID())
+	}
+
+	pid := parsed.Segment("PID")
+	identifiers := pid.Repeats(3)
+	if len(identifiers) != 2 || identifiers[0].Component(1) != "P00000042" || identifiers[0].Component(5) != "MR" ||
+		identifiers[1].Component(1) != "H100" || identifiers[1].Component(4) != "GENERAL_HOSP" {
+		t.Errorf("Unexpected identifiers %v", identifiers)
+	}
+	if pid.Get(7, 1) != "19751102" || pid.Get(8, 1) != "F" {
+		t.Errorf("Unexpected demographics in %q", pid.Get(7, 1)+"|"+pid.Get(8, 1))
+	}
+
+	obr := parsed.Segment("OBR")
+	if obr.Get(2, 1) != "PO-1001" || obr.Get(3, 1) != "ACC-12" || obr.Get(25, 1) != "F" || obr.Get(32, 1) != "7" {
+		t.Errorf("Unexpected OBR in %q", parsed.Bytes())
+	}
+
+	obx := parsed.All("OBX")
+	if len(obx) != 2 {
+		t.Fatalf("Expected 2 OBX segments, got %d", len(obx))
+	}
+	if obx[0].Get(2, 1) != "NM" || obx[0].Get(3, 1) != "ki67" || obx[0].Get(5, 1) != "12.5" || obx[0].Get(8, 1) != "H" {
+		t.Errorf("Unexpected numeric OBX in %q", parsed.Bytes())
+	}
+	if obx[1].Get(2, 1) != "ST" || obx[1].Get(5, 1) != "G2" {
+		t.Errorf("Unexpected text OBX in %q", parsed.Bytes())
+	}
+
+	if notes := parsed.All("NTE"); len(notes) != 2 || notes[1].Get(3, 1) != "Margins clear." {
+		t.Errorf("Expected one NTE per line of the interpretation, got %q", parsed.Bytes())
+	}
+
+	result.Version = 2
+	amended := BuildResult(testHeader, "CTRL2", verifiedAt, patient, order, result)
+	if amended.Segment("OBR").Get(25, 1) != "C" {
+		t.Errorf("Expected an amendment to be reported as a correction")
+	}
+}
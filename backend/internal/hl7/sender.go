@@ -0,0 +1,271 @@
+package hl7
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"backend/internal/config"
+	"backend/internal/models"
+)
+
+// maxSendErrorLength bounds the error text stored on an undelivered message
+const maxSendErrorLength = 2000
+
+// Outbox queues ORU^R01 messages for verified results. The Sender delivers
+// them.
+type Outbox struct {
+	messages *models.HL7MessageRepository
+	orders   *models.TestOrderRepository
+	patients *models.PatientRepository
+	header   Header
+	now      func() time.Time
+}
+
+// NewOutbox creates an outbox whose messages are sent as header
+func NewOutbox(db *sql.DB, header Header) *Outbox {
+	return &Outbox{
+		messages: models.NewHL7MessageRepository(db),
+		orders:   models.NewTestOrderRepository(db),
+		patients: models.NewPatientRepository(db),
+		header:   header,
+		now:      time.Now,
+	}
+}
+
+// QueueResult queues an ORU^R01 reporting a verified result
+func (o *Outbox) QueueResult(ctx context.Context, result *models.TestResult) error {
+	order, err := o.orders.GetByID(ctx, result.TestOrderID)
+	if err != nil {
+		return err
+	}
+	if order == nil {
+		return fmt.Errorf("test order %d not found", result.TestOrderID)
+	}
+
+	var patient *models.Patient
+	if order.PatientID != nil {
+		patient, err = o.patients.GetByID(ctx, *order.PatientID)
+	} else {
+		patient, err = o.patients.GetByMRN(ctx, order.PatientMRN)
+	}
+	if err != nil {
+		return err
+	}
+	if patient == nil {
+		patient = &models.Patient{MRN: order.PatientMRN}
+	}
+
+	msg := BuildResult(o.header, NewControlID(), o.now(), patient, order, result)
+
+	return o.messages.Enqueue(ctx, &models.HL7Message{
+		MessageType:        "ORU^R01",
+		ControlID:          msg.ControlID(),
+		SendingApplication: o.header.SendingApplication,
+		SendingFacility:    o.header.SendingFacility,
+		Body:               string(msg.Bytes()),
+		TestOrderID:        &order.ID,
+	})
+}
+
+// OutboundStore is the subset of models.HL7MessageRepository used by the Sender
+type OutboundStore interface {
+	ClaimOutbound(ctx context.Context, lease time.Duration) (*models.HL7Message, error)
+	Acknowledged(ctx context.Context, msg *models.HL7Message, ackCode, ackBody string) error
+	Undelivered(ctx context.Context, msg *models.HL7Message, message string, retryAfter time.Duration) error
+}
+
+// SenderOptions configures the Sender
+type SenderOptions struct {
+	Addr         string
+	AckTimeout   time.Duration
+	PollInterval time.Duration
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+}
+
+// SenderOptionsFromConfig builds sender options from application configuration
+func SenderOptionsFromConfig(cfg *config.Config) SenderOptions {
+	return SenderOptions{
+		Addr:         cfg.HL7OutboundAddr,
+		AckTimeout:   cfg.HL7AckTimeout,
+		PollInterval: cfg.HL7PollInterval,
+		MaxAttempts:  cfg.HL7MaxAttempts,
+		BaseBackoff:  cfg.HL7RetryBackoff,
+		MaxBackoff:   cfg.HL7RetryMaxBackoff,
+	}
+}
+
+// backoff returns the delay before retrying after the given attempt,
+// doubling from BaseBackoff up to MaxBackoff
+func (o SenderOptions) backoff(attempt int) time.Duration {
+	delay := o.BaseBackoff
+	for i := 1; i < attempt && delay < o.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > o.MaxBackoff {
+		delay = o.MaxBackoff
+	}
+	return delay
+}
+
+// Sender delivers queued outbound messages over MLLP, one at a time and
+// oldest first, keeping the connection open between them.
+// Messages that cannot be delivered are retried with backoff; ones the
+// receiver rejects are not.
+type Sender struct {
+	store OutboundStore
+	opts  SenderOptions
+
+	client *Client
+	stop   context.CancelFunc
+	done   chan struct{}
+}
+
+// NewSender creates a sender
+func NewSender(store OutboundStore, opts SenderOptions) *Sender {
+	return &Sender{store: store, opts: opts}
+}
+
+// Start begins delivering messages until Stop is called
+func (s *Sender) Start() {
+	var ctx context.Context
+	ctx, s.stop = context.WithCancel(context.Background())
+	s.done = make(chan struct{})
+
+	go s.run(ctx)
+	slog.Info("Sending HL7 messages", "addr", s.opts.Addr)
+}
+
+// Stop stops the sender after the message in flight, if any, is
+// acknowledged or times out
+func (s *Sender) Stop(ctx context.Context) error {
+	if s.stop == nil {
+		return nil
+	}
+	s.stop()
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run delivers messages until ctx is cancelled, sleeping for the poll
+// interval whenever none is due
+func (s *Sender) run(ctx context.Context) {
+	defer close(s.done)
+	defer s.disconnect()
+
+	for ctx.Err() == nil {
+		sent, err := s.sendOnce(ctx)
+		if err != nil {
+			slog.Error("HL7 sender failed", "error", err)
+		}
+		if sent && err == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(s.opts.PollInterval):
+		}
+	}
+}
+
+// sendOnce claims a single message and delivers it, reporting whether a
+// message was due
+func (s *Sender) sendOnce(ctx context.Context) (bool, error) {
+	msg, err := s.store.ClaimOutbound(ctx, s.opts.AckTimeout+30*time.Second)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim message: %w", err)
+	}
+	if msg == nil {
+		return false, nil
+	}
+
+	// A claimed message is recorded even if the sender is stopping
+	storeCtx := context.WithoutCancel(ctx)
+
+	parsed, err := Parse([]byte(msg.Body))
+	if err != nil {
+		// Retrying cannot fix a message we built wrongly
+		if err := s.store.Undelivered(storeCtx, msg, err.Error(), 0); err != nil {
+			return true, fmt.Errorf("failed to record message %d failure: %w", msg.ID, err)
+		}
+		return true, nil
+	}
+
+	ack, err := s.deliver(ctx, parsed)
+	if err != nil {
+		var retryAfter time.Duration
+		if msg.Attempts < s.opts.MaxAttempts {
+			retryAfter = s.opts.backoff(msg.Attempts)
+		}
+
+		message := err.Error()
+		if len(message) > maxSendErrorLength {
+			message = message[:maxSendErrorLength]
+		}
+
+		if err := s.store.Undelivered(storeCtx, msg, message, retryAfter); err != nil {
+			return true, fmt.Errorf("failed to record message %d failure: %w", msg.ID, err)
+		}
+		if retryAfter == 0 {
+			slog.Warn("HL7 message not delivered", "message_id", msg.ID, "control_id", msg.ControlID,
+				"attempts", msg.Attempts, "error", message)
+		}
+		// Back off before the next message too; the receiver is likely down
+		return false, nil
+	}
+
+	code := AckCode(ack)
+	if err := s.store.Acknowledged(storeCtx, msg, code, string(ack.Bytes())); err != nil {
+		return true, fmt.Errorf("failed to record ACK for message %d: %w", msg.ID, err)
+	}
+	if code != AckAccept {
+		slog.Warn("HL7 message rejected", "message_id", msg.ID, "control_id", msg.ControlID,
+			"ack_code", code, "error", ack.Segment("MSA").Get(3, 1))
+	}
+	return true, nil
+}
+
+// deliver sends msg over the open connection, connecting first if needed.
+// The connection is dropped after any error so the next attempt starts
+// clean.
+func (s *Sender) deliver(ctx context.Context, msg *Message) (*Message, error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.opts.AckTimeout)
+	defer cancel()
+
+	if s.client == nil {
+		client, err := Dial(ctx, s.opts.Addr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to %s: %w", s.opts.Addr, err)
+		}
+		s.client = client
+	}
+
+	ack, err := s.client.Send(ctx, msg)
+	if err != nil {
+		s.disconnect()
+		if errors.Is(err, context.DeadlineExceeded) || isTimeout(err) {
+			return nil, fmt.Errorf("no ACK within %s", s.opts.AckTimeout)
+		}
+		return nil, err
+	}
+	return ack, nil
+}
+
+// disconnect closes the connection, if open
+func (s *Sender) disconnect() {
+	if s.client != nil {
+		s.client.Close()
+		s.client = nil
+	}
+}
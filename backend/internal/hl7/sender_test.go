@@ -0,0 +1,167 @@
+package hl7
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+)
+
+// fakeOutbound is an in-memory OutboundStore
+type fakeOutbound struct {
+	mu       sync.Mutex
+	queue    []*models.HL7Message
+	attempts map[int64][]string
+	done     chan *models.HL7Message
+}
+
+func (s *fakeOutbound) ClaimOutbound(_ context.Context, lease time.Duration) (*models.HL7Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, msg := range s.queue {
+		if msg.Status == models.HL7Pending && (msg.NextAttemptAt == nil || !msg.NextAttemptAt.After(time.Now())) {
+			next := time.Now().Add(lease)
+			msg.Attempts++
+			msg.NextAttemptAt = &next
+			copied := *msg
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *fakeOutbound) Acknowledged(_ context.Context, msg *models.HL7Message, ackCode, ackBody string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.attempts[msg.ID] = append(s.attempts[msg.ID], ackCode)
+	stored := s.find(msg.ID)
+	stored.AckCode = ackCode
+	stored.Status = models.HL7Rejected
+	if ackCode == AckAccept {
+		stored.Status = models.HL7Accepted
+	}
+	s.done <- stored
+	return nil
+}
+
+func (s *fakeOutbound) Undelivered(_ context.Context, msg *models.HL7Message, message string, retryAfter time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.attempts[msg.ID] = append(s.attempts[msg.ID], message)
+	stored := s.find(msg.ID)
+	stored.Error = message
+	if retryAfter == 0 {
+		stored.Status = models.HL7Failed
+		s.done <- stored
+		return nil
+	}
+	next := time.Now().Add(retryAfter)
+	stored.NextAttemptAt = &next
+	return nil
+}
+
+func (s *fakeOutbound) find(id int64) *models.HL7Message {
+	for _, msg := range s.queue {
+		if msg.ID == id {
+			return msg
+		}
+	}
+	return nil
+}
+
+func newFakeOutbound(bodies ...string) *fakeOutbound {
+	store := &fakeOutbound{attempts: map[int64][]string{}, done: make(chan *models.HL7Message, len(bodies))}
+	for i, body := range bodies {
+		store.queue = append(store.queue, &models.HL7Message{
+			ID:     int64(i + 1),
+			Status: models.HL7Pending,
+			Body:   body,
+		})
+	}
+	return store
+}
+
+func TestSender_Deliver(t *testing.T) {
+	var mu sync.Mutex
+	var received []string
+	client := startServer(t, HandlerFunc(func(ctx context.Context, msg *Message) *Message {
+		mu.Lock()
+		received = append(received, msg.ControlID())
+		mu.Unlock()
+		return NewACK(msg, msg.Header().Reply(), nil, NewControlID(), time.Now())
+	}))
+	addr := client.conn.RemoteAddr().String()
+
+	now := time.Now()
+	first := NewMessage(testHeader, "ORU^R01^ORU_R01", "FIRST", now)
+	second := NewMessage(testHeader, "ORU^R01^ORU_R01", "SECOND", now)
+	store := newFakeOutbound(string(first.Bytes()), string(second.Bytes()))
+
+	sender := NewSender(store, SenderOptions{
+		Addr:         addr,
+		AckTimeout:   5 * time.Second,
+		PollInterval: 10 * time.Millisecond,
+		MaxAttempts:  3,
+		BaseBackoff:  time.Millisecond,
+		MaxBackoff:   time.Millisecond,
+	})
+	sender.Start()
+	defer sender.Stop(context.Background())
+
+	for i := 0; i < 2; i++ {
+		select {
+		case msg := <-store.done:
+			if msg.Status != models.HL7Accepted {
+				t.Errorf("Expected message %d to be accepted, got %s", msg.ID, msg.Status)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for delivery")
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 2 || received[0] != "FIRST" || received[1] != "SECOND" {
+		t.Errorf("Expected both messages in order, got %v", received)
+	}
+}
+
+func TestSender_GivesUp(t *testing.T) {
+	store := newFakeOutbound(string(NewMessage(testHeader, "ORU^R01^ORU_R01", "LOST", time.Now()).Bytes()))
+
+	sender := NewSender(store, SenderOptions{
+		Addr:         "127.0.0.1:1",
+		AckTimeout:   time.Second,
+		PollInterval: time.Millisecond,
+		MaxAttempts:  3,
+		BaseBackoff:  time.Millisecond,
+		MaxBackoff:   time.Millisecond,
+	})
+	sender.Start()
+	defer sender.Stop(context.Background())
+
+	select {
+	case msg := <-store.done:
+		if msg.Status != models.HL7Failed || msg.Attempts != 3 {
+			t.Errorf("Expected the message to fail after 3 attempts, got %s after %d", msg.Status, msg.Attempts)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for the sender to give up")
+	}
+}
+
+func TestSenderOptions_Backoff(t *testing.T) {
+	opts := SenderOptions{BaseBackoff: time.Second, MaxBackoff: 5 * time.Second}
+
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, want := range expected {
+		if got := opts.backoff(i + 1); got != want {
+			t.Errorf("backoff(%d): expected %s, got %s", i+1, want, got)
+		}
+	}
+}
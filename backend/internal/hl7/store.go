@@ -0,0 +1,152 @@
+package hl7
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"backend/internal/audit"
+	"backend/internal/models"
+)
+
+// dbStore is the Store backed by the application database
+type dbStore struct {
+	*models.HL7MessageRepository
+	db       *sql.DB
+	orders   *models.TestOrderRepository
+	patients *models.PatientRepository
+}
+
+// NewStore creates a Store backed by the application database. Orders and
+// patients it creates are recorded in the audit log as part of the change.
+func NewStore(db *sql.DB) Store {
+	return &dbStore{
+		HL7MessageRepository: models.NewHL7MessageRepository(db),
+		db:                   db,
+		orders:               models.NewTestOrderRepository(db),
+		patients:             models.NewPatientRepository(db),
+	}
+}
+
+// PlaceOrder implements Store
+func (s *dbStore) PlaceOrder(ctx context.Context, req *OrderRequest) (*models.TestOrder, error) {
+	existing, err := s.orders.GetByPlacer(ctx, req.PlacerSystem, req.PlacerOrderNumber)
+	if err != nil || existing != nil {
+		return existing, err
+	}
+
+	patient, err := s.resolvePatient(ctx, &req.Patient)
+	if err != nil {
+		return nil, err
+	}
+
+	order := &models.TestOrder{
+		PatientID:         &patient.ID,
+		PatientMRN:        patient.MRN,
+		PlacerSystem:      req.PlacerSystem,
+		PlacerOrderNumber: req.PlacerOrderNumber,
+		TestType:          req.TestType,
+		Priority:          req.Priority,
+		Notes:             req.Notes,
+	}
+	err = audit.Audited(ctx, s.db, func(tx *sql.Tx) (audit.Event, error) {
+		if err := models.NewTestOrderRepository(tx).Create(ctx, order); err != nil {
+			return audit.Event{}, err
+		}
+		return audit.Event{
+			Action:       "test_order.create",
+			ResourceType: "test_order",
+			ResourceID:   strconv.Itoa(order.ID),
+			After:        order,
+			Details:      map[string]interface{}{"source": "hl7"},
+		}, nil
+	})
+	if err != nil {
+		if errors.Is(err, models.ErrPlacerOrderExists) {
+			// Placed concurrently by a resent message
+			return s.orders.GetByPlacer(ctx, req.PlacerSystem, req.PlacerOrderNumber)
+		}
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// resolvePatient finds the registered patient holding one of the given
+// identifiers, or registers them
+func (s *dbStore) resolvePatient(ctx context.Context, patient *models.Patient) (*models.Patient, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		for _, identifier := range patient.Identifiers {
+			found, err := s.patients.GetByIdentifier(ctx, identifier.System, identifier.Value)
+			if err != nil || found != nil {
+				return found, err
+			}
+		}
+
+		created := *patient
+		created.Identifiers = append([]models.PatientIdentifier(nil), patient.Identifiers...)
+		err := audit.Audited(ctx, s.db, func(tx *sql.Tx) (audit.Event, error) {
+			if err := models.NewPatientRepository(tx).Create(ctx, &created); err != nil {
+				return audit.Event{}, err
+			}
+			return audit.Event{
+				Action:       "patient.create",
+				ResourceType: "patient",
+				ResourceID:   strconv.Itoa(created.ID),
+				After:        created,
+				Details:      map[string]interface{}{"source": "hl7"},
+			}, nil
+		})
+		if err == nil {
+			return &created, nil
+		}
+		if !errors.Is(err, models.ErrPatientConflict) {
+			return nil, err
+		}
+		// Registered concurrently; look again
+	}
+
+	return nil, errors.New("patient identifiers changed concurrently")
+}
+
+// CancelOrder implements Store
+func (s *dbStore) CancelOrder(ctx context.Context, req *OrderRequest) (*models.TestOrder, error) {
+	order, err := s.orders.GetByPlacer(ctx, req.PlacerSystem, req.PlacerOrderNumber)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, &Error{Condition: ConditionUnknownKey, Message: "unknown placer order number " + req.PlacerOrderNumber}
+	}
+
+	if order.Status == models.TestOrderCancelled {
+		return order, nil
+	}
+	if !order.Status.CanTransitionTo(models.TestOrderCancelled) {
+		return nil, &Error{Condition: ConditionApplicationRecord, Message: "order has been resulted and can no longer be cancelled"}
+	}
+
+	var cancelled *models.TestOrder
+	err = audit.Audited(ctx, s.db, func(tx *sql.Tx) (audit.Event, error) {
+		var err error
+		cancelled, err = models.NewTestOrderRepository(tx).UpdateStatus(ctx, order.ID, order.Status, models.TestOrderCancelled,
+			"Cancelled by the placing system")
+		if err != nil {
+			return audit.Event{}, err
+		}
+		return audit.Event{
+			Action:       "test_order.status_change",
+			ResourceType: "test_order",
+			ResourceID:   strconv.Itoa(order.ID),
+			Before:       order,
+			After:        cancelled,
+			Details:      map[string]interface{}{"source": "hl7"},
+		}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return cancelled, nil
+}
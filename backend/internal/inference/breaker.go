@@ -0,0 +1,112 @@
+package inference
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without calling the model server while the
+// circuit breaker is open. It is transient, so queued jobs back off and retry.
+var ErrCircuitOpen = errors.New("model server circuit breaker open")
+
+// CircuitBreaker stops calling a failing model server. After threshold
+// consecutive failures it opens and rejects calls for the cooldown; then a
+// single probe call is let through, closing the circuit on success and
+// reopening it on failure. Permanent errors are the caller's fault and do not
+// count as failures.
+type CircuitBreaker struct {
+	client    ModelClient
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// NewCircuitBreaker wraps client with a circuit breaker
+func NewCircuitBreaker(client ModelClient, threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		client:    client,
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// Predict forwards the request unless the circuit is open
+func (b *CircuitBreaker) Predict(ctx context.Context, req *Request) (*Response, error) {
+	allowed, probe := b.allow()
+	if !allowed {
+		return nil, ErrCircuitOpen
+	}
+
+	resp, err := b.client.Predict(ctx, req)
+	b.record(err, probe)
+	return resp, err
+}
+
+// Check fails while the circuit is open, without calling the model server,
+// and otherwise reports on the wrapped client
+func (b *CircuitBreaker) Check(ctx context.Context) error {
+	b.mu.Lock()
+	open := b.failures >= b.threshold
+	b.mu.Unlock()
+
+	if open {
+		return ErrCircuitOpen
+	}
+	return Check(ctx, b.client)
+}
+
+// Close closes the wrapped client
+func (b *CircuitBreaker) Close() error {
+	return Close(b.client)
+}
+
+// allow reports whether a call may proceed and whether it is the probe
+// admitted once the cooldown has elapsed
+func (b *CircuitBreaker) allow() (allowed, probe bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return true, false
+	}
+
+	if b.probing || b.now().Sub(b.openedAt) < b.cooldown {
+		return false, false
+	}
+
+	b.probing = true
+	return true, true
+}
+
+// record updates the breaker with the outcome of a call
+func (b *CircuitBreaker) record(err error, probe bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if probe {
+		b.probing = false
+	}
+
+	// Shutdown cancellations and caller errors say nothing about server health
+	if err != nil && (IsPermanent(err) || errors.Is(err, context.Canceled)) {
+		return
+	}
+
+	if err == nil {
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if probe || b.failures == b.threshold {
+		b.openedAt = b.now()
+	}
+}
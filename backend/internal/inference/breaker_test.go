@@ -0,0 +1,90 @@
+package inference
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// countingClient returns err and counts calls
+type countingClient struct {
+	err   error
+	calls int
+}
+
+func (c *countingClient) Predict(ctx context.Context, req *Request) (*Response, error) {
+	c.calls++
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &Response{Model: req.Model, Version: req.Version}, nil
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	client := &countingClient{err: errors.New("unavailable")}
+	breaker := NewCircuitBreaker(client, 3, time.Minute)
+	breaker.now = func() time.Time { return now }
+
+	req := &Request{Model: "alphapath"}
+
+	for i := 0; i < 3; i++ {
+		breaker.Predict(context.Background(), req)
+	}
+
+	// Open: calls fail fast without reaching the server
+	if _, err := breaker.Predict(context.Background(), req); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen, got %v", err)
+	}
+	if client.calls != 3 {
+		t.Errorf("Expected 3 calls to the server, got %d", client.calls)
+	}
+
+	// After the cooldown a failed probe reopens the circuit
+	now = now.Add(time.Minute)
+	breaker.Predict(context.Background(), req)
+	if client.calls != 4 {
+		t.Errorf("Expected probe call, got %d calls", client.calls)
+	}
+	if _, err := breaker.Predict(context.Background(), req); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected circuit to reopen after failed probe, got %v", err)
+	}
+
+	// A successful probe closes it
+	now = now.Add(time.Minute)
+	client.err = nil
+	if _, err := breaker.Predict(context.Background(), req); err != nil {
+		t.Fatalf("Expected probe to succeed, got %v", err)
+	}
+	if _, err := breaker.Predict(context.Background(), req); err != nil {
+		t.Errorf("Expected closed circuit, got %v", err)
+	}
+}
+
+func TestCircuitBreaker_IgnoresPermanentErrors(t *testing.T) {
+	client := &countingClient{err: Permanent(errors.New("bad input"))}
+	breaker := NewCircuitBreaker(client, 2, time.Minute)
+
+	for i := 0; i < 5; i++ {
+		if _, err := breaker.Predict(context.Background(), &Request{}); errors.Is(err, ErrCircuitOpen) {
+			t.Fatal("Permanent errors should not open the circuit")
+		}
+	}
+}
+
+func TestCircuitBreaker_Check(t *testing.T) {
+	client := &countingClient{err: errors.New("unavailable")}
+	breaker := NewCircuitBreaker(client, 2, time.Minute)
+
+	if err := Check(context.Background(), breaker); err != nil {
+		t.Errorf("Expected a closed circuit to pass, got %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		breaker.Predict(context.Background(), &Request{Model: "alphapath"})
+	}
+	if err := Check(context.Background(), breaker); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected an open circuit to fail, got %v", err)
+	}
+}
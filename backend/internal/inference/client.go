@@ -0,0 +1,176 @@
+// Package inference runs AlphaPath model predictions. Jobs are queued in
+// Postgres and processed by a worker pool that calls a pluggable ModelClient.
+package inference
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"backend/internal/config"
+)
+
+// Request is a single prediction request
+type Request struct {
+	Model   string
+	Version string
+	Input   json.RawMessage
+}
+
+// Response is the model's output for a Request
+type Response struct {
+	Model   string
+	Version string
+	Output  json.RawMessage
+}
+
+// ModelClient calls a model-serving backend
+type ModelClient interface {
+	Predict(ctx context.Context, req *Request) (*Response, error)
+}
+
+// HealthChecker is implemented by clients that can tell whether their model
+// server is ready
+type HealthChecker interface {
+	Check(ctx context.Context) error
+}
+
+// Check reports whether client's model server is ready. Clients that cannot
+// tell are assumed to be.
+func Check(ctx context.Context, client ModelClient) error {
+	if checker, ok := client.(HealthChecker); ok {
+		return checker.Check(ctx)
+	}
+	return nil
+}
+
+// Close releases client's resources, such as its connection to the model
+// server. Clients without any are left alone.
+func Close(client ModelClient) error {
+	if closer, ok := client.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// permanentError marks an error that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so the job fails without further retries, e.g. for
+// malformed input or an unknown model
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent
+func IsPermanent(err error) bool {
+	var perm *permanentError
+	return errors.As(err, &perm)
+}
+
+// NewClientFromConfig creates the model client selected by
+// cfg.InferenceClient, wrapped with version pinning and a circuit breaker
+func NewClientFromConfig(cfg *config.Config) (ModelClient, error) {
+	var client ModelClient
+	switch cfg.InferenceClient {
+	case "fake":
+		client = &FakeClient{}
+	case "http":
+		if cfg.InferenceEndpoint == "" {
+			return nil, errors.New("INFERENCE_ENDPOINT is required for the http client")
+		}
+		client = NewHTTPClient(cfg.InferenceEndpoint, cfg.InferenceRequestTimeout)
+	case "grpc":
+		if cfg.InferenceEndpoint == "" {
+			return nil, errors.New("INFERENCE_ENDPOINT is required for the grpc client")
+		}
+		grpcClient, err := NewGRPCClient(cfg.InferenceEndpoint, cfg.InferenceRequestTimeout, cfg.InferenceGRPCPlaintext)
+		if err != nil {
+			return nil, err
+		}
+		client = grpcClient
+	default:
+		return nil, fmt.Errorf("unknown inference client %q", cfg.InferenceClient)
+	}
+
+	if cfg.InferenceBreakerFailures > 0 {
+		client = NewCircuitBreaker(client, cfg.InferenceBreakerFailures, cfg.InferenceBreakerCooldown)
+	}
+
+	versions, err := parseModelVersions(cfg.InferenceModelVersions)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) > 0 {
+		client = &pinnedClient{client: client, versions: versions}
+	}
+
+	return client, nil
+}
+
+// parseModelVersions parses "model=version" pins
+func parseModelVersions(pins []string) (map[string]string, error) {
+	versions := make(map[string]string, len(pins))
+	for _, pin := range pins {
+		model, version, ok := strings.Cut(pin, "=")
+		model, version = strings.TrimSpace(model), strings.TrimSpace(version)
+		if !ok || model == "" || version == "" {
+			return nil, fmt.Errorf("invalid model version pin %q, expected model=version", pin)
+		}
+		versions[model] = version
+	}
+	return versions, nil
+}
+
+// pinnedClient serves pinned models at a fixed version. Requests that omit
+// the version get the pinned one; requests for any other version are rejected
+// so an unvalidated model never reaches reports.
+type pinnedClient struct {
+	client   ModelClient
+	versions map[string]string
+}
+
+// Check reports on the wrapped client
+func (c *pinnedClient) Check(ctx context.Context) error {
+	return Check(ctx, c.client)
+}
+
+// Close closes the wrapped client
+func (c *pinnedClient) Close() error {
+	return Close(c.client)
+}
+
+// Predict applies the pin and forwards the request. The response reports the
+// pinned version if the server does not say which it served.
+func (c *pinnedClient) Predict(ctx context.Context, req *Request) (*Response, error) {
+	pinned, ok := c.versions[req.Model]
+	if !ok {
+		return c.client.Predict(ctx, req)
+	}
+
+	if req.Version != "" && req.Version != pinned {
+		return nil, Permanent(fmt.Errorf("model %s is pinned to version %s, requested %s", req.Model, pinned, req.Version))
+	}
+
+	pinnedReq := *req
+	pinnedReq.Version = pinned
+	resp, err := c.client.Predict(ctx, &pinnedReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Version == "" {
+		resp.Version = pinned
+	}
+	return resp, nil
+}
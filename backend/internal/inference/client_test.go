@@ -0,0 +1,44 @@
+package inference
+
+import (
+	"context"
+	"testing"
+
+	"backend/internal/config"
+)
+
+func TestPinnedClient(t *testing.T) {
+	inner := &countingClient{}
+	client := &pinnedClient{client: inner, versions: map[string]string{"alphapath": "3"}}
+
+	resp, err := client.Predict(context.Background(), &Request{Model: "alphapath"})
+	if err != nil || resp.Version != "3" {
+		t.Errorf("Expected pinned version 3, got %+v (%v)", resp, err)
+	}
+
+	if _, err := client.Predict(context.Background(), &Request{Model: "alphapath", Version: "4"}); !IsPermanent(err) {
+		t.Errorf("Expected permanent error for unpinned version, got %v", err)
+	}
+
+	resp, err = client.Predict(context.Background(), &Request{Model: "other", Version: "7"})
+	if err != nil || resp.Version != "7" {
+		t.Errorf("Expected unpinned model to pass through, got %+v (%v)", resp, err)
+	}
+}
+
+func TestNewClientFromConfig(t *testing.T) {
+	if _, err := NewClientFromConfig(&config.Config{InferenceClient: "fake"}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	invalid := []*config.Config{
+		{InferenceClient: "tensorflow"},
+		{InferenceClient: "http"},
+		{InferenceClient: "fake", InferenceModelVersions: []string{"alphapath"}},
+	}
+	for _, cfg := range invalid {
+		if _, err := NewClientFromConfig(cfg); err == nil {
+			t.Errorf("Expected error for %+v", cfg)
+		}
+	}
+}
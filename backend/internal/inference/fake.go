@@ -0,0 +1,57 @@
+package inference
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// FakeClient is a deterministic ModelClient for development and tests. The
+// same input always produces the same score, so pipelines can be exercised
+// offline without a model server.
+type FakeClient struct {
+	// Latency is slept before responding, honouring context cancellation
+	Latency time.Duration
+}
+
+// fakeOutput is the prediction returned by FakeClient
+type fakeOutput struct {
+	Label string  `json:"label"`
+	Score float64 `json:"score"`
+	Fake  bool    `json:"fake"`
+}
+
+// Predict returns a score derived from a hash of the input
+func (c *FakeClient) Predict(ctx context.Context, req *Request) (*Response, error) {
+	if !json.Valid(req.Input) {
+		return nil, Permanent(errors.New("input is not valid JSON"))
+	}
+
+	if c.Latency > 0 {
+		timer := time.NewTimer(c.Latency)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	sum := sha256.Sum256(append([]byte(req.Model+"\x00"+req.Version+"\x00"), req.Input...))
+	score := float64(binary.BigEndian.Uint32(sum[:4])) / float64(^uint32(0))
+
+	label := "negative"
+	if score >= 0.5 {
+		label = "positive"
+	}
+
+	output, err := json.Marshal(fakeOutput{Label: label, Score: score, Fake: true})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Response{Model: req.Model, Version: req.Version, Output: output}, nil
+}
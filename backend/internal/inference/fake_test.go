@@ -0,0 +1,33 @@
+package inference
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestFakeClient_Deterministic(t *testing.T) {
+	client := &FakeClient{}
+	req := &Request{Model: "alphapath", Version: "1", Input: json.RawMessage(`{"slide":"S-1"}`)}
+
+	first, err := client.Predict(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	second, err := client.Predict(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if string(first.Output) != string(second.Output) {
+		t.Errorf("Expected identical output, got %s and %s", first.Output, second.Output)
+	}
+}
+
+func TestFakeClient_InvalidInput(t *testing.T) {
+	_, err := (&FakeClient{}).Predict(context.Background(), &Request{Model: "alphapath", Input: json.RawMessage(`{`)})
+	if !IsPermanent(err) {
+		t.Errorf("Expected permanent error, got %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package inference
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// grpcPredictMethod is the unary RPC called by GRPCClient. Request and
+// response are google.protobuf.Struct messages, so the service needs no
+// generated stubs:
+//
+//	request:  {"model": "alphapath", "version": "3", "input": <job input>}
+//	response: {"model_version": "3", "output": <prediction>}
+const grpcPredictMethod = "/alphapath.inference.v1.Predictor/Predict"
+
+// GRPCClient calls a model server over gRPC
+type GRPCClient struct {
+	conn    *grpc.ClientConn
+	timeout time.Duration
+}
+
+// NewGRPCClient creates a client for the server at target (host:port). TLS is
+// used unless plaintext is set. The connection is established lazily.
+func NewGRPCClient(target string, timeout time.Duration, plaintext bool) (*GRPCClient, error) {
+	creds := credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	if plaintext {
+		creds = insecure.NewCredentials()
+	}
+
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC client: %w", err)
+	}
+
+	return &GRPCClient{conn: conn, timeout: timeout}, nil
+}
+
+// Close releases the connection
+func (c *GRPCClient) Close() error {
+	return c.conn.Close()
+}
+
+// Check calls the standard gRPC health service. Servers that do not
+// implement it are taken to be ready once they answer at all.
+func (c *GRPCClient) Check(ctx context.Context) error {
+	resp, err := healthpb.NewHealthClient(c.conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if status.Code(err) == codes.Unimplemented {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("health check failed: %w", err)
+	}
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("model server is %s", resp.GetStatus())
+	}
+	return nil
+}
+
+// Predict calls the Predict RPC
+func (c *GRPCClient) Predict(ctx context.Context, req *Request) (*Response, error) {
+	var input interface{}
+	if err := json.Unmarshal(req.Input, &input); err != nil {
+		return nil, Permanent(fmt.Errorf("input is not valid JSON: %w", err))
+	}
+
+	in, err := structpb.NewStruct(map[string]interface{}{
+		"model":   req.Model,
+		"version": req.Version,
+		"input":   input,
+	})
+	if err != nil {
+		return nil, Permanent(fmt.Errorf("input cannot be encoded: %w", err))
+	}
+
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	out := new(structpb.Struct)
+	if err := c.conn.Invoke(ctx, grpcPredictMethod, in, out); err != nil {
+		code := status.Code(err)
+		err = fmt.Errorf("inference RPC failed: %w", err)
+		if retryableCode(code) {
+			return nil, err
+		}
+		return nil, Permanent(err)
+	}
+
+	outputValue, ok := out.Fields["output"]
+	if !ok {
+		return nil, errors.New("model server response has no output")
+	}
+
+	output, err := protojson.Marshal(outputValue)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode model output: %w", err)
+	}
+
+	version := out.Fields["model_version"].GetStringValue()
+	if version == "" {
+		version = req.Version
+	}
+
+	return &Response{Model: req.Model, Version: version, Output: json.RawMessage(output)}, nil
+}
+
+// retryableCode reports whether a failed RPC may succeed if repeated
+func retryableCode(code codes.Code) bool {
+	switch code {
+	case codes.InvalidArgument, codes.NotFound, codes.FailedPrecondition, codes.OutOfRange,
+		codes.Unimplemented, codes.PermissionDenied, codes.Unauthenticated, codes.AlreadyExists:
+		return false
+	}
+	return true
+}
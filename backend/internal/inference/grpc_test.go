@@ -0,0 +1,144 @@
+package inference
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// predictFunc implements the stub Predictor service
+type predictFunc func(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error)
+
+// startPredictor serves the Predict RPC on a local port and returns its address
+func startPredictor(t *testing.T, predict predictFunc) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	server := grpc.NewServer()
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "alphapath.inference.v1.Predictor",
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Predict",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+				in := new(structpb.Struct)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				return predict(ctx, in)
+			},
+		}},
+	}, struct{}{})
+
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	return listener.Addr().String()
+}
+
+func newTestGRPCClient(t *testing.T, addr string, timeout time.Duration) *GRPCClient {
+	t.Helper()
+
+	client, err := NewGRPCClient(addr, timeout, true)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestGRPCClient_Predict(t *testing.T) {
+	addr := startPredictor(t, func(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+		if in.Fields["model"].GetStringValue() != "alphapath" || in.Fields["version"].GetStringValue() != "3" {
+			t.Errorf("Unexpected request %v", in)
+		}
+		slide := in.Fields["input"].GetStructValue().Fields["slide"].GetStringValue()
+
+		return structpb.NewStruct(map[string]interface{}{
+			"model_version": "3",
+			"output":        map[string]interface{}{"slide": slide, "score": 0.5},
+		})
+	})
+
+	client := newTestGRPCClient(t, addr, time.Second)
+	resp, err := client.Predict(context.Background(), &Request{Model: "alphapath", Version: "3", Input: json.RawMessage(`{"slide":"S-1"}`)})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var output map[string]interface{}
+	if err := json.Unmarshal(resp.Output, &output); err != nil {
+		t.Fatalf("Invalid output %s: %v", resp.Output, err)
+	}
+	if output["slide"] != "S-1" || output["score"] != 0.5 || resp.Version != "3" {
+		t.Errorf("Unexpected response %+v with output %s", resp, resp.Output)
+	}
+}
+
+func TestGRPCClient_Predict_ErrorClassification(t *testing.T) {
+	tests := []struct {
+		code      codes.Code
+		permanent bool
+	}{
+		{codes.InvalidArgument, true},
+		{codes.NotFound, true},
+		{codes.Unavailable, false},
+		{codes.ResourceExhausted, false},
+	}
+
+	for _, tt := range tests {
+		addr := startPredictor(t, func(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+			return nil, status.Error(tt.code, "model failed")
+		})
+
+		_, err := newTestGRPCClient(t, addr, time.Second).Predict(context.Background(),
+			&Request{Model: "alphapath", Input: json.RawMessage(`{}`)})
+		if err == nil {
+			t.Fatalf("Code %s: expected error", tt.code)
+		}
+		if IsPermanent(err) != tt.permanent {
+			t.Errorf("Code %s: expected permanent=%v, got %v", tt.code, tt.permanent, err)
+		}
+	}
+}
+
+func TestGRPCClient_Predict_Timeout(t *testing.T) {
+	addr := startPredictor(t, func(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	_, err := newTestGRPCClient(t, addr, 20*time.Millisecond).Predict(context.Background(),
+		&Request{Model: "alphapath", Input: json.RawMessage(`{}`)})
+	if err == nil || IsPermanent(err) {
+		t.Errorf("Expected transient timeout error, got %v", err)
+	}
+}
+
+func TestGRPCClient_Check(t *testing.T) {
+	// The test server does not implement the health service, so answering
+	// at all counts as ready
+	addr := startPredictor(t, func(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+		return in, nil
+	})
+	if err := newTestGRPCClient(t, addr, time.Second).Check(context.Background()); err != nil {
+		t.Errorf("Expected a reachable server to pass, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := newTestGRPCClient(t, "127.0.0.1:1", time.Second).Check(ctx); err == nil {
+		t.Error("Expected an unreachable server to fail")
+	}
+}
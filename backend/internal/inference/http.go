@@ -0,0 +1,133 @@
+package inference
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxResponseBytes bounds the size of a model server response
+const maxResponseBytes = 32 << 20
+
+// HTTPClient calls a model server speaking the KServe / Triton v2 inference
+// protocol. The job input is sent as the v2 request body, so it must hold an
+// "inputs" array of tensors; the full v2 response becomes the job output.
+type HTTPClient struct {
+	baseURL string
+	timeout time.Duration
+	client  *http.Client
+}
+
+// NewHTTPClient creates a client for the v2 server at baseURL, e.g.
+// http://alphapath-predictor:8000. Each call is limited to timeout.
+func NewHTTPClient(baseURL string, timeout time.Duration) *HTTPClient {
+	return &HTTPClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		timeout: timeout,
+		client:  &http.Client{},
+	}
+}
+
+// v2Response is the part of a v2 inference response the client inspects
+type v2Response struct {
+	ModelName    string `json:"model_name"`
+	ModelVersion string `json:"model_version"`
+	Error        string `json:"error"`
+}
+
+// Predict posts the input to /v2/models/{model}[/versions/{version}]/infer
+func (c *HTTPClient) Predict(ctx context.Context, req *Request) (*Response, error) {
+	var body struct {
+		Inputs []json.RawMessage `json:"inputs"`
+	}
+	if err := json.Unmarshal(req.Input, &body); err != nil || len(body.Inputs) == 0 {
+		return nil, Permanent(errors.New(`input must be a v2 inference request with an "inputs" array`))
+	}
+
+	endpoint := c.baseURL + "/v2/models/" + url.PathEscape(req.Model)
+	if req.Version != "" {
+		endpoint += "/versions/" + url.PathEscape(req.Version)
+	}
+	endpoint += "/infer"
+
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(req.Input))
+	if err != nil {
+		return nil, Permanent(fmt.Errorf("failed to build inference request: %w", err))
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	httpResp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("inference request failed: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(httpResp.Body, maxResponseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read inference response: %w", err)
+	}
+
+	var parsed v2Response
+	_ = json.Unmarshal(raw, &parsed)
+
+	if httpResp.StatusCode != http.StatusOK {
+		message := parsed.Error
+		if message == "" {
+			message = http.StatusText(httpResp.StatusCode)
+		}
+		err := fmt.Errorf("model server returned %d: %s", httpResp.StatusCode, message)
+		if retryableStatus(httpResp.StatusCode) {
+			return nil, err
+		}
+		return nil, Permanent(err)
+	}
+
+	if !json.Valid(raw) {
+		return nil, errors.New("model server returned invalid JSON")
+	}
+
+	version := parsed.ModelVersion
+	if version == "" {
+		version = req.Version
+	}
+
+	return &Response{Model: req.Model, Version: version, Output: json.RawMessage(raw)}, nil
+}
+
+// Check asks the v2 server's /v2/health/ready endpoint whether it can serve
+// inference requests
+func (c *HTTPClient) Check(ctx context.Context) error {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/v2/health/ready", nil)
+	if err != nil {
+		return fmt.Errorf("failed to build readiness request: %w", err)
+	}
+
+	httpResp, err := c.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("readiness request failed: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode != http.StatusOK {
+		return fmt.Errorf("model server is not ready: %d", httpResp.StatusCode)
+	}
+	return nil
+}
+
+// retryableStatus reports whether a failed request may succeed if repeated
+func retryableStatus(code int) bool {
+	return code >= 500 || code == http.StatusRequestTimeout || code == http.StatusTooManyRequests
+}
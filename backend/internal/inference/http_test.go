@@ -0,0 +1,125 @@
+package inference
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const v2Input = `{"inputs":[{"name":"tile","shape":[1,3],"datatype":"FP32","data":[0.1,0.2,0.3]}]}`
+
+func TestHTTPClient_Predict(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v2/models/alphapath/versions/3/infer" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+
+		var body map[string]json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body["inputs"] == nil {
+			t.Errorf("Expected v2 request body, got %v (%v)", body, err)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"model_name":"alphapath","model_version":"3","outputs":[{"name":"score","shape":[1],"datatype":"FP32","data":[0.91]}]}`))
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL+"/", time.Second)
+	resp, err := client.Predict(context.Background(), &Request{Model: "alphapath", Version: "3", Input: json.RawMessage(v2Input)})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if resp.Version != "3" {
+		t.Errorf("Expected version 3, got %q", resp.Version)
+	}
+
+	var output struct {
+		Outputs []struct {
+			Data []float64 `json:"data"`
+		} `json:"outputs"`
+	}
+	if err := json.Unmarshal(resp.Output, &output); err != nil || len(output.Outputs) != 1 || output.Outputs[0].Data[0] != 0.91 {
+		t.Errorf("Unexpected output %s", resp.Output)
+	}
+}
+
+func TestHTTPClient_Predict_ErrorClassification(t *testing.T) {
+	tests := []struct {
+		status    int
+		permanent bool
+	}{
+		{http.StatusBadRequest, true},
+		{http.StatusNotFound, true},
+		{http.StatusTooManyRequests, false},
+		{http.StatusServiceUnavailable, false},
+	}
+
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+			w.Write([]byte(`{"error":"model failed"}`))
+		}))
+
+		_, err := NewHTTPClient(server.URL, time.Second).Predict(context.Background(),
+			&Request{Model: "alphapath", Input: json.RawMessage(v2Input)})
+		server.Close()
+
+		if err == nil {
+			t.Fatalf("Status %d: expected error", tt.status)
+		}
+		if IsPermanent(err) != tt.permanent {
+			t.Errorf("Status %d: expected permanent=%v, got %v", tt.status, tt.permanent, err)
+		}
+	}
+}
+
+func TestHTTPClient_Predict_Timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	_, err := NewHTTPClient(server.URL, 20*time.Millisecond).Predict(context.Background(),
+		&Request{Model: "alphapath", Input: json.RawMessage(v2Input)})
+	if err == nil || IsPermanent(err) {
+		t.Errorf("Expected transient timeout error, got %v", err)
+	}
+}
+
+func TestHTTPClient_Predict_InvalidInput(t *testing.T) {
+	_, err := NewHTTPClient("http://127.0.0.1:0", time.Second).Predict(context.Background(),
+		&Request{Model: "alphapath", Input: json.RawMessage(`{"slide":"S-1"}`)})
+	if !IsPermanent(err) {
+		t.Errorf("Expected permanent error for non-v2 input, got %v", err)
+	}
+}
+
+func TestHTTPClient_Check(t *testing.T) {
+	ready := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/v2/health/ready" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if !ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, time.Second)
+	if err := client.Check(context.Background()); err != nil {
+		t.Errorf("Expected a ready server to pass, got %v", err)
+	}
+
+	ready = false
+	if err := client.Check(context.Background()); err == nil {
+		t.Error("Expected a server that is not ready to fail")
+	}
+}
@@ -0,0 +1,206 @@
+package inference
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"backend/internal/config"
+	"backend/internal/models"
+)
+
+// maxErrorLength bounds the error text stored on a job
+const maxErrorLength = 2000
+
+// JobStore is the subset of models.InferenceJobRepository used by workers
+type JobStore interface {
+	Claim(ctx context.Context, workerID string, lease time.Duration) (*models.InferenceJob, error)
+	Complete(ctx context.Context, job *models.InferenceJob, version string, output json.RawMessage, latency time.Duration) error
+	Fail(ctx context.Context, job *models.InferenceJob, message string, latency, retryAfter time.Duration) error
+	Release(ctx context.Context, job *models.InferenceJob) error
+}
+
+// Options configures the worker pool
+type Options struct {
+	Workers      int
+	PollInterval time.Duration
+	JobTimeout   time.Duration
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+}
+
+// OptionsFromConfig builds worker pool options from application configuration
+func OptionsFromConfig(cfg *config.Config) Options {
+	return Options{
+		Workers:      cfg.InferenceWorkers,
+		PollInterval: cfg.InferencePollInterval,
+		JobTimeout:   cfg.InferenceJobTimeout,
+		BaseBackoff:  cfg.InferenceRetryBackoff,
+		MaxBackoff:   cfg.InferenceRetryMaxBackoff,
+	}
+}
+
+// lease is how long a claimed job stays locked to its worker. It outlasts the
+// job timeout so a healthy worker always records the outcome first.
+func (o Options) lease() time.Duration {
+	return o.JobTimeout + 30*time.Second
+}
+
+// backoff returns the delay before retrying after the given attempt,
+// doubling from BaseBackoff up to MaxBackoff
+func (o Options) backoff(attempt int) time.Duration {
+	delay := o.BaseBackoff
+	for i := 1; i < attempt && delay < o.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > o.MaxBackoff {
+		delay = o.MaxBackoff
+	}
+	return delay
+}
+
+// Pool runs inference jobs from the queue on a fixed number of workers
+type Pool struct {
+	store  JobStore
+	client ModelClient
+	opts   Options
+	now    func() time.Time
+
+	wg         sync.WaitGroup
+	stopClaims context.CancelFunc
+	cancelJobs context.CancelFunc
+	jobCtx     context.Context
+}
+
+// NewPool creates a new worker pool
+func NewPool(store JobStore, client ModelClient, opts Options) *Pool {
+	return &Pool{
+		store:  store,
+		client: client,
+		opts:   opts,
+		now:    time.Now,
+	}
+}
+
+// Start launches the workers. They poll for jobs until Stop is called.
+func (p *Pool) Start() {
+	var claimCtx context.Context
+	claimCtx, p.stopClaims = context.WithCancel(context.Background())
+	p.jobCtx, p.cancelJobs = context.WithCancel(context.Background())
+
+	hostname, _ := os.Hostname()
+	for i := 0; i < p.opts.Workers; i++ {
+		workerID := fmt.Sprintf("%s-%d-%d", hostname, os.Getpid(), i)
+		p.wg.Add(1)
+		go p.work(claimCtx, workerID)
+	}
+
+	slog.Info("Started inference workers", "workers", p.opts.Workers)
+}
+
+// Stop stops claiming new jobs and waits for running jobs to finish. If ctx
+// expires first, running jobs are cancelled and requeued.
+func (p *Pool) Stop(ctx context.Context) error {
+	if p.stopClaims == nil {
+		return nil
+	}
+	p.stopClaims()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		p.cancelJobs()
+		return nil
+	case <-ctx.Done():
+		p.cancelJobs()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// work claims and runs jobs until ctx is cancelled, sleeping for the poll
+// interval whenever the queue is empty
+func (p *Pool) work(ctx context.Context, workerID string) {
+	defer p.wg.Done()
+
+	for ctx.Err() == nil {
+		ran, err := p.runOnce(workerID)
+		if err != nil {
+			slog.Error("Inference worker failed", "worker", workerID, "error", err)
+		}
+		if ran && err == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(p.opts.PollInterval):
+		}
+	}
+}
+
+// runOnce claims a single job and runs it, reporting whether a job was found
+func (p *Pool) runOnce(workerID string) (bool, error) {
+	// Bookkeeping outlives job cancellation, so a job interrupted by shutdown
+	// is still handed back
+	storeCtx := context.WithoutCancel(p.jobCtx)
+
+	job, err := p.store.Claim(storeCtx, workerID, p.opts.lease())
+	if err != nil {
+		return false, fmt.Errorf("failed to claim job: %w", err)
+	}
+	if job == nil {
+		return false, nil
+	}
+
+	ctx, cancel := context.WithTimeout(p.jobCtx, p.opts.JobTimeout)
+	defer cancel()
+
+	start := p.now()
+	resp, err := p.client.Predict(ctx, &Request{Model: job.Model, Version: job.ModelVersion, Input: job.Input})
+	latency := p.now().Sub(start)
+
+	if err == nil {
+		if err := p.store.Complete(storeCtx, job, resp.Version, resp.Output, latency); err != nil {
+			return true, fmt.Errorf("failed to record job %d: %w", job.ID, err)
+		}
+		return true, nil
+	}
+
+	if p.jobCtx.Err() != nil {
+		// Shutting down: hand the job back for another instance to pick up,
+		// without counting the interrupted attempt
+		if err := p.store.Release(storeCtx, job); err != nil {
+			return true, fmt.Errorf("failed to hand back job %d: %w", job.ID, err)
+		}
+		return true, nil
+	}
+
+	var retryAfter time.Duration
+	if !IsPermanent(err) && job.Attempts < job.MaxAttempts {
+		retryAfter = p.opts.backoff(job.Attempts)
+	}
+
+	message := err.Error()
+	if len(message) > maxErrorLength {
+		message = message[:maxErrorLength]
+	}
+
+	if err := p.store.Fail(storeCtx, job, message, latency, retryAfter); err != nil {
+		return true, fmt.Errorf("failed to record job %d failure: %w", job.ID, err)
+	}
+
+	if retryAfter == 0 {
+		slog.Warn("Inference job failed", "job_id", job.ID, "attempts", job.Attempts, "error", message)
+	}
+	return true, nil
+}
@@ -0,0 +1,267 @@
+package inference
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+)
+
+// fakeJobStore is an in-memory JobStore
+type fakeJobStore struct {
+	mu   sync.Mutex
+	jobs []*models.InferenceJob
+
+	retries []time.Duration
+}
+
+func (s *fakeJobStore) Claim(_ context.Context, workerID string, lease time.Duration) (*models.InferenceJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, job := range s.jobs {
+		if job.Status == models.InferenceJobQueued {
+			job.Status = models.InferenceJobRunning
+			job.Attempts++
+			claimed := *job
+			return &claimed, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *fakeJobStore) Complete(_ context.Context, job *models.InferenceJob, version string, output json.RawMessage, latency time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := s.find(job.ID)
+	stored.Status = models.InferenceJobSucceeded
+	if version != "" {
+		stored.ModelVersion = version
+	}
+	stored.Output = output
+	return nil
+}
+
+func (s *fakeJobStore) Fail(_ context.Context, job *models.InferenceJob, message string, latency, retryAfter time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := s.find(job.ID)
+	stored.Error = message
+	if retryAfter > 0 {
+		stored.Status = models.InferenceJobQueued
+		s.retries = append(s.retries, retryAfter)
+	} else {
+		stored.Status = models.InferenceJobFailed
+	}
+	return nil
+}
+
+func (s *fakeJobStore) Release(_ context.Context, job *models.InferenceJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := s.find(job.ID)
+	stored.Status = models.InferenceJobQueued
+	stored.Attempts--
+	return nil
+}
+
+func (s *fakeJobStore) find(id int) *models.InferenceJob {
+	for _, job := range s.jobs {
+		if job.ID == id {
+			return job
+		}
+	}
+	return nil
+}
+
+// errorClient fails every prediction with err
+type errorClient struct {
+	err error
+}
+
+func (c *errorClient) Predict(ctx context.Context, req *Request) (*Response, error) {
+	return nil, c.err
+}
+
+func newTestJob() *models.InferenceJob {
+	return &models.InferenceJob{
+		ID:          1,
+		Model:       "alphapath",
+		Input:       json.RawMessage(`{"slide":"S-1"}`),
+		Status:      models.InferenceJobQueued,
+		MaxAttempts: 3,
+	}
+}
+
+func newTestPool(store JobStore, client ModelClient) *Pool {
+	pool := NewPool(store, client, Options{
+		Workers:      1,
+		PollInterval: 10 * time.Millisecond,
+		JobTimeout:   time.Second,
+		BaseBackoff:  time.Second,
+		MaxBackoff:   3 * time.Second,
+	})
+	pool.jobCtx = context.Background()
+	return pool
+}
+
+func TestPool_RunOnce_Success(t *testing.T) {
+	job := newTestJob()
+	store := &fakeJobStore{jobs: []*models.InferenceJob{job}}
+	pool := newTestPool(store, &FakeClient{})
+
+	ran, err := pool.runOnce("worker-0")
+	if err != nil || !ran {
+		t.Fatalf("Expected job to run, got ran=%v err=%v", ran, err)
+	}
+
+	if job.Status != models.InferenceJobSucceeded {
+		t.Errorf("Expected succeeded, got %s", job.Status)
+	}
+	if !json.Valid(job.Output) {
+		t.Errorf("Expected JSON output, got %s", job.Output)
+	}
+}
+
+func TestPool_RunOnce_RecordsServedVersion(t *testing.T) {
+	job := newTestJob()
+	store := &fakeJobStore{jobs: []*models.InferenceJob{job}}
+	client := &pinnedClient{client: &FakeClient{}, versions: map[string]string{"alphapath": "3"}}
+	pool := newTestPool(store, client)
+
+	if _, err := pool.runOnce("worker-0"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if job.Status != models.InferenceJobSucceeded || job.ModelVersion != "3" {
+		t.Errorf("Expected the job to record the pinned version 3, got %s at version %q", job.Status, job.ModelVersion)
+	}
+}
+
+func TestPool_RunOnce_EmptyQueue(t *testing.T) {
+	pool := newTestPool(&fakeJobStore{}, &FakeClient{})
+
+	ran, err := pool.runOnce("worker-0")
+	if err != nil || ran {
+		t.Errorf("Expected no job, got ran=%v err=%v", ran, err)
+	}
+}
+
+func TestPool_RunOnce_RetriesWithBackoff(t *testing.T) {
+	job := newTestJob()
+	store := &fakeJobStore{jobs: []*models.InferenceJob{job}}
+	pool := newTestPool(store, &errorClient{err: errors.New("model server unavailable")})
+
+	for i := 0; i < 3; i++ {
+		if _, err := pool.runOnce("worker-0"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	if job.Status != models.InferenceJobFailed {
+		t.Errorf("Expected failed after max attempts, got %s", job.Status)
+	}
+	if job.Error != "model server unavailable" {
+		t.Errorf("Expected error recorded, got %q", job.Error)
+	}
+
+	expected := []time.Duration{time.Second, 2 * time.Second}
+	if len(store.retries) != len(expected) {
+		t.Fatalf("Expected retries %v, got %v", expected, store.retries)
+	}
+	for i := range expected {
+		if store.retries[i] != expected[i] {
+			t.Errorf("Retry %d: expected %v, got %v", i, expected[i], store.retries[i])
+		}
+	}
+}
+
+func TestPool_RunOnce_PermanentErrorNotRetried(t *testing.T) {
+	job := newTestJob()
+	store := &fakeJobStore{jobs: []*models.InferenceJob{job}}
+	pool := newTestPool(store, &errorClient{err: Permanent(errors.New("unknown model"))})
+
+	if _, err := pool.runOnce("worker-0"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if job.Status != models.InferenceJobFailed || len(store.retries) != 0 {
+		t.Errorf("Expected immediate failure, got %s with retries %v", job.Status, store.retries)
+	}
+}
+
+func TestPool_RunOnce_ShutdownOnLastAttempt(t *testing.T) {
+	job := newTestJob()
+	job.Attempts = job.MaxAttempts - 1
+	store := &fakeJobStore{jobs: []*models.InferenceJob{job}}
+	pool := newTestPool(store, &errorClient{err: context.Canceled})
+
+	jobCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+	pool.jobCtx = jobCtx
+
+	if _, err := pool.runOnce("worker-0"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Handed back with its last attempt still to run, not failed or retried
+	if job.Status != models.InferenceJobQueued || job.Attempts != job.MaxAttempts-1 {
+		t.Errorf("Expected the job queued with %d attempts, got %s with %d", job.MaxAttempts-1, job.Status, job.Attempts)
+	}
+	if len(store.retries) != 0 || job.Error != "" {
+		t.Errorf("Expected the interrupted run not to be recorded as a failure, got retries %v error %q", store.retries, job.Error)
+	}
+}
+
+func TestOptions_Backoff(t *testing.T) {
+	opts := Options{BaseBackoff: 10 * time.Second, MaxBackoff: time.Minute}
+
+	tests := map[int]time.Duration{
+		1: 10 * time.Second,
+		2: 20 * time.Second,
+		3: 40 * time.Second,
+		4: time.Minute,
+		9: time.Minute,
+	}
+
+	for attempt, expected := range tests {
+		if got := opts.backoff(attempt); got != expected {
+			t.Errorf("Attempt %d: expected %v, got %v", attempt, expected, got)
+		}
+	}
+}
+
+func TestPool_StartStop(t *testing.T) {
+	job := newTestJob()
+	store := &fakeJobStore{jobs: []*models.InferenceJob{job}}
+	pool := newTestPool(store, &FakeClient{Latency: 20 * time.Millisecond})
+
+	pool.Start()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		store.mu.Lock()
+		status := job.Status
+		store.mu.Unlock()
+		if status == models.InferenceJobSucceeded {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Job did not complete, status %s", status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := pool.Stop(ctx); err != nil {
+		t.Errorf("Expected clean stop, got %v", err)
+	}
+}
@@ -0,0 +1,327 @@
+// Package listing parses the query string of list endpoints into a
+// filtered, sorted page of rows and builds the SQL to fetch it.
+//
+// Lists are paged with a cursor (keyset pagination) rather than an offset:
+// the cursor holds the sort value and key of the last row returned, and the
+// next page starts after it. Pages stay stable while rows are inserted and
+// cost the same however deep the client pages.
+//
+//	GET /api/users?sort=-created_at&limit=50&created_after=2026-01-01T00:00:00Z
+//	GET /api/users?sort=-created_at&limit=50&created_after=2026-01-01T00:00:00Z&cursor=eyJz...
+package listing
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Query string parameters common to every list
+const (
+	ParamLimit  = "limit"
+	ParamSort   = "sort"
+	ParamCursor = "cursor"
+)
+
+// Page sizes used when a Spec does not set its own
+const (
+	DefaultLimit = 50
+	MaxLimit     = 500
+)
+
+// Type is the type of a sortable or filterable column
+type Type int
+
+// Column types
+const (
+	String Type = iota
+	Int
+	Time
+	// Date is a calendar date, YYYY-MM-DD
+	Date
+)
+
+// Op is how a filter compares its column with the given value
+type Op int
+
+// Filter operators
+const (
+	// Equal matches the value exactly
+	Equal Op = iota
+	// EqualFold matches the value ignoring case
+	EqualFold
+	// After matches values later than (greater than) the given value
+	After
+	// Before matches values earlier than (less than) the given value
+	Before
+	// NotBefore matches the given value and anything later
+	NotBefore
+	// PrefixFold matches values starting with the given value, ignoring case
+	PrefixFold
+)
+
+// Field is a column a list can be sorted by. Sort columns must be NOT NULL
+// for the cursor comparison to hold.
+type Field struct {
+	Column string
+	Type   Type
+}
+
+// Filter is a query string parameter that narrows a list. Also lists
+// further columns compared the same way; a row matches if any column does.
+type Filter struct {
+	Column string
+	Type   Type
+	Op     Op
+	Also   []string
+}
+
+// Spec declares what a list endpoint supports. Only the sort fields and
+// filters named here are accepted, so column names in the generated SQL
+// never come from the client.
+type Spec struct {
+	// Key is a unique, NOT NULL integer column that breaks ties between
+	// rows with the same sort value
+	Key string
+
+	// Sorts maps the names accepted by the sort parameter to columns
+	Sorts map[string]Field
+
+	// DefaultSort is used when no sort is given, e.g. "-created_at"
+	DefaultSort string
+
+	// Filters maps query string parameters to the columns they filter
+	Filters map[string]Filter
+
+	// Scope, if set, is a condition every listed row meets whatever the
+	// filters, e.g. "deleted_at IS NULL"
+	Scope string
+
+	// DefaultLimit and MaxLimit default to the package's page sizes
+	DefaultLimit int
+	MaxLimit     int
+}
+
+// condition is one parsed filter
+type condition struct {
+	filter Filter
+	value  interface{}
+}
+
+// Query is a parsed list request
+type Query struct {
+	// Limit is the page size
+	Limit int
+
+	// Sort is the name of the sort field, Desc its direction
+	Sort string
+	Desc bool
+
+	// After is the decoded cursor; nil for the first page
+	After *Key
+
+	spec       *Spec
+	field      Field
+	conditions []condition
+}
+
+// Parse reads a list request from the query string. Unknown parameters,
+// sort fields and malformed values are rejected rather than ignored.
+func (s *Spec) Parse(values url.Values) (*Query, error) {
+	q := &Query{spec: s, Limit: s.DefaultLimit}
+	if q.Limit == 0 {
+		q.Limit = DefaultLimit
+	}
+	maxLimit := s.MaxLimit
+	if maxLimit == 0 {
+		maxLimit = MaxLimit
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	// Conditions are generated in a stable order so the same request always
+	// produces the same SQL
+	sort.Strings(names)
+
+	sortParam := s.DefaultSort
+	var cursor string
+	for _, name := range names {
+		if len(values[name]) > 1 {
+			return nil, fmt.Errorf("%s may only be given once", name)
+		}
+		value := values[name][0]
+
+		switch name {
+		case ParamLimit:
+			limit, err := strconv.Atoi(value)
+			if err != nil || limit <= 0 || limit > maxLimit {
+				return nil, fmt.Errorf("limit must be between 1 and %d", maxLimit)
+			}
+			q.Limit = limit
+
+		case ParamSort:
+			sortParam = value
+
+		case ParamCursor:
+			cursor = value
+
+		default:
+			filter, ok := s.Filters[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown parameter %q", name)
+			}
+			parsed, err := parseValue(filter.Type, value)
+			if err != nil {
+				return nil, fmt.Errorf("%s %v", name, err)
+			}
+			q.conditions = append(q.conditions, condition{filter: filter, value: parsed})
+		}
+	}
+
+	q.Sort, q.Desc = strings.TrimPrefix(sortParam, "-"), strings.HasPrefix(sortParam, "-")
+	field, ok := s.Sorts[q.Sort]
+	if !ok {
+		return nil, fmt.Errorf("cannot sort by %q; sort by one of %s", q.Sort, strings.Join(s.sortNames(), ", "))
+	}
+	q.field = field
+
+	if cursor != "" {
+		key, err := decodeCursor(cursor, sortParam, field.Type)
+		if err != nil {
+			return nil, err
+		}
+		q.After = key
+	}
+
+	return q, nil
+}
+
+// sortNames lists the accepted sort fields for error messages
+func (s *Spec) sortNames() []string {
+	names := make([]string, 0, len(s.Sorts))
+	for name := range s.Sorts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseValue reads a filter or cursor value of the given type. Times are
+// RFC 3339 and are compared in UTC, the zone timestamps are stored in.
+func parseValue(typ Type, value string) (interface{}, error) {
+	switch typ {
+	case Int:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, errors.New("must be an integer")
+		}
+		return n, nil
+	case Time:
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return nil, errors.New("must be an RFC 3339 timestamp")
+		}
+		return timestampParam(t), nil
+	case Date:
+		if _, err := time.Parse("2006-01-02", value); err != nil {
+			return nil, errors.New("must be a date (YYYY-MM-DD)")
+		}
+		return value, nil
+	default:
+		return value, nil
+	}
+}
+
+// timestampParam formats a time in UTC for comparison with a timestamp
+// column. The zone is ignored by TIMESTAMP columns, which hold UTC, and
+// keeps the comparison right for TIMESTAMPTZ ones.
+func timestampParam(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05.999999Z")
+}
+
+// Where returns the WHERE clause for the filters, or an empty string if
+// there are none, with placeholders numbered from $1. If withCursor is set
+// it also skips rows up to and including the cursor; leave it unset to
+// count every match.
+func (q *Query) Where(withCursor bool) (string, []interface{}) {
+	var clauses []string
+	var args []interface{}
+	placeholder := func(value interface{}) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if q.spec.Scope != "" {
+		clauses = append(clauses, q.spec.Scope)
+	}
+
+	for _, c := range q.conditions {
+		value := placeholder(c.value)
+		columns := append([]string{c.filter.Column}, c.filter.Also...)
+		matches := make([]string, len(columns))
+		for i, column := range columns {
+			matches[i] = compare(c.filter.Op, column, value)
+		}
+		if len(matches) == 1 {
+			clauses = append(clauses, matches[0])
+		} else {
+			clauses = append(clauses, "("+strings.Join(matches, " OR ")+")")
+		}
+	}
+
+	if withCursor && q.After != nil {
+		// Comparing (sort, key) as a row keeps rows with equal sort values
+		// in key order across pages
+		operator := ">"
+		if q.Desc {
+			operator = "<"
+		}
+		clauses = append(clauses, fmt.Sprintf("(%s, %s) %s (%s, %s)",
+			q.field.Column, q.spec.Key, operator, placeholder(q.After.Value), placeholder(q.After.ID)))
+	}
+
+	if len(clauses) == 0 {
+		return "", nil
+	}
+	return "WHERE " + strings.Join(clauses, " AND "), args
+}
+
+// compare returns the condition matching column against the placeholder
+// value with op
+func compare(op Op, column, value string) string {
+	switch op {
+	case EqualFold:
+		return fmt.Sprintf("LOWER(%s) = LOWER(%s)", column, value)
+	case After:
+		return fmt.Sprintf("%s > %s", column, value)
+	case Before:
+		return fmt.Sprintf("%s < %s", column, value)
+	case NotBefore:
+		return fmt.Sprintf("%s >= %s", column, value)
+	case PrefixFold:
+		return fmt.Sprintf("STARTS_WITH(LOWER(%s), LOWER(%s))", column, value)
+	default:
+		return fmt.Sprintf("%s = %s", column, value)
+	}
+}
+
+// OrderBy returns the ORDER BY expression, without the keyword
+func (q *Query) OrderBy() string {
+	direction := "ASC"
+	if q.Desc {
+		direction = "DESC"
+	}
+	return fmt.Sprintf("%s %s, %s %s", q.field.Column, direction, q.spec.Key, direction)
+}
+
+// Fetch is the number of rows to select: one more than the page, so NewPage
+// can tell whether there is a next page
+func (q *Query) Fetch() int {
+	return q.Limit + 1
+}
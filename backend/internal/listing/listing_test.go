@@ -0,0 +1,128 @@
+package listing
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+var testSpec = &Spec{
+	Key: "id",
+	Sorts: map[string]Field{
+		"id":         {Column: "id", Type: Int},
+		"email":      {Column: "email", Type: String},
+		"created_at": {Column: "created_at", Type: Time},
+	},
+	DefaultSort: "-created_at",
+	Filters: map[string]Filter{
+		"email":         {Column: "email", Type: String, Op: EqualFold},
+		"created_after": {Column: "created_at", Type: Time, Op: After},
+		"team":          {Column: "team_id", Type: Int, Op: Equal},
+	},
+	MaxLimit: 100,
+}
+
+func TestParse_Defaults(t *testing.T) {
+	q, err := testSpec.Parse(url.Values{})
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if q.Limit != DefaultLimit || q.Sort != "created_at" || !q.Desc || q.After != nil {
+		t.Errorf("Unexpected defaults %+v", q)
+	}
+
+	where, args := q.Where(true)
+	if where != "" || args != nil {
+		t.Errorf("Expected no WHERE clause, got %q %v", where, args)
+	}
+	if order := q.OrderBy(); order != "created_at DESC, id DESC" {
+		t.Errorf("Unexpected ORDER BY %q", order)
+	}
+	if q.Fetch() != DefaultLimit+1 {
+		t.Errorf("Expected to fetch one extra row, got %d", q.Fetch())
+	}
+}
+
+func TestParse_Filters(t *testing.T) {
+	values, _ := url.ParseQuery("email=Ann@Example.com&created_after=2026-03-01T09:00:00%2B01:00&team=4&sort=email&limit=10")
+
+	q, err := testSpec.Parse(values)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if q.Limit != 10 || q.Sort != "email" || q.Desc {
+		t.Errorf("Unexpected query %+v", q)
+	}
+
+	where, args := q.Where(false)
+	expected := "WHERE created_at > $1 AND LOWER(email) = LOWER($2) AND team_id = $3"
+	if where != expected {
+		t.Errorf("Expected %q, got %q", expected, where)
+	}
+	// Times are compared in UTC
+	if !reflect.DeepEqual(args, []interface{}{"2026-03-01 08:00:00Z", "Ann@Example.com", int64(4)}) {
+		t.Errorf("Unexpected arguments %#v", args)
+	}
+}
+
+func TestWhere_ScopeAndAlso(t *testing.T) {
+	spec := &Spec{
+		Key:         "id",
+		Sorts:       map[string]Field{"id": {Column: "id", Type: Int}},
+		DefaultSort: "id",
+		Filters: map[string]Filter{
+			"name":       {Column: "family_name", Type: String, Op: PrefixFold, Also: []string{"given_name"}},
+			"born":       {Column: "birth_date", Type: Date, Op: Equal},
+			"seen_since": {Column: "seen_at", Type: Time, Op: NotBefore},
+		},
+		Scope: "merged_into_id IS NULL",
+	}
+
+	values, _ := url.ParseQuery("name=ok&born=1975-11-02&seen_since=2026-03-01T00:00:00Z")
+	q, err := spec.Parse(values)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	where, args := q.Where(false)
+	expected := "WHERE merged_into_id IS NULL AND birth_date = $1 AND " +
+		"(STARTS_WITH(LOWER(family_name), LOWER($2)) OR STARTS_WITH(LOWER(given_name), LOWER($2))) AND seen_at >= $3"
+	if where != expected {
+		t.Errorf("Expected %q, got %q", expected, where)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"1975-11-02", "ok", "2026-03-01 00:00:00Z"}) {
+		t.Errorf("Unexpected arguments %#v", args)
+	}
+
+	// The scope applies even without filters
+	q, _ = spec.Parse(url.Values{})
+	if where, _ := q.Where(true); where != "WHERE merged_into_id IS NULL" {
+		t.Errorf("Expected only the scope, got %q", where)
+	}
+
+	values, _ = url.ParseQuery("born=02/11/1975")
+	if _, err := spec.Parse(values); err == nil {
+		t.Error("Expected a malformed date to be rejected")
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, query := range []string{
+		"limit=0",
+		"limit=101",
+		"limit=ten",
+		"sort=password_hash",
+		"sort=-name",
+		"created_after=2026-03-01",
+		"team=four",
+		"unknown=1",
+		"email=a&email=b",
+		"cursor=not-a-cursor",
+	} {
+		values, _ := url.ParseQuery(query)
+		if _, err := testSpec.Parse(values); err == nil {
+			t.Errorf("Expected %q to be rejected", query)
+		}
+	}
+}
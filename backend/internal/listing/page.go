@@ -0,0 +1,107 @@
+package listing
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Key is the position of a row in a sorted list: its value of the sort
+// field and its key column
+type Key struct {
+	Value interface{}
+	ID    int64
+}
+
+// cursor is the encoded form of a Key. The sort is recorded so a cursor
+// cannot be replayed against a list sorted differently.
+type cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    int64  `json:"k"`
+}
+
+// encodeCursor encodes the position after key in a list sorted by sort
+func encodeCursor(sort string, key Key) string {
+	c := cursor{Sort: sort, ID: key.ID}
+	switch value := key.Value.(type) {
+	case time.Time:
+		c.Value = value.UTC().Format(time.RFC3339Nano)
+	case int:
+		c.Value = strconv.Itoa(value)
+	case int64:
+		c.Value = strconv.FormatInt(value, 10)
+	default:
+		c.Value = fmt.Sprint(value)
+	}
+
+	// Marshalling a struct of strings and integers cannot fail
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor reads a cursor returned by a previous page of a list sorted
+// by sort
+func decodeCursor(encoded, sort string, typ Type) (*Key, error) {
+	invalid := errors.New("invalid cursor")
+
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, invalid
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, invalid
+	}
+	if c.Sort != sort {
+		return nil, errors.New("cursor was issued for a different sort")
+	}
+
+	value, err := parseValue(typ, c.Value)
+	if err != nil {
+		return nil, invalid
+	}
+	return &Key{Value: value, ID: c.ID}, nil
+}
+
+// Page is the envelope every list endpoint returns
+type Page[T any] struct {
+	Data []T `json:"data"`
+
+	// NextCursor fetches the following page; it is empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+
+	// Total counts every row matching the filters, across all pages. It is
+	// omitted by lists that cannot count cheaply.
+	Total *int `json:"total,omitempty"`
+}
+
+// NewPage builds the page for q from the rows fetched with q.Fetch(). The
+// extra row, if present, is dropped and the cursor set to resume after the
+// last row kept. key returns a row's position for the named sort field.
+func NewPage[T any](rows []T, q *Query, key func(row T, sort string) Key) *Page[T] {
+	page := &Page[T]{Data: rows}
+	if page.Data == nil {
+		page.Data = []T{}
+	}
+
+	if len(rows) > q.Limit {
+		page.Data = rows[:q.Limit]
+		sort := q.Sort
+		if q.Desc {
+			sort = "-" + sort
+		}
+		page.NextCursor = encodeCursor(sort, key(page.Data[q.Limit-1], q.Sort))
+	}
+
+	return page
+}
+
+// WithTotal sets the total number of matching rows
+func (p *Page[T]) WithTotal(total int) *Page[T] {
+	p.Total = &total
+	return p
+}
@@ -0,0 +1,76 @@
+package listing
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testRow struct {
+	ID        int
+	CreatedAt time.Time
+}
+
+func testRowKey(row testRow, sort string) Key {
+	if sort == "created_at" {
+		return Key{Value: row.CreatedAt, ID: int64(row.ID)}
+	}
+	return Key{Value: row.ID, ID: int64(row.ID)}
+}
+
+func TestNewPage(t *testing.T) {
+	q, _ := testSpec.Parse(url.Values{"limit": {"2"}})
+	created := time.Date(2026, 3, 1, 8, 0, 0, 123456000, time.UTC)
+	rows := []testRow{{ID: 9, CreatedAt: created}, {ID: 7, CreatedAt: created}, {ID: 3, CreatedAt: created}}
+
+	page := NewPage(rows, q, testRowKey).WithTotal(5)
+	if len(page.Data) != 2 || *page.Total != 5 {
+		t.Fatalf("Unexpected page %+v", page)
+	}
+	if page.NextCursor == "" {
+		t.Fatal("Expected a next cursor")
+	}
+
+	// The cursor resumes after the last row returned
+	next, err := testSpec.Parse(url.Values{"limit": {"2"}, "cursor": {page.NextCursor}})
+	if err != nil {
+		t.Fatalf("Parse of next page failed: %v", err)
+	}
+	where, args := next.Where(true)
+	if where != "WHERE (created_at, id) < ($1, $2)" {
+		t.Errorf("Unexpected cursor clause %q", where)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"2026-03-01 08:00:00.123456Z", int64(7)}) {
+		t.Errorf("Unexpected cursor arguments %#v", args)
+	}
+
+	// The cursor is only counted when asked for
+	if where, _ := next.Where(false); where != "" {
+		t.Errorf("Expected no WHERE clause for the count, got %q", where)
+	}
+
+	// A cursor cannot be reused with a different sort
+	if _, err := testSpec.Parse(url.Values{"sort": {"created_at"}, "cursor": {page.NextCursor}}); err == nil {
+		t.Error("Expected a cursor for another sort to be rejected")
+	}
+}
+
+func TestNewPage_Last(t *testing.T) {
+	q, _ := testSpec.Parse(url.Values{"sort": {"id"}, "limit": {"2"}})
+
+	page := NewPage([]testRow{{ID: 1}, {ID: 2}}, q, testRowKey)
+	if len(page.Data) != 2 || page.NextCursor != "" || page.Total != nil {
+		t.Errorf("Expected a last page without a cursor or total, got %+v", page)
+	}
+
+	page = NewPage[testRow](nil, q, testRowKey)
+	if page.Data == nil {
+		t.Error("Expected an empty page to encode as an empty list")
+	}
+
+	next, _ := testSpec.Parse(url.Values{"sort": {"id"}, "cursor": {encodeCursor("id", Key{Value: 2, ID: 2})}})
+	if where, args := next.Where(true); where != "WHERE (id, id) > ($1, $2)" || args[0] != int64(2) {
+		t.Errorf("Unexpected ascending cursor clause %q %v", where, args)
+	}
+}
@@ -0,0 +1,103 @@
+// Package logging configures the structured JSON logger and carries a
+// request-scoped logger in the context, so everything logged while serving
+// a request shares its request ID, client IP and, once authenticated, user
+// ID.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"backend/internal/config"
+)
+
+// New creates a JSON logger writing to w at the given level ("debug",
+// "info", "warn" or "error"), redacting the attributes named by policy
+func New(w io.Writer, level string, policy *Policy) (*slog.Logger, error) {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+	return newLogger(w, lvl, policy), nil
+}
+
+// NewFromConfig creates the logger described by LOG_LEVEL and
+// LOG_REDACT_FIELDS. DefaultRedactFields apply unless other fields are
+// listed; "none" turns redaction off. The logger reads its level from
+// level, which is set to LOG_LEVEL and can be changed while it is in use.
+func NewFromConfig(w io.Writer, cfg *config.Config, level *slog.LevelVar) (*slog.Logger, error) {
+	if err := SetLevel(level, cfg.LogLevel); err != nil {
+		return nil, err
+	}
+
+	fields := cfg.LogRedactFields
+	switch {
+	case fields == nil:
+		fields = DefaultRedactFields
+	case len(fields) == 1 && strings.EqualFold(fields[0], "none"):
+		fields = nil
+	}
+	return newLogger(w, level, NewPolicy(fields)), nil
+}
+
+func newLogger(w io.Writer, level slog.Leveler, policy *Policy) *slog.Logger {
+	var handler slog.Handler = slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level})
+	if policy != nil {
+		handler = NewRedactingHandler(handler, policy)
+	}
+	return slog.New(handler)
+}
+
+// SetLevel sets level to the named level, leaving it unchanged if the name
+// is unknown
+func SetLevel(level *slog.LevelVar, name string) error {
+	lvl, err := ParseLevel(name)
+	if err != nil {
+		return err
+	}
+	level.Set(lvl)
+	return nil
+}
+
+// ParseLevel reads a level name, ignoring case. "warning" is accepted for
+// "warn".
+func ParseLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("unknown log level %q", level)
+	}
+}
+
+type contextKey int
+
+const loggerContextKey contextKey = iota
+
+// WithLogger returns a context carrying logger
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey, logger)
+}
+
+// FromContext returns the request's logger, or the default logger outside
+// a request
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerContextKey).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// With returns a context whose logger adds args to every record
+func With(ctx context.Context, args ...interface{}) context.Context {
+	return WithLogger(ctx, FromContext(ctx).With(args...))
+}
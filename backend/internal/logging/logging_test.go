@@ -0,0 +1,112 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"backend/internal/config"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := map[string]slog.Level{
+		"":        slog.LevelInfo,
+		"debug":   slog.LevelDebug,
+		"INFO":    slog.LevelInfo,
+		"warning": slog.LevelWarn,
+		"error":   slog.LevelError,
+	}
+	for name, level := range tests {
+		if got, err := ParseLevel(name); err != nil || got != level {
+			t.Errorf("ParseLevel(%q) = %v, %v; expected %v", name, got, err, level)
+		}
+	}
+
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("Expected an unknown level to be rejected")
+	}
+}
+
+func TestNew_Level(t *testing.T) {
+	var out bytes.Buffer
+	logger, err := New(&out, "warn", nil)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	logger.Info("ignored")
+	logger.Warn("kept", "job_id", 7)
+
+	var line map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &line); err != nil {
+		t.Fatalf("Expected one JSON line, got %q", out.String())
+	}
+	if line["msg"] != "kept" || line["level"] != "WARN" || line["job_id"] != float64(7) {
+		t.Errorf("Unexpected log line %v", line)
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	if FromContext(context.Background()) != slog.Default() {
+		t.Error("Expected the default logger outside a request")
+	}
+
+	var out bytes.Buffer
+	logger, _ := New(&out, "info", nil)
+	ctx := With(WithLogger(context.Background(), logger), "request_id", "req-1")
+	FromContext(ctx).Info("hello")
+
+	if !strings.Contains(out.String(), `"request_id":"req-1"`) {
+		t.Errorf("Expected the request's attributes, got %q", out.String())
+	}
+}
+
+func TestNewFromConfig_Redaction(t *testing.T) {
+	tests := []struct {
+		fields   []string
+		redacted bool
+	}{
+		{nil, true},
+		{[]string{"token"}, false},
+		{[]string{"none"}, false},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		logger, err := NewFromConfig(&out, &config.Config{LogLevel: "info", LogRedactFields: tt.fields}, new(slog.LevelVar))
+		if err != nil {
+			t.Fatalf("NewFromConfig failed: %v", err)
+		}
+		logger.Info("login", "email", "jane@example.com")
+
+		if redacted := !strings.Contains(out.String(), "jane@example.com"); redacted != tt.redacted {
+			t.Errorf("Fields %v: expected email redacted %v, got %q", tt.fields, tt.redacted, out.String())
+		}
+	}
+}
+
+func TestNewFromConfig_LevelChange(t *testing.T) {
+	var out bytes.Buffer
+	level := new(slog.LevelVar)
+	logger, err := NewFromConfig(&out, &config.Config{LogLevel: "warn"}, level)
+	if err != nil {
+		t.Fatalf("NewFromConfig failed: %v", err)
+	}
+	requestLogger := logger.With("request_id", "req-1")
+
+	requestLogger.Info("before")
+	if err := SetLevel(level, "debug"); err != nil {
+		t.Fatalf("SetLevel failed: %v", err)
+	}
+	requestLogger.Debug("after")
+
+	if strings.Contains(out.String(), "before") || !strings.Contains(out.String(), "after") {
+		t.Errorf("Expected derived loggers to follow the level change, got %q", out.String())
+	}
+	if err := SetLevel(level, "verbose"); err == nil || level.Level() != slog.LevelDebug {
+		t.Errorf("Expected an unknown level to be rejected and ignored, got %v at %s", err, level.Level())
+	}
+}
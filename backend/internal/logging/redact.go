@@ -0,0 +1,103 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+)
+
+// Redacted replaces the value of a redacted attribute
+const Redacted = "[REDACTED]"
+
+// DefaultRedactFields are the attribute keys redacted unless
+// LOG_REDACT_FIELDS says otherwise: credentials, contact details and patient
+// identifiers
+var DefaultRedactFields = []string{
+	"password", "token", "secret", "authorization", "cookie", "api_key",
+	"email", "phone", "address",
+	"mrn", "patient_name", "family_name", "given_name", "birth_date",
+}
+
+// Policy names the attribute keys whose values must never reach the logs.
+// Keys match ignoring case, at any depth of grouping.
+type Policy struct {
+	keys map[string]bool
+}
+
+// NewPolicy redacts the given keys. An empty list redacts nothing.
+func NewPolicy(keys []string) *Policy {
+	p := &Policy{keys: make(map[string]bool, len(keys))}
+	for _, key := range keys {
+		if key = strings.ToLower(strings.TrimSpace(key)); key != "" {
+			p.keys[key] = true
+		}
+	}
+	return p
+}
+
+// Redacts reports whether values under key are redacted
+func (p *Policy) Redacts(key string) bool {
+	return p.keys[strings.ToLower(key)]
+}
+
+// redact returns attr with its value, or those of its group members,
+// replaced if the policy covers them
+func (p *Policy) redact(attr slog.Attr) slog.Attr {
+	if p.Redacts(attr.Key) {
+		return slog.String(attr.Key, Redacted)
+	}
+
+	value := attr.Value.Resolve()
+	if value.Kind() != slog.KindGroup {
+		return slog.Attr{Key: attr.Key, Value: value}
+	}
+
+	members := value.Group()
+	redacted := make([]slog.Attr, len(members))
+	for i, member := range members {
+		redacted[i] = p.redact(member)
+	}
+	return slog.Attr{Key: attr.Key, Value: slog.GroupValue(redacted...)}
+}
+
+// RedactingHandler applies a Policy to every attribute before passing
+// records on
+type RedactingHandler struct {
+	next   slog.Handler
+	policy *Policy
+}
+
+// NewRedactingHandler wraps next so attributes covered by policy are
+// redacted
+func NewRedactingHandler(next slog.Handler, policy *Policy) *RedactingHandler {
+	return &RedactingHandler{next: next, policy: policy}
+}
+
+// Enabled reports whether the wrapped handler handles records at level
+func (h *RedactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle redacts the record's attributes and passes it on
+func (h *RedactingHandler) Handle(ctx context.Context, record slog.Record) error {
+	redacted := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		redacted.AddAttrs(h.policy.redact(attr))
+		return true
+	})
+	return h.next.Handle(ctx, redacted)
+}
+
+// WithAttrs redacts attributes added with Logger.With
+func (h *RedactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		redacted[i] = h.policy.redact(attr)
+	}
+	return &RedactingHandler{next: h.next.WithAttrs(redacted), policy: h.policy}
+}
+
+// WithGroup starts a group in the wrapped handler
+func (h *RedactingHandler) WithGroup(name string) slog.Handler {
+	return &RedactingHandler{next: h.next.WithGroup(name), policy: h.policy}
+}
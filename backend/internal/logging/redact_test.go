@@ -0,0 +1,46 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestRedactingHandler(t *testing.T) {
+	var out bytes.Buffer
+	logger, _ := New(&out, "info", NewPolicy([]string{"Email", "mrn", "token"}))
+
+	logger.With("token", "secret-token").Info("registered",
+		"EMAIL", "jane@example.com",
+		"user_id", 7,
+		slog.Group("patient", "mrn", "MRN000123", "sex", "female"),
+	)
+
+	var line map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &line); err != nil {
+		t.Fatalf("Expected one JSON line, got %q", out.String())
+	}
+
+	if line["token"] != Redacted {
+		t.Errorf("Expected attributes added with With to be redacted, got %v", line["token"])
+	}
+	if line["EMAIL"] != Redacted {
+		t.Errorf("Expected keys to match ignoring case, got %v", line["EMAIL"])
+	}
+	if line["user_id"] != float64(7) {
+		t.Errorf("Expected other attributes to be kept, got %v", line["user_id"])
+	}
+
+	patient, _ := line["patient"].(map[string]interface{})
+	if patient["mrn"] != Redacted || patient["sex"] != "female" {
+		t.Errorf("Expected grouped attributes to be redacted by key, got %v", patient)
+	}
+}
+
+func TestPolicy_Empty(t *testing.T) {
+	policy := NewPolicy([]string{" ", ""})
+	if policy.Redacts("email") || policy.Redacts("") {
+		t.Error("Expected an empty policy to redact nothing")
+	}
+}
@@ -0,0 +1,277 @@
+package models
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"backend/internal/listing"
+)
+
+// auditChainLock is the advisory lock key that serializes appends to the
+// audit log, so every entry links to the one committed before it
+const auditChainLock = 0x61756469
+
+// AuditEntry is one record in the append-only audit log. Hash covers the
+// entry's content and PrevHash, the hash of the entry before it, so altering
+// or removing a past entry breaks the chain from that point on.
+type AuditEntry struct {
+	ID           int64           `json:"id"`
+	OccurredAt   time.Time       `json:"occurred_at"`
+	ActorID      *int            `json:"actor_id"`
+	ActorEmail   string          `json:"actor_email"`
+	APIKeyID     *int            `json:"api_key_id"`
+	Action       string          `json:"action"`
+	ResourceType string          `json:"resource_type"`
+	ResourceID   string          `json:"resource_id"`
+	Changes      json.RawMessage `json:"changes,omitempty"`
+	Details      json.RawMessage `json:"details,omitempty"`
+	RequestID    string          `json:"request_id"`
+	IP           string          `json:"ip"`
+	PrevHash     string          `json:"prev_hash"`
+	Hash         string          `json:"hash"`
+}
+
+// ComputeHash returns the chain hash of the entry. JSON fields are hashed in
+// canonical form, since Postgres does not preserve the key order or spacing
+// of JSONB values.
+func (e *AuditEntry) ComputeHash() (string, error) {
+	changes, err := canonicalJSON(e.Changes)
+	if err != nil {
+		return "", err
+	}
+	details, err := canonicalJSON(e.Details)
+	if err != nil {
+		return "", err
+	}
+
+	record, err := json.Marshal(struct {
+		PrevHash     string          `json:"prev_hash"`
+		OccurredAt   string          `json:"occurred_at"`
+		ActorID      *int            `json:"actor_id"`
+		ActorEmail   string          `json:"actor_email"`
+		APIKeyID     *int            `json:"api_key_id"`
+		Action       string          `json:"action"`
+		ResourceType string          `json:"resource_type"`
+		ResourceID   string          `json:"resource_id"`
+		Changes      json.RawMessage `json:"changes"`
+		Details      json.RawMessage `json:"details"`
+		RequestID    string          `json:"request_id"`
+		IP           string          `json:"ip"`
+	}{
+		e.PrevHash, e.OccurredAt.UTC().Format(time.RFC3339Nano), e.ActorID, e.ActorEmail, e.APIKeyID,
+		e.Action, e.ResourceType, e.ResourceID, changes, details, e.RequestID, e.IP,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(record)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// canonicalJSON re-encodes a JSON value with sorted keys and no spacing.
+// Empty input stays empty.
+func canonicalJSON(raw json.RawMessage) (json.RawMessage, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+// AuditList is the list spec of GET /api/audit. Entries occurred at or
+// after from and before to; either bound may be omitted.
+var AuditList = &listing.Spec{
+	Key: "id",
+	Sorts: map[string]listing.Field{
+		"id": {Column: "id", Type: listing.Int},
+	},
+	DefaultSort: "-id",
+	Filters: map[string]listing.Filter{
+		"actor_id":      {Column: "actor_id", Type: listing.Int, Op: listing.Equal},
+		"action":        {Column: "action", Type: listing.String, Op: listing.Equal},
+		"resource_type": {Column: "resource_type", Type: listing.String, Op: listing.Equal},
+		"resource_id":   {Column: "resource_id", Type: listing.String, Op: listing.Equal},
+		"request_id":    {Column: "request_id", Type: listing.String, Op: listing.Equal},
+		"from":          {Column: "occurred_at", Type: listing.Time, Op: listing.NotBefore},
+		"to":            {Column: "occurred_at", Type: listing.Time, Op: listing.Before},
+	},
+	DefaultLimit: 100,
+	MaxLimit:     1000,
+}
+
+// ListKey is the entry's position in a list sorted by the named AuditList
+// field
+func (e AuditEntry) ListKey(string) listing.Key {
+	return listing.Key{Value: e.ID, ID: e.ID}
+}
+
+// AuditVerification is the outcome of re-walking the audit log's hash chain
+type AuditVerification struct {
+	Valid          bool   `json:"valid"`
+	Checked        int64  `json:"checked"`
+	FirstInvalidID *int64 `json:"first_invalid_id,omitempty"`
+}
+
+// AuditRepository handles database operations for the audit log. Entries
+// can only be appended; a trigger rejects updates and deletes.
+type AuditRepository struct {
+	db DBTX
+}
+
+// NewAuditRepository creates a new audit repository. Created on a
+// transaction, entries are appended as part of it.
+func NewAuditRepository(db DBTX) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// auditColumns is the column list scanned by scanAuditEntry
+const auditColumns = `id, occurred_at, actor_id, actor_email, api_key_id, action, resource_type, resource_id,
+	changes, details, request_id, ip, prev_hash, hash`
+
+// scanAuditEntry scans a row selected with auditColumns
+func scanAuditEntry(row interface{ Scan(...interface{}) error }) (*AuditEntry, error) {
+	var entry AuditEntry
+	var actorID, apiKeyID sql.NullInt64
+	var changes, details []byte
+
+	err := row.Scan(&entry.ID, &entry.OccurredAt, &actorID, &entry.ActorEmail, &apiKeyID, &entry.Action,
+		&entry.ResourceType, &entry.ResourceID, &changes, &details, &entry.RequestID, &entry.IP,
+		&entry.PrevHash, &entry.Hash)
+	if err != nil {
+		return nil, err
+	}
+
+	entry.ActorID = nullIntPtr(actorID)
+	entry.APIKeyID = nullIntPtr(apiKeyID)
+	entry.Changes = changes
+	entry.Details = details
+
+	return &entry, nil
+}
+
+// Append links an entry to the end of the chain and stores it, setting its
+// ID, PrevHash and Hash. OccurredAt is rounded to the database's microsecond
+// precision so the hash can be recomputed from the stored row.
+func (r *AuditRepository) Append(ctx context.Context, entry *AuditEntry) error {
+	if entry.OccurredAt.IsZero() {
+		entry.OccurredAt = time.Now()
+	}
+	entry.OccurredAt = entry.OccurredAt.UTC().Truncate(time.Microsecond)
+
+	// Inside a larger transaction the chain stays locked until it commits,
+	// so the next entry links to this one only once it is permanent
+	return withTx(ctx, r.db, func(tx DBTX) error {
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, auditChainLock); err != nil {
+			return err
+		}
+
+		err := tx.QueryRowContext(ctx, `SELECT hash FROM audit_log ORDER BY id DESC LIMIT 1`).Scan(&entry.PrevHash)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+
+		if entry.Hash, err = entry.ComputeHash(); err != nil {
+			return err
+		}
+
+		query := `INSERT INTO audit_log (occurred_at, actor_id, actor_email, api_key_id, action, resource_type,
+				  resource_id, changes, details, request_id, ip, prev_hash, hash)
+				  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+				  RETURNING id`
+
+		return tx.QueryRowContext(ctx, query, entry.OccurredAt, entry.ActorID, entry.ActorEmail, entry.APIKeyID, entry.Action,
+			entry.ResourceType, entry.ResourceID, jsonParam(entry.Changes), jsonParam(entry.Details),
+			entry.RequestID, entry.IP, entry.PrevHash, entry.Hash).Scan(&entry.ID)
+	})
+}
+
+// jsonParam passes a JSONB value as text, or NULL when empty
+func jsonParam(raw json.RawMessage) interface{} {
+	if len(raw) == 0 {
+		return nil
+	}
+	return string(raw)
+}
+
+// List retrieves one page of entries, fetching q.Fetch() rows so the caller
+// can tell whether there are more. The log grows without bound, so matches
+// are not counted.
+func (r *AuditRepository) List(ctx context.Context, q *listing.Query) ([]AuditEntry, error) {
+	where, args := q.Where(true)
+	args = append(args, q.Fetch())
+	query := fmt.Sprintf(`SELECT %s FROM audit_log %s ORDER BY %s LIMIT $%d`,
+		auditColumns, where, q.OrderBy(), len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []AuditEntry
+	for rows.Next() {
+		entry, err := scanAuditEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// Verify re-walks the whole chain in order, recomputing each hash, and
+// reports the first entry that does not match
+func (r *AuditRepository) Verify(ctx context.Context) (*AuditVerification, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+auditColumns+` FROM audit_log ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	verification := &AuditVerification{Valid: true}
+	prevHash := ""
+	for rows.Next() {
+		entry, err := scanAuditEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		ok, err := entry.linksTo(prevHash)
+		if err != nil {
+			return nil, err
+		}
+		verification.Checked++
+		if !ok {
+			verification.Valid = false
+			verification.FirstInvalidID = &entry.ID
+			return verification, nil
+		}
+		prevHash = entry.Hash
+	}
+
+	return verification, rows.Err()
+}
+
+// linksTo reports whether the entry follows prevHash and its stored hash
+// matches its content
+func (e *AuditEntry) linksTo(prevHash string) (bool, error) {
+	if e.PrevHash != prevHash {
+		return false, nil
+	}
+	hash, err := e.ComputeHash()
+	if err != nil {
+		return false, err
+	}
+	return hash == e.Hash, nil
+}
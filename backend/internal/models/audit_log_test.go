@@ -0,0 +1,161 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func testAuditEntry() *AuditEntry {
+	actorID := 7
+	return &AuditEntry{
+		OccurredAt:   time.Date(2026, 3, 1, 12, 0, 0, 123456000, time.UTC),
+		ActorID:      &actorID,
+		ActorEmail:   "pathologist@example.com",
+		Action:       "test_order.update",
+		ResourceType: "test_order",
+		ResourceID:   "42",
+		Changes:      json.RawMessage(`{"priority":{"before":"routine","after":"stat"}}`),
+		RequestID:    "req-1",
+		IP:           "203.0.113.9",
+	}
+}
+
+func TestAuditEntry_ComputeHash_Canonical(t *testing.T) {
+	entry := testAuditEntry()
+	hash, err := entry.ComputeHash()
+	if err != nil {
+		t.Fatalf("ComputeHash failed: %v", err)
+	}
+	if len(hash) != 64 {
+		t.Fatalf("Expected hex SHA-256, got %q", hash)
+	}
+
+	// Postgres returns JSONB with its own key order and spacing, and
+	// timestamps in the session time zone
+	stored := *entry
+	stored.Changes = json.RawMessage(`{"priority": {"after": "stat", "before": "routine"}}`)
+	stored.OccurredAt = entry.OccurredAt.In(time.FixedZone("CET", 3600))
+
+	if got, _ := stored.ComputeHash(); got != hash {
+		t.Errorf("Expected the stored form to hash the same, got %s and %s", got, hash)
+	}
+}
+
+func TestAuditEntry_ComputeHash_CoversContent(t *testing.T) {
+	hash, _ := testAuditEntry().ComputeHash()
+
+	tampered := []func(e *AuditEntry){
+		func(e *AuditEntry) { e.PrevHash = "00" },
+		func(e *AuditEntry) { e.ActorEmail = "someone@example.com" },
+		func(e *AuditEntry) { e.ActorID = nil },
+		func(e *AuditEntry) { e.Action = "test_order.read" },
+		func(e *AuditEntry) { e.Changes = json.RawMessage(`{"priority":{"before":"routine","after":"urgent"}}`) },
+		func(e *AuditEntry) { e.OccurredAt = e.OccurredAt.Add(time.Microsecond) },
+		func(e *AuditEntry) { e.IP = "198.51.100.1" },
+	}
+
+	for i, tamper := range tampered {
+		entry := testAuditEntry()
+		tamper(entry)
+		if got, _ := entry.ComputeHash(); got == hash {
+			t.Errorf("Change %d did not alter the hash", i)
+		}
+	}
+}
+
+func TestAuditEntry_LinksTo(t *testing.T) {
+	first := testAuditEntry()
+	first.Hash, _ = first.ComputeHash()
+
+	second := testAuditEntry()
+	second.Action = "test_order.read"
+	second.PrevHash = first.Hash
+	second.Hash, _ = second.ComputeHash()
+
+	if ok, err := first.linksTo(""); !ok || err != nil {
+		t.Errorf("Expected the first entry to start the chain, got %v %v", ok, err)
+	}
+	if ok, _ := second.linksTo(first.Hash); !ok {
+		t.Error("Expected the second entry to link to the first")
+	}
+
+	// Removing the first entry breaks the link
+	if ok, _ := second.linksTo(""); ok {
+		t.Error("Expected a gap in the chain to be detected")
+	}
+
+	// Editing an entry in place breaks its own hash
+	second.ResourceID = "43"
+	if ok, _ := second.linksTo(first.Hash); ok {
+		t.Error("Expected an edited entry to be detected")
+	}
+}
+
+func TestInTx_AuditEntryCommitsWithChange(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	create := func(fail bool) (*User, error) {
+		user := &User{Name: "Audited User", Email: fmt.Sprintf("audited-%d@example.com", time.Now().UnixNano())}
+		err := InTx(ctx, db, func(tx *sql.Tx) error {
+			if err := NewUserRepository(tx).Create(ctx, user); err != nil {
+				return err
+			}
+			entry := &AuditEntry{Action: "user.create", ResourceType: "user", ResourceID: strconv.Itoa(user.ID)}
+			if err := NewAuditRepository(tx).Append(ctx, entry); err != nil {
+				return err
+			}
+			if fail {
+				return errors.New("change rejected")
+			}
+			return nil
+		})
+		return user, err
+	}
+
+	entries := func(user *User) int {
+		var n int
+		err := db.QueryRow(`SELECT COUNT(*) FROM audit_log WHERE resource_type = 'user' AND resource_id = $1`,
+			strconv.Itoa(user.ID)).Scan(&n)
+		if err != nil {
+			t.Fatalf("Failed to count audit entries: %v", err)
+		}
+		return n
+	}
+
+	rolledBack, err := create(true)
+	if err == nil {
+		t.Fatal("Expected the transaction to fail")
+	}
+	if found, _ := NewUserRepository(db).GetByEmail(ctx, rolledBack.Email); found != nil {
+		t.Error("Expected the user to be rolled back")
+	}
+	if n := entries(rolledBack); n != 0 {
+		t.Errorf("Expected the audit entry to be rolled back with the change, got %d", n)
+	}
+
+	committed, err := create(false)
+	if err != nil {
+		t.Fatalf("InTx failed: %v", err)
+	}
+	if found, _ := NewUserRepository(db).GetByEmail(ctx, committed.Email); found == nil {
+		t.Error("Expected the user to be committed")
+	}
+	if n := entries(committed); n != 1 {
+		t.Errorf("Expected one audit entry committed with the change, got %d", n)
+	}
+
+	verification, err := NewAuditRepository(db).Verify(ctx)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if !verification.Valid {
+		t.Errorf("Expected the chain to stay intact, broken at %v", verification.FirstInvalidID)
+	}
+}
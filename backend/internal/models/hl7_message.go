@@ -0,0 +1,202 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// HL7Direction says whether a message was received or sent
+type HL7Direction string
+
+const (
+	HL7Inbound  HL7Direction = "inbound"
+	HL7Outbound HL7Direction = "outbound"
+)
+
+// HL7MessageStatus is the processing state of a logged HL7 message
+type HL7MessageStatus string
+
+const (
+	// HL7Received inbound messages are being processed
+	HL7Received HL7MessageStatus = "received"
+	// HL7Pending outbound messages are waiting to be acknowledged
+	HL7Pending HL7MessageStatus = "pending"
+	// HL7Accepted messages were acknowledged with AA
+	HL7Accepted HL7MessageStatus = "accepted"
+	// HL7Rejected messages were acknowledged with AE or AR
+	HL7Rejected HL7MessageStatus = "rejected"
+	// HL7Failed outbound messages could not be delivered
+	HL7Failed HL7MessageStatus = "failed"
+)
+
+// HL7Message is an entry in the HL7 message log. Inbound entries record
+// the ACK sent back; outbound entries the ACK received and delivery attempts.
+type HL7Message struct {
+	ID                 int64            `json:"id"`
+	Direction          HL7Direction     `json:"direction"`
+	MessageType        string           `json:"message_type"`
+	ControlID          string           `json:"control_id"`
+	SendingApplication string           `json:"sending_application"`
+	SendingFacility    string           `json:"sending_facility"`
+	Body               string           `json:"body"`
+	Status             HL7MessageStatus `json:"status"`
+	AckCode            string           `json:"ack_code"`
+	AckBody            string           `json:"ack_body"`
+	Error              string           `json:"error"`
+	TestOrderID        *int             `json:"test_order_id"`
+	Attempts           int              `json:"attempts"`
+	NextAttemptAt      *time.Time       `json:"next_attempt_at"`
+	CreatedAt          time.Time        `json:"created_at"`
+	ProcessedAt        *time.Time       `json:"processed_at"`
+}
+
+// HL7MessageRepository handles database operations for the HL7 message log
+type HL7MessageRepository struct {
+	db *sql.DB
+}
+
+// NewHL7MessageRepository creates a new HL7 message repository
+func NewHL7MessageRepository(db *sql.DB) *HL7MessageRepository {
+	return &HL7MessageRepository{db: db}
+}
+
+// hl7MessageColumns is the column list scanned by scanHL7Message
+const hl7MessageColumns = `id, direction, message_type, control_id, sending_application, sending_facility, body,
+	status, ack_code, ack_body, error, test_order_id, attempts, next_attempt_at, created_at, processed_at`
+
+// scanHL7Message scans a row selected with hl7MessageColumns
+func scanHL7Message(row interface{ Scan(...interface{}) error }) (*HL7Message, error) {
+	var msg HL7Message
+	var testOrderID sql.NullInt64
+	var nextAttemptAt, processedAt sql.NullTime
+
+	err := row.Scan(&msg.ID, &msg.Direction, &msg.MessageType, &msg.ControlID, &msg.SendingApplication,
+		&msg.SendingFacility, &msg.Body, &msg.Status, &msg.AckCode, &msg.AckBody, &msg.Error, &testOrderID,
+		&msg.Attempts, &nextAttemptAt, &msg.CreatedAt, &processedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	msg.TestOrderID = nullIntPtr(testOrderID)
+	msg.NextAttemptAt = nullTimePtr(nextAttemptAt)
+	msg.ProcessedAt = nullTimePtr(processedAt)
+
+	return &msg, nil
+}
+
+// LogInbound records a received message in the received status. If the
+// sender already sent a message with the same control ID, nothing is
+// inserted and that earlier entry is returned with created false.
+func (r *HL7MessageRepository) LogInbound(ctx context.Context, msg *HL7Message) (*HL7Message, bool, error) {
+	query := `INSERT INTO hl7_messages (direction, message_type, control_id, sending_application, sending_facility,
+			  body, status)
+			  VALUES ('inbound', $1, $2, $3, $4, $5, 'received')
+			  ON CONFLICT (sending_application, sending_facility, control_id) WHERE direction = 'inbound'
+			  DO NOTHING
+			  RETURNING ` + hl7MessageColumns
+
+	logged, err := scanHL7Message(r.db.QueryRowContext(ctx, query, msg.MessageType, msg.ControlID, msg.SendingApplication,
+		msg.SendingFacility, msg.Body))
+	if err == nil {
+		return logged, true, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, false, err
+	}
+
+	query = `SELECT ` + hl7MessageColumns + ` FROM hl7_messages
+			 WHERE direction = 'inbound' AND sending_application = $1 AND sending_facility = $2 AND control_id = $3`
+
+	existing, err := scanHL7Message(r.db.QueryRowContext(ctx, query, msg.SendingApplication, msg.SendingFacility, msg.ControlID))
+	if err != nil {
+		return nil, false, err
+	}
+	return existing, false, nil
+}
+
+// FinishInbound records the ACK sent for a received message
+func (r *HL7MessageRepository) FinishInbound(ctx context.Context, id int64, status HL7MessageStatus, ackCode, ackBody, message string) error {
+	query := `UPDATE hl7_messages SET status = $1, ack_code = $2, ack_body = $3, error = $4,
+			  processed_at = CURRENT_TIMESTAMP
+			  WHERE id = $5 AND direction = 'inbound'`
+
+	return execAffectingOne(ctx, r.db, query, string(status), ackCode, ackBody, message, id)
+}
+
+// Enqueue adds an outbound message to be delivered as soon as possible
+func (r *HL7MessageRepository) Enqueue(ctx context.Context, msg *HL7Message) error {
+	query := `INSERT INTO hl7_messages (direction, message_type, control_id, sending_application, sending_facility,
+			  body, status, test_order_id, next_attempt_at)
+			  VALUES ('outbound', $1, $2, $3, $4, $5, 'pending', $6, CURRENT_TIMESTAMP)
+			  RETURNING ` + hl7MessageColumns
+
+	queued, err := scanHL7Message(r.db.QueryRowContext(ctx, query, msg.MessageType, msg.ControlID, msg.SendingApplication,
+		msg.SendingFacility, msg.Body, msg.TestOrderID))
+	if err != nil {
+		return err
+	}
+
+	*msg = *queued
+	return nil
+}
+
+// ClaimOutbound locks the oldest pending message that is due, counting a
+// delivery attempt. The message is not offered again until the lease
+// passes, so one left behind by a crashed sender is retried.
+func (r *HL7MessageRepository) ClaimOutbound(ctx context.Context, lease time.Duration) (*HL7Message, error) {
+	query := `UPDATE hl7_messages SET attempts = attempts + 1,
+			  next_attempt_at = CURRENT_TIMESTAMP + $1 * INTERVAL '1 millisecond'
+			  WHERE id = (
+				  SELECT id FROM hl7_messages
+				  WHERE status = 'pending' AND next_attempt_at <= CURRENT_TIMESTAMP
+				  ORDER BY id
+				  LIMIT 1
+				  FOR UPDATE SKIP LOCKED
+			  )
+			  RETURNING ` + hl7MessageColumns
+
+	msg, err := scanHL7Message(r.db.QueryRowContext(ctx, query, lease.Milliseconds()))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+// Acknowledged records the ACK received for a claimed message: AA accepts
+// it, any other code rejects it. It returns sql.ErrNoRows if the claim was
+// lost.
+func (r *HL7MessageRepository) Acknowledged(ctx context.Context, msg *HL7Message, ackCode, ackBody string) error {
+	status := HL7Rejected
+	if ackCode == "AA" {
+		status = HL7Accepted
+	}
+
+	query := `UPDATE hl7_messages SET status = $1, ack_code = $2, ack_body = $3, error = '',
+			  next_attempt_at = NULL, processed_at = CURRENT_TIMESTAMP
+			  WHERE id = $4 AND status = 'pending' AND attempts = $5`
+
+	return execAffectingOne(ctx, r.db, query, string(status), ackCode, ackBody, msg.ID, msg.Attempts)
+}
+
+// Undelivered records a failed delivery attempt. With a positive retryAfter
+// the message is tried again after that delay; otherwise it is marked
+// failed. It returns sql.ErrNoRows if the claim was lost.
+func (r *HL7MessageRepository) Undelivered(ctx context.Context, msg *HL7Message, message string, retryAfter time.Duration) error {
+	if retryAfter > 0 {
+		query := `UPDATE hl7_messages SET error = $1, next_attempt_at = CURRENT_TIMESTAMP + $2 * INTERVAL '1 millisecond'
+				  WHERE id = $3 AND status = 'pending' AND attempts = $4`
+
+		return execAffectingOne(ctx, r.db, query, message, retryAfter.Milliseconds(), msg.ID, msg.Attempts)
+	}
+
+	query := `UPDATE hl7_messages SET status = 'failed', error = $1, next_attempt_at = NULL,
+			  processed_at = CURRENT_TIMESTAMP
+			  WHERE id = $2 AND status = 'pending' AND attempts = $3`
+
+	return execAffectingOne(ctx, r.db, query, message, msg.ID, msg.Attempts)
+}
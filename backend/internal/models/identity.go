@@ -0,0 +1,63 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// UserIdentity links a user to an account at an external identity provider
+type UserIdentity struct {
+	ID          int       `json:"id"`
+	UserID      int       `json:"user_id"`
+	Issuer      string    `json:"issuer"`
+	Subject     string    `json:"subject"`
+	Email       string    `json:"email"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastLoginAt time.Time `json:"last_login_at"`
+}
+
+// UserIdentityRepository handles database operations for external identities
+type UserIdentityRepository struct {
+	db *sql.DB
+}
+
+// NewUserIdentityRepository creates a new user identity repository
+func NewUserIdentityRepository(db *sql.DB) *UserIdentityRepository {
+	return &UserIdentityRepository{db: db}
+}
+
+// GetUser retrieves the user linked to an issuer and subject
+func (r *UserIdentityRepository) GetUser(ctx context.Context, issuer, subject string) (*User, error) {
+	query := `SELECT u.id, u.name, u.email, u.created_at, u.updated_at
+			  FROM users u JOIN user_identities i ON i.user_id = u.id
+			  WHERE i.issuer = $1 AND i.subject = $2`
+
+	var user User
+	err := r.db.QueryRowContext(ctx, query, issuer, subject).Scan(&user.ID, &user.Name, &user.Email, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// Create links a new external identity to a user
+func (r *UserIdentityRepository) Create(ctx context.Context, identity *UserIdentity) error {
+	query := `INSERT INTO user_identities (user_id, issuer, subject, email) VALUES ($1, $2, $3, $4)
+			  RETURNING id, created_at, last_login_at`
+
+	return r.db.QueryRowContext(ctx, query, identity.UserID, identity.Issuer, identity.Subject, identity.Email).
+		Scan(&identity.ID, &identity.CreatedAt, &identity.LastLoginAt)
+}
+
+// TouchLogin records a successful login through an identity
+func (r *UserIdentityRepository) TouchLogin(ctx context.Context, issuer, subject string, at time.Time) error {
+	query := `UPDATE user_identities SET last_login_at = $1 WHERE issuer = $2 AND subject = $3`
+
+	_, err := r.db.ExecContext(ctx, query, at, issuer, subject)
+	return err
+}
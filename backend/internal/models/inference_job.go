@@ -0,0 +1,270 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"backend/internal/listing"
+)
+
+// InferenceJobStatus is the state of a queued model inference
+type InferenceJobStatus string
+
+const (
+	InferenceJobQueued    InferenceJobStatus = "queued"
+	InferenceJobRunning   InferenceJobStatus = "running"
+	InferenceJobSucceeded InferenceJobStatus = "succeeded"
+	InferenceJobFailed    InferenceJobStatus = "failed"
+)
+
+// Valid reports whether s is a known status
+func (s InferenceJobStatus) Valid() bool {
+	switch s {
+	case InferenceJobQueued, InferenceJobRunning, InferenceJobSucceeded, InferenceJobFailed:
+		return true
+	}
+	return false
+}
+
+// InferenceJob is a request to run the AlphaPath model, processed
+// asynchronously by the worker pool
+type InferenceJob struct {
+	ID           int                `json:"id"`
+	TestOrderID  *int               `json:"test_order_id"`
+	Model        string             `json:"model"`
+	ModelVersion string             `json:"model_version"`
+	Input        json.RawMessage    `json:"input"`
+	Status       InferenceJobStatus `json:"status"`
+	Output       json.RawMessage    `json:"output,omitempty"`
+	Error        string             `json:"error,omitempty"`
+	Attempts     int                `json:"attempts"`
+	MaxAttempts  int                `json:"max_attempts"`
+	RunAfter     time.Time          `json:"run_after"`
+	LatencyMS    *int               `json:"latency_ms"`
+	CreatedBy    *int               `json:"created_by"`
+	CreatedAt    time.Time          `json:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at"`
+	StartedAt    *time.Time         `json:"started_at"`
+	FinishedAt   *time.Time         `json:"finished_at"`
+}
+
+// InferenceJobList is what GET /api/inference/jobs can be sorted and
+// filtered by
+var InferenceJobList = &listing.Spec{
+	Key: "id",
+	Sorts: map[string]listing.Field{
+		"id":        {Column: "id", Type: listing.Int},
+		"run_after": {Column: "run_after", Type: listing.Time},
+	},
+	DefaultSort: "-id",
+	Filters: map[string]listing.Filter{
+		"status":        {Column: "status", Type: listing.String, Op: listing.Equal},
+		"model":         {Column: "model", Type: listing.String, Op: listing.Equal},
+		"test_order_id": {Column: "test_order_id", Type: listing.Int, Op: listing.Equal},
+	},
+}
+
+// ListKey is the job's position in a list sorted by the named
+// InferenceJobList field
+func (j InferenceJob) ListKey(sort string) listing.Key {
+	key := listing.Key{ID: int64(j.ID)}
+	if sort == "run_after" {
+		key.Value = j.RunAfter
+	} else {
+		key.Value = j.ID
+	}
+	return key
+}
+
+// InferenceJobRepository handles database operations for inference jobs
+type InferenceJobRepository struct {
+	db *sql.DB
+}
+
+// NewInferenceJobRepository creates a new inference job repository
+func NewInferenceJobRepository(db *sql.DB) *InferenceJobRepository {
+	return &InferenceJobRepository{db: db}
+}
+
+// inferenceJobColumns is the column list scanned by scanInferenceJob
+const inferenceJobColumns = `id, test_order_id, model, model_version, input, status, output, error, attempts,
+	max_attempts, run_after, latency_ms, created_by, created_at, updated_at, started_at, finished_at`
+
+// scanInferenceJob scans a row selected with inferenceJobColumns
+func scanInferenceJob(row interface{ Scan(...interface{}) error }) (*InferenceJob, error) {
+	var job InferenceJob
+	var input, output []byte
+	var testOrderID, latency, createdBy sql.NullInt64
+	var startedAt, finishedAt sql.NullTime
+
+	err := row.Scan(&job.ID, &testOrderID, &job.Model, &job.ModelVersion, &input, &job.Status, &output,
+		&job.Error, &job.Attempts, &job.MaxAttempts, &job.RunAfter, &latency, &createdBy, &job.CreatedAt,
+		&job.UpdatedAt, &startedAt, &finishedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	job.Input = json.RawMessage(input)
+	if output != nil {
+		job.Output = json.RawMessage(output)
+	}
+	job.TestOrderID = nullIntPtr(testOrderID)
+	job.LatencyMS = nullIntPtr(latency)
+	job.CreatedBy = nullIntPtr(createdBy)
+	job.StartedAt = nullTimePtr(startedAt)
+	job.FinishedAt = nullTimePtr(finishedAt)
+
+	return &job, nil
+}
+
+// List retrieves one page of jobs, fetching q.Fetch() rows so the caller
+// can tell whether there are more, and counts every job matching the filters
+func (r *InferenceJobRepository) List(ctx context.Context, q *listing.Query) ([]InferenceJob, int, error) {
+	where, args := q.Where(false)
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM inference_jobs `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	where, args = q.Where(true)
+	args = append(args, q.Fetch())
+	query := fmt.Sprintf(`SELECT %s FROM inference_jobs %s ORDER BY %s LIMIT $%d`,
+		inferenceJobColumns, where, q.OrderBy(), len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var jobs []InferenceJob
+	for rows.Next() {
+		job, err := scanInferenceJob(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		jobs = append(jobs, *job)
+	}
+
+	return jobs, total, rows.Err()
+}
+
+// GetByID retrieves an inference job by ID
+func (r *InferenceJobRepository) GetByID(ctx context.Context, id int) (*InferenceJob, error) {
+	query := `SELECT ` + inferenceJobColumns + ` FROM inference_jobs WHERE id = $1`
+
+	job, err := scanInferenceJob(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// Create enqueues a new job
+func (r *InferenceJobRepository) Create(ctx context.Context, job *InferenceJob) error {
+	query := `INSERT INTO inference_jobs (test_order_id, model, model_version, input, max_attempts, created_by)
+			  VALUES ($1, $2, $3, $4, $5, $6)
+			  RETURNING ` + inferenceJobColumns
+
+	created, err := scanInferenceJob(r.db.QueryRowContext(ctx, query, job.TestOrderID, job.Model, job.ModelVersion,
+		string(job.Input), job.MaxAttempts, job.CreatedBy))
+	if err != nil {
+		return err
+	}
+
+	*job = *created
+	return nil
+}
+
+// Claim locks the next runnable job for a worker and marks it running. Jobs
+// whose lease expired (their worker died mid-run) are runnable again while
+// they have attempts left; those without are marked failed, so a job that
+// crashes its worker is not retried forever. SKIP LOCKED lets concurrent
+// workers claim different jobs without blocking. It returns nil if no job
+// is runnable.
+func (r *InferenceJobRepository) Claim(ctx context.Context, workerID string, lease time.Duration) (*InferenceJob, error) {
+	expire := `UPDATE inference_jobs SET status = 'failed', error = 'lease expired after max attempts',
+			   lease_expires_at = NULL, finished_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+			   WHERE status = 'running' AND lease_expires_at < CURRENT_TIMESTAMP AND attempts >= max_attempts`
+
+	if _, err := r.db.ExecContext(ctx, expire); err != nil {
+		return nil, err
+	}
+
+	query := `UPDATE inference_jobs SET status = 'running', attempts = attempts + 1, locked_by = $1,
+			  lease_expires_at = CURRENT_TIMESTAMP + $2 * INTERVAL '1 millisecond',
+			  started_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+			  WHERE id = (
+				  SELECT id FROM inference_jobs
+				  WHERE (status = 'queued' AND run_after <= CURRENT_TIMESTAMP)
+				  OR (status = 'running' AND lease_expires_at < CURRENT_TIMESTAMP AND attempts < max_attempts)
+				  ORDER BY run_after, id
+				  LIMIT 1
+				  FOR UPDATE SKIP LOCKED
+			  )
+			  RETURNING ` + inferenceJobColumns
+
+	job, err := scanInferenceJob(r.db.QueryRowContext(ctx, query, workerID, lease.Milliseconds()))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// Complete records a successful run and the model version that served it,
+// keeping the requested version if the client could not tell. It returns
+// sql.ErrNoRows if the claim was lost, e.g. the lease expired and another
+// worker took the job.
+func (r *InferenceJobRepository) Complete(ctx context.Context, job *InferenceJob, version string, output json.RawMessage, latency time.Duration) error {
+	query := `UPDATE inference_jobs SET status = 'succeeded', model_version = COALESCE(NULLIF($1, ''), model_version),
+			  output = $2, error = '', latency_ms = $3,
+			  lease_expires_at = NULL, finished_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+			  WHERE id = $4 AND status = 'running' AND attempts = $5`
+
+	return execAffectingOne(ctx, r.db, query, version, string(output), latency.Milliseconds(), job.ID, job.Attempts)
+}
+
+// Release hands back a job whose run was interrupted by shutdown, queued to
+// run again at once. The interrupted run is not counted as an attempt, so a
+// job on its last attempt is neither failed nor run past max_attempts, and
+// deploys do not use up retries. It returns sql.ErrNoRows if the claim was
+// lost.
+func (r *InferenceJobRepository) Release(ctx context.Context, job *InferenceJob) error {
+	query := `UPDATE inference_jobs SET status = 'queued', attempts = attempts - 1, run_after = CURRENT_TIMESTAMP,
+			  lease_expires_at = NULL, updated_at = CURRENT_TIMESTAMP
+			  WHERE id = $1 AND status = 'running' AND attempts = $2`
+
+	return execAffectingOne(ctx, r.db, query, job.ID, job.Attempts)
+}
+
+// Fail records a failed run. With a positive retryAfter the job is queued
+// again after that delay; otherwise it is marked failed. It returns
+// sql.ErrNoRows if the claim was lost.
+func (r *InferenceJobRepository) Fail(ctx context.Context, job *InferenceJob, message string, latency, retryAfter time.Duration) error {
+	if retryAfter > 0 {
+		query := `UPDATE inference_jobs SET status = 'queued', error = $1, latency_ms = $2,
+				  run_after = CURRENT_TIMESTAMP + $3 * INTERVAL '1 millisecond',
+				  lease_expires_at = NULL, updated_at = CURRENT_TIMESTAMP
+				  WHERE id = $4 AND status = 'running' AND attempts = $5`
+
+		return execAffectingOne(ctx, r.db, query, message, latency.Milliseconds(), retryAfter.Milliseconds(), job.ID, job.Attempts)
+	}
+
+	query := `UPDATE inference_jobs SET status = 'failed', error = $1, latency_ms = $2,
+			  lease_expires_at = NULL, finished_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+			  WHERE id = $3 AND status = 'running' AND attempts = $4`
+
+	return execAffectingOne(ctx, r.db, query, message, latency.Milliseconds(), job.ID, job.Attempts)
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestInferenceJobRepository_Claim_ExpiredLeaseOutOfAttempts(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewInferenceJobRepository(db)
+
+	job := &InferenceJob{Model: "alphapath", ModelVersion: "test", Input: json.RawMessage(`{}`), MaxAttempts: 1}
+	if err := repo.Create(context.Background(), job); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	// Claim the job and let its worker die: the lease is already over
+	if _, err := db.Exec(`UPDATE inference_jobs SET status = 'running', attempts = 1,
+		lease_expires_at = CURRENT_TIMESTAMP - INTERVAL '1 minute' WHERE id = $1`, job.ID); err != nil {
+		t.Fatalf("Failed to expire the lease: %v", err)
+	}
+
+	claimed, err := repo.Claim(context.Background(), "worker-2", time.Minute)
+	if err != nil {
+		t.Fatalf("Claim failed: %v", err)
+	}
+	if claimed != nil && claimed.ID == job.ID {
+		t.Fatalf("Expected a job out of attempts not to be claimed, got attempt %d", claimed.Attempts)
+	}
+
+	stored, err := repo.GetByID(context.Background(), job.ID)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if stored.Status != InferenceJobFailed || stored.Error != "lease expired after max attempts" || stored.Attempts != 1 {
+		t.Errorf("Expected the job to fail after its last attempt, got status %s, error %q, attempts %d",
+			stored.Status, stored.Error, stored.Attempts)
+	}
+}
+
+func TestInferenceJobRepository_Release_DoesNotCountAttempt(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewInferenceJobRepository(db)
+	ctx := context.Background()
+
+	job := &InferenceJob{Model: "alphapath", ModelVersion: "test", Input: json.RawMessage(`{}`), MaxAttempts: 1}
+	if err := repo.Create(ctx, job); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := db.Exec(`UPDATE inference_jobs SET status = 'running', attempts = 1,
+		lease_expires_at = CURRENT_TIMESTAMP + INTERVAL '1 minute' WHERE id = $1`, job.ID); err != nil {
+		t.Fatalf("Failed to claim the job: %v", err)
+	}
+	job.Attempts = 1
+
+	if err := repo.Release(ctx, job); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+
+	stored, err := repo.GetByID(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if stored.Status != InferenceJobQueued || stored.Attempts != 0 {
+		t.Errorf("Expected the job queued with its attempt uncounted, got status %s, attempts %d", stored.Status, stored.Attempts)
+	}
+}
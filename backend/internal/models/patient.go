@@ -0,0 +1,683 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"backend/internal/listing"
+)
+
+var (
+	// ErrPatientConflict is returned when an MRN or identifier already belongs to another patient
+	ErrPatientConflict = errors.New("MRN or identifier already belongs to another patient")
+
+	// ErrPatientMerged is returned when a merged patient is changed or merged again
+	ErrPatientMerged = errors.New("patient has been merged into another record")
+
+	// ErrMergeReversed is returned when reversing a merge that was already reversed
+	ErrMergeReversed = errors.New("merge has already been reversed")
+)
+
+// PatientSex is administrative sex as recorded at registration
+type PatientSex string
+
+const (
+	PatientFemale     PatientSex = "female"
+	PatientMale       PatientSex = "male"
+	PatientOther      PatientSex = "other"
+	PatientSexUnknown PatientSex = "unknown"
+)
+
+// Valid reports whether s is a known value
+func (s PatientSex) Valid() bool {
+	switch s {
+	case PatientFemale, PatientMale, PatientOther, PatientSexUnknown:
+		return true
+	}
+	return false
+}
+
+// BirthDateLayout is the format of Patient.BirthDate
+const BirthDateLayout = "2006-01-02"
+
+// Patient is a person specimens are collected from. MRN is this lab's
+// medical record number; Identifiers carry the numbers other systems use.
+// A patient merged into another record keeps its row, with MergedIntoID
+// pointing at the surviving record.
+type Patient struct {
+	ID           int                 `json:"id"`
+	MRN          string              `json:"mrn"`
+	FamilyName   string              `json:"family_name"`
+	GivenName    string              `json:"given_name"`
+	BirthDate    string              `json:"birth_date,omitempty"`
+	Sex          PatientSex          `json:"sex"`
+	Phone        string              `json:"phone"`
+	Email        string              `json:"email"`
+	Address      string              `json:"address"`
+	Identifiers  []PatientIdentifier `json:"identifiers"`
+	MergedIntoID *int                `json:"merged_into_id,omitempty"`
+	CreatedAt    time.Time           `json:"created_at"`
+	UpdatedAt    time.Time           `json:"updated_at"`
+}
+
+// PatientIdentifier is a patient's identifier in another system, such as a
+// hospital MRN or a national health number. System names the issuer.
+type PatientIdentifier struct {
+	ID     int    `json:"id,omitempty"`
+	System string `json:"system"`
+	Value  string `json:"value"`
+}
+
+// PatientList is the list spec of GET /api/patients. Name matches a prefix
+// of the family or given name; merged records are never listed.
+var PatientList = &listing.Spec{
+	Key: "id",
+	Sorts: map[string]listing.Field{
+		"id":          {Column: "id", Type: listing.Int},
+		"family_name": {Column: "family_name", Type: listing.String},
+		"mrn":         {Column: "mrn", Type: listing.String},
+	},
+	DefaultSort: "family_name",
+	Filters: map[string]listing.Filter{
+		"name":       {Column: "family_name", Type: listing.String, Op: listing.PrefixFold, Also: []string{"given_name"}},
+		"mrn":        {Column: "mrn", Type: listing.String, Op: listing.Equal},
+		"birth_date": {Column: "birth_date", Type: listing.Date, Op: listing.Equal},
+	},
+	Scope: "merged_into_id IS NULL",
+}
+
+// ListKey is the patient's position in a list sorted by the named
+// PatientList field
+func (p Patient) ListKey(sort string) listing.Key {
+	key := listing.Key{ID: int64(p.ID)}
+	switch sort {
+	case "family_name":
+		key.Value = p.FamilyName
+	case "mrn":
+		key.Value = p.MRN
+	default:
+		key.Value = p.ID
+	}
+	return key
+}
+
+// PatientSearch selects a page of patients by ID or by an identifier from
+// another system. An empty IdentifierSystem matches any issuer.
+type PatientSearch struct {
+	IDs              []int
+	IdentifierSystem string
+	IdentifierValue  string
+	Limit            int
+	Offset           int
+}
+
+// PatientMerge records one patient being merged into another. Moved lists
+// the rows that were re-pointed to the survivor, so the merge can be reversed.
+type PatientMerge struct {
+	ID         int          `json:"id"`
+	SurvivorID int          `json:"survivor_id"`
+	MergedID   int          `json:"merged_id"`
+	Reason     string       `json:"reason"`
+	Moved      PatientMoves `json:"moved"`
+	MergedBy   *int         `json:"merged_by"`
+	MergedAt   time.Time    `json:"merged_at"`
+	ReversedBy *int         `json:"reversed_by"`
+	ReversedAt *time.Time   `json:"reversed_at"`
+}
+
+// PatientMoves lists the rows a merge re-pointed, with the values needed to
+// put them back
+type PatientMoves struct {
+	TestOrders  []MovedTestOrder `json:"test_orders"`
+	Identifiers []int            `json:"identifiers"`
+}
+
+// MovedTestOrder is a test order re-pointed by a merge and its original MRN
+type MovedTestOrder struct {
+	ID         int    `json:"id"`
+	PatientMRN string `json:"patient_mrn"`
+}
+
+// PatientRepository handles database operations for patients
+type PatientRepository struct {
+	db DBTX
+}
+
+// NewPatientRepository creates a new patient repository
+func NewPatientRepository(db DBTX) *PatientRepository {
+	return &PatientRepository{db: db}
+}
+
+// patientColumns is the column list scanned by scanPatient
+const patientColumns = `id, mrn, family_name, given_name, birth_date, sex, phone, email, address,
+	merged_into_id, created_at, updated_at`
+
+// scanPatient scans a row selected with patientColumns. Identifiers are
+// loaded separately.
+func scanPatient(row interface{ Scan(...interface{}) error }) (*Patient, error) {
+	var patient Patient
+	var birthDate sql.NullTime
+	var mergedInto sql.NullInt64
+
+	err := row.Scan(&patient.ID, &patient.MRN, &patient.FamilyName, &patient.GivenName, &birthDate, &patient.Sex,
+		&patient.Phone, &patient.Email, &patient.Address, &mergedInto, &patient.CreatedAt, &patient.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if birthDate.Valid {
+		patient.BirthDate = birthDate.Time.Format(BirthDateLayout)
+	}
+	patient.MergedIntoID = nullIntPtr(mergedInto)
+	patient.Identifiers = []PatientIdentifier{}
+
+	return &patient, nil
+}
+
+// birthDateParam passes a birth date as a query parameter, or NULL if unknown
+func birthDateParam(birthDate string) interface{} {
+	if birthDate == "" {
+		return nil
+	}
+	return birthDate
+}
+
+// queryPatients runs a query selecting patientColumns and loads each
+// patient's identifiers
+func (r *PatientRepository) queryPatients(ctx context.Context, query string, args ...interface{}) ([]Patient, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var patients []Patient
+	for rows.Next() {
+		patient, err := scanPatient(rows)
+		if err != nil {
+			return nil, err
+		}
+		patients = append(patients, *patient)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := r.loadIdentifiers(ctx, patients); err != nil {
+		return nil, err
+	}
+	return patients, nil
+}
+
+// loadIdentifiers fills in the identifiers of the given patients
+func (r *PatientRepository) loadIdentifiers(ctx context.Context, patients []Patient) error {
+	if len(patients) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(patients))
+	byID := make(map[int]*Patient, len(patients))
+	for i := range patients {
+		ids[i] = int64(patients[i].ID)
+		byID[patients[i].ID] = &patients[i]
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT id, patient_id, system, value FROM patient_identifiers
+							 WHERE patient_id = ANY($1) ORDER BY id`, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var identifier PatientIdentifier
+		var patientID int
+		if err := rows.Scan(&identifier.ID, &patientID, &identifier.System, &identifier.Value); err != nil {
+			return err
+		}
+		patient := byID[patientID]
+		patient.Identifiers = append(patient.Identifiers, identifier)
+	}
+
+	return rows.Err()
+}
+
+// List retrieves one page of unmerged patients, fetching q.Fetch() rows so
+// the caller can tell whether there are more, and counts every patient
+// matching the filters
+func (r *PatientRepository) List(ctx context.Context, q *listing.Query) ([]Patient, int, error) {
+	where, args := q.Where(false)
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM patients `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	where, args = q.Where(true)
+	args = append(args, q.Fetch())
+	query := fmt.Sprintf(`SELECT %s FROM patients %s ORDER BY %s LIMIT $%d`,
+		patientColumns, where, q.OrderBy(), len(args))
+
+	patients, err := r.queryPatients(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return patients, total, nil
+}
+
+// Search retrieves a page of patients matching the search, ordered by ID, and
+// the number of matches across all pages. Merged records are only returned
+// when asked for by ID.
+func (r *PatientRepository) Search(ctx context.Context, search PatientSearch) ([]Patient, int, error) {
+	where := ` WHERE (($1::bigint[] IS NULL AND merged_into_id IS NULL) OR id = ANY($1))
+			  AND ($3 = '' OR id IN (SELECT patient_id FROM patient_identifiers
+									 WHERE value = $3 AND ($2 = '' OR system = $2)))`
+	args := []interface{}{pq.Array(int64s(search.IDs)), search.IdentifierSystem, search.IdentifierValue}
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT count(*) FROM patients`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + patientColumns + ` FROM patients` + where + ` ORDER BY id LIMIT $4 OFFSET $5`
+	patients, err := r.queryPatients(ctx, query, append(args, search.Limit, search.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return patients, total, nil
+}
+
+// GetByID retrieves a patient by ID, including merged records
+func (r *PatientRepository) GetByID(ctx context.Context, id int) (*Patient, error) {
+	patients, err := r.queryPatients(ctx, `SELECT `+patientColumns+` FROM patients WHERE id = $1`, id)
+	if err != nil || len(patients) == 0 {
+		return nil, err
+	}
+	return &patients[0], nil
+}
+
+// GetByMRN retrieves a patient by MRN, including merged records
+func (r *PatientRepository) GetByMRN(ctx context.Context, mrn string) (*Patient, error) {
+	patients, err := r.queryPatients(ctx, `SELECT `+patientColumns+` FROM patients WHERE mrn = $1`, mrn)
+	if err != nil || len(patients) == 0 {
+		return nil, err
+	}
+	return &patients[0], nil
+}
+
+// GetByIdentifier retrieves the patient holding an identifier from another
+// system. Identifiers move to the survivor on merge, so the patient returned
+// is never a merged record.
+func (r *PatientRepository) GetByIdentifier(ctx context.Context, system, value string) (*Patient, error) {
+	query := `SELECT ` + patientColumns + ` FROM patients
+			  WHERE id = (SELECT patient_id FROM patient_identifiers WHERE system = $1 AND value = $2)`
+
+	patients, err := r.queryPatients(ctx, query, system, value)
+	if err != nil || len(patients) == 0 {
+		return nil, err
+	}
+	return &patients[0], nil
+}
+
+// FindCandidates retrieves unmerged patients that might be the same person:
+// those sharing an identifier, born on the given or a day/month-swapped
+// date, or with the same name. Scoring is left to MatchPatient.
+func (r *PatientRepository) FindCandidates(ctx context.Context, patient *Patient, limit int) ([]Patient, error) {
+	systems := make([]string, len(patient.Identifiers))
+	values := make([]string, len(patient.Identifiers))
+	for i, identifier := range patient.Identifiers {
+		systems[i], values[i] = identifier.System, identifier.Value
+	}
+
+	query := `SELECT ` + patientColumns + ` FROM patients p
+			  WHERE p.merged_into_id IS NULL AND p.id <> $1
+			  AND (
+				  p.birth_date = $2::date OR p.birth_date = $3::date
+				  OR (lower(p.family_name) = lower($4) AND lower(p.given_name) = lower($5) AND $4 <> '')
+				  OR EXISTS (SELECT 1 FROM patient_identifiers i
+							 JOIN unnest($6::text[], $7::text[]) AS c(system, value)
+							 ON c.system = i.system AND c.value = i.value
+							 WHERE i.patient_id = p.id)
+			  )
+			  ORDER BY p.id
+			  LIMIT $8`
+
+	return r.queryPatients(ctx, query, patient.ID, birthDateParam(patient.BirthDate),
+		birthDateParam(swapDayMonth(patient.BirthDate)), patient.FamilyName, patient.GivenName,
+		pq.Array(systems), pq.Array(values), limit)
+}
+
+// Create registers a patient and their identifiers. An MRN is assigned if
+// none is given. It returns ErrPatientConflict if the MRN or an identifier
+// is already in use.
+func (r *PatientRepository) Create(ctx context.Context, patient *Patient) error {
+	return withTx(ctx, r.db, func(tx DBTX) error {
+		return createPatient(ctx, tx, patient)
+	})
+}
+
+// createPatient inserts a patient and their identifiers in tx
+func createPatient(ctx context.Context, tx DBTX, patient *Patient) error {
+	query := `INSERT INTO patients (mrn, family_name, given_name, birth_date, sex, phone, email, address)
+			  VALUES (COALESCE(NULLIF($1, ''), 'P' || lpad(nextval('patient_mrn_seq')::text, 8, '0')),
+					  $2, $3, $4, $5, $6, $7, $8)
+			  RETURNING id, mrn, created_at, updated_at`
+
+	err := tx.QueryRowContext(ctx, query, patient.MRN, patient.FamilyName, patient.GivenName, birthDateParam(patient.BirthDate),
+		patient.Sex, patient.Phone, patient.Email, patient.Address).
+		Scan(&patient.ID, &patient.MRN, &patient.CreatedAt, &patient.UpdatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrPatientConflict
+		}
+		return err
+	}
+
+	return insertIdentifiers(ctx, tx, patient)
+}
+
+// Update replaces a patient's demographics and identifiers. It returns
+// sql.ErrNoRows if the patient does not exist, ErrPatientMerged if it was
+// merged and ErrPatientConflict if an identifier belongs to someone else.
+func (r *PatientRepository) Update(ctx context.Context, patient *Patient) error {
+	return withTx(ctx, r.db, func(tx DBTX) error {
+		return updatePatient(ctx, tx, patient)
+	})
+}
+
+// updatePatient replaces a patient's demographics and identifiers in tx
+func updatePatient(ctx context.Context, tx DBTX, patient *Patient) error {
+	var mergedInto sql.NullInt64
+	err := tx.QueryRowContext(ctx, `SELECT merged_into_id FROM patients WHERE id = $1 FOR UPDATE`, patient.ID).Scan(&mergedInto)
+	if err != nil {
+		return err
+	}
+	if mergedInto.Valid {
+		return ErrPatientMerged
+	}
+
+	query := `UPDATE patients SET family_name = $1, given_name = $2, birth_date = $3, sex = $4, phone = $5,
+			  email = $6, address = $7, updated_at = CURRENT_TIMESTAMP
+			  WHERE id = $8
+			  RETURNING mrn, created_at, updated_at`
+
+	err = tx.QueryRowContext(ctx, query, patient.FamilyName, patient.GivenName, birthDateParam(patient.BirthDate), patient.Sex,
+		patient.Phone, patient.Email, patient.Address, patient.ID).
+		Scan(&patient.MRN, &patient.CreatedAt, &patient.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM patient_identifiers WHERE patient_id = $1`, patient.ID); err != nil {
+		return err
+	}
+	return insertIdentifiers(ctx, tx, patient)
+}
+
+// insertIdentifiers stores a patient's identifiers, setting their IDs
+func insertIdentifiers(ctx context.Context, tx DBTX, patient *Patient) error {
+	for i := range patient.Identifiers {
+		identifier := &patient.Identifiers[i]
+		err := tx.QueryRowContext(ctx, `INSERT INTO patient_identifiers (patient_id, system, value) VALUES ($1, $2, $3) RETURNING id`,
+			patient.ID, identifier.System, identifier.Value).Scan(&identifier.ID)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return ErrPatientConflict
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Merge merges one patient into a survivor: every test order and identifier
+// of the merged patient is re-pointed to the survivor, and the merged record
+// is kept, marked with the survivor's ID. It returns sql.ErrNoRows if either
+// patient does not exist and ErrPatientMerged if either was already merged.
+func (r *PatientRepository) Merge(ctx context.Context, survivorID, mergedID int, reason string, mergedBy *int) (*PatientMerge, error) {
+	var merge *PatientMerge
+	err := withTx(ctx, r.db, func(tx DBTX) error {
+		var err error
+		merge, err = mergePatient(ctx, tx, survivorID, mergedID, reason, mergedBy)
+		return err
+	})
+	return merge, err
+}
+
+// mergePatient merges one patient into a survivor in tx
+func mergePatient(ctx context.Context, tx DBTX, survivorID, mergedID int, reason string, mergedBy *int) (*PatientMerge, error) {
+	mrns, err := lockUnmergedPatients(ctx, tx, survivorID, mergedID)
+	if err != nil {
+		return nil, err
+	}
+
+	moves := PatientMoves{TestOrders: []MovedTestOrder{}, Identifiers: []int{}}
+
+	rows, err := tx.QueryContext(ctx, `UPDATE test_orders o SET patient_id = $1, patient_mrn = $2, updated_at = CURRENT_TIMESTAMP
+						   FROM (SELECT id, patient_mrn FROM test_orders WHERE patient_id = $3 FOR UPDATE) old
+						   WHERE o.id = old.id
+						   RETURNING o.id, old.patient_mrn`, survivorID, mrns[survivorID], mergedID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var moved MovedTestOrder
+		if err := rows.Scan(&moved.ID, &moved.PatientMRN); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		moves.TestOrders = append(moves.TestOrders, moved)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = tx.QueryContext(ctx, `UPDATE patient_identifiers SET patient_id = $1 WHERE patient_id = $2 RETURNING id`,
+		survivorID, mergedID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		moves.Identifiers = append(moves.Identifiers, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE patients SET merged_into_id = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`,
+		survivorID, mergedID)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := json.Marshal(moves)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `INSERT INTO patient_merges (survivor_id, merged_id, reason, moved, merged_by)
+			  VALUES ($1, $2, $3, $4, $5)
+			  RETURNING ` + patientMergeColumns
+
+	merge, err := scanPatientMerge(tx.QueryRowContext(ctx, query, survivorID, mergedID, reason, string(encoded), mergedBy))
+	if err != nil {
+		return nil, err
+	}
+
+	return merge, nil
+}
+
+// Reverse undoes a merge into survivorID: the rows it moved that still
+// belong to the survivor go back to the merged patient, which becomes active
+// again. Test orders placed for the survivor since the merge stay with it.
+// It returns sql.ErrNoRows if there is no such merge, ErrMergeReversed if it
+// was already reversed and ErrPatientMerged if the survivor has since been
+// merged itself; that later merge must be reversed first.
+func (r *PatientRepository) Reverse(ctx context.Context, mergeID, survivorID int, reversedBy *int) (*PatientMerge, error) {
+	var reversed *PatientMerge
+	err := withTx(ctx, r.db, func(tx DBTX) error {
+		var err error
+		reversed, err = reverseMerge(ctx, tx, mergeID, survivorID, reversedBy)
+		return err
+	})
+	return reversed, err
+}
+
+// reverseMerge undoes a merge into survivorID in tx
+func reverseMerge(ctx context.Context, tx DBTX, mergeID, survivorID int, reversedBy *int) (*PatientMerge, error) {
+	query := `SELECT ` + patientMergeColumns + ` FROM patient_merges
+			  WHERE id = $1 AND survivor_id = $2
+			  FOR UPDATE`
+
+	merge, err := scanPatientMerge(tx.QueryRowContext(ctx, query, mergeID, survivorID))
+	if err != nil {
+		return nil, err
+	}
+	if merge.ReversedAt != nil {
+		return nil, ErrMergeReversed
+	}
+
+	if _, err := lockUnmergedPatients(ctx, tx, merge.SurvivorID); err != nil {
+		return nil, err
+	}
+
+	for _, order := range merge.Moved.TestOrders {
+		_, err := tx.ExecContext(ctx, `UPDATE test_orders SET patient_id = $1, patient_mrn = $2, updated_at = CURRENT_TIMESTAMP
+						   WHERE id = $3 AND patient_id = $4`, merge.MergedID, order.PatientMRN, order.ID, merge.SurvivorID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ids := make([]int64, len(merge.Moved.Identifiers))
+	for i, id := range merge.Moved.Identifiers {
+		ids[i] = int64(id)
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE patient_identifiers SET patient_id = $1 WHERE id = ANY($2) AND patient_id = $3`,
+		merge.MergedID, pq.Array(ids), merge.SurvivorID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE patients SET merged_into_id = NULL, updated_at = CURRENT_TIMESTAMP WHERE id = $1`,
+		merge.MergedID)
+	if err != nil {
+		return nil, err
+	}
+
+	query = `UPDATE patient_merges SET reversed_by = $1, reversed_at = CURRENT_TIMESTAMP
+			  WHERE id = $2
+			  RETURNING ` + patientMergeColumns
+
+	reversed, err := scanPatientMerge(tx.QueryRowContext(ctx, query, reversedBy, mergeID))
+	if err != nil {
+		return nil, err
+	}
+
+	return reversed, nil
+}
+
+// lockUnmergedPatients locks the given patients for the rest of the
+// transaction, in ID order to avoid deadlocks, and returns their MRNs. It
+// returns sql.ErrNoRows if any is missing and ErrPatientMerged if any has
+// been merged.
+func lockUnmergedPatients(ctx context.Context, tx DBTX, ids ...int) (map[int]string, error) {
+	wanted := make([]int64, len(ids))
+	for i, id := range ids {
+		wanted[i] = int64(id)
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT id, mrn, merged_into_id FROM patients WHERE id = ANY($1) ORDER BY id FOR UPDATE`,
+		pq.Array(wanted))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mrns := make(map[int]string, len(ids))
+	merged := false
+	for rows.Next() {
+		var id int
+		var mrn string
+		var mergedInto sql.NullInt64
+		if err := rows.Scan(&id, &mrn, &mergedInto); err != nil {
+			return nil, err
+		}
+		mrns[id] = mrn
+		merged = merged || mergedInto.Valid
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(mrns) != len(ids) {
+		return nil, sql.ErrNoRows
+	}
+	if merged {
+		return nil, ErrPatientMerged
+	}
+	return mrns, nil
+}
+
+// patientMergeColumns is the column list scanned by scanPatientMerge
+const patientMergeColumns = `id, survivor_id, merged_id, reason, moved, merged_by, merged_at, reversed_by, reversed_at`
+
+// scanPatientMerge scans a row selected with patientMergeColumns
+func scanPatientMerge(row interface{ Scan(...interface{}) error }) (*PatientMerge, error) {
+	var merge PatientMerge
+	var moved []byte
+	var mergedBy, reversedBy sql.NullInt64
+	var reversedAt sql.NullTime
+
+	err := row.Scan(&merge.ID, &merge.SurvivorID, &merge.MergedID, &merge.Reason, &moved, &mergedBy,
+		&merge.MergedAt, &reversedBy, &reversedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(moved, &merge.Moved); err != nil {
+		return nil, err
+	}
+	merge.MergedBy = nullIntPtr(mergedBy)
+	merge.ReversedBy = nullIntPtr(reversedBy)
+	merge.ReversedAt = nullTimePtr(reversedAt)
+
+	return &merge, nil
+}
+
+// GetMerges retrieves the merges a patient took part in, oldest first
+func (r *PatientRepository) GetMerges(ctx context.Context, patientID int) ([]PatientMerge, error) {
+	query := `SELECT ` + patientMergeColumns + ` FROM patient_merges
+			  WHERE survivor_id = $1 OR merged_id = $1
+			  ORDER BY merged_at, id`
+
+	rows, err := r.db.QueryContext(ctx, query, patientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var merges []PatientMerge
+	for rows.Next() {
+		merge, err := scanPatientMerge(rows)
+		if err != nil {
+			return nil, err
+		}
+		merges = append(merges, *merge)
+	}
+
+	return merges, rows.Err()
+}
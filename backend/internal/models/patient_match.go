@@ -0,0 +1,220 @@
+package models
+
+import (
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// DuplicateThreshold is the match score at or above which a new patient is
+// reported as a likely duplicate of an existing one
+const DuplicateThreshold = 0.85
+
+// Weights of the name and birth date in a match score
+const (
+	nameWeight      = 0.6
+	birthDateWeight = 0.4
+)
+
+// PatientMatch is an existing patient that may be the same person as a
+// patient being registered, with a score between 0 and 1 and the reasons
+// it matched
+type PatientMatch struct {
+	Patient Patient  `json:"patient"`
+	Score   float64  `json:"score"`
+	Reasons []string `json:"reasons"`
+}
+
+// MatchPatient scores how likely candidate is to be the same person as
+// patient. A shared identifier from the same source system is conclusive;
+// otherwise the score combines name similarity, tolerant of typos and of
+// given and family names being swapped, with birth date agreement, tolerant
+// of a transposed day and month or a single mistyped digit.
+func MatchPatient(patient, candidate *Patient) PatientMatch {
+	match := PatientMatch{Patient: *candidate, Reasons: []string{}}
+
+	for _, identifier := range patient.Identifiers {
+		for _, other := range candidate.Identifiers {
+			if identifier.System == other.System && identifier.Value == other.Value {
+				match.Reasons = append(match.Reasons, "identifier:"+identifier.System)
+			}
+		}
+	}
+	if len(match.Reasons) > 0 {
+		match.Score = 1
+		return match
+	}
+
+	name := nameSimilarity(patient, candidate)
+	switch {
+	case name == 1:
+		match.Reasons = append(match.Reasons, "name")
+	case name >= 0.85:
+		match.Reasons = append(match.Reasons, "similar_name")
+	}
+
+	birthDate := birthDateSimilarity(patient.BirthDate, candidate.BirthDate)
+	switch {
+	case birthDate == 1:
+		match.Reasons = append(match.Reasons, "birth_date")
+	case birthDate == similarBirthDate:
+		match.Reasons = append(match.Reasons, "similar_birth_date")
+	}
+
+	match.Score = nameWeight*name + birthDateWeight*birthDate
+	return match
+}
+
+// FindDuplicates scores the candidates against patient and returns those at
+// or above DuplicateThreshold, best match first
+func FindDuplicates(patient *Patient, candidates []Patient) []PatientMatch {
+	matches := []PatientMatch{}
+	for i := range candidates {
+		if candidates[i].ID == patient.ID {
+			continue
+		}
+		if match := MatchPatient(patient, &candidates[i]); match.Score >= DuplicateThreshold {
+			matches = append(matches, match)
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	return matches
+}
+
+// nameSimilarity compares full names, also trying the candidate's given and
+// family names the other way round. Unnamed patients, such as those
+// registered from an MRN alone, never match on name.
+func nameSimilarity(patient, candidate *Patient) float64 {
+	family, given := normalizeName(patient.FamilyName), normalizeName(patient.GivenName)
+	otherFamily, otherGiven := normalizeName(candidate.FamilyName), normalizeName(candidate.GivenName)
+	if family+given == "" || otherFamily+otherGiven == "" {
+		return 0
+	}
+
+	straight := jaroWinkler(family+" "+given, otherFamily+" "+otherGiven)
+	swapped := jaroWinkler(family+" "+given, otherGiven+" "+otherFamily)
+	if swapped > straight {
+		return swapped
+	}
+	return straight
+}
+
+// normalizeName lower-cases a name and drops everything but letters and
+// digits, so "O'Brien" and "obrien" compare equal
+func normalizeName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Birth date similarity scores
+const (
+	similarBirthDate = 0.7
+	unknownBirthDate = 0.5
+)
+
+// birthDateSimilarity is 1 for equal dates, similarBirthDate for dates that
+// differ by a swapped day and month or a single digit, unknownBirthDate if
+// either is missing and 0 otherwise
+func birthDateSimilarity(a, b string) float64 {
+	if a == "" || b == "" {
+		return unknownBirthDate
+	}
+	if a == b {
+		return 1
+	}
+	if swapDayMonth(a) == b || digitsDiffer(a, b) == 1 {
+		return similarBirthDate
+	}
+	return 0
+}
+
+// swapDayMonth returns a birth date with its day and month swapped, or ""
+// if that is not a valid date
+func swapDayMonth(birthDate string) string {
+	t, err := time.Parse(BirthDateLayout, birthDate)
+	if err != nil || t.Day() > 12 || t.Day() == int(t.Month()) {
+		return ""
+	}
+	return time.Date(t.Year(), time.Month(t.Day()), int(t.Month()), 0, 0, 0, 0, time.UTC).Format(BirthDateLayout)
+}
+
+// digitsDiffer counts the positions at which two equal-length strings
+// differ, or returns -1 if their lengths differ
+func digitsDiffer(a, b string) int {
+	if len(a) != len(b) {
+		return -1
+	}
+	n := 0
+	for i := 0; i < len(a); i++ {
+		if a[i] != b[i] {
+			n++
+		}
+	}
+	return n
+}
+
+// jaroWinkler returns the Jaro-Winkler similarity of two strings, from 0
+// for nothing in common to 1 for identical strings
+func jaroWinkler(a, b string) float64 {
+	s, t := []rune(a), []rune(b)
+	if len(s) == 0 && len(t) == 0 {
+		return 1
+	}
+	if len(s) == 0 || len(t) == 0 {
+		return 0
+	}
+
+	window := max(len(s), len(t))/2 - 1
+	if window < 0 {
+		window = 0
+	}
+
+	sMatched := make([]bool, len(s))
+	tMatched := make([]bool, len(t))
+	matches := 0
+	for i := range s {
+		for j := max(0, i-window); j < min(len(t), i+window+1); j++ {
+			if !tMatched[j] && s[i] == t[j] {
+				sMatched[i], tMatched[j] = true, true
+				matches++
+				break
+			}
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+
+	transpositions := 0
+	j := 0
+	for i := range s {
+		if !sMatched[i] {
+			continue
+		}
+		for !tMatched[j] {
+			j++
+		}
+		if s[i] != t[j] {
+			transpositions++
+		}
+		j++
+	}
+
+	m := float64(matches)
+	jaro := (m/float64(len(s)) + m/float64(len(t)) + (m-float64(transpositions)/2)/m) / 3
+
+	prefix := 0
+	for prefix < min(4, len(s), len(t)) && s[prefix] == t[prefix] {
+		prefix++
+	}
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
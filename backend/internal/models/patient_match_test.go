@@ -0,0 +1,106 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestJaroWinkler(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected float64
+	}{
+		{"martha", "marhta", 0.961},
+		{"dwayne", "duane", 0.84},
+		{"dixon", "dicksonx", 0.813},
+		{"same", "same", 1},
+		{"abc", "xyz", 0},
+		{"", "", 1},
+		{"abc", "", 0},
+	}
+
+	for _, tt := range tests {
+		if got := jaroWinkler(tt.a, tt.b); math.Abs(got-tt.expected) > 0.001 {
+			t.Errorf("jaroWinkler(%q, %q): expected %.3f, got %.3f", tt.a, tt.b, tt.expected, got)
+		}
+	}
+}
+
+func TestBirthDateSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected float64
+	}{
+		{"1980-03-07", "1980-03-07", 1},
+		{"1980-03-07", "1980-07-03", similarBirthDate},
+		{"1980-03-07", "1981-03-07", similarBirthDate},
+		{"1980-03-07", "1991-04-12", 0},
+		{"1980-03-07", "", unknownBirthDate},
+	}
+
+	for _, tt := range tests {
+		if got := birthDateSimilarity(tt.a, tt.b); got != tt.expected {
+			t.Errorf("birthDateSimilarity(%q, %q): expected %v, got %v", tt.a, tt.b, tt.expected, got)
+		}
+	}
+
+	if got := swapDayMonth("1980-03-25"); got != "" {
+		t.Errorf("Expected no swap for a day above 12, got %q", got)
+	}
+}
+
+func TestMatchPatient(t *testing.T) {
+	patient := &Patient{FamilyName: "O'Brien", GivenName: "Siobhan", BirthDate: "1975-11-02"}
+
+	tests := []struct {
+		name      string
+		candidate Patient
+		duplicate bool
+	}{
+		{"same person", Patient{FamilyName: "OBrien", GivenName: "siobhan", BirthDate: "1975-11-02"}, true},
+		{"typo in name", Patient{FamilyName: "O'Brien", GivenName: "Siobahn", BirthDate: "1975-11-02"}, true},
+		{"names swapped", Patient{FamilyName: "Siobhan", GivenName: "O'Brien", BirthDate: "1975-11-02"}, true},
+		{"day and month swapped", Patient{FamilyName: "O'Brien", GivenName: "Siobhan", BirthDate: "1975-02-11"}, true},
+		{"different birth date", Patient{FamilyName: "O'Brien", GivenName: "Siobhan", BirthDate: "1990-06-21"}, false},
+		{"different person", Patient{FamilyName: "Okafor", GivenName: "Chidi", BirthDate: "1975-11-02"}, false},
+		{"unnamed", Patient{MRN: "12345", BirthDate: "1975-11-02"}, false},
+	}
+
+	for _, tt := range tests {
+		match := MatchPatient(patient, &tt.candidate)
+		if got := match.Score >= DuplicateThreshold; got != tt.duplicate {
+			t.Errorf("%s: expected duplicate=%v, got score %.3f (%v)", tt.name, tt.duplicate, match.Score, match.Reasons)
+		}
+	}
+}
+
+func TestMatchPatient_SharedIdentifier(t *testing.T) {
+	patient := &Patient{FamilyName: "Smith", Identifiers: []PatientIdentifier{{System: "hospital-a", Value: "H100"}}}
+	candidate := &Patient{FamilyName: "Jones", Identifiers: []PatientIdentifier{{System: "hospital-a", Value: "H100"}}}
+
+	match := MatchPatient(patient, candidate)
+	if match.Score != 1 || len(match.Reasons) != 1 || match.Reasons[0] != "identifier:hospital-a" {
+		t.Errorf("Expected a conclusive identifier match, got %+v", match)
+	}
+
+	// The same value from a different system is a different identifier
+	candidate.Identifiers[0].System = "hospital-b"
+	if match := MatchPatient(patient, candidate); match.Score >= DuplicateThreshold {
+		t.Errorf("Expected no match across systems, got %+v", match)
+	}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	patient := &Patient{ID: 1, FamilyName: "Nguyen", GivenName: "Linh", BirthDate: "2001-05-09"}
+	candidates := []Patient{
+		{ID: 1, FamilyName: "Nguyen", GivenName: "Linh", BirthDate: "2001-05-09"},
+		{ID: 2, FamilyName: "Nguyen", GivenName: "Lin", BirthDate: "2001-05-09"},
+		{ID: 3, FamilyName: "Nguyen", GivenName: "Linh", BirthDate: "2001-05-09"},
+		{ID: 4, FamilyName: "Tran", GivenName: "Minh", BirthDate: "1960-01-01"},
+	}
+
+	matches := FindDuplicates(patient, candidates)
+	if len(matches) != 2 || matches[0].Patient.ID != 3 || matches[1].Patient.ID != 2 {
+		t.Errorf("Expected candidates 3 then 2, got %+v", matches)
+	}
+}
@@ -0,0 +1,176 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Role represents a named set of permissions
+type Role struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Permissions []string  `json:"permissions"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// UserRole represents a role assigned to a user
+type UserRole struct {
+	Role      string    `json:"role"`
+	GrantedBy *int      `json:"granted_by,omitempty"`
+	GrantedAt time.Time `json:"granted_at"`
+}
+
+// RoleRepository handles database operations for roles and role assignments
+type RoleRepository struct {
+	db *sql.DB
+}
+
+// NewRoleRepository creates a new role repository
+func NewRoleRepository(db *sql.DB) *RoleRepository {
+	return &RoleRepository{db: db}
+}
+
+// GetAll retrieves all roles with their permissions
+func (r *RoleRepository) GetAll(ctx context.Context) ([]Role, error) {
+	query := `SELECT r.id, r.name, r.description, r.created_at,
+			  COALESCE(array_agg(p.permission ORDER BY p.permission) FILTER (WHERE p.permission IS NOT NULL), '{}')
+			  FROM roles r LEFT JOIN role_permissions p ON p.role_id = r.id
+			  GROUP BY r.id ORDER BY r.name`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []Role
+	for rows.Next() {
+		var role Role
+		err := rows.Scan(&role.ID, &role.Name, &role.Description, &role.CreatedAt, pq.Array(&role.Permissions))
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+
+	return roles, rows.Err()
+}
+
+// GetUserRoles retrieves the roles assigned to a user
+func (r *RoleRepository) GetUserRoles(ctx context.Context, userID int) ([]UserRole, error) {
+	query := `SELECT r.name, ur.granted_by, ur.granted_at
+			  FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+			  WHERE ur.user_id = $1 ORDER BY r.name`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []UserRole
+	for rows.Next() {
+		var role UserRole
+		var grantedBy sql.NullInt64
+		if err := rows.Scan(&role.Role, &grantedBy, &role.GrantedAt); err != nil {
+			return nil, err
+		}
+		if grantedBy.Valid {
+			id := int(grantedBy.Int64)
+			role.GrantedBy = &id
+		}
+		roles = append(roles, role)
+	}
+
+	return roles, rows.Err()
+}
+
+// GetUserAccess retrieves the role names and the union of permissions granted to a user
+func (r *RoleRepository) GetUserAccess(ctx context.Context, userID int) ([]string, []string, error) {
+	query := `SELECT
+			  COALESCE(array_agg(DISTINCT r.name), '{}'),
+			  COALESCE(array_agg(DISTINCT p.permission) FILTER (WHERE p.permission IS NOT NULL), '{}')
+			  FROM user_roles ur
+			  JOIN roles r ON r.id = ur.role_id
+			  LEFT JOIN role_permissions p ON p.role_id = r.id
+			  WHERE ur.user_id = $1`
+
+	var roles, permissions []string
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(pq.Array(&roles), pq.Array(&permissions))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return roles, permissions, nil
+}
+
+// Assign grants a role to a user. Assigning a role the user already holds is a no-op.
+func (r *RoleRepository) Assign(ctx context.Context, userID int, roleName string, grantedBy *int) error {
+	query := `INSERT INTO user_roles (user_id, role_id, granted_by)
+			  SELECT $1, id, $3 FROM roles WHERE name = $2
+			  ON CONFLICT (user_id, role_id) DO NOTHING`
+
+	result, err := r.db.ExecContext(ctx, query, userID, roleName, grantedBy)
+	if err != nil {
+		return err
+	}
+
+	// Distinguish an unknown role from an existing assignment
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		var exists bool
+		if err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM roles WHERE name = $1)`, roleName).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return sql.ErrNoRows
+		}
+	}
+
+	return nil
+}
+
+// Revoke removes a role from a user
+func (r *RoleRepository) Revoke(ctx context.Context, userID int, roleName string) error {
+	query := `DELETE FROM user_roles WHERE user_id = $1 AND role_id = (SELECT id FROM roles WHERE name = $2)`
+
+	result, err := r.db.ExecContext(ctx, query, userID, roleName)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// AssignByEmail grants a role to the user with the given email, if one
+// exists, and reports whether a user was found
+func (r *RoleRepository) AssignByEmail(ctx context.Context, email, roleName string) (bool, error) {
+	query := `INSERT INTO user_roles (user_id, role_id)
+			  SELECT u.id, r.id FROM users u, roles r
+			  WHERE LOWER(u.email) = LOWER($1) AND r.name = $2
+			  ON CONFLICT (user_id, role_id) DO NOTHING`
+
+	if _, err := r.db.ExecContext(ctx, query, email, roleName); err != nil {
+		return false, err
+	}
+
+	var found bool
+	err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE LOWER(email) = LOWER($1))`, email).Scan(&found)
+	return found, err
+}
@@ -0,0 +1,128 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrSessionRotated is returned by Rotate for a session that has already
+// been rotated out and is only valid for its grace period
+var ErrSessionRotated = errors.New("session already rotated")
+
+// Session represents an authenticated browser session. ID is the SHA-256 of
+// the cookie token; the token itself is never stored.
+type Session struct {
+	ID              string    `json:"-"`
+	UserID          int       `json:"user_id"`
+	AuthenticatedAt time.Time `json:"authenticated_at"`
+	CreatedAt       time.Time `json:"created_at"`
+	LastSeenAt      time.Time `json:"last_seen_at"`
+	ExpiresAt       time.Time `json:"expires_at"`
+	IPAddress       string    `json:"ip_address"`
+	UserAgent       string    `json:"user_agent"`
+}
+
+// SessionRepository handles database operations for sessions
+type SessionRepository struct {
+	db *sql.DB
+}
+
+// NewSessionRepository creates a new session repository
+func NewSessionRepository(db *sql.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+// Create inserts a new session
+func (r *SessionRepository) Create(ctx context.Context, session *Session) error {
+	query := `INSERT INTO sessions (id, user_id, authenticated_at, created_at, last_seen_at, expires_at, ip_address, user_agent)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	_, err := r.db.ExecContext(ctx, query, session.ID, session.UserID, session.AuthenticatedAt, session.CreatedAt,
+		session.LastSeenAt, session.ExpiresAt, session.IPAddress, session.UserAgent)
+	return err
+}
+
+// GetByID retrieves a session by its token hash
+func (r *SessionRepository) GetByID(ctx context.Context, id string) (*Session, error) {
+	query := `SELECT id, user_id, authenticated_at, created_at, last_seen_at, expires_at, ip_address, user_agent
+			  FROM sessions WHERE id = $1`
+
+	var session Session
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&session.ID, &session.UserID, &session.AuthenticatedAt, &session.CreatedAt,
+		&session.LastSeenAt, &session.ExpiresAt, &session.IPAddress, &session.UserAgent)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// Touch records activity on a session
+func (r *SessionRepository) Touch(ctx context.Context, id string, lastSeen time.Time) error {
+	query := `UPDATE sessions SET last_seen_at = $1 WHERE id = $2`
+
+	_, err := r.db.ExecContext(ctx, query, lastSeen, id)
+	return err
+}
+
+// Rotate replaces a session with a new one. The old session is kept valid
+// until graceUntil so concurrent requests holding the old cookie still succeed.
+func (r *SessionRepository) Rotate(ctx context.Context, oldID string, graceUntil time.Time, session *Session) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Only one request may rotate a session: once its expiry has been cut to
+	// the grace period, later requests presenting the old cookie match nothing
+	result, err := tx.ExecContext(ctx, `UPDATE sessions SET expires_at = $1 WHERE id = $2 AND expires_at > $1`, graceUntil, oldID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrSessionRotated
+	}
+
+	query := `INSERT INTO sessions (id, user_id, authenticated_at, created_at, last_seen_at, expires_at, ip_address, user_agent)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	_, err = tx.ExecContext(ctx, query, session.ID, session.UserID, session.AuthenticatedAt, session.CreatedAt,
+		session.LastSeenAt, session.ExpiresAt, session.IPAddress, session.UserAgent)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Delete removes a session
+func (r *SessionRepository) Delete(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM sessions WHERE id = $1`, id)
+	return err
+}
+
+// DeleteByUserID removes every session belonging to a user
+func (r *SessionRepository) DeleteByUserID(ctx context.Context, userID int) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM sessions WHERE user_id = $1`, userID)
+	return err
+}
+
+// DeleteExpired removes sessions that expired before the given time
+func (r *SessionRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM sessions WHERE expires_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// SlideGrant gives a user access to view a slide they would not otherwise see
+type SlideGrant struct {
+	UploadID  int       `json:"upload_id"`
+	UserID    int       `json:"user_id"`
+	Email     string    `json:"email"`
+	GrantedBy *int      `json:"granted_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SlideGrantRepository handles database operations for slide access
+type SlideGrantRepository struct {
+	db *sql.DB
+}
+
+// NewSlideGrantRepository creates a new slide grant repository
+func NewSlideGrantRepository(db *sql.DB) *SlideGrantRepository {
+	return &SlideGrantRepository{db: db}
+}
+
+// viewableBy matches uploads u that user $2 may view: they uploaded it,
+// they ordered the test it belongs to, or they were granted access
+const viewableBy = `(u.owner_id = $2
+	OR EXISTS (SELECT 1 FROM test_orders o
+			   WHERE o.accession_number = u.accession_number AND o.ordering_clinician_id = $2)
+	OR EXISTS (SELECT 1 FROM slide_grants g WHERE g.upload_id = u.id AND g.user_id = $2))`
+
+// CanView reports whether a user may view a slide: they uploaded it, they
+// ordered the test it belongs to, or they were granted access
+func (r *SlideGrantRepository) CanView(ctx context.Context, uploadID, userID int) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM uploads u WHERE u.id = $1 AND ` + viewableBy + `)`
+
+	var allowed bool
+	err := r.db.QueryRowContext(ctx, query, uploadID, userID).Scan(&allowed)
+	return allowed, err
+}
+
+// GetByUpload lists the users granted access to a slide
+func (r *SlideGrantRepository) GetByUpload(ctx context.Context, uploadID int) ([]SlideGrant, error) {
+	query := `SELECT g.upload_id, g.user_id, u.email, g.granted_by, g.created_at
+			  FROM slide_grants g
+			  JOIN users u ON u.id = g.user_id
+			  WHERE g.upload_id = $1
+			  ORDER BY g.created_at`
+
+	rows, err := r.db.QueryContext(ctx, query, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var grants []SlideGrant
+	for rows.Next() {
+		var grant SlideGrant
+		var grantedBy sql.NullInt64
+		if err := rows.Scan(&grant.UploadID, &grant.UserID, &grant.Email, &grantedBy, &grant.CreatedAt); err != nil {
+			return nil, err
+		}
+		grant.GrantedBy = nullIntPtr(grantedBy)
+		grants = append(grants, grant)
+	}
+
+	return grants, rows.Err()
+}
+
+// Grant gives a user access to a slide. Granting existing access is a no-op.
+func (r *SlideGrantRepository) Grant(ctx context.Context, uploadID, userID int, grantedBy *int) error {
+	query := `INSERT INTO slide_grants (upload_id, user_id, granted_by)
+			  VALUES ($1, $2, $3)
+			  ON CONFLICT (upload_id, user_id) DO NOTHING`
+
+	_, err := r.db.ExecContext(ctx, query, uploadID, userID, grantedBy)
+	return err
+}
+
+// Revoke removes a user's access to a slide. It returns sql.ErrNoRows if
+// the user had no grant.
+func (r *SlideGrantRepository) Revoke(ctx context.Context, uploadID, userID int) error {
+	query := `DELETE FROM slide_grants WHERE upload_id = $1 AND user_id = $2`
+
+	return execAffectingOne(ctx, r.db, query, uploadID, userID)
+}
@@ -0,0 +1,473 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"backend/internal/listing"
+)
+
+// TestOrderStatus is the lifecycle state of a diagnostic test order
+type TestOrderStatus string
+
+const (
+	TestOrderOrdered    TestOrderStatus = "ordered"
+	TestOrderCollected  TestOrderStatus = "collected"
+	TestOrderReceived   TestOrderStatus = "received"
+	TestOrderInAnalysis TestOrderStatus = "in_analysis"
+	TestOrderResulted   TestOrderStatus = "resulted"
+	TestOrderVerified   TestOrderStatus = "verified"
+	TestOrderCancelled  TestOrderStatus = "cancelled"
+)
+
+// TestOrderPriority is how urgently a test should be processed
+type TestOrderPriority string
+
+const (
+	TestOrderRoutine TestOrderPriority = "routine"
+	TestOrderUrgent  TestOrderPriority = "urgent"
+	TestOrderStat    TestOrderPriority = "stat"
+)
+
+// testOrderTransitions lists the statuses each status may move to. Orders
+// progress one step at a time and may be cancelled until they are resulted.
+// Resulted and verified are only entered by recording and signing out a
+// result; see SetByResult.
+var testOrderTransitions = map[TestOrderStatus][]TestOrderStatus{
+	TestOrderOrdered:    {TestOrderCollected, TestOrderCancelled},
+	TestOrderCollected:  {TestOrderReceived, TestOrderCancelled},
+	TestOrderReceived:   {TestOrderInAnalysis, TestOrderCancelled},
+	TestOrderInAnalysis: {TestOrderResulted, TestOrderCancelled},
+	TestOrderResulted:   {TestOrderVerified},
+	TestOrderVerified:   {},
+	TestOrderCancelled:  {},
+}
+
+// ErrInvalidTransition is returned when a status change is not allowed
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+// ErrStatusConflict is returned when an order's status changed concurrently
+var ErrStatusConflict = errors.New("test order status changed concurrently")
+
+// ErrPlacerOrderExists is returned when an order is placed twice under the
+// same placer order number
+var ErrPlacerOrderExists = errors.New("placer order number already exists")
+
+// Valid reports whether s is a known status
+func (s TestOrderStatus) Valid() bool {
+	_, ok := testOrderTransitions[s]
+	return ok
+}
+
+// Terminal reports whether no further transitions are possible
+func (s TestOrderStatus) Terminal() bool {
+	return len(testOrderTransitions[s]) == 0
+}
+
+// SetByResult reports whether s is entered by writing a result rather than
+// by a status change: recording the result resulted, signing it out verified
+func (s TestOrderStatus) SetByResult() bool {
+	return s == TestOrderResulted || s == TestOrderVerified
+}
+
+// CanTransitionTo reports whether an order may move from s to next
+func (s TestOrderStatus) CanTransitionTo(next TestOrderStatus) bool {
+	for _, allowed := range testOrderTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// Valid reports whether p is a known priority
+func (p TestOrderPriority) Valid() bool {
+	return p == TestOrderRoutine || p == TestOrderUrgent || p == TestOrderStat
+}
+
+// TestOrder represents a diagnostic test ordered for a patient
+type TestOrder struct {
+	ID                  int               `json:"id"`
+	AccessionNumber     string            `json:"accession_number"`
+	PatientMRN          string            `json:"patient_mrn"`
+	PatientID           *int              `json:"patient_id"`
+	PlacerSystem        string            `json:"placer_system,omitempty"`
+	PlacerOrderNumber   string            `json:"placer_order_number,omitempty"`
+	OrderingClinicianID *int              `json:"ordering_clinician_id"`
+	TestType            string            `json:"test_type"`
+	Priority            TestOrderPriority `json:"priority"`
+	Status              TestOrderStatus   `json:"status"`
+	SpecimenID          string            `json:"specimen_id"`
+	Notes               string            `json:"notes"`
+	CancelReason        string            `json:"cancel_reason,omitempty"`
+	OrderedAt           time.Time         `json:"ordered_at"`
+	CollectedAt         *time.Time        `json:"collected_at"`
+	ReceivedAt          *time.Time        `json:"received_at"`
+	AnalysisStartedAt   *time.Time        `json:"analysis_started_at"`
+	ResultedAt          *time.Time        `json:"resulted_at"`
+	VerifiedAt          *time.Time        `json:"verified_at"`
+	CancelledAt         *time.Time        `json:"cancelled_at"`
+	CreatedAt           time.Time         `json:"created_at"`
+	UpdatedAt           time.Time         `json:"updated_at"`
+}
+
+// TestOrderList is what GET /api/tests can be sorted and filtered by
+var TestOrderList = &listing.Spec{
+	Key: "id",
+	Sorts: map[string]listing.Field{
+		"id":               {Column: "id", Type: listing.Int},
+		"accession_number": {Column: "accession_number", Type: listing.String},
+		"ordered_at":       {Column: "ordered_at", Type: listing.Time},
+	},
+	DefaultSort: "-ordered_at",
+	Filters: map[string]listing.Filter{
+		"status":         {Column: "status", Type: listing.String, Op: listing.Equal},
+		"patient_mrn":    {Column: "patient_mrn", Type: listing.String, Op: listing.Equal},
+		"patient_id":     {Column: "patient_id", Type: listing.Int, Op: listing.Equal},
+		"ordered_after":  {Column: "ordered_at", Type: listing.Time, Op: listing.After},
+		"ordered_before": {Column: "ordered_at", Type: listing.Time, Op: listing.Before},
+	},
+}
+
+// ListKey is the order's position in a list sorted by the named
+// TestOrderList field
+func (o TestOrder) ListKey(sort string) listing.Key {
+	key := listing.Key{ID: int64(o.ID)}
+	switch sort {
+	case "accession_number":
+		key.Value = o.AccessionNumber
+	case "ordered_at":
+		key.Value = o.OrderedAt
+	default:
+		key.Value = o.ID
+	}
+	return key
+}
+
+// TestOrderSearch selects a page of test orders. Orders placed at or after
+// From and before To match; either bound may be omitted.
+type TestOrderSearch struct {
+	IDs       []int
+	PatientID *int
+	Statuses  []TestOrderStatus
+	From      *time.Time
+	To        *time.Time
+	Limit     int
+	Offset    int
+}
+
+// TestOrderRepository handles database operations for test orders
+type TestOrderRepository struct {
+	db DBTX
+}
+
+// NewTestOrderRepository creates a new test order repository
+func NewTestOrderRepository(db DBTX) *TestOrderRepository {
+	return &TestOrderRepository{db: db}
+}
+
+// testOrderColumns is the column list scanned by scanTestOrder
+const testOrderColumns = `id, accession_number, patient_mrn, patient_id, placer_system, placer_order_number,
+	ordering_clinician_id, test_type, priority, status, specimen_id, notes, cancel_reason, ordered_at, collected_at, received_at, analysis_started_at,
+	resulted_at, verified_at, cancelled_at, created_at, updated_at`
+
+// testOrderStatusColumns maps each status to the timestamp recorded on entry
+var testOrderStatusColumns = map[TestOrderStatus]string{
+	TestOrderCollected:  "collected_at",
+	TestOrderReceived:   "received_at",
+	TestOrderInAnalysis: "analysis_started_at",
+	TestOrderResulted:   "resulted_at",
+	TestOrderVerified:   "verified_at",
+	TestOrderCancelled:  "cancelled_at",
+}
+
+// scanTestOrder scans a row selected with testOrderColumns
+func scanTestOrder(row interface{ Scan(...interface{}) error }) (*TestOrder, error) {
+	var order TestOrder
+	var patientID, clinicianID sql.NullInt64
+	var collectedAt, receivedAt, analysisStartedAt, resultedAt, verifiedAt, cancelledAt sql.NullTime
+
+	err := row.Scan(&order.ID, &order.AccessionNumber, &order.PatientMRN, &patientID, &order.PlacerSystem,
+		&order.PlacerOrderNumber, &clinicianID, &order.TestType,
+		&order.Priority, &order.Status, &order.SpecimenID, &order.Notes, &order.CancelReason, &order.OrderedAt,
+		&collectedAt, &receivedAt, &analysisStartedAt, &resultedAt, &verifiedAt, &cancelledAt,
+		&order.CreatedAt, &order.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	order.PatientID = nullIntPtr(patientID)
+	order.OrderingClinicianID = nullIntPtr(clinicianID)
+	order.CollectedAt = nullTimePtr(collectedAt)
+	order.ReceivedAt = nullTimePtr(receivedAt)
+	order.AnalysisStartedAt = nullTimePtr(analysisStartedAt)
+	order.ResultedAt = nullTimePtr(resultedAt)
+	order.VerifiedAt = nullTimePtr(verifiedAt)
+	order.CancelledAt = nullTimePtr(cancelledAt)
+
+	return &order, nil
+}
+
+// nullTimePtr converts a nullable timestamp to a pointer
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// nullIntPtr converts a nullable integer to a pointer
+func nullIntPtr(n sql.NullInt64) *int {
+	if !n.Valid {
+		return nil
+	}
+	id := int(n.Int64)
+	return &id
+}
+
+// int64s converts IDs for pq.Array, keeping an empty list NULL
+func int64s(ids []int) []int64 {
+	if len(ids) == 0 {
+		return nil
+	}
+	converted := make([]int64, len(ids))
+	for i, id := range ids {
+		converted[i] = int64(id)
+	}
+	return converted
+}
+
+// timestampParam passes an optional time as a query parameter. Timestamps
+// are stored without a time zone, in UTC.
+func timestampParam(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Format("2006-01-02 15:04:05.999999")
+}
+
+// List retrieves one page of test orders, fetching q.Fetch() rows so the
+// caller can tell whether there are more, and counts every order matching
+// the filters
+func (r *TestOrderRepository) List(ctx context.Context, q *listing.Query) ([]TestOrder, int, error) {
+	where, args := q.Where(false)
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM test_orders `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	where, args = q.Where(true)
+	args = append(args, q.Fetch())
+	query := fmt.Sprintf(`SELECT %s FROM test_orders %s ORDER BY %s LIMIT $%d`,
+		testOrderColumns, where, q.OrderBy(), len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var orders []TestOrder
+	for rows.Next() {
+		order, err := scanTestOrder(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		orders = append(orders, *order)
+	}
+
+	return orders, total, rows.Err()
+}
+
+// Search retrieves a page of test orders matching the search, most recent
+// first, and the number of matches across all pages
+func (r *TestOrderRepository) Search(ctx context.Context, search TestOrderSearch) ([]TestOrder, int, error) {
+	var statuses []string
+	for _, status := range search.Statuses {
+		statuses = append(statuses, string(status))
+	}
+
+	where := ` WHERE ($1::bigint[] IS NULL OR id = ANY($1))
+			  AND ($2::integer IS NULL OR patient_id = $2)
+			  AND ($3::text[] IS NULL OR status = ANY($3))
+			  AND ($4::timestamp IS NULL OR ordered_at >= $4)
+			  AND ($5::timestamp IS NULL OR ordered_at < $5)`
+	args := []interface{}{pq.Array(int64s(search.IDs)), search.PatientID, pq.Array(statuses),
+		timestampParam(search.From), timestampParam(search.To)}
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT count(*) FROM test_orders`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + testOrderColumns + ` FROM test_orders` + where +
+		` ORDER BY ordered_at DESC, id DESC LIMIT $6 OFFSET $7`
+
+	rows, err := r.db.QueryContext(ctx, query, append(args, search.Limit, search.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var orders []TestOrder
+	for rows.Next() {
+		order, err := scanTestOrder(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		orders = append(orders, *order)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return orders, total, nil
+}
+
+// GetByIDs retrieves the test orders with the given IDs, keyed by ID
+func (r *TestOrderRepository) GetByIDs(ctx context.Context, ids []int) (map[int]*TestOrder, error) {
+	query := `SELECT ` + testOrderColumns + ` FROM test_orders WHERE id = ANY($1)`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(int64s(ids)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := make(map[int]*TestOrder, len(ids))
+	for rows.Next() {
+		order, err := scanTestOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders[order.ID] = order
+	}
+
+	return orders, rows.Err()
+}
+
+// GetByID retrieves a test order by ID
+func (r *TestOrderRepository) GetByID(ctx context.Context, id int) (*TestOrder, error) {
+	query := `SELECT ` + testOrderColumns + ` FROM test_orders WHERE id = $1`
+
+	order, err := scanTestOrder(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// GetByAccession retrieves a test order by accession number
+func (r *TestOrderRepository) GetByAccession(ctx context.Context, accession string) (*TestOrder, error) {
+	query := `SELECT ` + testOrderColumns + ` FROM test_orders WHERE accession_number = $1`
+
+	order, err := scanTestOrder(r.db.QueryRowContext(ctx, query, accession))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// GetByPlacer retrieves a test order by the number the placing system gave it
+func (r *TestOrderRepository) GetByPlacer(ctx context.Context, system, number string) (*TestOrder, error) {
+	query := `SELECT ` + testOrderColumns + ` FROM test_orders WHERE placer_system = $1 AND placer_order_number = $2`
+
+	order, err := scanTestOrder(r.db.QueryRowContext(ctx, query, system, number))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// Create creates a new test order in the ordered status. It returns
+// ErrPlacerOrderExists if the placer order number is already known.
+func (r *TestOrderRepository) Create(ctx context.Context, order *TestOrder) error {
+	query := `INSERT INTO test_orders (patient_mrn, patient_id, placer_system, placer_order_number,
+			  ordering_clinician_id, test_type, priority, specimen_id, notes)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			  RETURNING id, accession_number, status, ordered_at, created_at, updated_at`
+
+	err := r.db.QueryRowContext(ctx, query, order.PatientMRN, order.PatientID, order.PlacerSystem, order.PlacerOrderNumber,
+		order.OrderingClinicianID, order.TestType, order.Priority, order.SpecimenID, order.Notes).
+		Scan(&order.ID, &order.AccessionNumber, &order.Status, &order.OrderedAt, &order.CreatedAt, &order.UpdatedAt)
+	if err != nil && isUniqueViolation(err) {
+		return ErrPlacerOrderExists
+	}
+	return err
+}
+
+// Update updates the editable fields of a test order that is not yet terminal
+func (r *TestOrderRepository) Update(ctx context.Context, order *TestOrder) error {
+	query := `UPDATE test_orders SET test_type = $1, priority = $2, specimen_id = $3, notes = $4,
+			  updated_at = CURRENT_TIMESTAMP
+			  WHERE id = $5 AND status NOT IN ('verified', 'cancelled') RETURNING updated_at`
+
+	return r.db.QueryRowContext(ctx, query, order.TestType, order.Priority, order.SpecimenID, order.Notes, order.ID).
+		Scan(&order.UpdatedAt)
+}
+
+// UpdateStatus moves an order from one status to another, recording the
+// transition time. The update only applies if the order is still in the
+// expected status, so concurrent transitions cannot skip a step. Statuses
+// set by results are refused with ErrInvalidTransition.
+func (r *TestOrderRepository) UpdateStatus(ctx context.Context, id int, from, to TestOrderStatus, reason string) (*TestOrder, error) {
+	if !from.CanTransitionTo(to) || to.SetByResult() {
+		return nil, fmt.Errorf("%w: %s to %s", ErrInvalidTransition, from, to)
+	}
+
+	column := testOrderStatusColumns[to]
+	query := `UPDATE test_orders SET status = $1, ` + column + ` = CURRENT_TIMESTAMP,
+			  cancel_reason = CASE WHEN $1 = 'cancelled' THEN $2 ELSE cancel_reason END,
+			  updated_at = CURRENT_TIMESTAMP
+			  WHERE id = $3 AND status = $4
+			  RETURNING ` + testOrderColumns
+
+	order, err := scanTestOrder(r.db.QueryRowContext(ctx, query, string(to), reason, id, string(from)))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrStatusConflict
+		}
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// Delete deletes a test order that has not progressed past ordered
+func (r *TestOrderRepository) Delete(ctx context.Context, id int) error {
+	query := `DELETE FROM test_orders WHERE id = $1 AND status = 'ordered'`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
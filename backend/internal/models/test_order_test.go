@@ -0,0 +1,75 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestTestOrderStatus_CanTransitionTo(t *testing.T) {
+	tests := []struct {
+		from     TestOrderStatus
+		to       TestOrderStatus
+		expected bool
+	}{
+		{TestOrderOrdered, TestOrderCollected, true},
+		{TestOrderCollected, TestOrderReceived, true},
+		{TestOrderReceived, TestOrderInAnalysis, true},
+		{TestOrderInAnalysis, TestOrderResulted, true},
+		{TestOrderResulted, TestOrderVerified, true},
+		{TestOrderOrdered, TestOrderCancelled, true},
+		{TestOrderInAnalysis, TestOrderCancelled, true},
+		{TestOrderOrdered, TestOrderReceived, false},
+		{TestOrderCollected, TestOrderOrdered, false},
+		{TestOrderResulted, TestOrderCancelled, false},
+		{TestOrderVerified, TestOrderCancelled, false},
+		{TestOrderCancelled, TestOrderOrdered, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.from.CanTransitionTo(tt.to); got != tt.expected {
+			t.Errorf("%s -> %s: expected %v, got %v", tt.from, tt.to, tt.expected, got)
+		}
+	}
+}
+
+func TestTestOrderStatus_Terminal(t *testing.T) {
+	if !TestOrderVerified.Terminal() || !TestOrderCancelled.Terminal() {
+		t.Error("Expected verified and cancelled to be terminal")
+	}
+
+	if TestOrderResulted.Terminal() {
+		t.Error("Expected resulted not to be terminal")
+	}
+
+	if TestOrderStatus("shipped").Valid() {
+		t.Error("Expected unknown status to be invalid")
+	}
+}
+
+func TestTestOrderStatus_SetByResult(t *testing.T) {
+	for _, status := range []TestOrderStatus{TestOrderResulted, TestOrderVerified} {
+		if !status.SetByResult() {
+			t.Errorf("Expected %s to be set by results", status)
+		}
+	}
+	if TestOrderInAnalysis.SetByResult() || TestOrderCancelled.SetByResult() {
+		t.Error("Expected other statuses to be set by status changes")
+	}
+
+	// A valid step is still refused when a result should drive it
+	_, err := NewTestOrderRepository(nil).UpdateStatus(context.Background(), 1, TestOrderInAnalysis, TestOrderResulted, "")
+	if !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Expected ErrInvalidTransition, got %v", err)
+	}
+}
+
+func TestTestOrderRepository_UpdateStatus_InvalidTransition(t *testing.T) {
+	// The transition is rejected before the database is touched
+	repo := NewTestOrderRepository(nil)
+
+	_, err := repo.UpdateStatus(context.Background(), 1, TestOrderOrdered, TestOrderVerified, "")
+	if !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Expected ErrInvalidTransition, got %v", err)
+	}
+}
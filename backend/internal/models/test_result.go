@@ -0,0 +1,431 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ErrResultExists is returned when a first result is recorded twice for an order
+var ErrResultExists = errors.New("test result already recorded")
+
+// ErrResultConflict is returned when another version was written concurrently
+var ErrResultConflict = errors.New("test result changed concurrently")
+
+// ErrOrderNotReady is returned when a result is recorded or signed out for
+// an order whose status does not allow it, such as one not yet in analysis
+var ErrOrderNotReady = errors.New("test order is not ready for this result")
+
+// Finding is a single structured observation within a result, e.g. a marker
+// with its measured value and an abnormal flag
+type Finding struct {
+	Code  string `json:"code"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	Unit  string `json:"unit,omitempty"`
+	Flag  string `json:"flag,omitempty"`
+}
+
+// TestResult is one version of the result reported for a test order. Version
+// 1 is the original report; each amendment adds a new version. A version is
+// immutable once verified.
+type TestResult struct {
+	ID                     int             `json:"id"`
+	TestOrderID            int             `json:"test_order_id"`
+	Version                int             `json:"version"`
+	Findings               []Finding       `json:"findings"`
+	Interpretation         string          `json:"interpretation"`
+	ReportingPathologistID *int            `json:"reporting_pathologist_id"`
+	ModelOutput            json.RawMessage `json:"model_output,omitempty"`
+	AmendmentReason        string          `json:"amendment_reason,omitempty"`
+	CreatedBy              *int            `json:"created_by"`
+	CreatedAt              time.Time       `json:"created_at"`
+	UpdatedAt              time.Time       `json:"updated_at"`
+	VerifiedBy             *int            `json:"verified_by"`
+	VerifiedAt             *time.Time      `json:"verified_at"`
+}
+
+// Verified reports whether this version has been signed out
+func (r *TestResult) Verified() bool {
+	return r.VerifiedAt != nil
+}
+
+// ResultSearch selects a page of reported results: the current version of
+// each order's result, if it has been verified. Results whose specimen was
+// collected at or after From and before To match; either bound may be
+// omitted. FindingIDs name individual findings as "{test order ID}-{n}",
+// counting from 1.
+type ResultSearch struct {
+	TestOrderIDs []int
+	FindingIDs   []string
+	PatientID    *int
+	Amended      *bool
+	From         *time.Time
+	To           *time.Time
+	Limit        int
+	Offset       int
+}
+
+// ResultFinding is one finding of a reported result. Number counts from 1.
+type ResultFinding struct {
+	Result TestResult
+	Number int
+}
+
+// TestResultRepository handles database operations for test results
+type TestResultRepository struct {
+	db DBTX
+}
+
+// NewTestResultRepository creates a new test result repository
+func NewTestResultRepository(db DBTX) *TestResultRepository {
+	return &TestResultRepository{db: db}
+}
+
+// testResultColumns is the column list scanned by scanTestResult
+const testResultColumns = `id, test_order_id, version, findings, interpretation, reporting_pathologist_id,
+	model_output, amendment_reason, created_by, created_at, updated_at, verified_by, verified_at`
+
+// scanTestResult scans a row selected with testResultColumns
+func scanTestResult(row interface{ Scan(...interface{}) error }) (*TestResult, error) {
+	var result TestResult
+	var findings, modelOutput []byte
+	var pathologistID, createdBy, verifiedBy sql.NullInt64
+	var verifiedAt sql.NullTime
+
+	err := row.Scan(&result.ID, &result.TestOrderID, &result.Version, &findings, &result.Interpretation,
+		&pathologistID, &modelOutput, &result.AmendmentReason, &createdBy, &result.CreatedAt, &result.UpdatedAt,
+		&verifiedBy, &verifiedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(findings, &result.Findings); err != nil {
+		return nil, err
+	}
+	if modelOutput != nil {
+		result.ModelOutput = json.RawMessage(modelOutput)
+	}
+	result.ReportingPathologistID = nullIntPtr(pathologistID)
+	result.CreatedBy = nullIntPtr(createdBy)
+	result.VerifiedBy = nullIntPtr(verifiedBy)
+	result.VerifiedAt = nullTimePtr(verifiedAt)
+
+	return &result, nil
+}
+
+// encodeResultJSON returns the findings and model output as query parameters.
+// JSONB parameters are passed as text; a missing model output becomes NULL.
+func encodeResultJSON(result *TestResult) (string, interface{}, error) {
+	findings := result.Findings
+	if findings == nil {
+		findings = []Finding{}
+	}
+
+	encoded, err := json.Marshal(findings)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var modelOutput interface{}
+	if len(result.ModelOutput) > 0 {
+		modelOutput = string(result.ModelOutput)
+	}
+
+	return string(encoded), modelOutput, nil
+}
+
+// GetHistory retrieves every version of the result for an order, oldest first
+func (r *TestResultRepository) GetHistory(ctx context.Context, testOrderID int) ([]TestResult, error) {
+	query := `SELECT ` + testResultColumns + ` FROM test_results
+			  WHERE test_order_id = $1 ORDER BY version`
+
+	rows, err := r.db.QueryContext(ctx, query, testOrderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []TestResult
+	for rows.Next() {
+		result, err := scanTestResult(rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, *result)
+	}
+
+	return results, rows.Err()
+}
+
+// GetLatest retrieves the current version of the result for an order
+func (r *TestResultRepository) GetLatest(ctx context.Context, testOrderID int) (*TestResult, error) {
+	query := `SELECT ` + testResultColumns + ` FROM test_results
+			  WHERE test_order_id = $1 ORDER BY version DESC LIMIT 1`
+
+	result, err := scanTestResult(r.db.QueryRowContext(ctx, query, testOrderID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// advanceOrder moves a result's order to status in tx, if it is in one of
+// the given statuses, and returns ErrOrderNotReady if it is not. The entry
+// time of a status already reached is kept.
+func advanceOrder(ctx context.Context, tx DBTX, orderID int, status TestOrderStatus, from ...TestOrderStatus) error {
+	column := testOrderStatusColumns[status]
+	query := `UPDATE test_orders SET status = $1, ` + column + ` = COALESCE(` + column + `, CURRENT_TIMESTAMP),
+			  updated_at = CURRENT_TIMESTAMP
+			  WHERE id = $2 AND status = ANY($3)`
+
+	statuses := make([]string, len(from))
+	for i, s := range from {
+		statuses[i] = string(s)
+	}
+
+	result, err := tx.ExecContext(ctx, query, string(status), orderID, pq.Array(statuses))
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrOrderNotReady
+	}
+	return nil
+}
+
+// Create records the first version of the result for an order in analysis,
+// moving the order to resulted. It returns ErrOrderNotReady for an order in
+// any other status.
+func (r *TestResultRepository) Create(ctx context.Context, result *TestResult) error {
+	findings, modelOutput, err := encodeResultJSON(result)
+	if err != nil {
+		return err
+	}
+
+	var created *TestResult
+	err = withTx(ctx, r.db, func(tx DBTX) error {
+		// Resulted is accepted too so a second result is reported as
+		// ErrResultExists rather than a status problem
+		if err := advanceOrder(ctx, tx, result.TestOrderID, TestOrderResulted, TestOrderInAnalysis, TestOrderResulted); err != nil {
+			return err
+		}
+
+		query := `INSERT INTO test_results (test_order_id, version, findings, interpretation,
+				  reporting_pathologist_id, model_output, created_by)
+				  VALUES ($1, 1, $2, $3, $4, $5, $6)
+				  RETURNING ` + testResultColumns
+
+		var err error
+		created, err = scanTestResult(tx.QueryRowContext(ctx, query, result.TestOrderID, findings, result.Interpretation,
+			result.ReportingPathologistID, modelOutput, result.CreatedBy))
+		if isUniqueViolation(err) {
+			return ErrResultExists
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	*result = *created
+	return nil
+}
+
+// Update replaces the content of an unverified version. It returns
+// sql.ErrNoRows if the version does not exist or is already verified.
+func (r *TestResultRepository) Update(ctx context.Context, result *TestResult) error {
+	findings, modelOutput, err := encodeResultJSON(result)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE test_results SET findings = $1, interpretation = $2, reporting_pathologist_id = $3,
+			  model_output = $4, updated_at = CURRENT_TIMESTAMP
+			  WHERE id = $5 AND verified_at IS NULL
+			  RETURNING ` + testResultColumns
+
+	updated, err := scanTestResult(r.db.QueryRowContext(ctx, query, findings, result.Interpretation,
+		result.ReportingPathologistID, modelOutput, result.ID))
+	if err != nil {
+		return err
+	}
+
+	*result = *updated
+	return nil
+}
+
+// Verify signs out an unverified version, making it immutable, and moves its
+// order to verified. The verifier becomes the reporting pathologist unless
+// one was already named. It returns sql.ErrNoRows if the version does not
+// exist or is already verified, and ErrOrderNotReady if the order is not
+// resulted.
+func (r *TestResultRepository) Verify(ctx context.Context, id, verifiedBy int) (*TestResult, error) {
+	var verified *TestResult
+	err := withTx(ctx, r.db, func(tx DBTX) error {
+		query := `UPDATE test_results SET verified_by = $1, verified_at = CURRENT_TIMESTAMP,
+				  reporting_pathologist_id = COALESCE(reporting_pathologist_id, $1)
+				  WHERE id = $2 AND verified_at IS NULL
+				  RETURNING ` + testResultColumns
+
+		var err error
+		if verified, err = scanTestResult(tx.QueryRowContext(ctx, query, verifiedBy, id)); err != nil {
+			return err
+		}
+
+		// Signing out an amendment leaves an already verified order as it is
+		return advanceOrder(ctx, tx, verified.TestOrderID, TestOrderVerified, TestOrderResulted, TestOrderVerified)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return verified, nil
+}
+
+// Amend records a corrected version on top of the current one, which must be
+// verified. The model output carries over unless the amendment replaces it.
+// It returns sql.ErrNoRows if the order has no verified current version and
+// ErrResultConflict if another amendment was recorded concurrently.
+func (r *TestResultRepository) Amend(ctx context.Context, result *TestResult) error {
+	findings, modelOutput, err := encodeResultJSON(result)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO test_results (test_order_id, version, findings, interpretation,
+			  reporting_pathologist_id, model_output, amendment_reason, created_by)
+			  SELECT test_order_id, version + 1, $2, $3, $4, COALESCE($5::jsonb, model_output), $6, $7
+			  FROM test_results
+			  WHERE test_order_id = $1 AND verified_at IS NOT NULL
+			  AND version = (SELECT MAX(version) FROM test_results WHERE test_order_id = $1)
+			  RETURNING ` + testResultColumns
+
+	amended, err := scanTestResult(r.db.QueryRowContext(ctx, query, result.TestOrderID, findings, result.Interpretation,
+		result.ReportingPathologistID, modelOutput, result.AmendmentReason, result.CreatedBy))
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrResultConflict
+		}
+		return err
+	}
+
+	*result = *amended
+	return nil
+}
+
+// reportedResults selects the latest verified version of each order's result
+// as "reported"; amendments awaiting verification are not reported yet
+const reportedResults = `WITH reported AS (
+	SELECT DISTINCT ON (test_order_id) ` + testResultColumns + ` FROM test_results
+	WHERE verified_at IS NOT NULL ORDER BY test_order_id, version DESC)`
+
+// resultSearchWhere filters reported results for a ResultSearch. The
+// finding number f.n is only available when searching findings.
+func resultSearchWhere(search ResultSearch) (string, []interface{}) {
+	where := ` WHERE ($1::bigint[] IS NULL OR test_order_id = ANY($1))
+			  AND ($2::boolean IS NULL OR (version > 1) = $2)
+			  AND test_order_id IN (SELECT id FROM test_orders
+									WHERE ($3::integer IS NULL OR patient_id = $3)
+									AND ($4::timestamp IS NULL OR collected_at >= $4)
+									AND ($5::timestamp IS NULL OR collected_at < $5))`
+	args := []interface{}{pq.Array(int64s(search.TestOrderIDs)), search.Amended, search.PatientID,
+		timestampParam(search.From), timestampParam(search.To)}
+	return where, args
+}
+
+// SearchReported retrieves a page of reported results matching the search,
+// most recent order first, and the number of matches across all pages
+func (r *TestResultRepository) SearchReported(ctx context.Context, search ResultSearch) ([]TestResult, int, error) {
+	where, args := resultSearchWhere(search)
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, reportedResults+` SELECT count(*) FROM reported`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := reportedResults + ` SELECT ` + testResultColumns + ` FROM reported` + where +
+		` ORDER BY test_order_id DESC LIMIT $6 OFFSET $7`
+
+	rows, err := r.db.QueryContext(ctx, query, append(args, search.Limit, search.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var results []TestResult
+	for rows.Next() {
+		result, err := scanTestResult(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		results = append(results, *result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return results, total, nil
+}
+
+// SearchFindings retrieves a page of the findings of reported results
+// matching the search, most recent order first, and the number of matching
+// findings across all pages
+func (r *TestResultRepository) SearchFindings(ctx context.Context, search ResultSearch) ([]ResultFinding, int, error) {
+	where, args := resultSearchWhere(search)
+	where += ` AND ($6::text[] IS NULL OR concat(test_order_id, '-', f.n) = ANY($6))`
+	args = append(args, pq.Array(search.FindingIDs))
+
+	from := ` FROM reported CROSS JOIN LATERAL jsonb_array_elements(findings) WITH ORDINALITY AS f(finding, n)`
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, reportedResults+` SELECT count(*)`+from+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := reportedResults + ` SELECT ` + testResultColumns + `, f.n` + from + where +
+		` ORDER BY test_order_id DESC, f.n LIMIT $7 OFFSET $8`
+
+	rows, err := r.db.QueryContext(ctx, query, append(args, search.Limit, search.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var findings []ResultFinding
+	for rows.Next() {
+		var finding ResultFinding
+		result, err := scanTestResult(scanWithNumber{rows, &finding.Number})
+		if err != nil {
+			return nil, 0, err
+		}
+		finding.Result = *result
+		findings = append(findings, finding)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return findings, total, nil
+}
+
+// scanWithNumber scans a row with one extra trailing integer column
+type scanWithNumber struct {
+	row    interface{ Scan(...interface{}) error }
+	number *int
+}
+
+func (s scanWithNumber) Scan(dest ...interface{}) error {
+	return s.row.Scan(append(dest, s.number)...)
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestEncodeResultJSON(t *testing.T) {
+	findings, modelOutput, err := encodeResultJSON(&TestResult{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if findings != "[]" {
+		t.Errorf("Expected empty findings array, got %s", findings)
+	}
+	if modelOutput != nil {
+		t.Errorf("Expected NULL model output, got %v", modelOutput)
+	}
+
+	result := &TestResult{
+		Findings:    []Finding{{Code: "AP-1", Name: "Marker", Value: "positive"}},
+		ModelOutput: json.RawMessage(`{"score":0.93}`),
+	}
+
+	findings, modelOutput, err = encodeResultJSON(result)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if findings != `[{"code":"AP-1","name":"Marker","value":"positive"}]` {
+		t.Errorf("Unexpected findings encoding: %s", findings)
+	}
+	if modelOutput != `{"score":0.93}` {
+		t.Errorf("Expected model output passed as text, got %v", modelOutput)
+	}
+}
+
+func TestTestResultRepository_AdvancesOrder(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+	orders := NewTestOrderRepository(db)
+	results := NewTestResultRepository(db)
+
+	order := &TestOrder{PatientMRN: "MRN-RESULTED", TestType: "histology", Priority: TestOrderRoutine}
+	if err := orders.Create(ctx, order); err != nil {
+		t.Fatalf("Create order failed: %v", err)
+	}
+
+	result := &TestResult{TestOrderID: order.ID, Interpretation: "Benign"}
+	if err := results.Create(ctx, result); !errors.Is(err, ErrOrderNotReady) {
+		t.Fatalf("Expected ErrOrderNotReady for an order not in analysis, got %v", err)
+	}
+
+	if _, err := db.Exec(`UPDATE test_orders SET status = 'in_analysis' WHERE id = $1`, order.ID); err != nil {
+		t.Fatalf("Failed to start analysis: %v", err)
+	}
+	if err := results.Create(ctx, result); err != nil {
+		t.Fatalf("Create result failed: %v", err)
+	}
+	if stored, _ := orders.GetByID(ctx, order.ID); stored.Status != TestOrderResulted || stored.ResultedAt == nil {
+		t.Errorf("Expected the order to be resulted, got %s", stored.Status)
+	}
+
+	user := &User{Name: "Verifying Pathologist", Email: "verifier-" + order.AccessionNumber + "@example.com"}
+	if err := NewUserRepository(db).Create(ctx, user); err != nil {
+		t.Fatalf("Create user failed: %v", err)
+	}
+	if _, err := results.Verify(ctx, result.ID, user.ID); err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	stored, _ := orders.GetByID(ctx, order.ID)
+	if stored.Status != TestOrderVerified || stored.VerifiedAt == nil {
+		t.Errorf("Expected the order to be verified, got %s", stored.Status)
+	}
+	if stored.Status.CanTransitionTo(TestOrderCancelled) {
+		t.Error("Expected an order with a verified result not to be cancellable")
+	}
+}
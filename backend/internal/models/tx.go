@@ -0,0 +1,42 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+)
+
+// DBTX is the handle a repository runs its queries on: the *sql.DB, or a
+// *sql.Tx so its writes commit or roll back together with other work, such
+// as the audit entry recording them
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// InTx runs fn in a transaction, committing it if fn returns nil and
+// rolling it back otherwise. Repositories created on tx take part in it.
+func InTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// withTx runs fn in a transaction on db. A repository created on a
+// transaction joins it instead of starting its own, leaving the commit to
+// the caller that began it.
+func withTx(ctx context.Context, db DBTX, fn func(tx DBTX) error) error {
+	if db, ok := db.(*sql.DB); ok {
+		return InTx(ctx, db, func(tx *sql.Tx) error {
+			return fn(tx)
+		})
+	}
+	return fn(db)
+}
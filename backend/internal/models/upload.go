@@ -0,0 +1,207 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// UploadStatus is the state of a resumable upload
+type UploadStatus string
+
+const (
+	UploadUploading UploadStatus = "uploading"
+	UploadComplete  UploadStatus = "complete"
+	UploadFailed    UploadStatus = "failed"
+)
+
+// Upload is a file uploaded in chunks, such as a whole-slide image. Chunks
+// are staged as separate blobs (PartKeys) and joined into StorageKey once
+// the last byte arrives.
+type Upload struct {
+	ID              int          `json:"id"`
+	AccessionNumber string       `json:"accession_number"`
+	Filename        string       `json:"filename"`
+	ContentType     string       `json:"content_type"`
+	Size            int64        `json:"size"`
+	Offset          int64        `json:"offset"`
+	PartKeys        []string     `json:"-"`
+	ExpectedSHA256  string       `json:"expected_sha256,omitempty"`
+	SHA256          string       `json:"sha256,omitempty"`
+	StorageKey      string       `json:"-"`
+	Status          UploadStatus `json:"status"`
+	Error           string       `json:"error,omitempty"`
+	OwnerID         *int         `json:"owner_id"`
+	CreatedAt       time.Time    `json:"created_at"`
+	UpdatedAt       time.Time    `json:"updated_at"`
+	CompletedAt     *time.Time   `json:"completed_at"`
+}
+
+// UploadRepository handles database operations for uploads
+type UploadRepository struct {
+	db *sql.DB
+}
+
+// NewUploadRepository creates a new upload repository
+func NewUploadRepository(db *sql.DB) *UploadRepository {
+	return &UploadRepository{db: db}
+}
+
+// uploadColumns is the column list scanned by scanUpload
+const uploadColumns = `id, accession_number, filename, content_type, size, upload_offset, part_keys,
+	expected_sha256, sha256, storage_key, status, error, owner_id, created_at, updated_at, completed_at`
+
+// scanUpload scans a row selected with uploadColumns
+func scanUpload(row interface{ Scan(...interface{}) error }) (*Upload, error) {
+	var upload Upload
+	var ownerID sql.NullInt64
+	var completedAt sql.NullTime
+
+	err := row.Scan(&upload.ID, &upload.AccessionNumber, &upload.Filename, &upload.ContentType, &upload.Size,
+		&upload.Offset, pq.Array(&upload.PartKeys), &upload.ExpectedSHA256, &upload.SHA256, &upload.StorageKey,
+		&upload.Status, &upload.Error, &ownerID, &upload.CreatedAt, &upload.UpdatedAt, &completedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	upload.OwnerID = nullIntPtr(ownerID)
+	upload.CompletedAt = nullTimePtr(completedAt)
+
+	return &upload, nil
+}
+
+// GetByID retrieves an upload by ID
+func (r *UploadRepository) GetByID(ctx context.Context, id int) (*Upload, error) {
+	query := `SELECT ` + uploadColumns + ` FROM uploads WHERE id = $1`
+
+	upload, err := scanUpload(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return upload, nil
+}
+
+// GetByAccession retrieves the completed uploads tagged with an accession number
+func (r *UploadRepository) GetByAccession(ctx context.Context, accession string) ([]Upload, error) {
+	query := `SELECT ` + uploadColumns + ` FROM uploads
+			  WHERE accession_number = $1 AND status = 'complete' ORDER BY id`
+
+	rows, err := r.db.QueryContext(ctx, query, accession)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var uploads []Upload
+	for rows.Next() {
+		upload, err := scanUpload(rows)
+		if err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, *upload)
+	}
+
+	return uploads, rows.Err()
+}
+
+// GetViewableByAccession retrieves the completed uploads tagged with an
+// accession number that a user may view, as decided by
+// SlideGrantRepository.CanView
+func (r *UploadRepository) GetViewableByAccession(ctx context.Context, accession string, userID int) ([]Upload, error) {
+	query := `SELECT ` + uploadColumns + ` FROM uploads u
+			  WHERE accession_number = $1 AND status = 'complete' AND ` + viewableBy + `
+			  ORDER BY id`
+
+	rows, err := r.db.QueryContext(ctx, query, accession, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var uploads []Upload
+	for rows.Next() {
+		upload, err := scanUpload(rows)
+		if err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, *upload)
+	}
+
+	return uploads, rows.Err()
+}
+
+// Create starts a new upload
+func (r *UploadRepository) Create(ctx context.Context, upload *Upload) error {
+	query := `INSERT INTO uploads (accession_number, filename, content_type, size, expected_sha256, owner_id)
+			  VALUES ($1, $2, $3, $4, $5, $6)
+			  RETURNING ` + uploadColumns
+
+	created, err := scanUpload(r.db.QueryRowContext(ctx, query, upload.AccessionNumber, upload.Filename, upload.ContentType,
+		upload.Size, upload.ExpectedSHA256, upload.OwnerID))
+	if err != nil {
+		return err
+	}
+
+	*upload = *created
+	return nil
+}
+
+// AppendPart records a staged chunk and advances the offset. The update only
+// applies if the offset is still fromOffset, so concurrent chunks for the
+// same range cannot both be accepted; it returns sql.ErrNoRows otherwise.
+func (r *UploadRepository) AppendPart(ctx context.Context, id int, fromOffset, toOffset int64, partKey string) error {
+	query := `UPDATE uploads SET upload_offset = $1, part_keys = array_append(part_keys, $2),
+			  updated_at = CURRENT_TIMESTAMP
+			  WHERE id = $3 AND upload_offset = $4 AND status = 'uploading'`
+
+	return execAffectingOne(ctx, r.db, query, toOffset, partKey, id, fromOffset)
+}
+
+// Complete marks an upload as joined into its final blob
+func (r *UploadRepository) Complete(ctx context.Context, id int, storageKey, sha256 string) error {
+	query := `UPDATE uploads SET status = 'complete', storage_key = $1, sha256 = $2, part_keys = '{}',
+			  completed_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+			  WHERE id = $3 AND status = 'uploading'`
+
+	return execAffectingOne(ctx, r.db, query, storageKey, sha256, id)
+}
+
+// Fail marks an upload as failed, e.g. after a checksum mismatch
+func (r *UploadRepository) Fail(ctx context.Context, id int, message string) error {
+	query := `UPDATE uploads SET status = 'failed', error = $1, part_keys = '{}', updated_at = CURRENT_TIMESTAMP
+			  WHERE id = $2 AND status = 'uploading'`
+
+	return execAffectingOne(ctx, r.db, query, message, id)
+}
+
+// Delete deletes an upload that has not completed
+func (r *UploadRepository) Delete(ctx context.Context, id int) error {
+	query := `DELETE FROM uploads WHERE id = $1 AND status <> 'complete'`
+
+	return execAffectingOne(ctx, r.db, query, id)
+}
+
+// execAffectingOne runs an update and returns sql.ErrNoRows if no row matched
+func execAffectingOne(ctx context.Context, db DBTX, query string, args ...interface{}) error {
+	result, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestUploadRepository_GetViewableByAccession(t *testing.T) {
+	db := setupTestDB(t)
+	users := NewUserRepository(db)
+	uploads := NewUploadRepository(db)
+
+	suffix := time.Now().UnixNano()
+	accession := fmt.Sprintf("ACC-%d", suffix)
+	var owner, granted, other User
+	for i, user := range []*User{&owner, &granted, &other} {
+		user.Name = "Viewer"
+		user.Email = fmt.Sprintf("viewer-%d-%d@example.com", suffix, i)
+		if err := users.Create(context.Background(), user); err != nil {
+			t.Fatalf("Create user failed: %v", err)
+		}
+	}
+
+	var own, shared Upload
+	for _, upload := range []*Upload{&own, &shared} {
+		upload.AccessionNumber = accession
+		upload.Filename = "slide.svs"
+		upload.Size = 1
+		upload.OwnerID = &owner.ID
+		if err := uploads.Create(context.Background(), upload); err != nil {
+			t.Fatalf("Create upload failed: %v", err)
+		}
+		if err := uploads.Complete(context.Background(), upload.ID, fmt.Sprintf("slides/%d", upload.ID), ""); err != nil {
+			t.Fatalf("Complete failed: %v", err)
+		}
+	}
+	if err := NewSlideGrantRepository(db).Grant(context.Background(), shared.ID, granted.ID, &owner.ID); err != nil {
+		t.Fatalf("Grant failed: %v", err)
+	}
+
+	expected := map[int][]int{owner.ID: {own.ID, shared.ID}, granted.ID: {shared.ID}, other.ID: nil}
+	for userID, ids := range expected {
+		viewable, err := uploads.GetViewableByAccession(context.Background(), accession, userID)
+		if err != nil {
+			t.Fatalf("GetViewableByAccession failed: %v", err)
+		}
+		got := make([]int, len(viewable))
+		for i, upload := range viewable {
+			got[i] = upload.ID
+		}
+		if fmt.Sprint(got) != fmt.Sprint(ids) {
+			t.Errorf("User %d: expected uploads %v, got %v", userID, ids, got)
+		}
+	}
+}
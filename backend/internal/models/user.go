@@ -1,10 +1,22 @@
 package models
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"time"
+
+	"backend/internal/listing"
 )
 
+// ErrEmailExists is returned when another user already has the email address
+var ErrEmailExists = errors.New("email already exists")
+
+// ErrUserInUse is returned when deleting a user who is named on a test
+// result, which must keep its record of who created, reported and signed it
+var ErrUserInUse = errors.New("user is referenced by test results")
+
 // User represents a user in the system
 type User struct {
 	ID        int       `json:"id"`
@@ -12,25 +24,75 @@ type User struct {
 	Email     string    `json:"email"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	// Credential fields are only loaded by GetByEmail and never serialized
+	PasswordHash        string     `json:"-"`
+	FailedLoginAttempts int        `json:"-"`
+	LockedUntil         *time.Time `json:"-"`
 }
 
 // UserRepository handles database operations for users
 type UserRepository struct {
-	db *sql.DB
+	db DBTX
 }
 
 // NewUserRepository creates a new user repository
-func NewUserRepository(db *sql.DB) *UserRepository {
+func NewUserRepository(db DBTX) *UserRepository {
 	return &UserRepository{db: db}
 }
 
-// GetAll retrieves all users from the database
-func (r *UserRepository) GetAll() ([]User, error) {
-	query := `SELECT id, name, email, created_at, updated_at FROM users ORDER BY created_at DESC`
+// UserList is what GET /api/users can be sorted and filtered by
+var UserList = &listing.Spec{
+	Key: "id",
+	Sorts: map[string]listing.Field{
+		"id":         {Column: "id", Type: listing.Int},
+		"name":       {Column: "name", Type: listing.String},
+		"email":      {Column: "email", Type: listing.String},
+		"created_at": {Column: "created_at", Type: listing.Time},
+	},
+	DefaultSort: "-created_at",
+	Filters: map[string]listing.Filter{
+		"email":          {Column: "email", Type: listing.String, Op: listing.EqualFold},
+		"created_after":  {Column: "created_at", Type: listing.Time, Op: listing.After},
+		"created_before": {Column: "created_at", Type: listing.Time, Op: listing.Before},
+	},
+}
+
+// ListKey is the user's position in a list sorted by the named UserList field
+func (u User) ListKey(sort string) listing.Key {
+	key := listing.Key{ID: int64(u.ID)}
+	switch sort {
+	case "name":
+		key.Value = u.Name
+	case "email":
+		key.Value = u.Email
+	case "created_at":
+		key.Value = u.CreatedAt
+	default:
+		key.Value = u.ID
+	}
+	return key
+}
 
-	rows, err := r.db.Query(query)
+// List retrieves one page of users, fetching q.Fetch() rows so the caller
+// can tell whether there are more, and counts every user matching the
+// filters
+func (r *UserRepository) List(ctx context.Context, q *listing.Query) ([]User, int, error) {
+	where, args := q.Where(false)
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	where, args = q.Where(true)
+	args = append(args, q.Fetch())
+	query := fmt.Sprintf(`SELECT id, name, email, created_at, updated_at FROM users %s ORDER BY %s LIMIT $%d`,
+		where, q.OrderBy(), len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer rows.Close()
 
@@ -39,20 +101,20 @@ func (r *UserRepository) GetAll() ([]User, error) {
 		var user User
 		err := rows.Scan(&user.ID, &user.Name, &user.Email, &user.CreatedAt, &user.UpdatedAt)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 		users = append(users, user)
 	}
 
-	return users, rows.Err()
+	return users, total, rows.Err()
 }
 
 // GetByID retrieves a user by ID
-func (r *UserRepository) GetByID(id int) (*User, error) {
+func (r *UserRepository) GetByID(ctx context.Context, id int) (*User, error) {
 	query := `SELECT id, name, email, created_at, updated_at FROM users WHERE id = $1`
 
 	var user User
-	err := r.db.QueryRow(query, id).Scan(&user.ID, &user.Name, &user.Email, &user.CreatedAt, &user.UpdatedAt)
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&user.ID, &user.Name, &user.Email, &user.CreatedAt, &user.UpdatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -63,36 +125,92 @@ func (r *UserRepository) GetByID(id int) (*User, error) {
 	return &user, nil
 }
 
-// Create creates a new user
-func (r *UserRepository) Create(user *User) error {
-	query := `INSERT INTO users (name, email) VALUES ($1, $2) RETURNING id, created_at, updated_at`
+// Create creates a new user, storing PasswordHash if set. It returns
+// ErrEmailExists if the email is taken.
+func (r *UserRepository) Create(ctx context.Context, user *User) error {
+	query := `INSERT INTO users (name, email, password_hash) VALUES ($1, $2, NULLIF($3, '')) RETURNING id, created_at, updated_at`
 
-	err := r.db.QueryRow(query, user.Name, user.Email).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+	err := r.db.QueryRowContext(ctx, query, user.Name, user.Email, user.PasswordHash).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
 	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrEmailExists
+		}
 		return err
 	}
 
 	return nil
 }
 
-// Update updates an existing user
-func (r *UserRepository) Update(user *User) error {
+// Update updates an existing user. It returns ErrEmailExists if the new
+// email is taken.
+func (r *UserRepository) Update(ctx context.Context, user *User) error {
 	query := `UPDATE users SET name = $1, email = $2, updated_at = CURRENT_TIMESTAMP 
 			  WHERE id = $3 RETURNING updated_at`
 
-	err := r.db.QueryRow(query, user.Name, user.Email, user.ID).Scan(&user.UpdatedAt)
+	err := r.db.QueryRowContext(ctx, query, user.Name, user.Email, user.ID).Scan(&user.UpdatedAt)
 	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrEmailExists
+		}
 		return err
 	}
 
 	return nil
 }
 
-// Delete deletes a user by ID
-func (r *UserRepository) Delete(id int) error {
+// Delete deletes a user by ID. It returns ErrUserInUse if the user is named
+// on a test result.
+func (r *UserRepository) Delete(ctx context.Context, id int) error {
 	query := `DELETE FROM users WHERE id = $1`
 
-	result, err := r.db.Exec(query, id)
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return ErrUserInUse
+		}
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// GetByEmail retrieves a user by email, including credential and lockout fields
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*User, error) {
+	query := `SELECT id, name, email, created_at, updated_at, COALESCE(password_hash, ''), failed_login_attempts, locked_until
+			  FROM users WHERE LOWER(email) = LOWER($1)`
+
+	var user User
+	var lockedUntil sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, email).Scan(&user.ID, &user.Name, &user.Email, &user.CreatedAt, &user.UpdatedAt,
+		&user.PasswordHash, &user.FailedLoginAttempts, &lockedUntil)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if lockedUntil.Valid {
+		user.LockedUntil = &lockedUntil.Time
+	}
+
+	return &user, nil
+}
+
+// SetPassword stores a password hash for a user
+func (r *UserRepository) SetPassword(ctx context.Context, id int, passwordHash string) error {
+	query := `UPDATE users SET password_hash = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
+
+	result, err := r.db.ExecContext(ctx, query, passwordHash, id)
 	if err != nil {
 		return err
 	}
@@ -108,3 +226,31 @@ func (r *UserRepository) Delete(id int) error {
 
 	return nil
 }
+
+// IncrementFailedLogins records a failed login and returns the new failure count
+func (r *UserRepository) IncrementFailedLogins(ctx context.Context, id int) (int, error) {
+	query := `UPDATE users SET failed_login_attempts = failed_login_attempts + 1 WHERE id = $1 RETURNING failed_login_attempts`
+
+	var attempts int
+	if err := r.db.QueryRowContext(ctx, query, id).Scan(&attempts); err != nil {
+		return 0, err
+	}
+
+	return attempts, nil
+}
+
+// Lock prevents logins until the given time and resets the failure count
+func (r *UserRepository) Lock(ctx context.Context, id int, until time.Time) error {
+	query := `UPDATE users SET locked_until = $1, failed_login_attempts = 0 WHERE id = $2`
+
+	_, err := r.db.ExecContext(ctx, query, until, id)
+	return err
+}
+
+// ResetFailedLogins clears the failure count and any lockout after a successful login
+func (r *UserRepository) ResetFailedLogins(ctx context.Context, id int) error {
+	query := `UPDATE users SET failed_login_attempts = 0, locked_until = NULL WHERE id = $1`
+
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
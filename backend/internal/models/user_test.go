@@ -1,21 +1,44 @@
 package models
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"os"
 	"testing"
 	"time"
 
 	_ "github.com/lib/pq"
+
+	"backend/internal/database"
 )
 
-// setupTestDB creates a test database connection
-// In a real scenario, you'd use a test database or mock
+// setupTestDB connects to the database named by TEST_DATABASE_URL and
+// applies the migrations, skipping the test when it is not set. The
+// database should be a disposable one: tests leave their rows behind.
 func setupTestDB(t *testing.T) *sql.DB {
 	t.Helper()
 
-	// This would typically connect to a test database
-	// For this example, we'll skip actual DB tests
-	return nil
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	return db
 }
 
 func TestUser_Struct(t *testing.T) {
@@ -59,3 +82,41 @@ func TestUserRepository_Create_Mock(t *testing.T) {
 	// 3. Test the actual database operations
 	// 4. Clean up
 }
+
+func TestUserRepository_Delete_ResultSigner(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+	users := NewUserRepository(db)
+	results := NewTestResultRepository(db)
+
+	signer := &User{Name: "Signing Pathologist", Email: fmt.Sprintf("signer-%d@example.com", time.Now().UnixNano())}
+	if err := users.Create(ctx, signer); err != nil {
+		t.Fatalf("Create user failed: %v", err)
+	}
+	order := &TestOrder{PatientMRN: "MRN-SIGNER", TestType: "histology", Priority: TestOrderRoutine}
+	if err := NewTestOrderRepository(db).Create(ctx, order); err != nil {
+		t.Fatalf("Create order failed: %v", err)
+	}
+	if _, err := db.Exec(`UPDATE test_orders SET status = 'in_analysis' WHERE id = $1`, order.ID); err != nil {
+		t.Fatalf("Failed to start analysis: %v", err)
+	}
+	result := &TestResult{TestOrderID: order.ID, Interpretation: "Benign"}
+	if err := results.Create(ctx, result); err != nil {
+		t.Fatalf("Create result failed: %v", err)
+	}
+	if _, err := results.Verify(ctx, result.ID, signer.ID); err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+
+	if err := users.Delete(ctx, signer.ID); !errors.Is(err, ErrUserInUse) {
+		t.Fatalf("Expected ErrUserInUse, got %v", err)
+	}
+
+	verified, err := results.GetLatest(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("GetLatest failed: %v", err)
+	}
+	if verified.VerifiedBy == nil || *verified.VerifiedBy != signer.ID {
+		t.Errorf("Expected the result to keep its signer, got %v", verified.VerifiedBy)
+	}
+}
@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// pruneInterval is how often stores drop buckets that have refilled, which
+// behave the same as missing ones
+const pruneInterval = time.Minute
+
+// MemoryStore holds buckets in memory. Each replica has its own, so a
+// client spreading requests over n replicas gets up to n times the limit.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*memoryBucket
+	pruned  time.Time
+}
+
+// memoryBucket is a bucket and when it will have refilled
+type memoryBucket struct {
+	bucket
+	full time.Time
+}
+
+// NewMemoryStore creates an empty store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*memoryBucket)}
+}
+
+// Take implements Store
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.pruned) >= pruneInterval {
+		for k, b := range s.buckets {
+			if !now.Before(b.full) {
+				delete(s.buckets, k)
+			}
+		}
+		s.pruned = now
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &memoryBucket{bucket: bucket{tokens: float64(limit.Requests), updated: now}}
+		s.buckets[key] = b
+	}
+	result := b.take(limit, now)
+	b.full = b.bucket.full(limit)
+	return result, nil
+}
+
+// Peek implements Store
+func (s *MemoryStore) Peek(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.buckets[key]
+	if !ok {
+		return bucket{tokens: float64(limit.Requests), updated: now}.peek(limit, now), nil
+	}
+	return b.bucket.peek(limit, now), nil
+}
@@ -0,0 +1,87 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// PostgresStore holds buckets in the rate_limit_buckets table, shared by
+// every replica. Each Take locks its bucket's row in a transaction, so
+// concurrent requests from one client are counted exactly.
+type PostgresStore struct {
+	db *sql.DB
+
+	mu     sync.Mutex
+	pruned time.Time
+}
+
+// NewPostgresStore creates a store in db
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Take implements Store
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	now = now.UTC()
+	if err := s.prune(ctx, now); err != nil {
+		return Result{}, fmt.Errorf("failed to purge rate limit buckets: %w", err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Result{}, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO rate_limit_buckets (key, tokens, updated_at, full_at)
+		VALUES ($1, $2, $3, $3) ON CONFLICT (key) DO NOTHING`, key, float64(limit.Requests), now)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var b bucket
+	err = tx.QueryRowContext(ctx, `SELECT tokens, updated_at FROM rate_limit_buckets WHERE key = $1 FOR UPDATE`, key).
+		Scan(&b.tokens, &b.updated)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := b.take(limit, now)
+	_, err = tx.ExecContext(ctx, `UPDATE rate_limit_buckets SET tokens = $2, updated_at = $3, full_at = $4 WHERE key = $1`,
+		key, b.tokens, b.updated.UTC(), b.full(limit).UTC())
+	if err != nil {
+		return Result{}, err
+	}
+
+	return result, tx.Commit()
+}
+
+// Peek implements Store
+func (s *PostgresStore) Peek(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	now = now.UTC()
+	b := bucket{tokens: float64(limit.Requests), updated: now}
+	err := s.db.QueryRowContext(ctx, `SELECT tokens, updated_at FROM rate_limit_buckets WHERE key = $1`, key).
+		Scan(&b.tokens, &b.updated)
+	if err != nil && err != sql.ErrNoRows {
+		return Result{}, err
+	}
+	return b.peek(limit, now), nil
+}
+
+// prune opportunistically deletes refilled buckets, at most once per
+// pruneInterval on each replica
+func (s *PostgresStore) prune(ctx context.Context, now time.Time) error {
+	s.mu.Lock()
+	if now.Sub(s.pruned) < pruneInterval {
+		s.mu.Unlock()
+		return nil
+	}
+	s.pruned = now
+	s.mu.Unlock()
+
+	_, err := s.db.ExecContext(ctx, `DELETE FROM rate_limit_buckets WHERE full_at <= $1`, now)
+	return err
+}
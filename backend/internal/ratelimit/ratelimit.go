@@ -0,0 +1,195 @@
+// Package ratelimit throttles clients with token buckets. Each client has a
+// bucket per policy holding up to Limit.Requests tokens, refilled evenly
+// over Limit.Period; a request takes a token or is refused. Buckets live in
+// a Store, in memory for one replica or in Postgres so limits hold across
+// replicas.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Limit allows Requests per Period, in bursts of up to Requests. A zero
+// limit is unlimited.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// Unlimited reports whether the limit allows every request
+func (l Limit) Unlimited() bool {
+	return l.Requests <= 0 || l.Period <= 0
+}
+
+// rate is the tokens refilled per second
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// Result is the outcome of taking a token
+type Result struct {
+	Allowed bool
+
+	// Remaining is the number of whole tokens left
+	Remaining int
+
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+
+	// RetryAfter is how long until a token is available, when refused
+	RetryAfter time.Duration
+}
+
+// Store holds buckets by key
+type Store interface {
+	// Take refills the key's bucket for the time since it was last used
+	// and takes a token if one is available. A new bucket starts full.
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+
+	// Peek reports what Take would, without taking a token
+	Peek(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+// bucket is a token bucket's state
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// refill adds the tokens earned since the last update. A now before the
+// last update, from clock skew between replicas, refills nothing.
+func (b *bucket) refill(limit Limit, now time.Time) {
+	if elapsed := now.Sub(b.updated); elapsed > 0 {
+		b.tokens = math.Min(float64(limit.Requests), b.tokens+elapsed.Seconds()*limit.rate())
+		b.updated = now
+	}
+}
+
+// take refills b up to now and takes a token if one is available
+func (b *bucket) take(limit Limit, now time.Time) Result {
+	b.refill(limit, now)
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return b.result(limit, allowed)
+}
+
+// peek reports what take would, leaving b unchanged
+func (b bucket) peek(limit Limit, now time.Time) Result {
+	b.refill(limit, now)
+	return b.result(limit, b.tokens >= 1)
+}
+
+// result describes b after a request that was allowed or not
+func (b *bucket) result(limit Limit, allowed bool) Result {
+	rate := limit.rate()
+	result := Result{Allowed: allowed, Remaining: int(b.tokens), Reset: seconds((float64(limit.Requests) - b.tokens) / rate)}
+	if !allowed {
+		result.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+	return result
+}
+
+// full returns when the bucket will have refilled completely
+func (b *bucket) full(limit Limit) time.Time {
+	return b.updated.Add(seconds((float64(limit.Requests) - b.tokens) / limit.rate()))
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
+
+// Policy is a named limit. Clients have a separate bucket per policy, so a
+// strict policy on some routes does not use up the default one.
+type Policy struct {
+	Name  string
+	Limit Limit
+}
+
+// Policies picks the policy for each request
+type Policies struct {
+	defaultPolicy Policy
+	routes        *http.ServeMux
+	policies      map[string]Policy
+}
+
+// NewPolicies creates policies applying routes' policies, keyed by
+// http.ServeMux patterns such as "POST /api/auth/login" or "/api/uploads/",
+// and defaultPolicy elsewhere. The most specific pattern wins, as in
+// ServeMux; patterns must be valid and distinct.
+func NewPolicies(defaultPolicy Policy, routes map[string]Policy) *Policies {
+	policies := &Policies{defaultPolicy: defaultPolicy, routes: http.NewServeMux(), policies: routes}
+	for pattern := range routes {
+		policies.routes.Handle(pattern, http.NotFoundHandler())
+	}
+	return policies
+}
+
+// For returns the policy for a request
+func (p *Policies) For(r *http.Request) Policy {
+	if _, pattern := p.routes.Handler(r); pattern != "" {
+		return p.policies[pattern]
+	}
+	return p.defaultPolicy
+}
+
+// Limiter takes tokens from a store at the time its clock gives
+type Limiter struct {
+	store Store
+	now   func() time.Time
+}
+
+// NewLimiter creates a limiter over store. now is the clock, time.Now when
+// nil; tests pass a FakeClock's Now.
+func NewLimiter(store Store, now func() time.Time) *Limiter {
+	if now == nil {
+		now = time.Now
+	}
+	return &Limiter{store: store, now: now}
+}
+
+// Allow takes a token from the client's bucket for the policy
+func (l *Limiter) Allow(ctx context.Context, policy Policy, client string) (Result, error) {
+	if policy.Limit.Unlimited() {
+		return Result{Allowed: true}, nil
+	}
+	return l.store.Take(ctx, policy.Name+":"+client, policy.Limit, l.now())
+}
+
+// Check reports whether Allow would succeed, without taking a token
+func (l *Limiter) Check(ctx context.Context, policy Policy, client string) (Result, error) {
+	if policy.Limit.Unlimited() {
+		return Result{Allowed: true}, nil
+	}
+	return l.store.Peek(ctx, policy.Name+":"+client, policy.Limit, l.now())
+}
+
+// FakeClock is a clock for tests that only moves when told to
+type FakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFakeClock creates a clock stopped at now
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now returns the clock's time
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
@@ -0,0 +1,117 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLimiter_TokenBucket(t *testing.T) {
+	clock := NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	limiter := NewLimiter(NewMemoryStore(), clock.Now)
+	policy := Policy{Name: "login", Limit: Limit{Requests: 3, Period: time.Minute}}
+
+	for i := 2; i >= 0; i-- {
+		result, err := limiter.Allow(context.Background(), policy, "ip:203.0.113.9")
+		if err != nil {
+			t.Fatalf("Allow failed: %v", err)
+		}
+		if !result.Allowed || result.Remaining != i {
+			t.Errorf("Expected request to be allowed with %d remaining, got %+v", i, result)
+		}
+	}
+
+	result, _ := limiter.Allow(context.Background(), policy, "ip:203.0.113.9")
+	if result.Allowed || result.RetryAfter != 20*time.Second || result.Reset != time.Minute {
+		t.Errorf("Expected the fourth request to be refused for 20s, got %+v", result)
+	}
+
+	// Other clients and policies have their own buckets
+	if result, _ := limiter.Allow(context.Background(), policy, "ip:198.51.100.1"); !result.Allowed {
+		t.Error("Expected another client to be allowed")
+	}
+	if result, _ := limiter.Allow(context.Background(), Policy{Name: "default", Limit: policy.Limit}, "ip:203.0.113.9"); !result.Allowed {
+		t.Error("Expected another policy to be allowed")
+	}
+
+	// One token refills every 20 seconds
+	clock.Advance(20 * time.Second)
+	if result, _ := limiter.Allow(context.Background(), policy, "ip:203.0.113.9"); !result.Allowed || result.Remaining != 0 {
+		t.Errorf("Expected a refilled token to be allowed, got %+v", result)
+	}
+	if result, _ := limiter.Allow(context.Background(), policy, "ip:203.0.113.9"); result.Allowed {
+		t.Error("Expected only one token to have refilled")
+	}
+
+	// Refills stop at the burst size
+	clock.Advance(time.Hour)
+	if result, _ := limiter.Allow(context.Background(), policy, "ip:203.0.113.9"); result.Remaining != 2 {
+		t.Errorf("Expected the bucket to hold at most 3 tokens, got %+v", result)
+	}
+}
+
+func TestLimiter_Check(t *testing.T) {
+	clock := NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	limiter := NewLimiter(NewMemoryStore(), clock.Now)
+	policy := Policy{Name: "login", Limit: Limit{Requests: 1, Period: time.Minute}}
+
+	for i := 0; i < 3; i++ {
+		if result, _ := limiter.Check(context.Background(), policy, "ip:203.0.113.9"); !result.Allowed || result.Remaining != 1 {
+			t.Fatalf("Expected checking to leave the token, got %+v", result)
+		}
+	}
+
+	limiter.Allow(context.Background(), policy, "ip:203.0.113.9")
+	result, _ := limiter.Check(context.Background(), policy, "ip:203.0.113.9")
+	if result.Allowed || result.RetryAfter != time.Minute {
+		t.Errorf("Expected the empty bucket to be reported, got %+v", result)
+	}
+}
+
+func TestLimiter_Unlimited(t *testing.T) {
+	limiter := NewLimiter(NewMemoryStore(), nil)
+	for i := 0; i < 10; i++ {
+		if result, _ := limiter.Allow(context.Background(), Policy{Name: "off"}, "ip:203.0.113.9"); !result.Allowed {
+			t.Fatal("Expected a zero limit to allow every request")
+		}
+	}
+}
+
+func TestMemoryStore_Prunes(t *testing.T) {
+	clock := NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	store := NewMemoryStore()
+	limit := Limit{Requests: 10, Period: time.Minute}
+
+	store.Take(context.Background(), "a", limit, clock.Now())
+	clock.Advance(5 * time.Minute)
+	store.Take(context.Background(), "b", limit, clock.Now())
+
+	if _, ok := store.buckets["a"]; ok || len(store.buckets) != 1 {
+		t.Errorf("Expected the refilled bucket to be dropped, got %d buckets", len(store.buckets))
+	}
+}
+
+func TestPolicies_For(t *testing.T) {
+	login := Policy{Name: "login"}
+	uploads := Policy{Name: "uploads"}
+	policies := NewPolicies(Policy{Name: "default"}, map[string]Policy{
+		"POST /api/auth/login": login,
+		"/api/uploads/":        uploads,
+	})
+
+	tests := []struct {
+		method, path, expected string
+	}{
+		{http.MethodPost, "/api/auth/login", "login"},
+		{http.MethodGet, "/api/auth/login", "default"},
+		{http.MethodPatch, "/api/uploads/7", "uploads"},
+		{http.MethodGet, "/api/users", "default"},
+	}
+	for _, tt := range tests {
+		if got := policies.For(httptest.NewRequest(tt.method, tt.path, nil)).Name; got != tt.expected {
+			t.Errorf("%s %s: expected policy %s, got %s", tt.method, tt.path, tt.expected, got)
+		}
+	}
+}
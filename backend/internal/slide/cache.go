@@ -0,0 +1,258 @@
+package slide
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"backend/internal/storage"
+)
+
+// lru is a least-recently-used set of values bounded by their total size.
+// It is not safe for concurrent use.
+type lru[V any] struct {
+	budget  int64
+	used    int64
+	order   *list.List
+	items   map[string]*list.Element
+	onEvict func(key string, value V)
+}
+
+type lruEntry[V any] struct {
+	key   string
+	value V
+	size  int64
+}
+
+func newLRU[V any](budget int64, onEvict func(string, V)) *lru[V] {
+	return &lru[V]{budget: budget, order: list.New(), items: make(map[string]*list.Element), onEvict: onEvict}
+}
+
+// get returns a value and marks it recently used
+func (c *lru[V]) get(key string) (V, bool) {
+	if elem, ok := c.items[key]; ok {
+		c.order.MoveToFront(elem)
+		return elem.Value.(*lruEntry[V]).value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// add stores a value, evicting the least recently used values over budget.
+// Values larger than the whole budget are not stored.
+func (c *lru[V]) add(key string, value V, size int64) {
+	if size > c.budget {
+		return
+	}
+
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(*lruEntry[V])
+		c.used += size - entry.size
+		entry.value, entry.size = value, size
+		c.order.MoveToFront(elem)
+	} else {
+		c.items[key] = c.order.PushFront(&lruEntry[V]{key: key, value: value, size: size})
+		c.used += size
+	}
+
+	for c.used > c.budget {
+		oldest := c.order.Back()
+		entry := oldest.Value.(*lruEntry[V])
+		c.order.Remove(oldest)
+		delete(c.items, entry.key)
+		c.used -= entry.size
+		if c.onEvict != nil {
+			c.onEvict(entry.key, entry.value)
+		}
+	}
+}
+
+// Cache keeps rendered tiles in memory and, optionally, on local disk, each
+// bounded in bytes with least-recently-used eviction. Keys should identify
+// immutable content. A Cache is safe for concurrent use.
+type Cache struct {
+	mu     sync.Mutex
+	memory *lru[[]byte]
+	disk   *lru[struct{}]
+	dir    string
+}
+
+// NewCache creates a cache holding up to memoryBytes in memory and diskBytes
+// under dir. An empty dir or zero diskBytes disables the disk tier. Files
+// left in dir by a previous run are reused.
+func NewCache(memoryBytes int64, dir string, diskBytes int64) (*Cache, error) {
+	c := &Cache{memory: newLRU[[]byte](memoryBytes, nil)}
+	if dir == "" || diskBytes <= 0 {
+		return c, nil
+	}
+
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create tile cache directory: %w", err)
+	}
+	c.dir = dir
+	c.disk = newLRU[struct{}](diskBytes, func(name string, _ struct{}) {
+		if err := os.Remove(c.path(name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("Failed to evict cached tile", "tile", name, "error", err)
+		}
+	})
+
+	// Index existing files, oldest first so they are evicted first
+	type cached struct {
+		name string
+		size int64
+		mod  int64
+	}
+	var files []cached
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if filepath.Base(filepath.Dir(path)) == "tmp" {
+			return os.Remove(path)
+		}
+		files = append(files, cached{name: d.Name(), size: info.Size(), mod: info.ModTime().UnixNano()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to index tile cache: %w", err)
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].mod < files[j].mod })
+	for _, f := range files {
+		c.disk.add(f.name, struct{}{}, f.size)
+	}
+
+	return c, nil
+}
+
+// Get returns a cached tile
+func (c *Cache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	data, ok := c.memory.get(key)
+	if ok || c.disk == nil {
+		c.mu.Unlock()
+		return data, ok
+	}
+
+	name := diskName(key)
+	_, ok = c.disk.get(name)
+	c.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(c.path(name))
+	if err != nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	c.memory.add(key, data, int64(len(data)))
+	c.mu.Unlock()
+	return data, true
+}
+
+// Put stores a tile
+func (c *Cache) Put(key string, data []byte) {
+	c.mu.Lock()
+	c.memory.add(key, data, int64(len(data)))
+	c.mu.Unlock()
+
+	if c.disk == nil {
+		return
+	}
+
+	name := diskName(key)
+	if err := c.writeFile(name, data); err != nil {
+		slog.Warn("Failed to write cached tile", "error", err)
+		return
+	}
+
+	c.mu.Lock()
+	c.disk.add(name, struct{}{}, int64(len(data)))
+	c.mu.Unlock()
+}
+
+// writeFile writes a cache file atomically
+func (c *Cache) writeFile(name string, data []byte) error {
+	path := c.path(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+
+	tmpDir := filepath.Join(c.dir, "tmp")
+	if err := os.MkdirAll(tmpDir, 0o750); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(tmpDir, "tile-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// path returns the file holding a cached tile, spread over subdirectories
+func (c *Cache) path(name string) string {
+	return filepath.Join(c.dir, name[:2], name)
+}
+
+// diskName hashes a cache key into a file name
+func diskName(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Library opens slides from a blob store and keeps recently used ones open,
+// so their directory structure is read once. It is safe for concurrent use.
+type Library struct {
+	blobs storage.BlobStore
+	mu    sync.Mutex
+	open  *lru[*Slide]
+}
+
+// NewLibrary creates a library keeping up to size slides open
+func NewLibrary(blobs storage.BlobStore, size int) *Library {
+	return &Library{blobs: blobs, open: newLRU[*Slide](int64(size), nil)}
+}
+
+// Open returns the slide stored under key
+func (l *Library) Open(ctx context.Context, key string) (*Slide, error) {
+	l.mu.Lock()
+	s, ok := l.open.get(key)
+	l.mu.Unlock()
+	if ok {
+		return s, nil
+	}
+
+	s, err := Open(ctx, BlobSource(l.blobs, key))
+	if err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	l.open.add(key, s, 1)
+	l.mu.Unlock()
+	return s, nil
+}
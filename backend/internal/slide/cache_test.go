@@ -0,0 +1,123 @@
+package slide
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"backend/internal/storage"
+)
+
+func TestLRU_EvictsLeastRecentlyUsed(t *testing.T) {
+	var evicted []string
+	c := newLRU[int](3, func(key string, _ int) { evicted = append(evicted, key) })
+
+	c.add("a", 1, 1)
+	c.add("b", 2, 1)
+	c.add("c", 3, 1)
+	c.get("a")
+	c.add("d", 4, 1)
+
+	if _, ok := c.get("b"); ok {
+		t.Error("Expected b to be evicted")
+	}
+	if _, ok := c.get("a"); !ok {
+		t.Error("Expected recently used a to be kept")
+	}
+	if len(evicted) != 1 || evicted[0] != "b" {
+		t.Errorf("Expected only b to be evicted, got %v", evicted)
+	}
+
+	// Values over budget are not stored
+	c.add("huge", 5, 4)
+	if _, ok := c.get("huge"); ok {
+		t.Error("Expected oversized value to be skipped")
+	}
+}
+
+func TestCache_Memory(t *testing.T) {
+	c, err := NewCache(10, "", 0)
+	if err != nil {
+		t.Fatalf("NewCache failed: %v", err)
+	}
+
+	c.Put("a", []byte("12345"))
+	c.Put("b", []byte("12345"))
+	c.Put("c", []byte("12345"))
+
+	if _, ok := c.Get("a"); ok {
+		t.Error("Expected a to be evicted from memory")
+	}
+	if data, ok := c.Get("c"); !ok || string(data) != "12345" {
+		t.Errorf("Expected c to be cached, got %q %v", data, ok)
+	}
+}
+
+func TestCache_DiskSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+
+	c, err := NewCache(1, dir, 1<<20)
+	if err != nil {
+		t.Fatalf("NewCache failed: %v", err)
+	}
+	c.Put("tile", []byte("tile bytes"))
+
+	reopened, err := NewCache(1<<20, dir, 1<<20)
+	if err != nil {
+		t.Fatalf("NewCache failed: %v", err)
+	}
+	if data, ok := reopened.Get("tile"); !ok || !bytes.Equal(data, []byte("tile bytes")) {
+		t.Errorf("Expected tile from disk, got %q %v", data, ok)
+	}
+}
+
+func TestCache_DiskEviction(t *testing.T) {
+	dir := t.TempDir()
+
+	c, err := NewCache(1, dir, 10)
+	if err != nil {
+		t.Fatalf("NewCache failed: %v", err)
+	}
+	c.Put("a", []byte("123456"))
+	c.Put("b", []byte("123456"))
+
+	if _, err := os.Stat(c.path(diskName("a"))); !os.IsNotExist(err) {
+		t.Errorf("Expected evicted file to be removed, got %v", err)
+	}
+	if _, ok := c.Get("b"); !ok {
+		t.Error("Expected b to remain on disk")
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*", "*"))
+	if len(files) != 1 {
+		t.Errorf("Expected one cached file, got %v", files)
+	}
+}
+
+func TestLibrary_OpensFromBlobStore(t *testing.T) {
+	blobs, err := storage.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStore failed: %v", err)
+	}
+
+	data := buildTIFF(t, false, []testLevel{{width: 64, height: 64, tile: 32, pixel: gradient}})
+	if err := blobs.Put(context.Background(), "uploads/1/content", bytes.NewReader(data)); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	library := NewLibrary(blobs, 4)
+	s, err := library.Open(context.Background(), "uploads/1/content")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if s.Width() != 64 {
+		t.Errorf("Expected width 64, got %d", s.Width())
+	}
+
+	again, _ := library.Open(context.Background(), "uploads/1/content")
+	if again != s {
+		t.Error("Expected the open slide to be reused")
+	}
+}
@@ -0,0 +1,65 @@
+package slide
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math/bits"
+)
+
+// Deep Zoom tile layout. 254 pixels plus one pixel of overlap on each side
+// keeps most tiles at 256x256, the size viewers such as OpenSeadragon expect.
+const (
+	DZITileSize = 254
+	DZIOverlap  = 1
+)
+
+// ErrTileOutOfRange is returned for tile coordinates outside the pyramid
+var ErrTileOutOfRange = errors.New("tile out of range")
+
+// DZIMaxLevel returns the Deep Zoom level holding the full-resolution image.
+// Level 0 is a single pixel and each level doubles the one before.
+func DZIMaxLevel(width, height int) int {
+	return bits.Len(uint(max(width, height) - 1))
+}
+
+// DZIDescriptor returns the XML .dzi descriptor for an image
+func DZIDescriptor(width, height int) string {
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<Image xmlns="http://schemas.microsoft.com/deepzoom/2008" Format="jpeg" Overlap="%d" TileSize="%d">
+  <Size Width="%d" Height="%d"/>
+</Image>
+`, DZIOverlap, DZITileSize, width, height)
+}
+
+// DZITile returns the full-resolution region covered by a Deep Zoom tile
+// and the tile's size in pixels
+func DZITile(width, height, level, col, row int) (image.Rectangle, image.Point, error) {
+	maxLevel := DZIMaxLevel(width, height)
+	if level < 0 || level > maxLevel || col < 0 || row < 0 {
+		return image.Rectangle{}, image.Point{}, ErrTileOutOfRange
+	}
+
+	shift := uint(maxLevel - level)
+	scale := 1 << shift
+	levelWidth := (width + scale - 1) / scale
+	levelHeight := (height + scale - 1) / scale
+
+	x0, y0 := col*DZITileSize, row*DZITileSize
+	if x0 >= levelWidth || y0 >= levelHeight {
+		return image.Rectangle{}, image.Point{}, ErrTileOutOfRange
+	}
+
+	// Interior edges overlap their neighbours
+	if col > 0 {
+		x0 -= DZIOverlap
+	}
+	if row > 0 {
+		y0 -= DZIOverlap
+	}
+	x1 := min((col+1)*DZITileSize+DZIOverlap, levelWidth)
+	y1 := min((row+1)*DZITileSize+DZIOverlap, levelHeight)
+
+	region := image.Rect(x0*scale, y0*scale, min(x1*scale, width), min(y1*scale, height))
+	return region, image.Pt(x1-x0, y1-y0), nil
+}
@@ -0,0 +1,72 @@
+package slide
+
+import (
+	"errors"
+	"image"
+	"strings"
+	"testing"
+)
+
+func TestDZIMaxLevel(t *testing.T) {
+	tests := []struct {
+		width, height, want int
+	}{
+		{1, 1, 0},
+		{2, 1, 1},
+		{256, 256, 8},
+		{257, 100, 9},
+		{100000, 80000, 17},
+	}
+
+	for _, tt := range tests {
+		if got := DZIMaxLevel(tt.width, tt.height); got != tt.want {
+			t.Errorf("DZIMaxLevel(%d, %d) = %d, want %d", tt.width, tt.height, got, tt.want)
+		}
+	}
+}
+
+func TestDZITile(t *testing.T) {
+	// 1000x600 has 10 levels; level 10 is full resolution
+	tests := []struct {
+		name            string
+		level, col, row int
+		wantRegion      image.Rectangle
+		wantSize        image.Point
+	}{
+		{"first tile", 10, 0, 0, image.Rect(0, 0, 255, 255), image.Pt(255, 255)},
+		{"interior tile", 10, 1, 1, image.Rect(253, 253, 509, 509), image.Pt(256, 256)},
+		{"edge tile", 10, 3, 2, image.Rect(761, 507, 1000, 600), image.Pt(239, 93)},
+		{"half resolution", 9, 0, 0, image.Rect(0, 0, 510, 510), image.Pt(255, 255)},
+		{"single pixel", 0, 0, 0, image.Rect(0, 0, 1000, 600), image.Pt(1, 1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			region, size, err := DZITile(1000, 600, tt.level, tt.col, tt.row)
+			if err != nil {
+				t.Fatalf("DZITile failed: %v", err)
+			}
+			if region != tt.wantRegion || size != tt.wantSize {
+				t.Errorf("Got region %v size %v, want %v %v", region, size, tt.wantRegion, tt.wantSize)
+			}
+		})
+	}
+}
+
+func TestDZITile_OutOfRange(t *testing.T) {
+	for _, tile := range [][3]int{{11, 0, 0}, {10, 4, 0}, {10, 0, 3}, {-1, 0, 0}, {10, -1, 0}} {
+		if _, _, err := DZITile(1000, 600, tile[0], tile[1], tile[2]); !errors.Is(err, ErrTileOutOfRange) {
+			t.Errorf("DZITile%v: expected ErrTileOutOfRange, got %v", tile, err)
+		}
+	}
+}
+
+func TestDZIDescriptor(t *testing.T) {
+	xml := DZIDescriptor(1000, 600)
+
+	for _, want := range []string{`TileSize="254"`, `Overlap="1"`, `Width="1000"`, `Height="600"`} {
+		if !strings.Contains(xml, want) {
+			t.Errorf("Expected descriptor to contain %s:\n%s", want, xml)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package slide
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// IIIF Image API 3.0 limits advertised in info.json
+const (
+	IIIFTileSize  = 256
+	IIIFMaxWidth  = 4096
+	IIIFMaxHeight = 4096
+)
+
+// ErrInvalidIIIF is returned for IIIF requests that are malformed or
+// cannot be satisfied
+var ErrInvalidIIIF = errors.New("invalid IIIF request")
+
+// IIIFRequest is a parsed IIIF image request, resolved against an image's
+// dimensions
+type IIIFRequest struct {
+	Region  image.Rectangle
+	Size    image.Point
+	Quality string
+	Format  string
+}
+
+// CacheKey returns a canonical name for the rendered image
+func (r IIIFRequest) CacheKey() string {
+	return fmt.Sprintf("iiif/%d,%d,%d,%d/%d,%d/%s.%s", r.Region.Min.X, r.Region.Min.Y, r.Region.Dx(), r.Region.Dy(),
+		r.Size.X, r.Size.Y, r.Quality, r.Format)
+}
+
+// ParseIIIFRequest parses the {region}/{size}/{rotation}/{quality}.{format}
+// path segments of an image request. Only rotation 0 is supported.
+func ParseIIIFRequest(width, height int, region, size, rotation, qualityFormat string) (IIIFRequest, error) {
+	var req IIIFRequest
+	var err error
+
+	if req.Region, err = parseIIIFRegion(width, height, region); err != nil {
+		return req, err
+	}
+	if req.Size, err = parseIIIFSize(req.Region, size); err != nil {
+		return req, err
+	}
+
+	if rotation != "0" {
+		return req, fmt.Errorf("%w: rotation %q is not supported", ErrInvalidIIIF, rotation)
+	}
+
+	quality, format, ok := strings.Cut(qualityFormat, ".")
+	if !ok {
+		return req, fmt.Errorf("%w: missing format", ErrInvalidIIIF)
+	}
+	switch quality {
+	case "default", "color":
+		req.Quality = "default"
+	case "gray":
+		req.Quality = quality
+	default:
+		return req, fmt.Errorf("%w: quality %q is not supported", ErrInvalidIIIF, quality)
+	}
+	switch format {
+	case "jpg", "png":
+		req.Format = format
+	default:
+		return req, fmt.Errorf("%w: format %q is not supported", ErrInvalidIIIF, format)
+	}
+
+	return req, nil
+}
+
+// parseIIIFRegion resolves full, square, x,y,w,h and pct:x,y,w,h regions
+func parseIIIFRegion(width, height int, region string) (image.Rectangle, error) {
+	bounds := image.Rect(0, 0, width, height)
+
+	var rect image.Rectangle
+	switch {
+	case region == "full":
+		return bounds, nil
+
+	case region == "square":
+		side := min(width, height)
+		x, y := (width-side)/2, (height-side)/2
+		return image.Rect(x, y, x+side, y+side), nil
+
+	case strings.HasPrefix(region, "pct:"):
+		values, err := parseFloats(strings.TrimPrefix(region, "pct:"), 4)
+		if err != nil {
+			return rect, fmt.Errorf("%w: region %q", ErrInvalidIIIF, region)
+		}
+		x := int(values[0] * float64(width) / 100)
+		y := int(values[1] * float64(height) / 100)
+		w := int(math.Round(values[2] * float64(width) / 100))
+		h := int(math.Round(values[3] * float64(height) / 100))
+		rect = image.Rect(x, y, x+w, y+h)
+
+	default:
+		values, err := parseInts(region, 4)
+		if err != nil {
+			return rect, fmt.Errorf("%w: region %q", ErrInvalidIIIF, region)
+		}
+		rect = image.Rect(values[0], values[1], values[0]+values[2], values[1]+values[3])
+	}
+
+	rect = rect.Intersect(bounds)
+	if rect.Empty() {
+		return rect, fmt.Errorf("%w: region %q is outside the image", ErrInvalidIIIF, region)
+	}
+	return rect, nil
+}
+
+// parseIIIFSize resolves max, w,, ,h, pct:n, w,h and !w,h sizes. A leading
+// ^ allows upscaling; otherwise sizes larger than the region are rejected.
+func parseIIIFSize(region image.Rectangle, size string) (image.Point, error) {
+	invalid := fmt.Errorf("%w: size %q", ErrInvalidIIIF, size)
+
+	upscale := strings.HasPrefix(size, "^")
+	size = strings.TrimPrefix(size, "^")
+	rw, rh := float64(region.Dx()), float64(region.Dy())
+
+	var w, h int
+	switch {
+	case size == "max":
+		scale := min(1, IIIFMaxWidth/rw, IIIFMaxHeight/rh)
+		if upscale {
+			scale = min(IIIFMaxWidth/rw, IIIFMaxHeight/rh)
+		}
+		w, h = int(math.Round(rw*scale)), int(math.Round(rh*scale))
+
+	case strings.HasPrefix(size, "pct:"):
+		pct, err := strconv.ParseFloat(strings.TrimPrefix(size, "pct:"), 64)
+		if err != nil || pct <= 0 {
+			return image.Point{}, invalid
+		}
+		w, h = int(math.Round(rw*pct/100)), int(math.Round(rh*pct/100))
+
+	case strings.HasPrefix(size, "!"):
+		values, err := parseInts(strings.TrimPrefix(size, "!"), 2)
+		if err != nil {
+			return image.Point{}, invalid
+		}
+		scale := min(float64(values[0])/rw, float64(values[1])/rh)
+		w, h = int(math.Round(rw*scale)), int(math.Round(rh*scale))
+
+	default:
+		ws, hs, ok := strings.Cut(size, ",")
+		if !ok || (ws == "" && hs == "") {
+			return image.Point{}, invalid
+		}
+		var err error
+		if ws != "" {
+			if w, err = strconv.Atoi(ws); err != nil || w < 0 {
+				return image.Point{}, invalid
+			}
+		}
+		if hs != "" {
+			if h, err = strconv.Atoi(hs); err != nil || h < 0 {
+				return image.Point{}, invalid
+			}
+		}
+		// Keep the aspect ratio when one dimension is omitted
+		if ws == "" {
+			w = int(math.Round(rw * float64(h) / rh))
+		}
+		if hs == "" {
+			h = int(math.Round(rh * float64(w) / rw))
+		}
+	}
+
+	w, h = max(w, 1), max(h, 1)
+	if !upscale && (w > region.Dx() || h > region.Dy()) {
+		return image.Point{}, fmt.Errorf("%w: size %q is larger than the region; use ^ to upscale", ErrInvalidIIIF, size)
+	}
+	if w > IIIFMaxWidth || h > IIIFMaxHeight {
+		return image.Point{}, fmt.Errorf("%w: size %q exceeds %dx%d", ErrInvalidIIIF, size, IIIFMaxWidth, IIIFMaxHeight)
+	}
+
+	return image.Pt(w, h), nil
+}
+
+// IIIFInfo returns the info.json document for an image served at id
+func IIIFInfo(id string, width, height int) map[string]interface{} {
+	// Halve the image until it fits in a single tile
+	var scaleFactors []int
+	for f := 1; ; f *= 2 {
+		scaleFactors = append(scaleFactors, f)
+		if (width+f-1)/f <= IIIFTileSize && (height+f-1)/f <= IIIFTileSize {
+			break
+		}
+	}
+
+	return map[string]interface{}{
+		"@context":  "http://iiif.io/api/image/3/context.json",
+		"id":        id,
+		"type":      "ImageService3",
+		"protocol":  "http://iiif.io/api/image",
+		"profile":   "level1",
+		"width":     width,
+		"height":    height,
+		"maxWidth":  IIIFMaxWidth,
+		"maxHeight": IIIFMaxHeight,
+		"tiles": []map[string]interface{}{
+			{"width": IIIFTileSize, "scaleFactors": scaleFactors},
+		},
+		"extraQualities": []string{"color", "gray"},
+		"extraFormats":   []string{"png"},
+		"extraFeatures":  []string{"regionByPct", "sizeByPct", "sizeByConfinedWh", "sizeUpscaling"},
+	}
+}
+
+// parseInts parses n comma-separated non-negative integers
+func parseInts(s string, n int) ([]int, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != n {
+		return nil, errors.New("wrong number of values")
+	}
+	values := make([]int, n)
+	for i, part := range parts {
+		v, err := strconv.Atoi(part)
+		if err != nil || v < 0 {
+			return nil, errors.New("invalid value")
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// parseFloats parses n comma-separated non-negative numbers
+func parseFloats(s string, n int) ([]float64, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != n {
+		return nil, errors.New("wrong number of values")
+	}
+	values := make([]float64, n)
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil || v < 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+			return nil, errors.New("invalid value")
+		}
+		values[i] = v
+	}
+	return values, nil
+}
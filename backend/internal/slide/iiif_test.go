@@ -0,0 +1,98 @@
+package slide
+
+import (
+	"errors"
+	"image"
+	"testing"
+)
+
+func TestParseIIIFRequest(t *testing.T) {
+	tests := []struct {
+		name, region, size, quality string
+		wantRegion                  image.Rectangle
+		wantSize                    image.Point
+	}{
+		{"full max", "full", "max", "default.jpg", image.Rect(0, 0, 2000, 1000), image.Pt(2000, 1000)},
+		{"square", "square", "max", "default.jpg", image.Rect(500, 0, 1500, 1000), image.Pt(1000, 1000)},
+		{"pixel region", "100,200,300,400", "max", "default.jpg", image.Rect(100, 200, 400, 600), image.Pt(300, 400)},
+		{"clipped region", "1900,900,500,500", "max", "default.jpg", image.Rect(1900, 900, 2000, 1000), image.Pt(100, 100)},
+		{"percent region", "pct:50,50,25,50", "max", "default.jpg", image.Rect(1000, 500, 1500, 1000), image.Pt(500, 500)},
+		{"width only", "full", "500,", "default.jpg", image.Rect(0, 0, 2000, 1000), image.Pt(500, 250)},
+		{"height only", "full", ",100", "default.jpg", image.Rect(0, 0, 2000, 1000), image.Pt(200, 100)},
+		{"exact size", "full", "300,300", "default.jpg", image.Rect(0, 0, 2000, 1000), image.Pt(300, 300)},
+		{"confined size", "full", "!400,400", "default.jpg", image.Rect(0, 0, 2000, 1000), image.Pt(400, 200)},
+		{"percent size", "full", "pct:10", "default.jpg", image.Rect(0, 0, 2000, 1000), image.Pt(200, 100)},
+		{"upscaled", "0,0,10,10", "^20,", "default.jpg", image.Rect(0, 0, 10, 10), image.Pt(20, 20)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := ParseIIIFRequest(2000, 1000, tt.region, tt.size, "0", tt.quality)
+			if err != nil {
+				t.Fatalf("ParseIIIFRequest failed: %v", err)
+			}
+			if req.Region != tt.wantRegion || req.Size != tt.wantSize {
+				t.Errorf("Got region %v size %v, want %v %v", req.Region, req.Size, tt.wantRegion, tt.wantSize)
+			}
+		})
+	}
+}
+
+func TestParseIIIFRequest_MaxIsLimited(t *testing.T) {
+	req, err := ParseIIIFRequest(100000, 50000, "full", "max", "0", "default.jpg")
+	if err != nil {
+		t.Fatalf("ParseIIIFRequest failed: %v", err)
+	}
+	if req.Size != image.Pt(IIIFMaxWidth, IIIFMaxWidth/2) {
+		t.Errorf("Expected max size to fit %dx%d, got %v", IIIFMaxWidth, IIIFMaxHeight, req.Size)
+	}
+}
+
+func TestParseIIIFRequest_Invalid(t *testing.T) {
+	tests := []struct {
+		name, region, size, rotation, quality string
+	}{
+		{"bad region", "1,2,3", "max", "0", "default.jpg"},
+		{"region outside image", "5000,0,10,10", "max", "0", "default.jpg"},
+		{"bad size", "full", "abc", "0", "default.jpg"},
+		{"upscale without caret", "0,0,10,10", "20,20", "0", "default.jpg"},
+		{"too large", "full", "^5000,", "0", "default.jpg"},
+		{"negative size", "full", "-5,", "0", "default.jpg"},
+		{"rotation", "full", "max", "90", "default.jpg"},
+		{"bitonal", "full", "max", "0", "bitonal.jpg"},
+		{"gif", "full", "max", "0", "default.gif"},
+		{"no format", "full", "max", "0", "default"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseIIIFRequest(2000, 1000, tt.region, tt.size, tt.rotation, tt.quality)
+			if !errors.Is(err, ErrInvalidIIIF) {
+				t.Errorf("Expected ErrInvalidIIIF, got %v", err)
+			}
+		})
+	}
+}
+
+func TestIIIFRequest_CacheKeyIsCanonical(t *testing.T) {
+	a, _ := ParseIIIFRequest(2000, 1000, "full", "max", "0", "default.jpg")
+	b, _ := ParseIIIFRequest(2000, 1000, "0,0,2000,1000", "2000,1000", "0", "color.jpg")
+
+	if a.CacheKey() != b.CacheKey() {
+		t.Errorf("Expected equivalent requests to share a cache key: %s vs %s", a.CacheKey(), b.CacheKey())
+	}
+}
+
+func TestIIIFInfo(t *testing.T) {
+	info := IIIFInfo("https://example.org/api/slides/1/iiif", 1000, 600)
+
+	if info["id"] != "https://example.org/api/slides/1/iiif" || info["width"] != 1000 || info["height"] != 600 {
+		t.Errorf("Unexpected info: %v", info)
+	}
+
+	tiles := info["tiles"].([]map[string]interface{})
+	factors := tiles[0]["scaleFactors"].([]int)
+	if want := []int{1, 2, 4}; len(factors) != len(want) || factors[2] != 4 {
+		t.Errorf("Expected scale factors %v, got %v", want, factors)
+	}
+}
@@ -0,0 +1,231 @@
+package slide
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// maxRenderPixels bounds the source pixels composed for one region
+const maxRenderPixels = 64 << 20
+
+// background fills areas outside the scanned image
+var background = image.NewUniform(color.White)
+
+// Render returns the region of the full-resolution image given by rect,
+// scaled to width x height. It reads from the smallest pyramid level that
+// still has enough detail.
+func (s *Slide) Render(ctx context.Context, rect image.Rectangle, width, height int) (*image.RGBA, error) {
+	rect = rect.Intersect(image.Rect(0, 0, s.Width(), s.Height()))
+	if rect.Empty() || width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("empty region %v", rect)
+	}
+
+	level := s.levelFor(float64(rect.Dx())/float64(width), float64(rect.Dy())/float64(height))
+	scaleX := float64(s.Width()) / float64(level.Width)
+	scaleY := float64(s.Height()) / float64(level.Height)
+
+	// The region in the chosen level's pixels
+	src := image.Rect(
+		int(float64(rect.Min.X)/scaleX),
+		int(float64(rect.Min.Y)/scaleY),
+		ceilDiv(float64(rect.Max.X), scaleX),
+		ceilDiv(float64(rect.Max.Y), scaleY),
+	).Intersect(image.Rect(0, 0, level.Width, level.Height))
+	if src.Empty() {
+		src = image.Rect(0, 0, 1, 1).Add(src.Min)
+	}
+	if src.Dx()*src.Dy() > maxRenderPixels {
+		return nil, fmt.Errorf("region %v is too large to render", rect)
+	}
+
+	canvas := image.NewRGBA(image.Rect(0, 0, src.Dx(), src.Dy()))
+	draw.Draw(canvas, canvas.Bounds(), background, image.Point{}, draw.Src)
+
+	for row := src.Min.Y / level.TileHeight; row*level.TileHeight < src.Max.Y; row++ {
+		for col := src.Min.X / level.TileWidth; col*level.TileWidth < src.Max.X; col++ {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			tile, err := s.readTile(ctx, level, col, row)
+			if err != nil {
+				return nil, err
+			}
+			if tile == nil {
+				continue
+			}
+
+			origin := image.Pt(col*level.TileWidth, row*level.TileHeight).Sub(src.Min)
+			draw.Draw(canvas, tile.Bounds().Add(origin), tile, tile.Bounds().Min, draw.Src)
+		}
+	}
+
+	if canvas.Bounds().Dx() == width && canvas.Bounds().Dy() == height {
+		return canvas, nil
+	}
+
+	out := image.NewRGBA(image.Rect(0, 0, width, height))
+	xdraw.BiLinear.Scale(out, out.Bounds(), canvas, canvas.Bounds(), draw.Src, nil)
+	return out, nil
+}
+
+// JPEGQuality is the quality of encoded JPEG tiles
+const JPEGQuality = 85
+
+// Encode encodes an image as "jpg" or "png", converting it to grayscale if
+// gray is set
+func Encode(img image.Image, format string, gray bool) ([]byte, error) {
+	if gray {
+		g := image.NewGray(img.Bounds())
+		draw.Draw(g, g.Bounds(), img, img.Bounds().Min, draw.Src)
+		img = g
+	}
+
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case "jpg", "jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: JPEGQuality})
+	case "png":
+		err = png.Encode(&buf, img)
+	default:
+		err = fmt.Errorf("unknown image format %q", format)
+	}
+	return buf.Bytes(), err
+}
+
+// levelFor picks the smallest level whose downsample does not exceed the
+// requested one
+func (s *Slide) levelFor(downsampleX, downsampleY float64) *Level {
+	downsample := min(downsampleX, downsampleY)
+
+	best := &s.Levels[0]
+	for i := range s.Levels {
+		level := &s.Levels[i]
+		if float64(s.Width())/float64(level.Width) <= downsample*1.01 {
+			best = level
+		}
+	}
+	return best
+}
+
+// readTile reads and decodes one stored tile. It returns nil for tiles that
+// hold no data.
+func (s *Slide) readTile(ctx context.Context, level *Level, col, row int) (image.Image, error) {
+	index := row*level.tilesAcross + col
+	offset, length := level.tileOffsets[index], level.tileCounts[index]
+
+	// Sparse files leave tiles outside the scanned area empty
+	if length == 0 {
+		return nil, nil
+	}
+	if length > 64<<20 {
+		return nil, fmt.Errorf("%w: tile of %d bytes", ErrUnsupported, length)
+	}
+
+	data, err := s.source.ReadRange(ctx, int64(offset), int64(length))
+	if err != nil {
+		return nil, err
+	}
+
+	tile, err := decodeTile(level, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode tile %d,%d: %w", col, row, err)
+	}
+	return tile, nil
+}
+
+// decodeTile decodes a tile's stored bytes
+func decodeTile(level *Level, data []byte) (image.Image, error) {
+	switch level.compression {
+	case compressionJPEG:
+		// Abbreviated JPEG streams share the quantization and Huffman tables
+		// stored once in the directory; splice them in ahead of the tile
+		if len(level.jpegTables) > 4 && len(data) > 2 {
+			tables := level.jpegTables[:len(level.jpegTables)-2]    // drop EOI
+			data = append(append([]byte{}, tables...), data[2:]...) // drop SOI
+		}
+		return jpeg.Decode(bytes.NewReader(data))
+
+	case compressionDeflate, compressionDeflateOld:
+		r, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+
+		raw, err := io.ReadAll(io.LimitReader(r, int64(level.TileWidth*level.TileHeight*level.samples)+1))
+		if err != nil {
+			return nil, err
+		}
+		return rawTile(level, raw)
+
+	default:
+		return rawTile(level, data)
+	}
+}
+
+// rawTile converts uncompressed 8-bit chunky samples to an image
+func rawTile(level *Level, raw []byte) (image.Image, error) {
+	w, h, samples := level.TileWidth, level.TileHeight, level.samples
+	if len(raw) < w*h*samples {
+		return nil, fmt.Errorf("tile has %d bytes, want %d", len(raw), w*h*samples)
+	}
+
+	if level.predictor == 2 {
+		// Horizontal differencing: each sample stores the change from its left neighbour
+		for y := 0; y < h; y++ {
+			row := raw[y*w*samples : (y+1)*w*samples]
+			for i := samples; i < len(row); i++ {
+				row[i] += row[i-samples]
+			}
+		}
+	} else if level.predictor != 1 {
+		return nil, fmt.Errorf("%w: predictor %d", ErrUnsupported, level.predictor)
+	}
+
+	switch {
+	case samples == 1:
+		gray := image.NewGray(image.Rect(0, 0, w, h))
+		copy(gray.Pix, raw)
+		if level.photometric == 0 { // WhiteIsZero
+			for i := range gray.Pix {
+				gray.Pix[i] = 255 - gray.Pix[i]
+			}
+		}
+		return gray, nil
+
+	case samples >= 3 && level.photometric == 2:
+		rgba := image.NewRGBA(image.Rect(0, 0, w, h))
+		for i := 0; i < w*h; i++ {
+			rgba.Pix[i*4] = raw[i*samples]
+			rgba.Pix[i*4+1] = raw[i*samples+1]
+			rgba.Pix[i*4+2] = raw[i*samples+2]
+			rgba.Pix[i*4+3] = 255
+		}
+		return rgba, nil
+
+	default:
+		return nil, fmt.Errorf("%w: %d samples with photometric interpretation %d",
+			ErrUnsupported, samples, level.photometric)
+	}
+}
+
+// ceilDiv divides and rounds up
+func ceilDiv(n, d float64) int {
+	q := n / d
+	if i := int(q); float64(i) < q {
+		return i + 1
+	}
+	return int(q)
+}
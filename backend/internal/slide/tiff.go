@@ -0,0 +1,369 @@
+// Package slide reads pyramidal whole-slide TIFF images and renders regions
+// of them as Deep Zoom and IIIF tiles.
+package slide
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"backend/internal/storage"
+)
+
+// ErrUnsupported is returned for TIFF files this package cannot render
+var ErrUnsupported = errors.New("unsupported slide format")
+
+// TIFF tags read by Open
+const (
+	tagNewSubfileType  = 254
+	tagImageWidth      = 256
+	tagImageLength     = 257
+	tagBitsPerSample   = 258
+	tagCompression     = 259
+	tagPhotometric     = 262
+	tagSamplesPerPixel = 277
+	tagPlanarConfig    = 284
+	tagPredictor       = 317
+	tagTileWidth       = 322
+	tagTileLength      = 323
+	tagTileOffsets     = 324
+	tagTileByteCounts  = 325
+	tagJPEGTables      = 347
+)
+
+// TIFF compression schemes that can be decoded
+const (
+	compressionNone       = 1
+	compressionJPEG       = 7
+	compressionDeflate    = 8
+	compressionDeflateOld = 32946
+)
+
+// maxIFDs bounds the directories read from one file, guarding against loops
+const maxIFDs = 64
+
+// maxTagValues bounds a single tag's value count, guarding against corrupt files
+const maxTagValues = 1 << 24
+
+// Source reads byte ranges of a slide file
+type Source interface {
+	ReadRange(ctx context.Context, offset, length int64) ([]byte, error)
+}
+
+// blobSource reads a slide from a blob store
+type blobSource struct {
+	blobs storage.BlobStore
+	key   string
+}
+
+// BlobSource returns a Source reading the blob stored under key
+func BlobSource(blobs storage.BlobStore, key string) Source {
+	return blobSource{blobs: blobs, key: key}
+}
+
+func (s blobSource) ReadRange(ctx context.Context, offset, length int64) ([]byte, error) {
+	r, err := s.blobs.Open(ctx, s.key, offset, length)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, fmt.Errorf("failed to read slide bytes %d-%d: %w", offset, offset+length, err)
+	}
+	return buf, nil
+}
+
+// Level is one resolution of the slide pyramid, stored as a grid of tiles
+type Level struct {
+	Width      int
+	Height     int
+	TileWidth  int
+	TileHeight int
+
+	compression int
+	photometric int
+	samples     int
+	predictor   int
+	jpegTables  []byte
+	tileOffsets []uint64
+	tileCounts  []uint64
+	tilesAcross int
+	tilesDown   int
+}
+
+// Slide is an opened pyramidal TIFF. Levels are ordered from full
+// resolution down. A Slide is safe for concurrent use.
+type Slide struct {
+	Levels []Level
+	source Source
+}
+
+// Width returns the full-resolution width in pixels
+func (s *Slide) Width() int {
+	return s.Levels[0].Width
+}
+
+// Height returns the full-resolution height in pixels
+func (s *Slide) Height() int {
+	return s.Levels[0].Height
+}
+
+// Open reads the directory structure of a tiled TIFF or BigTIFF. Untiled
+// directories, such as the label and macro images in Aperio SVS files, are
+// ignored.
+func Open(ctx context.Context, source Source) (*Slide, error) {
+	header, err := source.ReadRange(ctx, 0, 16)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &tiffReader{ctx: ctx, source: source}
+	switch string(header[:2]) {
+	case "II":
+		r.order = binary.LittleEndian
+	case "MM":
+		r.order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("%w: not a TIFF file", ErrUnsupported)
+	}
+
+	var next uint64
+	switch r.order.Uint16(header[2:]) {
+	case 42:
+		next = uint64(r.order.Uint32(header[4:]))
+	case 43:
+		r.big = true
+		next = r.order.Uint64(header[8:])
+	default:
+		return nil, fmt.Errorf("%w: not a TIFF file", ErrUnsupported)
+	}
+
+	var levels []Level
+	for i := 0; next != 0; i++ {
+		if i == maxIFDs {
+			return nil, fmt.Errorf("%w: too many image directories", ErrUnsupported)
+		}
+
+		tags, following, err := r.readIFD(next)
+		if err != nil {
+			return nil, err
+		}
+		next = following
+
+		level, ok, err := r.level(tags)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			levels = append(levels, level)
+		}
+	}
+
+	if len(levels) == 0 {
+		return nil, fmt.Errorf("%w: no tiled images", ErrUnsupported)
+	}
+
+	sort.SliceStable(levels, func(i, j int) bool { return levels[i].Width > levels[j].Width })
+
+	return &Slide{Levels: levels, source: source}, nil
+}
+
+// tiffReader decodes directories from a Source
+type tiffReader struct {
+	ctx    context.Context
+	source Source
+	order  binary.ByteOrder
+	big    bool
+}
+
+// tiffTag is a directory entry whose values have been read
+type tiffTag struct {
+	values []uint64
+	raw    []byte
+}
+
+// readIFD reads the directory at offset and returns its tags and the offset
+// of the next directory
+func (r *tiffReader) readIFD(offset uint64) (map[uint16]tiffTag, uint64, error) {
+	countSize, entrySize, pointerSize := int64(2), int64(12), int64(4)
+	if r.big {
+		countSize, entrySize, pointerSize = 8, 20, 8
+	}
+
+	countBytes, err := r.source.ReadRange(r.ctx, int64(offset), countSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	count := int64(r.uint(countBytes))
+	if count > 4096 {
+		return nil, 0, fmt.Errorf("%w: corrupt image directory", ErrUnsupported)
+	}
+
+	entries, err := r.source.ReadRange(r.ctx, int64(offset)+countSize, count*entrySize+pointerSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	tags := make(map[uint16]tiffTag)
+	for i := int64(0); i < count; i++ {
+		entry := entries[i*entrySize : (i+1)*entrySize]
+		tag := r.order.Uint16(entry)
+		switch tag {
+		case tagNewSubfileType, tagImageWidth, tagImageLength, tagBitsPerSample, tagCompression, tagPhotometric,
+			tagSamplesPerPixel, tagPlanarConfig, tagPredictor, tagTileWidth, tagTileLength, tagTileOffsets,
+			tagTileByteCounts, tagJPEGTables:
+		default:
+			continue
+		}
+
+		value, err := r.readTag(entry)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to read TIFF tag %d: %w", tag, err)
+		}
+		tags[tag] = value
+	}
+
+	return tags, r.uint(entries[count*entrySize:]), nil
+}
+
+// readTag reads the values of a directory entry, following its offset when
+// they do not fit inline
+func (r *tiffReader) readTag(entry []byte) (tiffTag, error) {
+	dataType := r.order.Uint16(entry[2:])
+
+	var count uint64
+	var inline []byte
+	if r.big {
+		count = r.order.Uint64(entry[4:])
+		inline = entry[12:20]
+	} else {
+		count = uint64(r.order.Uint32(entry[4:]))
+		inline = entry[8:12]
+	}
+
+	var size uint64
+	switch dataType {
+	case 1, 2, 6, 7: // BYTE, ASCII, SBYTE, UNDEFINED
+		size = 1
+	case 3, 8: // SHORT, SSHORT
+		size = 2
+	case 4, 9, 13: // LONG, SLONG, IFD
+		size = 4
+	case 16, 17, 18: // LONG8, SLONG8, IFD8
+		size = 8
+	default:
+		return tiffTag{}, nil
+	}
+
+	if count > maxTagValues {
+		return tiffTag{}, fmt.Errorf("%w: tag has %d values", ErrUnsupported, count)
+	}
+
+	data := inline
+	if count*size > uint64(len(inline)) {
+		var err error
+		data, err = r.source.ReadRange(r.ctx, int64(r.uint(inline)), int64(count*size))
+		if err != nil {
+			return tiffTag{}, err
+		}
+	}
+	data = data[:count*size]
+
+	tag := tiffTag{raw: data}
+	if size > 1 {
+		tag.values = make([]uint64, count)
+		for i := range tag.values {
+			switch size {
+			case 2:
+				tag.values[i] = uint64(r.order.Uint16(data[i*2:]))
+			case 4:
+				tag.values[i] = uint64(r.order.Uint32(data[i*4:]))
+			case 8:
+				tag.values[i] = r.order.Uint64(data[i*8:])
+			}
+		}
+	} else {
+		tag.values = make([]uint64, count)
+		for i, b := range data {
+			tag.values[i] = uint64(b)
+		}
+	}
+
+	return tag, nil
+}
+
+// uint decodes a 4-byte offset, or an 8-byte one in BigTIFF files
+func (r *tiffReader) uint(b []byte) uint64 {
+	if r.big {
+		return r.order.Uint64(b)
+	}
+	if len(b) == 2 {
+		return uint64(r.order.Uint16(b))
+	}
+	return uint64(r.order.Uint32(b))
+}
+
+// level builds a pyramid level from a directory. It reports false for
+// directories that are not part of the pyramid.
+func (r *tiffReader) level(tags map[uint16]tiffTag) (Level, bool, error) {
+	first := func(tag uint16, fallback int) int {
+		if t, ok := tags[tag]; ok && len(t.values) > 0 {
+			return int(t.values[0])
+		}
+		return fallback
+	}
+
+	// Reduced-resolution bit clear with other bits set marks label, macro
+	// and mask images
+	if subfile := first(tagNewSubfileType, 0); subfile&^1 != 0 {
+		return Level{}, false, nil
+	}
+	if _, tiled := tags[tagTileOffsets]; !tiled {
+		return Level{}, false, nil
+	}
+
+	level := Level{
+		Width:       first(tagImageWidth, 0),
+		Height:      first(tagImageLength, 0),
+		TileWidth:   first(tagTileWidth, 0),
+		TileHeight:  first(tagTileLength, 0),
+		compression: first(tagCompression, compressionNone),
+		photometric: first(tagPhotometric, 2),
+		samples:     first(tagSamplesPerPixel, 1),
+		predictor:   first(tagPredictor, 1),
+		jpegTables:  tags[tagJPEGTables].raw,
+		tileOffsets: tags[tagTileOffsets].values,
+		tileCounts:  tags[tagTileByteCounts].values,
+	}
+
+	if level.Width <= 0 || level.Height <= 0 || level.TileWidth <= 0 || level.TileHeight <= 0 {
+		return Level{}, false, fmt.Errorf("%w: invalid image dimensions", ErrUnsupported)
+	}
+
+	if bits := tags[tagBitsPerSample].values; len(bits) > 0 && bits[0] != 8 {
+		return Level{}, false, fmt.Errorf("%w: %d bits per sample", ErrUnsupported, bits[0])
+	}
+	if first(tagPlanarConfig, 1) != 1 {
+		return Level{}, false, fmt.Errorf("%w: planar sample layout", ErrUnsupported)
+	}
+
+	switch level.compression {
+	case compressionNone, compressionJPEG, compressionDeflate, compressionDeflateOld:
+	default:
+		return Level{}, false, fmt.Errorf("%w: compression scheme %d", ErrUnsupported, level.compression)
+	}
+
+	level.tilesAcross = (level.Width + level.TileWidth - 1) / level.TileWidth
+	level.tilesDown = (level.Height + level.TileHeight - 1) / level.TileHeight
+	tiles := level.tilesAcross * level.tilesDown
+	if len(level.tileOffsets) < tiles || len(level.tileCounts) < tiles {
+		return Level{}, false, fmt.Errorf("%w: missing tile offsets", ErrUnsupported)
+	}
+
+	return level, true, nil
+}
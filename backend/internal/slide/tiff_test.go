@@ -0,0 +1,341 @@
+package slide
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+// memSource serves a slide from memory
+type memSource []byte
+
+func (m memSource) ReadRange(ctx context.Context, offset, length int64) ([]byte, error) {
+	if offset < 0 || offset+length > int64(len(m)) {
+		return nil, errors.New("read out of range")
+	}
+	return m[offset : offset+length], nil
+}
+
+// testLevel describes one image directory written by buildTIFF
+type testLevel struct {
+	width, height, tile int
+	jpeg                bool
+	jpegTables          bool
+	untiled             bool
+	pixel               func(x, y int) color.RGBA
+}
+
+// tiffEntry is a directory entry for buildTIFF
+type tiffEntry struct {
+	tag      uint16
+	dataType uint16
+	values   []uint64
+	raw      []byte
+}
+
+// buildTIFF writes a little-endian TIFF, or BigTIFF if big is set
+func buildTIFF(t *testing.T, big bool, levels []testLevel) []byte {
+	t.Helper()
+
+	le := binary.LittleEndian
+	var buf bytes.Buffer
+	if big {
+		buf.Write([]byte{'I', 'I', 43, 0, 8, 0, 0, 0})
+		buf.Write(make([]byte, 8))
+	} else {
+		buf.Write([]byte{'I', 'I', 42, 0})
+		buf.Write(make([]byte, 4))
+	}
+	nextPointer := buf.Len() - 4
+	if big {
+		nextPointer = buf.Len() - 8
+	}
+
+	putPointer := func(at int, value uint64) {
+		b := buf.Bytes()
+		if big {
+			le.PutUint64(b[at:], value)
+		} else {
+			le.PutUint32(b[at:], uint32(value))
+		}
+	}
+
+	for _, level := range levels {
+		var offsets, counts []uint64
+		var tables []byte
+
+		if level.untiled {
+			offsets = []uint64{uint64(buf.Len())}
+			buf.Write(make([]byte, level.width*level.height*3))
+		} else {
+			for ty := 0; ty < (level.height+level.tile-1)/level.tile; ty++ {
+				for tx := 0; tx < (level.width+level.tile-1)/level.tile; tx++ {
+					data := encodeTestTile(t, level, tx, ty)
+					if level.jpegTables {
+						// Move everything before the scan into the shared tables
+						sos := bytes.Index(data, []byte{0xFF, 0xDA})
+						tables = append(append([]byte{}, data[:sos]...), 0xFF, 0xD9)
+						data = append([]byte{0xFF, 0xD8}, data[sos:]...)
+					}
+					offsets = append(offsets, uint64(buf.Len()))
+					counts = append(counts, uint64(len(data)))
+					buf.Write(data)
+				}
+			}
+		}
+
+		compression := uint64(compressionNone)
+		if level.jpeg {
+			compression = compressionJPEG
+		}
+		offsetType := uint16(4)
+		if big {
+			offsetType = 16
+		}
+
+		entries := []tiffEntry{
+			{tag: tagImageWidth, dataType: 4, values: []uint64{uint64(level.width)}},
+			{tag: tagImageLength, dataType: 4, values: []uint64{uint64(level.height)}},
+			{tag: tagBitsPerSample, dataType: 3, values: []uint64{8, 8, 8}},
+			{tag: tagCompression, dataType: 3, values: []uint64{compression}},
+			{tag: tagPhotometric, dataType: 3, values: []uint64{2}},
+			{tag: tagSamplesPerPixel, dataType: 3, values: []uint64{3}},
+		}
+		if level.untiled {
+			entries = append([]tiffEntry{{tag: tagNewSubfileType, dataType: 4, values: []uint64{1}}}, entries...)
+			entries = append(entries, tiffEntry{tag: 273, dataType: offsetType, values: offsets})
+		} else {
+			entries = append(entries,
+				tiffEntry{tag: tagTileWidth, dataType: 3, values: []uint64{uint64(level.tile)}},
+				tiffEntry{tag: tagTileLength, dataType: 3, values: []uint64{uint64(level.tile)}},
+				tiffEntry{tag: tagTileOffsets, dataType: offsetType, values: offsets},
+				tiffEntry{tag: tagTileByteCounts, dataType: offsetType, values: counts},
+			)
+			if tables != nil {
+				entries = append(entries, tiffEntry{tag: tagJPEGTables, dataType: 7, raw: tables})
+			}
+		}
+
+		putPointer(nextPointer, uint64(buf.Len()))
+		nextPointer = writeTestIFD(&buf, big, entries)
+	}
+
+	return buf.Bytes()
+}
+
+// writeTestIFD appends a directory and its out-of-line values, returning the
+// position of its next-directory pointer
+func writeTestIFD(buf *bytes.Buffer, big bool, entries []tiffEntry) int {
+	le := binary.LittleEndian
+	countSize, entrySize, inlineSize := 2, 12, 4
+	if big {
+		countSize, entrySize, inlineSize = 8, 20, 8
+	}
+
+	start := buf.Len()
+	dirSize := countSize + len(entries)*entrySize + inlineSize
+	dir := make([]byte, dirSize)
+	if big {
+		le.PutUint64(dir, uint64(len(entries)))
+	} else {
+		le.PutUint16(dir, uint16(len(entries)))
+	}
+
+	var extra bytes.Buffer
+	for i, e := range entries {
+		data := e.raw
+		if data == nil {
+			size := map[uint16]int{3: 2, 4: 4, 16: 8}[e.dataType]
+			data = make([]byte, len(e.values)*size)
+			for j, v := range e.values {
+				switch size {
+				case 2:
+					le.PutUint16(data[j*2:], uint16(v))
+				case 4:
+					le.PutUint32(data[j*4:], uint32(v))
+				case 8:
+					le.PutUint64(data[j*8:], v)
+				}
+			}
+		}
+		count := len(e.values)
+		if e.raw != nil {
+			count = len(e.raw)
+		}
+
+		entry := dir[countSize+i*entrySize:]
+		le.PutUint16(entry, e.tag)
+		le.PutUint16(entry[2:], e.dataType)
+		valueField := entry[8:12]
+		if big {
+			le.PutUint64(entry[4:], uint64(count))
+			valueField = entry[12:20]
+		} else {
+			le.PutUint32(entry[4:], uint32(count))
+		}
+
+		if len(data) <= inlineSize {
+			copy(valueField, data)
+			continue
+		}
+		offset := uint64(start + dirSize + extra.Len())
+		if big {
+			le.PutUint64(valueField, offset)
+		} else {
+			le.PutUint32(valueField, uint32(offset))
+		}
+		extra.Write(data)
+	}
+
+	buf.Write(dir)
+	buf.Write(extra.Bytes())
+	return start + dirSize - inlineSize
+}
+
+// encodeTestTile renders one tile of a test level
+func encodeTestTile(t *testing.T, level testLevel, tx, ty int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, level.tile, level.tile))
+	for y := 0; y < level.tile; y++ {
+		for x := 0; x < level.tile; x++ {
+			img.SetRGBA(x, y, level.pixel(tx*level.tile+x, ty*level.tile+y))
+		}
+	}
+
+	if level.jpeg {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+			t.Fatalf("Failed to encode test tile: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	raw := make([]byte, 0, level.tile*level.tile*3)
+	for i := 0; i < len(img.Pix); i += 4 {
+		raw = append(raw, img.Pix[i], img.Pix[i+1], img.Pix[i+2])
+	}
+	return raw
+}
+
+// gradient colors pixels by position so misplaced tiles are detectable
+func gradient(x, y int) color.RGBA {
+	return color.RGBA{R: uint8(x), G: uint8(y), B: 100, A: 255}
+}
+
+// solid returns a pixel function of a single color
+func solid(c color.RGBA) func(x, y int) color.RGBA {
+	return func(x, y int) color.RGBA { return c }
+}
+
+func testPyramid(t *testing.T, big bool) *Slide {
+	t.Helper()
+
+	data := buildTIFF(t, big, []testLevel{
+		{width: 200, height: 150, tile: 64, pixel: gradient},
+		{width: 40, height: 30, untiled: true},
+		{width: 100, height: 75, tile: 64, pixel: solid(color.RGBA{R: 255, A: 255})},
+	})
+
+	s, err := Open(context.Background(), memSource(data))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	return s
+}
+
+func TestOpen(t *testing.T) {
+	for _, big := range []bool{false, true} {
+		s := testPyramid(t, big)
+
+		if len(s.Levels) != 2 {
+			t.Fatalf("big=%v: expected 2 levels, got %d", big, len(s.Levels))
+		}
+		if s.Width() != 200 || s.Height() != 150 {
+			t.Errorf("big=%v: expected 200x150, got %dx%d", big, s.Width(), s.Height())
+		}
+		if s.Levels[1].Width != 100 || s.Levels[1].TileWidth != 64 {
+			t.Errorf("big=%v: unexpected second level %+v", big, s.Levels[1])
+		}
+	}
+}
+
+func TestOpen_NotTIFF(t *testing.T) {
+	_, err := Open(context.Background(), memSource([]byte("%PDF-1.7 not a tiff file")))
+	if !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported, got %v", err)
+	}
+}
+
+func TestRender_FullResolution(t *testing.T) {
+	s := testPyramid(t, false)
+
+	// A region spanning four stored tiles
+	img, err := s.Render(context.Background(), image.Rect(60, 60, 70, 70), 10, 10)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	for _, p := range []image.Point{{0, 0}, {3, 3}, {4, 5}, {9, 9}} {
+		want := gradient(60+p.X, 60+p.Y)
+		if got := img.RGBAAt(p.X, p.Y); got != want {
+			t.Errorf("Pixel %v = %v, want %v", p, got, want)
+		}
+	}
+}
+
+func TestRender_UsesReducedLevel(t *testing.T) {
+	s := testPyramid(t, false)
+
+	// Half resolution is served from the solid red level
+	img, err := s.Render(context.Background(), image.Rect(0, 0, 200, 150), 100, 75)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	if got := img.RGBAAt(50, 30); got.R != 255 || got.G != 0 {
+		t.Errorf("Expected red pixel from the reduced level, got %v", got)
+	}
+}
+
+func TestRender_JPEGTiles(t *testing.T) {
+	for _, tables := range []bool{false, true} {
+		data := buildTIFF(t, false, []testLevel{
+			{width: 64, height: 64, tile: 32, jpeg: true, jpegTables: tables, pixel: solid(color.RGBA{G: 200, A: 255})},
+		})
+
+		s, err := Open(context.Background(), memSource(data))
+		if err != nil {
+			t.Fatalf("tables=%v: Open failed: %v", tables, err)
+		}
+
+		img, err := s.Render(context.Background(), image.Rect(0, 0, 64, 64), 64, 64)
+		if err != nil {
+			t.Fatalf("tables=%v: Render failed: %v", tables, err)
+		}
+
+		if got := img.RGBAAt(40, 40); got.G < 190 || got.R > 10 {
+			t.Errorf("tables=%v: expected green pixel, got %v", tables, got)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+
+	for _, format := range []string{"jpg", "png"} {
+		data, err := Encode(img, format, true)
+		if err != nil || len(data) == 0 {
+			t.Errorf("Encode(%s) failed: %v", format, err)
+		}
+	}
+
+	if _, err := Encode(img, "gif", false); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,23 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+// TestAzureStore runs against Azurite or a real account when
+// AZURITE_CONNECTION_STRING is set
+func TestAzureStore(t *testing.T) {
+	connStr := os.Getenv("AZURITE_CONNECTION_STRING")
+	if connStr == "" {
+		t.Skip("AZURITE_CONNECTION_STRING not set")
+	}
+
+	store, err := NewAzureStore(context.Background(), connStr, "test-slides")
+	if err != nil {
+		t.Fatalf("NewAzureStore failed: %v", err)
+	}
+
+	testBlobStore(t, store)
+}
@@ -0,0 +1,58 @@
+// Package storage stores large binary artifacts, such as whole-slide images,
+// in a pluggable blob store and assembles them from resumable chunked uploads.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"backend/internal/config"
+)
+
+// ErrNotFound is returned when a blob does not exist
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore stores blobs by key. Keys are slash-separated paths such as
+// uploads/42/content.
+type BlobStore interface {
+	// Put stores everything read from r under key, replacing any existing blob
+	Put(ctx context.Context, key string, r io.Reader) error
+
+	// Open reads length bytes starting at offset; a negative length reads to the end
+	Open(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+
+	// Size returns the size of a blob in bytes
+	Size(ctx context.Context, key string) (int64, error)
+
+	// Delete removes a blob; deleting a missing blob is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// healthCheckKey is looked up by Check; it is never written
+const healthCheckKey = ".health/probe"
+
+// Check reports whether the store can be reached by looking up a blob that
+// does not exist: a not-found answer means the store is responding
+func Check(ctx context.Context, store BlobStore) error {
+	if _, err := store.Size(ctx, healthCheckKey); err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
+// NewBlobStoreFromConfig creates the blob store selected by cfg.BlobStore
+func NewBlobStoreFromConfig(ctx context.Context, cfg *config.Config) (BlobStore, error) {
+	switch cfg.BlobStore {
+	case "local":
+		return NewLocalStore(cfg.BlobLocalDir)
+	case "azure":
+		if cfg.BlobAzureConnectionString == "" {
+			return nil, errors.New("BLOB_AZURE_CONNECTION_STRING is required for the azure blob store")
+		}
+		return NewAzureStore(ctx, cfg.BlobAzureConnectionString, cfg.BlobAzureContainer)
+	default:
+		return nil, fmt.Errorf("unknown blob store %q", cfg.BlobStore)
+	}
+}
@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// testBlobStore exercises the BlobStore contract; backends share it
+func testBlobStore(t *testing.T, store BlobStore) {
+	ctx := context.Background()
+
+	if err := Check(ctx, store); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+
+	if err := store.Put(ctx, "slides/1/content", strings.NewReader("0123456789")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	size, err := store.Size(ctx, "slides/1/content")
+	if err != nil {
+		t.Fatalf("Size failed: %v", err)
+	}
+	if size != 10 {
+		t.Errorf("Expected size 10, got %d", size)
+	}
+
+	ranges := []struct {
+		offset, length int64
+		want           string
+	}{
+		{0, -1, "0123456789"},
+		{3, 4, "3456"},
+		{7, -1, "789"},
+	}
+	for _, tc := range ranges {
+		r, err := store.Open(ctx, "slides/1/content", tc.offset, tc.length)
+		if err != nil {
+			t.Fatalf("Open(%d, %d) failed: %v", tc.offset, tc.length, err)
+		}
+		data, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatalf("Read(%d, %d) failed: %v", tc.offset, tc.length, err)
+		}
+		if string(data) != tc.want {
+			t.Errorf("Open(%d, %d) = %q, want %q", tc.offset, tc.length, data, tc.want)
+		}
+	}
+
+	// Put replaces existing content
+	if err := store.Put(ctx, "slides/1/content", bytes.NewReader([]byte("abc"))); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if size, _ := store.Size(ctx, "slides/1/content"); size != 3 {
+		t.Errorf("Expected size 3 after overwrite, got %d", size)
+	}
+
+	if err := store.Delete(ctx, "slides/1/content"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := store.Open(ctx, "slides/1/content", 0, -1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after delete, got %v", err)
+	}
+	if _, err := store.Size(ctx, "slides/1/content"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for size after delete, got %v", err)
+	}
+
+	// Deleting a missing blob is not an error
+	if err := store.Delete(ctx, "slides/1/content"); err != nil {
+		t.Errorf("Expected deleting a missing blob to succeed, got %v", err)
+	}
+}
+
+func TestLocalStore(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStore failed: %v", err)
+	}
+
+	testBlobStore(t, store)
+}
+
+func TestLocalStore_RejectsEscapingKeys(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStore failed: %v", err)
+	}
+
+	for _, key := range []string{"", "/etc/passwd", "../outside", "a/../../b", "a//b", `a\b`} {
+		if err := store.Put(context.Background(), key, strings.NewReader("x")); err == nil {
+			t.Errorf("Expected key %q to be rejected", key)
+		}
+	}
+}
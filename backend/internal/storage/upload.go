@@ -0,0 +1,227 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"backend/internal/models"
+)
+
+var (
+	// ErrOffsetMismatch is returned when a chunk does not start at the upload's current offset
+	ErrOffsetMismatch = errors.New("upload offset mismatch")
+
+	// ErrUploadTooLarge is returned when a chunk would exceed the declared upload length
+	ErrUploadTooLarge = errors.New("chunk exceeds upload length")
+
+	// ErrChecksumMismatch is returned when a chunk or the assembled file fails verification
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+
+	// ErrUploadClosed is returned for chunks sent to a completed or failed upload
+	ErrUploadClosed = errors.New("upload is no longer accepting data")
+)
+
+// UploadStore is the subset of models.UploadRepository used by the Uploader
+type UploadStore interface {
+	AppendPart(ctx context.Context, id int, fromOffset, toOffset int64, partKey string) error
+	Complete(ctx context.Context, id int, storageKey, sha256 string) error
+	Fail(ctx context.Context, id int, message string) error
+}
+
+// Uploader stages upload chunks in a BlobStore and assembles them into the
+// final blob. Each chunk is its own blob, so an upload survives restarts and
+// can be resumed through any instance.
+type Uploader struct {
+	blobs   BlobStore
+	uploads UploadStore
+}
+
+// NewUploader creates a new uploader
+func NewUploader(blobs BlobStore, uploads UploadStore) *Uploader {
+	return &Uploader{blobs: blobs, uploads: uploads}
+}
+
+// ContentKey returns the blob key of a completed upload's content
+func ContentKey(uploadID int) string {
+	return fmt.Sprintf("uploads/%d/content", uploadID)
+}
+
+// WriteChunk stores the bytes read from body at offset. If checksum is not
+// nil it must equal the SHA-256 of the chunk. When the chunk completes the
+// upload, the parts are assembled and verified. upload is updated in place.
+func (u *Uploader) WriteChunk(ctx context.Context, upload *models.Upload, offset int64, body io.Reader, checksum []byte) error {
+	if upload.Status != models.UploadUploading {
+		return ErrUploadClosed
+	}
+	if offset != upload.Offset {
+		return ErrOffsetMismatch
+	}
+
+	remaining := upload.Size - offset
+	if remaining > 0 {
+		suffix := make([]byte, 4)
+		if _, err := rand.Read(suffix); err != nil {
+			return err
+		}
+		partKey := fmt.Sprintf("uploads/%d/parts/%016d-%s", upload.ID, offset, hex.EncodeToString(suffix))
+
+		// Read one byte past the remaining length to detect oversized chunks
+		counter := &countingReader{r: io.LimitReader(body, remaining+1)}
+		hasher := sha256.New()
+		if err := u.blobs.Put(ctx, partKey, io.TeeReader(counter, hasher)); err != nil {
+			u.discard(partKey)
+			return fmt.Errorf("failed to store chunk: %w", err)
+		}
+
+		switch {
+		case counter.n > remaining:
+			u.discard(partKey)
+			return ErrUploadTooLarge
+		case checksum != nil && !bytes.Equal(hasher.Sum(nil), checksum):
+			u.discard(partKey)
+			return ErrChecksumMismatch
+		case counter.n == 0:
+			u.discard(partKey)
+			return nil
+		}
+
+		if err := u.uploads.AppendPart(ctx, upload.ID, offset, offset+counter.n, partKey); err != nil {
+			u.discard(partKey)
+			if err == sql.ErrNoRows {
+				return ErrOffsetMismatch
+			}
+			return fmt.Errorf("failed to record chunk: %w", err)
+		}
+
+		upload.Offset += counter.n
+		upload.PartKeys = append(upload.PartKeys, partKey)
+	}
+
+	if upload.Offset == upload.Size {
+		return u.Finalize(ctx, upload)
+	}
+	return nil
+}
+
+// Finalize joins the staged parts of a fully received upload into its
+// content blob and verifies the expected checksum, if one was declared. It is
+// safe to call again if a previous attempt was interrupted.
+func (u *Uploader) Finalize(ctx context.Context, upload *models.Upload) error {
+	key := ContentKey(upload.ID)
+	hasher := sha256.New()
+
+	parts := &partsReader{ctx: ctx, blobs: u.blobs, keys: upload.PartKeys}
+	err := u.blobs.Put(ctx, key, io.TeeReader(parts, hasher))
+	parts.Close()
+	if err != nil {
+		return fmt.Errorf("failed to assemble upload: %w", err)
+	}
+
+	sum := hex.EncodeToString(hasher.Sum(nil))
+	if upload.ExpectedSHA256 != "" && sum != upload.ExpectedSHA256 {
+		u.discard(key)
+		if err := u.uploads.Fail(ctx, upload.ID, "checksum mismatch: received "+sum); err != nil {
+			return fmt.Errorf("failed to record checksum failure: %w", err)
+		}
+		u.discardParts(upload.PartKeys)
+		upload.Status = models.UploadFailed
+		return ErrChecksumMismatch
+	}
+
+	if err := u.uploads.Complete(ctx, upload.ID, key, sum); err != nil {
+		if err == sql.ErrNoRows {
+			// Another request finalized the upload first
+			return nil
+		}
+		return fmt.Errorf("failed to record completed upload: %w", err)
+	}
+
+	u.discardParts(upload.PartKeys)
+	upload.Status = models.UploadComplete
+	upload.StorageKey = key
+	upload.SHA256 = sum
+	upload.PartKeys = nil
+	return nil
+}
+
+// Abort removes the staged parts of an upload that is being deleted
+func (u *Uploader) Abort(upload *models.Upload) {
+	u.discardParts(upload.PartKeys)
+}
+
+// discardParts deletes staged parts, logging failures
+func (u *Uploader) discardParts(keys []string) {
+	for _, key := range keys {
+		u.discard(key)
+	}
+}
+
+// discard deletes a blob, logging failures; orphans waste space but are harmless
+func (u *Uploader) discard(key string) {
+	if err := u.blobs.Delete(context.Background(), key); err != nil {
+		slog.Warn("Failed to delete blob", "key", key, "error", err)
+	}
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// partsReader reads a sequence of blobs as one stream, opening each in turn
+type partsReader struct {
+	ctx     context.Context
+	blobs   BlobStore
+	keys    []string
+	current io.ReadCloser
+}
+
+func (p *partsReader) Read(buf []byte) (int, error) {
+	for {
+		if p.current == nil {
+			if len(p.keys) == 0 {
+				return 0, io.EOF
+			}
+			part, err := p.blobs.Open(p.ctx, p.keys[0], 0, -1)
+			if err != nil {
+				return 0, fmt.Errorf("failed to open part %s: %w", p.keys[0], err)
+			}
+			p.current = part
+			p.keys = p.keys[1:]
+		}
+
+		n, err := p.current.Read(buf)
+		if err == io.EOF {
+			p.current.Close()
+			p.current = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+// Close closes the part currently being read
+func (p *partsReader) Close() error {
+	if p.current != nil {
+		return p.current.Close()
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"backend/internal/models"
+)
+
+// fakeUploadStore records upload state in memory
+type fakeUploadStore struct {
+	uploads map[int]*models.Upload
+}
+
+func newFakeUploadStore(uploads ...*models.Upload) *fakeUploadStore {
+	s := &fakeUploadStore{uploads: make(map[int]*models.Upload)}
+	for _, u := range uploads {
+		stored := *u
+		s.uploads[u.ID] = &stored
+	}
+	return s
+}
+
+func (s *fakeUploadStore) AppendPart(_ context.Context, id int, fromOffset, toOffset int64, partKey string) error {
+	u := s.uploads[id]
+	if u == nil || u.Offset != fromOffset || u.Status != models.UploadUploading {
+		return sql.ErrNoRows
+	}
+	u.Offset = toOffset
+	u.PartKeys = append(u.PartKeys, partKey)
+	return nil
+}
+
+func (s *fakeUploadStore) Complete(_ context.Context, id int, storageKey, sum string) error {
+	u := s.uploads[id]
+	if u == nil || u.Status != models.UploadUploading {
+		return sql.ErrNoRows
+	}
+	u.Status = models.UploadComplete
+	u.StorageKey = storageKey
+	u.SHA256 = sum
+	u.PartKeys = nil
+	return nil
+}
+
+func (s *fakeUploadStore) Fail(_ context.Context, id int, message string) error {
+	u := s.uploads[id]
+	if u == nil || u.Status != models.UploadUploading {
+		return sql.ErrNoRows
+	}
+	u.Status = models.UploadFailed
+	u.Error = message
+	u.PartKeys = nil
+	return nil
+}
+
+func newTestUploader(t *testing.T, upload *models.Upload) (*Uploader, *LocalStore, *fakeUploadStore) {
+	blobs, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStore failed: %v", err)
+	}
+	uploads := newFakeUploadStore(upload)
+	return NewUploader(blobs, uploads), blobs, uploads
+}
+
+func sha256Sum(data string) []byte {
+	sum := sha256.Sum256([]byte(data))
+	return sum[:]
+}
+
+func readBlob(t *testing.T, blobs BlobStore, key string) string {
+	r, err := blobs.Open(context.Background(), key, 0, -1)
+	if err != nil {
+		t.Fatalf("Open(%s) failed: %v", key, err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Read(%s) failed: %v", key, err)
+	}
+	return string(data)
+}
+
+func TestUploader_WriteChunksAndFinalize(t *testing.T) {
+	content := "whole slide image bytes"
+	upload := &models.Upload{
+		ID:             1,
+		Size:           int64(len(content)),
+		Status:         models.UploadUploading,
+		ExpectedSHA256: hex.EncodeToString(sha256Sum(content)),
+	}
+	uploader, blobs, store := newTestUploader(t, upload)
+	ctx := context.Background()
+
+	if err := uploader.WriteChunk(ctx, upload, 0, strings.NewReader(content[:10]), sha256Sum(content[:10])); err != nil {
+		t.Fatalf("First chunk failed: %v", err)
+	}
+	if upload.Offset != 10 || upload.Status != models.UploadUploading {
+		t.Fatalf("Expected offset 10 and uploading, got %d and %s", upload.Offset, upload.Status)
+	}
+	firstPart := upload.PartKeys[0]
+
+	if err := uploader.WriteChunk(ctx, upload, 10, strings.NewReader(content[10:]), nil); err != nil {
+		t.Fatalf("Last chunk failed: %v", err)
+	}
+
+	if upload.Status != models.UploadComplete || store.uploads[1].Status != models.UploadComplete {
+		t.Fatalf("Expected upload to be complete, got %s", upload.Status)
+	}
+	if upload.SHA256 != upload.ExpectedSHA256 {
+		t.Errorf("Expected sha256 %s, got %s", upload.ExpectedSHA256, upload.SHA256)
+	}
+	if got := readBlob(t, blobs, ContentKey(1)); got != content {
+		t.Errorf("Expected content %q, got %q", content, got)
+	}
+
+	// Staged parts are removed once the upload is assembled
+	if _, err := blobs.Size(ctx, firstPart); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected part %s to be deleted, got %v", firstPart, err)
+	}
+}
+
+func TestUploader_OffsetMismatch(t *testing.T) {
+	upload := &models.Upload{ID: 1, Size: 10, Status: models.UploadUploading}
+	uploader, _, _ := newTestUploader(t, upload)
+
+	err := uploader.WriteChunk(context.Background(), upload, 5, strings.NewReader("12345"), nil)
+	if !errors.Is(err, ErrOffsetMismatch) {
+		t.Errorf("Expected ErrOffsetMismatch, got %v", err)
+	}
+}
+
+func TestUploader_ConcurrentChunkLosesRace(t *testing.T) {
+	upload := &models.Upload{ID: 1, Size: 10, Status: models.UploadUploading}
+	uploader, _, store := newTestUploader(t, upload)
+
+	// Another request already advanced the stored offset
+	store.uploads[1].Offset = 5
+
+	err := uploader.WriteChunk(context.Background(), upload, 0, strings.NewReader("12345"), nil)
+	if !errors.Is(err, ErrOffsetMismatch) {
+		t.Errorf("Expected ErrOffsetMismatch, got %v", err)
+	}
+}
+
+func TestUploader_ChunkChecksumMismatch(t *testing.T) {
+	upload := &models.Upload{ID: 1, Size: 10, Status: models.UploadUploading}
+	uploader, _, store := newTestUploader(t, upload)
+
+	err := uploader.WriteChunk(context.Background(), upload, 0, strings.NewReader("12345"), sha256Sum("other"))
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Expected ErrChecksumMismatch, got %v", err)
+	}
+	if store.uploads[1].Offset != 0 {
+		t.Errorf("Expected offset to stay 0, got %d", store.uploads[1].Offset)
+	}
+}
+
+func TestUploader_ChunkTooLarge(t *testing.T) {
+	upload := &models.Upload{ID: 1, Size: 4, Status: models.UploadUploading}
+	uploader, _, store := newTestUploader(t, upload)
+
+	err := uploader.WriteChunk(context.Background(), upload, 0, bytes.NewReader([]byte("12345")), nil)
+	if !errors.Is(err, ErrUploadTooLarge) {
+		t.Errorf("Expected ErrUploadTooLarge, got %v", err)
+	}
+	if store.uploads[1].Offset != 0 {
+		t.Errorf("Expected offset to stay 0, got %d", store.uploads[1].Offset)
+	}
+}
+
+func TestUploader_ExpectedChecksumMismatch(t *testing.T) {
+	upload := &models.Upload{
+		ID:             1,
+		Size:           5,
+		Status:         models.UploadUploading,
+		ExpectedSHA256: hex.EncodeToString(sha256Sum("other")),
+	}
+	uploader, blobs, store := newTestUploader(t, upload)
+
+	err := uploader.WriteChunk(context.Background(), upload, 0, strings.NewReader("12345"), nil)
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("Expected ErrChecksumMismatch, got %v", err)
+	}
+	if store.uploads[1].Status != models.UploadFailed {
+		t.Errorf("Expected upload to be failed, got %s", store.uploads[1].Status)
+	}
+	if _, err := blobs.Size(context.Background(), ContentKey(1)); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected assembled content to be deleted, got %v", err)
+	}
+
+	// Further chunks are refused
+	err = uploader.WriteChunk(context.Background(), upload, 5, strings.NewReader(""), nil)
+	if !errors.Is(err, ErrUploadClosed) {
+		t.Errorf("Expected ErrUploadClosed, got %v", err)
+	}
+}
+
+func TestUploader_EmptyUpload(t *testing.T) {
+	upload := &models.Upload{ID: 1, Size: 0, Status: models.UploadUploading}
+	uploader, blobs, _ := newTestUploader(t, upload)
+
+	if err := uploader.Finalize(context.Background(), upload); err != nil {
+		t.Fatalf("Finalize failed: %v", err)
+	}
+	if upload.Status != models.UploadComplete {
+		t.Errorf("Expected upload to be complete, got %s", upload.Status)
+	}
+	if got := readBlob(t, blobs, ContentKey(1)); got != "" {
+		t.Errorf("Expected empty content, got %q", got)
+	}
+}
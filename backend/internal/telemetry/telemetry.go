@@ -0,0 +1,161 @@
+// Package telemetry sets up OpenTelemetry tracing and metrics, exported
+// over OTLP/HTTP and scraped by Prometheus. The HTTP and database
+// instrumentation that records to these providers lives with the code it
+// observes, in the middleware and database packages.
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	otelprometheus "go.opentelemetry.io/otel/exporters/prometheus"
+	"go.opentelemetry.io/otel/metric"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.32.0"
+	"go.opentelemetry.io/otel/trace"
+	tracenoop "go.opentelemetry.io/otel/trace/noop"
+
+	"backend/internal/config"
+	"backend/internal/version"
+)
+
+// ScopeName identifies this service's instrumentation in exported telemetry
+const ScopeName = "backend"
+
+// Telemetry holds the providers that instrumentation records to and the
+// Prometheus registry served at /metrics
+type Telemetry struct {
+	TracerProvider trace.TracerProvider
+	MeterProvider  metric.MeterProvider
+	Gatherer       prometheus.Gatherer
+
+	shutdown []func(context.Context) error
+}
+
+// Disabled returns providers that record nothing, for commands that do not
+// report telemetry
+func Disabled() *Telemetry {
+	return &Telemetry{
+		TracerProvider: tracenoop.NewTracerProvider(),
+		MeterProvider:  metricnoop.NewMeterProvider(),
+		Gatherer:       prometheus.NewRegistry(),
+	}
+}
+
+// New wraps SDK providers, such as ones exporting to memory in tests, and
+// the registry their metrics are gathered from. Shutdown flushes and stops
+// the providers.
+func New(tp trace.TracerProvider, mp *sdkmetric.MeterProvider, gatherer prometheus.Gatherer) *Telemetry {
+	t := &Telemetry{
+		TracerProvider: tp,
+		MeterProvider:  mp,
+		Gatherer:       gatherer,
+		shutdown:       []func(context.Context) error{mp.Shutdown},
+	}
+	if sdk, ok := tp.(*sdktrace.TracerProvider); ok {
+		t.shutdown = append(t.shutdown, sdk.Shutdown)
+	}
+	return t
+}
+
+// NewFromConfig records metrics for Prometheus to scrape, along with Go
+// runtime, process and build information, including the version and commit
+// from the version package. When OTEL_EXPORTER_OTLP_ENDPOINT
+// is set, traces and metrics are also exported there, sampling
+// OTEL_SAMPLE_RATIO of new traces; requests that arrive with a sampled W3C
+// traceparent are always traced.
+//
+// The providers are also installed globally, along with the W3C trace
+// context propagator.
+func NewFromConfig(ctx context.Context, cfg *config.Config) (*Telemetry, error) {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		collectors.NewBuildInfoCollector(),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name:        "alphapath_build_info",
+			Help:        "Version and commit of the running build; always 1.",
+			ConstLabels: prometheus.Labels{"version": version.Version, "commit": version.Commit},
+		}, func() float64 { return 1 }),
+	)
+	promExporter, err := otelprometheus.New(otelprometheus.WithRegisterer(registry))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Prometheus exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		semconv.ServiceName(cfg.OTelServiceName),
+		semconv.ServiceVersion(version.Version),
+		semconv.DeploymentEnvironmentName(cfg.Environment),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe service: %w", err)
+	}
+
+	var tp trace.TracerProvider = tracenoop.NewTracerProvider()
+	metricOptions := []sdkmetric.Option{sdkmetric.WithReader(promExporter), sdkmetric.WithResource(res)}
+
+	if cfg.OTelEndpoint != "" {
+		traceExporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.OTelEndpoint))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+		}
+		metricExporter, err := otlpmetrichttp.New(ctx, otlpmetrichttp.WithEndpointURL(cfg.OTelEndpoint))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create metric exporter: %w", err)
+		}
+
+		tp = sdktrace.NewTracerProvider(
+			sdktrace.WithBatcher(traceExporter),
+			sdktrace.WithResource(res),
+			sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.OTelSampleRatio))),
+		)
+		metricOptions = append(metricOptions, sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter)))
+	}
+
+	t := New(tp, sdkmetric.NewMeterProvider(metricOptions...), registry)
+
+	otel.SetTracerProvider(t.TracerProvider)
+	otel.SetMeterProvider(t.MeterProvider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	return t, nil
+}
+
+// Tracer returns the tracer for this service's instrumentation
+func (t *Telemetry) Tracer() trace.Tracer {
+	return t.TracerProvider.Tracer(ScopeName)
+}
+
+// Meter returns the meter for this service's instrumentation
+func (t *Telemetry) Meter() metric.Meter {
+	return t.MeterProvider.Meter(ScopeName)
+}
+
+// MetricsHandler serves the gathered metrics in the Prometheus exposition
+// format
+func (t *Telemetry) MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(t.Gatherer, promhttp.HandlerOpts{})
+}
+
+// Shutdown exports buffered spans and metrics and stops the providers
+func (t *Telemetry) Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, shutdown := range t.shutdown {
+		errs = append(errs, shutdown(ctx))
+	}
+	return errors.Join(errs...)
+}
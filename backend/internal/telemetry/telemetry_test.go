@@ -0,0 +1,69 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"backend/internal/config"
+)
+
+func TestNewFromConfig_Prometheus(t *testing.T) {
+	tel, err := NewFromConfig(context.Background(), &config.Config{OTelServiceName: "alphapath-api"})
+	if err != nil {
+		t.Fatalf("NewFromConfig failed: %v", err)
+	}
+	defer tel.Shutdown(context.Background())
+
+	_, span := tel.Tracer().Start(context.Background(), "ignored")
+	if span.SpanContext().IsValid() {
+		t.Error("Expected no spans to be recorded without an OTLP endpoint")
+	}
+
+	counter, _ := tel.Meter().Int64Counter("hl7.messages.received")
+	counter.Add(context.Background(), 3)
+
+	w := httptest.NewRecorder()
+	tel.MetricsHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+
+	for _, expected := range []string{"hl7_messages_received_total", "go_goroutines", "go_build_info", "alphapath_build_info", "process_cpu_seconds_total", `service_name="alphapath-api"`} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Expected %s in the scrape, got %s", expected, body)
+		}
+	}
+}
+
+// keptExporter holds on to exported spans, which the in-memory exporter
+// discards when it is shut down
+type keptExporter struct {
+	*tracetest.InMemoryExporter
+}
+
+func (keptExporter) Shutdown(context.Context) error { return nil }
+
+func TestShutdown_Flushes(t *testing.T) {
+	exporter := keptExporter{tracetest.NewInMemoryExporter()}
+	tel := New(
+		sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter)),
+		sdkmetric.NewMeterProvider(),
+		prometheus.NewRegistry(),
+	)
+
+	_, span := tel.Tracer().Start(context.Background(), "GET /api/health")
+	span.End()
+
+	if err := tel.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	if spans := exporter.GetSpans(); len(spans) != 1 {
+		t.Errorf("Expected the batched span to be exported on shutdown, got %d", len(spans))
+	}
+}
@@ -0,0 +1,32 @@
+// Package version reports the build's version and commit. Release builds
+// set both at link time:
+//
+//	go build -ldflags "-X backend/internal/version.Version=1.4.0 -X backend/internal/version.Commit=$(git rev-parse HEAD)" ./cmd/api
+//
+// Builds without them fall back to the VCS revision Go stamps into the
+// binary, when there is one.
+package version
+
+import "runtime/debug"
+
+var (
+	// Version is the release version, or "dev" for local builds
+	Version = "dev"
+
+	// Commit is the source revision the binary was built from
+	Commit = ""
+)
+
+func init() {
+	if Commit != "" {
+		return
+	}
+	Commit = "unknown"
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				Commit = setting.Value
+			}
+		}
+	}
+}
@@ -0,0 +1,20 @@
+// Errors from the API are RFC 9457 problem details (application/problem+json)
+export interface Problem {
+  type?: string
+  title?: string
+  status?: number
+  detail?: string
+  code?: string
+}
+
+// Returns the message to show for a failed response: the problem's detail,
+// falling back to its title and then the HTTP status text
+export async function problemMessage(response: Response): Promise<string> {
+  const fallback = response.statusText || `Request failed (${response.status})`
+  try {
+    const problem: Problem = await response.json()
+    return problem.detail || problem.title || fallback
+  } catch {
+    return fallback
+  }
+}
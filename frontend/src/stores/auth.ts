@@ -0,0 +1,47 @@
+import { ref, computed } from 'vue'
+import { defineStore } from 'pinia'
+import { problemMessage } from '@/api/problem'
+
+export interface AuthUser {
+  id: number
+  name: string
+  email: string
+}
+
+const API_URL = __API_URL__
+
+export const useAuthStore = defineStore('auth', () => {
+  const user = ref<AuthUser | null>(null)
+  const isLoggedIn = computed(() => user.value !== null)
+
+  // Restore the session from the HttpOnly cookie, if any
+  async function fetchCurrentUser() {
+    const response = await fetch(`${API_URL}/api/auth/me`, { credentials: 'include' })
+    user.value = response.ok ? await response.json() : null
+  }
+
+  async function login(email: string, password: string) {
+    const response = await fetch(`${API_URL}/api/auth/login`, {
+      method: 'POST',
+      credentials: 'include',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ email, password })
+    })
+
+    if (!response.ok) {
+      throw new Error(await problemMessage(response))
+    }
+
+    user.value = await response.json()
+  }
+
+  async function logout() {
+    await fetch(`${API_URL}/api/auth/logout`, {
+      method: 'POST',
+      credentials: 'include'
+    })
+    user.value = null
+  }
+
+  return { user, isLoggedIn, fetchCurrentUser, login, logout }
+})
@@ -127,6 +127,13 @@ resource "azurerm_container_app" "backend" {
         value = "production"
       }
 
+      # Frontend and backend are served from different sites, so the
+      # session cookie must be sent on cross-site credentialed requests
+      env {
+        name  = "SESSION_COOKIE_SAMESITE"
+        value = "none"
+      }
+
       dynamic "env" {
         for_each = var.additional_env_vars
         content {
@@ -134,6 +141,32 @@ resource "azurerm_container_app" "backend" {
           value = env.value
         }
       }
+
+      # Migrations run before the server listens, so startup allows up to
+      # five minutes; readiness drops the replica while a dependency is down
+      startup_probe {
+        transport               = "HTTP"
+        port                    = tonumber(var.container_port)
+        path                    = "/startupz"
+        interval_seconds        = 30
+        failure_count_threshold = 10
+      }
+
+      readiness_probe {
+        transport               = "HTTP"
+        port                    = tonumber(var.container_port)
+        path                    = "/readyz"
+        interval_seconds        = 10
+        failure_count_threshold = 3
+      }
+
+      liveness_probe {
+        transport               = "HTTP"
+        port                    = tonumber(var.container_port)
+        path                    = "/livez"
+        interval_seconds        = 10
+        failure_count_threshold = 3
+      }
     }
   }
 
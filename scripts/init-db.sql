@@ -10,7 +10,8 @@ SET timezone = 'UTC';
 -- Create any extensions we might need
 CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
 
--- The users table will be created by the Go application migrations
--- when it starts up and calls database.Migrate()
+-- Application tables are created by the versioned migrations in
+-- backend/internal/database/migrations, applied at startup (AUTO_MIGRATE)
+-- or with `go run ./cmd/migrate up`
 
 -- You can add any other initial setup here that your research projects commonly need 
\ No newline at end of file